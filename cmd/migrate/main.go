@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/db/migrations"
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/migrate"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] status|up|down-to <version>\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	log := slog.Default()
+
+	dryRun := flag.Bool("dry-run", false, "log the migrations that would run without running them")
+	timeout := flag.Duration("timeout", 5*time.Minute, "give up if the migrations (including waiting on the lock) take longer than this")
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("invalid config")
+		panic(err)
+	}
+
+	lvl, err := logutil.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.With(
+			logutil.LogAttrError(err),
+			slog.String("logLevel", cfg.LogLevel),
+		).Error("invalid log level")
+		panic(err)
+	}
+	slogOpts := slog.HandlerOptions{
+		Level: lvl,
+	}
+
+	if cfg.Mode != "local" {
+		log = slog.New(slog.NewJSONHandler(os.Stderr, &slogOpts))
+	} else {
+		log = slog.New(slog.NewTextHandler(os.Stderr, &slogOpts))
+	}
+
+	if err := run(log, cfg, flag.Arg(0), flag.Arg(1), *dryRun, *timeout); err != nil {
+		if errors.Is(err, errUsage) {
+			usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// errUsage is a bad command line, it exits with 2 instead of 1.
+var errUsage = errors.New("invalid usage")
+
+// run does the work so its defers (closing the db, etc.) happen before main
+// exits.
+func run(log *slog.Logger, cfg config.Config, cmd string, arg string, dryRun bool, timeout time.Duration) error {
+	dbx := sqlx.MustConnect("postgres", cfg.DB.ConnStr())
+	defer dbx.Close()
+
+	migrator, err := migrate.NewMigrator(log, dbx, migrations.FS)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("invalid migrations")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch cmd {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Error("failed to get status")
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	case "up":
+		migrated, err := migrator.Up(ctx, dryRun)
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Error("failed to migrate up")
+			return err
+		}
+		log.With(slog.Int("count", len(migrated)), slog.Bool("dryRun", dryRun)).Info("done")
+		return nil
+	case "down-to":
+		version, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return errUsage
+		}
+		migrated, err := migrator.DownTo(ctx, version, dryRun)
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Error("failed to migrate down")
+			return err
+		}
+		log.With(slog.Int("count", len(migrated)), slog.Bool("dryRun", dryRun)).Info("done")
+		return nil
+	default:
+		return errUsage
+	}
+}
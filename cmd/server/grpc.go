@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/mdlw"
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	orgv1 "github.com/RyanBard/go-service-ex/pkg/pb/org/v1"
+	userv1 "github.com/RyanBard/go-service-ex/pkg/pb/user/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// grpcPublic are the services that don't need a token, like /health and
+// /readiness they're for load balancers and tooling (ex. grpcurl).
+var grpcPublic = []string{
+	healthpb.Health_ServiceDesc.ServiceName,
+	reflectionv1.ServerReflection_ServiceDesc.ServiceName,
+	reflectionv1alpha.ServerReflection_ServiceDesc.ServiceName,
+}
+
+// newGRPCServer registers the gRPC services, health reports every service as
+// serving until it's shut down (when the server starts draining).
+func newGRPCServer(
+	log *slog.Logger,
+	authCfg config.AuthConfig,
+	keys mdlw.KeySource,
+	memberships mdlw.MembershipSource,
+	reg prometheus.Registerer,
+	orgSrv orgv1.OrgServiceServer,
+	userSrv userv1.UserServiceServer,
+) (*grpc.Server, *health.Server) {
+	unaryMetrics, streamMetrics := metrics.GRPC(reg)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			mdlw.UnaryRecovery(log),
+			mdlw.UnaryReqID(log),
+			mdlw.UnaryTrace(log),
+			unaryMetrics,
+			mdlw.UnaryAuth(log, authCfg, keys, grpcPublic...),
+			mdlw.UnaryMemberships(log, memberships),
+		),
+		grpc.ChainStreamInterceptor(
+			mdlw.StreamRecovery(log),
+			mdlw.StreamReqID(log),
+			mdlw.StreamTrace(log),
+			streamMetrics,
+			mdlw.StreamAuth(log, authCfg, keys, grpcPublic...),
+			mdlw.StreamMemberships(log, memberships),
+		),
+	)
+	// permissions are checked by the services (see internal/authz), the
+	// interceptors only require a valid token
+	orgv1.RegisterOrgServiceServer(srv, orgSrv)
+	userv1.RegisterUserServiceServer(srv, userSrv)
+
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus(orgv1.OrgService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthSrv.SetServingStatus(userv1.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthSrv)
+	reflection.Register(srv)
+	return srv, healthSrv
+}
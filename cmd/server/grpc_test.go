@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	orgv1 "github.com/RyanBard/go-service-ex/pkg/pb/org/v1"
+	userv1 "github.com/RyanBard/go-service-ex/pkg/pb/user/v1"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var authCfg = config.AuthConfig{
+	JWTSecret:   "jwt-secret",
+	JWTAudience: "jwt-audience",
+	JWTIssuer:   "jwt-issuer",
+}
+
+// initGRPC serves the services, which are left unimplemented since only the
+// interceptors are under test.
+func initGRPC(t *testing.T) *grpc.ClientConn {
+	return serveGRPC(t, orgv1.UnimplementedOrgServiceServer{})
+}
+
+func serveGRPC(t *testing.T, orgSrv orgv1.OrgServiceServer) *grpc.ClientConn {
+	lis := bufconn.Listen(1024 * 1024)
+	srv, _ := newGRPCServer(
+		testutil.GetLogger(),
+		authCfg,
+		nil,
+		noMemberships{},
+		prometheus.NewRegistry(),
+		orgSrv,
+		userv1.UnimplementedUserServiceServer{},
+	)
+	go srv.Serve(lis)
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		srv.Stop()
+	})
+	return conn
+}
+
+func withToken(t *testing.T) context.Context {
+	return withClaims(t, jwt.MapClaims{})
+}
+
+func withClaims(t *testing.T, claims jwt.MapClaims) context.Context {
+	claims["sub"] = "foo-id"
+	claims["aud"] = authCfg.JWTAudience
+	claims["iss"] = authCfg.JWTIssuer
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(authCfg.JWTSecret))
+	assert.Nil(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGRPC_Health(t *testing.T) {
+	client := healthpb.NewHealthClient(initGRPC(t))
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: orgv1.OrgService_ServiceDesc.ServiceName})
+
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestGRPC_Reflection(t *testing.T) {
+	client := reflectionv1.NewServerReflectionClient(initGRPC(t))
+
+	stream, err := client.ServerReflectionInfo(context.Background())
+	assert.Nil(t, err)
+	err = stream.Send(&reflectionv1.ServerReflectionRequest{
+		MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+	})
+	assert.Nil(t, err)
+	resp, err := stream.Recv()
+
+	assert.Nil(t, err)
+	var services []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services = append(services, s.Name)
+	}
+	assert.Contains(t, services, orgv1.OrgService_ServiceDesc.ServiceName)
+	assert.Contains(t, services, userv1.UserService_ServiceDesc.ServiceName)
+}
+
+func TestGRPC_NoToken(t *testing.T) {
+	client := orgv1.NewOrgServiceClient(initGRPC(t))
+
+	_, err := client.GetOrg(context.Background(), &orgv1.GetOrgRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPC_NoTokenStream(t *testing.T) {
+	client := userv1.NewUserServiceClient(initGRPC(t))
+
+	stream, err := client.ExportUsers(context.Background(), &userv1.ExportUsersRequest{})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPC_Token(t *testing.T) {
+	client := orgv1.NewOrgServiceClient(initGRPC(t))
+
+	_, err := client.GetOrg(withToken(t), &orgv1.GetOrgRequest{Id: "foo-id"})
+
+	// past the interceptors, to the unimplemented service
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestGRPC_NonAdminForbidden(t *testing.T) {
+	// no dao is needed, the service turns a non-admin away before using it
+	log := testutil.GetLogger()
+	client := orgv1.NewOrgServiceClient(serveGRPC(t, org.NewGRPCServer(log, org.NewService(log, nil, nil, nil, nil, nil))))
+
+	_, err := client.CreateOrg(withClaims(t, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin}), &orgv1.CreateOrgRequest{Name: "foo-name", Desc: "foo-desc"})
+
+	// there's no admin interceptor, it's the service's check that answers
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, problem.CodeForbidden, rpcerr.Reason(err))
+}
+
+type noMemberships struct{}
+
+func (noMemberships) GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error) {
+	return nil, nil
+}
@@ -1,17 +1,37 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/db/migrations"
+	"github.com/RyanBard/go-service-ex/internal/audit"
 	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/health"
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/internal/idempotency"
 	"github.com/RyanBard/go-service-ex/internal/idgen"
+	"github.com/RyanBard/go-service-ex/internal/invitation"
+	"github.com/RyanBard/go-service-ex/internal/jwks"
+	"github.com/RyanBard/go-service-ex/internal/lifecycle"
 	"github.com/RyanBard/go-service-ex/internal/mdlw"
+	"github.com/RyanBard/go-service-ex/internal/membership"
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/migrate"
+	"github.com/RyanBard/go-service-ex/internal/notify"
+	"github.com/RyanBard/go-service-ex/internal/onboarding"
 	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/purge"
 	"github.com/RyanBard/go-service-ex/internal/timer"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
 	"github.com/RyanBard/go-service-ex/internal/tx"
 	"github.com/RyanBard/go-service-ex/internal/user"
 	"github.com/gin-gonic/gin"
@@ -46,72 +66,164 @@ func main() {
 		log = slog.New(slog.NewTextHandler(os.Stdout, &slogOpts))
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), log, cfg.Tracing)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to setup tracing")
+		panic(err)
+	}
+
 	timer := timer.New()
 	idGenerator := idgen.New()
 
-	dbx := sqlx.MustConnect(
-		"postgres",
-		fmt.Sprintf(
-			"user=%s password=%s dbname=%s sslmode=%s",
-			cfg.DB.User,
-			cfg.DB.Password,
-			cfg.DB.DBName,
-			cfg.DB.SSLMode,
-		),
-	)
+	dbx := sqlx.MustConnect("postgres", cfg.DB.ConnStr())
 	dbx.SetMaxIdleConns(cfg.DB.MaxIdleConns)
 	dbx.SetMaxOpenConns(cfg.DB.MaxOpenConns)
 
-	txMGR := tx.NewTXMGR(log, dbx)
+	migrator, err := migrate.NewMigrator(log, dbx, migrations.FS)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("invalid migrations")
+		panic(err)
+	}
+	if cfg.DB.MigrateOnBoot {
+		if _, err := migrator.Up(context.Background(), false); err != nil {
+			log.With(logutil.LogAttrError(err)).Error("failed to migrate db")
+			panic(err)
+		}
+	}
+
+	metricsRegistry := metrics.NewRegistry()
+	metrics.RegisterDBStats(metricsRegistry, dbx.DB, cfg.DB.DBName)
+	daoMetrics := metrics.NewDAOMetrics(metricsRegistry)
 
-	orgDAO := org.NewDAO(log, cfg.DB.QueryTimeout, dbx)
-	orgService := org.NewService(log, orgDAO, txMGR, timer, idGenerator)
+	txMGR := tx.NewTXMGR(log, dbx, metrics.NewTXMetrics(metricsRegistry))
+
+	auditDAO := audit.NewInstrumentedDAO(audit.NewDAO(log, cfg.DB.QueryTimeout, dbx), daoMetrics)
+	auditService := audit.NewTracedService(audit.NewService(log, auditDAO, timer, idGenerator))
+	auditCtrl := audit.NewController(log, auditService)
+
+	orgDAO := org.NewInstrumentedDAO(org.NewDAO(log, cfg.DB.QueryTimeout, dbx), daoMetrics)
+	orgService := org.NewTracedService(org.NewService(log, orgDAO, auditService, txMGR, timer, idGenerator))
 	orgCtrl := org.NewController(log, orgService)
 
-	userDAO := user.NewDAO(log, cfg.DB.QueryTimeout, dbx)
-	userService := user.NewService(log, orgService, userDAO, txMGR, timer, idGenerator)
+	notifier, err := notify.New(log, cfg.Notify)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to setup notifier")
+		panic(err)
+	}
+
+	userDAO := user.NewInstrumentedDAO(user.NewDAO(log, cfg.DB.QueryTimeout, dbx), daoMetrics)
+	userService := user.NewTracedService(user.NewService(log, orgService, userDAO, auditService, txMGR, timer, idGenerator, notifier, cfg.User.EmailChangeTTL))
 	userCtrl := user.NewController(log, userService)
 
-	r := gin.New()
-	r.Use(gin.Recovery())
-	r.Use(mdlw.ReqID(log))
+	membershipDAO := membership.NewInstrumentedDAO(membership.NewDAO(log, cfg.DB.QueryTimeout, dbx), daoMetrics)
+	membershipService := membership.NewTracedService(membership.NewService(log, orgService, userService, membershipDAO, auditService, txMGR, timer))
+	membershipCtrl := membership.NewController(log, membershipService)
 
-	r.GET("/health", func(c *gin.Context) {
-		log.With(logutil.LogAttrReqID(c.Request.Context())).Debug("Health called")
-		c.Status(http.StatusNoContent)
-	})
+	invitationDAO := invitation.NewInstrumentedDAO(invitation.NewDAO(log, cfg.DB.QueryTimeout, dbx), daoMetrics)
+	invitationService := invitation.NewTracedService(invitation.NewService(log, orgService, userService, membershipDAO, invitationDAO, auditService, txMGR, timer, idGenerator, notifier, cfg.Invitation.TTL))
+	invitationCtrl := invitation.NewController(log, invitationService)
 
-	r.GET("/readiness", func(c *gin.Context) {
-		log.With(logutil.LogAttrReqID(c.Request.Context())).Debug("Readiness called")
-		c.Status(http.StatusNoContent)
-	})
+	onboardingService := onboarding.NewTracedService(onboarding.NewService(log, orgService, userService, txMGR))
+	onboardingCtrl := onboarding.NewController(log, onboardingService)
 
-	authorized := r.Group("/api")
-	authorized.Use(mdlw.Auth(log, cfg.AuthConfig))
+	// users first, they reference their org
+	purgeJob := purge.NewJob(log, timer, cfg.Purge.Retention)
+	purgeJob.Add("users", userDAO)
+	purgeJob.Add("orgs", orgDAO)
 
-	adminPriv := r.Group("/api")
-	adminPriv.Use(mdlw.Auth(log, cfg.AuthConfig))
-	adminPriv.Use(mdlw.RequiresAdmin(log))
+	// idempotency keys have their own expiry, so there's no retention on top
+	idempotencyDAO := idempotency.NewInstrumentedDAO(idempotency.NewDAO(log, cfg.DB.QueryTimeout, dbx), daoMetrics)
+	idempotencyPurgeJob := purge.NewJob(log, timer, 0)
+	idempotencyPurgeJob.Add("idempotency keys", idempotencyDAO)
 
-	authorized.GET("/orgs/:id", orgCtrl.GetByID)
-	authorized.GET("/orgs", orgCtrl.GetAll)
+	r := gin.New()
+	r.Use(mdlw.Recovery(log))
+	r.Use(mdlw.ReqID(log))
+	r.Use(mdlw.Trace(log))
+	r.Use(metrics.HTTP(metricsRegistry))
+
+	readiness := lifecycle.NewReadiness(log)
+
+	healthRegistry := health.NewRegistry(log, cfg.Health.CheckTimeout)
+	healthRegistry.AddReadiness("draining", readiness.Check)
+	healthRegistry.AddReadiness("db", health.DBPing(dbx))
+	healthRegistry.AddReadiness("dbPool", health.PoolSaturation(dbx.Stats, cfg.Health.PoolMaxInUseRatio))
+	healthRegistry.AddReadiness("dbSchema", health.MigrationVersion(migrator))
+
+	var keySource mdlw.KeySource
+	if cfg.AuthConfig.JWKSURL != "" {
+		keySource = jwks.NewURLSource(log, timer, httpx.NewClient(http.Client{Timeout: 10 * time.Second}), cfg.AuthConfig.JWKSURL, cfg.AuthConfig.JWKSCacheTTL)
+	} else if cfg.AuthConfig.JWKSFile != "" {
+		keySource, err = jwks.NewFileSource(log, timer, cfg.AuthConfig.JWKSFile, cfg.AuthConfig.JWKSCacheTTL)
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Error("invalid jwks file")
+			panic(err)
+		}
+	}
 
-	adminPriv.POST("/orgs", orgCtrl.Save)
-	adminPriv.PUT("/orgs", orgCtrl.Save)
-	adminPriv.POST("/orgs/:id", orgCtrl.Save)
-	adminPriv.PUT("/orgs/:id", orgCtrl.Save)
-	adminPriv.DELETE("/orgs/:id", orgCtrl.Delete)
+	routes(r, handlers{
+		health:      healthRegistry,
+		metrics:     metrics.Handler(metricsRegistry),
+		auth:        mdlw.Auth(log, cfg.AuthConfig, keySource),
+		memberships: mdlw.Memberships(log, membershipDAO),
+		idempotency: idempotency.Middleware(log, idempotencyDAO, timer, cfg.Idempotency.TTL, cfg.Idempotency.MaxBodySize),
+		org:         orgCtrl,
+		user:        userCtrl,
+		membership:  membershipCtrl,
+		invitation:  invitationCtrl,
+		audit:       auditCtrl,
+		onboarding:  onboardingCtrl,
+		docsUI:      cfg.Docs.UI,
+	})
 
-	authorized.GET("/orgs/:id/users", userCtrl.GetAllByOrgID)
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%v", cfg.Port),
+		Handler:      r,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
 
-	authorized.GET("/users/:id", userCtrl.GetByID)
-	authorized.GET("/users", userCtrl.GetAll)
+	grpcSrv, grpcHealth := newGRPCServer(log, cfg.AuthConfig, keySource, membershipDAO, metricsRegistry, org.NewGRPCServer(log, orgService), user.NewGRPCServer(log, userService))
+	grpcLis, err := net.Listen("tcp", fmt.Sprintf(":%v", cfg.GRPCPort))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to listen for grpc")
+		panic(err)
+	}
 
-	adminPriv.POST("/users", userCtrl.Save)
-	adminPriv.PUT("/users", userCtrl.Save)
-	adminPriv.POST("/users/:id", userCtrl.Save)
-	adminPriv.PUT("/users/:id", userCtrl.Save)
-	adminPriv.DELETE("/users/:id", userCtrl.Delete)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	r.Run(fmt.Sprintf(":%v", cfg.Port))
+	if cfg.Purge.Interval > 0 {
+		go purgeJob.Run(ctx, cfg.Purge.Interval)
+		go idempotencyPurgeJob.Run(ctx, cfg.Purge.Interval)
+	}
+
+	grpcErr := make(chan error, 1)
+	go func() {
+		grpcErr <- lifecycle.ServeGRPC(ctx, log, grpcSrv, grpcLis, lifecycle.DrainerFunc(grpcHealth.Shutdown), cfg.Server.DrainDelay, cfg.Server.ShutdownTimeout)
+		// either server stopping unexpectedly shuts the other down
+		stop()
+	}()
+
+	err = lifecycle.Serve(ctx, log, srv, readiness, cfg.Server.DrainDelay, cfg.Server.ShutdownTimeout)
+	stop()
+	if gErr := <-grpcErr; err == nil {
+		err = gErr
+	}
+
+	// only close the pool once the server has stopped handling requests so
+	// in-flight transactions can finish
+	if closeErr := dbx.Close(); closeErr != nil {
+		log.With(logutil.LogAttrError(closeErr)).Error("failed to close db")
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if tracingErr := shutdownTracing(shutdownCtx); tracingErr != nil {
+		log.With(logutil.LogAttrError(tracingErr)).Error("failed to flush traces")
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+	log.Info("shutdown complete")
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"github.com/RyanBard/go-service-ex/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// The controllers' handlers, named here so routes can be registered (and
+// checked against the openapi spec) without a db behind them.
+type (
+	healthHandlers interface {
+		Liveness(c *gin.Context)
+		Readiness(c *gin.Context)
+	}
+	orgHandlers interface {
+		GetByID(c *gin.Context)
+		GetAll(c *gin.Context)
+		Export(c *gin.Context)
+		Save(c *gin.Context)
+		Patch(c *gin.Context)
+		Delete(c *gin.Context)
+		Restore(c *gin.Context)
+	}
+	userHandlers interface {
+		GetByID(c *gin.Context)
+		GetAll(c *gin.Context)
+		GetAllByOrgID(c *gin.Context)
+		Export(c *gin.Context)
+		Save(c *gin.Context)
+		Patch(c *gin.Context)
+		Delete(c *gin.Context)
+		Restore(c *gin.Context)
+		RequestEmailChange(c *gin.Context)
+		ConfirmEmailChange(c *gin.Context)
+		Import(c *gin.Context)
+		Batch(c *gin.Context)
+	}
+	membershipHandlers interface {
+		GetByOrgID(c *gin.Context)
+		GetByUserID(c *gin.Context)
+		Add(c *gin.Context)
+		Remove(c *gin.Context)
+	}
+	invitationHandlers interface {
+		GetByOrgID(c *gin.Context)
+		Create(c *gin.Context)
+		Resend(c *gin.Context)
+		Revoke(c *gin.Context)
+		Accept(c *gin.Context)
+	}
+	auditHandlers interface {
+		Search(c *gin.Context)
+	}
+	onboardingHandlers interface {
+		Onboard(c *gin.Context)
+	}
+)
+
+type handlers struct {
+	health      healthHandlers
+	metrics     gin.HandlerFunc
+	auth        gin.HandlerFunc
+	memberships gin.HandlerFunc
+	idempotency gin.HandlerFunc
+	org         orgHandlers
+	user        userHandlers
+	membership  membershipHandlers
+	invitation  invitationHandlers
+	audit       auditHandlers
+	onboarding  onboardingHandlers
+	// docsUI serves the Swagger UI at /docs
+	docsUI bool
+}
+
+// routes registers every route, internal/openapi describes each of them so
+// add a route there too (routes_test checks the two match).
+func routes(r *gin.Engine, h handlers) {
+	r.GET("/health", h.health.Liveness)
+	r.GET("/readiness", h.health.Readiness)
+	r.GET("/metrics", h.metrics)
+
+	r.GET("/openapi.json", openapi.Handler(openapi.Spec()))
+	if h.docsUI {
+		r.GET("/docs", openapi.DocsHandler("/openapi.json", "/docs"))
+		r.GET("/docs/:file", openapi.DocsAssets())
+	}
+
+	// the invitation's token is what's checked, the person accepting it
+	// doesn't have a jwt yet
+	public := r.Group("/api")
+	public.POST("/invitations/accept", h.invitation.Accept)
+
+	// the import streams its body a row at a time, idempotency would have to
+	// read all of it first (and keep the report)
+	streamed := r.Group("/api")
+	streamed.Use(h.auth)
+	streamed.Use(h.memberships)
+	streamed.POST("/users/import", h.user.Import)
+
+	authorized := r.Group("/api")
+	authorized.Use(h.auth)
+	authorized.Use(h.memberships)
+	authorized.Use(h.idempotency)
+
+	// permissions are checked by the services (see internal/authz), the
+	// routes only require a valid token
+	authorized.GET("/orgs/:id", h.org.GetByID)
+	authorized.GET("/orgs", h.org.GetAll)
+	authorized.GET("/orgs/export", h.org.Export)
+
+	authorized.POST("/orgs", h.org.Save)
+	authorized.PUT("/orgs", h.org.Save)
+	authorized.POST("/orgs/:id", h.org.Save)
+	authorized.PUT("/orgs/:id", h.org.Save)
+	authorized.PATCH("/orgs/:id", h.org.Patch)
+	authorized.DELETE("/orgs/:id", h.org.Delete)
+	authorized.POST("/orgs/:id/restore", h.org.Restore)
+
+	authorized.GET("/orgs/:id/users", h.user.GetAllByOrgID)
+	authorized.GET("/orgs/:id/memberships", h.membership.GetByOrgID)
+	authorized.POST("/orgs/:id/memberships", h.membership.Add)
+	authorized.DELETE("/orgs/:id/memberships/:userID", h.membership.Remove)
+	authorized.GET("/orgs/:id/invitations", h.invitation.GetByOrgID)
+	authorized.POST("/orgs/:id/invitations", h.invitation.Create)
+
+	authorized.GET("/users/:id", h.user.GetByID)
+	authorized.GET("/users", h.user.GetAll)
+	authorized.GET("/users/export", h.user.Export)
+
+	authorized.POST("/users", h.user.Save)
+	authorized.PUT("/users", h.user.Save)
+	authorized.POST("/users/:id", h.user.Save)
+	authorized.PUT("/users/:id", h.user.Save)
+	authorized.PATCH("/users/:id", h.user.Patch)
+	authorized.DELETE("/users/:id", h.user.Delete)
+	authorized.POST("/users/:id/restore", h.user.Restore)
+	authorized.POST("/users/:id/email-change", h.user.RequestEmailChange)
+	authorized.POST("/users/:id/email-change/confirm", h.user.ConfirmEmailChange)
+	authorized.GET("/users/:id/memberships", h.membership.GetByUserID)
+	// POST /users:batch, see Batch for why it's a param
+	authorized.POST("/users:method", h.user.Batch)
+
+	authorized.POST("/invitations/:id/resend", h.invitation.Resend)
+	authorized.DELETE("/invitations/:id", h.invitation.Revoke)
+
+	authorized.GET("/audit", h.audit.Search)
+
+	authorized.POST("/onboarding", h.onboarding.Onboard)
+}
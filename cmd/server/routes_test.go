@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/audit"
+	"github.com/RyanBard/go-service-ex/internal/health"
+	"github.com/RyanBard/go-service-ex/internal/invitation"
+	"github.com/RyanBard/go-service-ex/internal/membership"
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/onboarding"
+	"github.com/RyanBard/go-service-ex/internal/openapi"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// aliases are the routes whose OpenAPI path isn't their gin path.
+var aliases = map[string]string{
+	// gin can't route a literal colon, see user's Batch
+	"/api/users:method": "/api/users:batch",
+}
+
+// undocumented are the routes that aren't part of the API.
+var undocumented = map[string]bool{
+	"GET /docs":       true,
+	"GET /docs/:file": true,
+}
+
+func initRouter(docsUI bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	log := testutil.GetLogger()
+	noop := func(c *gin.Context) {}
+	r := gin.New()
+	// none of the handlers are called, so the services aren't needed
+	routes(r, handlers{
+		health:      health.NewRegistry(log, time.Second),
+		metrics:     metrics.Handler(metrics.NewRegistry()),
+		auth:        noop,
+		memberships: noop,
+		idempotency: noop,
+		org:         org.NewController(log, nil),
+		user:        user.NewController(log, nil),
+		membership:  membership.NewController(log, nil),
+		invitation:  invitation.NewController(log, nil),
+		audit:       audit.NewController(log, nil),
+		onboarding:  onboarding.NewController(log, nil),
+		docsUI:      docsUI,
+	})
+	return r
+}
+
+func documentedRoutes() map[string]bool {
+	documented := map[string]bool{}
+	for path, item := range openapi.Spec().Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return documented
+}
+
+func TestRoutes_Documented(t *testing.T) {
+	documented := documentedRoutes()
+
+	for _, route := range initRouter(true).Routes() {
+		name := route.Method + " " + route.Path
+		if undocumented[name] {
+			continue
+		}
+		path, found := aliases[route.Path]
+		if !found {
+			path = openapi.Path(route.Path)
+		}
+		assert.True(t, documented[route.Method+" "+path], "%s isn't in the openapi spec", name)
+	}
+}
+
+func TestRoutes_NothingExtraDocumented(t *testing.T) {
+	registered := map[string]bool{}
+	for _, route := range initRouter(false).Routes() {
+		path, found := aliases[route.Path]
+		if !found {
+			path = openapi.Path(route.Path)
+		}
+		registered[route.Method+" "+path] = true
+	}
+
+	for name := range documentedRoutes() {
+		assert.True(t, registered[name], "%s is in the openapi spec but isn't routed", name)
+	}
+}
+
+func TestRoutes_DocsUIOff(t *testing.T) {
+	r := initRouter(false)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestRoutes_DocsUIAssets(t *testing.T) {
+	r := initRouter(true)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/swagger-ui-bundle.js", nil))
+
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestRoutes_OpenAPI(t *testing.T) {
+	r := initRouter(false)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"openapi":"3.0.3"`)
+}
@@ -1,3 +1,4 @@
 #!/bin/bash
 
 sudo -u postgres psql -f db/local-setup.sql
+DB_USER=go_micro_ex_user DB_PASSWORD=changeit DB_NAME=go_micro_ex_db go run ./cmd/migrate up
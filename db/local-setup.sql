@@ -1,10 +1,8 @@
 CREATE USER go_micro_ex_user PASSWORD 'changeit';
 CREATE DATABASE go_micro_ex_db WITH OWNER go_micro_ex_user;
 
-\connect go_micro_ex_db
-
-\i db/initial-schema.sql
-\i db/initial-data.sql
+-- The schema and seed data are created by the migrations (see db/migrations
+-- and cmd/migrate), they run as go_micro_ex_user so it owns the tables.
 
 -- ALTER ROLE go_micro_ex_ddl SUPERUSER;
 -- or maybe: CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
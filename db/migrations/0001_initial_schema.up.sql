@@ -1,4 +1,6 @@
-CREATE TABLE orgs(
+-- IF NOT EXISTS lets databases created by the old psql setup script adopt
+-- the migrations without having to be rebuilt.
+CREATE TABLE IF NOT EXISTS orgs(
 	id TEXT NOT NULL,
 	name TEXT NOT NULL,
 	description TEXT,
@@ -12,7 +14,7 @@ CREATE TABLE orgs(
 	CONSTRAINT orgs_name_uk UNIQUE (name)
 );
 
-CREATE TABLE users(
+CREATE TABLE IF NOT EXISTS users(
 	id TEXT NOT NULL,
 	-- TODO - should this be nullable (allow pending users to not be associated with an org)
 	org_id TEXT NOT NULL,
@@ -0,0 +1,2 @@
+DELETE FROM users WHERE id = 'fc83cf36-bba0-41f0-8125-2ebc03087140';
+DELETE FROM orgs WHERE id = 'a517c24e-9b5f-4e5a-b840-e4f70a74725f';
@@ -19,7 +19,8 @@ VALUES(
 	CURRENT_TIMESTAMP,
 	'init-script',
 	1
-);
+)
+ON CONFLICT (id) DO NOTHING;
 
 INSERT INTO users (
 	id,
@@ -48,4 +49,5 @@ VALUES(
 	CURRENT_TIMESTAMP,
 	'init-script',
 	1
-);
+)
+ON CONFLICT (id) DO NOTHING;
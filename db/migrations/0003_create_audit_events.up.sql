@@ -0,0 +1,18 @@
+-- One row per create, update and delete, written in the same tx as the change
+-- so a rolled back change never shows up here.
+CREATE TABLE IF NOT EXISTS audit_events(
+	id TEXT NOT NULL,
+	occurred_at TIMESTAMP NOT NULL,
+	actor_id TEXT NOT NULL,
+	req_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	entity_type TEXT NOT NULL,
+	entity_id TEXT NOT NULL,
+	-- {"<field>": {"before": <old value>, "after": <new value>}} for the fields that changed
+	diff JSONB NOT NULL,
+	CONSTRAINT audit_events_pk PRIMARY KEY(id)
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_occurred_at_idx ON audit_events(occurred_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS audit_events_entity_idx ON audit_events(entity_type, entity_id, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events(actor_id, occurred_at DESC);
@@ -0,0 +1,13 @@
+-- Rows that were soft deleted would come back to life without the columns,
+-- so they're removed first.
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+DELETE FROM orgs o WHERE o.deleted_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users u WHERE u.org_id = o.id);
+
+DROP INDEX IF EXISTS users_deleted_at_idx;
+DROP INDEX IF EXISTS orgs_deleted_at_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE orgs DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE orgs DROP COLUMN IF EXISTS deleted_at;
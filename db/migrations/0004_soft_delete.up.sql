@@ -0,0 +1,12 @@
+-- Deletes only stamp these, the rows are removed for good by the purge job
+-- once they're older than the retention window. The unique constraints still
+-- cover deleted rows, so a name/email isn't reusable until it's purged and a
+-- restore can never collide with a newer row.
+ALTER TABLE orgs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE orgs ADD COLUMN IF NOT EXISTS deleted_by TEXT;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_by TEXT;
+
+CREATE INDEX IF NOT EXISTS orgs_deleted_at_idx ON orgs(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users(deleted_at) WHERE deleted_at IS NOT NULL;
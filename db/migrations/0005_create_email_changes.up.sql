@@ -0,0 +1,14 @@
+-- At most one pending email change per user, a new request replaces the old
+-- one. Only a hash of the token is kept, the token itself is only ever in the
+-- notification sent to the new email. Confirming deletes the row, so a token
+-- can't be used twice.
+CREATE TABLE IF NOT EXISTS email_changes(
+	user_id TEXT NOT NULL,
+	new_email TEXT NOT NULL,
+	token_hash TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	created_by TEXT NOT NULL,
+	CONSTRAINT email_changes_pk PRIMARY KEY(user_id),
+	CONSTRAINT email_changes_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
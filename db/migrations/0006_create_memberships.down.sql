@@ -0,0 +1,5 @@
+-- Only the home memberships can be represented without the table, the rest
+-- are dropped with it.
+DROP TRIGGER IF EXISTS users_home_membership_trg ON users;
+DROP FUNCTION IF EXISTS sync_home_membership();
+DROP TABLE IF EXISTS memberships;
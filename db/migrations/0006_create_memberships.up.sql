@@ -0,0 +1,59 @@
+-- Lets a user belong to more than one org. users.org_id stays as the user's
+-- home org (so the existing responses don't change) and every user has a
+-- membership for it, the trigger below keeps that one in sync with the column
+-- so inserts and reassigns don't have to know about memberships. The unique
+-- email on users is fine as is, a user in several orgs is still one row.
+CREATE TABLE IF NOT EXISTS memberships(
+	user_id TEXT NOT NULL,
+	org_id TEXT NOT NULL,
+	role TEXT NOT NULL,
+	joined_at TIMESTAMP NOT NULL,
+	created_by TEXT NOT NULL,
+	CONSTRAINT memberships_pk PRIMARY KEY(user_id, org_id),
+	CONSTRAINT memberships_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	CONSTRAINT memberships_org_fk FOREIGN KEY (org_id) REFERENCES orgs(id) ON DELETE CASCADE,
+	CONSTRAINT memberships_role_ck CHECK (role IN ('member', 'org_admin'))
+);
+
+CREATE INDEX IF NOT EXISTS memberships_org_id_idx ON memberships(org_id);
+
+INSERT INTO memberships (user_id, org_id, role, joined_at, created_by)
+SELECT
+	u.id,
+	u.org_id,
+	CASE WHEN u.is_admin THEN 'org_admin' ELSE 'member' END,
+	u.created_at,
+	u.created_by
+FROM users u
+ON CONFLICT (user_id, org_id) DO NOTHING;
+
+-- A user moved to another org keeps their role there if they were already a
+-- member, otherwise they join it the same way a new user would.
+CREATE OR REPLACE FUNCTION sync_home_membership() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'UPDATE' THEN
+		IF OLD.org_id = NEW.org_id THEN
+			RETURN NEW;
+		END IF;
+		DELETE FROM memberships
+		WHERE user_id = OLD.id
+		AND org_id = OLD.org_id;
+	END IF;
+	INSERT INTO memberships (user_id, org_id, role, joined_at, created_by)
+	VALUES (
+		NEW.id,
+		NEW.org_id,
+		CASE WHEN NEW.is_admin THEN 'org_admin' ELSE 'member' END,
+		NEW.updated_at,
+		NEW.updated_by
+	)
+	ON CONFLICT (user_id, org_id) DO NOTHING;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_home_membership_trg ON users;
+CREATE TRIGGER users_home_membership_trg
+AFTER INSERT OR UPDATE OF org_id ON users
+FOR EACH ROW
+EXECUTE FUNCTION sync_home_membership();
@@ -0,0 +1,27 @@
+-- An invitation is pending until it's accepted, revoked or it expires. Every
+-- one that hasn't been accepted or revoked holds the (org_id, email) slot, so
+-- an expired one is resent rather than invited again. Only a hash of the
+-- token is kept, resending replaces it.
+CREATE TABLE IF NOT EXISTS invitations(
+	id TEXT NOT NULL,
+	org_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	role TEXT NOT NULL,
+	token_hash TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	created_by TEXT NOT NULL,
+	accepted_at TIMESTAMP,
+	accepted_by TEXT,
+	revoked_at TIMESTAMP,
+	revoked_by TEXT,
+	CONSTRAINT invitations_pk PRIMARY KEY(id),
+	CONSTRAINT invitations_org_fk FOREIGN KEY (org_id) REFERENCES orgs(id) ON DELETE CASCADE,
+	CONSTRAINT invitations_token_hash_uk UNIQUE (token_hash),
+	CONSTRAINT invitations_role_ck CHECK (role IN ('member', 'org_admin'))
+);
+
+CREATE INDEX IF NOT EXISTS invitations_org_id_idx ON invitations(org_id, created_at, id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS invitations_pending_uk ON invitations(org_id, email)
+	WHERE accepted_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,17 @@
+-- The response to a POST/PUT that was sent with an Idempotency-Key, so a retry
+-- gets the same response instead of running it again. Keys are per user. A row
+-- with no status_code is still being handled, the fingerprint is a hash of the
+-- method, uri and body so a key can't be reused for a different request.
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+	user_id TEXT NOT NULL,
+	key TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	status_code INTEGER,
+	content_type TEXT NOT NULL DEFAULT '',
+	body BYTEA,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	CONSTRAINT idempotency_keys_pk PRIMARY KEY(user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys(expires_at);
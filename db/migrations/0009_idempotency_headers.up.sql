@@ -0,0 +1,3 @@
+-- The headers that are replayed with a stored response (ex. ETag, Location),
+-- {"<header>": ["<value>", ...]}.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS headers JSONB NOT NULL DEFAULT '{}';
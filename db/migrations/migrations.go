@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS holds the numbered up/down migrations, they are compiled into the
+// binaries so the schema always matches the code that ships with it.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -2,24 +2,71 @@ package apiclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKeyIdempotencyKey struct{}
+
+// WithIdempotencyKey makes the POST, PUT or PATCH sent with ctx use key. Each
+// call already keeps its key across the client's own retries, this is for
+// when the caller retries the call itself (ex. a job that's run again) and it
+// mustn't run twice on the server. Use a new key for every call that's meant
+// to run, without one each call gets its own random key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKeyIdempotencyKey{}, key)
+}
+
+func idempotencyKey(ctx context.Context) string {
+	if key, _ := ctx.Value(contextKeyIdempotencyKey{}).(string); key != "" {
+		return key
+	}
+	return uuid.NewString()
+}
+
+const (
+	defaultRetries = 2
+	defaultBackoff = 100 * time.Millisecond
 )
 
 type Client struct {
 	hc       *httpx.Client
 	getToken func(isRetry bool) (string, error)
+	retries  int
+	backoff  time.Duration
 }
 
 func NewClient(hc *httpx.Client, getToken func(isRetry bool) (string, error)) *Client {
 	return &Client{
 		hc:       hc,
 		getToken: getToken,
+		retries:  defaultRetries,
+		backoff:  defaultBackoff,
 	}
 }
 
+// WithRetries is a copy of the client that retries a network error or 5xx up
+// to retries times, waiting backoff before the first retry and twice as long
+// before each one after that. 0 turns retrying off. Only the calls sent with
+// an Idempotency-Key (POST, PUT and PATCH) are retried, the server can tell a
+// retry of one of those apart from a new call.
+func (ac *Client) WithRetries(retries int, backoff time.Duration) *Client {
+	c := *ac
+	c.retries = retries
+	c.backoff = backoff
+	return &c
+}
+
 func (ac *Client) Get(ctx context.Context, path string, pathParams map[string]string, queryParams map[string][]string, out interface{}) (err error) {
 	return ac.common(ctx, "GET", path, pathParams, queryParams, nil, out)
 }
@@ -37,9 +84,11 @@ func (ac *Client) Delete(ctx context.Context, path string, pathParams map[string
 }
 
 func (ac *Client) common(ctx context.Context, method string, path string, pathParams map[string]string, queryParams map[string][]string, in interface{}, out interface{}) (err error) {
-	reqID, _ := ctx.Value(ctxutil.ContextKeyReqID{}).(string)
+	ctx, span := tracing.Tracer().Start(ctx, fmt.Sprintf("%s %s", method, path), trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
 
 	var hb httpx.Builder
+	var key string
 	switch method {
 	case "GET":
 		hb = ac.hc.Get(path, pathParams).
@@ -51,12 +100,14 @@ func (ac *Client) common(ctx context.Context, method string, path string, pathPa
 			WithAccept("application/json").
 			WithContentType("application/json").
 			WithBody(in)
+		key = idempotencyKey(ctx)
 	case "PUT":
 		hb = ac.hc.Put(path, pathParams).
 			WithQueryParams(queryParams).
 			WithAccept("application/json").
 			WithContentType("application/json").
 			WithBody(in)
+		key = idempotencyKey(ctx)
 	case "DELETE":
 		hb = ac.hc.Delete(path, pathParams).
 			WithQueryParams(queryParams).
@@ -65,6 +116,61 @@ func (ac *Client) common(ctx context.Context, method string, path string, pathPa
 			WithBody(in)
 	}
 
+	return ac.send(ctx, hb, key, func(hb httpx.Builder) (int, error) {
+		return hb.RetrieveWithContext(ctx, &out)
+	})
+}
+
+// Patch takes the content type since there's more than one kind of patch (ex.
+// application/merge-patch+json), in is sent as json either way.
+func (ac *Client) Patch(ctx context.Context, path string, pathParams map[string]string, queryParams map[string][]string, contentType string, in interface{}, out interface{}) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, fmt.Sprintf("PATCH %s", path), trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+	hb := ac.hc.Patch(path, pathParams).
+		WithQueryParams(queryParams).
+		WithAccept("application/json").
+		WithContentType(contentType).
+		WithBody(in)
+	return ac.send(ctx, hb, idempotencyKey(ctx), func(hb httpx.Builder) (int, error) {
+		return hb.RetrieveWithContext(ctx, &out)
+	})
+}
+
+// GetStr is Get for responses that aren't json, ex. a csv export.
+func (ac *Client) GetStr(ctx context.Context, path string, pathParams map[string]string, queryParams map[string][]string, accept string, out *string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, fmt.Sprintf("GET %s", path), trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+	hb := ac.hc.Get(path, pathParams).
+		WithQueryParams(queryParams).
+		WithAccept(accept)
+	return ac.send(ctx, hb, "", func(hb httpx.Builder) (int, error) {
+		return hb.RetrieveStrWithContext(ctx, out)
+	})
+}
+
+// PostStr is Post for request bodies that aren't json, ex. a csv import, the
+// response is still json. The server streams these bodies so it doesn't
+// handle an Idempotency-Key for them, none is sent and they aren't retried.
+func (ac *Client) PostStr(ctx context.Context, path string, pathParams map[string]string, queryParams map[string][]string, contentType string, in string, out interface{}) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, fmt.Sprintf("POST %s", path), trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+	hb := ac.hc.Post(path, pathParams).
+		WithQueryParams(queryParams).
+		WithAccept("application/json").
+		WithContentType(contentType).
+		WithBody(in)
+	return ac.send(ctx, hb, "", func(hb httpx.Builder) (int, error) {
+		return hb.RetrieveWithContext(ctx, &out)
+	})
+}
+
+// send adds the auth, request id, idempotency key (when there is one) and trace
+// headers before retrieving. A 401 is retried once with a fresh token, and a
+// network error or 5xx with the same headers when there's a key (see
+// WithRetries), so a POST whose response was lost is replayed by the server
+// instead of run again.
+func (ac *Client) send(ctx context.Context, hb httpx.Builder, idempotencyKey string, retrieve func(hb httpx.Builder) (int, error)) (err error) {
+	reqID, _ := ctx.Value(ctxutil.ContextKeyReqID{}).(string)
 	token, err := ac.getToken(false)
 	if err != nil {
 		return err
@@ -75,18 +181,47 @@ func (ac *Client) common(ctx context.Context, method string, path string, pathPa
 	if reqID != "" {
 		headers["X-Request-Id"] = []string{reqID}
 	}
-	statusCode, err := hb.WithHeaders(headers).
-		RetrieveWithContext(ctx, &out)
+	if idempotencyKey != "" {
+		headers["Idempotency-Key"] = []string{idempotencyKey}
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(headers))
+	for attempt := 0; ; attempt++ {
+		statusCode, err := ac.attempt(hb, headers, retrieve)
+		if err == nil || idempotencyKey == "" || attempt >= ac.retries || !retryable(ctx, statusCode, err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(ac.backoff << attempt):
+		}
+	}
+}
+
+func (ac *Client) attempt(hb httpx.Builder, headers map[string][]string, retrieve func(hb httpx.Builder) (int, error)) (int, error) {
+	statusCode, err := retrieve(hb.WithHeaders(headers))
 	if err != nil {
 		if statusCode == 401 {
 			token, tokenErr := ac.getToken(true)
 			if tokenErr != nil {
-				return err
+				return statusCode, err
 			}
 			headers["Authorization"] = []string{fmt.Sprintf("Bearer %s", token)}
-			_, err = hb.WithHeaders(headers).
-				RetrieveWithContext(ctx, &out)
+			statusCode, err = retrieve(hb.WithHeaders(headers))
 		}
 	}
-	return err
+	return statusCode, err
+}
+
+// retryable is a 5xx or a request that failed in the transport, so it may not
+// have reached the server or its response may not have made it back.
+func retryable(ctx context.Context, statusCode int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if statusCode >= 500 {
+		return true
+	}
+	var urlErr *url.Error
+	return statusCode == 0 && errors.As(err, &urlErr)
 }
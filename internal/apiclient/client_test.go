@@ -7,11 +7,20 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/internal/idempotency"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/timer"
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Payload struct {
@@ -42,6 +51,7 @@ func TestGet(t *testing.T) {
 	mockResp := `{"foo":"bar"}`
 	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "", r.Header.Get("idempotency-key"))
 		b, err := io.ReadAll(r.Body)
 		assert.Nil(t, err)
 		defer r.Body.Close()
@@ -75,6 +85,7 @@ func TestPost(t *testing.T) {
 	mockResp := `{"foo":"bar"}`
 	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "POST", r.Method)
+		assert.NotEqual(t, "", r.Header.Get("idempotency-key"))
 		b, err := io.ReadAll(r.Body)
 		assert.Nil(t, err)
 		defer r.Body.Close()
@@ -109,6 +120,7 @@ func TestPut(t *testing.T) {
 	mockResp := `{"foo":"bar"}`
 	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "PUT", r.Method)
+		assert.NotEqual(t, "", r.Header.Get("idempotency-key"))
 		b, err := io.ReadAll(r.Body)
 		assert.Nil(t, err)
 		defer r.Body.Close()
@@ -126,6 +138,41 @@ func TestPut(t *testing.T) {
 	assert.Equal(t, "bar", out.Foo)
 }
 
+func TestPatch(t *testing.T) {
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	path := "/api/foo"
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	in := Payload{
+		Foo: "foobar",
+	}
+	var out Payload
+	mockResp := `{"foo":"bar"}`
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		assert.NotEqual(t, "", r.Header.Get("idempotency-key"))
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"foo":"foobar"}`), b)
+		assert.Equal(t, path, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, "application/merge-patch+json", r.Header.Get("content-type"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(mockResp))
+	})
+	err := client.Patch(ctx, server.URL+path, pathParams, queryParams, "application/merge-patch+json", in, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", out.Foo)
+}
+
 func TestDelete(t *testing.T) {
 	token := "test-token"
 	getToken := func(isRetry bool) (string, error) {
@@ -160,6 +207,65 @@ func TestDelete(t *testing.T) {
 	assert.Equal(t, "bar", out.Foo)
 }
 
+func TestGetStr(t *testing.T) {
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	path := "/api/foo/export"
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	var out string
+	mockResp := "foo\nbar\n"
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, path, r.URL.Path)
+		assert.Equal(t, "text/csv", r.Header.Get("accept"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "text/csv")
+		w.Write([]byte(mockResp))
+	})
+	err := client.GetStr(ctx, server.URL+path, pathParams, queryParams, "text/csv", &out)
+	assert.Nil(t, err)
+	assert.Equal(t, mockResp, out)
+}
+
+func TestPostStr(t *testing.T) {
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	path := "/api/foo/import"
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	in := "foo\nfoobar\n"
+	var out Payload
+	mockResp := `{"foo":"bar"}`
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(in), b)
+		assert.Equal(t, path, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, "text/csv", r.Header.Get("content-type"))
+		assert.Equal(t, "", r.Header.Get("idempotency-key"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(mockResp))
+	})
+	err := client.PostStr(ctx, server.URL+path, pathParams, queryParams, "text/csv", in, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", out.Foo)
+}
+
 func TestTokenErr(t *testing.T) {
 	mockTokenErr := errors.New("unit-test token error")
 	getToken := func(isRetry bool) (string, error) {
@@ -295,9 +401,327 @@ func Test401Then400(t *testing.T) {
 	assert.Equal(t, "", out.Foo)
 }
 
+func TestPost_WithIdempotencyKey(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	ctx := WithIdempotencyKey(context.Background(), "test-key")
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	var out Payload
+	var keys []string
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		keys = append(keys, r.Header.Get("idempotency-key"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"foo":"bar"}`))
+	})
+	err := client.Post(ctx, server.URL, pathParams, queryParams, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	err = client.Post(ctx, server.URL, pathParams, queryParams, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"test-key", "test-key"}, keys)
+}
+
+func TestPost_NewIdempotencyKeyPerCall(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	ctx := context.Background()
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	var out Payload
+	var keys []string
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		keys = append(keys, r.Header.Get("idempotency-key"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"foo":"bar"}`))
+	})
+	err := client.Post(ctx, server.URL, pathParams, queryParams, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	err = client.Post(ctx, server.URL, pathParams, queryParams, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	assert.NotEqual(t, keys[0], keys[1])
+}
+
+func Test401Recover_SameIdempotencyKey(t *testing.T) {
+	expiredToken := "test-expired-token"
+	getToken := func(isRetry bool) (string, error) {
+		if isRetry {
+			return "test-token", nil
+		}
+		return expiredToken, nil
+	}
+	ctx := context.Background()
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	var out Payload
+	var keys []string
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		keys = append(keys, r.Header.Get("idempotency-key"))
+		if r.Header.Get("authorization") == bearer(expiredToken) {
+			w.WriteHeader(401)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"foo":"bar"}`))
+	})
+	err := client.Put(ctx, server.URL, pathParams, queryParams, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	assert.NotEqual(t, "", keys[0])
+	assert.Equal(t, keys[0], keys[1])
+}
+
+// TestPost_RetriesLostResponse runs the request through the idempotency
+// middleware and drops the connection before the first response is sent, the
+// retry has to get the stored response instead of creating another row.
+func TestPost_RetriesLostResponse(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	gin.SetMode(gin.TestMode)
+	rows := 0
+	engine := gin.New()
+	engine.Use(idempotency.Middleware(testutil.GetLogger(), newMemDAO(), timer.New(), time.Hour, 1<<20))
+	engine.POST("/api/foo", func(c *gin.Context) {
+		rows++
+		c.JSON(http.StatusCreated, Payload{Foo: fmt.Sprintf("row-%d", rows)})
+	})
+	var keys []string
+	lost := false
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("idempotency-key"))
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, r)
+		if !lost {
+			lost = true
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.Nil(t, err)
+			conn.Close()
+			return
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.Post(context.Background(), server.URL+"/api/foo", map[string]string{}, map[string][]string{}, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rows)
+	assert.Equal(t, "row-1", out.Foo)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, keys[0], keys[1])
+}
+
+func TestPost_Retries5xx(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	var keys []string
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		keys = append(keys, r.Header.Get("idempotency-key"))
+		if len(keys) == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"foo":"bar"}`))
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.Post(context.Background(), server.URL, map[string]string{}, map[string][]string{}, Payload{Foo: "foobar"}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", out.Foo)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, keys[0], keys[1])
+}
+
+func TestPostStr_NoRetry(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	calls := 0
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		calls++
+		w.WriteHeader(500)
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.PostStr(context.Background(), server.URL, map[string]string{}, map[string][]string{}, "text/csv", "foo\n", &out)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestPost_RetriesExhausted(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	calls := 0
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		calls++
+		w.WriteHeader(500)
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.Post(context.Background(), server.URL, map[string]string{}, map[string][]string{}, Payload{Foo: "foobar"}, &out)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "500")
+	assert.Equal(t, 3, calls)
+}
+
+func TestPost_NoRetryOn4xx(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	calls := 0
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		calls++
+		w.WriteHeader(409)
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.Post(context.Background(), server.URL, map[string]string{}, map[string][]string{}, Payload{Foo: "foobar"}, &out)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestPost_RetriesOff(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	calls := 0
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		calls++
+		w.WriteHeader(500)
+	})
+	client = client.WithRetries(0, 0)
+	var out Payload
+	err := client.Post(context.Background(), server.URL, map[string]string{}, map[string][]string{}, Payload{Foo: "foobar"}, &out)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGet_NoRetry(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	calls := 0
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		calls++
+		w.WriteHeader(500)
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.Get(context.Background(), server.URL, map[string]string{}, map[string][]string{}, &out)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDelete_NoRetry(t *testing.T) {
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	calls := 0
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		calls++
+		w.WriteHeader(500)
+	})
+	client = client.WithRetries(2, time.Millisecond)
+	var out Payload
+	err := client.Delete(context.Background(), server.URL, map[string]string{}, map[string][]string{}, Payload{Foo: "foobar"}, &out)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
 // token error
 // no reqID
 // 401 error - token success - 200 success
 // 401 error - token error
 // 401 error - token success - 401 error (not infinite loop)
 // 401 error - token success - 400 error
+
+func TestGet_PropagatesTraceContext(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	traceID, _ := trace.TraceIDFromHex("0af7651916cd43dd8448eb211c80319c")
+	spanID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	var out Payload
+	client, server := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", r.Header.Get("traceparent"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"foo":"bar"}`))
+	})
+	err := client.Get(ctx, server.URL+"/api/foo", map[string]string{}, map[string][]string{}, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", out.Foo)
+}
+
+// memDAO keeps the idempotency keys in memory.
+type memDAO struct {
+	mu   sync.Mutex
+	keys map[string]idempotency.StoredResponse
+}
+
+func newMemDAO() *memDAO {
+	return &memDAO{keys: map[string]idempotency.StoredResponse{}}
+}
+
+func (d *memDAO) Reserve(ctx context.Context, sr idempotency.StoredResponse) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.keys[sr.UserID+"/"+sr.Key]; ok {
+		return false, nil
+	}
+	d.keys[sr.UserID+"/"+sr.Key] = sr
+	return true, nil
+}
+
+func (d *memDAO) Get(ctx context.Context, userID string, key string) (idempotency.StoredResponse, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sr, ok := d.keys[userID+"/"+key]
+	if !ok {
+		return sr, idempotency.ErrNotFound{Key: key}
+	}
+	return sr, nil
+}
+
+func (d *memDAO) Complete(ctx context.Context, sr idempotency.StoredResponse) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.keys[sr.UserID+"/"+sr.Key] = sr
+	return nil
+}
+
+func (d *memDAO) Delete(ctx context.Context, userID string, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.keys, userID+"/"+key)
+	return nil
+}
+
+func (d *memDAO) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,188 @@
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/gin-gonic/gin"
+)
+
+// coder is implemented by the typed errors that have a stable code.
+type coder interface {
+	Code() string
+}
+
+// extender is implemented by the typed errors that add members to the
+// problem (ex. num_users).
+type extender interface {
+	Extensions() map[string]any
+}
+
+// statuses is the status each code is responded with.
+var statuses = map[string]int{
+	problem.CodeInvalidRequest:             http.StatusBadRequest,
+	problem.CodeValidationFailed:           http.StatusBadRequest,
+	problem.CodeUnauthenticated:            http.StatusUnauthorized,
+	problem.CodeForbidden:                  http.StatusForbidden,
+	problem.CodePreconditionFailed:         http.StatusPreconditionFailed,
+	problem.CodeInternal:                   http.StatusInternalServerError,
+	problem.CodeUnavailable:                http.StatusServiceUnavailable,
+	problem.CodeInvalidLimit:               http.StatusBadRequest,
+	problem.CodeInvalidCursor:              http.StatusBadRequest,
+	problem.CodeInvalidIncludeDeleted:      http.StatusBadRequest,
+	problem.CodeInvalidDryRun:              http.StatusBadRequest,
+	problem.CodeInvalidFormat:              http.StatusBadRequest,
+	problem.CodeMissingColumn:              http.StatusBadRequest,
+	problem.CodeInvalidRow:                 http.StatusBadRequest,
+	problem.CodeMalformedBody:              http.StatusBadRequest,
+	problem.CodeInvalidTime:                http.StatusBadRequest,
+	problem.CodeUnsupportedPatch:           http.StatusUnsupportedMediaType,
+	problem.CodeMalformedPatch:             http.StatusBadRequest,
+	problem.CodePatchTestFailed:            http.StatusConflict,
+	problem.CodeOrgNotFound:                http.StatusNotFound,
+	problem.CodeOrgNameInUse:               http.StatusConflict,
+	problem.CodeOrgVersionConflict:         http.StatusConflict,
+	problem.CodeSysOrgReadOnly:             http.StatusForbidden,
+	problem.CodeOrgHasUsers:                http.StatusConflict,
+	problem.CodeInvalidDeleteMode:          http.StatusBadRequest,
+	problem.CodeInvalidReassignTarget:      http.StatusBadRequest,
+	problem.CodeUserNotFound:               http.StatusNotFound,
+	problem.CodeEmailInUse:                 http.StatusConflict,
+	problem.CodeUserVersionConflict:        http.StatusConflict,
+	problem.CodeSysUserReadOnly:            http.StatusForbidden,
+	problem.CodeCannotAssociateSysOrg:      http.StatusForbidden,
+	problem.CodeInvalidFilter:              http.StatusBadRequest,
+	problem.CodeInvalidSort:                http.StatusBadRequest,
+	problem.CodeUnknownMethod:              http.StatusNotFound,
+	problem.CodeInvalidBatchOp:             http.StatusBadRequest,
+	problem.CodeBatchAborted:               http.StatusFailedDependency,
+	problem.CodeNoPendingEmailChange:       http.StatusNotFound,
+	problem.CodeInvalidEmailChangeToken:    http.StatusBadRequest,
+	problem.CodeEmailChangeExpired:         http.StatusGone,
+	problem.CodeEmailUnchanged:             http.StatusBadRequest,
+	problem.CodeNameRequired:               http.StatusBadRequest,
+	problem.CodeMembershipNotFound:         http.StatusNotFound,
+	problem.CodeAlreadyMember:              http.StatusConflict,
+	problem.CodeCannotRemoveHomeMembership: http.StatusConflict,
+	problem.CodeNoMemberships:              http.StatusNotFound,
+	problem.CodeCannotAddSysUser:           http.StatusForbidden,
+	problem.CodeInvitationNotFound:         http.StatusNotFound,
+	// the token is the invitation's id as far as the caller knows
+	problem.CodeInvalidInvitationToken:   http.StatusNotFound,
+	problem.CodeAlreadyInvited:           http.StatusConflict,
+	problem.CodeInvitationNotPending:     http.StatusConflict,
+	problem.CodeCannotAcceptInvitation:   http.StatusGone,
+	problem.CodeCannotInviteToSysOrg:     http.StatusForbidden,
+	problem.CodeInvalidInvitationState:   http.StatusBadRequest,
+	problem.CodeIdempotencyKeyTooLong:    http.StatusBadRequest,
+	problem.CodeIdempotencyKeyReused:     http.StatusUnprocessableEntity,
+	problem.CodeIdempotencyKeyInProgress: http.StatusConflict,
+	problem.CodeIdempotencyBodyTooLarge:  http.StatusRequestEntityTooLarge,
+}
+
+// Status is the status err is responded with, picked by its code. A binding
+// error is a 400 and anything without a code a 500.
+func Status(err error) int {
+	err = validate.FromErr(err, "")
+	var c coder
+	if errors.As(err, &c) {
+		if status, ok := statuses[c.Code()]; ok {
+			return status
+		}
+	}
+	if isBindingErr(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// FromError responds with err at its Status and logs it.
+func FromError(c *gin.Context, log *slog.Logger, err error) {
+	FromErrorWithStatus(c, log, Status(err), err)
+}
+
+// FromErrorWithStatus is FromError for the few places the same error means
+// something else (ex. a stale version is a 412 when it came from If-Match, or
+// an org that isn't found is a 400 when it's in the body).
+func FromErrorWithStatus(c *gin.Context, log *slog.Logger, statusCode int, err error) {
+	Log(log, statusCode, err)
+	Respond(c, statusCode, err)
+}
+
+// Log logs err as a warning, or as an error when it's a 5xx since those are
+// ours to fix.
+func Log(log *slog.Logger, statusCode int, err error) {
+	log = log.With(logutil.LogAttrError(err), logAttrStatusCode(statusCode))
+	if statusCode >= http.StatusInternalServerError {
+		log.Error("service call failed")
+		return
+	}
+	log.Warn("request failed")
+}
+
+// Respond writes err as a problem with the status, see FromError for the
+// status err is usually responded with.
+func Respond(c *gin.Context, statusCode int, err error) {
+	p := New(statusCode, err)
+	p.Instance = c.Request.URL.Path
+	b, mErr := json.Marshal(p)
+	if mErr != nil {
+		c.Status(statusCode)
+		return
+	}
+	c.Data(statusCode, problem.ContentType, b)
+}
+
+// Abort is Respond for middleware, the rest of the chain is skipped.
+func Abort(c *gin.Context, statusCode int, err error) {
+	c.Abort()
+	Respond(c, statusCode, err)
+}
+
+// New is the problem err is responded with, the validator's errors are
+// turned into violations.
+func New(statusCode int, err error) problem.Problem {
+	err = validate.FromErr(err, "")
+	p := problem.New(statusCode, Code(statusCode, err), err.Error())
+	var invalid validate.ErrInvalid
+	if errors.As(err, &invalid) {
+		p.Violations = invalid.Violations
+	}
+	var ext extender
+	if errors.As(err, &ext) {
+		p.Extensions = ext.Extensions()
+	}
+	return p
+}
+
+// Code is err's own code, binding errors are validation_failed when the body
+// broke its rules, invalid_request when it couldn't be read and anything else
+// falls back to the status' code.
+func Code(statusCode int, err error) string {
+	err = validate.FromErr(err, "")
+	var c coder
+	if errors.As(err, &c) {
+		return c.Code()
+	}
+	if isBindingErr(err) {
+		return problem.CodeInvalidRequest
+	}
+	return problem.CodeForStatus(statusCode)
+}
+
+// isBindingErr is true for the errors gin's ShouldBindJSON fails with when
+// the body isn't json for the model.
+func isBindingErr(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,156 @@
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type errCoded struct{}
+
+func (err errCoded) Error() string {
+	return "unit-test coded error"
+}
+
+func (err errCoded) Code() string {
+	return problem.CodeOrgHasUsers
+}
+
+func (err errCoded) Extensions() map[string]any {
+	return map[string]any{"num_users": 3}
+}
+
+func ginCtx(body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/foo", strings.NewReader(body))
+	return c, w
+}
+
+func parseProblem(t *testing.T, w *httptest.ResponseRecorder) problem.Problem {
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	var p problem.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &p)
+	assert.Nil(t, err)
+	return p
+}
+
+func TestRespond_Coded(t *testing.T) {
+	c, w := ginCtx("")
+
+	Respond(c, http.StatusConflict, fmt.Errorf("wrapped: %w", errCoded{}))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, problem.Problem{
+		Title:      "Conflict",
+		Status:     http.StatusConflict,
+		Detail:     "wrapped: unit-test coded error",
+		Instance:   "/api/foo",
+		Code:       problem.CodeOrgHasUsers,
+		Extensions: map[string]any{"num_users": float64(3)},
+	}, parseProblem(t, w))
+	assert.False(t, c.IsAborted())
+}
+
+func TestRespond_ValidationErr(t *testing.T) {
+	c, w := ginCtx(`{}`)
+	var body struct {
+		Name string `json:"name" binding:"required"`
+	}
+	err := c.ShouldBindJSON(&body)
+
+	Respond(c, http.StatusBadRequest, err)
+
+	p := parseProblem(t, w)
+	assert.Equal(t, problem.CodeValidationFailed, p.Code)
+	assert.Equal(t, "name is required", p.Detail)
+	assert.Equal(t, []problem.Violation{{Field: "name", Rule: "required", Message: "name is required"}}, p.Violations)
+}
+
+func TestRespond_MalformedBody(t *testing.T) {
+	c, w := ginCtx(`{"name":`)
+	var body struct {
+		Name string `json:"name" binding:"required"`
+	}
+	err := c.ShouldBindJSON(&body)
+
+	Respond(c, http.StatusBadRequest, err)
+
+	p := parseProblem(t, w)
+	assert.Equal(t, problem.CodeInvalidRequest, p.Code)
+	assert.Nil(t, p.Violations)
+}
+
+func TestRespond_Fallback(t *testing.T) {
+	c, w := ginCtx("")
+
+	Respond(c, http.StatusInternalServerError, errors.New("unit-test error"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	p := parseProblem(t, w)
+	assert.Equal(t, problem.CodeInternal, p.Code)
+	assert.Equal(t, "unit-test error", p.Detail)
+	assert.Nil(t, p.Extensions)
+}
+
+func TestAbort(t *testing.T) {
+	c, w := ginCtx("")
+
+	Abort(c, http.StatusNotFound, errors.New("unit-test error"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", parseProblem(t, w).Code)
+	assert.True(t, c.IsAborted())
+}
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, problem.CodeOrgHasUsers, Code(http.StatusConflict, errCoded{}))
+	assert.Equal(t, problem.CodeInvalidRequest, Code(http.StatusUnprocessableEntity, &json.SyntaxError{}))
+	assert.Equal(t, "conflict", Code(http.StatusConflict, errors.New("foo")))
+}
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, http.StatusConflict, Status(fmt.Errorf("wrapped: %w", errCoded{})))
+	assert.Equal(t, http.StatusBadRequest, Status(&json.SyntaxError{}))
+	assert.Equal(t, http.StatusInternalServerError, Status(errors.New("foo")))
+}
+
+func TestStatus_ValidationErr(t *testing.T) {
+	c, _ := ginCtx(`{}`)
+	var body struct {
+		Name string `json:"name" binding:"required"`
+	}
+	err := c.ShouldBindJSON(&body)
+
+	assert.Equal(t, http.StatusBadRequest, Status(err))
+}
+
+func TestFromError(t *testing.T) {
+	c, w := ginCtx("")
+
+	FromError(c, testutil.GetLogger(), errCoded{})
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, problem.CodeOrgHasUsers, parseProblem(t, w).Code)
+	assert.False(t, c.IsAborted())
+}
+
+func TestFromErrorWithStatus(t *testing.T) {
+	c, w := ginCtx("")
+
+	FromErrorWithStatus(c, testutil.GetLogger(), http.StatusPreconditionFailed, errCoded{})
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	// the code is still the error's own
+	assert.Equal(t, problem.CodeOrgHasUsers, parseProblem(t, w).Code)
+}
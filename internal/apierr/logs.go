@@ -0,0 +1,7 @@
+package apierr
+
+import "log/slog"
+
+func logAttrStatusCode(statusCode int) slog.Attr {
+	return slog.Int("statusCode", statusCode)
+}
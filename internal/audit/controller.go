@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error
+	Search(ctx context.Context, q audit.Query, pr page.Request) (audit.EventPage, error)
+}
+
+type ctrl struct {
+	log     *slog.Logger
+	service AuditService
+}
+
+func NewController(log *slog.Logger, service AuditService) *ctrl {
+	return &ctrl{
+		log:     log.With(logutil.LogAttrSVC("AuditCTL")),
+		service: service,
+	}
+}
+
+func (ctr ctrl) Search(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Search"),
+	)
+	log.Debug("called")
+	pr, err := page.ParseRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	q, err := parseQuery(c)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrQuery(q))
+	ep, err := ctr.service.Search(ctx, q, pr)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrEventsLen(len(ep.Events))).Debug("success")
+	c.JSON(http.StatusOK, ep)
+}
+
+func parseQuery(c *gin.Context) (q audit.Query, err error) {
+	q.EntityType = c.Query("entity_type")
+	q.EntityID = c.Query("entity_id")
+	q.ActorID = c.Query("actor_id")
+	if q.From, err = parseTime("from", c.Query("from")); err != nil {
+		return q, err
+	}
+	if q.To, err = parseTime("to", c.Query("to")); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+func parseTime(param string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, ErrInvalidTime{Param: param, Value: value}
+	}
+	return &t, nil
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSVC struct {
+	mock.Mock
+}
+
+func initCTRL() (c *ctrl, ms *mockSVC) {
+	log := testutil.GetLogger()
+	ms = new(mockSVC)
+	c = NewController(log, ms)
+	return c, ms
+}
+
+func ginCtx(url string) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	gc, _ := gin.CreateTestContext(w)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, w, err
+	}
+	gc.Request = req
+	return gc, w, nil
+}
+
+func TestCTRLSearch(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	mockRes := audit.EventPage{
+		Events: []audit.Event{
+			{
+				ID:         "foo-id",
+				OccurredAt: time.UnixMilli(100).UTC(),
+				ActorID:    actorID,
+				Action:     audit.ActionCreate,
+				EntityType: entityType,
+				EntityID:   entityID,
+				Diff:       audit.Diff{"name": {After: "foo"}},
+			},
+		},
+		NextCursor: "foo-cursor",
+	}
+	ms.On("Search", mock.Anything, audit.Query{}, page.Request{Limit: page.DefaultLimit}).Return(mockRes, nil)
+
+	c.Search(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual audit.EventPage
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLSearch_Filters(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?entity_type=user&entity_id=foo-entity-id&actor_id=logged-in-user-id&from=2024-01-02T03:04:05Z&to=2024-01-03T03:04:05.5Z")
+	assert.Nil(t, err)
+
+	from := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	to := time.Date(2024, 1, 3, 3, 4, 5, 500000000, time.UTC)
+	expected := audit.Query{
+		EntityType: entityType,
+		EntityID:   entityID,
+		ActorID:    actorID,
+		From:       &from,
+		To:         &to,
+	}
+	ms.On("Search", mock.Anything, expected, page.Request{Limit: page.DefaultLimit}).Return(audit.EventPage{}, nil)
+
+	c.Search(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLSearch_InvalidTime(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtx("/?from=yesterday")
+	assert.Nil(t, err)
+
+	c.Search(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, actual.Detail, "from=yesterday")
+}
+
+func TestCTRLSearch_InvalidLimit(t *testing.T) {
+	c, _ := initCTRL()
+	gc, _, err := ginCtx("/?limit=0")
+	assert.Nil(t, err)
+
+	c.Search(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLSearch_ForbiddenError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: actorID, Action: "audit:read"}
+	ms.On("Search", mock.Anything, audit.Query{}, page.Request{Limit: page.DefaultLimit}).Return(audit.EventPage{}, mockErr)
+
+	c.Search(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLSearch_ServiceError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	mockErr := errors.New("unit-test mock service error")
+	ms.On("Search", mock.Anything, audit.Query{}, page.Request{Limit: page.DefaultLimit}).Return(audit.EventPage{}, mockErr)
+
+	c.Search(gc)
+	assert.Equal(t, 500, gc.Writer.Status())
+}
+
+func (m *mockSVC) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error {
+	args := m.Called(ctx, tx, action, entityType, entityID, before, after)
+	return args.Error(0)
+}
+
+func (m *mockSVC) Search(ctx context.Context, q audit.Query, pr page.Request) (audit.EventPage, error) {
+	args := m.Called(ctx, q, pr)
+	return args.Get(0).(audit.EventPage), args.Error(1)
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+type dao struct {
+	log     *slog.Logger
+	timeout time.Duration
+	db      *sqlx.DB
+}
+
+func NewDAO(log *slog.Logger, timeout time.Duration, db *sqlx.DB) *dao {
+	return &dao{
+		log:     log.With(logutil.LogAttrSVC("AuditDAO")),
+		timeout: timeout,
+		db:      db,
+	}
+}
+
+func (d dao) Create(ctx context.Context, tx *sqlx.Tx, e audit.Event) (err error) {
+	ctx, span := tracing.StartDB(ctx, "AuditDAO.Create")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrEvent(e),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("createQuery"))
+	r, err := tx.NamedExecContext(ctx, createQuery, &e)
+	if err != nil {
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows != 1 {
+		return fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	return err
+}
+
+func (d dao) Search(ctx context.Context, q audit.Query, after *page.Cursor, limit int) (events []audit.Event, err error) {
+	ctx, span := tracing.StartDB(ctx, "AuditDAO.Search")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Search"),
+		logAttrQuery(q),
+		logAttrAfter(after),
+		logAttrLimit(limit),
+	)
+	log.Debug("called")
+	events = []audit.Event{}
+	if after == nil {
+		span.SetAttributes(tracing.AttrStatement("searchQuery"))
+		err = d.db.SelectContext(ctx, &events, searchQuery, q.EntityType, q.EntityID, q.ActorID, q.From, q.To, limit)
+	} else {
+		span.SetAttributes(tracing.AttrStatement("searchAfterQuery"))
+		err = d.db.SelectContext(ctx, &events, searchAfterQuery, q.EntityType, q.EntityID, q.ActorID, q.From, q.To, after.CreatedAt, after.ID, limit)
+	}
+	if err != nil {
+		return events, err
+	}
+	log.Debug("success")
+	return events, err
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+const daoName = "AuditDAO"
+
+type DAOMetrics interface {
+	ObserveQuery(dao string, method string, d time.Duration, errClass string)
+}
+
+// instrumentedDAO records the duration and error class of every AuditDAO call.
+type instrumentedDAO struct {
+	dao     AuditDAO
+	metrics DAOMetrics
+}
+
+func NewInstrumentedDAO(dao AuditDAO, metrics DAOMetrics) *instrumentedDAO {
+	return &instrumentedDAO{
+		dao:     dao,
+		metrics: metrics,
+	}
+}
+
+func (d instrumentedDAO) observe(method string, start time.Time, err error) {
+	d.metrics.ObserveQuery(daoName, method, time.Since(start), metrics.ErrClass(err))
+}
+
+func (d instrumentedDAO) Create(ctx context.Context, tx *sqlx.Tx, e audit.Event) (err error) {
+	start := time.Now()
+	err = d.dao.Create(ctx, tx, e)
+	d.observe("Create", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Search(ctx context.Context, q audit.Query, after *page.Cursor, limit int) (events []audit.Event, err error) {
+	start := time.Now()
+	events, err = d.dao.Search(ctx, q, after, limit)
+	d.observe("Search", start, err)
+	return events, err
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAOMetrics struct {
+	mock.Mock
+}
+
+func (m *mockDAOMetrics) ObserveQuery(dao string, method string, d time.Duration, errClass string) {
+	m.Called(dao, method, d, errClass)
+}
+
+func initInstrumentedDAO() (d *instrumentedDAO, md *mockDAO, mm *mockDAOMetrics) {
+	md = new(mockDAO)
+	mm = new(mockDAOMetrics)
+	d = NewInstrumentedDAO(md, mm)
+	return d, md, mm
+}
+
+func TestInstrumentedDAO_Create(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	var tx *sqlx.Tx
+	e := audit.Event{ID: "foo-id"}
+	md.On("Create", ctx, tx, e).Return(nil)
+	mm.On("ObserveQuery", daoName, "Create", mock.AnythingOfType("time.Duration"), "none")
+
+	err := d.Create(ctx, tx, e)
+
+	assert.Nil(t, err)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_Search(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	var after *page.Cursor
+	mockErr := errors.New("unit-test mock error")
+	md.On("Search", ctx, audit.Query{}, after, 2).Return([]audit.Event{}, mockErr)
+	mm.On("ObserveQuery", daoName, "Search", mock.AnythingOfType("time.Duration"), "other")
+
+	_, err := d.Search(ctx, audit.Query{}, after, 2)
+
+	assert.Equal(t, mockErr, err)
+	mm.AssertExpectations(t)
+}
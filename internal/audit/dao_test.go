@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx        = context.Background()
+	occurredAt = time.UnixMilli(100)
+	from       = time.UnixMilli(50)
+	after      = page.Cursor{
+		CreatedAt: time.UnixMilli(200),
+		ID:        "after-id",
+	}
+)
+
+const (
+	id         = "foo-id"
+	actorID    = "logged-in-user-id"
+	reqID      = "foo-req-id"
+	entityID   = "foo-entity-id"
+	limit      = 11
+	diffJSON   = `{"name":{"before":"foo","after":"bar"}}`
+	entityType = audit.EntityUser
+)
+
+func getRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id",
+		"occurred_at",
+		"actor_id",
+		"req_id",
+		"action",
+		"entity_type",
+		"entity_id",
+		"diff",
+	}).AddRow(
+		id,
+		occurredAt,
+		actorID,
+		reqID,
+		audit.ActionUpdate,
+		entityType,
+		entityID,
+		[]byte(diffJSON),
+	)
+}
+
+func initDAO() (d *dao, dbx *sqlx.DB, md sqlmock.Sqlmock) {
+	log := testutil.GetLogger()
+	db, md, err := sqlmock.New()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to mock db")
+		panic(err)
+	}
+	dbx = sqlx.NewDb(db, "sqlmock")
+	queryTimeout := 30 * time.Second
+	d = NewDAO(log, queryTimeout, dbx)
+	return d, dbx, md
+}
+
+func TestDAOCreate(t *testing.T) {
+	d, db, md := initDAO()
+
+	e := audit.Event{
+		ID:         id,
+		OccurredAt: occurredAt,
+		ActorID:    actorID,
+		ReqID:      reqID,
+		Action:     audit.ActionUpdate,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       audit.Diff{"name": {Before: "foo", After: "bar"}},
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO audit_events").
+		WithArgs(id, occurredAt, actorID, reqID, audit.ActionUpdate, entityType, entityID, diffJSON).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, e)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOCreate_Err(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO audit_events").
+		WillReturnError(mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, audit.Event{ID: id})
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
+
+func TestDAOCreate_TooManyRowsAffected(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO audit_events").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, audit.Event{ID: id})
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unexpected number of rows")
+}
+
+func TestDAOSearch(t *testing.T) {
+	d, _, md := initDAO()
+
+	q := audit.Query{EntityType: entityType, EntityID: entityID, From: &from}
+	md.ExpectQuery(regexp.QuoteMeta(searchQuery)).
+		WithArgs(entityType, entityID, "", &from, nil, limit).
+		WillReturnRows(getRows())
+
+	actual, err := d.Search(ctx, q, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Len(t, actual, 1)
+	assert.Equal(t, id, actual[0].ID)
+	assert.Equal(t, occurredAt, actual[0].OccurredAt)
+	assert.Equal(t, actorID, actual[0].ActorID)
+	assert.Equal(t, reqID, actual[0].ReqID)
+	assert.Equal(t, audit.ActionUpdate, actual[0].Action)
+	assert.Equal(t, entityType, actual[0].EntityType)
+	assert.Equal(t, entityID, actual[0].EntityID)
+	assert.Equal(t, audit.Diff{"name": {Before: "foo", After: "bar"}}, actual[0].Diff)
+}
+
+func TestDAOSearch_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	q := audit.Query{ActorID: actorID}
+	md.ExpectQuery(regexp.QuoteMeta(searchAfterQuery)).
+		WithArgs("", "", actorID, nil, nil, after.CreatedAt, after.ID, limit).
+		WillReturnRows(getRows())
+
+	actual, err := d.Search(ctx, q, &after, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Len(t, actual, 1)
+}
+
+func TestDAOSearch_Err(t *testing.T) {
+	d, _, md := initDAO()
+
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectQuery(regexp.QuoteMeta(searchQuery)).
+		WillReturnError(mockErr)
+
+	_, err := d.Search(ctx, audit.Query{}, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+)
+
+// diff compares the json forms of before and after so the field names match
+// what API callers see, a nil before (create) or after (delete) shows every
+// field as added or removed.
+func diff(before any, after any) (audit.Diff, error) {
+	b, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+	d := audit.Diff{}
+	for k, v := range b {
+		if !reflect.DeepEqual(v, a[k]) {
+			d[k] = audit.Change{Before: v, After: a[k]}
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			d[k] = audit.Change{After: v}
+		}
+	}
+	return d, nil
+}
+
+func toMap(v any) (m map[string]any, err error) {
+	m = map[string]any{}
+	if v == nil {
+		return m, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
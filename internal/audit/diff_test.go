@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+type entity struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	IsAdmin bool   `json:"is_admin"`
+	Version int64  `json:"version"`
+}
+
+func TestDiff_Create(t *testing.T) {
+	actual, err := diff(nil, entity{ID: "foo-id", Name: "foo", Version: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Diff{
+		"id":       {After: "foo-id"},
+		"name":     {After: "foo"},
+		"is_admin": {After: false},
+		"version":  {After: float64(1)},
+	}, actual)
+}
+
+func TestDiff_Update(t *testing.T) {
+	before := entity{ID: "foo-id", Name: "foo", IsAdmin: false, Version: 1}
+	after := entity{ID: "foo-id", Name: "foo", IsAdmin: true, Version: 2}
+
+	actual, err := diff(before, after)
+
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Diff{
+		"is_admin": {Before: false, After: true},
+		"version":  {Before: float64(1), After: float64(2)},
+	}, actual)
+}
+
+func TestDiff_UpdateOmittedField(t *testing.T) {
+	actual, err := diff(entity{ID: "foo-id", Name: "foo"}, entity{ID: "foo-id"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Diff{
+		"name": {Before: "foo"},
+	}, actual)
+}
+
+func TestDiff_Delete(t *testing.T) {
+	actual, err := diff(entity{ID: "foo-id", Version: 3}, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Diff{
+		"id":       {Before: "foo-id"},
+		"is_admin": {Before: false},
+		"version":  {Before: float64(3)},
+	}, actual)
+}
+
+func TestDiff_Unmarshalable(t *testing.T) {
+	_, err := diff(nil, map[string]any{"foo": make(chan int)})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,20 @@
+package audit
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrInvalidTime struct {
+	Param string
+	Value string
+}
+
+func (err ErrInvalidTime) Error() string {
+	return fmt.Sprintf("Invalid time, expected RFC 3339: %s=%s", err.Param, err.Value)
+}
+
+func (err ErrInvalidTime) Code() string {
+	return problem.CodeInvalidTime
+}
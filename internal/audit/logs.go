@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"log/slog"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+)
+
+func logAttrEvent(event any) slog.Attr {
+	return slog.Any("event", event)
+}
+
+func logAttrQuery(q any) slog.Attr {
+	return slog.Any("query", q)
+}
+
+func logAttrAction(action string) slog.Attr {
+	return slog.String("action", action)
+}
+
+func logAttrEntity(entityType string, entityID string) slog.Attr {
+	return slog.Group("entity", slog.String("type", entityType), slog.String("id", entityID))
+}
+
+func logAttrEventsLen(len int) slog.Attr {
+	return slog.Int("eventsLen", len)
+}
+
+func logAttrAfter(after *page.Cursor) slog.Attr {
+	return slog.Any("after", after)
+}
+
+func logAttrLimit(limit int) slog.Attr {
+	return slog.Int("limit", limit)
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditDAO interface {
+	Create(ctx context.Context, tx *sqlx.Tx, e audit.Event) error
+	Search(ctx context.Context, q audit.Query, after *page.Cursor, limit int) ([]audit.Event, error)
+}
+
+type Timer interface {
+	Now() time.Time
+}
+
+type IDGenerator interface {
+	GenID() string
+}
+
+type service struct {
+	log   *slog.Logger
+	dao   AuditDAO
+	timer Timer
+	idGen IDGenerator
+}
+
+func NewService(log *slog.Logger, dao AuditDAO, timer Timer, idGen IDGenerator) *service {
+	return &service{
+		log:   log.With(logutil.LogAttrSVC("AuditSVC")),
+		dao:   dao,
+		timer: timer,
+		idGen: idGen,
+	}
+}
+
+// Record has to be called with the tx the change was made in so the event is
+// only kept if the change is.
+func (s service) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Record"),
+		logAttrAction(action),
+		logAttrEntity(entityType, entityID),
+	)
+	log.Debug("called")
+	d, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	actorID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	reqID, _ := ctx.Value(ctxutil.ContextKeyReqID{}).(string)
+	return s.dao.Create(ctx, tx, audit.Event{
+		ID:         s.idGen.GenID(),
+		OccurredAt: s.timer.Now(),
+		ActorID:    actorID,
+		ReqID:      reqID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       d,
+	})
+}
+
+func (s service) Search(ctx context.Context, q audit.Query, pr page.Request) (ep audit.EventPage, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Search"),
+		logAttrQuery(q),
+		logAttrAfter(pr.After),
+		logAttrLimit(pr.Limit),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return ep, err
+	}
+	if !p.IsSystemAdmin() {
+		log.Warn("forbidden")
+		return ep, authz.ErrForbidden{UserID: p.UserID, Action: "audit:read"}
+	}
+	events, err := s.dao.Search(ctx, q, pr.After, pr.Limit+1)
+	if err != nil {
+		return ep, err
+	}
+	ep.Events, ep.NextCursor = page.Trim(events, pr.Limit, toCursor)
+	return ep, nil
+}
+
+func toCursor(e audit.Event) page.Cursor {
+	return page.Cursor{
+		CreatedAt: e.OccurredAt,
+		ID:        e.ID,
+	}
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAO struct {
+	mock.Mock
+}
+
+type mockTimer struct {
+	mock.Mock
+}
+
+type mockIDGen struct {
+	mock.Mock
+}
+
+func initSVC() (s *service, md *mockDAO, mt *mockTimer, mi *mockIDGen) {
+	log := testutil.GetLogger()
+	md = new(mockDAO)
+	mt = new(mockTimer)
+	mi = new(mockIDGen)
+	s = NewService(log, md, mt, mi)
+	return s, md, mt, mi
+}
+
+// principalCTX is what the ReqID and Auth middleware would leave in the
+// context for a token with the given claims.
+func principalCTX(userID string, claims jwt.MapClaims) context.Context {
+	claims["sub"] = userID
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	ctx = context.WithValue(ctx, ctxutil.ContextKeyUserID{}, userID)
+	return context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
+}
+
+type named struct {
+	Name string `json:"name"`
+}
+
+func TestSVCRecord(t *testing.T) {
+	s, md, mt, mi := initSVC()
+
+	ctx := principalCTX(actorID, jwt.MapClaims{"admin": true})
+	now := time.UnixMilli(300)
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return(id)
+
+	var tx *sqlx.Tx
+	expected := audit.Event{
+		ID:         id,
+		OccurredAt: now,
+		ActorID:    actorID,
+		ReqID:      reqID,
+		Action:     audit.ActionUpdate,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Diff:       audit.Diff{"name": {Before: "foo", After: "bar"}},
+	}
+	md.On("Create", ctx, tx, expected).Return(nil)
+
+	err := s.Record(ctx, tx, audit.ActionUpdate, entityType, entityID, named{Name: "foo"}, named{Name: "bar"})
+
+	assert.Nil(t, err)
+	md.AssertExpectations(t)
+}
+
+func TestSVCRecord_DAOErr(t *testing.T) {
+	s, md, mt, mi := initSVC()
+
+	ctx := principalCTX(actorID, jwt.MapClaims{"admin": true})
+	mt.On("Now").Return(time.UnixMilli(300))
+	mi.On("GenID").Return(id)
+
+	var tx *sqlx.Tx
+	mockErr := errors.New("unit-test mock error")
+	md.On("Create", ctx, tx, mock.Anything).Return(mockErr)
+
+	err := s.Record(ctx, tx, audit.ActionCreate, entityType, entityID, nil, named{Name: "foo"})
+
+	assert.Equal(t, mockErr, err)
+}
+
+func TestSVCSearch(t *testing.T) {
+	s, md, _, _ := initSVC()
+
+	ctx := principalCTX(actorID, jwt.MapClaims{"admin": true})
+	q := audit.Query{EntityType: entityType}
+	mockRes := []audit.Event{
+		{ID: "foo-id", OccurredAt: time.UnixMilli(300)},
+		{ID: "bar-id", OccurredAt: time.UnixMilli(200)},
+	}
+	var after *page.Cursor
+	md.On("Search", ctx, q, after, 2).Return(mockRes, nil)
+
+	actual, err := s.Search(ctx, q, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[:1], actual.Events)
+	c, err := page.DecodeCursor(actual.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-id", c.ID)
+	assert.True(t, mockRes[0].OccurredAt.Equal(c.CreatedAt))
+}
+
+func TestSVCSearch_DAOErr(t *testing.T) {
+	s, md, _, _ := initSVC()
+
+	ctx := principalCTX(actorID, jwt.MapClaims{"admin": true})
+	mockErr := errors.New("unit-test mock error")
+	var after *page.Cursor
+	md.On("Search", ctx, audit.Query{}, after, 2).Return([]audit.Event{}, mockErr)
+
+	_, err := s.Search(ctx, audit.Query{}, page.Request{Limit: 1})
+
+	assert.Equal(t, mockErr, err)
+}
+
+func TestSVCSearch_OrgAdminForbidden(t *testing.T) {
+	s, md, _, _ := initSVC()
+
+	ctx := principalCTX(actorID, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	_, err := s.Search(ctx, audit.Query{}, page.Request{Limit: 1})
+
+	var forbidden authz.ErrForbidden
+	assert.True(t, errors.As(err, &forbidden))
+	md.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSearch_NotLoggedIn(t *testing.T) {
+	s, _, _, _ := initSVC()
+
+	_, err := s.Search(context.Background(), audit.Query{}, page.Request{Limit: 1})
+
+	var expected authz.ErrUnauthenticated
+	assert.True(t, errors.As(err, &expected))
+}
+
+func (d *mockDAO) Create(ctx context.Context, tx *sqlx.Tx, e audit.Event) error {
+	args := d.Called(ctx, tx, e)
+	return args.Error(0)
+}
+
+func (d *mockDAO) Search(ctx context.Context, q audit.Query, after *page.Cursor, limit int) ([]audit.Event, error) {
+	args := d.Called(ctx, q, after, limit)
+	return args.Get(0).([]audit.Event), args.Error(1)
+}
+
+func (t *mockTimer) Now() time.Time {
+	args := t.Called()
+	return args.Get(0).(time.Time)
+}
+
+func (t *mockIDGen) GenID() string {
+	args := t.Called()
+	return args.String(0)
+}
@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+// tracedService wraps every AuditService call in a span.
+type tracedService struct {
+	svc AuditService
+}
+
+func NewTracedService(svc AuditService) *tracedService {
+	return &tracedService{
+		svc: svc,
+	}
+}
+
+func (s tracedService) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuditSVC.Record")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Record(ctx, tx, action, entityType, entityID, before, after)
+}
+
+func (s tracedService) Search(ctx context.Context, q audit.Query, pr page.Request) (ep audit.EventPage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AuditSVC.Search")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Search(ctx, q, pr)
+}
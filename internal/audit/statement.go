@@ -0,0 +1,69 @@
+package audit
+
+const createQuery = `
+	INSERT INTO audit_events (
+		id,
+		occurred_at,
+		actor_id,
+		req_id,
+		action,
+		entity_type,
+		entity_id,
+		diff
+	) VALUES (
+		:id,
+		:occurred_at,
+		:actor_id,
+		:req_id,
+		:action,
+		:entity_type,
+		:entity_id,
+		:diff
+	)
+`
+
+// An empty filter param matches everything so one query covers every
+// combination of filters, from is inclusive and to is exclusive.
+const searchQuery = `
+	SELECT
+		e.id,
+		e.occurred_at,
+		e.actor_id,
+		e.req_id,
+		e.action,
+		e.entity_type,
+		e.entity_id,
+		e.diff
+	FROM audit_events e
+	WHERE ($1 = '' OR e.entity_type = $1)
+	AND ($2 = '' OR e.entity_id = $2)
+	AND ($3 = '' OR e.actor_id = $3)
+	AND ($4::TIMESTAMP IS NULL OR e.occurred_at >= $4)
+	AND ($5::TIMESTAMP IS NULL OR e.occurred_at < $5)
+	ORDER BY e.occurred_at DESC, e.id DESC
+	LIMIT $6
+`
+
+const searchAfterQuery = `
+	SELECT
+		e.id,
+		e.occurred_at,
+		e.actor_id,
+		e.req_id,
+		e.action,
+		e.entity_type,
+		e.entity_id,
+		e.diff
+	FROM audit_events e
+	WHERE ($1 = '' OR e.entity_type = $1)
+	AND ($2 = '' OR e.entity_id = $2)
+	AND ($3 = '' OR e.actor_id = $3)
+	AND ($4::TIMESTAMP IS NULL OR e.occurred_at >= $4)
+	AND ($5::TIMESTAMP IS NULL OR e.occurred_at < $5)
+	AND (
+		e.occurred_at < $6
+		OR (e.occurred_at = $6 AND e.id < $7)
+	)
+	ORDER BY e.occurred_at DESC, e.id DESC
+	LIMIT $8
+`
@@ -0,0 +1,92 @@
+package authz
+
+import (
+	"context"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// RoleSystemAdmin can read and modify everything.
+	RoleSystemAdmin = "system_admin"
+	// RoleOrgAdmin can read and modify the users of their own org and the org
+	// itself.
+	RoleOrgAdmin = "org_admin"
+	// RoleMember can only read within their own org.
+	RoleMember = "member"
+)
+
+// Principal is the logged in user as far as permission checks are concerned,
+// it's built from the jwt claims the Auth middleware put in the context and the
+// memberships the Memberships middleware did.
+type Principal struct {
+	UserID string
+	OrgID  string
+	Role   string
+	// Memberships is the role in each org the user is a member of, the jwt's
+	// role wins for their own org (OrgID).
+	Memberships map[string]string
+}
+
+func (p Principal) IsSystemAdmin() bool {
+	return p.Role == RoleSystemAdmin
+}
+
+// roleIn is the principal's role in the org, "" when they aren't a member.
+func (p Principal) roleIn(orgID string) string {
+	if orgID == "" {
+		return ""
+	}
+	if orgID == p.OrgID {
+		return p.Role
+	}
+	return p.Memberships[orgID]
+}
+
+func (p Principal) CanRead(orgID string) bool {
+	if p.IsSystemAdmin() {
+		return true
+	}
+	return p.roleIn(orgID) != ""
+}
+
+func (p Principal) CanManage(orgID string) bool {
+	if p.IsSystemAdmin() {
+		return true
+	}
+	return p.roleIn(orgID) == RoleOrgAdmin
+}
+
+type membershipsKey struct{}
+
+// WithMemberships puts the logged in user's role in each of their orgs in the
+// context for FromContext.
+func WithMemberships(ctx context.Context, roles map[string]string) context.Context {
+	return context.WithValue(ctx, membershipsKey{}, roles)
+}
+
+// FromContext reads the principal from the "sub", "org_id" and "role" claims.
+// Tokens minted before roles existed only have "admin", those map to system
+// admin. A missing or unrecognized role is treated as a plain member. The
+// memberships are only there when WithMemberships was called.
+func FromContext(ctx context.Context) (p Principal, err error) {
+	claims, ok := ctx.Value(ctxutil.ContextKeyJWTClaims{}).(jwt.MapClaims)
+	if !ok {
+		return p, ErrUnauthenticated{}
+	}
+	p.UserID, _ = claims["sub"].(string)
+	p.OrgID, _ = claims["org_id"].(string)
+	p.Memberships, _ = ctx.Value(membershipsKey{}).(map[string]string)
+	role, _ := claims["role"].(string)
+	switch role {
+	case RoleSystemAdmin, RoleOrgAdmin, RoleMember:
+		p.Role = role
+	default:
+		p.Role = RoleMember
+	}
+	if isAdmin, _ := claims["admin"].(bool); isAdmin {
+		p.Role = RoleSystemAdmin
+	}
+	return p, nil
+}
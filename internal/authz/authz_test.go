@@ -0,0 +1,128 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	userID   = "unit-test-user-id"
+	orgID    = "unit-test-org-id"
+	otherOrg = "unit-test-other-org-id"
+)
+
+func ctxWithClaims(claims jwt.MapClaims) context.Context {
+	return context.WithValue(context.Background(), ctxutil.ContextKeyJWTClaims{}, claims)
+}
+
+func TestFromContext(t *testing.T) {
+	p, err := FromContext(ctxWithClaims(jwt.MapClaims{
+		"sub":    userID,
+		"org_id": orgID,
+		"role":   RoleOrgAdmin,
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, Principal{UserID: userID, OrgID: orgID, Role: RoleOrgAdmin}, p)
+}
+
+func TestFromContext_LegacyAdmin(t *testing.T) {
+	p, err := FromContext(ctxWithClaims(jwt.MapClaims{
+		"sub":   userID,
+		"admin": true,
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, RoleSystemAdmin, p.Role)
+}
+
+func TestFromContext_LegacyAdminFalse(t *testing.T) {
+	p, err := FromContext(ctxWithClaims(jwt.MapClaims{
+		"sub":   userID,
+		"admin": false,
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, RoleMember, p.Role)
+}
+
+func TestFromContext_UnknownRole(t *testing.T) {
+	p, err := FromContext(ctxWithClaims(jwt.MapClaims{
+		"sub":  userID,
+		"role": "superuser",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, RoleMember, p.Role)
+}
+
+func TestFromContext_Memberships(t *testing.T) {
+	ctx := WithMemberships(ctxWithClaims(jwt.MapClaims{
+		"sub":    userID,
+		"org_id": orgID,
+	}), map[string]string{otherOrg: RoleOrgAdmin})
+	p, err := FromContext(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{otherOrg: RoleOrgAdmin}, p.Memberships)
+}
+
+func TestFromContext_NoClaims(t *testing.T) {
+	_, err := FromContext(context.Background())
+	var expected ErrUnauthenticated
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestCanRead_SystemAdmin(t *testing.T) {
+	p := Principal{UserID: userID, Role: RoleSystemAdmin}
+	assert.True(t, p.CanRead(otherOrg))
+}
+
+func TestCanRead_Member(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleMember}
+	assert.True(t, p.CanRead(orgID))
+	assert.False(t, p.CanRead(otherOrg))
+}
+
+func TestCanRead_OtherMembership(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleMember, Memberships: map[string]string{otherOrg: RoleMember}}
+	assert.True(t, p.CanRead(otherOrg))
+	assert.False(t, p.CanRead("unit-test-third-org-id"))
+}
+
+func TestCanRead_NoOrg(t *testing.T) {
+	p := Principal{UserID: userID, Role: RoleMember}
+	assert.False(t, p.CanRead(""))
+}
+
+func TestCanManage_SystemAdmin(t *testing.T) {
+	p := Principal{UserID: userID, Role: RoleSystemAdmin}
+	assert.True(t, p.CanManage(otherOrg))
+}
+
+func TestCanManage_OrgAdmin(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleOrgAdmin}
+	assert.True(t, p.CanManage(orgID))
+	assert.False(t, p.CanManage(otherOrg))
+}
+
+func TestCanManage_Member(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleMember}
+	assert.False(t, p.CanManage(orgID))
+}
+
+func TestCanManage_OtherMembershipOrgAdmin(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleMember, Memberships: map[string]string{otherOrg: RoleOrgAdmin}}
+	assert.True(t, p.CanManage(otherOrg))
+	assert.False(t, p.CanManage(orgID))
+}
+
+func TestCanManage_OtherMembershipMember(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleOrgAdmin, Memberships: map[string]string{otherOrg: RoleMember}}
+	assert.False(t, p.CanManage(otherOrg))
+}
+
+func TestCanManage_OwnOrgUsesJWTRole(t *testing.T) {
+	p := Principal{UserID: userID, OrgID: orgID, Role: RoleMember, Memberships: map[string]string{orgID: RoleOrgAdmin}}
+	assert.False(t, p.CanManage(orgID))
+}
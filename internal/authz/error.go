@@ -0,0 +1,30 @@
+package authz
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrUnauthenticated struct{}
+
+func (err ErrUnauthenticated) Error() string {
+	return "No logged in user to authorize"
+}
+
+func (err ErrUnauthenticated) Code() string {
+	return problem.CodeUnauthenticated
+}
+
+type ErrForbidden struct {
+	UserID string
+	Action string
+}
+
+func (err ErrForbidden) Error() string {
+	return fmt.Sprintf("User is not allowed to perform this action: userID=%s action=%s", err.UserID, err.Action)
+}
+
+func (err ErrForbidden) Code() string {
+	return problem.CodeForbidden
+}
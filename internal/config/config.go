@@ -1,23 +1,105 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
-	Mode       string `envconfig:"MODE" default:"local"`
-	Port       int    `envconfig:"PORT" default:"4000"`
-	LogLevel   string `envconfig:"LOG_LEVEL" default:"debug"`
-	DB         DBConfig
-	AuthConfig AuthConfig
+	Mode        string `envconfig:"MODE" default:"local"`
+	Port        int    `envconfig:"PORT" default:"4000"`
+	GRPCPort    int    `envconfig:"GRPC_PORT" default:"4001"`
+	LogLevel    string `envconfig:"LOG_LEVEL" default:"debug"`
+	Server      ServerConfig
+	Health      HealthConfig
+	Tracing     TracingConfig
+	DB          DBConfig
+	Purge       PurgeConfig
+	Notify      NotifyConfig
+	User        UserConfig
+	Invitation  InvitationConfig
+	Idempotency IdempotencyConfig
+	Docs        DocsConfig
+	AuthConfig  AuthConfig
+}
+
+type ServerConfig struct {
+	ReadTimeout  time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"15s"`
+	WriteTimeout time.Duration `envconfig:"SERVER_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout  time.Duration `envconfig:"SERVER_IDLE_TIMEOUT" default:"60s"`
+	// DrainDelay is how long /readiness fails before the listener is closed,
+	// it should be longer than the load balancer's readiness probe interval
+	DrainDelay      time.Duration `envconfig:"SERVER_DRAIN_DELAY" default:"5s"`
+	ShutdownTimeout time.Duration `envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"30s"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s"`
+	// readiness fails once this share of DB_MAX_OPEN_CONNS is in use
+	PoolMaxInUseRatio float64 `envconfig:"HEALTH_POOL_MAX_IN_USE_RATIO" default:"0.9"`
+}
+
+type TracingConfig struct {
+	// none, stdout (handy locally) or otlp (configured with the standard OTEL_EXPORTER_OTLP_* env vars)
+	Exporter    string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	ServiceName string  `envconfig:"OTEL_SERVICE_NAME" default:"go-service-ex"`
+	SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+type PurgeConfig struct {
+	// Retention is how long soft deleted orgs and users can still be restored
+	Retention time.Duration `envconfig:"PURGE_RETENTION" default:"720h"`
+	// Interval of 0 turns the purge job off
+	Interval time.Duration `envconfig:"PURGE_INTERVAL" default:"1h"`
+}
+
+type NotifyConfig struct {
+	// log or file, both are only meant for local use
+	Notifier string `envconfig:"NOTIFIER" default:"log"`
+	// Dir is where the file notifier writes a file per message
+	Dir string `envconfig:"NOTIFIER_DIR" default:"_notifications"`
+}
+
+type UserConfig struct {
+	// EmailChangeTTL is how long the token sent to a new email stays valid
+	EmailChangeTTL time.Duration `envconfig:"USER_EMAIL_CHANGE_TTL" default:"24h"`
+}
+
+type InvitationConfig struct {
+	// TTL is how long an invitation's token stays valid, resending starts it
+	// over
+	TTL time.Duration `envconfig:"INVITATION_TTL" default:"168h"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long the response to a request with an Idempotency-Key is
+	// replayed for, a key can be used for a new request after that
+	TTL time.Duration `envconfig:"IDEMPOTENCY_TTL" default:"24h"`
+	// MaxBodySize is the most bytes of a request (or its response) with an
+	// Idempotency-Key that's held in memory, a larger request is a 413
+	MaxBodySize int64 `envconfig:"IDEMPOTENCY_MAX_BODY_SIZE" default:"1048576"`
+}
+
+type DocsConfig struct {
+	// UI serves Swagger UI at /docs, /openapi.json is always served. The UI's
+	// files are embedded in the binary (see internal/openapi).
+	UI bool `envconfig:"DOCS_UI" default:"false"`
 }
 
 type AuthConfig struct {
+	// JWTSecret enables HS256, leave it empty to only accept asymmetric tokens
 	JWTSecret   string `envconfig:"JWT_SECRET"`
 	JWTAudience string `envconfig:"JWT_AUDIENCE" default:"gin-ex"`
 	JWTIssuer   string `envconfig:"JWT_ISSUER" default:"something"`
+	// JWKSURL (or JWKSFile) enables RS256/ES256/EdDSA, keys are picked by kid
+	// so several can be valid at once while rotating
+	JWKSURL        string        `envconfig:"JWT_JWKS_URL"`
+	JWKSFile       string        `envconfig:"JWT_JWKS_FILE"`
+	JWKSCacheTTL   time.Duration `envconfig:"JWT_JWKS_CACHE_TTL" default:"10m"`
+	ClockSkew      time.Duration `envconfig:"JWT_CLOCK_SKEW" default:"30s"`
+	RequiredClaims []string      `envconfig:"JWT_REQUIRED_CLAIMS" default:"sub,exp"`
 }
 
 type DBConfig struct {
@@ -28,6 +110,19 @@ type DBConfig struct {
 	QueryTimeout time.Duration `envconfig:"DB_QUERY_TIMEOUT" default:"30s"`
 	MaxIdleConns int           `envconfig:"DB_MAX_IDLE_CONNS" default:"2"`
 	MaxOpenConns int           `envconfig:"DB_MAX_OPEN_CONNS" default:"20"`
+	// MigrateOnBoot is handy locally, but in prod prefer running cmd/migrate
+	// as a deploy step so a bad migration doesn't crash loop every pod
+	MigrateOnBoot bool `envconfig:"DB_MIGRATE_ON_BOOT" default:"false"`
+}
+
+func (c DBConfig) ConnStr() string {
+	return fmt.Sprintf(
+		"user=%s password=%s dbname=%s sslmode=%s",
+		c.User,
+		c.Password,
+		c.DBName,
+		c.SSLMode,
+	)
 }
 
 func LoadConfig() (c Config, err error) {
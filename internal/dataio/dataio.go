@@ -0,0 +1,215 @@
+package dataio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// maxLineSize caps a single ndjson line.
+const maxLineSize = 1024 * 1024
+
+// flushWriteTimeout is how long the client has to take the rows written after
+// a flush.
+const flushWriteTimeout = 30 * time.Second
+
+// ParseFormat treats a missing format as csv.
+func ParseFormat(s string) (string, error) {
+	switch s {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatNDJSON:
+		return FormatNDJSON, nil
+	default:
+		return "", ErrInvalidFormat{Format: s}
+	}
+}
+
+func ContentType(format string) string {
+	if format == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// Encoder writes rows as they come instead of buffering them, csv rows are
+// laid out by header and toCSV, ndjson rows are the json of T.
+type Encoder[T any] struct {
+	format  string
+	header  []string
+	toCSV   func(T) []string
+	csvW    *csv.Writer
+	jsonEnc *json.Encoder
+}
+
+func NewEncoder[T any](w io.Writer, format string, header []string, toCSV func(T) []string) *Encoder[T] {
+	e := &Encoder[T]{
+		format: format,
+		header: header,
+		toCSV:  toCSV,
+	}
+	if format == FormatNDJSON {
+		e.jsonEnc = json.NewEncoder(w)
+	} else {
+		e.csvW = csv.NewWriter(w)
+	}
+	return e
+}
+
+// WriteHeader is a noop for ndjson, it has no header.
+func (e *Encoder[T]) WriteHeader() error {
+	if e.csvW == nil {
+		return nil
+	}
+	return e.csvW.Write(e.header)
+}
+
+func (e *Encoder[T]) Encode(v T) error {
+	if e.csvW == nil {
+		return e.jsonEnc.Encode(v)
+	}
+	return e.csvW.Write(e.toCSV(v))
+}
+
+// Flush pushes any buffered csv to the underlying writer, ndjson isn't
+// buffered.
+func (e *Encoder[T]) Flush() error {
+	if e.csvW == nil {
+		return nil
+	}
+	e.csvW.Flush()
+	return e.csvW.Error()
+}
+
+// FlushResponse sends what's been written so far to the client and pushes
+// the write deadline out, so a long export isn't cut off by the server's
+// write timeout.
+func FlushResponse(w http.ResponseWriter) error {
+	rc := http.NewResponseController(w)
+	// not every writer has a deadline, ex. the one in tests
+	if err := rc.SetWriteDeadline(time.Now().Add(flushWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return rc.Flush()
+}
+
+// Abort sends what's been written so far and drops the connection, the client
+// sees a broken transfer rather than the end of the body. It panics with
+// http.ErrAbortHandler, which mdlw.Recovery leaves for net/http.
+func Abort(w http.ResponseWriter) {
+	// the client may be gone already, it's being cut off either way
+	_ = http.NewResponseController(w).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+// Decode yields the rows of r one at a time. A row that can't be read is
+// yielded as an ErrInvalidRow and decoding carries on, anything else ends
+// it. CSV columns are matched by the header, the ones fromCSV doesn't look up
+// are ignored and the required ones have to be in it.
+func Decode[T any](r io.Reader, format string, required []string, fromCSV func(row map[string]string) (T, error)) iter.Seq2[T, error] {
+	if format == FormatNDJSON {
+		return decodeNDJSON[T](r)
+	}
+	return decodeCSV(r, required, fromCSV)
+}
+
+func decodeNDJSON[T any](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var v T
+			if err := json.Unmarshal([]byte(line), &v); err != nil {
+				if !yield(v, ErrInvalidRow{Reason: err.Error()}) {
+					return
+				}
+				continue
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			var zero T
+			yield(zero, ErrMalformed{Reason: err.Error()})
+		}
+	}
+}
+
+func decodeCSV[T any](r io.Reader, required []string, fromCSV func(row map[string]string) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		csvR := csv.NewReader(r)
+		header, err := csvR.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(zero, ErrMalformed{Reason: err.Error()})
+			return
+		}
+		for i := range header {
+			header[i] = strings.TrimSpace(header[i])
+		}
+		for _, column := range required {
+			if !slices.Contains(header, column) {
+				yield(zero, ErrMissingColumn{Column: column})
+				return
+			}
+		}
+		for {
+			record, err := csvR.Read()
+			if err == io.EOF {
+				return
+			}
+			if errors.Is(err, csv.ErrFieldCount) {
+				if !yield(zero, ErrInvalidRow{Reason: err.Error()}) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(zero, ErrMalformed{Reason: err.Error()})
+				return
+			}
+			row := make(map[string]string, len(header))
+			for i, column := range header {
+				row[column] = record[i]
+			}
+			if !yield(fromCSV(row)) {
+				return
+			}
+		}
+	}
+}
+
+// ParseBool treats a missing or empty column as false.
+func ParseBool(row map[string]string, column string) (bool, error) {
+	v := strings.TrimSpace(row[column])
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, ErrInvalidRow{Reason: fmt.Sprintf("%s must be true or false: %s=%s", column, column, v)}
+	}
+	return b, nil
+}
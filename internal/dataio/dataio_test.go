@@ -0,0 +1,181 @@
+package dataio
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type row struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+var header = []string{"name", "active"}
+
+func toCSV(r row) []string {
+	return []string{r.Name, "true"}
+}
+
+func fromCSV(m map[string]string) (r row, err error) {
+	r.Name = m["name"]
+	r.Active, err = ParseBool(m, "active")
+	return r, err
+}
+
+func decodeAll(input string, format string) (rows []row, errs []error) {
+	for r, err := range Decode(strings.NewReader(input), format, []string{"name"}, fromCSV) {
+		rows = append(rows, r)
+		errs = append(errs, err)
+	}
+	return rows, errs
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	assert.Nil(t, err)
+	assert.Equal(t, FormatCSV, format)
+
+	format, err = ParseFormat("ndjson")
+	assert.Nil(t, err)
+	assert.Equal(t, FormatNDJSON, format)
+}
+
+func TestParseFormat_Invalid(t *testing.T) {
+	_, err := ParseFormat("xml")
+	var expected ErrInvalidFormat
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "xml", expected.Format)
+}
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, "text/csv", ContentType(FormatCSV))
+	assert.Equal(t, "application/x-ndjson", ContentType(FormatNDJSON))
+}
+
+func TestEncoder_CSV(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b, FormatCSV, header, toCSV)
+
+	assert.Nil(t, e.WriteHeader())
+	assert.Nil(t, e.Encode(row{Name: "a, b"}))
+	assert.Nil(t, e.Flush())
+
+	assert.Equal(t, "name,active\n\"a, b\",true\n", b.String())
+}
+
+func TestEncoder_NDJSON(t *testing.T) {
+	var b bytes.Buffer
+	e := NewEncoder(&b, FormatNDJSON, header, toCSV)
+
+	assert.Nil(t, e.WriteHeader())
+	assert.Nil(t, e.Encode(row{Name: "a", Active: true}))
+	assert.Nil(t, e.Encode(row{Name: "b"}))
+	assert.Nil(t, e.Flush())
+
+	assert.Equal(t, "{\"name\":\"a\",\"active\":true}\n{\"name\":\"b\",\"active\":false}\n", b.String())
+}
+
+func TestDecode_CSV(t *testing.T) {
+	rows, errs := decodeAll("active,ignored,name\ntrue,x,a\n,y,b\n", FormatCSV)
+
+	assert.Equal(t, []row{{Name: "a", Active: true}, {Name: "b"}}, rows)
+	assert.Equal(t, []error{nil, nil}, errs)
+}
+
+func TestDecode_CSVEmpty(t *testing.T) {
+	rows, errs := decodeAll("", FormatCSV)
+
+	assert.Len(t, rows, 0)
+	assert.Len(t, errs, 0)
+}
+
+func TestDecode_CSVMissingColumn(t *testing.T) {
+	_, errs := decodeAll("active\ntrue\n", FormatCSV)
+
+	assert.Len(t, errs, 1)
+	var expected ErrMissingColumn
+	assert.True(t, errors.As(errs[0], &expected))
+	assert.Equal(t, "name", expected.Column)
+}
+
+func TestDecode_CSVInvalidRowsCarryOn(t *testing.T) {
+	rows, errs := decodeAll("name,active\na,true,extra\nb,maybe\nc,false\n", FormatCSV)
+
+	assert.Len(t, errs, 3)
+	var invalidRow ErrInvalidRow
+	assert.True(t, errors.As(errs[0], &invalidRow))
+	assert.True(t, errors.As(errs[1], &invalidRow))
+	assert.Nil(t, errs[2])
+	assert.Equal(t, row{Name: "c"}, rows[2])
+}
+
+func TestDecode_CSVMalformed(t *testing.T) {
+	_, errs := decodeAll("name,active\n\"a,true\nb,false\n", FormatCSV)
+
+	assert.Len(t, errs, 1)
+	var expected ErrMalformed
+	assert.True(t, errors.As(errs[0], &expected))
+}
+
+func TestDecode_NDJSON(t *testing.T) {
+	rows, errs := decodeAll("{\"name\":\"a\",\"active\":true}\n\n{\"name\":\"b\"}\n", FormatNDJSON)
+
+	assert.Equal(t, []row{{Name: "a", Active: true}, {Name: "b"}}, rows)
+	assert.Equal(t, []error{nil, nil}, errs)
+}
+
+func TestDecode_NDJSONInvalidRowsCarryOn(t *testing.T) {
+	rows, errs := decodeAll("{\"name\":\n{\"name\":\"b\"}\n", FormatNDJSON)
+
+	assert.Len(t, errs, 2)
+	var expected ErrInvalidRow
+	assert.True(t, errors.As(errs[0], &expected))
+	assert.Nil(t, errs[1])
+	assert.Equal(t, row{Name: "b"}, rows[1])
+}
+
+func TestDecode_StopEarly(t *testing.T) {
+	var rows []row
+	for r := range Decode(strings.NewReader("name\na\nb\n"), FormatCSV, nil, fromCSV) {
+		rows = append(rows, r)
+		break
+	}
+
+	assert.Equal(t, []row{{Name: "a"}}, rows)
+}
+
+func TestParseBool(t *testing.T) {
+	b, err := ParseBool(map[string]string{"active": " TRUE "}, "active")
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	b, err = ParseBool(map[string]string{}, "active")
+	assert.Nil(t, err)
+	assert.False(t, b)
+
+	_, err = ParseBool(map[string]string{"active": "maybe"}, "active")
+	var expected ErrInvalidRow
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestFlushResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	err := FlushResponse(w)
+
+	assert.Nil(t, err)
+	assert.True(t, w.Flushed)
+}
+
+func TestAbort(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { Abort(w) })
+	assert.True(t, w.Flushed)
+}
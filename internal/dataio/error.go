@@ -0,0 +1,58 @@
+package dataio
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrInvalidFormat struct {
+	Format string
+}
+
+func (err ErrInvalidFormat) Error() string {
+	return fmt.Sprintf("Invalid format, must be csv or ndjson: format=%s", err.Format)
+}
+
+func (err ErrInvalidFormat) Code() string {
+	return problem.CodeInvalidFormat
+}
+
+type ErrMissingColumn struct {
+	Column string
+}
+
+func (err ErrMissingColumn) Error() string {
+	return fmt.Sprintf("Invalid csv header, missing column: %s", err.Column)
+}
+
+func (err ErrMissingColumn) Code() string {
+	return problem.CodeMissingColumn
+}
+
+// ErrInvalidRow is a row that couldn't be read, the rows after it still can
+// be.
+type ErrInvalidRow struct {
+	Reason string
+}
+
+func (err ErrInvalidRow) Error() string {
+	return fmt.Sprintf("Invalid row: %s", err.Reason)
+}
+
+func (err ErrInvalidRow) Code() string {
+	return problem.CodeInvalidRow
+}
+
+// ErrMalformed is input that can't be read past, nothing after it is read.
+type ErrMalformed struct {
+	Reason string
+}
+
+func (err ErrMalformed) Error() string {
+	return fmt.Sprintf("Malformed input: %s", err.Reason)
+}
+
+func (err ErrMalformed) Code() string {
+	return problem.CodeMalformedBody
+}
@@ -0,0 +1,19 @@
+package etag
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrPreconditionFailed struct {
+	IfMatch string
+}
+
+func (err ErrPreconditionFailed) Error() string {
+	return fmt.Sprintf("If-Match doesn't match the current version: ifMatch=%s", err.IfMatch)
+}
+
+func (err ErrPreconditionFailed) Code() string {
+	return problem.CodePreconditionFailed
+}
@@ -0,0 +1,70 @@
+package etag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// New is the strong ETag of a row at a version, every change to a row bumps
+// its version so the pair identifies the representation.
+func New(id string, version int64) string {
+	return fmt.Sprintf(`"%s:%d"`, id, version)
+}
+
+// NoneMatch reports whether an If-None-Match header matches the tag (so the
+// client's copy is current and a 304 can be sent). It uses the weak
+// comparison, a W/ prefix is ignored.
+func NoneMatch(header string, tag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, t := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseIfMatch returns the version from the If-Match tag for the row with id,
+// ok is false when there's no header (or it's *) so the version in the body
+// should be used. A header without a tag for the row is ErrPreconditionFailed,
+// including weak tags since If-Match needs the strong comparison.
+func ParseIfMatch(header string, id string) (version int64, ok bool, err error) {
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return 0, false, nil
+	}
+	for _, t := range strings.Split(header, ",") {
+		tagID, v, found := parse(strings.TrimSpace(t))
+		if found && tagID == id {
+			return v, true, nil
+		}
+	}
+	return 0, false, ErrPreconditionFailed{IfMatch: header}
+}
+
+func parse(tag string) (id string, version int64, ok bool) {
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return "", 0, false
+	}
+	tag = tag[1 : len(tag)-1]
+	i := strings.LastIndex(tag, ":")
+	if i < 0 {
+		return "", 0, false
+	}
+	version, err := strconv.ParseInt(tag[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return tag[:i], version, true
+}
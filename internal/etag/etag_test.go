@@ -0,0 +1,87 @@
+package etag
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, `"foo-id:3"`, New("foo-id", 3))
+}
+
+func TestNoneMatch(t *testing.T) {
+	assert.True(t, NoneMatch(`"foo-id:3"`, New("foo-id", 3)))
+}
+
+func TestNoneMatch_List(t *testing.T) {
+	assert.True(t, NoneMatch(`"foo-id:2", W/"foo-id:3"`, New("foo-id", 3)))
+}
+
+func TestNoneMatch_Star(t *testing.T) {
+	assert.True(t, NoneMatch("*", New("foo-id", 3)))
+}
+
+func TestNoneMatch_Stale(t *testing.T) {
+	assert.False(t, NoneMatch(`"foo-id:2"`, New("foo-id", 3)))
+}
+
+func TestNoneMatch_NoHeader(t *testing.T) {
+	assert.False(t, NoneMatch("", New("foo-id", 3)))
+}
+
+func TestParseIfMatch(t *testing.T) {
+	version, ok, err := ParseIfMatch(`"foo-id:3"`, "foo-id")
+
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), version)
+}
+
+func TestParseIfMatch_List(t *testing.T) {
+	version, ok, err := ParseIfMatch(`"bar-id:1", "foo-id:3"`, "foo-id")
+
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), version)
+}
+
+func TestParseIfMatch_NoHeader(t *testing.T) {
+	_, ok, err := ParseIfMatch("", "foo-id")
+
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestParseIfMatch_Star(t *testing.T) {
+	_, ok, err := ParseIfMatch("*", "foo-id")
+
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func assertPreconditionFailed(t *testing.T, header string) {
+	_, ok, err := ParseIfMatch(header, "foo-id")
+
+	assert.False(t, ok)
+	var expected ErrPreconditionFailed
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, header, expected.IfMatch)
+}
+
+func TestParseIfMatch_OtherID(t *testing.T) {
+	assertPreconditionFailed(t, `"bar-id:3"`)
+}
+
+func TestParseIfMatch_Weak(t *testing.T) {
+	assertPreconditionFailed(t, `W/"foo-id:3"`)
+}
+
+func TestParseIfMatch_Unquoted(t *testing.T) {
+	assertPreconditionFailed(t, `foo-id:3`)
+}
+
+func TestParseIfMatch_NotAVersion(t *testing.T) {
+	assertPreconditionFailed(t, `"foo-id:abc"`)
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+)
+
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type versioner interface {
+	Latest() int64
+	Current(ctx context.Context) (int64, error)
+}
+
+func DBPing(db pinger) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// PoolSaturation fails once the share of open connections in use reaches
+// maxInUseRatio, past that point requests start queueing for a connection.
+func PoolSaturation(stats func() sql.DBStats, maxInUseRatio float64) Check {
+	return func(ctx context.Context) error {
+		s := stats()
+		if s.MaxOpenConnections <= 0 {
+			return nil
+		}
+		if float64(s.InUse)/float64(s.MaxOpenConnections) >= maxInUseRatio {
+			return ErrPoolSaturated{InUse: s.InUse, MaxOpen: s.MaxOpenConnections}
+		}
+		return nil
+	}
+}
+
+// MigrationVersion fails while the schema is behind this binary. A schema
+// ahead of it is fine, that's what a rolling deploy of a newer version looks
+// like from the old instances.
+func MigrationVersion(m versioner) Check {
+	return func(ctx context.Context) error {
+		current, err := m.Current(ctx)
+		if err != nil {
+			return err
+		}
+		if current < m.Latest() {
+			return ErrSchemaBehind{Expected: m.Latest(), Actual: current}
+		}
+		return nil
+	}
+}
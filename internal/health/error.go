@@ -0,0 +1,23 @@
+package health
+
+import (
+	"fmt"
+)
+
+type ErrPoolSaturated struct {
+	InUse   int
+	MaxOpen int
+}
+
+func (err ErrPoolSaturated) Error() string {
+	return fmt.Sprintf("DB connection pool is saturated: inUse=%d, maxOpen=%d", err.InUse, err.MaxOpen)
+}
+
+type ErrSchemaBehind struct {
+	Expected int64
+	Actual   int64
+}
+
+func (err ErrSchemaBehind) Error() string {
+	return fmt.Sprintf("DB schema is behind, migrations are pending: expected=%d, actual=%d", err.Expected, err.Actual)
+}
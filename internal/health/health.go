@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check returns nil when the thing it checks is healthy.
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type registry struct {
+	log       *slog.Logger
+	timeout   time.Duration
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+func NewRegistry(log *slog.Logger, timeout time.Duration) *registry {
+	return &registry{
+		log:     log.With(logutil.LogAttrSVC("HealthRegistry")),
+		timeout: timeout,
+	}
+}
+
+// AddLiveness registers a check that means the process itself is broken and
+// should be restarted, don't add dependencies here (restarting doesn't fix a
+// db outage).
+func (r *registry) AddLiveness(name string, check Check) {
+	r.liveness = append(r.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadiness registers a check that means the instance shouldn't be sent
+// traffic right now.
+func (r *registry) AddReadiness(name string, check Check) {
+	r.readiness = append(r.readiness, namedCheck{name: name, check: check})
+}
+
+func (r *registry) Liveness(c *gin.Context) {
+	log := r.log.With(
+		logutil.LogAttrReqID(c.Request.Context()),
+		logutil.LogAttrFN("Liveness"),
+	)
+	log.Debug("called")
+	r.reply(c, log, r.run(c.Request.Context(), r.liveness))
+}
+
+// Readiness runs the liveness checks too, an instance that isn't alive isn't
+// ready either.
+func (r *registry) Readiness(c *gin.Context) {
+	log := r.log.With(
+		logutil.LogAttrReqID(c.Request.Context()),
+		logutil.LogAttrFN("Readiness"),
+	)
+	log.Debug("called")
+	checks := append(append([]namedCheck{}, r.liveness...), r.readiness...)
+	r.reply(c, log, r.run(c.Request.Context(), checks))
+}
+
+func (r *registry) reply(c *gin.Context, log *slog.Logger, report Report) {
+	if report.Status != StatusUp {
+		log.With(slog.Any("report", report)).Warn("unhealthy")
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// run executes the checks concurrently, each bounded by the registry's
+// timeout, so one hung dependency can't stall the probe.
+func (r *registry) run(ctx context.Context, checks []namedCheck) Report {
+	report := Report{
+		Status: StatusUp,
+		Checks: make([]CheckResult, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.runOne(ctx, nc)
+		}()
+	}
+	wg.Wait()
+	for _, cr := range report.Checks {
+		if cr.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (r *registry) runOne(ctx context.Context, nc namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+	err := nc.check(ctx)
+	cr := CheckResult{
+		Name:      nc.name,
+		Status:    StatusUp,
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		cr.Status = StatusDown
+		cr.Error = err.Error()
+	}
+	return cr
+}
@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+func ginCtx() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	return c, w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) (report Report) {
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return report
+}
+
+func ok(ctx context.Context) error {
+	return nil
+}
+
+func TestLiveness_NoChecks(t *testing.T) {
+	r := NewRegistry(testutil.GetLogger(), time.Second)
+	c, w := ginCtx()
+
+	r.Liveness(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Report{Status: StatusUp, Checks: []CheckResult{}}, decode(t, w))
+}
+
+func TestLiveness_IgnoresReadinessChecks(t *testing.T) {
+	r := NewRegistry(testutil.GetLogger(), time.Second)
+	r.AddLiveness("alive", ok)
+	r.AddReadiness("db", func(ctx context.Context) error {
+		return errors.New("unit-test mock error")
+	})
+	c, w := ginCtx()
+
+	r.Liveness(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	report := decode(t, w)
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, "alive", report.Checks[0].Name)
+}
+
+func TestReadiness(t *testing.T) {
+	r := NewRegistry(testutil.GetLogger(), time.Second)
+	r.AddLiveness("alive", ok)
+	r.AddReadiness("db", ok)
+	c, w := ginCtx()
+
+	r.Readiness(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	report := decode(t, w)
+	assert.Equal(t, StatusUp, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, "alive", report.Checks[0].Name)
+	assert.Equal(t, "db", report.Checks[1].Name)
+}
+
+func TestReadiness_CheckFails(t *testing.T) {
+	r := NewRegistry(testutil.GetLogger(), time.Second)
+	r.AddReadiness("ok", ok)
+	r.AddReadiness("db", func(ctx context.Context) error {
+		return errors.New("unit-test mock error")
+	})
+	c, w := ginCtx()
+
+	r.Readiness(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	report := decode(t, w)
+	assert.Equal(t, StatusDown, report.Status)
+	assert.Equal(t, StatusUp, report.Checks[0].Status)
+	assert.Equal(t, CheckResult{Name: "db", Status: StatusDown, Error: "unit-test mock error"}, report.Checks[1])
+}
+
+func TestReadiness_CheckTimesOut(t *testing.T) {
+	r := NewRegistry(testutil.GetLogger(), 10*time.Millisecond)
+	r.AddReadiness("hung", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c, w := ginCtx()
+
+	r.Readiness(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), decode(t, w).Checks[0].Error)
+}
+
+type mockPinger struct {
+	err error
+}
+
+func (m mockPinger) PingContext(ctx context.Context) error {
+	return m.err
+}
+
+func TestDBPing(t *testing.T) {
+	assert.Nil(t, DBPing(mockPinger{})(ctx))
+	mockErr := errors.New("unit-test mock error")
+	assert.Equal(t, mockErr, DBPing(mockPinger{err: mockErr})(ctx))
+}
+
+func TestPoolSaturation(t *testing.T) {
+	stats := sql.DBStats{MaxOpenConnections: 10, InUse: 8}
+	check := PoolSaturation(func() sql.DBStats { return stats }, 0.9)
+
+	assert.Nil(t, check(ctx))
+
+	stats.InUse = 9
+	assert.Equal(t, ErrPoolSaturated{InUse: 9, MaxOpen: 10}, check(ctx))
+}
+
+func TestPoolSaturation_Unlimited(t *testing.T) {
+	check := PoolSaturation(func() sql.DBStats { return sql.DBStats{InUse: 100} }, 0.9)
+
+	assert.Nil(t, check(ctx))
+}
+
+type mockVersioner struct {
+	latest  int64
+	current int64
+	err     error
+}
+
+func (m mockVersioner) Latest() int64 {
+	return m.latest
+}
+
+func (m mockVersioner) Current(ctx context.Context) (int64, error) {
+	return m.current, m.err
+}
+
+func TestMigrationVersion(t *testing.T) {
+	assert.Nil(t, MigrationVersion(mockVersioner{latest: 2, current: 2})(ctx))
+	assert.Nil(t, MigrationVersion(mockVersioner{latest: 2, current: 3})(ctx))
+	assert.Equal(t, ErrSchemaBehind{Expected: 2, Actual: 1}, MigrationVersion(mockVersioner{latest: 2, current: 1})(ctx))
+	mockErr := errors.New("unit-test mock error")
+	assert.Equal(t, mockErr, MigrationVersion(mockVersioner{latest: 2, err: mockErr})(ctx))
+}
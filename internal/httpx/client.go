@@ -7,9 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 )
 
 type Client struct {
@@ -34,22 +37,45 @@ func NewClient(httpClient http.Client) *Client {
 	}
 }
 
+// HTTPError is a response that wasn't a 2xx. When it was an
+// application/problem+json it unwraps to the problem.Problem, so callers can
+// errors.As on that for the code.
 type HTTPError struct {
 	StatusCode int
 	ErrMessage string
+	Problem    *problem.Problem
 }
 
 func (h HTTPError) Error() string {
 	return fmt.Sprintf("http call failed with %d status: %s", h.StatusCode, h.ErrMessage)
 }
 
-func newHTTPError(statusCode int, body io.ReadCloser) error {
+func (h HTTPError) Unwrap() error {
+	if h.Problem == nil {
+		return nil
+	}
+	return *h.Problem
+}
+
+// newHTTPError falls back to a json body's message (or error) for responses
+// that aren't problems, ex. from servers other than this one.
+func newHTTPError(statusCode int, contentType string, body io.ReadCloser) error {
 	b, err := io.ReadAll(body)
 	if err != nil {
 		return HTTPError{
 			StatusCode: statusCode,
 		}
 	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == problem.ContentType {
+		var p problem.Problem
+		if err := json.Unmarshal(b, &p); err == nil {
+			return HTTPError{
+				StatusCode: statusCode,
+				ErrMessage: p.Error(),
+				Problem:    &p,
+			}
+		}
+	}
 	var errorBody map[string]string
 	err = json.Unmarshal(b, &errorBody)
 	if err != nil {
@@ -58,7 +84,6 @@ func newHTTPError(statusCode int, body io.ReadCloser) error {
 			ErrMessage: string(b),
 		}
 	}
-	// TODO - think up something more generic than this
 	errMessage := errorBody["message"]
 	if errMessage == "" {
 		errMessage = errorBody["error"]
@@ -69,6 +94,16 @@ func newHTTPError(statusCode int, body io.ReadCloser) error {
 	}
 }
 
+// isJSON is true for application/json and the types built on it, ex.
+// application/merge-patch+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
 func isSuccess(statusCode int) bool {
 	return statusCode >= 200 && statusCode <= 299
 }
@@ -89,6 +124,10 @@ func (hc *Client) Put(uri string, pathParams map[string]string) Builder {
 	return hc.newBuilder("PUT", uri, pathParams)
 }
 
+func (hc *Client) Patch(uri string, pathParams map[string]string) Builder {
+	return hc.newBuilder("PATCH", uri, pathParams)
+}
+
 func (hc *Client) Delete(uri string, pathParams map[string]string) Builder {
 	return hc.newBuilder("DELETE", uri, pathParams)
 }
@@ -231,7 +270,7 @@ func (hb Builder) common(ctx context.Context) (statusCode int, b []byte, err err
 	var body io.Reader
 	if hb.method != "GET" && hb.method != "HEAD" && hb.body != nil && hb.body != "" {
 		var data []byte
-		if hb.contentType == "application/json" {
+		if isJSON(hb.contentType) {
 			if data, err = json.Marshal(hb.body); err != nil {
 				return statusCode, b, err
 			}
@@ -264,7 +303,7 @@ func (hb Builder) common(ctx context.Context) (statusCode int, b []byte, err err
 	defer resp.Body.Close()
 	statusCode = resp.StatusCode
 	if !isSuccess(statusCode) {
-		err = newHTTPError(statusCode, resp.Body)
+		err = newHTTPError(statusCode, resp.Header.Get("Content-Type"), resp.Body)
 		return statusCode, b, err
 	}
 	b, err = io.ReadAll(resp.Body)
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,7 +39,7 @@ func initClient(f func(w http.ResponseWriter, r *http.Request)) (*Client, *httpt
 
 func TestNewHTTPError_IOErr(t *testing.T) {
 	statusCode := 400
-	err := newHTTPError(statusCode, errReader(0))
+	err := newHTTPError(statusCode, "", errReader(0))
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("%d", statusCode))
 	var httpErr HTTPError
@@ -50,7 +51,7 @@ func TestNewHTTPError_IOErr(t *testing.T) {
 func TestNewHTTPError_StrResp(t *testing.T) {
 	statusCode := 400
 	errMsg := "unit-test err message"
-	err := newHTTPError(statusCode, io.NopCloser(strings.NewReader(errMsg)))
+	err := newHTTPError(statusCode, "", io.NopCloser(strings.NewReader(errMsg)))
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("%d", statusCode))
 	assert.Contains(t, err.Error(), errMsg)
@@ -63,7 +64,7 @@ func TestNewHTTPError_StrResp(t *testing.T) {
 func TestNewHTTPError_EmptyStrResp(t *testing.T) {
 	statusCode := 400
 	errMsg := ""
-	err := newHTTPError(statusCode, io.NopCloser(strings.NewReader(errMsg)))
+	err := newHTTPError(statusCode, "", io.NopCloser(strings.NewReader(errMsg)))
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("%d", statusCode))
 	assert.Contains(t, err.Error(), errMsg)
@@ -77,7 +78,7 @@ func TestNewHTTPError_JSONRespWithMessage(t *testing.T) {
 	statusCode := 400
 	errMsg := "unit-test err message"
 	jsonStr := fmt.Sprintf(`{"message": "%s"}`, errMsg)
-	err := newHTTPError(statusCode, io.NopCloser(strings.NewReader(jsonStr)))
+	err := newHTTPError(statusCode, "", io.NopCloser(strings.NewReader(jsonStr)))
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("%d", statusCode))
 	assert.Contains(t, err.Error(), errMsg)
@@ -91,7 +92,7 @@ func TestNewHTTPError_JSONRespWithError(t *testing.T) {
 	statusCode := 400
 	errMsg := "unit-test err message"
 	jsonStr := fmt.Sprintf(`{"error": "%s"}`, errMsg)
-	err := newHTTPError(statusCode, io.NopCloser(strings.NewReader(jsonStr)))
+	err := newHTTPError(statusCode, "", io.NopCloser(strings.NewReader(jsonStr)))
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("%d", statusCode))
 	assert.Contains(t, err.Error(), errMsg)
@@ -105,7 +106,7 @@ func TestNewHTTPError_JSONResp(t *testing.T) {
 	statusCode := 400
 	errMsg := "unit-test err message"
 	jsonStr := fmt.Sprintf(`{"other": "%s"}`, errMsg)
-	err := newHTTPError(statusCode, io.NopCloser(strings.NewReader(jsonStr)))
+	err := newHTTPError(statusCode, "", io.NopCloser(strings.NewReader(jsonStr)))
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), fmt.Sprintf("%d", statusCode))
 	var httpErr HTTPError
@@ -114,6 +115,35 @@ func TestNewHTTPError_JSONResp(t *testing.T) {
 	assert.Equal(t, "", httpErr.ErrMessage)
 }
 
+func TestNewHTTPError_ProblemResp(t *testing.T) {
+	statusCode := 409
+	jsonStr := `{"title":"Conflict","status":409,"detail":"unit-test err message","code":"org_has_users","num_users":3}`
+	err := newHTTPError(statusCode, "application/problem+json; charset=utf-8", io.NopCloser(strings.NewReader(jsonStr)))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unit-test err message")
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, statusCode, httpErr.StatusCode)
+	assert.Equal(t, "unit-test err message", httpErr.ErrMessage)
+	var p problem.Problem
+	assert.True(t, errors.As(err, &p))
+	assert.Equal(t, problem.CodeOrgHasUsers, p.Code)
+	assert.Equal(t, float64(3), p.Extensions["num_users"])
+	assert.True(t, problem.HasCode(err, problem.CodeOrgHasUsers))
+}
+
+func TestNewHTTPError_JSONRespIsNotProblem(t *testing.T) {
+	statusCode := 400
+	jsonStr := `{"message": "unit-test err message", "code": "foo"}`
+	err := newHTTPError(statusCode, "application/json", io.NopCloser(strings.NewReader(jsonStr)))
+	var httpErr HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, "unit-test err message", httpErr.ErrMessage)
+	assert.Nil(t, httpErr.Problem)
+	var p problem.Problem
+	assert.False(t, errors.As(err, &p))
+}
+
 func TestIsSuccess(t *testing.T) {
 	assert.True(t, isSuccess(200))
 	assert.True(t, isSuccess(201))
@@ -131,6 +161,16 @@ func TestIsSuccess(t *testing.T) {
 	assert.False(t, isSuccess(504))
 }
 
+func TestIsJSON(t *testing.T) {
+	assert.True(t, isJSON("application/json"))
+	assert.True(t, isJSON("application/json; charset=utf-8"))
+	assert.True(t, isJSON("application/merge-patch+json"))
+	assert.True(t, isJSON("application/json-patch+json"))
+	assert.False(t, isJSON("text/plain"))
+	assert.False(t, isJSON("text/csv"))
+	assert.False(t, isJSON(""))
+}
+
 func TestRenderPath(t *testing.T) {
 	inputPath := "/foo/:fooID/bar/:barID/baz/:bazID"
 	pathParams := map[string]string{
@@ -239,7 +279,8 @@ func TestRetrieveStrWithContext_TimeoutErr(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 		w.Write([]byte(mockResp))
 	})
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	defer cancel()
 	var s string
 	_, err := client.Get(server.URL, map[string]string{}).
 		RetrieveStrWithContext(ctx, &s)
@@ -301,7 +342,8 @@ func TestRetrieveWithContext_TimeoutErr(t *testing.T) {
 		time.Sleep(10 * time.Millisecond)
 		w.Write([]byte(mockResp))
 	})
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	defer cancel()
 	var r Payload
 	_, err := client.Get(server.URL, map[string]string{}).
 		RetrieveWithContext(ctx, &r)
@@ -506,6 +548,32 @@ func TestPut(t *testing.T) {
 	assert.Equal(t, mockResp, s)
 }
 
+func TestPatch(t *testing.T) {
+	path := "/api/foo"
+	mockResp := `{"foo":"bar"}`
+	client, server := initClient(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"foo":"foobar"}`), b)
+		assert.Equal(t, path, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, "application/merge-patch+json", r.Header.Get("content-type"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(mockResp))
+	})
+	var out Payload
+	statusCode, err := client.Patch(server.URL+path, map[string]string{}).
+		WithAccept("application/json").
+		WithContentType("application/merge-patch+json").
+		WithBody(Payload{Foo: "foobar"}).
+		Retrieve(&out)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, statusCode)
+	assert.Equal(t, "bar", out.Foo)
+}
+
 func TestDelete(t *testing.T) {
 	path := "/api/foo"
 	mockResp := `{"foo":"bar"}`
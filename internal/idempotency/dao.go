@@ -0,0 +1,165 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/jmoiron/sqlx"
+)
+
+type dao struct {
+	log     *slog.Logger
+	timeout time.Duration
+	db      *sqlx.DB
+}
+
+func NewDAO(log *slog.Logger, timeout time.Duration, db *sqlx.DB) *dao {
+	return &dao{
+		log:     log.With(logutil.LogAttrSVC("IdempotencyDAO")),
+		timeout: timeout,
+		db:      db,
+	}
+}
+
+// Reserve claims the key for the request, it's false when the key is already
+// in use (handled or still being handled) and hasn't expired.
+func (d dao) Reserve(ctx context.Context, sr StoredResponse) (reserved bool, err error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyDAO.Reserve")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Reserve"),
+		logAttrUserID(sr.UserID),
+		logAttrKey(sr.Key),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("reserveQuery"))
+	r, err := d.db.NamedExecContext(ctx, reserveQuery, &sr)
+	if err != nil {
+		return false, err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if numRows > 1 {
+		return false, fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	reserved = numRows == 1
+	log.With(logAttrReserved(reserved)).Debug("success")
+	return reserved, err
+}
+
+func (d dao) Get(ctx context.Context, userID string, key string) (sr StoredResponse, err error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyDAO.Get")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Get"),
+		logAttrUserID(userID),
+		logAttrKey(key),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getQuery"))
+	err = d.db.GetContext(ctx, &sr, getQuery, userID, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sr, ErrNotFound{Key: key}
+		}
+		return sr, err
+	}
+	log.Debug("success")
+	return sr, err
+}
+
+// Complete saves the response for a key this request reserved.
+func (d dao) Complete(ctx context.Context, sr StoredResponse) (err error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyDAO.Complete")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Complete"),
+		logAttrUserID(sr.UserID),
+		logAttrKey(sr.Key),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("completeQuery"))
+	r, err := d.db.NamedExecContext(ctx, completeQuery, &sr)
+	if err != nil {
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows == 0 {
+		return ErrNotFound{Key: sr.Key}
+	}
+	log.Debug("success")
+	return err
+}
+
+// Delete releases a key this request reserved, so a retry runs again.
+func (d dao) Delete(ctx context.Context, userID string, key string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyDAO.Delete")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Delete"),
+		logAttrUserID(userID),
+		logAttrKey(key),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("deleteQuery"))
+	_, err = d.db.ExecContext(ctx, deleteQuery, userID, key)
+	if err != nil {
+		return err
+	}
+	log.Debug("success")
+	return err
+}
+
+// Purge deletes the keys that expired before the cutoff.
+func (d dao) Purge(ctx context.Context, before time.Time) (numRows int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyDAO.Purge")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Purge"),
+		logAttrBefore(before),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("purgeQuery"))
+	r, err := d.db.ExecContext(ctx, purgeQuery, before)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err = r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	log.With(logAttrNumRows(numRows)).Debug("success")
+	return numRows, err
+}
@@ -0,0 +1,77 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+)
+
+const daoName = "IdempotencyDAO"
+
+type DAOMetrics interface {
+	ObserveQuery(dao string, method string, d time.Duration, errClass string)
+}
+
+// instrumentedDAO records the duration and error class of every
+// IdempotencyDAO call.
+type instrumentedDAO struct {
+	dao     IdempotencyDAO
+	metrics DAOMetrics
+}
+
+func NewInstrumentedDAO(dao IdempotencyDAO, metrics DAOMetrics) *instrumentedDAO {
+	return &instrumentedDAO{
+		dao:     dao,
+		metrics: metrics,
+	}
+}
+
+func (d instrumentedDAO) observe(method string, start time.Time, err error) {
+	d.metrics.ObserveQuery(daoName, method, time.Since(start), errClass(err))
+}
+
+func (d instrumentedDAO) Reserve(ctx context.Context, sr StoredResponse) (reserved bool, err error) {
+	start := time.Now()
+	reserved, err = d.dao.Reserve(ctx, sr)
+	d.observe("Reserve", start, err)
+	return reserved, err
+}
+
+func (d instrumentedDAO) Get(ctx context.Context, userID string, key string) (sr StoredResponse, err error) {
+	start := time.Now()
+	sr, err = d.dao.Get(ctx, userID, key)
+	d.observe("Get", start, err)
+	return sr, err
+}
+
+func (d instrumentedDAO) Complete(ctx context.Context, sr StoredResponse) (err error) {
+	start := time.Now()
+	err = d.dao.Complete(ctx, sr)
+	d.observe("Complete", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Delete(ctx context.Context, userID string, key string) (err error) {
+	start := time.Now()
+	err = d.dao.Delete(ctx, userID, key)
+	d.observe("Delete", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Purge(ctx context.Context, before time.Time) (numRows int64, err error) {
+	start := time.Now()
+	numRows, err = d.dao.Purge(ctx, before)
+	d.observe("Purge", start, err)
+	return numRows, err
+}
+
+func errClass(err error) string {
+	switch {
+	case errors.As(err, &ErrNotFound{}):
+		return "not_found"
+	default:
+		return metrics.ErrClass(err)
+	}
+}
@@ -0,0 +1,59 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAOMetrics struct {
+	mock.Mock
+}
+
+func (m *mockDAOMetrics) ObserveQuery(dao string, method string, d time.Duration, errClass string) {
+	m.Called(dao, method, d, errClass)
+}
+
+func initInstrumentedDAO() (d *instrumentedDAO, md *mockDAO, mm *mockDAOMetrics) {
+	md = new(mockDAO)
+	mm = new(mockDAOMetrics)
+	d = NewInstrumentedDAO(md, mm)
+	return d, md, mm
+}
+
+func TestInstrumentedDAO_Reserve(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	md.On("Reserve", ctx, reserved()).Return(true, nil)
+	mm.On("ObserveQuery", daoName, "Reserve", mock.AnythingOfType("time.Duration"), "none")
+
+	actual, err := d.Reserve(ctx, reserved())
+
+	assert.Nil(t, err)
+	assert.True(t, actual)
+	mm.AssertExpectations(t)
+}
+
+func assertErrClass(t *testing.T, mockErr error, expected string) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	md.On("Get", ctx, userID, key).Return(StoredResponse{}, mockErr)
+	mm.On("ObserveQuery", daoName, "Get", mock.AnythingOfType("time.Duration"), expected)
+
+	_, err := d.Get(ctx, userID, key)
+
+	assert.Equal(t, mockErr, err)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_NotFound(t *testing.T) {
+	assertErrClass(t, ErrNotFound{Key: key}, "not_found")
+}
+
+func TestInstrumentedDAO_OtherErr(t *testing.T) {
+	assertErrClass(t, errors.New("unit-test mock error"), "other")
+}
@@ -0,0 +1,208 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+func initDAO() (d *dao, md sqlmock.Sqlmock) {
+	log := testutil.GetLogger()
+	db, md, err := sqlmock.New()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to mock db")
+		panic(err)
+	}
+	dbx := sqlx.NewDb(db, "sqlmock")
+	queryTimeout := 30 * time.Second
+	d = NewDAO(log, queryTimeout, dbx)
+	return d, md
+}
+
+func TestDAOReserve(t *testing.T) {
+	d, md := initDAO()
+	sr := reserved()
+
+	md.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs(userID, key, sr.Fingerprint, now, now.Add(ttl)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	actual, err := d.Reserve(ctx, sr)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.True(t, actual)
+}
+
+func TestDAOReserve_InUse(t *testing.T) {
+	d, md := initDAO()
+	sr := reserved()
+
+	md.ExpectExec("INSERT INTO idempotency_keys").
+		WithArgs(userID, key, sr.Fingerprint, now, now.Add(ttl)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	actual, err := d.Reserve(ctx, sr)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.False(t, actual)
+}
+
+func TestDAOReserve_Err(t *testing.T) {
+	d, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectExec("INSERT INTO idempotency_keys").
+		WillReturnError(&mockErr)
+
+	_, err := d.Reserve(ctx, reserved())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOGet(t *testing.T) {
+	d, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getQuery)).
+		WithArgs(userID, key).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"user_id",
+			"key",
+			"fingerprint",
+			"status_code",
+			"content_type",
+			"headers",
+			"body",
+			"created_at",
+			"expires_at",
+		}).AddRow(
+			userID,
+			key,
+			"foo-fingerprint",
+			201,
+			"application/json",
+			[]byte(`{"Etag":["\"1\""]}`),
+			[]byte(body),
+			now,
+			now.Add(ttl),
+		))
+
+	actual, err := d.Get(ctx, userID, key)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	status := 201
+	assert.Equal(t, StoredResponse{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: "foo-fingerprint",
+		StatusCode:  &status,
+		ContentType: "application/json",
+		Headers:     Headers{"Etag": {`"1"`}},
+		Body:        []byte(body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}, actual)
+}
+
+func TestDAOGet_NotFoundErr(t *testing.T) {
+	d, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getQuery)).
+		WithArgs(userID, key).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := d.Get(ctx, userID, key)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrNotFound
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, key, expected.Key)
+}
+
+func TestDAOComplete(t *testing.T) {
+	d, md := initDAO()
+	status := 200
+	sr := reserved()
+	sr.StatusCode = &status
+	sr.ContentType = "application/json"
+	sr.Headers = Headers{"Location": {"/users/foo-id"}}
+	sr.Body = []byte(body)
+
+	md.ExpectExec("UPDATE idempotency_keys").
+		WithArgs(&status, "application/json", `{"Location":["/users/foo-id"]}`, []byte(body), userID, key).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := d.Complete(ctx, sr)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOComplete_NotFoundErr(t *testing.T) {
+	d, md := initDAO()
+
+	md.ExpectExec("UPDATE idempotency_keys").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := d.Complete(ctx, reserved())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrNotFound
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestDAODelete(t *testing.T) {
+	d, md := initDAO()
+
+	md.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+		WithArgs(userID, key).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := d.Delete(ctx, userID, key)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOPurge(t *testing.T) {
+	d, md := initDAO()
+
+	md.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	actual, err := d.Purge(ctx, now)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), actual)
+}
+
+func TestDAOPurge_Err(t *testing.T) {
+	d, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(now).
+		WillReturnError(&mockErr)
+
+	_, err := d.Purge(ctx, now)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
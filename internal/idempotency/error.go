@@ -0,0 +1,63 @@
+package idempotency
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrNotFound struct {
+	Key string
+}
+
+func (err ErrNotFound) Error() string {
+	return fmt.Sprintf("Idempotency key not found: key=%s", err.Key)
+}
+
+type ErrKeyTooLong struct {
+	Len int
+}
+
+func (err ErrKeyTooLong) Error() string {
+	return fmt.Sprintf("Idempotency-Key is too long, the max is %d: len=%d", maxKeyLen, err.Len)
+}
+
+func (err ErrKeyTooLong) Code() string {
+	return problem.CodeIdempotencyKeyTooLong
+}
+
+type ErrBodyTooLarge struct {
+	Limit int64
+}
+
+func (err ErrBodyTooLarge) Error() string {
+	return fmt.Sprintf("Body is too large for a request with an Idempotency-Key: limit=%d", err.Limit)
+}
+
+func (err ErrBodyTooLarge) Code() string {
+	return problem.CodeIdempotencyBodyTooLarge
+}
+
+type ErrKeyReused struct {
+	Key string
+}
+
+func (err ErrKeyReused) Error() string {
+	return fmt.Sprintf("Idempotency-Key was already used for a different request: key=%s", err.Key)
+}
+
+func (err ErrKeyReused) Code() string {
+	return problem.CodeIdempotencyKeyReused
+}
+
+type ErrInProgress struct {
+	Key string
+}
+
+func (err ErrInProgress) Error() string {
+	return fmt.Sprintf("A request with this Idempotency-Key is still being handled, try again later: key=%s", err.Key)
+}
+
+func (err ErrInProgress) Code() string {
+	return problem.CodeIdempotencyKeyInProgress
+}
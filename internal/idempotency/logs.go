@@ -0,0 +1,30 @@
+package idempotency
+
+import (
+	"log/slog"
+	"time"
+)
+
+func logAttrUserID(userID string) slog.Attr {
+	return slog.String("userID", userID)
+}
+
+func logAttrKey(key string) slog.Attr {
+	return slog.String("idempotencyKey", key)
+}
+
+func logAttrStatusCode(statusCode int) slog.Attr {
+	return slog.Int("statusCode", statusCode)
+}
+
+func logAttrReserved(reserved bool) slog.Attr {
+	return slog.Bool("reserved", reserved)
+}
+
+func logAttrBefore(before time.Time) slog.Attr {
+	return slog.Time("before", before)
+}
+
+func logAttrNumRows(numRows int64) slog.Attr {
+	return slog.Int64("numRows", numRows)
+}
@@ -0,0 +1,253 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderKey = "Idempotency-Key"
+	// HeaderReplayed is set on a response that was stored by an earlier
+	// request with the same key
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+const maxKeyLen = 255
+
+// keptHeaders are stored and replayed along with the content type and body,
+// they describe the response. The rest are left to the retry's own request
+// (ex. its X-Request-Id).
+var keptHeaders = []string{"ETag", "Location", "Content-Location", "Last-Modified"}
+
+type IdempotencyDAO interface {
+	Reserve(ctx context.Context, sr StoredResponse) (reserved bool, err error)
+	Get(ctx context.Context, userID string, key string) (StoredResponse, error)
+	Complete(ctx context.Context, sr StoredResponse) error
+	Delete(ctx context.Context, userID string, key string) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Timer interface {
+	Now() time.Time
+}
+
+// handles is true for the methods that change something, a retried PATCH
+// isn't safe either (ex. a json patch that adds to a list).
+func handles(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// Middleware handles a POST, PUT or PATCH with an Idempotency-Key once and replays
+// its response for every retry with the same key until it expires (ttl).
+// Keys are per logged in user so it has to come after the auth middleware.
+// A key that's reused for a different request is a 422 and one that's still
+// being handled is a 409. A 5xx isn't kept, the retry runs again.
+// The body has to be read to fingerprint it and the response is kept in
+// memory until it's stored, so a body larger than maxBodySize is a 413 and a
+// response larger than it isn't kept (routes that stream shouldn't be behind
+// this at all).
+func Middleware(logger *slog.Logger, dao IdempotencyDAO, timer Timer, ttl time.Duration, maxBodySize int64) gin.HandlerFunc {
+	logger = logger.With(logutil.LogAttrSVC("IdempotencyMiddleware"))
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderKey)
+		if key == "" || !handles(c.Request.Method) {
+			return
+		}
+		ctx := c.Request.Context()
+		userID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+		log := logger.With(
+			logutil.LogAttrReqID(ctx),
+			logutil.LogAttrLoggedInUserID(ctx),
+			logutil.LogAttrFN("Middleware"),
+			logAttrKey(key),
+		)
+		log.Debug("called")
+		if len(key) > maxKeyLen {
+			err := ErrKeyTooLong{Len: len(key)}
+			log.With(logutil.LogAttrError(err)).Warn("invalid key")
+			apierr.Abort(c, http.StatusBadRequest, err)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = ErrBodyTooLarge{Limit: tooLarge.Limit}
+			log.With(logutil.LogAttrError(err)).Warn("body too large")
+			apierr.Abort(c, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("failed to read body")
+			apierr.Abort(c, http.StatusBadRequest, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		now := timer.Now()
+		sr := StoredResponse{
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint(c.Request, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+		reserved, err := dao.Reserve(ctx, sr)
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Error("failed to reserve key")
+			apierr.Abort(c, http.StatusInternalServerError, err)
+			return
+		}
+		if !reserved {
+			replay(c, log, dao, sr)
+			return
+		}
+
+		w := &recorder{ResponseWriter: c.Writer, max: maxBodySize}
+		c.Writer = w
+		// the client may be gone by now (ex. that's why it'll retry), the
+		// response still has to be kept or released
+		saveCTX := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := dao.Delete(saveCTX, userID, key); err != nil {
+				log.With(logutil.LogAttrError(err)).Error("failed to release key")
+			}
+		}()
+		c.Next()
+		status := w.Status()
+		if status >= http.StatusInternalServerError {
+			log.With(logAttrStatusCode(status)).Info("not keeping failed response")
+			return
+		}
+		if w.tooLarge {
+			log.With(logAttrStatusCode(status)).Warn("response too large to keep")
+			return
+		}
+		sr.StatusCode = &status
+		sr.ContentType = w.Header().Get("Content-Type")
+		sr.Headers = keep(w.Header())
+		sr.Body = w.body.Bytes()
+		if err := dao.Complete(saveCTX, sr); err != nil {
+			log.With(logutil.LogAttrError(err)).Error("failed to save response")
+			return
+		}
+		completed = true
+		log.With(logAttrStatusCode(status)).Debug("saved response")
+	}
+}
+
+// replay responds with what was stored for the key, as long as it's for the
+// same request and it's done.
+func replay(c *gin.Context, log *slog.Logger, dao IdempotencyDAO, sr StoredResponse) {
+	stored, err := dao.Get(c.Request.Context(), sr.UserID, sr.Key)
+	if err != nil {
+		// released between the reserve and the get, the retry will get it
+		if errors.As(err, &ErrNotFound{}) {
+			err = ErrInProgress{Key: sr.Key}
+			log.With(logutil.LogAttrError(err)).Warn("key was released")
+			apierr.Abort(c, http.StatusConflict, err)
+			return
+		}
+		log.With(logutil.LogAttrError(err)).Error("failed to get key")
+		apierr.Abort(c, http.StatusInternalServerError, err)
+		return
+	}
+	if stored.Fingerprint != sr.Fingerprint {
+		err = ErrKeyReused{Key: sr.Key}
+		log.With(logutil.LogAttrError(err)).Warn("key reused")
+		apierr.Abort(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if stored.StatusCode == nil {
+		err = ErrInProgress{Key: sr.Key}
+		log.With(logutil.LogAttrError(err)).Warn("key in progress")
+		apierr.Abort(c, http.StatusConflict, err)
+		return
+	}
+	log.With(logAttrStatusCode(*stored.StatusCode)).Info("replaying response")
+	for k, vs := range stored.Headers {
+		for _, v := range vs {
+			c.Writer.Header().Add(k, v)
+		}
+	}
+	c.Header(HeaderReplayed, "true")
+	if stored.ContentType == "" {
+		c.AbortWithStatus(*stored.StatusCode)
+		return
+	}
+	c.Abort()
+	c.Data(*stored.StatusCode, stored.ContentType, stored.Body)
+}
+
+// keep is the keptHeaders that are set on h, nil when none are.
+func keep(h http.Header) Headers {
+	var kept Headers
+	for _, k := range keptHeaders {
+		vs := h.Values(k)
+		if len(vs) == 0 {
+			continue
+		}
+		if kept == nil {
+			kept = Headers{}
+		}
+		kept[http.CanonicalHeaderKey(k)] = vs
+	}
+	return kept
+}
+
+// fingerprint is what makes two requests with the same key the same request.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of everything written so it can be stored, up to max
+// bytes, past that it drops the copy and only writes through.
+type recorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	max      int64
+	tooLarge bool
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	if w.keep(len(b)) {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) WriteString(s string) (int, error) {
+	if w.keep(len(s)) {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recorder) keep(n int) bool {
+	if w.tooLarge {
+		return false
+	}
+	if int64(w.body.Len()+n) > w.max {
+		w.tooLarge = true
+		w.body = bytes.Buffer{}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,450 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAO struct {
+	mock.Mock
+}
+
+type mockTimer struct {
+	mock.Mock
+}
+
+var now = time.UnixMilli(1_000_000_000).UTC()
+
+const (
+	ttl         = 24 * time.Hour
+	maxBodySize = 64
+	userID      = "foo-user-id"
+	key         = "foo-key"
+	body        = `{"name":"foo"}`
+)
+
+// initRouter sets the user id the way the auth middleware would, the handler
+// echoes the body back with the status and counts how many times it ran
+// (/users/large responds with more than maxBodySize, a chunk at a time, and
+// /users/created sets headers).
+func initRouter(status int) (r *gin.Engine, md *mockDAO, calls *int) {
+	gin.SetMode(gin.TestMode)
+	md = new(mockDAO)
+	mt := new(mockTimer)
+	mt.On("Now").Return(now)
+	calls = new(int)
+	r = gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), ctxutil.ContextKeyUserID{}, userID)
+		c.Request = c.Request.WithContext(ctx)
+	})
+	r.Use(Middleware(testutil.GetLogger(), md, mt, ttl, maxBodySize))
+	handler := func(c *gin.Context) {
+		*calls++
+		b, _ := io.ReadAll(c.Request.Body)
+		c.Data(status, "application/json", b)
+	}
+	r.POST("/users", handler)
+	r.PUT("/users", handler)
+	r.PATCH("/users", handler)
+	r.DELETE("/users", handler)
+	r.POST("/users/large", func(c *gin.Context) {
+		*calls++
+		c.Status(status)
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString(strings.Repeat("a", maxBodySize/2))
+		}
+	})
+	r.POST("/users/created", func(c *gin.Context) {
+		*calls++
+		c.Header("Location", "/users/first-id")
+		c.Header("ETag", `"1"`)
+		c.Header("X-Request-Id", "first-req-id")
+		c.Data(status, "application/json", []byte(`{"id":"first-id"}`))
+	})
+	return r, md, calls
+}
+
+func request(method string, key string, b string) *http.Request {
+	req := httptest.NewRequest(method, "/users", strings.NewReader(b))
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	return req
+}
+
+func reserved() StoredResponse {
+	return StoredResponse{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint(request(http.MethodPost, key, body), []byte(body)),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
+
+func TestMiddleware_NoKey(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, request(http.MethodPost, "", body))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 1, *calls)
+	md.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_NotChange(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, request(http.MethodDelete, key, body))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 1, *calls)
+	md.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_KeyTooLong(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, request(http.MethodPost, strings.Repeat("a", maxKeyLen+1), body))
+
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, 0, *calls)
+	md.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_BodyTooLarge(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, request(http.MethodPost, key, strings.Repeat("a", maxBodySize+1)))
+
+	assert.Equal(t, 413, w.Code)
+	assert.Contains(t, w.Body.String(), problem.CodeIdempotencyBodyTooLarge)
+	assert.Equal(t, 0, *calls)
+	md.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_BodyAtMax(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	b := strings.Repeat("a", maxBodySize)
+	md.On("Reserve", mock.Anything, mock.Anything).Return(true, nil)
+	md.On("Complete", mock.Anything, mock.MatchedBy(func(sr StoredResponse) bool {
+		return string(sr.Body) == b
+	})).Return(nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, b))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, b, w.Body.String())
+	assert.Equal(t, 1, *calls)
+	md.AssertExpectations(t)
+}
+
+func TestMiddleware_ReleasesResponseTooLarge(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users/large", strings.NewReader(body))
+	req.Header.Set(HeaderKey, key)
+	md.On("Reserve", mock.Anything, mock.Anything).Return(true, nil)
+	md.On("Delete", mock.Anything, userID, key).Return(nil)
+
+	r.ServeHTTP(w, req)
+
+	// the whole response still goes out, it just isn't kept
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, strings.Repeat("a", maxBodySize/2*3), w.Body.String())
+	assert.Equal(t, 1, *calls)
+	md.AssertExpectations(t)
+	md.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_FirstRequest(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	status := 200
+	completed := reserved()
+	completed.StatusCode = &status
+	completed.ContentType = "application/json"
+	completed.Body = []byte(body)
+	md.On("Reserve", mock.Anything, reserved()).Return(true, nil)
+	md.On("Complete", mock.Anything, completed).Return(nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, "", w.Header().Get(HeaderReplayed))
+	assert.Equal(t, 1, *calls)
+	md.AssertExpectations(t)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_Patch(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	req := request(http.MethodPatch, key, body)
+	patched := reserved()
+	patched.Fingerprint = fingerprint(req, []byte(body))
+	md.On("Reserve", mock.Anything, patched).Return(true, nil)
+	md.On("Complete", mock.Anything, mock.Anything).Return(nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, 1, *calls)
+	md.AssertExpectations(t)
+}
+
+func TestMiddleware_KeepsClientErr(t *testing.T) {
+	r, md, calls := initRouter(409)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(true, nil)
+	md.On("Complete", mock.Anything, mock.MatchedBy(func(sr StoredResponse) bool {
+		return *sr.StatusCode == 409
+	})).Return(nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 409, w.Code)
+	assert.Equal(t, 1, *calls)
+	md.AssertExpectations(t)
+}
+
+func TestMiddleware_ReleasesServerErr(t *testing.T) {
+	r, md, calls := initRouter(500)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(true, nil)
+	md.On("Delete", mock.Anything, userID, key).Return(nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, 1, *calls)
+	md.AssertExpectations(t)
+	md.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+}
+
+func TestMiddleware_ReleasesWhenCompleteFails(t *testing.T) {
+	r, md, _ := initRouter(200)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(true, nil)
+	md.On("Complete", mock.Anything, mock.Anything).Return(errors.New("unit-test mock error"))
+	md.On("Delete", mock.Anything, userID, key).Return(nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	// the handler's response already went out
+	assert.Equal(t, 200, w.Code)
+	md.AssertExpectations(t)
+}
+
+func TestMiddleware_Replay(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	status := 200
+	stored := reserved()
+	stored.StatusCode = &status
+	stored.ContentType = "application/json"
+	stored.Body = []byte(`{"id":"first-id"}`)
+	md.On("Reserve", mock.Anything, reserved()).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(stored, nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `{"id":"first-id"}`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "true", w.Header().Get(HeaderReplayed))
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_KeepsHeaders(t *testing.T) {
+	r, md, _ := initRouter(201)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users/created", strings.NewReader(body))
+	req.Header.Set(HeaderKey, key)
+	md.On("Reserve", mock.Anything, mock.Anything).Return(true, nil)
+	md.On("Complete", mock.Anything, mock.Anything).Return(nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 201, w.Code)
+	completed := md.Calls[1].Arguments.Get(1).(StoredResponse)
+	// the request id is the first request's, the retry has its own
+	assert.Equal(t, Headers{"Location": {"/users/first-id"}, "Etag": {`"1"`}}, completed.Headers)
+}
+
+func TestMiddleware_ReplayHeaders(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	status := 201
+	stored := reserved()
+	stored.StatusCode = &status
+	stored.ContentType = "application/json"
+	stored.Headers = Headers{"Location": {"/users/first-id"}, "Etag": {`"1"`}}
+	stored.Body = []byte(`{"id":"first-id"}`)
+	md.On("Reserve", mock.Anything, reserved()).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(stored, nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "/users/first-id", w.Header().Get("Location"))
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "true", w.Header().Get(HeaderReplayed))
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_ReplayNoContent(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	status := 204
+	stored := reserved()
+	stored.StatusCode = &status
+	md.On("Reserve", mock.Anything, reserved()).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(stored, nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "", w.Body.String())
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_KeyReused(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	status := 200
+	stored := reserved()
+	stored.Fingerprint = "some-other-request"
+	stored.StatusCode = &status
+	md.On("Reserve", mock.Anything, mock.Anything).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(stored, nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, `{"name":"bar"}`))
+
+	assert.Equal(t, 422, w.Code)
+	assert.Contains(t, w.Body.String(), ErrKeyReused{Key: key}.Error())
+	assert.Contains(t, w.Body.String(), `"code":"idempotency_key_reused"`)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_KeyReusedOtherMethod(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	status := 200
+	stored := reserved()
+	stored.StatusCode = &status
+	md.On("Reserve", mock.Anything, mock.Anything).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(stored, nil)
+
+	r.ServeHTTP(w, request(http.MethodPut, key, body))
+
+	assert.Equal(t, 422, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(reserved(), nil)
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 409, w.Code)
+	assert.Contains(t, w.Body.String(), ErrInProgress{Key: key}.Error())
+	assert.Contains(t, w.Body.String(), `"code":"idempotency_key_in_progress"`)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_ReleasedBeforeGet(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(StoredResponse{}, ErrNotFound{Key: key})
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 409, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_ReserveErr(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(false, errors.New("unit-test mock error"))
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestMiddleware_GetErr(t *testing.T) {
+	r, md, calls := initRouter(200)
+	w := httptest.NewRecorder()
+	md.On("Reserve", mock.Anything, reserved()).Return(false, nil)
+	md.On("Get", mock.Anything, userID, key).Return(StoredResponse{}, errors.New("unit-test mock error"))
+
+	r.ServeHTTP(w, request(http.MethodPost, key, body))
+
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestFingerprint(t *testing.T) {
+	a := fingerprint(request(http.MethodPost, key, body), []byte(body))
+
+	assert.Equal(t, a, fingerprint(request(http.MethodPost, "other-key", body), []byte(body)))
+	assert.NotEqual(t, a, fingerprint(request(http.MethodPost, key, body), []byte(`{"name":"bar"}`)))
+	assert.NotEqual(t, a, fingerprint(httptest.NewRequest(http.MethodPost, "/orgs", nil), []byte(body)))
+}
+
+func (d *mockDAO) Reserve(ctx context.Context, sr StoredResponse) (bool, error) {
+	args := d.Called(ctx, sr)
+	return args.Bool(0), args.Error(1)
+}
+
+func (d *mockDAO) Get(ctx context.Context, userID string, key string) (StoredResponse, error) {
+	args := d.Called(ctx, userID, key)
+	return args.Get(0).(StoredResponse), args.Error(1)
+}
+
+func (d *mockDAO) Complete(ctx context.Context, sr StoredResponse) error {
+	args := d.Called(ctx, sr)
+	return args.Error(0)
+}
+
+func (d *mockDAO) Delete(ctx context.Context, userID string, key string) error {
+	args := d.Called(ctx, userID, key)
+	return args.Error(0)
+}
+
+func (d *mockDAO) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := d.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (t *mockTimer) Now() time.Time {
+	args := t.Called()
+	return args.Get(0).(time.Time)
+}
@@ -0,0 +1,52 @@
+package idempotency
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StoredResponse is what's kept for a key, StatusCode is nil until the first
+// request with the key has been handled.
+type StoredResponse struct {
+	UserID      string    `db:"user_id"`
+	Key         string    `db:"key"`
+	Fingerprint string    `db:"fingerprint"`
+	StatusCode  *int      `db:"status_code"`
+	ContentType string    `db:"content_type"`
+	Headers     Headers   `db:"headers"`
+	Body        []byte    `db:"body"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// Headers are the response headers that are replayed with the body (see
+// keptHeaders).
+type Headers map[string][]string
+
+func (h Headers) Value() (driver.Value, error) {
+	if h == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	// lib/pq sends []byte as bytea, jsonb needs it as text
+	return string(b), nil
+}
+
+func (h *Headers) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	case nil:
+		*h = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into idempotency.Headers", src)
+	}
+}
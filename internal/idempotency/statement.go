@@ -0,0 +1,65 @@
+package idempotency
+
+// reserveQuery only takes over an existing key once it has expired, no row is
+// affected while it's still in use.
+const reserveQuery = `
+	INSERT INTO idempotency_keys (
+		user_id,
+		key,
+		fingerprint,
+		created_at,
+		expires_at
+	) VALUES (
+		:user_id,
+		:key,
+		:fingerprint,
+		:created_at,
+		:expires_at
+	)
+	ON CONFLICT (user_id, key) DO UPDATE SET
+		fingerprint = EXCLUDED.fingerprint,
+		status_code = NULL,
+		content_type = '',
+		headers = '{}',
+		body = NULL,
+		created_at = EXCLUDED.created_at,
+		expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+`
+
+const getQuery = `
+	SELECT
+		user_id,
+		key,
+		fingerprint,
+		status_code,
+		content_type,
+		headers,
+		body,
+		created_at,
+		expires_at
+	FROM idempotency_keys
+	WHERE user_id = $1
+	AND key = $2
+`
+
+const completeQuery = `
+	UPDATE idempotency_keys SET
+		status_code = :status_code,
+		content_type = :content_type,
+		headers = :headers,
+		body = :body
+	WHERE user_id = :user_id
+	AND key = :key
+`
+
+const deleteQuery = `
+	DELETE FROM idempotency_keys
+	WHERE user_id = $1
+	AND key = $2
+`
+
+const purgeQuery = `
+	DELETE FROM idempotency_keys
+	WHERE expires_at < $1
+`
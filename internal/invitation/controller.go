@@ -0,0 +1,156 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	pkguser "github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/gin-gonic/gin"
+)
+
+type InvitationService interface {
+	GetByOrgID(ctx context.Context, orgID string, status string, pr page.Request) (invitation.InvitationPage, error)
+	Create(ctx context.Context, orgID string, ci invitation.CreateInvitation) (invitation.Invitation, error)
+	Resend(ctx context.Context, id string) (invitation.Invitation, error)
+	Revoke(ctx context.Context, id string) error
+	Accept(ctx context.Context, ai invitation.AcceptInvitation) (pkguser.User, error)
+}
+
+type ctrl struct {
+	log     *slog.Logger
+	service InvitationService
+}
+
+func NewController(log *slog.Logger, service InvitationService) *ctrl {
+	return &ctrl{
+		log:     log.With(logutil.LogAttrSVC("InvitationCTL")),
+		service: service,
+	}
+}
+
+func (ctr ctrl) GetByOrgID(c *gin.Context) {
+	ctx := c.Request.Context()
+	orgID := c.Param("id")
+	status := c.Query("status")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByOrgID"),
+		logAttrOrgID(orgID),
+		logAttrStatus(status),
+	)
+	log.Debug("called")
+	pr, err := page.ParseRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	ip, err := ctr.service.GetByOrgID(ctx, orgID, status, pr)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrInvitationsLen(len(ip.Invitations))).Debug("success")
+	c.JSON(http.StatusOK, ip)
+}
+
+func (ctr ctrl) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	orgID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrOrgID(orgID),
+	)
+	log.Debug("called")
+	var input invitation.CreateInvitation
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrInvitation(input))
+	log.Debug("body processed, about to call service")
+	i, err := ctr.service.Create(ctx, orgID, input)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.JSON(http.StatusOK, i)
+}
+
+func (ctr ctrl) Resend(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Resend"),
+		logAttrInvitationID(id),
+	)
+	log.Debug("called")
+	i, err := ctr.service.Resend(ctx, id)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.JSON(http.StatusOK, i)
+}
+
+func (ctr ctrl) Revoke(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Revoke"),
+		logAttrInvitationID(id),
+	)
+	log.Debug("called")
+	err := ctr.service.Revoke(ctx, id)
+	if err != nil {
+		if errors.As(err, &ErrNotFound{}) {
+			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not revoking")
+			c.Status(http.StatusNoContent)
+			return
+		}
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.Status(http.StatusNoContent)
+}
+
+// Accept is the one route without a jwt, the body is never logged since the
+// token is in it.
+func (ctr ctrl) Accept(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Accept"),
+	)
+	log.Debug("called")
+	var input invitation.AcceptInvitation
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	u, err := ctr.service.Accept(ctx, input)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrUserID(u.ID)).Debug("success")
+	c.JSON(http.StatusOK, u)
+}
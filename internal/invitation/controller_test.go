@@ -0,0 +1,346 @@
+package invitation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/user"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	pkguser "github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSVC struct {
+	mock.Mock
+}
+
+func initCTRL() (c *ctrl, ms *mockSVC) {
+	log := testutil.GetLogger()
+	ms = new(mockSVC)
+	c = NewController(log, ms)
+	return c, ms
+}
+
+func ginCtx(url string, body string, params ...gin.Param) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	gc, _ := gin.CreateTestContext(w)
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	if err != nil {
+		return nil, w, err
+	}
+	gc.Request = req
+	gc.Params = params
+	return gc, w, nil
+}
+
+func TestCTRLGetByOrgID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?limit=1&status=pending", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	mockRes := invitation.InvitationPage{
+		Invitations: []invitation.Invitation{{ID: "foo-id", OrgID: "foo-org-id", Status: invitation.StatusPending, CreatedAt: time.UnixMilli(100).UTC()}},
+		NextCursor:  "next",
+	}
+	ms.On("GetByOrgID", mock.Anything, "foo-org-id", invitation.StatusPending, page.Request{Limit: 1}).Return(mockRes, nil)
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual invitation.InvitationPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLGetByOrgID_InvalidLimit(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?limit=abc", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "GetByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLGetByOrgID_InvalidStatus(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?status=bogus", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	ms.On("GetByOrgID", mock.Anything, "foo-org-id", "bogus", mock.Anything).Return(invitation.InvitationPage{}, ErrInvalidStatus{Status: "bogus"})
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestCTRLGetByOrgID_Forbidden(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	ms.On("GetByOrgID", mock.Anything, "foo-org-id", "", mock.Anything).Return(invitation.InvitationPage{}, authz.ErrForbidden{})
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 403, w.Code)
+}
+
+func TestCTRLCreate(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"email": "foo@bar.com", "role": "org_admin"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	input := invitation.CreateInvitation{Email: "foo@bar.com", Role: invitation.RoleOrgAdmin}
+	mockRes := invitation.Invitation{ID: "foo-id", OrgID: "foo-org-id", Email: "foo@bar.com", Role: invitation.RoleOrgAdmin, Status: invitation.StatusPending}
+	ms.On("Create", mock.Anything, "foo-org-id", input).Return(mockRes, nil)
+
+	c.Create(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual invitation.Invitation
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLCreate_InvalidEmail(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"email": "foo"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	c.Create(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLCreate_InvalidRole(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"email": "foo@bar.com", "role": "owner"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	c.Create(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func assertCreateErrStatus(t *testing.T, mockErr error, expected int) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"email": "foo@bar.com"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	ms.On("Create", mock.Anything, "foo-org-id", mock.Anything).Return(invitation.Invitation{}, mockErr)
+
+	c.Create(gc)
+
+	assert.Equal(t, expected, w.Code)
+	assert.Contains(t, w.Body.String(), mockErr.Error())
+}
+
+func TestCTRLCreate_OrgNotFound(t *testing.T) {
+	assertCreateErrStatus(t, org.ErrNotFound{ID: "foo-org-id"}, 404)
+}
+
+func TestCTRLCreate_AlreadyInvited(t *testing.T) {
+	assertCreateErrStatus(t, ErrAlreadyInvited{OrgID: "foo-org-id", Email: "foo@bar.com"}, 409)
+}
+
+func TestCTRLCreate_SysOrg(t *testing.T) {
+	assertCreateErrStatus(t, ErrCannotInviteToSysOrg{OrgID: "foo-org-id"}, 403)
+}
+
+func TestCTRLCreate_Forbidden(t *testing.T) {
+	assertCreateErrStatus(t, authz.ErrForbidden{UserID: "logged-in-user-id", Action: "invitation:create"}, 403)
+}
+
+func TestCTRLCreate_OtherErr(t *testing.T) {
+	assertCreateErrStatus(t, errors.New("unit-test mock error"), 500)
+}
+
+func TestCTRLResend(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	mockRes := invitation.Invitation{ID: "foo-id", OrgID: "foo-org-id", Status: invitation.StatusPending}
+	ms.On("Resend", mock.Anything, "foo-id").Return(mockRes, nil)
+
+	c.Resend(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual invitation.Invitation
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLResend_NotFound(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	ms.On("Resend", mock.Anything, "foo-id").Return(invitation.Invitation{}, ErrNotFound{ID: "foo-id"})
+
+	c.Resend(gc)
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestCTRLResend_NotPending(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	ms.On("Resend", mock.Anything, "foo-id").Return(invitation.Invitation{}, ErrNotPending{ID: "foo-id", Status: invitation.StatusAccepted})
+
+	c.Resend(gc)
+
+	assert.Equal(t, 409, w.Code)
+}
+
+func TestCTRLRevoke(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	ms.On("Revoke", mock.Anything, "foo-id").Return(nil)
+
+	c.Revoke(gc)
+
+	assert.Equal(t, 204, gc.Writer.Status())
+}
+
+func TestCTRLRevoke_AlreadyGone(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	ms.On("Revoke", mock.Anything, "foo-id").Return(ErrNotFound{ID: "foo-id"})
+
+	c.Revoke(gc)
+
+	assert.Equal(t, 204, gc.Writer.Status())
+}
+
+func TestCTRLRevoke_Accepted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	ms.On("Revoke", mock.Anything, "foo-id").Return(ErrNotPending{ID: "foo-id", Status: invitation.StatusAccepted})
+
+	c.Revoke(gc)
+
+	assert.Equal(t, 409, w.Code)
+}
+
+func TestCTRLRevoke_Forbidden(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-id"})
+	assert.Nil(t, err)
+
+	ms.On("Revoke", mock.Anything, "foo-id").Return(authz.ErrForbidden{})
+
+	c.Revoke(gc)
+
+	assert.Equal(t, 403, w.Code)
+}
+
+func TestCTRLAccept(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"token": "foo-token", "name": "Foo"}`)
+	assert.Nil(t, err)
+
+	mockRes := pkguser.User{ID: "foo-user-id", OrgID: "foo-org-id", Name: "Foo", Email: "foo@bar.com", IsActive: true}
+	ms.On("Accept", mock.Anything, invitation.AcceptInvitation{Token: "foo-token", Name: "Foo"}).Return(mockRes, nil)
+
+	c.Accept(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual pkguser.User
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLAccept_MissingToken(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"name": "Foo"}`)
+	assert.Nil(t, err)
+
+	c.Accept(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "Accept", mock.Anything, mock.Anything)
+}
+
+func assertAcceptErrStatus(t *testing.T, mockErr error, expected int) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"token": "foo-token"}`)
+	assert.Nil(t, err)
+
+	ms.On("Accept", mock.Anything, mock.Anything).Return(pkguser.User{}, mockErr)
+
+	c.Accept(gc)
+
+	assert.Equal(t, expected, w.Code)
+	assert.Contains(t, w.Body.String(), mockErr.Error())
+}
+
+func TestCTRLAccept_InvalidToken(t *testing.T) {
+	assertAcceptErrStatus(t, ErrInvalidToken{}, 404)
+}
+
+func TestCTRLAccept_CannotAccept(t *testing.T) {
+	assertAcceptErrStatus(t, ErrCannotAccept{ID: "foo-id", Status: invitation.StatusExpired}, 410)
+}
+
+func TestCTRLAccept_NameRequired(t *testing.T) {
+	assertAcceptErrStatus(t, user.ErrNameRequired{Email: "foo@bar.com"}, 400)
+}
+
+func TestCTRLAccept_EmailInUse(t *testing.T) {
+	assertAcceptErrStatus(t, user.ErrEmailAlreadyInUse{Email: "foo@bar.com"}, 409)
+}
+
+func TestCTRLAccept_OtherErr(t *testing.T) {
+	assertAcceptErrStatus(t, errors.New("unit-test mock error"), 500)
+}
+
+func (s *mockSVC) GetByOrgID(ctx context.Context, orgID string, status string, pr page.Request) (invitation.InvitationPage, error) {
+	args := s.Called(ctx, orgID, status, pr)
+	return args.Get(0).(invitation.InvitationPage), args.Error(1)
+}
+
+func (s *mockSVC) Create(ctx context.Context, orgID string, ci invitation.CreateInvitation) (invitation.Invitation, error) {
+	args := s.Called(ctx, orgID, ci)
+	return args.Get(0).(invitation.Invitation), args.Error(1)
+}
+
+func (s *mockSVC) Resend(ctx context.Context, id string) (invitation.Invitation, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).(invitation.Invitation), args.Error(1)
+}
+
+func (s *mockSVC) Revoke(ctx context.Context, id string) error {
+	args := s.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (s *mockSVC) Accept(ctx context.Context, ai invitation.AcceptInvitation) (pkguser.User, error) {
+	args := s.Called(ctx, ai)
+	return args.Get(0).(pkguser.User), args.Error(1)
+}
@@ -0,0 +1,179 @@
+package invitation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type dao struct {
+	log     *slog.Logger
+	timeout time.Duration
+	db      *sqlx.DB
+}
+
+func NewDAO(log *slog.Logger, timeout time.Duration, db *sqlx.DB) *dao {
+	return &dao{
+		log:     log.With(logutil.LogAttrSVC("InvitationDAO")),
+		timeout: timeout,
+		db:      db,
+	}
+}
+
+// GetByOrgID works out each invitation's status as of now, an empty status
+// returns them all.
+func (d dao) GetByOrgID(ctx context.Context, orgID string, status string, now time.Time, after *page.Cursor, limit int) (invitations []invitation.Invitation, err error) {
+	ctx, span := tracing.StartDB(ctx, "InvitationDAO.GetByOrgID")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByOrgID"),
+		logAttrOrgID(orgID),
+		logAttrStatus(status),
+		logAttrAfter(after),
+		logAttrLimit(limit),
+	)
+	log.Debug("called")
+	invitations = []invitation.Invitation{}
+	if after == nil {
+		span.SetAttributes(tracing.AttrStatement("getByOrgIDQuery"))
+		err = d.db.SelectContext(ctx, &invitations, getByOrgIDQuery, now, orgID, status, limit)
+	} else {
+		span.SetAttributes(tracing.AttrStatement("getByOrgIDAfterQuery"))
+		err = d.db.SelectContext(ctx, &invitations, getByOrgIDAfterQuery, now, orgID, status, after.CreatedAt, after.ID, limit)
+	}
+	if err != nil {
+		return invitations, err
+	}
+	log.With(logAttrInvitationsLen(len(invitations))).Debug("success")
+	return invitations, err
+}
+
+// GetForUpdate returns the invitation and locks it until tx ends.
+func (d dao) GetForUpdate(ctx context.Context, tx *sqlx.Tx, id string, now time.Time) (si StoredInvitation, err error) {
+	ctx, span := tracing.StartDB(ctx, "InvitationDAO.GetForUpdate")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetForUpdate"),
+		logAttrInvitationID(id),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getForUpdateQuery"))
+	err = tx.GetContext(ctx, &si, getForUpdateQuery, now, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return si, ErrNotFound{ID: id}
+		}
+		return si, err
+	}
+	log.Debug("success")
+	return si, err
+}
+
+// GetByTokenHashForUpdate returns the invitation the token was issued for
+// and locks it until tx ends.
+func (d dao) GetByTokenHashForUpdate(ctx context.Context, tx *sqlx.Tx, tokenHash string, now time.Time) (si StoredInvitation, err error) {
+	ctx, span := tracing.StartDB(ctx, "InvitationDAO.GetByTokenHashForUpdate")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByTokenHashForUpdate"),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getByTokenHashForUpdateQuery"))
+	err = tx.GetContext(ctx, &si, getByTokenHashForUpdateQuery, now, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return si, ErrInvalidToken{}
+		}
+		return si, err
+	}
+	log.With(logAttrInvitationID(si.ID)).Debug("success")
+	return si, err
+}
+
+func (d dao) Create(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) (err error) {
+	ctx, span := tracing.StartDB(ctx, "InvitationDAO.Create")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrInvitation(si.Invitation),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("createQuery"))
+	r, err := tx.NamedExecContext(ctx, createQuery, &si)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			if pqErr.Constraint == "invitations_pending_uk" {
+				return ErrAlreadyInvited{OrgID: si.OrgID, Email: si.Email}
+			}
+		}
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows != 1 {
+		return fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	return err
+}
+
+// Update is only called on an invitation locked by GetForUpdate or
+// GetByTokenHashForUpdate, so there's no version to check.
+func (d dao) Update(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) (err error) {
+	ctx, span := tracing.StartDB(ctx, "InvitationDAO.Update")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Update"),
+		logAttrInvitation(si.Invitation),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("updateQuery"))
+	r, err := tx.NamedExecContext(ctx, updateQuery, &si)
+	if err != nil {
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows == 0 {
+		return ErrNotFound{ID: si.ID}
+	}
+	log.Debug("success")
+	return err
+}
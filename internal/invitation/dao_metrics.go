@@ -0,0 +1,82 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	"github.com/jmoiron/sqlx"
+)
+
+const daoName = "InvitationDAO"
+
+type DAOMetrics interface {
+	ObserveQuery(dao string, method string, d time.Duration, errClass string)
+}
+
+// instrumentedDAO records the duration and error class of every
+// InvitationDAO call.
+type instrumentedDAO struct {
+	dao     InvitationDAO
+	metrics DAOMetrics
+}
+
+func NewInstrumentedDAO(dao InvitationDAO, metrics DAOMetrics) *instrumentedDAO {
+	return &instrumentedDAO{
+		dao:     dao,
+		metrics: metrics,
+	}
+}
+
+func (d instrumentedDAO) observe(method string, start time.Time, err error) {
+	d.metrics.ObserveQuery(daoName, method, time.Since(start), errClass(err))
+}
+
+func (d instrumentedDAO) GetByOrgID(ctx context.Context, orgID string, status string, now time.Time, after *page.Cursor, limit int) (invitations []invitation.Invitation, err error) {
+	start := time.Now()
+	invitations, err = d.dao.GetByOrgID(ctx, orgID, status, now, after, limit)
+	d.observe("GetByOrgID", start, err)
+	return invitations, err
+}
+
+func (d instrumentedDAO) GetForUpdate(ctx context.Context, tx *sqlx.Tx, id string, now time.Time) (si StoredInvitation, err error) {
+	start := time.Now()
+	si, err = d.dao.GetForUpdate(ctx, tx, id, now)
+	d.observe("GetForUpdate", start, err)
+	return si, err
+}
+
+func (d instrumentedDAO) GetByTokenHashForUpdate(ctx context.Context, tx *sqlx.Tx, tokenHash string, now time.Time) (si StoredInvitation, err error) {
+	start := time.Now()
+	si, err = d.dao.GetByTokenHashForUpdate(ctx, tx, tokenHash, now)
+	d.observe("GetByTokenHashForUpdate", start, err)
+	return si, err
+}
+
+func (d instrumentedDAO) Create(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) (err error) {
+	start := time.Now()
+	err = d.dao.Create(ctx, tx, si)
+	d.observe("Create", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Update(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) (err error) {
+	start := time.Now()
+	err = d.dao.Update(ctx, tx, si)
+	d.observe("Update", start, err)
+	return err
+}
+
+func errClass(err error) string {
+	switch {
+	case errors.As(err, &ErrNotFound{}), errors.As(err, &ErrInvalidToken{}):
+		return "not_found"
+	case errors.As(err, &ErrAlreadyInvited{}):
+		return "conflict"
+	default:
+		return metrics.ErrClass(err)
+	}
+}
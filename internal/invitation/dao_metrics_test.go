@@ -0,0 +1,69 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAOMetrics struct {
+	mock.Mock
+}
+
+func (m *mockDAOMetrics) ObserveQuery(dao string, method string, d time.Duration, errClass string) {
+	m.Called(dao, method, d, errClass)
+}
+
+func initInstrumentedDAO() (d *instrumentedDAO, md *mockDAO, mm *mockDAOMetrics) {
+	md = new(mockDAO)
+	mm = new(mockDAOMetrics)
+	d = NewInstrumentedDAO(md, mm)
+	return d, md, mm
+}
+
+func TestInstrumentedDAO_GetForUpdate(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	now := time.UnixMilli(300)
+	mockRes := StoredInvitation{TokenHash: "foo-hash"}
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-id", now).Return(mockRes, nil)
+	mm.On("ObserveQuery", daoName, "GetForUpdate", mock.AnythingOfType("time.Duration"), "none")
+
+	actual, err := d.GetForUpdate(ctx, nil, "foo-id", now)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	mm.AssertExpectations(t)
+}
+
+func assertErrClass(t *testing.T, mockErr error, expected string) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(mockErr)
+	mm.On("ObserveQuery", daoName, "Create", mock.AnythingOfType("time.Duration"), expected)
+
+	err := d.Create(ctx, nil, StoredInvitation{})
+
+	assert.Equal(t, mockErr, err)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_NotFound(t *testing.T) {
+	assertErrClass(t, ErrNotFound{ID: "foo-id"}, "not_found")
+}
+
+func TestInstrumentedDAO_InvalidToken(t *testing.T) {
+	assertErrClass(t, ErrInvalidToken{}, "not_found")
+}
+
+func TestInstrumentedDAO_AlreadyInvited(t *testing.T) {
+	assertErrClass(t, ErrAlreadyInvited{OrgID: "foo-org-id", Email: "foo@bar.com"}, "conflict")
+}
+
+func TestInstrumentedDAO_OtherErr(t *testing.T) {
+	assertErrClass(t, errors.New("unit-test mock error"), "other")
+}
@@ -0,0 +1,334 @@
+package invitation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx       = context.Background()
+	now       = time.UnixMilli(300)
+	expiresAt = time.UnixMilli(500)
+	createdAt = time.UnixMilli(100)
+	after     = page.Cursor{
+		CreatedAt: time.UnixMilli(50),
+		ID:        "after-id",
+	}
+)
+
+const (
+	id        = "foo-id"
+	orgID     = "foo-org-id"
+	email     = "foo@bar.com"
+	tokenHash = "foo-hash"
+	createdBy = "creator-id"
+	limit     = 11
+)
+
+func getRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id",
+		"org_id",
+		"email",
+		"role",
+		"status",
+		"expires_at",
+		"created_at",
+		"created_by",
+		"accepted_at",
+		"accepted_by",
+		"revoked_at",
+		"revoked_by",
+	}).AddRow(
+		id,
+		orgID,
+		email,
+		invitation.RoleMember,
+		invitation.StatusPending,
+		expiresAt,
+		createdAt,
+		createdBy,
+		nil,
+		"",
+		nil,
+		"",
+	)
+}
+
+func getStoredRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id",
+		"org_id",
+		"email",
+		"role",
+		"status",
+		"token_hash",
+		"expires_at",
+		"created_at",
+		"created_by",
+		"accepted_at",
+		"accepted_by",
+		"revoked_at",
+		"revoked_by",
+	}).AddRow(
+		id,
+		orgID,
+		email,
+		invitation.RoleMember,
+		invitation.StatusPending,
+		tokenHash,
+		expiresAt,
+		createdAt,
+		createdBy,
+		nil,
+		"",
+		nil,
+		"",
+	)
+}
+
+func expectedInvitation() invitation.Invitation {
+	return invitation.Invitation{
+		ID:        id,
+		OrgID:     orgID,
+		Email:     email,
+		Role:      invitation.RoleMember,
+		Status:    invitation.StatusPending,
+		ExpiresAt: expiresAt,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy,
+	}
+}
+
+func expectedStored() StoredInvitation {
+	return StoredInvitation{
+		Invitation: expectedInvitation(),
+		TokenHash:  tokenHash,
+	}
+}
+
+func initDAO() (d *dao, dbx *sqlx.DB, md sqlmock.Sqlmock) {
+	log := testutil.GetLogger()
+	db, md, err := sqlmock.New()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to mock db")
+		panic(err)
+	}
+	dbx = sqlx.NewDb(db, "sqlmock")
+	queryTimeout := 30 * time.Second
+	d = NewDAO(log, queryTimeout, dbx)
+	return d, dbx, md
+}
+
+func TestDAOGetByOrgID(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByOrgIDQuery)).
+		WithArgs(now, orgID, invitation.StatusPending, limit).
+		WillReturnRows(getRows())
+
+	actual, err := d.GetByOrgID(ctx, orgID, invitation.StatusPending, now, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, []invitation.Invitation{expectedInvitation()}, actual)
+}
+
+func TestDAOGetByOrgID_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByOrgIDAfterQuery)).
+		WithArgs(now, orgID, "", after.CreatedAt, after.ID, limit).
+		WillReturnRows(getRows())
+
+	actual, err := d.GetByOrgID(ctx, orgID, "", now, &after, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, []invitation.Invitation{expectedInvitation()}, actual)
+}
+
+func TestDAOGetByOrgID_Error(t *testing.T) {
+	d, _, md := initDAO()
+
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectQuery(regexp.QuoteMeta(getByOrgIDQuery)).
+		WithArgs(now, orgID, "", limit).
+		WillReturnError(mockErr)
+
+	_, err := d.GetByOrgID(ctx, orgID, "", now, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
+
+func TestDAOGetForUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getForUpdateQuery)).
+		WithArgs(now, id).
+		WillReturnRows(getStoredRows())
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetForUpdate(ctx, tx, id, now)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, expectedStored(), actual)
+}
+
+func TestDAOGetForUpdate_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getForUpdateQuery)).
+		WithArgs(now, id).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetForUpdate(ctx, tx, id, now)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrNotFound{ID: id}, err)
+}
+
+func TestDAOGetByTokenHashForUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByTokenHashForUpdateQuery)).
+		WithArgs(now, tokenHash).
+		WillReturnRows(getStoredRows())
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetByTokenHashForUpdate(ctx, tx, tokenHash, now)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, expectedStored(), actual)
+}
+
+func TestDAOGetByTokenHashForUpdate_InvalidTokenErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByTokenHashForUpdateQuery)).
+		WithArgs(now, tokenHash).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetByTokenHashForUpdate(ctx, tx, tokenHash, now)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrInvalidToken{}, err)
+}
+
+func TestDAOCreate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO invitations").
+		WithArgs(id, orgID, email, invitation.RoleMember, tokenHash, expiresAt, createdAt, createdBy).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, expectedStored())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOCreate_AlreadyInvitedErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error", Constraint: "invitations_pending_uk"}
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO invitations").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, expectedStored())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrAlreadyInvited{OrgID: orgID, Email: email}, err)
+}
+
+func TestDAOCreate_OtherPQErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error", Constraint: "invitations_org_fk"}
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO invitations").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, expectedStored())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	si := expectedStored()
+	si.AcceptedAt = &now
+	si.AcceptedBy = "acceptor-id"
+	var revokedAt *time.Time
+	md.ExpectBegin()
+	md.ExpectExec("UPDATE invitations").
+		WithArgs(tokenHash, expiresAt, &now, "acceptor-id", revokedAt, "", id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Update(ctx, tx, si)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOUpdate_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec("UPDATE invitations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Update(ctx, tx, expectedStored())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrNotFound{ID: id}, err)
+}
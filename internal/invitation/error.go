@@ -0,0 +1,94 @@
+package invitation
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrNotFound struct {
+	ID string
+}
+
+func (err ErrNotFound) Error() string {
+	return fmt.Sprintf("Invitation not found: id=%s", err.ID)
+}
+
+func (err ErrNotFound) Code() string {
+	return problem.CodeInvitationNotFound
+}
+
+// ErrInvalidToken doesn't say anything about the invitation, the token is the
+// only thing the caller has.
+type ErrInvalidToken struct{}
+
+func (err ErrInvalidToken) Error() string {
+	return "Invitation not found for token"
+}
+
+func (err ErrInvalidToken) Code() string {
+	return problem.CodeInvalidInvitationToken
+}
+
+type ErrAlreadyInvited struct {
+	OrgID string
+	Email string
+}
+
+func (err ErrAlreadyInvited) Error() string {
+	return fmt.Sprintf("Email already has an invitation to the org, resend or revoke it instead: orgID=%s email=%s", err.OrgID, err.Email)
+}
+
+func (err ErrAlreadyInvited) Code() string {
+	return problem.CodeAlreadyInvited
+}
+
+type ErrNotPending struct {
+	ID     string
+	Status string
+}
+
+func (err ErrNotPending) Error() string {
+	return fmt.Sprintf("Invitation is no longer pending: id=%s status=%s", err.ID, err.Status)
+}
+
+func (err ErrNotPending) Code() string {
+	return problem.CodeInvitationNotPending
+}
+
+type ErrCannotAccept struct {
+	ID     string
+	Status string
+}
+
+func (err ErrCannotAccept) Error() string {
+	return fmt.Sprintf("Invitation can no longer be accepted: id=%s status=%s", err.ID, err.Status)
+}
+
+func (err ErrCannotAccept) Code() string {
+	return problem.CodeCannotAcceptInvitation
+}
+
+type ErrCannotInviteToSysOrg struct {
+	OrgID string
+}
+
+func (err ErrCannotInviteToSysOrg) Error() string {
+	return fmt.Sprintf("Cannot invite users to the system org: orgID=%s", err.OrgID)
+}
+
+func (err ErrCannotInviteToSysOrg) Code() string {
+	return problem.CodeCannotInviteToSysOrg
+}
+
+type ErrInvalidStatus struct {
+	Status string
+}
+
+func (err ErrInvalidStatus) Error() string {
+	return fmt.Sprintf("Invalid status, must be one of pending, accepted, revoked or expired: status=%s", err.Status)
+}
+
+func (err ErrInvalidStatus) Code() string {
+	return problem.CodeInvalidInvitationState
+}
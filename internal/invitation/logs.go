@@ -0,0 +1,39 @@
+package invitation
+
+import (
+	"log/slog"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+)
+
+func logAttrOrgID(orgID string) slog.Attr {
+	return slog.String("orgID", orgID)
+}
+
+func logAttrUserID(userID string) slog.Attr {
+	return slog.String("userID", userID)
+}
+
+func logAttrInvitationID(id string) slog.Attr {
+	return slog.String("invitationID", id)
+}
+
+func logAttrInvitation(i any) slog.Attr {
+	return slog.Any("invitation", i)
+}
+
+func logAttrStatus(status string) slog.Attr {
+	return slog.String("status", status)
+}
+
+func logAttrInvitationsLen(len int) slog.Attr {
+	return slog.Int("invitationsLen", len)
+}
+
+func logAttrAfter(after *page.Cursor) slog.Attr {
+	return slog.Any("after", after)
+}
+
+func logAttrLimit(limit int) slog.Attr {
+	return slog.Int("limit", limit)
+}
@@ -0,0 +1,425 @@
+package invitation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/membership"
+	"github.com/RyanBard/go-service-ex/internal/notify"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	pkgmembership "github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+)
+
+type InvitationDAO interface {
+	GetByOrgID(ctx context.Context, orgID string, status string, now time.Time, after *page.Cursor, limit int) ([]invitation.Invitation, error)
+	GetForUpdate(ctx context.Context, tx *sqlx.Tx, id string, now time.Time) (StoredInvitation, error)
+	GetByTokenHashForUpdate(ctx context.Context, tx *sqlx.Tx, tokenHash string, now time.Time) (StoredInvitation, error)
+	Create(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) error
+	Update(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) error
+}
+
+type OrgSVC interface {
+	GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error)
+}
+
+type UserSVC interface {
+	SaveInvited(ctx context.Context, joinTX *sqlx.Tx, u user.User) (user.User, error)
+}
+
+type MembershipDAO interface {
+	Create(ctx context.Context, tx *sqlx.Tx, m pkgmembership.Membership) error
+}
+
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error
+}
+
+type TXManager interface {
+	Do(ctx context.Context, tx *sqlx.Tx, f func(*sqlx.Tx) error) error
+}
+
+type Timer interface {
+	Now() time.Time
+}
+
+type IDGenerator interface {
+	GenID() string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, m notify.Message) error
+}
+
+// StoredInvitation is an invitation as it's stored, only a hash of the token
+// is kept.
+type StoredInvitation struct {
+	invitation.Invitation
+	TokenHash string `db:"token_hash"`
+}
+
+type service struct {
+	log           *slog.Logger
+	orgSVC        OrgSVC
+	userSVC       UserSVC
+	membershipDAO MembershipDAO
+	dao           InvitationDAO
+	auditor       Auditor
+	txMGR         TXManager
+	timer         Timer
+	idGen         IDGenerator
+	notifier      Notifier
+	ttl           time.Duration
+}
+
+func NewService(log *slog.Logger, orgSVC OrgSVC, userSVC UserSVC, membershipDAO MembershipDAO, dao InvitationDAO, auditor Auditor, txMGR TXManager, timer Timer, idGen IDGenerator, notifier Notifier, ttl time.Duration) *service {
+	return &service{
+		log:           log.With(logutil.LogAttrSVC("InvitationSVC")),
+		orgSVC:        orgSVC,
+		userSVC:       userSVC,
+		membershipDAO: membershipDAO,
+		dao:           dao,
+		auditor:       auditor,
+		txMGR:         txMGR,
+		timer:         timer,
+		idGen:         idGen,
+		notifier:      notifier,
+		ttl:           ttl,
+	}
+}
+
+var statuses = map[string]bool{
+	invitation.StatusPending:  true,
+	invitation.StatusAccepted: true,
+	invitation.StatusRevoked:  true,
+	invitation.StatusExpired:  true,
+}
+
+// GetByOrgID is only for the admins that manage the org, the invitations
+// have emails of people that aren't in it yet.
+func (s service) GetByOrgID(ctx context.Context, orgID string, status string, pr page.Request) (ip invitation.InvitationPage, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByOrgID"),
+		logAttrOrgID(orgID),
+		logAttrStatus(status),
+		logAttrAfter(pr.After),
+		logAttrLimit(pr.Limit),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return ip, err
+	}
+	if !p.CanManage(orgID) {
+		log.Warn("forbidden")
+		return ip, authz.ErrForbidden{UserID: p.UserID, Action: "invitation:list"}
+	}
+	if status != "" && !statuses[status] {
+		return ip, ErrInvalidStatus{Status: status}
+	}
+	invitations, err := s.dao.GetByOrgID(ctx, orgID, status, s.timer.Now(), pr.After, pr.Limit+1)
+	if err != nil {
+		return ip, err
+	}
+	ip.Invitations, ip.NextCursor = page.Trim(invitations, pr.Limit, toCursor)
+	return ip, nil
+}
+
+func toCursor(i invitation.Invitation) page.Cursor {
+	return page.Cursor{
+		CreatedAt: i.CreatedAt,
+		ID:        i.ID,
+	}
+}
+
+// Create sends a single use token to the email, nothing is created for the
+// user until it's accepted.
+func (s service) Create(ctx context.Context, orgID string, ci invitation.CreateInvitation) (out invitation.Invitation, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrOrgID(orgID),
+		logAttrInvitation(ci),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	if !p.CanManage(orgID) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "invitation:create"}
+	}
+	o, err := s.orgSVC.GetByID(ctx, orgID, false)
+	if err != nil {
+		return out, err
+	}
+	if o.IsSystem {
+		return out, ErrCannotInviteToSysOrg{OrgID: orgID}
+	}
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return out, err
+	}
+	role := ci.Role
+	if role == "" {
+		role = invitation.RoleMember
+	}
+	now := s.timer.Now()
+	stored := StoredInvitation{
+		Invitation: invitation.Invitation{
+			ID:        s.idGen.GenID(),
+			OrgID:     orgID,
+			Email:     ci.Email,
+			Role:      role,
+			Status:    invitation.StatusPending,
+			ExpiresAt: now.Add(s.ttl),
+			CreatedAt: now,
+			CreatedBy: loggedInUserID,
+		},
+		TokenHash: tokenHash,
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		if err := s.dao.Create(ctx, tx, stored); err != nil {
+			return err
+		}
+		if err := s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityInvitation, stored.ID, nil, stored.Invitation); err != nil {
+			return err
+		}
+		return s.notifier.Send(ctx, invitationMessage(stored.Invitation, token))
+	})
+	if err != nil {
+		return invitation.Invitation{}, err
+	}
+	log.Info("invitation created")
+	return stored.Invitation, nil
+}
+
+// Resend issues a new token (the old one stops working) and starts the
+// expiry over, expired invitations can be resent too.
+func (s service) Resend(ctx context.Context, id string) (out invitation.Invitation, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Resend"),
+		logAttrInvitationID(id),
+	)
+	log.Debug("called")
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return out, err
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		now := s.timer.Now()
+		stored, err := s.getForManage(ctx, tx, id, now, "invitation:resend")
+		if err != nil {
+			return err
+		}
+		if stored.Status != invitation.StatusPending && stored.Status != invitation.StatusExpired {
+			return ErrNotPending{ID: id, Status: stored.Status}
+		}
+		resent := stored
+		resent.TokenHash = tokenHash
+		resent.ExpiresAt = now.Add(s.ttl)
+		resent.Status = invitation.StatusPending
+		if err := s.dao.Update(ctx, tx, resent); err != nil {
+			return err
+		}
+		if err := s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityInvitation, id, stored.Invitation, resent.Invitation); err != nil {
+			return err
+		}
+		out = resent.Invitation
+		return s.notifier.Send(ctx, invitationMessage(out, token))
+	})
+	if err != nil {
+		return invitation.Invitation{}, err
+	}
+	log.Info("invitation resent")
+	return out, nil
+}
+
+// Revoke stops the token from being accepted, revoking one that's already
+// revoked does nothing. Accepted invitations can't be revoked, remove the
+// user's membership instead.
+func (s service) Revoke(ctx context.Context, id string) error {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Revoke"),
+		logAttrInvitationID(id),
+	)
+	log.Debug("called")
+	return s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		now := s.timer.Now()
+		stored, err := s.getForManage(ctx, tx, id, now, "invitation:revoke")
+		if err != nil {
+			return err
+		}
+		switch stored.Status {
+		case invitation.StatusRevoked:
+			log.Info("already revoked")
+			return nil
+		case invitation.StatusAccepted:
+			return ErrNotPending{ID: id, Status: stored.Status}
+		}
+		revoked := stored
+		revoked.Status = invitation.StatusRevoked
+		revoked.RevokedAt = &now
+		revoked.RevokedBy = loggedInUserID
+		if err := s.dao.Update(ctx, tx, revoked); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityInvitation, id, stored.Invitation, revoked.Invitation)
+	})
+}
+
+// getForManage locks the invitation and checks the logged in user can manage
+// its org.
+func (s service) getForManage(ctx context.Context, tx *sqlx.Tx, id string, now time.Time, action string) (StoredInvitation, error) {
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return StoredInvitation{}, err
+	}
+	stored, err := s.dao.GetForUpdate(ctx, tx, id, now)
+	if err != nil {
+		return StoredInvitation{}, err
+	}
+	if !p.CanManage(stored.OrgID) {
+		s.log.With(
+			logutil.LogAttrReqID(ctx),
+			logutil.LogAttrLoggedInUserID(ctx),
+			logutil.LogAttrFN("getForManage"),
+			logAttrInvitationID(id),
+		).Warn("forbidden")
+		return StoredInvitation{}, authz.ErrForbidden{UserID: p.UserID, Action: action}
+	}
+	return stored, nil
+}
+
+// Accept doesn't need a logged in user, the token is the credential. The
+// invited user is created (or activated) in the invitation's org, a user that
+// already belongs to another org gets a membership in it instead. The user is
+// recorded as making every change.
+func (s service) Accept(ctx context.Context, ai invitation.AcceptInvitation) (out user.User, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Accept"),
+	)
+	log.Debug("called")
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		now := s.timer.Now()
+		stored, err := s.dao.GetByTokenHashForUpdate(ctx, tx, hashToken(ai.Token), now)
+		if err != nil {
+			return err
+		}
+		if stored.Status != invitation.StatusPending {
+			return ErrCannotAccept{ID: stored.ID, Status: stored.Status}
+		}
+		out, err = s.userSVC.SaveInvited(ctx, tx, user.User{
+			OrgID:   stored.OrgID,
+			Name:    ai.Name,
+			Email:   stored.Email,
+			IsAdmin: stored.Role == invitation.RoleOrgAdmin,
+		})
+		if err != nil {
+			return err
+		}
+		ctx := context.WithValue(ctx, ctxutil.ContextKeyUserID{}, out.ID)
+		if out.OrgID != stored.OrgID {
+			if err := s.addMembership(ctx, tx, stored.Invitation, out.ID, now); err != nil {
+				return err
+			}
+		}
+		accepted := stored
+		accepted.Status = invitation.StatusAccepted
+		accepted.AcceptedAt = &now
+		accepted.AcceptedBy = out.ID
+		if err := s.dao.Update(ctx, tx, accepted); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityInvitation, stored.ID, stored.Invitation, accepted.Invitation)
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+	log.With(logAttrUserID(out.ID)).Info("invitation accepted")
+	return out, nil
+}
+
+// addMembership puts a user whose own org is another one in the invitation's
+// org, a user that's already a member keeps the role they have.
+func (s service) addMembership(ctx context.Context, tx *sqlx.Tx, i invitation.Invitation, userID string, now time.Time) error {
+	m := pkgmembership.Membership{
+		UserID:    userID,
+		OrgID:     i.OrgID,
+		Role:      i.Role,
+		JoinedAt:  now,
+		CreatedBy: userID,
+	}
+	err := s.membershipDAO.Create(ctx, tx, m)
+	if errors.As(err, &membership.ErrAlreadyMember{}) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityMembership, m.OrgID+"/"+m.UserID, nil, m)
+}
+
+// newToken is 32 random bytes, only its hash is stored so the table alone
+// isn't enough to accept an invitation.
+func newToken() (token string, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func invitationMessage(i invitation.Invitation, token string) notify.Message {
+	return notify.Message{
+		To:      i.Email,
+		Subject: "You've been invited",
+		Body: fmt.Sprintf(
+			"You've been invited to join org %s, accept before %s with:\n\nPOST /api/invitations/accept {\"token\": \"%s\", \"name\": \"<your name>\"}",
+			i.OrgID,
+			i.ExpiresAt.Format(time.RFC3339),
+			token,
+		),
+	}
+}
@@ -0,0 +1,581 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/membership"
+	"github.com/RyanBard/go-service-ex/internal/notify"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	pkgmembership "github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAO struct {
+	mock.Mock
+}
+
+type mockOrgSVC struct {
+	mock.Mock
+}
+
+type mockUserSVC struct {
+	mock.Mock
+}
+
+type mockMembershipDAO struct {
+	mock.Mock
+}
+
+type mockAuditor struct {
+	mock.Mock
+}
+
+type mockTXManager struct {
+	mock.Mock
+}
+
+type mockTimer struct {
+	mock.Mock
+}
+
+type mockIDGen struct {
+	mock.Mock
+}
+
+type mockNotifier struct {
+	mock.Mock
+}
+
+const ttl = time.Hour
+
+func initSVC() (s *service, md *mockDAO, mo *mockOrgSVC, mu *mockUserSVC, mm *mockMembershipDAO, ma *mockAuditor, mt *mockTimer, mi *mockIDGen) {
+	log := testutil.GetLogger()
+	md = new(mockDAO)
+	mo = new(mockOrgSVC)
+	mu = new(mockUserSVC)
+	mm = new(mockMembershipDAO)
+	ma = new(mockAuditor)
+	mt = new(mockTimer)
+	mi = new(mockIDGen)
+	s = NewService(log, mo, mu, mm, md, ma, new(mockTXManager), mt, mi, new(mockNotifier), ttl)
+	return s, md, mo, mu, mm, ma, mt, mi
+}
+
+// notifierOf is the mock notifier initSVC gave s.
+func notifierOf(s *service) *mockNotifier {
+	return s.notifier.(*mockNotifier)
+}
+
+// principalCTX is what the Auth middleware would leave in the context for a
+// token with the given claims.
+func principalCTX(userID string, claims jwt.MapClaims) context.Context {
+	claims["sub"] = userID
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, userID)
+	return context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
+}
+
+func assertForbidden(t *testing.T, err error) {
+	var forbidden authz.ErrForbidden
+	assert.True(t, errors.As(err, &forbidden))
+}
+
+// actorCTX matches a ctx with userID as the logged in user.
+func actorCTX(userID string) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Value(ctxutil.ContextKeyUserID{}) == userID
+	})
+}
+
+// sentToken is the token in the body of the notice sent by the service.
+func sentToken(t *testing.T, mn *mockNotifier) string {
+	msg := mn.Calls[0].Arguments.Get(1).(notify.Message)
+	_, after, found := strings.Cut(msg.Body, `{"token": "`)
+	assert.True(t, found)
+	token, _, _ := strings.Cut(after, `"`)
+	return token
+}
+
+func pendingInvitation() StoredInvitation {
+	return StoredInvitation{
+		Invitation: invitation.Invitation{
+			ID:        "foo-id",
+			OrgID:     "foo-org-id",
+			Email:     "foo@bar.com",
+			Role:      invitation.RoleMember,
+			Status:    invitation.StatusPending,
+			ExpiresAt: time.UnixMilli(500).UTC(),
+			CreatedAt: time.UnixMilli(100).UTC(),
+			CreatedBy: "creator-id",
+		},
+		TokenHash: hashToken("foo-token"),
+	}
+}
+
+func TestSVCGetByOrgID(t *testing.T) {
+	s, md, _, _, _, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	mockRes := []invitation.Invitation{
+		{ID: "foo-id", OrgID: "foo-org-id", Status: invitation.StatusPending, CreatedAt: time.UnixMilli(100)},
+		{ID: "bar-id", OrgID: "foo-org-id", Status: invitation.StatusPending, CreatedAt: time.UnixMilli(200)},
+	}
+	var after *page.Cursor
+	md.On("GetByOrgID", ctx, "foo-org-id", invitation.StatusPending, now, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetByOrgID(ctx, "foo-org-id", invitation.StatusPending, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[:1], actual.Invitations)
+	c, err := page.DecodeCursor(actual.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-id", c.ID)
+	assert.True(t, time.UnixMilli(100).Equal(c.CreatedAt))
+}
+
+func TestSVCGetByOrgID_MemberForbidden(t *testing.T) {
+	s, md, _, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	_, err := s.GetByOrgID(ctx, "foo-org-id", "", page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetByOrgID_InvalidStatus(t *testing.T) {
+	s, md, _, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	_, err := s.GetByOrgID(ctx, "foo-org-id", "bogus", page.Request{Limit: 1})
+
+	var expected ErrInvalidStatus
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "bogus", expected.Status)
+	md.AssertNotCalled(t, "GetByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCCreate(t *testing.T) {
+	s, md, mo, _, _, ma, mt, mi := initSVC()
+	mn := notifierOf(s)
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return("foo-id")
+	mo.On("GetByID", ctx, "foo-org-id", false).Return(org.Org{ID: "foo-org-id"}, nil)
+	expected := invitation.Invitation{
+		ID:        "foo-id",
+		OrgID:     "foo-org-id",
+		Email:     "foo@bar.com",
+		Role:      invitation.RoleMember,
+		Status:    invitation.StatusPending,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		CreatedBy: "logged-in-user-id",
+	}
+	var expectedTX *sqlx.Tx
+	md.On("Create", ctx, expectedTX, mock.AnythingOfType("StoredInvitation")).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityInvitation, "foo-id", nil, expected).Return(nil)
+	mn.On("Send", ctx, mock.AnythingOfType("notify.Message")).Return(nil)
+
+	actual, err := s.Create(ctx, "foo-org-id", invitation.CreateInvitation{Email: "foo@bar.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+	ma.AssertExpectations(t)
+	stored := md.Calls[0].Arguments.Get(2).(StoredInvitation)
+	assert.Equal(t, expected, stored.Invitation)
+	assert.Equal(t, hashToken(sentToken(t, mn)), stored.TokenHash)
+	assert.Equal(t, "foo@bar.com", mn.Calls[0].Arguments.Get(1).(notify.Message).To)
+}
+
+func TestSVCCreate_OrgAdminOtherOrgForbidden(t *testing.T) {
+	s, md, _, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleOrgAdmin})
+
+	_, err := s.Create(ctx, "foo-org-id", invitation.CreateInvitation{Email: "foo@bar.com"})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCCreate_SysOrg(t *testing.T) {
+	s, md, mo, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mo.On("GetByID", ctx, "sys-org-id", false).Return(org.Org{ID: "sys-org-id", IsSystem: true}, nil)
+
+	_, err := s.Create(ctx, "sys-org-id", invitation.CreateInvitation{Email: "foo@bar.com"})
+
+	var expected ErrCannotInviteToSysOrg
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCCreate_DAOErr(t *testing.T) {
+	s, md, mo, _, _, _, mt, mi := initSVC()
+	mn := notifierOf(s)
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mt.On("Now").Return(time.UnixMilli(300).UTC())
+	mi.On("GenID").Return("foo-id")
+	mo.On("GetByID", ctx, "foo-org-id", false).Return(org.Org{ID: "foo-org-id"}, nil)
+	mockErr := ErrAlreadyInvited{OrgID: "foo-org-id", Email: "foo@bar.com"}
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(mockErr)
+
+	_, err := s.Create(ctx, "foo-org-id", invitation.CreateInvitation{Email: "foo@bar.com"})
+
+	assert.Equal(t, mockErr, err)
+	mn.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestSVCCreate_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, _, _, _, _, _, _ := initSVC()
+
+	_, err := s.Create(context.Background(), "foo-org-id", invitation.CreateInvitation{Email: "foo@bar.com"})
+
+	assert.NotNil(t, err)
+}
+
+func TestSVCResend(t *testing.T) {
+	s, md, _, _, _, ma, mt, _ := initSVC()
+	mn := notifierOf(s)
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	now := time.UnixMilli(600).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	stored.Status = invitation.StatusExpired
+	var expectedTX *sqlx.Tx
+	md.On("GetForUpdate", ctx, expectedTX, "foo-id", now).Return(stored, nil)
+	md.On("Update", ctx, expectedTX, mock.AnythingOfType("StoredInvitation")).Return(nil)
+	expected := stored.Invitation
+	expected.Status = invitation.StatusPending
+	expected.ExpiresAt = now.Add(ttl)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityInvitation, "foo-id", stored.Invitation, expected).Return(nil)
+	mn.On("Send", ctx, mock.AnythingOfType("notify.Message")).Return(nil)
+
+	actual, err := s.Resend(ctx, "foo-id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+	ma.AssertExpectations(t)
+	updated := md.Calls[1].Arguments.Get(2).(StoredInvitation)
+	assert.Equal(t, hashToken(sentToken(t, mn)), updated.TokenHash)
+	assert.NotEqual(t, stored.TokenHash, updated.TokenHash)
+}
+
+func TestSVCResend_Accepted(t *testing.T) {
+	s, md, _, _, _, _, mt, _ := initSVC()
+	mn := notifierOf(s)
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	stored.Status = invitation.StatusAccepted
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-id", now).Return(stored, nil)
+
+	_, err := s.Resend(ctx, "foo-id")
+
+	var expected ErrNotPending
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, invitation.StatusAccepted, expected.Status)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	mn.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestSVCResend_OtherOrgForbidden(t *testing.T) {
+	s, md, _, _, _, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleOrgAdmin})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-id", now).Return(pendingInvitation(), nil)
+
+	_, err := s.Resend(ctx, "foo-id")
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRevoke(t *testing.T) {
+	s, md, _, _, _, ma, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	var expectedTX *sqlx.Tx
+	md.On("GetForUpdate", ctx, expectedTX, "foo-id", now).Return(stored, nil)
+	revoked := stored
+	revoked.Status = invitation.StatusRevoked
+	revoked.RevokedAt = &now
+	revoked.RevokedBy = "logged-in-user-id"
+	md.On("Update", ctx, expectedTX, revoked).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityInvitation, "foo-id", stored.Invitation, revoked.Invitation).Return(nil)
+
+	err := s.Revoke(ctx, "foo-id")
+
+	assert.Nil(t, err)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCRevoke_AlreadyRevoked(t *testing.T) {
+	s, md, _, _, _, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	stored.Status = invitation.StatusRevoked
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-id", now).Return(stored, nil)
+
+	err := s.Revoke(ctx, "foo-id")
+
+	assert.Nil(t, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRevoke_Accepted(t *testing.T) {
+	s, md, _, _, _, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	stored.Status = invitation.StatusAccepted
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-id", now).Return(stored, nil)
+
+	err := s.Revoke(ctx, "foo-id")
+
+	var expected ErrNotPending
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRevoke_NotFound(t *testing.T) {
+	s, md, _, _, _, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-id", now).Return(StoredInvitation{}, ErrNotFound{ID: "foo-id"})
+
+	err := s.Revoke(ctx, "foo-id")
+
+	var expected ErrNotFound
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestSVCAccept_NewUser(t *testing.T) {
+	s, md, _, mu, mm, ma, mt, _ := initSVC()
+
+	ctx := context.Background()
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	stored.Role = invitation.RoleOrgAdmin
+	var expectedTX *sqlx.Tx
+	md.On("GetByTokenHashForUpdate", ctx, expectedTX, hashToken("foo-token"), now).Return(stored, nil)
+	created := user.User{ID: "new-id", OrgID: "foo-org-id", Name: "Foo", Email: "foo@bar.com", IsAdmin: true, IsActive: true}
+	mu.On("SaveInvited", ctx, expectedTX, user.User{OrgID: "foo-org-id", Name: "Foo", Email: "foo@bar.com", IsAdmin: true}).Return(created, nil)
+	accepted := stored
+	accepted.Status = invitation.StatusAccepted
+	accepted.AcceptedAt = &now
+	accepted.AcceptedBy = "new-id"
+	md.On("Update", actorCTX("new-id"), expectedTX, accepted).Return(nil)
+	ma.On("Record", actorCTX("new-id"), expectedTX, audit.ActionUpdate, audit.EntityInvitation, "foo-id", stored.Invitation, accepted.Invitation).Return(nil)
+
+	actual, err := s.Accept(ctx, invitation.AcceptInvitation{Token: "foo-token", Name: "Foo"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, created, actual)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+	mm.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAccept_UserInOtherOrg(t *testing.T) {
+	s, md, _, mu, mm, ma, mt, _ := initSVC()
+
+	ctx := context.Background()
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	var expectedTX *sqlx.Tx
+	md.On("GetByTokenHashForUpdate", ctx, expectedTX, hashToken("foo-token"), now).Return(stored, nil)
+	existing := user.User{ID: "bar-id", OrgID: "bar-org-id", Name: "Bar", Email: "foo@bar.com", IsActive: true}
+	mu.On("SaveInvited", ctx, expectedTX, mock.AnythingOfType("user.User")).Return(existing, nil)
+	expectedMS := pkgmembership.Membership{
+		UserID:    "bar-id",
+		OrgID:     "foo-org-id",
+		Role:      invitation.RoleMember,
+		JoinedAt:  now,
+		CreatedBy: "bar-id",
+	}
+	mm.On("Create", actorCTX("bar-id"), expectedTX, expectedMS).Return(nil)
+	ma.On("Record", actorCTX("bar-id"), expectedTX, audit.ActionCreate, audit.EntityMembership, "foo-org-id/bar-id", nil, expectedMS).Return(nil)
+	md.On("Update", actorCTX("bar-id"), expectedTX, mock.AnythingOfType("StoredInvitation")).Return(nil)
+	ma.On("Record", actorCTX("bar-id"), expectedTX, audit.ActionUpdate, audit.EntityInvitation, "foo-id", mock.Anything, mock.Anything).Return(nil)
+
+	actual, err := s.Accept(ctx, invitation.AcceptInvitation{Token: "foo-token"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, existing, actual)
+	mm.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCAccept_AlreadyMember(t *testing.T) {
+	s, md, _, mu, mm, ma, mt, _ := initSVC()
+
+	ctx := context.Background()
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByTokenHashForUpdate", ctx, mock.Anything, hashToken("foo-token"), now).Return(pendingInvitation(), nil)
+	existing := user.User{ID: "bar-id", OrgID: "bar-org-id", Email: "foo@bar.com", IsActive: true}
+	mu.On("SaveInvited", ctx, mock.Anything, mock.Anything).Return(existing, nil)
+	mm.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(membership.ErrAlreadyMember{OrgID: "foo-org-id", UserID: "bar-id"})
+	md.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ma.On("Record", mock.Anything, mock.Anything, audit.ActionUpdate, audit.EntityInvitation, "foo-id", mock.Anything, mock.Anything).Return(nil)
+
+	actual, err := s.Accept(ctx, invitation.AcceptInvitation{Token: "foo-token"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, existing, actual)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, audit.ActionCreate, audit.EntityMembership, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAccept_Expired(t *testing.T) {
+	s, md, _, mu, _, _, mt, _ := initSVC()
+
+	ctx := context.Background()
+	now := time.UnixMilli(600).UTC()
+	mt.On("Now").Return(now)
+	stored := pendingInvitation()
+	stored.Status = invitation.StatusExpired
+	md.On("GetByTokenHashForUpdate", ctx, mock.Anything, hashToken("foo-token"), now).Return(stored, nil)
+
+	_, err := s.Accept(ctx, invitation.AcceptInvitation{Token: "foo-token", Name: "Foo"})
+
+	var expected ErrCannotAccept
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, invitation.StatusExpired, expected.Status)
+	mu.AssertNotCalled(t, "SaveInvited", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAccept_InvalidToken(t *testing.T) {
+	s, md, _, mu, _, _, mt, _ := initSVC()
+
+	ctx := context.Background()
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByTokenHashForUpdate", ctx, mock.Anything, hashToken("bogus"), now).Return(StoredInvitation{}, ErrInvalidToken{})
+
+	_, err := s.Accept(ctx, invitation.AcceptInvitation{Token: "bogus"})
+
+	var expected ErrInvalidToken
+	assert.True(t, errors.As(err, &expected))
+	mu.AssertNotCalled(t, "SaveInvited", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAccept_UserErr(t *testing.T) {
+	s, md, _, mu, _, _, mt, _ := initSVC()
+
+	ctx := context.Background()
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByTokenHashForUpdate", ctx, mock.Anything, hashToken("foo-token"), now).Return(pendingInvitation(), nil)
+	mockErr := errors.New("unit-test mock error")
+	mu.On("SaveInvited", ctx, mock.Anything, mock.Anything).Return(user.User{}, mockErr)
+
+	_, err := s.Accept(ctx, invitation.AcceptInvitation{Token: "foo-token"})
+
+	assert.Equal(t, mockErr, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (d *mockDAO) GetByOrgID(ctx context.Context, orgID string, status string, now time.Time, after *page.Cursor, limit int) ([]invitation.Invitation, error) {
+	args := d.Called(ctx, orgID, status, now, after, limit)
+	return args.Get(0).([]invitation.Invitation), args.Error(1)
+}
+
+func (d *mockDAO) GetForUpdate(ctx context.Context, tx *sqlx.Tx, id string, now time.Time) (StoredInvitation, error) {
+	args := d.Called(ctx, tx, id, now)
+	return args.Get(0).(StoredInvitation), args.Error(1)
+}
+
+func (d *mockDAO) GetByTokenHashForUpdate(ctx context.Context, tx *sqlx.Tx, tokenHash string, now time.Time) (StoredInvitation, error) {
+	args := d.Called(ctx, tx, tokenHash, now)
+	return args.Get(0).(StoredInvitation), args.Error(1)
+}
+
+func (d *mockDAO) Create(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) error {
+	args := d.Called(ctx, tx, si)
+	return args.Error(0)
+}
+
+func (d *mockDAO) Update(ctx context.Context, tx *sqlx.Tx, si StoredInvitation) error {
+	args := d.Called(ctx, tx, si)
+	return args.Error(0)
+}
+
+func (o *mockOrgSVC) GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error) {
+	args := o.Called(ctx, id, includeDeleted)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (u *mockUserSVC) SaveInvited(ctx context.Context, joinTX *sqlx.Tx, input user.User) (user.User, error) {
+	args := u.Called(ctx, joinTX, input)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (d *mockMembershipDAO) Create(ctx context.Context, tx *sqlx.Tx, m pkgmembership.Membership) error {
+	args := d.Called(ctx, tx, m)
+	return args.Error(0)
+}
+
+func (m *mockAuditor) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error {
+	args := m.Called(ctx, tx, action, entityType, entityID, before, after)
+	return args.Error(0)
+}
+
+func (m *mockTXManager) Do(ctx context.Context, tx *sqlx.Tx, f func(tx *sqlx.Tx) error) error {
+	return f(tx)
+}
+
+func (t *mockTimer) Now() time.Time {
+	args := t.Called()
+	return args.Get(0).(time.Time)
+}
+
+func (t *mockIDGen) GenID() string {
+	args := t.Called()
+	return args.String(0)
+}
+
+func (n *mockNotifier) Send(ctx context.Context, m notify.Message) error {
+	args := n.Called(ctx, m)
+	return args.Error(0)
+}
@@ -0,0 +1,51 @@
+package invitation
+
+import (
+	"context"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
+
+// tracedService wraps every InvitationService call in a span.
+type tracedService struct {
+	svc InvitationService
+}
+
+func NewTracedService(svc InvitationService) *tracedService {
+	return &tracedService{
+		svc: svc,
+	}
+}
+
+func (s tracedService) GetByOrgID(ctx context.Context, orgID string, status string, pr page.Request) (ip invitation.InvitationPage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "InvitationSVC.GetByOrgID")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetByOrgID(ctx, orgID, status, pr)
+}
+
+func (s tracedService) Create(ctx context.Context, orgID string, ci invitation.CreateInvitation) (i invitation.Invitation, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "InvitationSVC.Create")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Create(ctx, orgID, ci)
+}
+
+func (s tracedService) Resend(ctx context.Context, id string) (i invitation.Invitation, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "InvitationSVC.Resend")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Resend(ctx, id)
+}
+
+func (s tracedService) Revoke(ctx context.Context, id string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "InvitationSVC.Revoke")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Revoke(ctx, id)
+}
+
+func (s tracedService) Accept(ctx context.Context, ai invitation.AcceptInvitation) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "InvitationSVC.Accept")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Accept(ctx, ai)
+}
@@ -0,0 +1,135 @@
+package invitation
+
+// statusColumn works out the status as of $1, so every query that selects it
+// takes the current time as its first param.
+const statusColumn = `
+		CASE
+			WHEN i.accepted_at IS NOT NULL THEN 'accepted'
+			WHEN i.revoked_at IS NOT NULL THEN 'revoked'
+			WHEN i.expires_at <= $1 THEN 'expired'
+			ELSE 'pending'
+		END`
+
+// An empty status ($3) matches every invitation.
+const getByOrgIDQuery = `
+	SELECT
+		i.id,
+		i.org_id,
+		i.email,
+		i.role,` + statusColumn + ` AS status,
+		i.expires_at,
+		i.created_at,
+		i.created_by,
+		i.accepted_at,
+		COALESCE(i.accepted_by, '') AS accepted_by,
+		i.revoked_at,
+		COALESCE(i.revoked_by, '') AS revoked_by
+	FROM invitations i
+	WHERE i.org_id = $2
+	AND ($3::TEXT = '' OR $3::TEXT = ` + statusColumn + `)
+	ORDER BY i.created_at ASC, i.id ASC
+	LIMIT $4
+`
+
+const getByOrgIDAfterQuery = `
+	SELECT
+		i.id,
+		i.org_id,
+		i.email,
+		i.role,` + statusColumn + ` AS status,
+		i.expires_at,
+		i.created_at,
+		i.created_by,
+		i.accepted_at,
+		COALESCE(i.accepted_by, '') AS accepted_by,
+		i.revoked_at,
+		COALESCE(i.revoked_by, '') AS revoked_by
+	FROM invitations i
+	WHERE i.org_id = $2
+	AND ($3::TEXT = '' OR $3::TEXT = ` + statusColumn + `)
+	AND (
+		i.created_at > $4
+		OR (i.created_at = $4 AND i.id > $5)
+	)
+	ORDER BY i.created_at ASC, i.id ASC
+	LIMIT $6
+`
+
+// Locked so a resend, revoke and accept of the same invitation can't
+// interleave.
+const getForUpdateQuery = `
+	SELECT
+		i.id,
+		i.org_id,
+		i.email,
+		i.role,` + statusColumn + ` AS status,
+		i.token_hash,
+		i.expires_at,
+		i.created_at,
+		i.created_by,
+		i.accepted_at,
+		COALESCE(i.accepted_by, '') AS accepted_by,
+		i.revoked_at,
+		COALESCE(i.revoked_by, '') AS revoked_by
+	FROM invitations i
+	WHERE i.id = $2
+	FOR UPDATE OF i
+`
+
+// The invitations of a soft deleted org can't be accepted, they're treated
+// as if the token didn't match.
+const getByTokenHashForUpdateQuery = `
+	SELECT
+		i.id,
+		i.org_id,
+		i.email,
+		i.role,` + statusColumn + ` AS status,
+		i.token_hash,
+		i.expires_at,
+		i.created_at,
+		i.created_by,
+		i.accepted_at,
+		COALESCE(i.accepted_by, '') AS accepted_by,
+		i.revoked_at,
+		COALESCE(i.revoked_by, '') AS revoked_by
+	FROM invitations i
+	JOIN orgs o ON o.id = i.org_id
+	WHERE i.token_hash = $2
+	AND o.deleted_at IS NULL
+	FOR UPDATE OF i
+`
+
+const createQuery = `
+	INSERT INTO invitations (
+		id,
+		org_id,
+		email,
+		role,
+		token_hash,
+		expires_at,
+		created_at,
+		created_by
+	) VALUES (
+		:id,
+		:org_id,
+		:email,
+		:role,
+		:token_hash,
+		:expires_at,
+		:created_at,
+		:created_by
+	)
+`
+
+// The email, org and role never change, an update is a resend (a new token
+// and expiry), an accept or a revoke.
+const updateQuery = `
+	UPDATE invitations SET
+		token_hash = :token_hash,
+		expires_at = :expires_at,
+		accepted_at = :accepted_at,
+		accepted_by = NULLIF(:accepted_by, ''),
+		revoked_at = :revoked_at,
+		revoked_by = NULLIF(:revoked_by, '')
+	WHERE id = :id
+`
@@ -0,0 +1,30 @@
+package jwks
+
+import (
+	"fmt"
+)
+
+type ErrInvalidKey struct {
+	KeyID  string
+	Reason string
+}
+
+func (err ErrInvalidKey) Error() string {
+	return fmt.Sprintf("Invalid JWK: kid=%s reason=%s", err.KeyID, err.Reason)
+}
+
+type ErrDuplicateKeyID struct {
+	KeyID string
+}
+
+func (err ErrDuplicateKeyID) Error() string {
+	return fmt.Sprintf("JWK kid is used more than once: kid=%s", err.KeyID)
+}
+
+type ErrKeyNotFound struct {
+	KeyID string
+}
+
+func (err ErrKeyNotFound) Error() string {
+	return fmt.Sprintf("No JWK found for kid: kid=%s", err.KeyID)
+}
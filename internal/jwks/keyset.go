@@ -0,0 +1,142 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+)
+
+// Key is a public verification key, Alg is empty when the JWK didn't pin one.
+type Key struct {
+	ID  string
+	Alg string
+	Key any
+}
+
+type KeySet map[string]Key
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// ParseKeySet parses a JWKS document (RFC 7517). Keys that aren't for
+// signatures or whose type we don't support are skipped, so an IdP adding a
+// new kind of key doesn't break verification with the ones we understand.
+func ParseKeySet(b []byte) (KeySet, error) {
+	var set jwkSet
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	keys := KeySet{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var pub any
+		var err error
+		switch k.Kty {
+		case "RSA":
+			pub, err = parseRSA(k)
+		case "EC":
+			pub, err = parseEC(k)
+		case "OKP":
+			pub, err = parseOKP(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, ErrDuplicateKeyID{KeyID: k.Kid}
+		}
+		keys[k.Kid] = Key{ID: k.Kid, Alg: k.Alg, Key: pub}
+	}
+	return keys, nil
+}
+
+func decode(k jwk, field string, val string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil || len(b) == 0 {
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "invalid " + field}
+	}
+	return b, nil
+}
+
+func parseRSA(k jwk) (*rsa.PublicKey, error) {
+	n, err := decode(k, "n", k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decode(k, "e", k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 {
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "invalid e"}
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+func parseEC(k jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "unsupported crv " + k.Crv}
+	}
+	x, err := decode(k, "x", k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode(k, "y", k.Y)
+	if err != nil {
+		return nil, err
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) != size || len(y) != size {
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "invalid point size"}
+	}
+	point := append(append([]byte{4}, x...), y...)
+	pub, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+	if err != nil {
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "point not on curve"}
+	}
+	return pub, nil
+}
+
+func parseOKP(k jwk) (ed25519.PublicKey, error) {
+	if k.Crv != "Ed25519" {
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "unsupported crv " + k.Crv}
+	}
+	x, err := decode(k, "x", k.X)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, ErrInvalidKey{KeyID: k.Kid, Reason: "invalid x"}
+	}
+	return ed25519.PublicKey(x), nil
+}
@@ -0,0 +1,128 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	rsaKey, _   = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _    = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ = ed25519.GenerateKey(rand.Reader)
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Alg: "RS256",
+		Use: "sig",
+		N:   b64(rsaKey.N.Bytes()),
+		E:   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string) jwk {
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: "P-256",
+		X:   b64(ecKey.X.FillBytes(make([]byte, 32))),
+		Y:   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func edJWK(kid string) jwk {
+	return jwk{
+		Kty: "OKP",
+		Kid: kid,
+		Crv: "Ed25519",
+		X:   b64(edPub),
+	}
+}
+
+func jwksJSON(keys ...jwk) []byte {
+	b, err := json.Marshal(jwkSet{Keys: keys})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestParseKeySet(t *testing.T) {
+	actual, err := ParseKeySet(jwksJSON(rsaJWK("rsa"), ecJWK("ec"), edJWK("ed")))
+
+	assert.Nil(t, err)
+	assert.Len(t, actual, 3)
+	assert.Equal(t, "RS256", actual["rsa"].Alg)
+	assert.True(t, rsaKey.PublicKey.Equal(actual["rsa"].Key))
+	assert.True(t, ecKey.PublicKey.Equal(actual["ec"].Key))
+	assert.True(t, edPub.Equal(actual["ed"].Key))
+}
+
+func TestParseKeySet_SkipsEncryptionAndUnknownKeys(t *testing.T) {
+	enc := rsaJWK("enc")
+	enc.Use = "enc"
+	oct := jwk{Kty: "oct", Kid: "oct"}
+
+	actual, err := ParseKeySet(jwksJSON(rsaJWK("rsa"), enc, oct))
+
+	assert.Nil(t, err)
+	assert.Len(t, actual, 1)
+	assert.Contains(t, actual, "rsa")
+}
+
+func TestParseKeySet_InvalidJSON(t *testing.T) {
+	_, err := ParseKeySet([]byte("not json"))
+
+	assert.NotNil(t, err)
+}
+
+func TestParseKeySet_DuplicateKid(t *testing.T) {
+	_, err := ParseKeySet(jwksJSON(rsaJWK("dup"), ecJWK("dup")))
+
+	assert.Equal(t, ErrDuplicateKeyID{KeyID: "dup"}, err)
+}
+
+func TestParseKeySet_InvalidRSA(t *testing.T) {
+	k := rsaJWK("rsa")
+	k.N = "!!!"
+
+	_, err := ParseKeySet(jwksJSON(k))
+
+	var expected ErrInvalidKey
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "invalid n", expected.Reason)
+}
+
+func TestParseKeySet_ECPointNotOnCurve(t *testing.T) {
+	k := ecJWK("ec")
+	k.Y = b64(make([]byte, 32))
+
+	_, err := ParseKeySet(jwksJSON(k))
+
+	assert.Equal(t, ErrInvalidKey{KeyID: "ec", Reason: "point not on curve"}, err)
+}
+
+func TestParseKeySet_UnsupportedCurve(t *testing.T) {
+	k := edJWK("ed")
+	k.Crv = "X25519"
+
+	_, err := ParseKeySet(jwksJSON(k))
+
+	assert.Equal(t, ErrInvalidKey{KeyID: "ed", Reason: "unsupported crv X25519"}, err)
+}
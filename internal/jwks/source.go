@@ -0,0 +1,112 @@
+package jwks
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+)
+
+// An unknown kid triggers an early refetch (the IdP may have rotated in a new
+// key), this bounds how often garbage kids can make us hit the IdP.
+const minRefreshInterval = 30 * time.Second
+
+type Timer interface {
+	Now() time.Time
+}
+
+type source struct {
+	log         *slog.Logger
+	timer       Timer
+	ttl         time.Duration
+	fetch       func(ctx context.Context) ([]byte, error)
+	mu          sync.Mutex
+	keys        KeySet
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// NewFileSource loads the JWKS eagerly so a bad file fails at startup, the
+// file is re-read every ttl to pick up rotated keys.
+func NewFileSource(log *slog.Logger, timer Timer, path string, ttl time.Duration) (*source, error) {
+	s := &source{
+		log:   log.With(logutil.LogAttrSVC("JWKSFileSource"), slog.String("path", path)),
+		timer: timer,
+		ttl:   ttl,
+		fetch: func(ctx context.Context) ([]byte, error) {
+			return os.ReadFile(path)
+		},
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.refresh(context.Background(), timer.Now()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewURLSource fetches the JWKS lazily, on the first token that needs it, so
+// the IdP being down doesn't keep us from booting.
+func NewURLSource(log *slog.Logger, timer Timer, hc *httpx.Client, url string, ttl time.Duration) *source {
+	return &source{
+		log:   log.With(logutil.LogAttrSVC("JWKSURLSource"), slog.String("url", url)),
+		timer: timer,
+		ttl:   ttl,
+		fetch: func(ctx context.Context) ([]byte, error) {
+			var body string
+			_, err := hc.Get(url, nil).
+				WithAccept("application/json").
+				RetrieveStrWithContext(ctx, &body)
+			return []byte(body), err
+		},
+	}
+}
+
+// Key returns the key for kid. If a refetch fails the previously fetched keys
+// keep being used, an IdP outage shouldn't log everyone out.
+func (s *source) Key(ctx context.Context, kid string) (Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.timer.Now()
+	_, known := s.keys[kid]
+	stale := s.keys == nil || now.Sub(s.fetchedAt) >= s.ttl
+	var err error
+	if (stale || !known) && now.Sub(s.lastAttempt) >= minRefreshInterval {
+		err = s.refresh(ctx, now)
+	}
+	if s.keys == nil && err != nil {
+		return Key{}, err
+	}
+	k, ok := s.keys[kid]
+	if !ok {
+		return Key{}, ErrKeyNotFound{KeyID: kid}
+	}
+	return k, nil
+}
+
+func (s *source) refresh(ctx context.Context, now time.Time) error {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("refresh"),
+	)
+	log.Debug("called")
+	s.lastAttempt = now
+	b, err := s.fetch(ctx)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to fetch jwks")
+		return err
+	}
+	keys, err := ParseKeySet(b)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to parse jwks")
+		return err
+	}
+	s.keys = keys
+	s.fetchedAt = now
+	log.With(slog.Int("keysLen", len(keys))).Info("jwks refreshed")
+	return nil
+}
@@ -0,0 +1,204 @@
+package jwks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var ctx = context.Background()
+
+type mockTimer struct {
+	mock.Mock
+}
+
+func (t *mockTimer) Now() time.Time {
+	args := t.Called()
+	return args.Get(0).(time.Time)
+}
+
+func initFetchSource(fetch func(ctx context.Context) ([]byte, error)) (*source, *mockTimer) {
+	mt := new(mockTimer)
+	return &source{
+		log:   testutil.GetLogger(),
+		timer: mt,
+		ttl:   10 * time.Minute,
+		fetch: fetch,
+	}, mt
+}
+
+func TestKey_FetchesLazilyAndCaches(t *testing.T) {
+	var calls atomic.Int32
+	s, mt := initFetchSource(func(ctx context.Context) ([]byte, error) {
+		calls.Add(1)
+		return jwksJSON(rsaJWK("rsa")), nil
+	})
+	now := time.UnixMilli(0)
+	mt.On("Now").Return(now)
+
+	_, err := s.Key(ctx, "rsa")
+	assert.Nil(t, err)
+	actual, err := s.Key(ctx, "rsa")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "rsa", actual.ID)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestKey_RefetchesAfterTTL(t *testing.T) {
+	var calls atomic.Int32
+	s, mt := initFetchSource(func(ctx context.Context) ([]byte, error) {
+		calls.Add(1)
+		return jwksJSON(rsaJWK("rsa")), nil
+	})
+	now := time.UnixMilli(0)
+	mt.On("Now").Return(now).Once()
+	mt.On("Now").Return(now.Add(s.ttl)).Once()
+
+	_, err := s.Key(ctx, "rsa")
+	assert.Nil(t, err)
+	_, err = s.Key(ctx, "rsa")
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestKey_UnknownKidRefetchesForRotation(t *testing.T) {
+	var calls atomic.Int32
+	s, mt := initFetchSource(func(ctx context.Context) ([]byte, error) {
+		if calls.Add(1) == 1 {
+			return jwksJSON(rsaJWK("old")), nil
+		}
+		return jwksJSON(rsaJWK("old"), ecJWK("new")), nil
+	})
+	now := time.UnixMilli(0)
+	mt.On("Now").Return(now).Once()
+	mt.On("Now").Return(now.Add(minRefreshInterval)).Once()
+
+	_, err := s.Key(ctx, "old")
+	assert.Nil(t, err)
+	actual, err := s.Key(ctx, "new")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "new", actual.ID)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestKey_UnknownKidRefetchIsRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	s, mt := initFetchSource(func(ctx context.Context) ([]byte, error) {
+		calls.Add(1)
+		return jwksJSON(rsaJWK("rsa")), nil
+	})
+	now := time.UnixMilli(0)
+	mt.On("Now").Return(now)
+
+	_, err := s.Key(ctx, "rsa")
+	assert.Nil(t, err)
+	_, err = s.Key(ctx, "garbage")
+
+	assert.Equal(t, ErrKeyNotFound{KeyID: "garbage"}, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestKey_FailedRefetchKeepsPreviousKeys(t *testing.T) {
+	mockErr := errors.New("unit-test mock error")
+	var calls atomic.Int32
+	s, mt := initFetchSource(func(ctx context.Context) ([]byte, error) {
+		if calls.Add(1) == 1 {
+			return jwksJSON(rsaJWK("rsa")), nil
+		}
+		return nil, mockErr
+	})
+	now := time.UnixMilli(0)
+	mt.On("Now").Return(now).Once()
+	mt.On("Now").Return(now.Add(s.ttl)).Once()
+
+	_, err := s.Key(ctx, "rsa")
+	assert.Nil(t, err)
+	actual, err := s.Key(ctx, "rsa")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "rsa", actual.ID)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestKey_FetchErrNoKeys(t *testing.T) {
+	mockErr := errors.New("unit-test mock error")
+	s, mt := initFetchSource(func(ctx context.Context) ([]byte, error) {
+		return nil, mockErr
+	})
+	mt.On("Now").Return(time.UnixMilli(0))
+
+	_, err := s.Key(ctx, "rsa")
+
+	assert.Equal(t, mockErr, err)
+}
+
+func TestNewFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, jwksJSON(edJWK("ed")), 0600))
+	mt := new(mockTimer)
+	mt.On("Now").Return(time.UnixMilli(0))
+
+	s, err := NewFileSource(testutil.GetLogger(), mt, path, time.Minute)
+	assert.Nil(t, err)
+	actual, err := s.Key(ctx, "ed")
+
+	assert.Nil(t, err)
+	assert.True(t, edPub.Equal(actual.Key))
+}
+
+func TestNewFileSource_MissingFile(t *testing.T) {
+	mt := new(mockTimer)
+	mt.On("Now").Return(time.UnixMilli(0))
+
+	_, err := NewFileSource(testutil.GetLogger(), mt, filepath.Join(t.TempDir(), "nope.json"), time.Minute)
+
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestNewURLSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/.well-known/jwks.json", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(jwksJSON(ecJWK("ec")))
+	}))
+	defer server.Close()
+	mt := new(mockTimer)
+	mt.On("Now").Return(time.UnixMilli(0))
+
+	s := NewURLSource(testutil.GetLogger(), mt, httpx.NewClient(http.Client{}), server.URL+"/.well-known/jwks.json", time.Minute)
+	actual, err := s.Key(ctx, "ec")
+
+	assert.Nil(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(actual.Key))
+}
+
+func TestNewURLSource_ErrStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	mt := new(mockTimer)
+	mt.On("Now").Return(time.UnixMilli(0))
+
+	s := NewURLSource(testutil.GetLogger(), mt, httpx.NewClient(http.Client{}), server.URL, time.Minute)
+	_, err := s.Key(ctx, "ec")
+
+	var expected httpx.HTTPError
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, http.StatusServiceUnavailable, expected.StatusCode)
+}
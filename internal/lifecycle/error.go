@@ -0,0 +1,20 @@
+package lifecycle
+
+import (
+	"fmt"
+	"time"
+)
+
+type ErrDraining struct{}
+
+func (err ErrDraining) Error() string {
+	return "Server is draining, shutdown in progress"
+}
+
+type ErrShutdownTimeout struct {
+	Timeout time.Duration
+}
+
+func (err ErrShutdownTimeout) Error() string {
+	return fmt.Sprintf("Server did not stop in time, in-flight calls were cancelled: timeout=%s", err.Timeout)
+}
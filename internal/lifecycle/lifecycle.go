@@ -0,0 +1,158 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"google.golang.org/grpc"
+)
+
+type readiness struct {
+	log      *slog.Logger
+	draining atomic.Bool
+}
+
+func NewReadiness(log *slog.Logger) *readiness {
+	return &readiness{
+		log: log.With(logutil.LogAttrSVC("Readiness")),
+	}
+}
+
+// Drain makes readiness start failing so load balancers stop sending new
+// requests before the server stops accepting them.
+func (r *readiness) Drain() {
+	r.log.With(logutil.LogAttrFN("Drain")).Info("readiness will now fail")
+	r.draining.Store(true)
+}
+
+func (r *readiness) Draining() bool {
+	return r.draining.Load()
+}
+
+// Check fails while draining, register it as a readiness check.
+func (r *readiness) Check(ctx context.Context) error {
+	if r.Draining() {
+		return ErrDraining{}
+	}
+	return nil
+}
+
+type Drainer interface {
+	Drain()
+}
+
+// DrainerFunc lets a func be a Drainer (ex. the gRPC health server's
+// Shutdown).
+type DrainerFunc func()
+
+func (f DrainerFunc) Drain() {
+	f()
+}
+
+// Serve runs srv until ctx is done, then shuts it down in order: readiness
+// starts failing, after drainDelay the listener is closed and in-flight
+// requests get up to shutdownTimeout to finish.
+func Serve(
+	ctx context.Context,
+	log *slog.Logger,
+	srv *http.Server,
+	readiness Drainer,
+	drainDelay time.Duration,
+	shutdownTimeout time.Duration,
+) error {
+	log = log.With(
+		logutil.LogAttrSVC("Lifecycle"),
+		logutil.LogAttrFN("Serve"),
+		slog.String("addr", srv.Addr),
+	)
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("listening")
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		log.With(logutil.LogAttrError(err)).Error("server stopped unexpectedly")
+		return err
+	case <-ctx.Done():
+	}
+
+	log.With(slog.Duration("drainDelay", drainDelay)).Info("shutdown requested, draining")
+	readiness.Drain()
+	time.Sleep(drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	log.With(slog.Duration("shutdownTimeout", shutdownTimeout)).Info("shutting down server")
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.With(logutil.LogAttrError(err)).Error("server did not shut down cleanly")
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Info("server stopped")
+	return nil
+}
+
+// ServeGRPC is Serve for a gRPC server listening on lis. Once the server stops
+// taking new calls, in-flight ones (streams included) get up to
+// shutdownTimeout to finish before they're cancelled.
+func ServeGRPC(
+	ctx context.Context,
+	log *slog.Logger,
+	srv *grpc.Server,
+	lis net.Listener,
+	readiness Drainer,
+	drainDelay time.Duration,
+	shutdownTimeout time.Duration,
+) error {
+	log = log.With(
+		logutil.LogAttrSVC("Lifecycle"),
+		logutil.LogAttrFN("ServeGRPC"),
+		slog.String("addr", lis.Addr().String()),
+	)
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("listening")
+		errCh <- srv.Serve(lis)
+	}()
+
+	select {
+	case err := <-errCh:
+		log.With(logutil.LogAttrError(err)).Error("server stopped unexpectedly")
+		return err
+	case <-ctx.Done():
+	}
+
+	log.With(slog.Duration("drainDelay", drainDelay)).Info("shutdown requested, draining")
+	readiness.Drain()
+	time.Sleep(drainDelay)
+
+	log.With(slog.Duration("shutdownTimeout", shutdownTimeout)).Info("shutting down server")
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		log.Error("server did not shut down cleanly")
+		srv.Stop()
+		<-stopped
+		return ErrShutdownTimeout{Timeout: shutdownTimeout}
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+	log.Info("server stopped")
+	return nil
+}
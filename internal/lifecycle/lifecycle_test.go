@@ -0,0 +1,166 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestReadiness_Ready(t *testing.T) {
+	r := NewReadiness(testutil.GetLogger())
+
+	err := r.Check(context.Background())
+
+	assert.False(t, r.Draining())
+	assert.Nil(t, err)
+}
+
+func TestReadiness_Draining(t *testing.T) {
+	r := NewReadiness(testutil.GetLogger())
+	r.Drain()
+
+	err := r.Check(context.Background())
+
+	assert.True(t, r.Draining())
+	assert.Equal(t, ErrDraining{}, err)
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	log := testutil.GetLogger()
+	r := NewReadiness(log)
+	started := make(chan struct{})
+	srv := &http.Server{
+		Addr: freeAddr(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "done")
+		}),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- Serve(ctx, log, srv, r, 0, time.Second)
+	}()
+
+	var resp *http.Response
+	var respErr error
+	respDone := make(chan struct{})
+	go func() {
+		defer close(respDone)
+		assert.Eventually(t, func() bool {
+			resp, respErr = http.Get("http://" + srv.Addr)
+			return respErr == nil
+		}, time.Second, 10*time.Millisecond)
+	}()
+	<-started
+	cancel()
+
+	assert.Nil(t, <-serveErr)
+	<-respDone
+	assert.Nil(t, respErr)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "done", string(body))
+	assert.True(t, r.Draining())
+}
+
+func TestServe_ListenErr(t *testing.T) {
+	log := testutil.GetLogger()
+	r := NewReadiness(log)
+	srv := &http.Server{Addr: "not-a-valid-addr"}
+
+	err := Serve(context.Background(), log, srv, r, 0, time.Second)
+
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, http.ErrServerClosed))
+	assert.False(t, r.Draining())
+}
+
+func TestDrainerFunc(t *testing.T) {
+	drained := false
+
+	DrainerFunc(func() { drained = true }).Drain()
+
+	assert.True(t, drained)
+}
+
+// grpcHealth serves the gRPC health service, it has a unary call (Check) and
+// a stream that doesn't end on its own (Watch).
+func grpcHealth(t *testing.T) (*grpc.Server, net.Listener, healthpb.HealthClient) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return srv, lis, healthpb.NewHealthClient(conn)
+}
+
+func TestServeGRPC_Stops(t *testing.T) {
+	log := testutil.GetLogger()
+	srv, lis, client := grpcHealth(t)
+	drained := false
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- ServeGRPC(ctx, log, srv, lis, DrainerFunc(func() { drained = true }), 0, time.Second)
+	}()
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	cancel()
+
+	assert.Nil(t, <-serveErr)
+	assert.True(t, drained)
+}
+
+func TestServeGRPC_ShutdownTimeout(t *testing.T) {
+	log := testutil.GetLogger()
+	srv, lis, client := grpcHealth(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- ServeGRPC(ctx, log, srv, lis, DrainerFunc(func() {}), 0, 50*time.Millisecond)
+	}()
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Nil(t, err)
+	cancel()
+
+	assert.Equal(t, ErrShutdownTimeout{Timeout: 50 * time.Millisecond}, <-serveErr)
+}
+
+func TestServeGRPC_ServeErr(t *testing.T) {
+	log := testutil.GetLogger()
+	srv, lis, _ := grpcHealth(t)
+	lis.Close()
+	drained := false
+
+	err := ServeGRPC(context.Background(), log, srv, lis, DrainerFunc(func() { drained = true }), 0, time.Second)
+
+	assert.NotNil(t, err)
+	assert.False(t, drained)
+}
@@ -0,0 +1,267 @@
+package mdlw
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serverStream replaces the stream's context, there's no other way for a
+// stream interceptor to hand a new one to the handler.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// incoming is the first value of the metadata key, keys are lower case.
+func incoming(ctx context.Context, key string) string {
+	vals := metadata.ValueFromIncomingContext(ctx, key)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+// metadataCarrier lets the propagator read the caller's trace context
+// (traceparent) out of the incoming metadata, like HeaderCarrier does for
+// REST.
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	vals := metadata.MD(mc).Get(key)
+	if len(vals) == 0 {
+		return ""
+	}
+	return vals[0]
+}
+
+func (mc metadataCarrier) Set(key, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range mc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// serviceOf is the service of a full method (ex. /grpc.health.v1.Health/Check).
+func serviceOf(fullMethod string) string {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	return service
+}
+
+// UnaryRecovery is Recovery for gRPC, a panic is an Internal status
+// instead of taking the server down.
+func UnaryRecovery(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer recoverTo(ctx, logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func StreamRecovery(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverTo(ss.Context(), logger, info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverTo(ctx context.Context, logger *slog.Logger, fullMethod string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	logger.With(
+		logAttrSVC(),
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Recovery"),
+		slog.String("method", fullMethod),
+		slog.Any("panic", r),
+	).Error("recovered from panic")
+	*err = rpcerr.New(codes.Internal, fmt.Errorf("panic: %v", r))
+}
+
+// UnaryReqID is ReqID for gRPC, the id comes from the x-request-id metadata.
+func UnaryReqID(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(grpcReqID(ctx, logger), req)
+	}
+}
+
+func StreamReqID(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, serverStream{ServerStream: ss, ctx: grpcReqID(ss.Context(), logger)})
+	}
+}
+
+func grpcReqID(ctx context.Context, logger *slog.Logger) context.Context {
+	ctx = withReqID(ctx, incoming(ctx, "x-request-id"))
+	log := logger.With(
+		logAttrSVC(),
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("ReqID"),
+	)
+	log.Debug("called")
+	return ctx
+}
+
+// UnaryTrace is Trace for gRPC, the span is named after the method (ex.
+// goserviceex.org.v1.OrgService/GetOrg).
+func UnaryTrace(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := grpcTrace(ctx, logger, info.FullMethod)
+		defer span.End()
+		resp, err := handler(ctx, req)
+		endTrace(span, err)
+		return resp, err
+	}
+}
+
+func StreamTrace(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := grpcTrace(ss.Context(), logger, info.FullMethod)
+		defer span.End()
+		err := handler(srv, serverStream{ServerStream: ss, ctx: ctx})
+		endTrace(span, err)
+		return err
+	}
+}
+
+func grpcTrace(ctx context.Context, logger *slog.Logger, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	method := strings.TrimPrefix(fullMethod, "/")
+	reqID, _ := ctx.Value(ctxutil.ContextKeyReqID{}).(string)
+	ctx, span := tracing.Tracer().Start(
+		ctx,
+		method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemNameGRPC,
+			semconv.RPCMethod(method),
+			attribute.String("request.id", reqID),
+		),
+	)
+	log := logger.With(
+		logAttrSVC(),
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Trace"),
+		slog.String("traceID", span.SpanContext().TraceID().String()),
+	)
+	log.Debug("called")
+	return ctx, span
+}
+
+// endTrace records the status, only the codes that are the server's fault
+// (the 5xx of gRPC) mark the span as an error.
+func endTrace(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCResponseStatusCode(code.String()))
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+}
+
+// UnaryAuth is Auth for gRPC, the token comes from the authorization metadata.
+// The methods of the public services (ex. health and reflection) don't need
+// one.
+func UnaryAuth(logger *slog.Logger, cfg config.AuthConfig, keys KeySource, public ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(public, serviceOf(info.FullMethod)) {
+			return handler(ctx, req)
+		}
+		ctx, err := grpcAuth(ctx, logger, cfg, keys)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamAuth(logger *slog.Logger, cfg config.AuthConfig, keys KeySource, public ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if slices.Contains(public, serviceOf(info.FullMethod)) {
+			return handler(srv, ss)
+		}
+		ctx, err := grpcAuth(ss.Context(), logger, cfg, keys)
+		if err != nil {
+			return err
+		}
+		return handler(srv, serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func grpcAuth(ctx context.Context, logger *slog.Logger, cfg config.AuthConfig, keys KeySource) (context.Context, error) {
+	log := logger.With(
+		logAttrSVC(),
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Auth"),
+	)
+	log.Debug("called")
+	ctx, err := authenticate(ctx, log, cfg, keys, incoming(ctx, "authorization"))
+	if err != nil {
+		return ctx, rpcerr.New(codes.Unauthenticated, err)
+	}
+	return ctx, nil
+}
+
+// UnaryMemberships is Memberships for gRPC, it goes after UnaryAuth.
+func UnaryMemberships(logger *slog.Logger, source MembershipSource) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := grpcMemberships(ctx, logger, source)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamMemberships(logger *slog.Logger, source MembershipSource) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := grpcMemberships(ss.Context(), logger, source)
+		if err != nil {
+			return err
+		}
+		return handler(srv, serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func grpcMemberships(ctx context.Context, logger *slog.Logger, source MembershipSource) (context.Context, error) {
+	log := logger.With(
+		logAttrSVC(),
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Memberships"),
+	)
+	log.Debug("called")
+	ctx, err := withMemberships(ctx, log, source)
+	if err != nil {
+		return ctx, rpcerr.New(codes.Internal, err)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,232 @@
+package mdlw
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	unaryMethod  = "/goserviceex.org.v1.OrgService/GetOrg"
+	streamMethod = "/goserviceex.org.v1.OrgService/ExportOrgs"
+	healthMethod = "/grpc.health.v1.Health/Check"
+)
+
+func incomingCtx(kv ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+}
+
+// unaryCtx calls the interceptor and returns the context the handler got.
+func unaryCtx(ctx context.Context, interceptor grpc.UnaryServerInterceptor, method string) (context.Context, error) {
+	var handlerCtx context.Context
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		handlerCtx = ctx
+		return nil, nil
+	})
+	return handlerCtx, err
+}
+
+// streamCtx calls the interceptor and returns the stream's context the
+// handler got.
+func streamCtx(ctx context.Context, interceptor grpc.StreamServerInterceptor, method string) (context.Context, error) {
+	var handlerCtx context.Context
+	err := interceptor(nil, mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, func(srv any, ss grpc.ServerStream) error {
+		handlerCtx = ss.Context()
+		return nil
+	})
+	return handlerCtx, err
+}
+
+func TestUnaryReqID_NoMetadata(t *testing.T) {
+	ctx, err := unaryCtx(context.Background(), UnaryReqID(testutil.GetLogger()), unaryMethod)
+
+	assert.Nil(t, err)
+	reqID, _ := ctx.Value(ctxutil.ContextKeyReqID{}).(string)
+	assert.Regexp(t, "^generated-", reqID)
+}
+
+func TestUnaryReqID_Metadata(t *testing.T) {
+	ctx, err := unaryCtx(incomingCtx("x-request-id", "foo"), UnaryReqID(testutil.GetLogger()), unaryMethod)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", ctx.Value(ctxutil.ContextKeyReqID{}))
+}
+
+func TestStreamReqID_Metadata(t *testing.T) {
+	ctx, err := streamCtx(incomingCtx("x-request-id", "foo"), StreamReqID(testutil.GetLogger()), streamMethod)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", ctx.Value(ctxutil.ContextKeyReqID{}))
+}
+
+func TestUnaryTrace(t *testing.T) {
+	sr := initTracing()
+	ctx := incomingCtx("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	_, err := UnaryTrace(testutil.GetLogger())(ctx, nil, &grpc.UnaryServerInfo{FullMethod: unaryMethod}, func(ctx context.Context, req any) (any, error) {
+		assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		return nil, rpcerr.New(codes.NotFound, errors.New("unit-test"))
+	})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "goserviceex.org.v1.OrgService/GetOrg", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Parent().SpanID().String())
+	assert.Equal(t, otelcodes.Unset, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("rpc.response.status_code", "NotFound"))
+}
+
+func TestUnaryTrace_NoTraceparent(t *testing.T) {
+	sr := initTracing()
+
+	_, err := unaryCtx(context.Background(), UnaryTrace(testutil.GetLogger()), unaryMethod)
+
+	assert.Nil(t, err)
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("rpc.response.status_code", "OK"))
+}
+
+func TestStreamTrace_Internal(t *testing.T) {
+	sr := initTracing()
+
+	err := StreamTrace(testutil.GetLogger())(nil, mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: streamMethod}, func(srv any, ss grpc.ServerStream) error {
+		assert.True(t, trace.SpanContextFromContext(ss.Context()).IsValid())
+		return rpcerr.New(codes.Internal, errors.New("unit-test"))
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "goserviceex.org.v1.OrgService/ExportOrgs", spans[0].Name())
+	assert.Equal(t, otelcodes.Error, spans[0].Status().Code)
+}
+
+func TestUnaryAuth_NoMetadata(t *testing.T) {
+	ctx, err := unaryCtx(context.Background(), UnaryAuth(testutil.GetLogger(), cfg, nil), unaryMethod)
+
+	assert.Nil(t, ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, problem.CodeUnauthenticated, rpcerr.Reason(err))
+}
+
+func TestUnaryAuth_NotBearer(t *testing.T) {
+	ctx, err := unaryCtx(incomingCtx("authorization", basic(validNonAdminJWT())), UnaryAuth(testutil.GetLogger(), cfg, nil), unaryMethod)
+
+	assert.Nil(t, ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestUnaryAuth_InvalidExpiredToken(t *testing.T) {
+	ctx, err := unaryCtx(incomingCtx("authorization", bearer(invalidExpiredJWT())), UnaryAuth(testutil.GetLogger(), cfg, nil), unaryMethod)
+
+	assert.Nil(t, ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestUnaryAuth_ValidToken(t *testing.T) {
+	ctx, err := unaryCtx(incomingCtx("authorization", bearer(validAdminJWT())), UnaryAuth(testutil.GetLogger(), cfg, nil), unaryMethod)
+
+	assert.Nil(t, err)
+	assert.Equal(t, adminUserID, ctx.Value(ctxutil.ContextKeyUserID{}))
+	claims, _ := ctx.Value(ctxutil.ContextKeyJWTClaims{}).(jwt.MapClaims)
+	assert.Equal(t, true, claims["admin"])
+}
+
+func TestUnaryAuth_PublicService(t *testing.T) {
+	ctx, err := unaryCtx(context.Background(), UnaryAuth(testutil.GetLogger(), cfg, nil, "grpc.health.v1.Health"), healthMethod)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, ctx)
+	assert.Nil(t, ctx.Value(ctxutil.ContextKeyUserID{}))
+}
+
+func TestStreamAuth_NoMetadata(t *testing.T) {
+	ctx, err := streamCtx(context.Background(), StreamAuth(testutil.GetLogger(), cfg, nil), streamMethod)
+
+	assert.Nil(t, ctx)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestStreamAuth_ValidToken(t *testing.T) {
+	ctx, err := streamCtx(incomingCtx("authorization", bearer(validNonAdminJWT())), StreamAuth(testutil.GetLogger(), cfg, nil), streamMethod)
+
+	assert.Nil(t, err)
+	assert.Equal(t, nonAdminUserID, ctx.Value(ctxutil.ContextKeyUserID{}))
+}
+
+func TestUnaryRecovery(t *testing.T) {
+	_, err := UnaryRecovery(testutil.GetLogger())(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: unaryMethod}, func(ctx context.Context, req any) (any, error) {
+		panic("unit-test panic")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestStreamRecovery(t *testing.T) {
+	err := StreamRecovery(testutil.GetLogger())(nil, mockServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: streamMethod}, func(srv any, ss grpc.ServerStream) error {
+		panic("unit-test panic")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestUnaryMemberships(t *testing.T) {
+	source := mockMembershipSource{memberships: []membership.Membership{
+		{UserID: nonAdminUserID, OrgID: "other-org-id", Role: membership.RoleMember},
+	}}
+
+	ctx, err := unaryCtx(loggedInCtx(nonAdminUserID), UnaryMemberships(testutil.GetLogger(), source), unaryMethod)
+
+	assert.Nil(t, err)
+	p, err := authz.FromContext(ctx)
+	assert.Nil(t, err)
+	assert.True(t, p.CanRead("other-org-id"))
+}
+
+func TestUnaryMemberships_NotLoggedIn(t *testing.T) {
+	source := mockMembershipSource{err: errors.New("should not be called")}
+
+	ctx, err := unaryCtx(context.Background(), UnaryMemberships(testutil.GetLogger(), source), healthMethod)
+
+	assert.Nil(t, err)
+	assert.NotNil(t, ctx)
+}
+
+func TestStreamMemberships_SourceErr(t *testing.T) {
+	source := mockMembershipSource{err: errors.New("unit-test db error")}
+
+	ctx, err := streamCtx(loggedInCtx(nonAdminUserID), StreamMemberships(testutil.GetLogger(), source), streamMethod)
+
+	assert.Nil(t, ctx)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (m mockServerStream) Context() context.Context {
+	return m.ctx
+}
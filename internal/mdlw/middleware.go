@@ -7,26 +7,74 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/authz"
 	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/jwks"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func logAttrSVC() slog.Attr {
 	return logutil.LogAttrSVC("Middleware")
 }
 
+// withReqID puts the caller's request id in the context, one is generated when
+// the caller didn't send one.
+func withReqID(ctx context.Context, reqID string) context.Context {
+	if reqID == "" {
+		reqID = "generated-" + uuid.New().String()
+	}
+	return context.WithValue(ctx, ctxutil.ContextKeyReqID{}, reqID)
+}
+
+// Recovery turns a panic into a 500 instead of taking the server down, like
+// gin.Recovery. http.ErrAbortHandler is passed on so net/http drops the
+// connection, that's how a handler that already sent its status (ex. an
+// export that failed part way through) tells the client it's cut short.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			ctx := c.Request.Context()
+			logger.With(
+				logAttrSVC(),
+				logutil.LogAttrReqID(ctx),
+				logutil.LogAttrFN("Recovery"),
+				slog.Any("panic", r),
+			).Error("recovered from panic")
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			apierr.Abort(c, http.StatusInternalServerError, fmt.Errorf("panic: %v", r))
+		}()
+		c.Next()
+	}
+}
+
 func ReqID(logger *slog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		reqID := c.GetHeader("x-request-id")
-		if reqID == "" {
-			reqID = "generated-" + uuid.New().String()
-		}
-		ctx := context.WithValue(c.Request.Context(), ctxutil.ContextKeyReqID{}, reqID)
+		ctx := withReqID(c.Request.Context(), c.GetHeader("x-request-id"))
 		c.Request = c.Request.WithContext(ctx)
 		log := logger.With(
 			logAttrSVC(),
@@ -37,7 +85,45 @@ func ReqID(logger *slog.Logger) gin.HandlerFunc {
 	}
 }
 
-func Auth(logger *slog.Logger, cfg config.AuthConfig) gin.HandlerFunc {
+// Trace continues the caller's trace (W3C traceparent) or starts a new one,
+// the server span covers the rest of the handler chain.
+func Trace(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		reqID, _ := ctx.Value(ctxutil.ContextKeyReqID{}).(string)
+		ctx, span := tracing.Tracer().Start(
+			ctx,
+			fmt.Sprintf("%s %s", c.Request.Method, route),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				attribute.String("request.id", reqID),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		log := logger.With(
+			logAttrSVC(),
+			logutil.LogAttrReqID(ctx),
+			logutil.LogAttrFN("Trace"),
+			slog.String("traceID", span.SpanContext().TraceID().String()),
+		)
+		log.Debug("called")
+		c.Next()
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+func Auth(logger *slog.Logger, cfg config.AuthConfig, keys KeySource) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := logger.With(
 			logAttrSVC(),
@@ -45,47 +131,122 @@ func Auth(logger *slog.Logger, cfg config.AuthConfig) gin.HandlerFunc {
 			logutil.LogAttrFN("Auth"),
 		)
 		log.Debug("called")
-		auth := c.GetHeader("authorization")
-		if auth == "" {
-			log.Warn("Authorization header was missing or empty")
-			c.AbortWithStatus(http.StatusUnauthorized)
-			return
-		}
-		parts := strings.Split(auth, "Bearer ")
-		partsLen := len(parts)
-		if partsLen != 2 {
-			log.With(slog.Int("partsLen", partsLen)).Warn("Authorization header was malformed (not well formed Bearer)")
-			c.AbortWithStatus(http.StatusUnauthorized)
+		ctx, err := authenticate(c.Request.Context(), log, cfg, keys, c.GetHeader("authorization"))
+		if err != nil {
+			apierr.Abort(c, http.StatusUnauthorized, err)
 			return
 		}
-		token := strings.Trim(parts[1], " \t\r\n")
-		claims, err := validateJWT(cfg, token)
+		c.Request = c.Request.WithContext(ctx)
+	}
+}
+
+// authenticate validates the bearer token in auth (the authorization header
+// or metadata) and puts the logged in user's id and claims in the context.
+func authenticate(ctx context.Context, log *slog.Logger, cfg config.AuthConfig, keys KeySource, auth string) (context.Context, error) {
+	if auth == "" {
+		log.Warn("Authorization header was missing or empty")
+		return ctx, authz.ErrUnauthenticated{}
+	}
+	parts := strings.Split(auth, "Bearer ")
+	partsLen := len(parts)
+	if partsLen != 2 {
+		log.With(slog.Int("partsLen", partsLen)).Warn("Authorization header was malformed (not well formed Bearer)")
+		return ctx, authz.ErrUnauthenticated{}
+	}
+	token := strings.Trim(parts[1], " \t\r\n")
+	claims, err := validateJWT(ctx, cfg, keys, token)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("jwt validation failed")
+		return ctx, authz.ErrUnauthenticated{}
+	}
+	userID := claims["sub"]
+	ctx = context.WithValue(ctx, ctxutil.ContextKeyUserID{}, userID)
+	ctx = context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
+	log.With(
+		slog.Any("claims", claims),
+		logutil.LogAttrLoggedInUserID(ctx),
+	).Debug("Token was valid, proceeding")
+	return ctx, nil
+}
+
+// MembershipSource is where the logged in user's memberships come from (the
+// membership dao), they're read on every request so adding or removing one
+// takes effect without a new token.
+type MembershipSource interface {
+	GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error)
+}
+
+// Memberships goes after Auth, it puts the logged in user's role in each of
+// their orgs in the context for authz.FromContext.
+func Memberships(logger *slog.Logger, source MembershipSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.With(
+			logAttrSVC(),
+			logutil.LogAttrReqID(c.Request.Context()),
+			logutil.LogAttrLoggedInUserID(c.Request.Context()),
+			logutil.LogAttrFN("Memberships"),
+		)
+		log.Debug("called")
+		ctx, err := withMemberships(c.Request.Context(), log, source)
 		if err != nil {
-			log.With(logutil.LogAttrError(err)).Warn("jwt validation failed")
-			c.AbortWithStatus(http.StatusUnauthorized)
+			apierr.Abort(c, http.StatusInternalServerError, err)
 			return
 		}
-		userID := claims["sub"]
-		ctx := context.WithValue(c.Request.Context(), ctxutil.ContextKeyUserID{}, userID)
-		ctx = context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
-		log.With(
-			slog.Any("claims", claims),
-			logutil.LogAttrLoggedInUserID(ctx),
-		).Debug("Token was valid, proceeding")
 		c.Request = c.Request.WithContext(ctx)
 	}
 }
 
-func validateJWT(cfg config.AuthConfig, tokenStr string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		method, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, fmt.Errorf("unexpected signing algorithm: %v", token.Header["alg"])
+// withMemberships leaves ctx alone when no one is logged in.
+func withMemberships(ctx context.Context, log *slog.Logger, source MembershipSource) (context.Context, error) {
+	userID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if userID == "" {
+		return ctx, nil
+	}
+	memberships, err := source.GetByUserID(ctx, userID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to load memberships")
+		return ctx, err
+	}
+	roles := make(map[string]string, len(memberships))
+	for _, m := range memberships {
+		roles[m.OrgID] = m.Role
+	}
+	return authz.WithMemberships(ctx, roles), nil
+}
+
+type KeySource interface {
+	Key(ctx context.Context, kid string) (jwks.Key, error)
+}
+
+// validateJWT accepts HS256 when a shared secret is configured and
+// RS256/ES256/EdDSA when a key source is, the key is picked by the token's kid.
+func validateJWT(ctx context.Context, cfg config.AuthConfig, keys KeySource, tokenStr string) (jwt.MapClaims, error) {
+	var methods []string
+	if cfg.JWTSecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if keys != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg())
+	}
+	if len(methods) == 0 {
+		// an empty list means any method to the parser
+		return nil, errors.New("no jwt secret or key source configured")
+	}
+	// claims are validated below so clock skew can be applied
+	parser := jwt.NewParser(jwt.WithValidMethods(methods), jwt.WithoutClaimsValidation())
+	token, err := parser.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		if token.Method == jwt.SigningMethodHS256 {
+			return []byte(cfg.JWTSecret), nil
 		}
-		if method != jwt.SigningMethodHS256 {
-			return nil, fmt.Errorf("unexpected signing method: %v", method)
+		kid, _ := token.Header["kid"].(string)
+		key, err := keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Alg != "" && key.Alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key does not allow signing method: kid=%s alg=%s method=%s", kid, key.Alg, token.Method.Alg())
 		}
-		return []byte(cfg.JWTSecret), nil
+		return key.Key, nil
 	})
 	if err != nil {
 		return nil, err
@@ -95,6 +256,21 @@ func validateJWT(cfg config.AuthConfig, tokenStr string) (jwt.MapClaims, error)
 	if !ok {
 		return nil, errors.New("could not cast to jwt.MapClaims")
 	}
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-cfg.ClockSkew).Unix(), false) {
+		return nil, errors.New("token is expired")
+	}
+	if !claims.VerifyNotBefore(now.Add(cfg.ClockSkew).Unix(), false) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if !claims.VerifyIssuedAt(now.Add(cfg.ClockSkew).Unix(), false) {
+		return nil, errors.New("token was issued in the future")
+	}
+	for _, name := range cfg.RequiredClaims {
+		if _, ok := claims[name]; !ok {
+			return nil, fmt.Errorf("required claim was missing: %s", name)
+		}
+	}
 	if !claims.VerifyAudience(cfg.JWTAudience, true) {
 		return nil, errors.New("audience was invalid")
 	}
@@ -103,33 +279,3 @@ func validateJWT(cfg config.AuthConfig, tokenStr string) (jwt.MapClaims, error)
 	}
 	return claims, nil
 }
-
-func RequiresAdmin(logger *slog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ctx := c.Request.Context()
-		log := logger.With(
-			logAttrSVC(),
-			logutil.LogAttrReqID(ctx),
-			logutil.LogAttrLoggedInUserID(ctx),
-			logutil.LogAttrFN("RequiresAdmin"),
-		)
-		log.Debug("called")
-		claims, ok := ctx.Value(ctxutil.ContextKeyJWTClaims{}).(jwt.MapClaims)
-		if !ok {
-			log.Warn("jwtClaims not in context")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
-		isAdmin, ok := claims["admin"].(bool)
-		if !ok {
-			log.Warn("admin claim not in jwtClaims")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
-		if !isAdmin {
-			log.Warn("admin claim is false")
-			c.AbortWithStatus(http.StatusForbidden)
-			return
-		}
-	}
-}
@@ -2,7 +2,14 @@ package mdlw
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,11 +17,22 @@ import (
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
 	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/jwks"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -183,6 +201,44 @@ func invalidSecretJWT() string {
 	return tokenStr
 }
 
+var (
+	rsaKey, _        = rsa.GenerateKey(rand.Reader, 2048)
+	rotatedRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _         = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _      = ed25519.GenerateKey(rand.Reader)
+)
+
+type mockKeySource map[string]jwks.Key
+
+func (m mockKeySource) Key(ctx context.Context, kid string) (jwks.Key, error) {
+	k, ok := m[kid]
+	if !ok {
+		return jwks.Key{}, jwks.ErrKeyNotFound{KeyID: kid}
+	}
+	return k, nil
+}
+
+func keySource() mockKeySource {
+	return mockKeySource{
+		"rsa":         {ID: "rsa", Alg: "RS256", Key: &rsaKey.PublicKey},
+		"rsa-rotated": {ID: "rsa-rotated", Key: &rotatedRSAKey.PublicKey},
+		"rsa-ps256":   {ID: "rsa-ps256", Alg: "PS256", Key: &rsaKey.PublicKey},
+		"ec":          {ID: "ec", Alg: "ES256", Key: &ecKey.PublicKey},
+		"ed":          {ID: "ed", Alg: "EdDSA", Key: edKey.Public()},
+	}
+}
+
+func signedJWT(method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	tokenStr, err := token.SignedString(key)
+	if err != nil {
+		testutil.GetLogger().With(logutil.LogAttrError(err)).Error("failed to sign jwt")
+		panic(err)
+	}
+	return tokenStr
+}
+
 func ginContext(headers map[string]string) (*gin.Context, *httptest.ResponseRecorder, error) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -198,6 +254,44 @@ func ginContext(headers map[string]string) (*gin.Context, *httptest.ResponseReco
 	return gc, w, nil
 }
 
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery(testutil.GetLogger()))
+	r.GET("/", func(c *gin.Context) {
+		panic("unit-test panic")
+	})
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+}
+
+func TestRecovery_AbortHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Recovery(testutil.GetLogger()))
+	r.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("partial")
+		c.Writer.Flush()
+		panic(http.ErrAbortHandler)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	_, err = io.ReadAll(res.Body)
+
+	// the body is cut off, not ended
+	assert.Equal(t, 200, res.StatusCode)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
 func TestReqID_NoHeader(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{})
 	assert.Nil(t, err)
@@ -226,15 +320,63 @@ func TestReqID_Header(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func initTracing() *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return sr
+}
+
+func TestTrace(t *testing.T) {
+	sr := initTracing()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Trace(testutil.GetLogger()))
+	r.GET("/users/:id", func(c *gin.Context) {
+		assert.True(t, trace.SpanContextFromContext(c.Request.Context()).IsValid())
+		c.Status(http.StatusInternalServerError)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /users/:id", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", spans[0].Parent().SpanID().String())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", 500))
+}
+
+func TestTrace_NoTraceparent(t *testing.T) {
+	sr := initTracing()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Trace(testutil.GetLogger()))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET unmatched", spans[0].Name())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
 func TestAuth_NoHeader(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
 	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"code":"unauthenticated"`)
 	actual := c.Value(ctxutil.ContextKeyUserID{})
 	assert.Nil(t, actual)
 }
@@ -243,7 +385,7 @@ func TestAuth_NotBearer(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": basic(validAdminJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -256,7 +398,7 @@ func TestAuth_MalformedTooManyParts(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(bearer(validAdminJWT()))})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -269,7 +411,7 @@ func TestAuth_InvalidExpiredToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(invalidExpiredJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -282,7 +424,7 @@ func TestAuth_InvalidIssuerToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(invalidIssuerJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -295,7 +437,7 @@ func TestAuth_InvalidAudienceToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(invalidAudienceJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -308,7 +450,7 @@ func TestAuth_InvalidSecretToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(invalidSecretJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -321,7 +463,7 @@ func TestAuth_InvalidHMACAlgToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(invalidHMACSigningMethodJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -334,7 +476,7 @@ func TestAuth_InvalidRSAToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(invalidRSAJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -347,7 +489,7 @@ func TestAuth_ValidNonAdminToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(validNonAdminJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -363,7 +505,7 @@ func TestAuth_ValidAdminToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(validAdminJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -379,7 +521,7 @@ func TestAuth_ValidExplicitAdminFalseToken(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{"Authorization": bearer(validNonAdminExplicitFalseJWT())})
 	assert.Nil(t, err)
 
-	mw := Auth(testutil.GetLogger(), cfg)
+	mw := Auth(testutil.GetLogger(), cfg, nil)
 	mw(gc)
 	c := gc.Request.Context()
 
@@ -391,55 +533,178 @@ func TestAuth_ValidExplicitAdminFalseToken(t *testing.T) {
 	assert.Equal(t, false, admin)
 }
 
-func TestRequiresAdmin_Admin(t *testing.T) {
-	gc, w, err := ginContext(map[string]string{})
+func assertAuthorized(t *testing.T, authCfg config.AuthConfig, token string) {
+	gc, w, err := ginContext(map[string]string{"Authorization": bearer(token)})
 	assert.Nil(t, err)
 
-	claims := jwt.MapClaims{
-		"admin": true,
-	}
-	gc.Request = gc.Request.WithContext(context.WithValue(gc.Request.Context(), ctxutil.ContextKeyJWTClaims{}, claims))
-
-	mw := RequiresAdmin(testutil.GetLogger())
+	mw := Auth(testutil.GetLogger(), authCfg, keySource())
 	mw(gc)
+	c := gc.Request.Context()
 
 	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.Equal(t, adminUserID, c.Value(ctxutil.ContextKeyUserID{}))
 }
 
-func TestRequiresAdmin_NonAdmin(t *testing.T) {
-	gc, w, err := ginContext(map[string]string{})
+func assertUnauthorized(t *testing.T, authCfg config.AuthConfig, token string) {
+	gc, w, err := ginContext(map[string]string{"Authorization": bearer(token)})
 	assert.Nil(t, err)
 
-	claims := jwt.MapClaims{}
-	gc.Request = gc.Request.WithContext(context.WithValue(gc.Request.Context(), ctxutil.ContextKeyJWTClaims{}, claims))
+	mw := Auth(testutil.GetLogger(), authCfg, keySource())
+	mw(gc)
+	c := gc.Request.Context()
+
+	assert.Equal(t, 401, w.Result().StatusCode)
+	assert.Nil(t, c.Value(ctxutil.ContextKeyUserID{}))
+}
+
+func TestAuth_ValidRS256Token(t *testing.T) {
+	assertAuthorized(t, cfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, validClaims(adminUserID)))
+}
+
+func TestAuth_ValidRotatedRS256Token(t *testing.T) {
+	assertAuthorized(t, cfg, signedJWT(jwt.SigningMethodRS256, "rsa-rotated", rotatedRSAKey, validClaims(adminUserID)))
+}
 
-	mw := RequiresAdmin(testutil.GetLogger())
+func TestAuth_ValidES256Token(t *testing.T) {
+	assertAuthorized(t, cfg, signedJWT(jwt.SigningMethodES256, "ec", ecKey, validClaims(adminUserID)))
+}
+
+func TestAuth_ValidEdDSAToken(t *testing.T) {
+	assertAuthorized(t, cfg, signedJWT(jwt.SigningMethodEdDSA, "ed", edKey, validClaims(adminUserID)))
+}
+
+func TestAuth_UnknownKid(t *testing.T) {
+	assertUnauthorized(t, cfg, signedJWT(jwt.SigningMethodRS256, "unknown", rsaKey, validClaims(adminUserID)))
+}
+
+func TestAuth_WrongKeyForKid(t *testing.T) {
+	assertUnauthorized(t, cfg, signedJWT(jwt.SigningMethodRS256, "rsa", rotatedRSAKey, validClaims(adminUserID)))
+}
+
+func TestAuth_KeyAlgMismatch(t *testing.T) {
+	assertUnauthorized(t, cfg, signedJWT(jwt.SigningMethodRS256, "rsa-ps256", rsaKey, validClaims(adminUserID)))
+}
+
+func TestAuth_UnsupportedAsymmetricAlg(t *testing.T) {
+	assertUnauthorized(t, cfg, signedJWT(jwt.SigningMethodRS512, "rsa-rotated", rotatedRSAKey, validClaims(adminUserID)))
+}
+
+func TestAuth_HS256WithoutSecret(t *testing.T) {
+	noSecretCfg := cfg
+	noSecretCfg.JWTSecret = ""
+	assertUnauthorized(t, noSecretCfg, hmacJWT(validClaims(adminUserID)))
+}
+
+func TestAuth_NoSecretOrKeySource(t *testing.T) {
+	noSecretCfg := cfg
+	noSecretCfg.JWTSecret = ""
+	gc, w, err := ginContext(map[string]string{"Authorization": bearer(hmacJWT(validClaims(adminUserID)))})
+	assert.Nil(t, err)
+
+	mw := Auth(testutil.GetLogger(), noSecretCfg, nil)
 	mw(gc)
 
-	assert.Equal(t, 403, w.Result().StatusCode)
+	assert.Equal(t, 401, w.Result().StatusCode)
+}
+
+func TestAuth_ExpiredWithinClockSkew(t *testing.T) {
+	skewCfg := cfg
+	skewCfg.ClockSkew = time.Minute
+	claims := validClaims(adminUserID)
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	assertAuthorized(t, skewCfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+}
+
+func TestAuth_ExpiredBeyondClockSkew(t *testing.T) {
+	skewCfg := cfg
+	skewCfg.ClockSkew = time.Minute
+	claims := validClaims(adminUserID)
+	claims["exp"] = time.Now().Add(-2 * time.Minute).Unix()
+	assertUnauthorized(t, skewCfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, claims))
 }
 
-func TestRequiresAdmin_AdminFalse(t *testing.T) {
+func TestAuth_NotBeforeWithinClockSkew(t *testing.T) {
+	skewCfg := cfg
+	skewCfg.ClockSkew = time.Minute
+	claims := validClaims(adminUserID)
+	claims["nbf"] = time.Now().Add(30 * time.Second).Unix()
+	assertAuthorized(t, skewCfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+}
+
+func TestAuth_NotBeforeBeyondClockSkew(t *testing.T) {
+	skewCfg := cfg
+	skewCfg.ClockSkew = time.Minute
+	claims := validClaims(adminUserID)
+	claims["nbf"] = time.Now().Add(2 * time.Minute).Unix()
+	assertUnauthorized(t, skewCfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, claims))
+}
+
+func TestAuth_MissingRequiredClaim(t *testing.T) {
+	requiredCfg := cfg
+	requiredCfg.RequiredClaims = []string{"sub", "exp", "email"}
+	assertUnauthorized(t, requiredCfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, validClaims(adminUserID)))
+}
+
+func TestAuth_RequiredClaimsPresent(t *testing.T) {
+	requiredCfg := cfg
+	requiredCfg.RequiredClaims = []string{"sub", "exp"}
+	assertAuthorized(t, requiredCfg, signedJWT(jwt.SigningMethodRS256, "rsa", rsaKey, validClaims(adminUserID)))
+}
+
+func loggedInCtx(userID string) context.Context {
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, userID)
+	return context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, jwt.MapClaims{"sub": userID})
+}
+
+func TestMemberships(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{})
 	assert.Nil(t, err)
+	gc.Request = gc.Request.WithContext(loggedInCtx(nonAdminUserID))
+	source := mockMembershipSource{memberships: []membership.Membership{
+		{UserID: nonAdminUserID, OrgID: "home-org-id", Role: membership.RoleMember, IsHome: true},
+		{UserID: nonAdminUserID, OrgID: "other-org-id", Role: membership.RoleOrgAdmin},
+	}}
 
-	claims := jwt.MapClaims{
-		"admin": false,
-	}
-	gc.Request = gc.Request.WithContext(context.WithValue(gc.Request.Context(), ctxutil.ContextKeyJWTClaims{}, claims))
+	mw := Memberships(testutil.GetLogger(), source)
+	mw(gc)
 
-	mw := RequiresAdmin(testutil.GetLogger())
+	assert.Equal(t, 200, w.Result().StatusCode)
+	p, err := authz.FromContext(gc.Request.Context())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"home-org-id": membership.RoleMember, "other-org-id": membership.RoleOrgAdmin}, p.Memberships)
+	assert.True(t, p.CanManage("other-org-id"))
+}
+
+func TestMemberships_NotLoggedIn(t *testing.T) {
+	gc, w, err := ginContext(map[string]string{})
+	assert.Nil(t, err)
+	source := mockMembershipSource{err: errors.New("should not be called")}
+
+	mw := Memberships(testutil.GetLogger(), source)
 	mw(gc)
 
-	assert.Equal(t, 403, w.Result().StatusCode)
+	assert.Equal(t, 200, w.Result().StatusCode)
+	assert.False(t, gc.IsAborted())
 }
 
-func TestRequiresAdmin_NoClaimsAvailable(t *testing.T) {
+func TestMemberships_SourceErr(t *testing.T) {
 	gc, w, err := ginContext(map[string]string{})
 	assert.Nil(t, err)
+	gc.Request = gc.Request.WithContext(loggedInCtx(nonAdminUserID))
+	source := mockMembershipSource{err: errors.New("unit-test db error")}
 
-	mw := RequiresAdmin(testutil.GetLogger())
+	mw := Memberships(testutil.GetLogger(), source)
 	mw(gc)
 
-	assert.Equal(t, 403, w.Result().StatusCode)
+	assert.Equal(t, 500, w.Result().StatusCode)
+	assert.True(t, gc.IsAborted())
+}
+
+type mockMembershipSource struct {
+	memberships []membership.Membership
+	err         error
+}
+
+func (m mockMembershipSource) GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error) {
+	return m.memberships, m.err
 }
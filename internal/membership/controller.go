@@ -0,0 +1,130 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/gin-gonic/gin"
+)
+
+type MembershipService interface {
+	GetByOrgID(ctx context.Context, orgID string, pr page.Request) (membership.MembershipPage, error)
+	GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error)
+	Add(ctx context.Context, orgID string, input membership.AddMembership) (membership.Membership, error)
+	Remove(ctx context.Context, orgID string, userID string) error
+}
+
+type ctrl struct {
+	log     *slog.Logger
+	service MembershipService
+}
+
+func NewController(log *slog.Logger, service MembershipService) *ctrl {
+	return &ctrl{
+		log:     log.With(logutil.LogAttrSVC("MembershipCTL")),
+		service: service,
+	}
+}
+
+func (ctr ctrl) GetByOrgID(c *gin.Context) {
+	ctx := c.Request.Context()
+	orgID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByOrgID"),
+		logAttrOrgID(orgID),
+	)
+	log.Debug("called")
+	pr, err := page.ParseRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	mp, err := ctr.service.GetByOrgID(ctx, orgID, pr)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrMembershipsLen(len(mp.Memberships))).Debug("success")
+	c.JSON(http.StatusOK, mp)
+}
+
+func (ctr ctrl) GetByUserID(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByUserID"),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	memberships, err := ctr.service.GetByUserID(ctx, userID)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrMembershipsLen(len(memberships))).Debug("success")
+	c.JSON(http.StatusOK, memberships)
+}
+
+func (ctr ctrl) Add(c *gin.Context) {
+	ctx := c.Request.Context()
+	orgID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Add"),
+		logAttrOrgID(orgID),
+	)
+	log.Debug("called")
+	var input membership.AddMembership
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrMembership(input))
+	log.Debug("body processed, about to call service")
+	m, err := ctr.service.Add(ctx, orgID, input)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.JSON(http.StatusOK, m)
+}
+
+func (ctr ctrl) Remove(c *gin.Context) {
+	ctx := c.Request.Context()
+	orgID := c.Param("id")
+	userID := c.Param("userID")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Remove"),
+		logAttrOrgID(orgID),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	err := ctr.service.Remove(ctx, orgID, userID)
+	if err != nil {
+		if errors.As(err, &ErrNotFound{}) {
+			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not removing")
+			c.Status(http.StatusNoContent)
+			return
+		}
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.Status(http.StatusNoContent)
+}
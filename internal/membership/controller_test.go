@@ -0,0 +1,260 @@
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/user"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSVC struct {
+	mock.Mock
+}
+
+func initCTRL() (c *ctrl, ms *mockSVC) {
+	log := testutil.GetLogger()
+	ms = new(mockSVC)
+	c = NewController(log, ms)
+	return c, ms
+}
+
+func ginCtx(url string, body string, params ...gin.Param) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	gc, _ := gin.CreateTestContext(w)
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	if err != nil {
+		return nil, w, err
+	}
+	gc.Request = req
+	gc.Params = params
+	return gc, w, nil
+}
+
+func TestCTRLGetByOrgID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?limit=1", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	mockRes := membership.MembershipPage{
+		Memberships: []membership.Membership{{UserID: "foo-user-id", OrgID: "foo-org-id", Role: membership.RoleMember, JoinedAt: time.UnixMilli(100).UTC()}},
+		NextCursor:  "next",
+	}
+	ms.On("GetByOrgID", mock.Anything, "foo-org-id", page.Request{Limit: 1}).Return(mockRes, nil)
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual membership.MembershipPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLGetByOrgID_InvalidLimit(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?limit=abc", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "GetByOrgID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLGetByOrgID_Forbidden(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	ms.On("GetByOrgID", mock.Anything, "foo-org-id", mock.Anything).Return(membership.MembershipPage{}, authz.ErrForbidden{})
+
+	c.GetByOrgID(gc)
+
+	assert.Equal(t, 403, w.Code)
+}
+
+func TestCTRLGetByUserID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-user-id"})
+	assert.Nil(t, err)
+
+	mockRes := []membership.Membership{{UserID: "foo-user-id", OrgID: "foo-org-id", IsHome: true, JoinedAt: time.UnixMilli(100).UTC()}}
+	ms.On("GetByUserID", mock.Anything, "foo-user-id").Return(mockRes, nil)
+
+	c.GetByUserID(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual []membership.Membership
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLGetByUserID_NoMemberships(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-user-id"})
+	assert.Nil(t, err)
+
+	ms.On("GetByUserID", mock.Anything, "foo-user-id").Return([]membership.Membership(nil), ErrNoMemberships{UserID: "foo-user-id"})
+
+	c.GetByUserID(gc)
+
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestCTRLAdd(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"user_id": "foo-user-id", "role": "org_admin"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	input := membership.AddMembership{UserID: "foo-user-id", Role: membership.RoleOrgAdmin}
+	mockRes := membership.Membership{UserID: "foo-user-id", OrgID: "foo-org-id", Role: membership.RoleOrgAdmin, JoinedAt: time.UnixMilli(100).UTC()}
+	ms.On("Add", mock.Anything, "foo-org-id", input).Return(mockRes, nil)
+
+	c.Add(gc)
+
+	assert.Equal(t, 200, w.Code)
+	var actual membership.Membership
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &actual))
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLAdd_InvalidRole(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"user_id": "foo-user-id", "role": "owner"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	c.Add(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLAdd_MissingUserID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	c.Add(gc)
+
+	assert.Equal(t, 400, w.Code)
+	ms.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func assertAddErrStatus(t *testing.T, mockErr error, expected int) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", `{"user_id": "foo-user-id"}`, gin.Param{Key: "id", Value: "foo-org-id"})
+	assert.Nil(t, err)
+
+	ms.On("Add", mock.Anything, "foo-org-id", mock.Anything).Return(membership.Membership{}, mockErr)
+
+	c.Add(gc)
+
+	assert.Equal(t, expected, w.Code)
+	assert.Contains(t, w.Body.String(), mockErr.Error())
+}
+
+func TestCTRLAdd_OrgNotFound(t *testing.T) {
+	assertAddErrStatus(t, org.ErrNotFound{ID: "foo-org-id"}, 404)
+}
+
+func TestCTRLAdd_UserNotFound(t *testing.T) {
+	assertAddErrStatus(t, user.ErrNotFound{ID: "foo-user-id"}, 404)
+}
+
+func TestCTRLAdd_AlreadyMember(t *testing.T) {
+	assertAddErrStatus(t, ErrAlreadyMember{OrgID: "foo-org-id", UserID: "foo-user-id"}, 409)
+}
+
+func TestCTRLAdd_SysUser(t *testing.T) {
+	assertAddErrStatus(t, ErrCannotAddSysUser{UserID: "foo-user-id"}, 403)
+}
+
+func TestCTRLAdd_Forbidden(t *testing.T) {
+	assertAddErrStatus(t, authz.ErrForbidden{UserID: "logged-in-user-id", Action: "membership:add"}, 403)
+}
+
+func TestCTRLAdd_OtherErr(t *testing.T) {
+	assertAddErrStatus(t, errors.New("unit-test mock error"), 500)
+}
+
+func TestCTRLRemove(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-org-id"}, gin.Param{Key: "userID", Value: "foo-user-id"})
+	assert.Nil(t, err)
+
+	ms.On("Remove", mock.Anything, "foo-org-id", "foo-user-id").Return(nil)
+
+	c.Remove(gc)
+
+	assert.Equal(t, 204, gc.Writer.Status())
+}
+
+func TestCTRLRemove_AlreadyGone(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-org-id"}, gin.Param{Key: "userID", Value: "foo-user-id"})
+	assert.Nil(t, err)
+
+	ms.On("Remove", mock.Anything, "foo-org-id", "foo-user-id").Return(ErrNotFound{OrgID: "foo-org-id", UserID: "foo-user-id"})
+
+	c.Remove(gc)
+
+	assert.Equal(t, 204, gc.Writer.Status())
+}
+
+func TestCTRLRemove_Home(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-org-id"}, gin.Param{Key: "userID", Value: "foo-user-id"})
+	assert.Nil(t, err)
+
+	ms.On("Remove", mock.Anything, "foo-org-id", "foo-user-id").Return(ErrCannotRemoveHomeMembership{OrgID: "foo-org-id", UserID: "foo-user-id"})
+
+	c.Remove(gc)
+
+	assert.Equal(t, 409, w.Code)
+}
+
+func TestCTRLRemove_Forbidden(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/", "", gin.Param{Key: "id", Value: "foo-org-id"}, gin.Param{Key: "userID", Value: "foo-user-id"})
+	assert.Nil(t, err)
+
+	ms.On("Remove", mock.Anything, "foo-org-id", "foo-user-id").Return(authz.ErrForbidden{})
+
+	c.Remove(gc)
+
+	assert.Equal(t, 403, w.Code)
+}
+
+func (s *mockSVC) GetByOrgID(ctx context.Context, orgID string, pr page.Request) (membership.MembershipPage, error) {
+	args := s.Called(ctx, orgID, pr)
+	return args.Get(0).(membership.MembershipPage), args.Error(1)
+}
+
+func (s *mockSVC) GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error) {
+	args := s.Called(ctx, userID)
+	return args.Get(0).([]membership.Membership), args.Error(1)
+}
+
+func (s *mockSVC) Add(ctx context.Context, orgID string, input membership.AddMembership) (membership.Membership, error) {
+	args := s.Called(ctx, orgID, input)
+	return args.Get(0).(membership.Membership), args.Error(1)
+}
+
+func (s *mockSVC) Remove(ctx context.Context, orgID string, userID string) error {
+	args := s.Called(ctx, orgID, userID)
+	return args.Error(0)
+}
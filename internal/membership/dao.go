@@ -0,0 +1,173 @@
+package membership
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type dao struct {
+	log     *slog.Logger
+	timeout time.Duration
+	db      *sqlx.DB
+}
+
+func NewDAO(log *slog.Logger, timeout time.Duration, db *sqlx.DB) *dao {
+	return &dao{
+		log:     log.With(logutil.LogAttrSVC("MembershipDAO")),
+		timeout: timeout,
+		db:      db,
+	}
+}
+
+func (d dao) GetByOrgID(ctx context.Context, orgID string, after *page.Cursor, limit int) (memberships []membership.Membership, err error) {
+	ctx, span := tracing.StartDB(ctx, "MembershipDAO.GetByOrgID")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByOrgID"),
+		logAttrOrgID(orgID),
+		logAttrAfter(after),
+		logAttrLimit(limit),
+	)
+	log.Debug("called")
+	memberships = []membership.Membership{}
+	if after == nil {
+		span.SetAttributes(tracing.AttrStatement("getByOrgIDQuery"))
+		err = d.db.SelectContext(ctx, &memberships, getByOrgIDQuery, orgID, limit)
+	} else {
+		span.SetAttributes(tracing.AttrStatement("getByOrgIDAfterQuery"))
+		err = d.db.SelectContext(ctx, &memberships, getByOrgIDAfterQuery, orgID, after.CreatedAt, after.ID, limit)
+	}
+	if err != nil {
+		return memberships, err
+	}
+	log.Debug("success")
+	return memberships, err
+}
+
+func (d dao) GetByUserID(ctx context.Context, userID string) (memberships []membership.Membership, err error) {
+	ctx, span := tracing.StartDB(ctx, "MembershipDAO.GetByUserID")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByUserID"),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getByUserIDQuery"))
+	memberships = []membership.Membership{}
+	err = d.db.SelectContext(ctx, &memberships, getByUserIDQuery, userID)
+	if err != nil {
+		return memberships, err
+	}
+	log.With(logAttrMembershipsLen(len(memberships))).Debug("success")
+	return memberships, err
+}
+
+// GetForUpdate returns the membership and locks it until tx ends.
+func (d dao) GetForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) (m membership.Membership, err error) {
+	ctx, span := tracing.StartDB(ctx, "MembershipDAO.GetForUpdate")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetForUpdate"),
+		logAttrOrgID(orgID),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getForUpdateQuery"))
+	err = tx.GetContext(ctx, &m, getForUpdateQuery, orgID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return m, ErrNotFound{OrgID: orgID, UserID: userID}
+		}
+		return m, err
+	}
+	log.Debug("success")
+	return m, err
+}
+
+func (d dao) Create(ctx context.Context, tx *sqlx.Tx, m membership.Membership) (err error) {
+	ctx, span := tracing.StartDB(ctx, "MembershipDAO.Create")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrMembership(m),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("createQuery"))
+	r, err := tx.NamedExecContext(ctx, createQuery, &m)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			if pqErr.Constraint == "memberships_pk" {
+				return ErrAlreadyMember{OrgID: m.OrgID, UserID: m.UserID}
+			}
+		}
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows != 1 {
+		return fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	return err
+}
+
+func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "MembershipDAO.Delete")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Delete"),
+		logAttrOrgID(orgID),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("deleteQuery"))
+	r, err := tx.ExecContext(ctx, deleteQuery, orgID, userID)
+	if err != nil {
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows == 0 {
+		return ErrNotFound{OrgID: orgID, UserID: userID}
+	}
+	log.Debug("success")
+	return err
+}
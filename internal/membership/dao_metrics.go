@@ -0,0 +1,82 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/jmoiron/sqlx"
+)
+
+const daoName = "MembershipDAO"
+
+type DAOMetrics interface {
+	ObserveQuery(dao string, method string, d time.Duration, errClass string)
+}
+
+// instrumentedDAO records the duration and error class of every
+// MembershipDAO call.
+type instrumentedDAO struct {
+	dao     MembershipDAO
+	metrics DAOMetrics
+}
+
+func NewInstrumentedDAO(dao MembershipDAO, metrics DAOMetrics) *instrumentedDAO {
+	return &instrumentedDAO{
+		dao:     dao,
+		metrics: metrics,
+	}
+}
+
+func (d instrumentedDAO) observe(method string, start time.Time, err error) {
+	d.metrics.ObserveQuery(daoName, method, time.Since(start), errClass(err))
+}
+
+func (d instrumentedDAO) GetByOrgID(ctx context.Context, orgID string, after *page.Cursor, limit int) (memberships []membership.Membership, err error) {
+	start := time.Now()
+	memberships, err = d.dao.GetByOrgID(ctx, orgID, after, limit)
+	d.observe("GetByOrgID", start, err)
+	return memberships, err
+}
+
+func (d instrumentedDAO) GetByUserID(ctx context.Context, userID string) (memberships []membership.Membership, err error) {
+	start := time.Now()
+	memberships, err = d.dao.GetByUserID(ctx, userID)
+	d.observe("GetByUserID", start, err)
+	return memberships, err
+}
+
+func (d instrumentedDAO) GetForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) (m membership.Membership, err error) {
+	start := time.Now()
+	m, err = d.dao.GetForUpdate(ctx, tx, orgID, userID)
+	d.observe("GetForUpdate", start, err)
+	return m, err
+}
+
+func (d instrumentedDAO) Create(ctx context.Context, tx *sqlx.Tx, m membership.Membership) (err error) {
+	start := time.Now()
+	err = d.dao.Create(ctx, tx, m)
+	d.observe("Create", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Delete(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) (err error) {
+	start := time.Now()
+	err = d.dao.Delete(ctx, tx, orgID, userID)
+	d.observe("Delete", start, err)
+	return err
+}
+
+func errClass(err error) string {
+	switch {
+	case errors.As(err, &ErrNotFound{}):
+		return "not_found"
+	case errors.As(err, &ErrAlreadyMember{}):
+		return "conflict"
+	default:
+		return metrics.ErrClass(err)
+	}
+}
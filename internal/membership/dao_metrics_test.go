@@ -0,0 +1,65 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAOMetrics struct {
+	mock.Mock
+}
+
+func (m *mockDAOMetrics) ObserveQuery(dao string, method string, d time.Duration, errClass string) {
+	m.Called(dao, method, d, errClass)
+}
+
+func initInstrumentedDAO() (d *instrumentedDAO, md *mockDAO, mm *mockDAOMetrics) {
+	md = new(mockDAO)
+	mm = new(mockDAOMetrics)
+	d = NewInstrumentedDAO(md, mm)
+	return d, md, mm
+}
+
+func TestInstrumentedDAO_GetByUserID(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	mockRes := []membership.Membership{{UserID: "foo-user-id", OrgID: "foo-org-id"}}
+	md.On("GetByUserID", ctx, "foo-user-id").Return(mockRes, nil)
+	mm.On("ObserveQuery", daoName, "GetByUserID", mock.AnythingOfType("time.Duration"), "none")
+
+	actual, err := d.GetByUserID(ctx, "foo-user-id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	mm.AssertExpectations(t)
+}
+
+func assertErrClass(t *testing.T, mockErr error, expected string) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	md.On("Delete", ctx, mock.Anything, "foo-org-id", "foo-user-id").Return(mockErr)
+	mm.On("ObserveQuery", daoName, "Delete", mock.AnythingOfType("time.Duration"), expected)
+
+	err := d.Delete(ctx, nil, "foo-org-id", "foo-user-id")
+
+	assert.Equal(t, mockErr, err)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_NotFound(t *testing.T) {
+	assertErrClass(t, ErrNotFound{OrgID: "foo-org-id", UserID: "foo-user-id"}, "not_found")
+}
+
+func TestInstrumentedDAO_AlreadyMember(t *testing.T) {
+	assertErrClass(t, ErrAlreadyMember{OrgID: "foo-org-id", UserID: "foo-user-id"}, "conflict")
+}
+
+func TestInstrumentedDAO_OtherErr(t *testing.T) {
+	assertErrClass(t, errors.New("unit-test mock error"), "other")
+}
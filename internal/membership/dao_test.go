@@ -0,0 +1,302 @@
+package membership
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx      = context.Background()
+	joinedAt = time.UnixMilli(100)
+	after    = page.Cursor{
+		CreatedAt: time.UnixMilli(50),
+		ID:        "after-user-id",
+	}
+)
+
+const (
+	userID    = "foo-user-id"
+	orgID     = "foo-org-id"
+	createdBy = "creator-id"
+	limit     = 11
+)
+
+func getRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"user_id",
+		"org_id",
+		"role",
+		"is_home",
+		"joined_at",
+		"created_by",
+	}).AddRow(
+		userID,
+		orgID,
+		membership.RoleMember,
+		true,
+		joinedAt,
+		createdBy,
+	)
+}
+
+func expectedMembership() membership.Membership {
+	return membership.Membership{
+		UserID:    userID,
+		OrgID:     orgID,
+		Role:      membership.RoleMember,
+		IsHome:    true,
+		JoinedAt:  joinedAt,
+		CreatedBy: createdBy,
+	}
+}
+
+func initDAO() (d *dao, dbx *sqlx.DB, md sqlmock.Sqlmock) {
+	log := testutil.GetLogger()
+	db, md, err := sqlmock.New()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to mock db")
+		panic(err)
+	}
+	dbx = sqlx.NewDb(db, "sqlmock")
+	queryTimeout := 30 * time.Second
+	d = NewDAO(log, queryTimeout, dbx)
+	return d, dbx, md
+}
+
+func TestDAOGetByOrgID(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByOrgIDQuery)).
+		WithArgs(orgID, limit).
+		WillReturnRows(getRows())
+
+	actual, err := d.GetByOrgID(ctx, orgID, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, []membership.Membership{expectedMembership()}, actual)
+}
+
+func TestDAOGetByOrgID_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByOrgIDAfterQuery)).
+		WithArgs(orgID, after.CreatedAt, after.ID, limit).
+		WillReturnRows(getRows())
+
+	actual, err := d.GetByOrgID(ctx, orgID, &after, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, []membership.Membership{expectedMembership()}, actual)
+}
+
+func TestDAOGetByOrgID_Error(t *testing.T) {
+	d, _, md := initDAO()
+
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectQuery(regexp.QuoteMeta(getByOrgIDQuery)).
+		WithArgs(orgID, limit).
+		WillReturnError(mockErr)
+
+	_, err := d.GetByOrgID(ctx, orgID, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
+
+func TestDAOGetByUserID(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByUserIDQuery)).
+		WithArgs(userID).
+		WillReturnRows(getRows())
+
+	actual, err := d.GetByUserID(ctx, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, []membership.Membership{expectedMembership()}, actual)
+}
+
+func TestDAOGetByUserID_Error(t *testing.T) {
+	d, _, md := initDAO()
+
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectQuery(regexp.QuoteMeta(getByUserIDQuery)).
+		WithArgs(userID).
+		WillReturnError(mockErr)
+
+	_, err := d.GetByUserID(ctx, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
+
+func TestDAOGetForUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getForUpdateQuery)).
+		WithArgs(orgID, userID).
+		WillReturnRows(getRows())
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetForUpdate(ctx, tx, orgID, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, expectedMembership(), actual)
+}
+
+func TestDAOGetForUpdate_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getForUpdateQuery)).
+		WithArgs(orgID, userID).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetForUpdate(ctx, tx, orgID, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrNotFound{OrgID: orgID, UserID: userID}, err)
+}
+
+func TestDAOCreate(t *testing.T) {
+	d, db, md := initDAO()
+
+	m := expectedMembership()
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO memberships").
+		WithArgs(userID, orgID, membership.RoleMember, joinedAt, createdBy).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, m)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOCreate_AlreadyMemberErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error", Constraint: "memberships_pk"}
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO memberships").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, expectedMembership())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrAlreadyMember{OrgID: orgID, UserID: userID}, err)
+}
+
+func TestDAOCreate_OtherPQErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error", Constraint: "memberships_user_fk"}
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO memberships").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, expectedMembership())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOCreate_TooManyRowsAffected(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO memberships").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Create(ctx, tx, expectedMembership())
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Contains(t, err.Error(), "unexpected number of rows affected")
+}
+
+func TestDAODelete(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+		WithArgs(orgID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Delete(ctx, tx, orgID, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAODelete_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+		WithArgs(orgID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Delete(ctx, tx, orgID, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrNotFound{OrgID: orgID, UserID: userID}, err)
+}
+
+func TestDAODelete_Err(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+		WithArgs(orgID, userID).
+		WillReturnError(mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.Delete(ctx, tx, orgID, userID)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
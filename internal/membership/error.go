@@ -0,0 +1,70 @@
+package membership
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrNotFound struct {
+	OrgID  string
+	UserID string
+}
+
+func (err ErrNotFound) Error() string {
+	return fmt.Sprintf("Membership not found: orgID=%s userID=%s", err.OrgID, err.UserID)
+}
+
+func (err ErrNotFound) Code() string {
+	return problem.CodeMembershipNotFound
+}
+
+type ErrAlreadyMember struct {
+	OrgID  string
+	UserID string
+}
+
+func (err ErrAlreadyMember) Error() string {
+	return fmt.Sprintf("User is already a member of the org: orgID=%s userID=%s", err.OrgID, err.UserID)
+}
+
+func (err ErrAlreadyMember) Code() string {
+	return problem.CodeAlreadyMember
+}
+
+type ErrCannotRemoveHomeMembership struct {
+	OrgID  string
+	UserID string
+}
+
+func (err ErrCannotRemoveHomeMembership) Error() string {
+	return fmt.Sprintf("Cannot remove a user from their own org, move them to another org first: orgID=%s userID=%s", err.OrgID, err.UserID)
+}
+
+func (err ErrCannotRemoveHomeMembership) Code() string {
+	return problem.CodeCannotRemoveHomeMembership
+}
+
+type ErrNoMemberships struct {
+	UserID string
+}
+
+func (err ErrNoMemberships) Error() string {
+	return fmt.Sprintf("No memberships found for user: userID=%s", err.UserID)
+}
+
+func (err ErrNoMemberships) Code() string {
+	return problem.CodeNoMemberships
+}
+
+type ErrCannotAddSysUser struct {
+	UserID string
+}
+
+func (err ErrCannotAddSysUser) Error() string {
+	return fmt.Sprintf("Cannot add the system user to another org: userID=%s", err.UserID)
+}
+
+func (err ErrCannotAddSysUser) Code() string {
+	return problem.CodeCannotAddSysUser
+}
@@ -0,0 +1,31 @@
+package membership
+
+import (
+	"log/slog"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+)
+
+func logAttrOrgID(orgID string) slog.Attr {
+	return slog.String("orgID", orgID)
+}
+
+func logAttrUserID(userID string) slog.Attr {
+	return slog.String("userID", userID)
+}
+
+func logAttrMembership(m any) slog.Attr {
+	return slog.Any("membership", m)
+}
+
+func logAttrMembershipsLen(len int) slog.Attr {
+	return slog.Int("membershipsLen", len)
+}
+
+func logAttrAfter(after *page.Cursor) slog.Attr {
+	return slog.Any("after", after)
+}
+
+func logAttrLimit(limit int) slog.Attr {
+	return slog.Int("limit", limit)
+}
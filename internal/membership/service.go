@@ -0,0 +1,228 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+)
+
+type MembershipDAO interface {
+	GetByOrgID(ctx context.Context, orgID string, after *page.Cursor, limit int) ([]membership.Membership, error)
+	GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error)
+	GetForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) (membership.Membership, error)
+	Create(ctx context.Context, tx *sqlx.Tx, m membership.Membership) error
+	Delete(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) error
+}
+
+type OrgSVC interface {
+	GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error)
+}
+
+type UserSVC interface {
+	GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error)
+}
+
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error
+}
+
+type TXManager interface {
+	Do(ctx context.Context, tx *sqlx.Tx, f func(*sqlx.Tx) error) error
+}
+
+type Timer interface {
+	Now() time.Time
+}
+
+type service struct {
+	log     *slog.Logger
+	orgSVC  OrgSVC
+	userSVC UserSVC
+	dao     MembershipDAO
+	auditor Auditor
+	txMGR   TXManager
+	timer   Timer
+}
+
+func NewService(log *slog.Logger, orgSVC OrgSVC, userSVC UserSVC, dao MembershipDAO, auditor Auditor, txMGR TXManager, timer Timer) *service {
+	return &service{
+		log:     log.With(logutil.LogAttrSVC("MembershipSVC")),
+		orgSVC:  orgSVC,
+		userSVC: userSVC,
+		dao:     dao,
+		auditor: auditor,
+		txMGR:   txMGR,
+		timer:   timer,
+	}
+}
+
+func (s service) GetByOrgID(ctx context.Context, orgID string, pr page.Request) (mp membership.MembershipPage, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByOrgID"),
+		logAttrOrgID(orgID),
+		logAttrAfter(pr.After),
+		logAttrLimit(pr.Limit),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return mp, err
+	}
+	if !p.CanRead(orgID) {
+		log.Warn("forbidden")
+		return mp, authz.ErrForbidden{UserID: p.UserID, Action: "membership:list"}
+	}
+	memberships, err := s.dao.GetByOrgID(ctx, orgID, pr.After, pr.Limit+1)
+	if err != nil {
+		return mp, err
+	}
+	mp.Memberships, mp.NextCursor = page.Trim(memberships, pr.Limit, toCursor)
+	return mp, nil
+}
+
+func toCursor(m membership.Membership) page.Cursor {
+	return page.Cursor{
+		CreatedAt: m.JoinedAt,
+		ID:        m.UserID,
+	}
+}
+
+// GetByUserID only returns the memberships in orgs the caller can read, a
+// user can always see all of their own.
+func (s service) GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByUserID"),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := s.dao.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	visible := []membership.Membership{}
+	for _, m := range memberships {
+		if p.UserID == userID || p.CanRead(m.OrgID) {
+			visible = append(visible, m)
+		}
+	}
+	// every user has at least their home membership, so there's nothing the
+	// caller is allowed to know about
+	if len(visible) == 0 {
+		log.Warn("no visible memberships")
+		return nil, ErrNoMemberships{UserID: userID}
+	}
+	return visible, nil
+}
+
+// Add needs the caller to be able to manage the org and read the user, so an
+// org admin can only bring in users from orgs they're also a member of.
+func (s service) Add(ctx context.Context, orgID string, input membership.AddMembership) (out membership.Membership, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Add"),
+		logAttrOrgID(orgID),
+		logAttrMembership(input),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	if !p.CanManage(orgID) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "membership:add"}
+	}
+	if _, err := s.orgSVC.GetByID(ctx, orgID, false); err != nil {
+		return out, err
+	}
+	u, err := s.userSVC.GetByID(ctx, input.UserID, false)
+	if err != nil {
+		return out, err
+	}
+	if u.IsSystem {
+		return out, ErrCannotAddSysUser{UserID: u.ID}
+	}
+	role := input.Role
+	if role == "" {
+		role = membership.RoleMember
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		out = membership.Membership{
+			UserID:    u.ID,
+			OrgID:     orgID,
+			Role:      role,
+			JoinedAt:  s.timer.Now(),
+			CreatedBy: loggedInUserID,
+		}
+		if err := s.dao.Create(ctx, tx, out); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityMembership, entityID(out), nil, out)
+	})
+	if err != nil {
+		return membership.Membership{}, err
+	}
+	return out, nil
+}
+
+// Remove can't remove a user from their home org, that's only changed by
+// moving them (see the org delete's reassign_to).
+func (s service) Remove(ctx context.Context, orgID string, userID string) error {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Remove"),
+		logAttrOrgID(orgID),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !p.CanManage(orgID) {
+		log.Warn("forbidden")
+		return authz.ErrForbidden{UserID: p.UserID, Action: "membership:remove"}
+	}
+	return s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		m, err := s.dao.GetForUpdate(ctx, tx, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if m.IsHome {
+			return ErrCannotRemoveHomeMembership{OrgID: orgID, UserID: userID}
+		}
+		if err := s.dao.Delete(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionDelete, audit.EntityMembership, entityID(m), m, nil)
+	})
+}
+
+func entityID(m membership.Membership) string {
+	return m.OrgID + "/" + m.UserID
+}
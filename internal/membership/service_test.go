@@ -0,0 +1,403 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAO struct {
+	mock.Mock
+}
+
+type mockOrgSVC struct {
+	mock.Mock
+}
+
+type mockUserSVC struct {
+	mock.Mock
+}
+
+type mockAuditor struct {
+	mock.Mock
+}
+
+type mockTXManager struct {
+	mock.Mock
+}
+
+type mockTimer struct {
+	mock.Mock
+}
+
+func initSVC() (s *service, md *mockDAO, mo *mockOrgSVC, mu *mockUserSVC, ma *mockAuditor, mt *mockTimer) {
+	log := testutil.GetLogger()
+	md = new(mockDAO)
+	mo = new(mockOrgSVC)
+	mu = new(mockUserSVC)
+	ma = new(mockAuditor)
+	mt = new(mockTimer)
+	s = NewService(log, mo, mu, md, ma, new(mockTXManager), mt)
+	return s, md, mo, mu, ma, mt
+}
+
+// principalCTX is what the Auth middleware would leave in the context for a
+// token with the given claims.
+func principalCTX(userID string, claims jwt.MapClaims) context.Context {
+	claims["sub"] = userID
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, userID)
+	return context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
+}
+
+func assertForbidden(t *testing.T, err error) {
+	var forbidden authz.ErrForbidden
+	assert.True(t, errors.As(err, &forbidden))
+}
+
+func TestSVCGetByOrgID(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+	mockRes := []membership.Membership{
+		{UserID: "foo-user-id", OrgID: "foo-org-id", Role: membership.RoleMember, JoinedAt: time.UnixMilli(100)},
+		{UserID: "bar-user-id", OrgID: "foo-org-id", Role: membership.RoleOrgAdmin, JoinedAt: time.UnixMilli(200)},
+	}
+	var after *page.Cursor
+	md.On("GetByOrgID", ctx, "foo-org-id", after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetByOrgID(ctx, "foo-org-id", page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[:1], actual.Memberships)
+	c, err := page.DecodeCursor(actual.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-user-id", c.ID)
+	assert.True(t, time.UnixMilli(100).Equal(c.CreatedAt))
+}
+
+func TestSVCGetByOrgID_OtherOrgForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleOrgAdmin})
+
+	_, err := s.GetByOrgID(ctx, "foo-org-id", page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetByOrgID_DAOErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mockErr := errors.New("unit-test mock error")
+	var after *page.Cursor
+	md.On("GetByOrgID", ctx, "foo-org-id", after, 2).Return([]membership.Membership{}, mockErr)
+
+	_, err := s.GetByOrgID(ctx, "foo-org-id", page.Request{Limit: 1})
+
+	assert.Equal(t, mockErr, err)
+}
+
+func TestSVCGetByUserID_SystemAdmin(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mockRes := []membership.Membership{
+		{UserID: "foo-user-id", OrgID: "foo-org-id", IsHome: true},
+		{UserID: "foo-user-id", OrgID: "bar-org-id"},
+	}
+	md.On("GetByUserID", ctx, "foo-user-id").Return(mockRes, nil)
+
+	actual, err := s.GetByUserID(ctx, "foo-user-id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByUserID_OnlyReadableOrgs(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleMember})
+	mockRes := []membership.Membership{
+		{UserID: "foo-user-id", OrgID: "foo-org-id", IsHome: true},
+		{UserID: "foo-user-id", OrgID: "bar-org-id"},
+	}
+	md.On("GetByUserID", ctx, "foo-user-id").Return(mockRes, nil)
+
+	actual, err := s.GetByUserID(ctx, "foo-user-id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[1:], actual)
+}
+
+func TestSVCGetByUserID_Self(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("foo-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+	mockRes := []membership.Membership{
+		{UserID: "foo-user-id", OrgID: "foo-org-id", IsHome: true},
+		{UserID: "foo-user-id", OrgID: "bar-org-id"},
+	}
+	md.On("GetByUserID", ctx, "foo-user-id").Return(mockRes, nil)
+
+	actual, err := s.GetByUserID(ctx, "foo-user-id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByUserID_NoneVisible(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "baz-org-id", "role": authz.RoleOrgAdmin})
+	mockRes := []membership.Membership{
+		{UserID: "foo-user-id", OrgID: "foo-org-id", IsHome: true},
+	}
+	md.On("GetByUserID", ctx, "foo-user-id").Return(mockRes, nil)
+
+	_, err := s.GetByUserID(ctx, "foo-user-id")
+
+	var expected ErrNoMemberships
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "foo-user-id", expected.UserID)
+}
+
+func TestSVCAdd(t *testing.T) {
+	s, md, mo, mu, ma, mt := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	now := time.UnixMilli(100)
+	var expectedTX *sqlx.Tx
+	expected := membership.Membership{
+		UserID:    "foo-user-id",
+		OrgID:     "bar-org-id",
+		Role:      membership.RoleMember,
+		JoinedAt:  now,
+		CreatedBy: loggedInUserID,
+	}
+	mt.On("Now").Return(now)
+	mo.On("GetByID", ctx, "bar-org-id", false).Return(org.Org{ID: "bar-org-id"}, nil)
+	mu.On("GetByID", ctx, "foo-user-id", false).Return(user.User{ID: "foo-user-id", OrgID: "foo-org-id"}, nil)
+	md.On("Create", ctx, expectedTX, expected).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityMembership, "bar-org-id/foo-user-id", nil, expected).Return(nil)
+
+	actual, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCAdd_OrgAdminRole(t *testing.T) {
+	s, md, mo, mu, ma, mt := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mt.On("Now").Return(time.UnixMilli(100))
+	mo.On("GetByID", ctx, "bar-org-id", false).Return(org.Org{ID: "bar-org-id"}, nil)
+	mu.On("GetByID", ctx, "foo-user-id", false).Return(user.User{ID: "foo-user-id", OrgID: "foo-org-id"}, nil)
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(nil)
+	ma.On("Record", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	actual, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id", Role: membership.RoleOrgAdmin})
+
+	assert.Nil(t, err)
+	assert.Equal(t, membership.RoleOrgAdmin, actual.Role)
+}
+
+func TestSVCAdd_MemberForbidden(t *testing.T) {
+	s, md, mo, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleMember})
+
+	_, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assertForbidden(t, err)
+	mo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAdd_UserInAnotherOrgForbidden(t *testing.T) {
+	s, md, mo, mu, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleOrgAdmin})
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:read"}
+	mo.On("GetByID", ctx, "bar-org-id", false).Return(org.Org{ID: "bar-org-id"}, nil)
+	mu.On("GetByID", ctx, "foo-user-id", false).Return(user.User{}, mockErr)
+
+	_, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assert.Equal(t, mockErr, err)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAdd_OrgErr(t *testing.T) {
+	s, md, mo, mu, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mockErr := errors.New("unit-test mock error")
+	mo.On("GetByID", ctx, "bar-org-id", false).Return(org.Org{}, mockErr)
+
+	_, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assert.Equal(t, mockErr, err)
+	mu.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAdd_SysUser(t *testing.T) {
+	s, md, mo, mu, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mo.On("GetByID", ctx, "bar-org-id", false).Return(org.Org{ID: "bar-org-id"}, nil)
+	mu.On("GetByID", ctx, "foo-user-id", false).Return(user.User{ID: "foo-user-id", IsSystem: true}, nil)
+
+	_, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assert.Equal(t, ErrCannotAddSysUser{UserID: "foo-user-id"}, err)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAdd_DAOErr(t *testing.T) {
+	s, md, mo, mu, ma, mt := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mockErr := ErrAlreadyMember{OrgID: "bar-org-id", UserID: "foo-user-id"}
+	mt.On("Now").Return(time.UnixMilli(100))
+	mo.On("GetByID", ctx, "bar-org-id", false).Return(org.Org{ID: "bar-org-id"}, nil)
+	mu.On("GetByID", ctx, "foo-user-id", false).Return(user.User{ID: "foo-user-id"}, nil)
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(mockErr)
+
+	actual, err := s.Add(ctx, "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, membership.Membership{}, actual)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCAdd_ErrIfNoAuditInfo(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	_, err := s.Add(context.Background(), "bar-org-id", membership.AddMembership{UserID: "foo-user-id"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, err.Error(), "user not logged in")
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRemove(t *testing.T) {
+	s, md, _, _, ma, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleOrgAdmin})
+	var expectedTX *sqlx.Tx
+	m := membership.Membership{UserID: "foo-user-id", OrgID: "bar-org-id", Role: membership.RoleMember}
+	md.On("GetForUpdate", ctx, expectedTX, "bar-org-id", "foo-user-id").Return(m, nil)
+	md.On("Delete", ctx, expectedTX, "bar-org-id", "foo-user-id").Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityMembership, "bar-org-id/foo-user-id", m, nil).Return(nil)
+
+	err := s.Remove(ctx, "bar-org-id", "foo-user-id")
+
+	assert.Nil(t, err)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCRemove_Home(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	m := membership.Membership{UserID: "foo-user-id", OrgID: "foo-org-id", IsHome: true}
+	md.On("GetForUpdate", ctx, mock.Anything, "foo-org-id", "foo-user-id").Return(m, nil)
+
+	err := s.Remove(ctx, "foo-org-id", "foo-user-id")
+
+	assert.Equal(t, ErrCannotRemoveHomeMembership{OrgID: "foo-org-id", UserID: "foo-user-id"}, err)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRemove_NotFound(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	mockErr := ErrNotFound{OrgID: "bar-org-id", UserID: "foo-user-id"}
+	md.On("GetForUpdate", ctx, mock.Anything, "bar-org-id", "foo-user-id").Return(membership.Membership{}, mockErr)
+
+	err := s.Remove(ctx, "bar-org-id", "foo-user-id")
+
+	assert.Equal(t, mockErr, err)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRemove_OtherOrgForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	err := s.Remove(ctx, "bar-org-id", "foo-user-id")
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetForUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (d *mockDAO) GetByOrgID(ctx context.Context, orgID string, after *page.Cursor, limit int) ([]membership.Membership, error) {
+	args := d.Called(ctx, orgID, after, limit)
+	return args.Get(0).([]membership.Membership), args.Error(1)
+}
+
+func (d *mockDAO) GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error) {
+	args := d.Called(ctx, userID)
+	return args.Get(0).([]membership.Membership), args.Error(1)
+}
+
+func (d *mockDAO) GetForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) (membership.Membership, error) {
+	args := d.Called(ctx, tx, orgID, userID)
+	return args.Get(0).(membership.Membership), args.Error(1)
+}
+
+func (d *mockDAO) Create(ctx context.Context, tx *sqlx.Tx, m membership.Membership) error {
+	args := d.Called(ctx, tx, m)
+	return args.Error(0)
+}
+
+func (d *mockDAO) Delete(ctx context.Context, tx *sqlx.Tx, orgID string, userID string) error {
+	args := d.Called(ctx, tx, orgID, userID)
+	return args.Error(0)
+}
+
+func (o *mockOrgSVC) GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error) {
+	args := o.Called(ctx, id, includeDeleted)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (u *mockUserSVC) GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error) {
+	args := u.Called(ctx, id, includeDeleted)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockAuditor) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error {
+	args := m.Called(ctx, tx, action, entityType, entityID, before, after)
+	return args.Error(0)
+}
+
+func (m *mockTXManager) Do(ctx context.Context, tx *sqlx.Tx, f func(tx *sqlx.Tx) error) error {
+	return f(tx)
+}
+
+func (t *mockTimer) Now() time.Time {
+	args := t.Called()
+	return args.Get(0).(time.Time)
+}
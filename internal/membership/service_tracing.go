@@ -0,0 +1,44 @@
+package membership
+
+import (
+	"context"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+)
+
+// tracedService wraps every MembershipService call in a span.
+type tracedService struct {
+	svc MembershipService
+}
+
+func NewTracedService(svc MembershipService) *tracedService {
+	return &tracedService{
+		svc: svc,
+	}
+}
+
+func (s tracedService) GetByOrgID(ctx context.Context, orgID string, pr page.Request) (mp membership.MembershipPage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "MembershipSVC.GetByOrgID")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetByOrgID(ctx, orgID, pr)
+}
+
+func (s tracedService) GetByUserID(ctx context.Context, userID string) (m []membership.Membership, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "MembershipSVC.GetByUserID")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetByUserID(ctx, userID)
+}
+
+func (s tracedService) Add(ctx context.Context, orgID string, input membership.AddMembership) (m membership.Membership, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "MembershipSVC.Add")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Add(ctx, orgID, input)
+}
+
+func (s tracedService) Remove(ctx context.Context, orgID string, userID string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "MembershipSVC.Remove")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Remove(ctx, orgID, userID)
+}
@@ -0,0 +1,95 @@
+package membership
+
+// is_home marks the membership for the user's own org_id, it's the one the
+// users_home_membership_trg trigger keeps in sync so it can't be removed here.
+// Soft deleted users (and orgs, for a user's list) are left out of every read.
+const getByOrgIDQuery = `
+	SELECT
+		m.user_id,
+		m.org_id,
+		m.role,
+		m.org_id = u.org_id AS is_home,
+		m.joined_at,
+		m.created_by
+	FROM memberships m
+	JOIN users u ON u.id = m.user_id
+	WHERE m.org_id = $1
+	AND u.deleted_at IS NULL
+	ORDER BY m.joined_at ASC, m.user_id ASC
+	LIMIT $2
+`
+
+const getByOrgIDAfterQuery = `
+	SELECT
+		m.user_id,
+		m.org_id,
+		m.role,
+		m.org_id = u.org_id AS is_home,
+		m.joined_at,
+		m.created_by
+	FROM memberships m
+	JOIN users u ON u.id = m.user_id
+	WHERE m.org_id = $1
+	AND (
+		m.joined_at > $2
+		OR (m.joined_at = $2 AND m.user_id > $3)
+	)
+	AND u.deleted_at IS NULL
+	ORDER BY m.joined_at ASC, m.user_id ASC
+	LIMIT $4
+`
+
+const getByUserIDQuery = `
+	SELECT
+		m.user_id,
+		m.org_id,
+		m.role,
+		m.org_id = u.org_id AS is_home,
+		m.joined_at,
+		m.created_by
+	FROM memberships m
+	JOIN users u ON u.id = m.user_id
+	JOIN orgs o ON o.id = m.org_id
+	WHERE m.user_id = $1
+	AND u.deleted_at IS NULL
+	AND o.deleted_at IS NULL
+	ORDER BY m.joined_at ASC, m.org_id ASC
+`
+
+// Locked so the membership can't be removed twice at the same time.
+const getForUpdateQuery = `
+	SELECT
+		m.user_id,
+		m.org_id,
+		m.role,
+		m.org_id = u.org_id AS is_home,
+		m.joined_at,
+		m.created_by
+	FROM memberships m
+	JOIN users u ON u.id = m.user_id
+	WHERE m.org_id = $1
+	AND m.user_id = $2
+	FOR UPDATE OF m
+`
+
+const createQuery = `
+	INSERT INTO memberships (
+		user_id,
+		org_id,
+		role,
+		joined_at,
+		created_by
+	) VALUES (
+		:user_id,
+		:org_id,
+		:role,
+		:joined_at,
+		:created_by
+	)
+`
+
+const deleteQuery = `
+	DELETE FROM memberships
+	WHERE org_id = $1
+	AND user_id = $2
+`
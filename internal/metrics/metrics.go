@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "go_service_ex"
+
+const (
+	TXCommit      = "commit"
+	TXRollback    = "rollback"
+	TXBeginError  = "begin_error"
+	TXCommitError = "commit_error"
+)
+
+const (
+	ErrClassNone     = "none"
+	ErrClassTimeout  = "timeout"
+	ErrClassCanceled = "canceled"
+	ErrClassNoRows   = "no_rows"
+	ErrClassOther    = "other"
+)
+
+// NewRegistry creates a registry with the go runtime and process collectors,
+// the app's metrics are registered on it by the New* funcs below.
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+func Handler(reg *prometheus.Registry) gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
+}
+
+// RegisterDBStats exposes the sqlx pool's DB.Stats() as gauges/counters.
+func RegisterDBStats(reg prometheus.Registerer, db *sql.DB, dbName string) {
+	reg.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// HTTP records request count and latency per route template (ex.
+// /api/users/:id), unmatched routes share a single label to keep the
+// cardinality bounded.
+func HTTP(reg prometheus.Registerer) gin.HandlerFunc {
+	labels := []string{"method", "route", "status"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests handled.",
+	}, labels)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, labels)
+	reg.MustRegister(requests, duration)
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		duration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// GRPC is HTTP for gRPC, the counts and latency are per full method (ex.
+// /goserviceex.org.v1.OrgService/GetOrg) and status code. A stream is
+// observed once, when the handler returns.
+func GRPC(reg prometheus.Registerer) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	labels := []string{"method", "code"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Number of gRPC calls handled.",
+	}, labels)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, labels)
+	reg.MustRegister(requests, duration)
+	observe := func(fullMethod string, start time.Time, err error) {
+		code := status.Code(err).String()
+		requests.WithLabelValues(fullMethod, code).Inc()
+		duration.WithLabelValues(fullMethod, code).Observe(time.Since(start).Seconds())
+	}
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, start, err)
+		return resp, err
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(info.FullMethod, start, err)
+		return err
+	}
+	return unary, stream
+}
+
+type daoMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+func NewDAOMetrics(reg prometheus.Registerer) *daoMetrics {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "dao_query_duration_seconds",
+		Help:      "DAO method latency by error class.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"dao", "method", "error_class"})
+	reg.MustRegister(duration)
+	return &daoMetrics{
+		duration: duration,
+	}
+}
+
+func (m *daoMetrics) ObserveQuery(dao string, method string, d time.Duration, errClass string) {
+	m.duration.WithLabelValues(dao, method, errClass).Observe(d.Seconds())
+}
+
+type txMetrics struct {
+	total *prometheus.CounterVec
+}
+
+func NewTXMetrics(reg prometheus.Registerer) *txMetrics {
+	total := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tx_total",
+		Help:      "Number of transactions by outcome (commit, rollback, begin_error, commit_error).",
+	}, []string{"outcome"})
+	reg.MustRegister(total)
+	return &txMetrics{
+		total: total,
+	}
+}
+
+func (m *txMetrics) ObserveTX(outcome string) {
+	m.total.WithLabelValues(outcome).Inc()
+}
+
+// ErrClass buckets errors that aren't specific to a domain, DAO decorators
+// check their own error types first and fall back to this.
+func ErrClass(err error) string {
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		return ErrClassNone
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrClassCanceled
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrClassNoRows
+	case errors.As(err, &pqErr):
+		return "pq_" + pqErr.Code.Class().Name()
+	default:
+		return ErrClassOther
+	}
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+	r := gin.New()
+	r.Use(HTTP(reg))
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, url := range []string{"/users/a", "/users/b", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	expected := `
+		# HELP go_service_ex_http_requests_total Number of HTTP requests handled.
+		# TYPE go_service_ex_http_requests_total counter
+		go_service_ex_http_requests_total{method="GET",route="/users/:id",status="204"} 2
+		go_service_ex_http_requests_total{method="GET",route="unmatched",status="404"} 1
+	`
+	assert.Nil(t, promtestutil.GatherAndCompare(reg, strings.NewReader(expected), "go_service_ex_http_requests_total"))
+	assert.Equal(t, 2, promtestutil.CollectAndCount(reg, "go_service_ex_http_request_duration_seconds"))
+}
+
+func TestGRPC_Unary(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	unary, _ := GRPC(reg)
+	info := &grpc.UnaryServerInfo{FullMethod: "/goserviceex.org.v1.OrgService/GetOrg"}
+
+	for _, err := range []error{nil, nil, status.Error(codes.NotFound, "unit-test")} {
+		unary(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+			return nil, err
+		})
+	}
+
+	expected := `
+		# HELP go_service_ex_grpc_requests_total Number of gRPC calls handled.
+		# TYPE go_service_ex_grpc_requests_total counter
+		go_service_ex_grpc_requests_total{code="NotFound",method="/goserviceex.org.v1.OrgService/GetOrg"} 1
+		go_service_ex_grpc_requests_total{code="OK",method="/goserviceex.org.v1.OrgService/GetOrg"} 2
+	`
+	assert.Nil(t, promtestutil.GatherAndCompare(reg, strings.NewReader(expected), "go_service_ex_grpc_requests_total"))
+	assert.Equal(t, 2, promtestutil.CollectAndCount(reg, "go_service_ex_grpc_request_duration_seconds"))
+}
+
+func TestGRPC_Stream(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	_, stream := GRPC(reg)
+	info := &grpc.StreamServerInfo{FullMethod: "/goserviceex.org.v1.OrgService/ExportOrgs"}
+
+	err := stream(nil, nil, info, func(srv any, ss grpc.ServerStream) error {
+		return status.Error(codes.Internal, "unit-test")
+	})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	expected := `
+		# HELP go_service_ex_grpc_requests_total Number of gRPC calls handled.
+		# TYPE go_service_ex_grpc_requests_total counter
+		go_service_ex_grpc_requests_total{code="Internal",method="/goserviceex.org.v1.OrgService/ExportOrgs"} 1
+	`
+	assert.Nil(t, promtestutil.GatherAndCompare(reg, strings.NewReader(expected), "go_service_ex_grpc_requests_total"))
+}
+
+func TestDAOMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewDAOMetrics(reg)
+
+	m.ObserveQuery("UserDAO", "GetByID", time.Millisecond, ErrClassNone)
+	m.ObserveQuery("UserDAO", "GetByID", time.Millisecond, "not_found")
+
+	assert.Equal(t, 2, promtestutil.CollectAndCount(reg, "go_service_ex_dao_query_duration_seconds"))
+}
+
+func TestTXMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := NewTXMetrics(reg)
+
+	m.ObserveTX(TXCommit)
+	m.ObserveTX(TXCommit)
+	m.ObserveTX(TXRollback)
+
+	assert.Equal(t, float64(2), promtestutil.ToFloat64(m.total.WithLabelValues(TXCommit)))
+	assert.Equal(t, float64(1), promtestutil.ToFloat64(m.total.WithLabelValues(TXRollback)))
+}
+
+func TestErrClass(t *testing.T) {
+	assert.Equal(t, ErrClassNone, ErrClass(nil))
+	assert.Equal(t, ErrClassTimeout, ErrClass(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
+	assert.Equal(t, ErrClassCanceled, ErrClass(context.Canceled))
+	assert.Equal(t, ErrClassNoRows, ErrClass(sql.ErrNoRows))
+	assert.Equal(t, "pq_integrity_constraint_violation", ErrClass(&pq.Error{Code: "23505"}))
+	assert.Equal(t, ErrClassOther, ErrClass(errors.New("unit-test mock error")))
+}
@@ -0,0 +1,45 @@
+package migrate
+
+import (
+	"fmt"
+)
+
+type ErrInvalidFileName struct {
+	FileName string
+}
+
+func (err ErrInvalidFileName) Error() string {
+	return fmt.Sprintf("Invalid migration file name, expected <version>_<name>.(up|down).sql: fileName=%s", err.FileName)
+}
+
+type ErrDuplicateVersion struct {
+	Version int64
+}
+
+func (err ErrDuplicateVersion) Error() string {
+	return fmt.Sprintf("Migration version defined more than once: version=%d", err.Version)
+}
+
+type ErrMissingUp struct {
+	Version int64
+}
+
+func (err ErrMissingUp) Error() string {
+	return fmt.Sprintf("Migration has no up file: version=%d", err.Version)
+}
+
+type ErrMissingDown struct {
+	Version int64
+}
+
+func (err ErrMissingDown) Error() string {
+	return fmt.Sprintf("Migration cannot be rolled back, it has no down file: version=%d", err.Version)
+}
+
+type ErrInvalidTarget struct {
+	Version int64
+}
+
+func (err ErrInvalidTarget) Error() string {
+	return fmt.Sprintf("Invalid target version, must be 0 or greater: version=%d", err.Version)
+}
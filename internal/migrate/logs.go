@@ -0,0 +1,15 @@
+package migrate
+
+import "log/slog"
+
+func logAttrVersion(version int64) slog.Attr {
+	return slog.Int64("version", version)
+}
+
+func logAttrName(name string) slog.Attr {
+	return slog.String("name", name)
+}
+
+func logAttrDryRun(dryRun bool) slog.Attr {
+	return slog.Bool("dryRun", dryRun)
+}
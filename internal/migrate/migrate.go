@@ -0,0 +1,288 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/jmoiron/sqlx"
+)
+
+var fileNameRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64      `db:"version"`
+	Name      string     `db:"name"`
+	AppliedAt *time.Time `db:"applied_at"`
+}
+
+type migrator struct {
+	log        *slog.Logger
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+func NewMigrator(log *slog.Logger, db *sqlx.DB, fsys fs.FS) (*migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{
+		log:        log.With(logutil.LogAttrSVC("Migrator")),
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		matches := fileNameRegex.FindStringSubmatch(e.Name())
+		if matches == nil {
+			return nil, ErrInvalidFileName{FileName: e.Name()}
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidFileName{FileName: e.Name()}
+		}
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, ErrDuplicateVersion{Version: version}
+		}
+		if matches[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, ErrMissingUp{Version: m.Version}
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
+
+// Latest is the version the database will be at once Up has run.
+func (m migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current is the newest version applied to the database. It doesn't take the
+// lock, so it's cheap enough for health checks.
+func (m migrator) Current(ctx context.Context) (version int64, err error) {
+	log := m.log.With(
+		logutil.LogAttrFN("Current"),
+	)
+	log.Debug("called")
+	err = m.db.GetContext(ctx, &version, getCurrentVersionQuery)
+	return version, err
+}
+
+// Status lists every known migration, the ones that exist in the database but
+// not in this binary are included so a newer schema is easy to spot.
+func (m migrator) Status(ctx context.Context) (statuses []MigrationStatus, err error) {
+	log := m.log.With(
+		logutil.LogAttrFN("Status"),
+	)
+	log.Debug("called")
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+			if a, ok := applied[mig.Version]; ok {
+				s.AppliedAt = a.AppliedAt
+				delete(applied, mig.Version)
+			}
+			statuses = append(statuses, s)
+		}
+		for _, a := range applied {
+			statuses = append(statuses, a)
+		}
+		return nil
+	})
+	slices.SortFunc(statuses, func(a, b MigrationStatus) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return statuses, err
+}
+
+func (m migrator) Up(ctx context.Context, dryRun bool) (migrated []Migration, err error) {
+	log := m.log.With(
+		logutil.LogAttrFN("Up"),
+		logAttrDryRun(dryRun),
+	)
+	log.Debug("called")
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			log := log.With(logAttrVersion(mig.Version), logAttrName(mig.Name))
+			if !dryRun {
+				if err := run(ctx, conn, mig.Up, insertQuery, mig.Version, mig.Name); err != nil {
+					log.With(logutil.LogAttrError(err)).Error("migration failed")
+					return err
+				}
+			}
+			log.Info("migrated up")
+			migrated = append(migrated, mig)
+		}
+		return nil
+	})
+	return migrated, err
+}
+
+// DownTo rolls back every applied migration newer than version, newest first.
+func (m migrator) DownTo(ctx context.Context, version int64, dryRun bool) (migrated []Migration, err error) {
+	log := m.log.With(
+		logutil.LogAttrFN("DownTo"),
+		logAttrVersion(version),
+		logAttrDryRun(dryRun),
+	)
+	log.Debug("called")
+	if version < 0 {
+		return nil, ErrInvalidTarget{Version: version}
+	}
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		var toRollback []Migration
+		for _, mig := range slices.Backward(m.migrations) {
+			if mig.Version <= version {
+				break
+			}
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			delete(applied, mig.Version)
+			if mig.Down == "" {
+				return ErrMissingDown{Version: mig.Version}
+			}
+			toRollback = append(toRollback, mig)
+		}
+		for v := range applied {
+			if v > version {
+				// applied by a newer binary, we don't have its down file
+				return ErrMissingDown{Version: v}
+			}
+		}
+		for _, mig := range toRollback {
+			log := log.With(logAttrVersion(mig.Version), logAttrName(mig.Name))
+			if !dryRun {
+				if err := run(ctx, conn, mig.Down, deleteQuery, mig.Version); err != nil {
+					log.With(logutil.LogAttrError(err)).Error("migration failed")
+					return err
+				}
+			}
+			log.Info("migrated down")
+			migrated = append(migrated, mig)
+		}
+		return nil
+	})
+	return migrated, err
+}
+
+// withLock holds a session level advisory lock on a dedicated connection so
+// concurrent migrators (ex. several pods booting at once) run one at a time.
+func (m migrator) withLock(ctx context.Context, f func(*sqlx.Conn) error) (err error) {
+	log := m.log.With(
+		logutil.LogAttrFN("withLock"),
+	)
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	log.Debug("waiting for lock")
+	if _, err = conn.ExecContext(ctx, lockQuery, lockKey); err != nil {
+		return err
+	}
+	defer func() {
+		if _, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), unlockQuery, lockKey); unlockErr != nil {
+			log.With(logutil.LogAttrError(unlockErr)).Error("failed to release lock")
+		}
+	}()
+	log.Debug("lock acquired")
+	if _, err = conn.ExecContext(ctx, createTableQuery); err != nil {
+		return err
+	}
+	return f(conn)
+}
+
+func getApplied(ctx context.Context, conn *sqlx.Conn) (map[int64]MigrationStatus, error) {
+	var rows []MigrationStatus
+	if err := conn.SelectContext(ctx, &rows, getAppliedQuery); err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]MigrationStatus, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// run applies the migration and records it in the same tx, postgres has
+// transactional DDL so a failed migration leaves nothing half applied.
+func run(ctx context.Context, conn *sqlx.Conn, migrationSQL string, trackingQuery string, args ...any) (err error) {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	if _, err = tx.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, trackingQuery, args...)
+	return err
+}
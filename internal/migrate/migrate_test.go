@@ -0,0 +1,309 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/db/migrations"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx       = context.Background()
+	appliedAt = time.UnixMilli(100)
+)
+
+const (
+	firstUp    = "CREATE TABLE foo(id TEXT);"
+	firstDown  = "DROP TABLE foo;"
+	secondUp   = "CREATE TABLE bar(id TEXT);"
+	secondDown = "DROP TABLE bar;"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_foo.up.sql":   {Data: []byte(firstUp)},
+		"0001_create_foo.down.sql": {Data: []byte(firstDown)},
+		"0002_create_bar.up.sql":   {Data: []byte(secondUp)},
+		"0002_create_bar.down.sql": {Data: []byte(secondDown)},
+	}
+}
+
+func initMigrator(fsys fstest.MapFS) (m *migrator, md sqlmock.Sqlmock) {
+	log := testutil.GetLogger()
+	db, md, err := sqlmock.New()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to mock db")
+		panic(err)
+	}
+	m, err = NewMigrator(log, sqlx.NewDb(db, "sqlmock"), fsys)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to create migrator")
+		panic(err)
+	}
+	return m, md
+}
+
+func expectLock(md sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	md.ExpectExec(regexp.QuoteMeta(lockQuery)).
+		WithArgs(lockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	md.ExpectExec(regexp.QuoteMeta(createTableQuery)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	md.ExpectQuery(regexp.QuoteMeta(getAppliedQuery)).
+		WillReturnRows(applied)
+}
+
+func expectUnlock(md sqlmock.Sqlmock) {
+	md.ExpectExec(regexp.QuoteMeta(unlockQuery)).
+		WithArgs(lockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func appliedRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"version", "name", "applied_at"})
+}
+
+func TestNewMigrator_EmbeddedMigrations(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.Nil(t, err)
+	m, err := NewMigrator(testutil.GetLogger(), sqlx.NewDb(db, "sqlmock"), migrations.FS)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, m.Latest(), int64(2))
+	for _, mig := range m.migrations {
+		assert.NotEqual(t, "", mig.Down, mig.Name)
+	}
+}
+
+func TestNewMigrator_InvalidFileName(t *testing.T) {
+	fsys := testFS()
+	fsys["foo.sql"] = &fstest.MapFile{Data: []byte(firstUp)}
+	_, err := NewMigrator(testutil.GetLogger(), nil, fsys)
+	var expected ErrInvalidFileName
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "foo.sql", expected.FileName)
+}
+
+func TestNewMigrator_DuplicateVersion(t *testing.T) {
+	fsys := testFS()
+	fsys["0001_create_baz.up.sql"] = &fstest.MapFile{Data: []byte(firstUp)}
+	_, err := NewMigrator(testutil.GetLogger(), nil, fsys)
+	var expected ErrDuplicateVersion
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, int64(1), expected.Version)
+}
+
+func TestNewMigrator_MissingUp(t *testing.T) {
+	fsys := testFS()
+	delete(fsys, "0002_create_bar.up.sql")
+	_, err := NewMigrator(testutil.GetLogger(), nil, fsys)
+	var expected ErrMissingUp
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, int64(2), expected.Version)
+}
+
+func TestLatest(t *testing.T) {
+	m, _ := initMigrator(testFS())
+	assert.Equal(t, int64(2), m.Latest())
+}
+
+func TestCurrent(t *testing.T) {
+	m, md := initMigrator(testFS())
+	md.ExpectQuery(regexp.QuoteMeta(getCurrentVersionQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1))
+
+	actual, err := m.Current(ctx)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), actual)
+}
+
+func TestCurrent_Err(t *testing.T) {
+	m, md := initMigrator(testFS())
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectQuery(regexp.QuoteMeta(getCurrentVersionQuery)).
+		WillReturnError(mockErr)
+
+	_, err := m.Current(ctx)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
+
+func TestStatus(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows().
+		AddRow(1, "create_foo", appliedAt).
+		AddRow(3, "from_newer_binary", appliedAt))
+	expectUnlock(md)
+
+	actual, err := m.Status(ctx)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, []MigrationStatus{
+		{Version: 1, Name: "create_foo", AppliedAt: &appliedAt},
+		{Version: 2, Name: "create_bar"},
+		{Version: 3, Name: "from_newer_binary", AppliedAt: &appliedAt},
+	}, actual)
+}
+
+func TestUp(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows().AddRow(1, "create_foo", appliedAt))
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(secondUp)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	md.ExpectExec(regexp.QuoteMeta(insertQuery)).
+		WithArgs(int64(2), "create_bar").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	md.ExpectCommit()
+	expectUnlock(md)
+
+	actual, err := m.Up(ctx, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Len(t, actual, 1)
+	assert.Equal(t, int64(2), actual[0].Version)
+}
+
+func TestUp_DryRun(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows())
+	expectUnlock(md)
+
+	actual, err := m.Up(ctx, true)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Len(t, actual, 2)
+}
+
+func TestUp_MigrationErr(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows())
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(firstUp)).
+		WillReturnError(mockErr)
+	md.ExpectRollback()
+	expectUnlock(md)
+
+	actual, err := m.Up(ctx, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+	assert.Len(t, actual, 0)
+}
+
+func TestUp_LockErr(t *testing.T) {
+	m, md := initMigrator(testFS())
+	mockErr := errors.New("unit-test mock error")
+	md.ExpectExec(regexp.QuoteMeta(lockQuery)).
+		WithArgs(lockKey).
+		WillReturnError(mockErr)
+
+	_, err := m.Up(ctx, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, mockErr, err)
+}
+
+func TestDownTo(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows().
+		AddRow(1, "create_foo", appliedAt).
+		AddRow(2, "create_bar", appliedAt))
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(secondDown)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	md.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	md.ExpectCommit()
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(firstDown)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	md.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	md.ExpectCommit()
+	expectUnlock(md)
+
+	actual, err := m.DownTo(ctx, 0, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Len(t, actual, 2)
+	assert.Equal(t, int64(2), actual[0].Version)
+	assert.Equal(t, int64(1), actual[1].Version)
+}
+
+func TestDownTo_DryRun(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows().
+		AddRow(1, "create_foo", appliedAt).
+		AddRow(2, "create_bar", appliedAt))
+	expectUnlock(md)
+
+	actual, err := m.DownTo(ctx, 1, true)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Len(t, actual, 1)
+	assert.Equal(t, int64(2), actual[0].Version)
+}
+
+func TestDownTo_MissingDown(t *testing.T) {
+	fsys := testFS()
+	delete(fsys, "0002_create_bar.down.sql")
+	m, md := initMigrator(fsys)
+	expectLock(md, appliedRows().
+		AddRow(1, "create_foo", appliedAt).
+		AddRow(2, "create_bar", appliedAt))
+	expectUnlock(md)
+
+	_, err := m.DownTo(ctx, 0, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrMissingDown
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, int64(2), expected.Version)
+}
+
+func TestDownTo_AppliedByNewerBinary(t *testing.T) {
+	m, md := initMigrator(testFS())
+	expectLock(md, appliedRows().
+		AddRow(1, "create_foo", appliedAt).
+		AddRow(2, "create_bar", appliedAt).
+		AddRow(3, "from_newer_binary", appliedAt))
+	expectUnlock(md)
+
+	actual, err := m.DownTo(ctx, 0, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrMissingDown
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, int64(3), expected.Version)
+	assert.Len(t, actual, 0)
+}
+
+func TestDownTo_InvalidTarget(t *testing.T) {
+	m, md := initMigrator(testFS())
+
+	_, err := m.DownTo(ctx, -1, false)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrInvalidTarget
+	assert.True(t, errors.As(err, &expected))
+}
@@ -0,0 +1,51 @@
+package migrate
+
+// Arbitrary, but it has to be the same for every process migrating this schema
+const lockKey = int64(7391463226147040941)
+
+const lockQuery = `
+	SELECT pg_advisory_lock($1)
+`
+
+const unlockQuery = `
+	SELECT pg_advisory_unlock($1)
+`
+
+const createTableQuery = `
+	CREATE TABLE IF NOT EXISTS schema_migrations(
+		version BIGINT NOT NULL,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		CONSTRAINT schema_migrations_pk PRIMARY KEY(version)
+	)
+`
+
+const getAppliedQuery = `
+	SELECT
+		m.version,
+		m.name,
+		m.applied_at
+	FROM schema_migrations m
+	ORDER BY m.version ASC
+`
+
+const getCurrentVersionQuery = `
+	SELECT
+		COALESCE(MAX(m.version), 0)
+	FROM schema_migrations m
+`
+
+const insertQuery = `
+	INSERT INTO schema_migrations (
+		version,
+		name
+	) VALUES (
+		$1,
+		$2
+	)
+`
+
+const deleteQuery = `
+	DELETE FROM schema_migrations
+	WHERE version = $1
+`
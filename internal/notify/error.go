@@ -0,0 +1,13 @@
+package notify
+
+import (
+	"fmt"
+)
+
+type ErrUnknownNotifier struct {
+	Notifier string
+}
+
+func (err ErrUnknownNotifier) Error() string {
+	return fmt.Sprintf("Unknown notifier, expected log or file: notifier=%s", err.Notifier)
+}
@@ -0,0 +1,19 @@
+package notify
+
+import "log/slog"
+
+func logAttrTo(to string) slog.Attr {
+	return slog.String("to", to)
+}
+
+func logAttrSubject(subject string) slog.Attr {
+	return slog.String("subject", subject)
+}
+
+func logAttrBody(body string) slog.Attr {
+	return slog.String("body", body)
+}
+
+func logAttrFile(file string) slog.Attr {
+	return slog.String("file", file)
+}
@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/config"
+)
+
+const (
+	NotifierLog  = "log"
+	NotifierFile = "file"
+)
+
+// Message is a plain text notification for a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages, ex. as emails. The ones here are only meant for
+// local use, a real one plugs in behind the same interface. Services send in
+// the tx that stores what the message is about, so a failed send rolls it
+// back instead of leaving behind something (ex. a token) nobody received.
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
+
+func New(log *slog.Logger, cfg config.NotifyConfig) (Notifier, error) {
+	switch cfg.Notifier {
+	case NotifierLog:
+		return NewLogNotifier(log), nil
+	case NotifierFile:
+		return NewFileNotifier(log, cfg.Dir)
+	default:
+		return nil, ErrUnknownNotifier{Notifier: cfg.Notifier}
+	}
+}
+
+type logNotifier struct {
+	log *slog.Logger
+}
+
+// NewLogNotifier logs every message (body included) at info, so anything
+// secret in them (ex. a verification token) ends up in the logs.
+func NewLogNotifier(log *slog.Logger) *logNotifier {
+	return &logNotifier{
+		log: log.With(logutil.LogAttrSVC("LogNotifier")),
+	}
+}
+
+func (n logNotifier) Send(ctx context.Context, m Message) error {
+	n.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Send"),
+		logAttrTo(m.To),
+		logAttrSubject(m.Subject),
+		logAttrBody(m.Body),
+	).Info("notification")
+	return nil
+}
+
+type fileNotifier struct {
+	log *slog.Logger
+	dir string
+}
+
+// NewFileNotifier writes every message to its own file in dir (creating it if
+// needed), like a mail drop that can be read back in tests.
+func NewFileNotifier(log *slog.Logger, dir string) (*fileNotifier, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fileNotifier{
+		log: log.With(logutil.LogAttrSVC("FileNotifier")),
+		dir: dir,
+	}, nil
+}
+
+func (n fileNotifier) Send(ctx context.Context, m Message) (err error) {
+	log := n.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Send"),
+		logAttrTo(m.To),
+		logAttrSubject(m.Subject),
+	)
+	log.Debug("called")
+	f, err := os.CreateTemp(n.dir, "notification-*.txt")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if _, err = fmt.Fprintf(f, "To: %s\nSubject: %s\n\n%s\n", m.To, m.Subject, m.Body); err != nil {
+		return err
+	}
+	log.With(logAttrFile(f.Name())).Info("notification written")
+	return nil
+}
@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	ctx = context.Background()
+	msg = Message{
+		To:      "foo@bar.com",
+		Subject: "foo-subject",
+		Body:    "foo-body",
+	}
+)
+
+func TestNew_Log(t *testing.T) {
+	n, err := New(testutil.GetLogger(), config.NotifyConfig{Notifier: NotifierLog})
+	assert.Nil(t, err)
+	assert.IsType(t, &logNotifier{}, n)
+}
+
+func TestNew_File(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "notifications")
+	n, err := New(testutil.GetLogger(), config.NotifyConfig{Notifier: NotifierFile, Dir: dir})
+	assert.Nil(t, err)
+	assert.IsType(t, &fileNotifier{}, n)
+	assert.DirExists(t, dir)
+}
+
+func TestNew_Unknown(t *testing.T) {
+	_, err := New(testutil.GetLogger(), config.NotifyConfig{Notifier: "carrier-pigeon"})
+	var expected ErrUnknownNotifier
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "carrier-pigeon", expected.Notifier)
+}
+
+func TestLogNotifierSend(t *testing.T) {
+	n := NewLogNotifier(testutil.GetLogger())
+	assert.Nil(t, n.Send(ctx, msg))
+}
+
+func TestFileNotifierSend(t *testing.T) {
+	dir := t.TempDir()
+	n, err := NewFileNotifier(testutil.GetLogger(), dir)
+	assert.Nil(t, err)
+
+	assert.Nil(t, n.Send(ctx, msg))
+	assert.Nil(t, n.Send(ctx, msg))
+
+	files, err := filepath.Glob(filepath.Join(dir, "notification-*.txt"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+	b, err := os.ReadFile(files[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "To: foo@bar.com\nSubject: foo-subject\n\nfoo-body\n", string(b))
+}
+
+func TestFileNotifierSend_Err(t *testing.T) {
+	dir := t.TempDir()
+	n, err := NewFileNotifier(testutil.GetLogger(), dir)
+	assert.Nil(t, err)
+	assert.Nil(t, os.Remove(dir))
+
+	assert.NotNil(t, n.Send(ctx, msg))
+}
@@ -0,0 +1,74 @@
+package onboarding
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
+	pkguser "github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/gin-gonic/gin"
+)
+
+type OnboardingService interface {
+	Onboard(ctx context.Context, o onboarding.Onboarding) (onboarding.Onboarding, error)
+}
+
+type ctrl struct {
+	log     *slog.Logger
+	service OnboardingService
+}
+
+func NewController(log *slog.Logger, service OnboardingService) *ctrl {
+	return &ctrl{
+		log:     log.With(logutil.LogAttrSVC("OnboardingCTL")),
+		service: service,
+	}
+}
+
+func (ctr ctrl) Onboard(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Onboard"),
+	)
+	log.Debug("called")
+	var o onboarding.Onboarding
+	if err := c.ShouldBindJSON(&o); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateUsers(o.Users); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrOnboarding(o))
+	log.Debug("body processed, about to call service")
+	o, err := ctr.service.Onboard(ctx, o)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.JSON(http.StatusOK, o)
+}
+
+// validateUsers checks the users against the same rules as the user endpoints
+// except for org_id, which is ignored.
+func validateUsers(users []pkguser.User) error {
+	var violations validate.Violations
+	for i, u := range users {
+		err := validate.StructExcept(u, fmt.Sprintf("users[%d].", i), "OrgID")
+		if err := violations.Add(err); err != nil {
+			return err
+		}
+	}
+	return violations.Err()
+}
@@ -0,0 +1,154 @@
+package onboarding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/user"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSVC struct {
+	mock.Mock
+}
+
+const validBody = `{"org":{"name":"foo-name","desc":"foo-desc"},"users":[{"name":"bar-name","email":"bar@baz.com"}]}`
+
+func (m *mockSVC) Onboard(ctx context.Context, o onboarding.Onboarding) (onboarding.Onboarding, error) {
+	args := m.Called(ctx, o)
+	return args.Get(0).(onboarding.Onboarding), args.Error(1)
+}
+
+func initCTRL() (c *ctrl, ms *mockSVC) {
+	log := testutil.GetLogger()
+	ms = new(mockSVC)
+	c = NewController(log, ms)
+	return c, ms
+}
+
+func ginCtxWithStrBody(body string) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	gc, _ := gin.CreateTestContext(w)
+	req, err := http.NewRequest("POST", "/", strings.NewReader(body))
+	if err != nil {
+		return nil, w, err
+	}
+	gc.Request = req
+	return gc, w, nil
+}
+
+func parseBody(t *testing.T, body string) onboarding.Onboarding {
+	var o onboarding.Onboarding
+	assert.Nil(t, json.Unmarshal([]byte(body), &o))
+	return o
+}
+
+func TestCTRLOnboard(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithStrBody(validBody)
+	assert.Nil(t, err)
+
+	mockRes := parseBody(t, `{"org":{"id":"foo-id","name":"foo-name","desc":"foo-desc"},"users":[{"id":"bar-id","org_id":"foo-id","name":"bar-name","email":"bar@baz.com"}]}`)
+	ms.On("Onboard", mock.Anything, parseBody(t, validBody)).Return(mockRes, nil)
+
+	c.Onboard(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, parseBody(t, string(bytes)))
+}
+
+func TestCTRLOnboard_ValidationError_NoUsers(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtxWithStrBody(`{"org":{"name":"foo-name","desc":"foo-desc"},"users":[]}`)
+	assert.Nil(t, err)
+
+	c.Onboard(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "users", Rule: "min", Message: "users must have at least 1 item"},
+	}, actual.Violations)
+}
+
+func TestCTRLOnboard_ValidationError_UserMissingEmail(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtxWithStrBody(`{"org":{"name":"foo-name","desc":"foo-desc"},"users":[{"name":"bar-name"}]}`)
+	assert.Nil(t, err)
+
+	c.Onboard(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "users[0].email", Rule: "required", Message: "users[0].email is required"},
+	}, actual.Violations)
+}
+
+func assertServiceErr(t *testing.T, mockErr error, expectedStatus int) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithStrBody(validBody)
+	assert.Nil(t, err)
+
+	ms.On("Onboard", mock.Anything, parseBody(t, validBody)).Return(onboarding.Onboarding{}, mockErr)
+
+	c.Onboard(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, expectedStatus, gc.Writer.Status())
+	assert.Equal(t, mockErr.Error(), actual.Detail)
+}
+
+func TestCTRLOnboard_NameAlreadyInUseError(t *testing.T) {
+	assertServiceErr(t, org.ErrNameAlreadyInUse{Name: "foo-name"}, 409)
+}
+
+func TestCTRLOnboard_EmailAlreadyInUseError(t *testing.T) {
+	assertServiceErr(t, user.ErrEmailAlreadyInUse{Email: "bar@baz.com"}, 409)
+}
+
+func TestCTRLOnboard_ForbiddenError(t *testing.T) {
+	assertServiceErr(t, authz.ErrForbidden{UserID: "foo-id", Action: "org:create"}, 403)
+}
+
+func TestCTRLOnboard_UnauthenticatedError(t *testing.T) {
+	assertServiceErr(t, authz.ErrUnauthenticated{}, 401)
+}
+
+func TestCTRLOnboard_ServiceError(t *testing.T) {
+	assertServiceErr(t, errors.New("unit-test mock service error"), 500)
+}
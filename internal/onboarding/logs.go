@@ -0,0 +1,15 @@
+package onboarding
+
+import "log/slog"
+
+func logAttrOrgName(orgName string) slog.Attr {
+	return slog.String("orgName", orgName)
+}
+
+func logAttrUsersLen(len int) slog.Attr {
+	return slog.Int("usersLen", len)
+}
+
+func logAttrOnboarding(o any) slog.Attr {
+	return slog.Any("onboarding", o)
+}
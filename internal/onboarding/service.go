@@ -0,0 +1,77 @@
+package onboarding
+
+import (
+	"context"
+	"log/slog"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+)
+
+type OrgSVC interface {
+	Create(ctx context.Context, joinTX *sqlx.Tx, o org.Org) (org.Org, error)
+}
+
+type UserSVC interface {
+	CreateInOrg(ctx context.Context, joinTX *sqlx.Tx, o org.Org, u user.User) (user.User, error)
+}
+
+type TXManager interface {
+	Do(ctx context.Context, tx *sqlx.Tx, f func(*sqlx.Tx) error) error
+}
+
+type service struct {
+	log     *slog.Logger
+	orgSVC  OrgSVC
+	userSVC UserSVC
+	txMGR   TXManager
+}
+
+func NewService(log *slog.Logger, orgSVC OrgSVC, userSVC UserSVC, txMGR TXManager) *service {
+	return &service{
+		log:     log.With(logutil.LogAttrSVC("OnboardingSVC")),
+		orgSVC:  orgSVC,
+		userSVC: userSVC,
+		txMGR:   txMGR,
+	}
+}
+
+// Onboard creates the org and its users in one tx, if any of them fails
+// nothing is kept. The org and user services do their own authorization.
+func (s service) Onboard(ctx context.Context, input onboarding.Onboarding) (out onboarding.Onboarding, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Onboard"),
+		logAttrOrgName(input.Org.Name),
+		logAttrUsersLen(len(input.Users)),
+	)
+	log.Debug("called")
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		o, err := s.orgSVC.Create(ctx, tx, input.Org)
+		if err != nil {
+			return err
+		}
+		users := make([]user.User, 0, len(input.Users))
+		for _, u := range input.Users {
+			u, err = s.userSVC.CreateInOrg(ctx, tx, o, u)
+			if err != nil {
+				return err
+			}
+			users = append(users, u)
+		}
+		out = onboarding.Onboarding{
+			Org:   o,
+			Users: users,
+		}
+		return nil
+	})
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("onboarding rolled back")
+		return onboarding.Onboarding{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,127 @@
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockOrgSVC struct {
+	mock.Mock
+}
+
+type mockUserSVC struct {
+	mock.Mock
+}
+
+type mockTXManager struct {
+	mock.Mock
+}
+
+var (
+	ctx = context.Background()
+	tx  = &sqlx.Tx{}
+)
+
+func (m *mockOrgSVC) Create(ctx context.Context, joinTX *sqlx.Tx, o org.Org) (org.Org, error) {
+	args := m.Called(ctx, joinTX, o)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (m *mockUserSVC) CreateInOrg(ctx context.Context, joinTX *sqlx.Tx, o org.Org, u user.User) (user.User, error) {
+	args := m.Called(ctx, joinTX, o, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+// mockTXManager hands f the tx a real manager would have begun, so the test
+// can check it's the one every create joins.
+func (m *mockTXManager) Do(ctx context.Context, joinTX *sqlx.Tx, f func(*sqlx.Tx) error) error {
+	m.Called(ctx, joinTX)
+	return f(tx)
+}
+
+func initSVC() (s *service, mo *mockOrgSVC, mu *mockUserSVC, mm *mockTXManager) {
+	log := testutil.GetLogger()
+	mo = new(mockOrgSVC)
+	mu = new(mockUserSVC)
+	mm = new(mockTXManager)
+	s = NewService(log, mo, mu, mm)
+	return s, mo, mu, mm
+}
+
+func TestSVCOnboard(t *testing.T) {
+	s, mo, mu, mm := initSVC()
+
+	input := onboarding.Onboarding{
+		Org: org.Org{Name: "foo-name", Desc: "foo-desc"},
+		Users: []user.User{
+			{Name: "bar-name", Email: "bar@baz.com"},
+			{Name: "baz-name", Email: "baz@baz.com"},
+		},
+	}
+	var nilTX *sqlx.Tx
+	mm.On("Do", ctx, nilTX).Return(nil)
+	o := org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc"}
+	mo.On("Create", ctx, tx, input.Org).Return(o, nil)
+	u1 := user.User{ID: "bar-id", OrgID: o.ID, Name: "bar-name", Email: "bar@baz.com"}
+	u2 := user.User{ID: "baz-id", OrgID: o.ID, Name: "baz-name", Email: "baz@baz.com"}
+	mu.On("CreateInOrg", ctx, tx, o, input.Users[0]).Return(u1, nil)
+	mu.On("CreateInOrg", ctx, tx, o, input.Users[1]).Return(u2, nil)
+
+	actual, err := s.Onboard(ctx, input)
+
+	assert.Nil(t, err)
+	assert.Equal(t, onboarding.Onboarding{Org: o, Users: []user.User{u1, u2}}, actual)
+	mm.AssertExpectations(t)
+	mu.AssertExpectations(t)
+}
+
+func TestSVCOnboard_OrgErr(t *testing.T) {
+	s, mo, _, mm := initSVC()
+
+	input := onboarding.Onboarding{
+		Org:   org.Org{Name: "foo-name", Desc: "foo-desc"},
+		Users: []user.User{{Name: "bar-name", Email: "bar@baz.com"}},
+	}
+	var nilTX *sqlx.Tx
+	mm.On("Do", ctx, nilTX).Return(nil)
+	mockErr := errors.New("unit-test mock error")
+	mo.On("Create", ctx, tx, input.Org).Return(org.Org{}, mockErr)
+
+	actual, err := s.Onboard(ctx, input)
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, onboarding.Onboarding{}, actual)
+}
+
+func TestSVCOnboard_UserErr(t *testing.T) {
+	s, mo, mu, mm := initSVC()
+
+	input := onboarding.Onboarding{
+		Org: org.Org{Name: "foo-name", Desc: "foo-desc"},
+		Users: []user.User{
+			{Name: "bar-name", Email: "bar@baz.com"},
+			{Name: "baz-name", Email: "baz@baz.com"},
+		},
+	}
+	var nilTX *sqlx.Tx
+	mm.On("Do", ctx, nilTX).Return(nil)
+	o := org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc"}
+	mo.On("Create", ctx, tx, input.Org).Return(o, nil)
+	mu.On("CreateInOrg", ctx, tx, o, input.Users[0]).Return(user.User{ID: "bar-id"}, nil)
+	mockErr := errors.New("unit-test mock error")
+	mu.On("CreateInOrg", ctx, tx, o, input.Users[1]).Return(user.User{}, mockErr)
+
+	actual, err := s.Onboard(ctx, input)
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, onboarding.Onboarding{}, actual)
+}
@@ -0,0 +1,25 @@
+package onboarding
+
+import (
+	"context"
+
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
+)
+
+// tracedService wraps every OnboardingService call in a span.
+type tracedService struct {
+	svc OnboardingService
+}
+
+func NewTracedService(svc OnboardingService) *tracedService {
+	return &tracedService{
+		svc: svc,
+	}
+}
+
+func (s tracedService) Onboard(ctx context.Context, input onboarding.Onboarding) (o onboarding.Onboarding, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OnboardingSVC.Onboard")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Onboard(ctx, input)
+}
@@ -0,0 +1,70 @@
+package openapi
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// swaggerUI is the version of swagger-ui-dist in swaggerui/, update it along
+// with swagger-ui-bundle.js and swagger-ui.css (copied as is from its dist).
+const swaggerUI = "5.18.2"
+
+//go:embed swaggerui/swagger-ui-bundle.js swaggerui/swagger-ui.css
+var swaggerUIFiles embed.FS
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>go-service-ex API</title>
+  <link rel="stylesheet" href="%[2]s/swagger-ui.css?v=%[1]s">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%[2]s/swagger-ui-bundle.js?v=%[1]s"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: %[3]s, dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+// Handler responds with doc.
+func Handler(doc Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// DocsHandler is a Swagger UI page for the document at specURL, it loads the
+// UI's files from assetsURL (see DocsAssets).
+func DocsHandler(specURL string, assetsURL string) gin.HandlerFunc {
+	page := []byte(fmt.Sprintf(docsPage, swaggerUI, assetsURL, strconv.Quote(specURL)))
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
+
+// DocsAssets serves the Swagger UI files the docs page loads, they're embedded
+// in the binary so the page doesn't depend on a CDN. The route needs a :file
+// param.
+func DocsAssets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("file")
+		b, err := fs.ReadFile(swaggerUIFiles, path.Join("swaggerui", name))
+		if err != nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Data(http.StatusOK, mime.TypeByExtension(path.Ext(name)), b)
+	}
+}
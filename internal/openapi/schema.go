@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// schemas turns go types into the schemas of their json, named structs are
+// added to the components once and referenced everywhere else.
+type schemas struct {
+	components map[string]*Schema
+	types      map[string]reflect.Type
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: map[string]*Schema{},
+		types:      map[string]reflect.Type{},
+	}
+}
+
+// of panics for a type json can't be made of (ex. a chan) or two structs with
+// the same name, both are mistakes in the spec rather than something to
+// handle.
+func (s *schemas) of(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		// a $ref can't have siblings in 3.0, the field's omitempty covers it
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Struct:
+		return s.ref(t)
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Interface:
+		// any json at all
+		return &Schema{}
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+func (s *schemas) ref(t reflect.Type) *Schema {
+	name := t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if existing, found := s.types[name]; found {
+		if existing != t {
+			panic(fmt.Sprintf("openapi: %s and %s are both named %s", existing, t, name))
+		}
+		return ref
+	}
+	if name == "" {
+		panic(fmt.Sprintf("openapi: no schema for the unnamed %s", t))
+	}
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	// added before the fields so a struct can refer to itself
+	s.types[name] = t
+	s.components[name] = schema
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fieldName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if fieldName == "-" {
+			continue
+		}
+		if fieldName == "" {
+			fieldName = f.Name
+		}
+		fieldSchema := s.of(f.Type)
+		if rules(fieldSchema, f.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, fieldName)
+		}
+		schema.Properties[fieldName] = fieldSchema
+	}
+	return ref
+}
+
+// rules applies the binding rules validate describes to schema, it's true
+// when the field is required.
+func rules(schema *Schema, binding string) (required bool) {
+	if binding == "" || binding == "-" {
+		return false
+	}
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, v)
+			}
+		case "min", "max":
+			if n, err := strconv.Atoi(param); err == nil {
+				bound(schema, name, n)
+			}
+		case "dive":
+			// the rules after dive are the items', which have their own
+			// schema
+			return required
+		}
+	}
+	return required
+}
+
+func bound(schema *Schema, rule string, n int) {
+	isMin := rule == "min"
+	switch schema.Type {
+	case "string":
+		if isMin {
+			schema.MinLength = &n
+		} else {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if isMin {
+			schema.MinItems = &n
+		} else {
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if isMin {
+			schema.Minimum = &f
+		} else {
+			schema.Maximum = &f
+		}
+	}
+}
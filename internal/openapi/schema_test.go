@@ -0,0 +1,91 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf_Struct(t *testing.T) {
+	s := newSchemas()
+
+	schema := s.of(reflect.TypeFor[org.Org]())
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/Org"}, schema)
+	o := s.components["Org"]
+	assert.Equal(t, "object", o.Type)
+	assert.Equal(t, []string{"name", "desc"}, o.Required)
+	assert.Equal(t, &Schema{Type: "string", MaxLength: ptr(100)}, o.Properties["name"])
+	assert.Equal(t, &Schema{Type: "boolean"}, o.Properties["is_system"])
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, o.Properties["version"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, o.Properties["created_at"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time", Nullable: true}, o.Properties["deleted_at"])
+}
+
+func TestOf_Rules(t *testing.T) {
+	s := newSchemas()
+
+	s.of(reflect.TypeFor[user.Batch]())
+
+	b := s.components["Batch"]
+	assert.Equal(t, []string{"mode", "ops"}, b.Required)
+	assert.Equal(t, []any{"all-or-nothing", "best-effort"}, b.Properties["mode"].Enum)
+	assert.Equal(t, &Schema{
+		Type:     "array",
+		Items:    &Schema{Ref: "#/components/schemas/BatchOp"},
+		MinItems: ptr(1),
+		MaxItems: ptr(500),
+	}, b.Properties["ops"])
+	// binding:"-" skips the user's rules, they're checked per op
+	assert.Equal(t, []string{"op"}, s.components["BatchOp"].Required)
+	assert.Equal(t, "email", s.components["User"].Properties["email"].Format)
+}
+
+func TestOf_PointerToStruct(t *testing.T) {
+	s := newSchemas()
+
+	s.of(reflect.TypeFor[user.BatchResult]())
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/User"}, s.components["BatchResult"].Properties["user"])
+}
+
+func TestOf_Map(t *testing.T) {
+	s := newSchemas()
+
+	schema := s.of(reflect.TypeFor[map[string]any]())
+
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{}}, schema)
+}
+
+func TestOf_SameStructTwice(t *testing.T) {
+	s := newSchemas()
+
+	s.of(reflect.TypeFor[org.Org]())
+	s.of(reflect.TypeFor[[]org.Org]())
+
+	assert.Len(t, s.components, 1)
+}
+
+func TestOf_NameCollision(t *testing.T) {
+	type Org struct {
+		Name string `json:"name"`
+	}
+	s := newSchemas()
+	s.of(reflect.TypeFor[org.Org]())
+
+	assert.Panics(t, func() {
+		s.of(reflect.TypeFor[Org]())
+	})
+}
+
+func TestOf_Unsupported(t *testing.T) {
+	s := newSchemas()
+
+	assert.Panics(t, func() {
+		s.of(reflect.TypeFor[chan time.Time]())
+	})
+}
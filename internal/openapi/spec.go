@@ -0,0 +1,739 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/internal/etag"
+	"github.com/RyanBard/go-service-ex/internal/health"
+	"github.com/RyanBard/go-service-ex/internal/idempotency"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
+
+const bearerAuth = "bearerAuth"
+
+const description = `Every error is an application/problem+json (RFC 7807) with a stable code, see the Problem schema.
+
+Send an x-request-id to have it in the server's logs, one is generated otherwise.`
+
+// spec is the document as it's being built, the schemas are only added to
+// the components once every operation has been.
+type spec struct {
+	doc     Document
+	schemas *schemas
+}
+
+// Spec describes every route cmd/server registers, a test there checks the
+// two match. The models' schemas come from their json and binding tags.
+func Spec() Document {
+	s := &spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       "go-service-ex",
+				Description: description,
+				Version:     "1.0.0",
+			},
+			Tags: []Tag{
+				{Name: "orgs"},
+				{Name: "users"},
+				{Name: "memberships"},
+				{Name: "invitations"},
+				{Name: "audit"},
+				{Name: "onboarding"},
+				{Name: "ops", Description: "Health checks, metrics and this document, none need a token."},
+			},
+			Paths: map[string]PathItem{},
+			Components: Components{
+				Parameters: parameters(),
+				Responses:  map[string]Response{},
+				SecuritySchemes: map[string]SecurityScheme{
+					bearerAuth: {
+						Type:         "http",
+						Scheme:       "bearer",
+						BearerFormat: "JWT",
+						Description:  "HS256 with the shared secret, or RS256/ES256/EdDSA with a key from the JWKS picked by kid.",
+					},
+				},
+			},
+			Security: []SecurityRequirement{{bearerAuth: {}}},
+		},
+		schemas: newSchemas(),
+	}
+	s.ops()
+	s.orgs()
+	s.users()
+	s.memberships()
+	s.invitations()
+	s.audit()
+	s.onboarding()
+	s.doc.Components.Schemas = s.schemas.components
+	// the problem's extensions (ex. num_users) depend on its code
+	s.doc.Components.Schemas["Problem"].AdditionalProperties = true
+	var ops []any
+	for _, op := range []string{patch.OpAdd, patch.OpRemove, patch.OpReplace, patch.OpMove, patch.OpCopy, patch.OpTest} {
+		ops = append(ops, op)
+	}
+	s.doc.Components.Schemas["Op"].Properties["op"].Enum = ops
+	return s.doc
+}
+
+func (s *spec) ops() {
+	ops := []string{"ops"}
+	none := &[]SecurityRequirement{}
+	s.add(http.MethodGet, "/health", Operation{
+		Tags:        ops,
+		OperationID: "getLiveness",
+		Summary:     "Liveness, whether the process should be restarted",
+		Security:    none,
+		Responses: map[string]Response{
+			"200": s.json("Up", health.Report{}),
+			"503": s.json("Down", health.Report{}),
+		},
+	})
+	s.add(http.MethodGet, "/readiness", Operation{
+		Tags:        ops,
+		OperationID: "getReadiness",
+		Summary:     "Readiness, whether the instance should be sent traffic",
+		Security:    none,
+		Responses: map[string]Response{
+			"200": s.json("Up", health.Report{}),
+			"503": s.json("Down (or draining)", health.Report{}),
+		},
+	})
+	s.add(http.MethodGet, "/metrics", Operation{
+		Tags:        ops,
+		OperationID: "getMetrics",
+		Summary:     "Prometheus metrics",
+		Security:    none,
+		Responses: map[string]Response{
+			"200": {
+				Description: "The metrics in the Prometheus text format",
+				Content:     map[string]MediaType{"text/plain": {Schema: &Schema{Type: "string"}}},
+			},
+		},
+	})
+	s.add(http.MethodGet, "/openapi.json", Operation{
+		Tags:        ops,
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Security:    none,
+		Responses: map[string]Response{
+			"200": {
+				Description: "The OpenAPI document",
+				Content:     map[string]MediaType{"application/json": {Schema: &Schema{Type: "object"}}},
+			},
+		},
+	})
+}
+
+func (s *spec) orgs() {
+	orgs := []string{"orgs"}
+	s.authorized(http.MethodGet, "/api/orgs/{id}", Operation{
+		Tags:        orgs,
+		OperationID: "getOrg",
+		Summary:     "Get an org",
+		Parameters:  refs("ID", "IncludeDeleted", "IfNoneMatch"),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The org", org.Org{}, etagHeader()),
+			"304": {Description: "The org hasn't changed since the If-None-Match", Headers: etagHeader()},
+		}, 400, 403, 404),
+	})
+	s.authorized(http.MethodGet, "/api/orgs", Operation{
+		Tags:        orgs,
+		OperationID: "listOrgs",
+		Summary:     "List the orgs the caller can see",
+		Parameters: append(refs("IncludeDeleted", "Limit", "Cursor"),
+			query("name", "Only the orgs whose name contains this, case insensitive", &Schema{Type: "string"}),
+		),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("A page of orgs", org.OrgPage{}),
+		}, 400, 403),
+	})
+	s.authorized(http.MethodGet, "/api/orgs/export", Operation{
+		Tags:        orgs,
+		OperationID: "exportOrgs",
+		Summary:     "Export every org the caller can see",
+		Parameters:  refs("Format"),
+		Responses: s.responses(map[string]Response{
+			"200": export("The orgs, streamed"),
+		}, 400, 403),
+	})
+	save := func(method string, path string, id string, summary string) {
+		params := []Parameter{}
+		if strings.Contains(path, "{id}") {
+			params = refs("ID")
+		}
+		s.authorized(method, path, Operation{
+			Tags:        orgs,
+			OperationID: id,
+			Summary:     summary,
+			Description: "Creates the org when it has no id, otherwise updates it. The version is checked on update, If-Match can send it instead of the body.",
+			Parameters:  append(params, refs("IfMatch")...),
+			RequestBody: s.body("The org", org.Org{}),
+			Responses: s.responses(map[string]Response{
+				"200": s.json("The saved org", org.Org{}, etagHeader()),
+			}, 400, 403, 404, 409, 412),
+		})
+	}
+	save(http.MethodPost, "/api/orgs", "createOrg", "Create an org")
+	save(http.MethodPut, "/api/orgs", "createOrgWithPut", "Create an org, same as POST")
+	save(http.MethodPost, "/api/orgs/{id}", "updateOrgWithPost", "Update an org, same as PUT")
+	save(http.MethodPut, "/api/orgs/{id}", "updateOrg", "Update an org")
+	s.authorized(http.MethodPatch, "/api/orgs/{id}", Operation{
+		Tags:        orgs,
+		OperationID: "patchOrg",
+		Summary:     "Change part of an org",
+		Description: "Only name and desc can be changed. The version is only checked when it's sent, in the patch or as If-Match.",
+		Parameters:  refs("ID", "IfMatch"),
+		RequestBody: s.patchBody(),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The patched org", org.Org{}, etagHeader()),
+		}, 400, 403, 404, 409, 412, 415),
+	})
+	s.authorized(http.MethodDelete, "/api/orgs/{id}", Operation{
+		Tags:        orgs,
+		OperationID: "deleteOrg",
+		Summary:     "Soft delete an org",
+		Description: "An org that still has users can't be deleted without a mode or reassign_to. The body can be left out when If-Match is sent.",
+		Parameters: append(refs("ID", "IfMatch"),
+			query("mode", "cascade soft deletes the org's users along with it", &Schema{Type: "string", Enum: []any{org.DeleteModeCascade}}),
+			query("reassign_to", "The org to move the org's users to", &Schema{Type: "string"}),
+		),
+		RequestBody: s.optionalBody("The org's id and version", org.DeleteOrg{}),
+		Responses: s.responses(map[string]Response{
+			"204": {Description: "Deleted (or already gone)"},
+		}, 400, 403, 409, 412),
+	})
+	s.authorized(http.MethodPost, "/api/orgs/{id}/restore", Operation{
+		Tags:        orgs,
+		OperationID: "restoreOrg",
+		Summary:     "Restore a soft deleted org",
+		Parameters:  refs("ID"),
+		RequestBody: s.body("The org's version", org.RestoreOrg{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The restored org", org.Org{}, etagHeader()),
+		}, 400, 403, 404, 409),
+	})
+	s.authorized(http.MethodGet, "/api/orgs/{id}/users", Operation{
+		Tags:        []string{"orgs", "users"},
+		OperationID: "listOrgUsers",
+		Summary:     "List an org's users",
+		Parameters:  refs("ID", "IncludeDeleted", "Limit", "Cursor"),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("A page of users", user.UserPage{}),
+		}, 400, 403, 404),
+	})
+}
+
+func (s *spec) users() {
+	users := []string{"users"}
+	s.authorized(http.MethodGet, "/api/users/{id}", Operation{
+		Tags:        users,
+		OperationID: "getUser",
+		Summary:     "Get a user",
+		Parameters:  refs("ID", "IncludeDeleted", "IfNoneMatch"),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The user", user.User{}, etagHeader()),
+			"304": {Description: "The user hasn't changed since the If-None-Match", Headers: etagHeader()},
+		}, 400, 403, 404),
+	})
+	sorts := []any{}
+	for _, sort := range []string{user.SortEmail, user.SortName, user.SortCreatedAt, user.SortUpdatedAt} {
+		sorts = append(sorts, sort, "-"+sort)
+	}
+	s.authorized(http.MethodGet, "/api/users", Operation{
+		Tags:        users,
+		OperationID: "listUsers",
+		Summary:     "List (and filter) the users the caller can see",
+		Description: "Email and name match case insensitive substrings, the from times are inclusive and the to times are exclusive.",
+		Parameters: append(refs("IncludeDeleted", "Limit", "Cursor"),
+			query("org_id", "", &Schema{Type: "string"}),
+			query("is_active", "", &Schema{Type: "boolean"}),
+			query("is_admin", "", &Schema{Type: "boolean"}),
+			query("email", "", &Schema{Type: "string"}),
+			query("name", "", &Schema{Type: "string"}),
+			query("created_from", "", &Schema{Type: "string", Format: "date-time"}),
+			query("created_to", "", &Schema{Type: "string", Format: "date-time"}),
+			query("updated_from", "", &Schema{Type: "string", Format: "date-time"}),
+			query("updated_to", "", &Schema{Type: "string", Format: "date-time"}),
+			query("sort", "Prefix with - to sort descending, email by default", &Schema{Type: "string", Enum: sorts}),
+		),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("A page of users", user.UserPage{}),
+		}, 400, 403),
+	})
+	s.authorized(http.MethodGet, "/api/users/export", Operation{
+		Tags:        users,
+		OperationID: "exportUsers",
+		Summary:     "Export every user the caller can see",
+		Parameters:  refs("Format"),
+		Responses: s.responses(map[string]Response{
+			"200": export("The users, streamed"),
+		}, 400, 403),
+	})
+	save := func(method string, path string, id string, summary string) {
+		params := []Parameter{}
+		if strings.Contains(path, "{id}") {
+			params = refs("ID")
+		}
+		s.authorized(method, path, Operation{
+			Tags:        users,
+			OperationID: id,
+			Summary:     summary,
+			Description: "Creates the user when it has no id, otherwise updates it. The version is checked on update, If-Match can send it instead of the body.",
+			Parameters:  append(params, refs("IfMatch")...),
+			RequestBody: s.body("The user", user.User{}),
+			Responses: s.responses(map[string]Response{
+				"200": s.json("The saved user", user.User{}, etagHeader()),
+			}, 400, 403, 404, 409, 412),
+		})
+	}
+	save(http.MethodPost, "/api/users", "createUser", "Create a user")
+	save(http.MethodPut, "/api/users", "createUserWithPut", "Create a user, same as POST")
+	save(http.MethodPost, "/api/users/{id}", "updateUserWithPost", "Update a user, same as PUT")
+	save(http.MethodPut, "/api/users/{id}", "updateUser", "Update a user")
+	s.authorized(http.MethodPatch, "/api/users/{id}", Operation{
+		Tags:        users,
+		OperationID: "patchUser",
+		Summary:     "Change part of a user",
+		Description: "Only name, is_admin and is_active can be changed, the email has its own endpoints. The version is only checked when it's sent, in the patch or as If-Match.",
+		Parameters:  refs("ID", "IfMatch"),
+		RequestBody: s.patchBody(),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The patched user", user.User{}, etagHeader()),
+		}, 400, 403, 404, 409, 412, 415),
+	})
+	s.authorized(http.MethodDelete, "/api/users/{id}", Operation{
+		Tags:        users,
+		OperationID: "deleteUser",
+		Summary:     "Soft delete a user",
+		Description: "The body can be left out when If-Match is sent.",
+		Parameters:  refs("ID", "IfMatch"),
+		RequestBody: s.optionalBody("The user's id and version", user.DeleteUser{}),
+		Responses: s.responses(map[string]Response{
+			"204": {Description: "Deleted (or already gone)"},
+		}, 400, 403, 409, 412),
+	})
+	s.authorized(http.MethodPost, "/api/users/{id}/restore", Operation{
+		Tags:        users,
+		OperationID: "restoreUser",
+		Summary:     "Restore a soft deleted user",
+		Parameters:  refs("ID"),
+		RequestBody: s.body("The user's version", user.RestoreUser{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The restored user", user.User{}, etagHeader()),
+		}, 400, 403, 404, 409),
+	})
+	s.authorized(http.MethodPost, "/api/users/{id}/email-change", Operation{
+		Tags:        users,
+		OperationID: "requestEmailChange",
+		Summary:     "Ask for a user's email to be changed",
+		Description: "A token is sent to the new email, the email only changes once it's confirmed.",
+		Parameters:  refs("ID"),
+		RequestBody: s.body("The new email", user.EmailChange{}),
+		Responses: s.responses(map[string]Response{
+			"202": s.json("The change waiting to be confirmed", user.PendingEmailChange{}),
+		}, 400, 403, 404, 409),
+	})
+	s.authorized(http.MethodPost, "/api/users/{id}/email-change/confirm", Operation{
+		Tags:        users,
+		OperationID: "confirmEmailChange",
+		Summary:     "Confirm a user's email change",
+		Parameters:  refs("ID"),
+		RequestBody: s.body("The token sent to the new email", user.ConfirmEmailChange{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The user with the new email", user.User{}),
+		}, 400, 403, 404, 409, 410),
+	})
+	s.streamed(http.MethodPost, "/api/users/import", Operation{
+		Tags:        users,
+		OperationID: "importUsers",
+		Summary:     "Create a user for every row",
+		Description: "Rows are validated the same as a created user, the report lists the rows that failed.",
+		Parameters: append(refs("Format"),
+			query("dry_run", "Only report what would be imported", &Schema{Type: "boolean"}),
+		),
+		RequestBody: &RequestBody{
+			Description: "The users, csv has a header row",
+			Required:    true,
+			Content:     exportContent(),
+		},
+		Responses: s.responses(map[string]Response{
+			"200": s.json("What was imported", user.ImportReport{}),
+		}, 400, 403),
+	})
+	s.authorized(http.MethodPost, "/api/users:batch", Operation{
+		Tags:        users,
+		OperationID: "batchUsers",
+		Summary:     "Create, update and delete users in one request",
+		Description: "A best-effort batch that had an op fail is a 207. An all-or-nothing batch that had an op fail is rolled back and responded with that op's status, the body is still the batch response.",
+		RequestBody: s.body("The ops", user.Batch{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("Every op's result", user.BatchResponse{}),
+			"207": s.json("Every op's result, some failed", user.BatchResponse{}),
+		}, 400, 403, 404),
+	})
+}
+
+func (s *spec) memberships() {
+	memberships := []string{"memberships"}
+	s.authorized(http.MethodGet, "/api/orgs/{id}/memberships", Operation{
+		Tags:        memberships,
+		OperationID: "listOrgMemberships",
+		Summary:     "List an org's memberships",
+		Parameters:  refs("ID", "Limit", "Cursor"),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("A page of memberships", membership.MembershipPage{}),
+		}, 400, 403),
+	})
+	s.authorized(http.MethodPost, "/api/orgs/{id}/memberships", Operation{
+		Tags:        memberships,
+		OperationID: "addMembership",
+		Summary:     "Add a user to an org",
+		Parameters:  refs("ID"),
+		RequestBody: s.body("The user and their role", membership.AddMembership{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The membership", membership.Membership{}),
+		}, 400, 403, 404, 409),
+	})
+	s.authorized(http.MethodDelete, "/api/orgs/{id}/memberships/{userID}", Operation{
+		Tags:        memberships,
+		OperationID: "removeMembership",
+		Summary:     "Remove a user from an org",
+		Parameters: append(refs("ID"), Parameter{
+			Name:     "userID",
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		}),
+		Responses: s.responses(map[string]Response{
+			"204": {Description: "Removed (or already gone)"},
+		}, 403, 409),
+	})
+	s.authorized(http.MethodGet, "/api/users/{id}/memberships", Operation{
+		Tags:        memberships,
+		OperationID: "listUserMemberships",
+		Summary:     "List a user's memberships",
+		Description: "Not paged, a user is only ever in a handful of orgs.",
+		Parameters:  refs("ID"),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("Every membership of the user", []membership.Membership{}),
+		}, 404),
+	})
+}
+
+func (s *spec) invitations() {
+	invitations := []string{"invitations"}
+	s.authorized(http.MethodGet, "/api/orgs/{id}/invitations", Operation{
+		Tags:        invitations,
+		OperationID: "listOrgInvitations",
+		Summary:     "List an org's invitations",
+		Parameters: append(refs("ID", "Limit", "Cursor"),
+			query("status", "", &Schema{Type: "string", Enum: []any{
+				invitation.StatusPending,
+				invitation.StatusAccepted,
+				invitation.StatusRevoked,
+				invitation.StatusExpired,
+			}}),
+		),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("A page of invitations", invitation.InvitationPage{}),
+		}, 400, 403),
+	})
+	s.authorized(http.MethodPost, "/api/orgs/{id}/invitations", Operation{
+		Tags:        invitations,
+		OperationID: "createInvitation",
+		Summary:     "Invite an email to an org",
+		Description: "The token that accepts the invitation is only sent to the email.",
+		Parameters:  refs("ID"),
+		RequestBody: s.body("The email and the role it's invited as", invitation.CreateInvitation{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The invitation", invitation.Invitation{}),
+		}, 400, 403, 404, 409),
+	})
+	s.authorized(http.MethodPost, "/api/invitations/{id}/resend", Operation{
+		Tags:        invitations,
+		OperationID: "resendInvitation",
+		Summary:     "Send a pending invitation again with a new token",
+		Parameters:  refs("ID"),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The invitation", invitation.Invitation{}),
+		}, 403, 404, 409),
+	})
+	s.authorized(http.MethodDelete, "/api/invitations/{id}", Operation{
+		Tags:        invitations,
+		OperationID: "revokeInvitation",
+		Summary:     "Revoke a pending invitation",
+		Parameters:  refs("ID"),
+		Responses: s.responses(map[string]Response{
+			"204": {Description: "Revoked (or already gone)"},
+		}, 403, 409),
+	})
+	s.public(http.MethodPost, "/api/invitations/accept", Operation{
+		Tags:        invitations,
+		OperationID: "acceptInvitation",
+		Summary:     "Accept an invitation",
+		Description: "The token is what's checked, the person accepting doesn't have a token yet. A name is only needed when the email doesn't belong to a user yet.",
+		RequestBody: s.body("The invitation's token", invitation.AcceptInvitation{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The user that joined the org", user.User{}),
+		}, 400, 404, 409, 410),
+	})
+}
+
+func (s *spec) audit() {
+	s.authorized(http.MethodGet, "/api/audit", Operation{
+		Tags:        []string{"audit"},
+		OperationID: "searchAudit",
+		Summary:     "Search the audit log",
+		Description: "The from time is inclusive and the to time is exclusive.",
+		Parameters: append(refs("Limit", "Cursor"),
+			query("entity_type", "", &Schema{Type: "string", Enum: []any{
+				audit.EntityOrg,
+				audit.EntityUser,
+				audit.EntityMembership,
+				audit.EntityInvitation,
+			}}),
+			query("entity_id", "", &Schema{Type: "string"}),
+			query("actor_id", "", &Schema{Type: "string"}),
+			query("from", "", &Schema{Type: "string", Format: "date-time"}),
+			query("to", "", &Schema{Type: "string", Format: "date-time"}),
+		),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("A page of events", audit.EventPage{}),
+		}, 400, 403),
+	})
+}
+
+func (s *spec) onboarding() {
+	s.authorized(http.MethodPost, "/api/onboarding", Operation{
+		Tags:        []string{"onboarding"},
+		OperationID: "onboard",
+		Summary:     "Create an org and its initial users together",
+		RequestBody: s.body("The org and its users, the users' org_id is ignored", onboarding.Onboarding{}),
+		Responses: s.responses(map[string]Response{
+			"200": s.json("The created org and users", onboarding.Onboarding{}),
+		}, 400, 403, 409),
+	})
+}
+
+// public is for the routes that don't need a token.
+func (s *spec) public(method string, path string, op Operation) {
+	op.Security = &[]SecurityRequirement{}
+	s.api(method, path, op)
+}
+
+// authorized is for the routes behind mdlw.Auth and idempotency.Middleware,
+// it adds what those can respond with.
+func (s *spec) authorized(method string, path string, op Operation) {
+	if method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch {
+		op.Parameters = append(op.Parameters, refs("IdempotencyKey")...)
+		for _, status := range []int{http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity} {
+			op.Responses[strconv.Itoa(status)] = s.problem(status)
+		}
+		for status, r := range op.Responses {
+			if strings.HasPrefix(status, "2") {
+				r.Headers = withHeader(r.Headers, idempotency.HeaderReplayed, Header{
+					Description: "true when this is the response to an earlier request with the same Idempotency-Key",
+					Schema:      &Schema{Type: "string", Enum: []any{"true"}},
+				})
+				op.Responses[status] = r
+			}
+		}
+	}
+	s.streamed(method, path, op)
+}
+
+// streamed is for the routes behind mdlw.Auth that stream their body, they
+// aren't behind idempotency.Middleware.
+func (s *spec) streamed(method string, path string, op Operation) {
+	op.Responses[strconv.Itoa(http.StatusUnauthorized)] = s.problem(http.StatusUnauthorized)
+	s.api(method, path, op)
+}
+
+// api adds what every route under /api has.
+func (s *spec) api(method string, path string, op Operation) {
+	op.Parameters = append(op.Parameters, refs("RequestID")...)
+	op.Responses[strconv.Itoa(http.StatusInternalServerError)] = s.problem(http.StatusInternalServerError)
+	s.add(method, path, op)
+}
+
+func (s *spec) add(method string, path string, op Operation) {
+	item, found := s.doc.Paths[path]
+	if !found {
+		item = PathItem{}
+		s.doc.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = &op
+}
+
+func (s *spec) schema(v any) *Schema {
+	return s.schemas.of(reflect.TypeOf(v))
+}
+
+func (s *spec) json(description string, v any, headers ...map[string]Header) Response {
+	r := Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: s.schema(v)}},
+	}
+	for _, h := range headers {
+		for name, header := range h {
+			r.Headers = withHeader(r.Headers, name, header)
+		}
+	}
+	return r
+}
+
+func (s *spec) body(description string, v any) *RequestBody {
+	return &RequestBody{
+		Description: description,
+		Required:    true,
+		Content:     map[string]MediaType{"application/json": {Schema: s.schema(v)}},
+	}
+}
+
+func (s *spec) optionalBody(description string, v any) *RequestBody {
+	b := s.body(description, v)
+	b.Required = false
+	return b
+}
+
+func (s *spec) patchBody() *RequestBody {
+	return &RequestBody{
+		Description: "A merge patch (RFC 7396) or a json patch (RFC 6902), picked by the Content-Type",
+		Required:    true,
+		Content: map[string]MediaType{
+			patch.ContentTypeMerge: {Schema: &Schema{Type: "object", Description: "The members to change, null removes one"}},
+			patch.ContentTypeJSON:  {Schema: s.schema([]patch.Op{})},
+		},
+	}
+}
+
+// responses adds a problem response for every status to rs.
+func (s *spec) responses(rs map[string]Response, statuses ...int) map[string]Response {
+	for _, status := range statuses {
+		rs[strconv.Itoa(status)] = s.problem(status)
+	}
+	return rs
+}
+
+// problem is a reference to the status' response, which is added to the
+// components the first time it's used.
+func (s *spec) problem(status int) Response {
+	name := strings.ReplaceAll(http.StatusText(status), " ", "")
+	if _, found := s.doc.Components.Responses[name]; !found {
+		s.doc.Components.Responses[name] = Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{problem.ContentType: {Schema: s.schema(problem.Problem{})}},
+		}
+	}
+	return Response{Ref: "#/components/responses/" + name}
+}
+
+// parameters are the ones more than one operation has, see refs.
+func parameters() map[string]Parameter {
+	return map[string]Parameter{
+		"ID": {
+			Name:     "id",
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		},
+		"IncludeDeleted": query("include_deleted", "Include the soft deleted ones", &Schema{Type: "boolean"}),
+		"Limit": query("limit", "The page size, "+strconv.Itoa(page.DefaultLimit)+" by default, a larger one than "+strconv.Itoa(page.MaxLimit)+" is cut down to it", &Schema{
+			Type:    "integer",
+			Minimum: ptr(1.0),
+		}),
+		"Cursor": query("cursor", "The next_cursor of the previous page", &Schema{Type: "string"}),
+		"Format": query("format", "csv by default", &Schema{Type: "string", Enum: []any{dataio.FormatCSV, dataio.FormatNDJSON}}),
+		"IfMatch": {
+			Name:        etag.HeaderIfMatch,
+			In:          "header",
+			Description: "The ETag the change was based on, used as the version (ex. \"<id>:<version>\")",
+			Schema:      &Schema{Type: "string"},
+		},
+		"IfNoneMatch": {
+			Name:        etag.HeaderIfNoneMatch,
+			In:          "header",
+			Description: "A 304 while the ETag still matches",
+			Schema:      &Schema{Type: "string"},
+		},
+		"IdempotencyKey": {
+			Name:        idempotency.HeaderKey,
+			In:          "header",
+			Description: "Only handle the request once, retries with the same key get the first response back",
+			Schema:      &Schema{Type: "string", MaxLength: ptr(255)},
+		},
+		"RequestID": {
+			Name:        "x-request-id",
+			In:          "header",
+			Description: "Logged with everything the request does",
+			Schema:      &Schema{Type: "string"},
+		},
+	}
+}
+
+func refs(names ...string) []Parameter {
+	params := make([]Parameter, len(names))
+	for i, name := range names {
+		params[i] = Parameter{Ref: "#/components/parameters/" + name}
+	}
+	return params
+}
+
+func query(name string, description string, schema *Schema) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      schema,
+	}
+}
+
+func etagHeader() map[string]Header {
+	return map[string]Header{
+		etag.HeaderETag: {
+			Description: "The id and version, send it back as If-None-Match or If-Match",
+			Schema:      &Schema{Type: "string"},
+		},
+	}
+}
+
+func export(description string) Response {
+	return Response{
+		Description: description,
+		Content:     exportContent(),
+	}
+}
+
+func exportContent() map[string]MediaType {
+	return map[string]MediaType{
+		dataio.ContentType(dataio.FormatCSV):    {Schema: &Schema{Type: "string"}},
+		dataio.ContentType(dataio.FormatNDJSON): {Schema: &Schema{Type: "string"}},
+	}
+}
+
+func withHeader(headers map[string]Header, name string, h Header) map[string]Header {
+	copied := make(map[string]Header, len(headers)+1)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	copied[name] = h
+	return copied
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+	assert.Equal(t, "/api/orgs/{id}/memberships/{userID}", Path("/api/orgs/:id/memberships/:userID"))
+	assert.Equal(t, "/api/users:method", Path("/api/users:method"))
+	assert.Equal(t, "/health", Path("/health"))
+}
+
+// refsIn is every $ref in v, which is the document as plain json.
+func refsIn(v any) (refs []string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if ref, ok := child.(string); ok && k == "$ref" {
+				refs = append(refs, ref)
+			} else {
+				refs = append(refs, refsIn(child)...)
+			}
+		}
+	case []any:
+		for _, child := range t {
+			refs = append(refs, refsIn(child)...)
+		}
+	}
+	return refs
+}
+
+func TestSpec_RefsResolve(t *testing.T) {
+	b, err := json.Marshal(Spec())
+	assert.Nil(t, err)
+	var doc map[string]any
+	assert.Nil(t, json.Unmarshal(b, &doc))
+
+	refs := refsIn(doc)
+
+	assert.NotEmpty(t, refs)
+	components := doc["components"].(map[string]any)
+	for _, ref := range refs {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		assert.Len(t, parts, 2, ref)
+		kind, _ := components[parts[0]].(map[string]any)
+		assert.Contains(t, kind, parts[1], ref)
+	}
+}
+
+func TestSpec_OperationIDsUnique(t *testing.T) {
+	seen := map[string]string{}
+	for path, item := range Spec().Paths {
+		for method, op := range item {
+			route := method + " " + path
+			assert.NotEmpty(t, op.OperationID, route)
+			assert.NotContains(t, seen, op.OperationID, route)
+			seen[op.OperationID] = route
+		}
+	}
+}
+
+func TestSpec_PathParams(t *testing.T) {
+	params := Spec().Components.Parameters
+	for path, item := range Spec().Paths {
+		for method, op := range item {
+			var inPath []string
+			for _, p := range op.Parameters {
+				if p.Ref != "" {
+					p = params[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+				}
+				if p.In == "path" {
+					inPath = append(inPath, p.Name)
+				}
+			}
+			var expected []string
+			for _, segment := range strings.Split(path, "/") {
+				if strings.HasPrefix(segment, "{") {
+					expected = append(expected, strings.Trim(segment, "{}"))
+				}
+			}
+			assert.Equal(t, expected, inPath, method+" "+path)
+		}
+	}
+}
+
+func TestSpec_Security(t *testing.T) {
+	doc := Spec()
+
+	assert.Nil(t, doc.Paths["/api/orgs/{id}"]["get"].Security)
+	assert.Contains(t, doc.Paths["/api/orgs/{id}"]["get"].Responses, "401")
+	assert.Equal(t, &[]SecurityRequirement{}, doc.Paths["/api/invitations/accept"]["post"].Security)
+	assert.NotContains(t, doc.Paths["/api/invitations/accept"]["post"].Responses, "401")
+	assert.Equal(t, &[]SecurityRequirement{}, doc.Paths["/health"]["get"].Security)
+}
+
+func TestSpec_Idempotency(t *testing.T) {
+	doc := Spec()
+
+	post := doc.Paths["/api/orgs"]["post"]
+	assert.Contains(t, post.Parameters, Parameter{Ref: "#/components/parameters/IdempotencyKey"})
+	assert.Contains(t, post.Responses, "422")
+	assert.Contains(t, post.Responses, "413")
+	assert.Contains(t, post.Responses["200"].Headers, "Idempotent-Replayed")
+	assert.Contains(t, post.Responses["200"].Headers, "ETag")
+	get := doc.Paths["/api/orgs"]["get"]
+	assert.NotContains(t, get.Parameters, Parameter{Ref: "#/components/parameters/IdempotencyKey"})
+	assert.NotContains(t, get.Responses, "422")
+	imp := doc.Paths["/api/users/import"]["post"]
+	assert.NotContains(t, imp.Parameters, Parameter{Ref: "#/components/parameters/IdempotencyKey"})
+	assert.Contains(t, imp.Responses, "401")
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/openapi.json", Handler(Spec()))
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, 200, w.Code)
+	var doc Document
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/api/users/{id}")
+}
+
+func TestDocsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/docs", DocsHandler("/openapi.json", "/docs"))
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+	assert.Contains(t, w.Body.String(), `src="/docs/swagger-ui-bundle.js?v=`+swaggerUI+`"`)
+	assert.NotContains(t, w.Body.String(), "https://")
+}
+
+func TestDocsAssets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/docs/:file", DocsAssets())
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/swagger-ui.css", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+	assert.Contains(t, w.Body.String(), ".swagger-ui")
+}
+
+func TestDocsAssets_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/docs/:file", DocsAssets())
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/index.html", nil))
+
+	assert.Equal(t, 404, w.Code)
+}
@@ -3,21 +3,39 @@ package org
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/internal/etag"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
+	"github.com/RyanBard/go-service-ex/internal/validate"
 	"github.com/RyanBard/go-service-ex/pkg/org"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 )
 
 type OrgService interface {
-	GetByID(ctx context.Context, id string) (org.Org, error)
-	GetAll(ctx context.Context, name string) ([]org.Org, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error)
+	GetAll(ctx context.Context, name string, includeDeleted bool, pr page.Request) (org.OrgPage, error)
 	Save(ctx context.Context, o org.Org) (org.Org, error)
-	Delete(ctx context.Context, o org.DeleteOrg) error
+	Patch(ctx context.Context, id string, version int64, changes patch.Patch) (org.Org, error)
+	Create(ctx context.Context, joinTX *sqlx.Tx, o org.Org) (org.Org, error)
+	Delete(ctx context.Context, o org.DeleteOrg, opts org.DeleteOrgOptions) error
+	Restore(ctx context.Context, o org.RestoreOrg) (org.Org, error)
+	Export(ctx context.Context) iter.Seq2[org.Org, error]
 }
 
+// exportFlushEvery is how many rows are written before they're flushed to the
+// client.
+const exportFlushEvery = 100
+
 type ctrl struct {
 	log     *slog.Logger
 	service OrgService
@@ -40,18 +58,22 @@ func (ctr ctrl) GetByID(c *gin.Context) {
 		logAttrOrgID(id),
 	)
 	log.Debug("called")
-	o, err := ctr.service.GetByID(ctx, id)
+	includeDeleted, err := parseIncludeDeleted(c)
 	if err != nil {
-		var statusCode int
-		var notFound ErrNotFound
-		if errors.As(err, &notFound) {
-			log.With(logutil.LogAttrError(err)).Warn("resource not found")
-			statusCode = http.StatusNotFound
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
-		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	o, err := ctr.service.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	tag := etag.New(o.ID, o.Version)
+	c.Header(etag.HeaderETag, tag)
+	if etag.NoneMatch(c.GetHeader(etag.HeaderIfNoneMatch), tag) {
+		log.Debug("not modified")
+		c.Status(http.StatusNotModified)
 		return
 	}
 	log.With(logAttrOrg(o)).Debug("success")
@@ -68,14 +90,25 @@ func (ctr ctrl) GetAll(c *gin.Context) {
 		logAttrOrgName(name),
 	)
 	log.Debug("called")
-	o, err := ctr.service.GetAll(ctx, name)
+	pr, err := page.ParseRequest(c.Query("limit"), c.Query("cursor"))
 	if err != nil {
-		log.With(logutil.LogAttrError(err)).Error("service call failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
-	log.With(logAttrOrgsLen(len(o))).Debug("success")
-	c.JSON(http.StatusOK, o)
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	op, err := ctr.service.GetAll(ctx, name, includeDeleted, pr)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrOrgsLen(len(op.Orgs))).Debug("success")
+	c.JSON(http.StatusOK, op)
 }
 
 func (ctr ctrl) Save(c *gin.Context) {
@@ -88,44 +121,87 @@ func (ctr ctrl) Save(c *gin.Context) {
 		logAttrPathID(pathID),
 	)
 	log.Debug("called")
-	var o org.Org
-	if err := c.ShouldBindJSON(&o); err != nil {
+	// an update's rules are checked by the service, only against the fields
+	// it changes
+	var body struct {
+		org.Org `binding:"-"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
 		log.With(logutil.LogAttrError(err)).Warn("invalid request")
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
+	o := body.Org
 	if pathID != "" {
 		o.ID = pathID
 	}
+	if o.ID == "" {
+		if err := validate.Struct(o, ""); err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("invalid request")
+			apierr.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	// If-Match takes the place of the version in the body
+	version, ifMatch, err := etag.ParseIfMatch(c.GetHeader(etag.HeaderIfMatch), o.ID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("precondition failed")
+		apierr.Respond(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	if ifMatch {
+		o.Version = version
+	}
 	log = log.With(logAttrOrg(o))
 	log.Debug("body processed, about to call service")
-	o, err := ctr.service.Save(ctx, o)
+	o, err = ctr.service.Save(ctx, o)
 	if err != nil {
-		var statusCode int
-		var notFound ErrNotFound
-		var modSysOrg ErrCannotModifySysOrg
-		var optLock ErrOptimisticLock
-		var dupName ErrNameAlreadyInUse
-		if errors.As(err, &notFound) {
-			log.With(logutil.LogAttrError(err)).Warn("resource not found")
-			statusCode = http.StatusNotFound
-		} else if errors.As(err, &modSysOrg) {
-			log.With(logutil.LogAttrError(err)).Warn("cannot modify system org")
-			statusCode = http.StatusForbidden
-		} else if errors.As(err, &optLock) {
-			log.With(logutil.LogAttrError(err)).Warn("optimistic lock error")
-			statusCode = http.StatusConflict
-		} else if errors.As(err, &dupName) {
-			log.With(logutil.LogAttrError(err)).Warn("duplicate name error")
-			statusCode = http.StatusConflict
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
-		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
+		apierr.FromErrorWithStatus(c, log, ifMatchStatus(ifMatch, err, apierr.Status(err)), err)
 		return
 	}
 	log.Debug("success")
+	c.Header(etag.HeaderETag, etag.New(o.ID, o.Version))
+	c.JSON(http.StatusOK, o)
+}
+
+// Patch takes a merge patch or a json patch (picked by the Content-Type),
+// If-Match is the only way to check the version besides the patch itself.
+func (ctr ctrl) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	pathID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Patch"),
+		logAttrPathID(pathID),
+	)
+	log.Debug("called")
+	body, err := c.GetRawData()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	changes, err := patch.Parse(c.ContentType(), body)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	version, ifMatch, err := etag.ParseIfMatch(c.GetHeader(etag.HeaderIfMatch), pathID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("precondition failed")
+		apierr.Respond(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	log = log.With(logAttrContentType(changes.ContentType), logAttrVersion(version))
+	log.Debug("body processed, about to call service")
+	o, err := ctr.service.Patch(ctx, pathID, version, changes)
+	if err != nil {
+		apierr.FromErrorWithStatus(c, log, ifMatchStatus(ifMatch, err, apierr.Status(err)), err)
+		return
+	}
+	log.Debug("success")
+	c.Header(etag.HeaderETag, etag.New(o.ID, o.Version))
 	c.JSON(http.StatusOK, o)
 }
 
@@ -139,10 +215,58 @@ func (ctr ctrl) Delete(c *gin.Context) {
 		logAttrPathID(pathID),
 	)
 	log.Debug("called")
-	var o org.DeleteOrg
+	version, ifMatch, err := etag.ParseIfMatch(c.GetHeader(etag.HeaderIfMatch), pathID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("precondition failed")
+		apierr.Respond(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	// with If-Match there's nothing left for the body to say
+	o := org.DeleteOrg{ID: pathID, Version: version}
+	if !ifMatch {
+		if err := c.ShouldBindJSON(&o); err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("invalid request")
+			apierr.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		if pathID != "" {
+			o.ID = pathID
+		}
+	}
+	opts := org.DeleteOrgOptions{
+		Mode:       c.Query("mode"),
+		ReassignTo: c.Query("reassign_to"),
+	}
+	log = log.With(logAttrOrg(o), logAttrOpts(opts))
+	log.Debug("body processed, about to call service")
+	if err := ctr.service.Delete(ctx, o, opts); err != nil {
+		statusCode := ifMatchStatus(ifMatch, err, deleteErrStatus(err))
+		if statusCode == http.StatusNoContent {
+			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not deleting")
+			c.Status(statusCode)
+			return
+		}
+		apierr.FromErrorWithStatus(c, log, statusCode, err)
+		return
+	}
+	log.Debug("Success")
+	c.Status(http.StatusNoContent)
+}
+
+func (ctr ctrl) Restore(c *gin.Context) {
+	ctx := c.Request.Context()
+	pathID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Restore"),
+		logAttrPathID(pathID),
+	)
+	log.Debug("called")
+	var o org.RestoreOrg
 	if err := c.ShouldBindJSON(&o); err != nil {
 		log.With(logutil.LogAttrError(err)).Warn("invalid request")
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 	if pathID != "" {
@@ -150,28 +274,107 @@ func (ctr ctrl) Delete(c *gin.Context) {
 	}
 	log = log.With(logAttrOrg(o))
 	log.Debug("body processed, about to call service")
-	if err := ctr.service.Delete(ctx, o); err != nil {
-		var statusCode int
-		var notFound ErrNotFound
-		var modSysOrg ErrCannotModifySysOrg
-		var optLock ErrOptimisticLock
-		if errors.As(err, &notFound) {
-			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not deleting")
-			c.Status(http.StatusNoContent)
+	restored, err := ctr.service.Restore(ctx, o)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.Header(etag.HeaderETag, etag.New(restored.ID, restored.Version))
+	c.JSON(http.StatusOK, restored)
+}
+
+// ifMatchStatus is 412 for an optimistic lock error when the version came
+// from If-Match, a stale version in the body is still a 409.
+func ifMatchStatus(ifMatch bool, err error, statusCode int) int {
+	if ifMatch && errors.As(err, &ErrOptimisticLock{}) {
+		return http.StatusPreconditionFailed
+	}
+	return statusCode
+}
+
+// deleteErrStatus is the status a failed delete responds with, deleting an org
+// that's already gone is a 204.
+func deleteErrStatus(err error) int {
+	if errors.As(err, &ErrNotFound{}) {
+		return http.StatusNoContent
+	}
+	return apierr.Status(err)
+}
+
+// parseIncludeDeleted treats a missing include_deleted as false.
+func parseIncludeDeleted(c *gin.Context) (bool, error) {
+	v := c.Query("include_deleted")
+	if v == "" {
+		return false, nil
+	}
+	includeDeleted, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, ErrInvalidIncludeDeleted{Value: v}
+	}
+	return includeDeleted, nil
+}
+
+// Export streams every org the logged in user can see. Errors before the
+// first row are responded with as usual, after that the status is already
+// sent so the connection is dropped (see dataio.Abort), a cut short export
+// can't be mistaken for a whole one.
+func (ctr ctrl) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Export"),
+	)
+	log.Debug("called")
+	format, err := dataio.ParseFormat(c.Query("format"))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	enc := dataio.NewEncoder(c.Writer, format, csvHeader, toCSV)
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", dataio.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="orgs.%s"`, format))
+		c.Status(http.StatusOK)
+		if err := enc.WriteHeader(); err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("failed to write header")
+		}
+	}
+	numRows := 0
+	for o, err := range ctr.service.Export(ctx) {
+		if err != nil && !started {
+			apierr.FromError(c, log, err)
 			return
-		} else if errors.As(err, &modSysOrg) {
-			log.With(logutil.LogAttrError(err)).Warn("cannot modify system org")
-			statusCode = http.StatusForbidden
-		} else if errors.As(err, &optLock) {
-			log.With(logutil.LogAttrError(err)).Warn("optimistic lock error")
-			statusCode = http.StatusConflict
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
 		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
-		return
+		if err != nil {
+			log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Error("export failed part way through")
+			enc.Flush()
+			dataio.Abort(c.Writer)
+		}
+		if !started {
+			start()
+		}
+		if err := enc.Encode(o); err != nil {
+			// the client went away
+			log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Warn("failed to write row")
+			return
+		}
+		numRows++
+		if numRows%exportFlushEvery == 0 {
+			enc.Flush()
+			if err := dataio.FlushResponse(c.Writer); err != nil {
+				log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Warn("failed to flush")
+				return
+			}
+		}
 	}
-	log.Debug("Success")
-	c.Status(http.StatusNoContent)
+	if !started {
+		start()
+	}
+	enc.Flush()
+	log.With(logAttrRows(numRows)).Debug("success")
 }
@@ -5,15 +5,23 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/validate"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	pkgpatch "github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -112,7 +120,7 @@ func TestCTRLGetByID(t *testing.T) {
 		CreatedAt: time.UnixMilli(100),
 		UpdatedAt: time.UnixMilli(200),
 	}
-	ms.On("GetByID", mock.Anything, id).Return(mockRes, nil)
+	ms.On("GetByID", mock.Anything, id, false).Return(mockRes, nil)
 
 	c.GetByID(gc)
 	res := w.Result()
@@ -141,18 +149,18 @@ func TestCTRLGetByID_NotFoundError(t *testing.T) {
 	}
 
 	mockErr := ErrNotFound{ID: id}
-	ms.On("GetByID", mock.Anything, id).Return(org.Org{}, mockErr)
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{}, mockErr)
 
 	c.GetByID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 404, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLGetByID_ServiceError(t *testing.T) {
@@ -170,18 +178,18 @@ func TestCTRLGetByID_ServiceError(t *testing.T) {
 	}
 
 	mockErr := errors.New("unit-test mock service error")
-	ms.On("GetByID", mock.Anything, id).Return(org.Org{}, mockErr)
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{}, mockErr)
 
 	c.GetByID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLGetAll(t *testing.T) {
@@ -189,22 +197,25 @@ func TestCTRLGetAll(t *testing.T) {
 	gc, w, err := ginCtx("/")
 	assert.Nil(t, err)
 
-	mockRes := []org.Org{
-		{
-			ID:        "foo-id",
-			Name:      "foo-name",
-			Desc:      "foo-desc",
-			CreatedAt: time.UnixMilli(100),
-			UpdatedAt: time.UnixMilli(200),
+	mockRes := org.OrgPage{
+		Orgs: []org.Org{
+			{
+				ID:        "foo-id",
+				Name:      "foo-name",
+				Desc:      "foo-desc",
+				CreatedAt: time.UnixMilli(100),
+				UpdatedAt: time.UnixMilli(200),
+			},
 		},
+		NextCursor: "foo-cursor",
 	}
-	ms.On("GetAll", mock.Anything, "").Return(mockRes, nil)
+	ms.On("GetAll", mock.Anything, "", false, page.Request{Limit: page.DefaultLimit}).Return(mockRes, nil)
 
 	c.GetAll(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual []org.Org
+	var actual org.OrgPage
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
@@ -226,22 +237,51 @@ func TestCTRLGetAll_NameSpecified(t *testing.T) {
 		},
 	}
 
-	mockRes := []org.Org{
-		{
-			ID:        "foo-id",
-			Name:      name,
-			Desc:      "foo-desc",
-			CreatedAt: time.UnixMilli(100),
-			UpdatedAt: time.UnixMilli(200),
+	mockRes := org.OrgPage{
+		Orgs: []org.Org{
+			{
+				ID:        "foo-id",
+				Name:      name,
+				Desc:      "foo-desc",
+				CreatedAt: time.UnixMilli(100),
+				UpdatedAt: time.UnixMilli(200),
+			},
 		},
 	}
-	ms.On("GetAll", mock.Anything, name).Return(mockRes, nil)
+	ms.On("GetAll", mock.Anything, name, false, page.Request{Limit: page.DefaultLimit}).Return(mockRes, nil)
+
+	c.GetAll(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual org.OrgPage
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLGetAll_PageParams(t *testing.T) {
+	after := page.Cursor{
+		Key:       "foo-name",
+		CreatedAt: time.UnixMilli(100).UTC(),
+		ID:        "foo-id",
+	}
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?limit=10&cursor=" + page.EncodeCursor(after))
+	assert.Nil(t, err)
+
+	mockRes := org.OrgPage{
+		Orgs: []org.Org{},
+	}
+	ms.On("GetAll", mock.Anything, "", false, page.Request{Limit: 10, After: &after}).Return(mockRes, nil)
 
 	c.GetAll(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual []org.Org
+	var actual org.OrgPage
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
@@ -249,24 +289,58 @@ func TestCTRLGetAll_NameSpecified(t *testing.T) {
 	assert.Equal(t, mockRes, actual)
 }
 
+func TestCTRLGetAll_InvalidLimit(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtx("/?limit=foo")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, actual.Detail, "limit")
+}
+
+func TestCTRLGetAll_InvalidCursor(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtx("/?cursor=foo")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, actual.Detail, "cursor")
+}
+
 func TestCTRLGetAll_ServiceError(t *testing.T) {
 	c, ms := initCTRL()
 	gc, w, err := ginCtx("/")
 	assert.Nil(t, err)
 
 	mockErr := errors.New("unit-test mock service error")
-	ms.On("GetAll", mock.Anything, "").Return([]org.Org{}, mockErr)
+	ms.On("GetAll", mock.Anything, "", false, page.Request{Limit: page.DefaultLimit}).Return(org.OrgPage{}, mockErr)
 
 	c.GetAll(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave(t *testing.T) {
@@ -355,12 +429,12 @@ func TestCTRLSave_ReadError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, mockIOErrMsg, actual["message"])
+	assert.Equal(t, mockIOErrMsg, actual.Detail)
 }
 
 func TestCTRLSave_UnmarshalError(t *testing.T) {
@@ -374,12 +448,12 @@ func TestCTRLSave_UnmarshalError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, "unexpected EOF", actual["message"])
+	assert.Equal(t, "unexpected EOF", actual.Detail)
 }
 
 func TestCTRLSave_ValidationError_MissingName(t *testing.T) {
@@ -396,14 +470,15 @@ func TestCTRLSave_ValidationError_MissingName(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Name")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "name", Rule: "required", Message: "name is required"},
+	}, actual.Violations)
 }
 
 func TestCTRLSave_ValidationError_MissingDesc(t *testing.T) {
@@ -420,14 +495,15 @@ func TestCTRLSave_ValidationError_MissingDesc(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Desc")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "desc", Rule: "required", Message: "desc is required"},
+	}, actual.Violations)
 }
 
 func TestCTRLSave_ValidationError_MissingNameAndDesc(t *testing.T) {
@@ -444,15 +520,72 @@ func TestCTRLSave_ValidationError_MissingNameAndDesc(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "name", Rule: "required", Message: "name is required"},
+		{Field: "desc", Rule: "required", Message: "desc is required"},
+	}, actual.Violations)
+}
+
+func TestCTRLSave_ValidationError_NameTooLong(t *testing.T) {
+	o := org.Org{
+		Name: strings.Repeat("a", 101),
+		Desc: "foo-desc",
+	}
+
+	c, _ := initCTRL()
+	gc, w, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	c.Save(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "name", Rule: "max", Message: "name must be at most 100 characters"},
+	}, actual.Violations)
+}
+
+func TestCTRLSave_ID_ValidatedByService(t *testing.T) {
+	// an update's unchanged fields don't have to follow the rules, so only the
+	// service can tell
+	o := org.Org{
+		ID:   "body-foo-id",
+		Name: "foo-name",
+		Desc: strings.Repeat("a", 1001),
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	mockErr := validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "desc", Rule: "max", Message: "desc must be at most 1000 characters"},
+	}}
+	ms.On("Save", mock.Anything, o).Return(org.Org{}, mockErr)
+
+	c.Save(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Name")
-	assert.Contains(t, errMsg, "Desc")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, mockErr.Violations, actual.Violations)
 }
 
 func TestCTRLSave_NotFoundError(t *testing.T) {
@@ -473,12 +606,12 @@ func TestCTRLSave_NotFoundError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 404, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_CannotModifySysOrgError(t *testing.T) {
@@ -499,12 +632,12 @@ func TestCTRLSave_CannotModifySysOrgError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 403, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_OptimisticLockError(t *testing.T) {
@@ -525,12 +658,12 @@ func TestCTRLSave_OptimisticLockError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 409, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_NameAlreadyInUseError(t *testing.T) {
@@ -551,12 +684,12 @@ func TestCTRLSave_NameAlreadyInUseError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 409, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_ServiceError(t *testing.T) {
@@ -577,12 +710,12 @@ func TestCTRLSave_ServiceError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLDelete(t *testing.T) {
@@ -602,7 +735,7 @@ func TestCTRLDelete(t *testing.T) {
 		},
 	}
 
-	ms.On("Delete", mock.Anything, o).Return(nil)
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(nil)
 
 	c.Delete(gc)
 	assert.Equal(t, 204, gc.Writer.Status())
@@ -624,12 +757,12 @@ func TestCTRLDelete_ReadError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, mockIOErrMsg, actual["message"])
+	assert.Equal(t, mockIOErrMsg, actual.Detail)
 }
 
 func TestCTRLDelete_UnmarshalError(t *testing.T) {
@@ -650,12 +783,12 @@ func TestCTRLDelete_UnmarshalError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, "unexpected EOF", actual["message"])
+	assert.Equal(t, "unexpected EOF", actual.Detail)
 }
 
 func TestCTRLDelete_ValidationError_MissingVersion(t *testing.T) {
@@ -678,14 +811,15 @@ func TestCTRLDelete_ValidationError_MissingVersion(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Version")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "version", Rule: "required", Message: "version is required"},
+	}, actual.Violations)
 }
 
 func TestCTRLDelete_NotFoundErr(t *testing.T) {
@@ -706,7 +840,7 @@ func TestCTRLDelete_NotFoundErr(t *testing.T) {
 	}
 
 	mockErr := ErrNotFound{ID: o.ID}
-	ms.On("Delete", mock.Anything, o).Return(mockErr)
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(mockErr)
 
 	c.Delete(gc)
 	assert.Equal(t, 204, gc.Writer.Status())
@@ -730,7 +864,7 @@ func TestCTRLDelete_CannotModifySysOrgErr(t *testing.T) {
 	}
 
 	mockErr := ErrCannotModifySysOrg{ID: o.ID}
-	ms.On("Delete", mock.Anything, o).Return(mockErr)
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(mockErr)
 
 	c.Delete(gc)
 	assert.Equal(t, 403, gc.Writer.Status())
@@ -754,7 +888,7 @@ func TestCTRLDelete_OptimisticLockError(t *testing.T) {
 	}
 
 	mockErr := ErrOptimisticLock{ID: o.ID, Version: o.Version}
-	ms.On("Delete", mock.Anything, o).Return(mockErr)
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(mockErr)
 
 	c.Delete(gc)
 	assert.Equal(t, 409, gc.Writer.Status())
@@ -778,28 +912,883 @@ func TestCTRLDelete_ServiceError(t *testing.T) {
 	}
 
 	mockErr := errors.New("unit-test mock service error")
-	ms.On("Delete", mock.Anything, o).Return(mockErr)
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(mockErr)
 
 	c.Delete(gc)
 	assert.Equal(t, 500, gc.Writer.Status())
 }
 
-func (m *mockSVC) GetByID(ctx context.Context, id string) (org.Org, error) {
-	args := m.Called(ctx, id)
-	return args.Get(0).(org.Org), args.Error(1)
+func TestCTRLGetByID_ForbiddenError(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "org:read"}
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{}, mockErr)
+
+	c.GetByID(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
-func (m *mockSVC) GetAll(ctx context.Context, name string) ([]org.Org, error) {
-	args := m.Called(ctx, name)
-	return args.Get(0).([]org.Org), args.Error(1)
+func TestCTRLGetByID_UnauthenticatedError(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{}, authz.ErrUnauthenticated{})
+
+	c.GetByID(gc)
+	assert.Equal(t, 401, gc.Writer.Status())
 }
 
-func (m *mockSVC) Save(ctx context.Context, o org.Org) (org.Org, error) {
-	args := m.Called(ctx, o)
-	return args.Get(0).(org.Org), args.Error(1)
+func TestCTRLGetAll_ForbiddenError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "org:list"}
+	ms.On("GetAll", mock.Anything, "", false, page.Request{Limit: page.DefaultLimit}).Return(org.OrgPage{}, mockErr)
+
+	c.GetAll(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
 }
 
-func (m *mockSVC) Delete(ctx context.Context, o org.DeleteOrg) error {
-	args := m.Called(ctx, o)
-	return args.Error(0)
+func TestCTRLSave_ForbiddenError(t *testing.T) {
+	o := org.Org{
+		ID:   "body-foo-id",
+		Name: "foo-name",
+		Desc: "foo-desc",
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "org:update"}
+	ms.On("Save", mock.Anything, o).Return(org.Org{}, mockErr)
+
+	c.Save(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLDelete_ForbiddenError(t *testing.T) {
+	o := org.DeleteOrg{
+		ID:      "body-foo-id",
+		Version: 1,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: o.ID,
+		},
+	}
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "org:delete"}
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(mockErr)
+
+	c.Delete(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLDelete_Cascade(t *testing.T) {
+	o := org.DeleteOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/?mode=cascade", o)
+	assert.Nil(t, err)
+
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{Mode: org.DeleteModeCascade}).Return(nil)
+
+	c.Delete(gc)
+	assert.Equal(t, 204, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLDelete_ReassignTo(t *testing.T) {
+	o := org.DeleteOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/?reassign_to=bar-id", o)
+	assert.Nil(t, err)
+
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{ReassignTo: "bar-id"}).Return(nil)
+
+	c.Delete(gc)
+	assert.Equal(t, 204, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLDelete_HasUsersError(t *testing.T) {
+	o := org.DeleteOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{}).Return(ErrOrgHasUsers{ID: o.ID, NumUsers: 3})
+
+	c.Delete(gc)
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	var actual problem.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, problem.CodeOrgHasUsers, actual.Code)
+	assert.Equal(t, float64(3), actual.Extensions["num_users"])
+	assert.Contains(t, actual.Detail, "still has users")
+}
+
+func TestCTRLDelete_InvalidModeError(t *testing.T) {
+	o := org.DeleteOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/?mode=everything", o)
+	assert.Nil(t, err)
+
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{Mode: "everything"}).Return(ErrInvalidDeleteMode{Mode: "everything"})
+
+	c.Delete(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLDelete_InvalidReassignTargetError(t *testing.T) {
+	o := org.DeleteOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/?reassign_to=bar-id", o)
+	assert.Nil(t, err)
+
+	ms.On("Delete", mock.Anything, o, org.DeleteOrgOptions{ReassignTo: "bar-id"}).Return(ErrInvalidReassignTarget{ID: "bar-id"})
+
+	c.Delete(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLGetByID_IncludeDeleted(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?include_deleted=true")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, true).Return(org.Org{ID: id}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLGetByID_InvalidIncludeDeleted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?include_deleted=maybe")
+	assert.Nil(t, err)
+
+	c.GetByID(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, w.Body.String(), "include_deleted")
+	ms.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLGetAll_IncludeDeleted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?include_deleted=true")
+	assert.Nil(t, err)
+
+	ms.On("GetAll", mock.Anything, "", true, page.Request{Limit: page.DefaultLimit}).Return(org.OrgPage{}, nil)
+
+	c.GetAll(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLGetAll_InvalidIncludeDeleted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?include_deleted=maybe")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLRestore(t *testing.T) {
+	o := org.RestoreOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", org.RestoreOrg{Version: o.Version})
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: o.ID,
+		},
+	}
+
+	mockRes := org.Org{ID: o.ID, Name: "foo-name", Version: 3}
+	ms.On("Restore", mock.Anything, o).Return(mockRes, nil)
+
+	c.Restore(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual org.Org
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLRestore_ValidationError_MissingVersion(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", org.RestoreOrg{ID: "foo-id"})
+	assert.Nil(t, err)
+
+	c.Restore(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
+
+func TestCTRLRestore_NotFoundErr(t *testing.T) {
+	o := org.RestoreOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, o).Return(org.Org{}, ErrNotFound{ID: o.ID})
+
+	c.Restore(gc)
+	assert.Equal(t, 404, gc.Writer.Status())
+}
+
+func TestCTRLRestore_OptimisticLockError(t *testing.T) {
+	o := org.RestoreOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, o).Return(org.Org{}, ErrOptimisticLock{ID: o.ID, Version: o.Version})
+
+	c.Restore(gc)
+	assert.Equal(t, 409, gc.Writer.Status())
+}
+
+func TestCTRLRestore_ForbiddenError(t *testing.T) {
+	o := org.RestoreOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "org:restore"}
+	ms.On("Restore", mock.Anything, o).Return(org.Org{}, mockErr)
+
+	c.Restore(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLRestore_ServiceError(t *testing.T) {
+	o := org.RestoreOrg{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, o).Return(org.Org{}, errors.New("unit-test mock error"))
+
+	c.Restore(gc)
+	assert.Equal(t, 500, gc.Writer.Status())
+}
+
+func TestCTRLGetByID_ETag(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{ID: id, Version: 3}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:3"`, w.Header().Get("ETag"))
+}
+
+func TestCTRLGetByID_NotModified(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-None-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{ID: id, Version: 3}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 304, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:3"`, w.Header().Get("ETag"))
+	assert.Equal(t, 0, w.Body.Len())
+}
+
+func TestCTRLGetByID_StaleIfNoneMatch(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-None-Match", `"foo-id:2"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, false).Return(org.Org{ID: id, Version: 3}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.NotEqual(t, 0, w.Body.Len())
+}
+
+func TestCTRLSave_IfMatch(t *testing.T) {
+	id := "foo-id"
+	o := org.Org{
+		Name:    "foo-name",
+		Desc:    "foo-desc",
+		Version: 1,
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", o)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	expectedOrg := org.Org{
+		ID:      id,
+		Name:    o.Name,
+		Desc:    o.Desc,
+		Version: 3,
+	}
+	mockRes := expectedOrg
+	mockRes.Version = 4
+	ms.On("Save", mock.Anything, expectedOrg).Return(mockRes, nil)
+
+	c.Save(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:4"`, w.Header().Get("ETag"))
+}
+
+func TestCTRLSave_IfMatchOtherID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", org.Org{Name: "foo-name", Desc: "foo-desc"})
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"bar-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	c.Save(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestCTRLSave_IfMatchOptimisticLockError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", org.Org{Name: "foo-name", Desc: "foo-desc"})
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	mockErr := ErrOptimisticLock{ID: "foo-id", Version: 3}
+	ms.On("Save", mock.Anything, mock.Anything).Return(org.Org{}, mockErr)
+
+	c.Save(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+}
+
+func patchCtx(contentType string, body string) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gc, w, err := ginCtxWithStrBody("/", &body)
+	if err != nil {
+		return gc, w, err
+	}
+	gc.Request.Method = "PATCH"
+	gc.Request.Header.Set("Content-Type", contentType)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+	return gc, w, nil
+}
+
+func parseProblem(t *testing.T, w *httptest.ResponseRecorder) problem.Problem {
+	var actual problem.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	return actual
+}
+
+func TestCTRLPatch(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx(pkgpatch.ContentTypeMerge, `{"desc":"bar-desc"}`)
+	assert.Nil(t, err)
+
+	expectedPatch := patch.Patch{ContentType: pkgpatch.ContentTypeMerge, Body: []byte(`{"desc":"bar-desc"}`)}
+	mockRes := org.Org{ID: "foo-id", Name: "foo-name", Desc: "bar-desc", Version: 4}
+	ms.On("Patch", mock.Anything, "foo-id", int64(0), expectedPatch).Return(mockRes, nil)
+
+	c.Patch(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:4"`, w.Header().Get("ETag"))
+	var actual org.Org
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLPatch_IfMatch(t *testing.T) {
+	c, ms := initCTRL()
+	body := `[{"op":"replace","path":"/name","value":"bar-name"}]`
+	gc, _, err := patchCtx(pkgpatch.ContentTypeJSON, body)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	expectedPatch := patch.Patch{ContentType: pkgpatch.ContentTypeJSON, Body: []byte(body)}
+	ms.On("Patch", mock.Anything, "foo-id", int64(3), expectedPatch).Return(org.Org{ID: "foo-id", Version: 4}, nil)
+
+	c.Patch(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+}
+
+func TestCTRLPatch_ReadError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithIOErr("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("Content-Type", pkgpatch.ContentTypeMerge)
+
+	c.Patch(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_UnsupportedError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx("application/json", `{"desc":"bar-desc"}`)
+	assert.Nil(t, err)
+
+	c.Patch(gc)
+	assert.Equal(t, 415, gc.Writer.Status())
+	assert.Equal(t, problem.CodeUnsupportedPatch, parseProblem(t, w).Code)
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_MalformedError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx(pkgpatch.ContentTypeJSON, `[{"op":"foo","path":"/name"}]`)
+	assert.Nil(t, err)
+
+	c.Patch(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeMalformedPatch, parseProblem(t, w).Code)
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_IfMatchOtherID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := patchCtx(pkgpatch.ContentTypeMerge, `{"desc":"bar-desc"}`)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"bar-id:3"`)
+
+	c.Patch(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// patchWithErr is a merge patch the service fails with mockErr.
+func patchWithErr(t *testing.T, mockErr error) (*gin.Context, problem.Problem) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx(pkgpatch.ContentTypeMerge, `{"desc":"bar-desc"}`)
+	assert.Nil(t, err)
+
+	ms.On("Patch", mock.Anything, "foo-id", int64(0), mock.Anything).Return(org.Org{}, mockErr)
+
+	c.Patch(gc)
+	return gc, parseProblem(t, w)
+}
+
+func TestCTRLPatch_NotFoundError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrNotFound{ID: "foo-id"})
+	assert.Equal(t, 404, gc.Writer.Status())
+	assert.Equal(t, problem.CodeOrgNotFound, actual.Code)
+}
+
+func TestCTRLPatch_CannotModifySysOrgError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrCannotModifySysOrg{ID: "foo-id"})
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, problem.CodeSysOrgReadOnly, actual.Code)
+}
+
+func TestCTRLPatch_OptimisticLockError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrOptimisticLock{ID: "foo-id", Version: 2})
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, problem.CodeOrgVersionConflict, actual.Code)
+}
+
+func TestCTRLPatch_NameAlreadyInUseError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrNameAlreadyInUse{Name: "bar-name"})
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, problem.CodeOrgNameInUse, actual.Code)
+}
+
+func TestCTRLPatch_ValidationError(t *testing.T) {
+	gc, actual := patchWithErr(t, validate.ErrInvalid{Violations: []problem.Violation{validate.ReadOnly("id")}})
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "id", Rule: "readonly", Message: "id is read only"},
+	}, actual.Violations)
+}
+
+func TestCTRLPatch_MalformedPatchError(t *testing.T) {
+	gc, actual := patchWithErr(t, patch.ErrMalformed{Reason: "unit-test reason"})
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeMalformedPatch, actual.Code)
+}
+
+func TestCTRLPatch_TestFailedError(t *testing.T) {
+	gc, actual := patchWithErr(t, patch.ErrTestFailed{Path: "/version"})
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, problem.CodePatchTestFailed, actual.Code)
+}
+
+func TestCTRLPatch_ForbiddenError(t *testing.T) {
+	gc, actual := patchWithErr(t, authz.ErrForbidden{UserID: "foo-user-id", Action: "org:update"})
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, problem.CodeForbidden, actual.Code)
+}
+
+func TestCTRLPatch_ServiceError(t *testing.T) {
+	gc, actual := patchWithErr(t, errors.New("unit-test mock error"))
+	assert.Equal(t, 500, gc.Writer.Status())
+	assert.Equal(t, problem.CodeInternal, actual.Code)
+}
+
+func TestCTRLPatch_IfMatchOptimisticLockError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := patchCtx(pkgpatch.ContentTypeMerge, `{"desc":"bar-desc"}`)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	mockErr := ErrOptimisticLock{ID: "foo-id", Version: 3}
+	ms.On("Patch", mock.Anything, "foo-id", int64(3), mock.Anything).Return(org.Org{}, mockErr)
+
+	c.Patch(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+}
+
+func TestCTRLDelete_IfMatch(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	ms.On("Delete", mock.Anything, org.DeleteOrg{ID: "foo-id", Version: 3}, org.DeleteOrgOptions{}).Return(nil)
+
+	c.Delete(gc)
+	assert.Equal(t, 204, gc.Writer.Status())
+}
+
+func TestCTRLDelete_IfMatchOtherID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"bar-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	c.Delete(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLDelete_IfMatchOptimisticLockError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	mockErr := ErrOptimisticLock{ID: "foo-id", Version: 3}
+	ms.On("Delete", mock.Anything, org.DeleteOrg{ID: "foo-id", Version: 3}, org.DeleteOrgOptions{}).Return(mockErr)
+
+	c.Delete(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+}
+
+func (m *mockSVC) GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error) {
+	args := m.Called(ctx, id, includeDeleted)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (m *mockSVC) GetAll(ctx context.Context, name string, includeDeleted bool, pr page.Request) (org.OrgPage, error) {
+	args := m.Called(ctx, name, includeDeleted, pr)
+	return args.Get(0).(org.OrgPage), args.Error(1)
+}
+
+func (m *mockSVC) Save(ctx context.Context, o org.Org) (org.Org, error) {
+	args := m.Called(ctx, o)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (m *mockSVC) Patch(ctx context.Context, id string, version int64, changes patch.Patch) (org.Org, error) {
+	args := m.Called(ctx, id, version, changes)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (m *mockSVC) Create(ctx context.Context, joinTX *sqlx.Tx, o org.Org) (org.Org, error) {
+	args := m.Called(ctx, joinTX, o)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (m *mockSVC) Delete(ctx context.Context, o org.DeleteOrg, opts org.DeleteOrgOptions) error {
+	args := m.Called(ctx, o, opts)
+	return args.Error(0)
+}
+
+func (m *mockSVC) Export(ctx context.Context) iter.Seq2[org.Org, error] {
+	args := m.Called(ctx)
+	return args.Get(0).(iter.Seq2[org.Org, error])
+}
+
+func (m *mockSVC) Restore(ctx context.Context, o org.RestoreOrg) (org.Org, error) {
+	args := m.Called(ctx, o)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+// seqOf yields the orgs, then err if there is one.
+func seqOf(orgs []org.Org, err error) iter.Seq2[org.Org, error] {
+	return func(yield func(org.Org, error) bool) {
+		for _, o := range orgs {
+			if !yield(o, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(org.Org{}, err)
+		}
+	}
+}
+
+func TestCTRLExport_CSV(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	orgs := []org.Org{
+		{ID: "foo-id", Name: "foo-name", Desc: "foo \"desc\"", CreatedAt: time.UnixMilli(100).UTC(), UpdatedAt: time.UnixMilli(200).UTC(), Version: 1},
+	}
+	ms.On("Export", mock.Anything).Return(seqOf(orgs, nil))
+
+	c.Export(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="orgs.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,name,desc,is_system,created_at,created_by,updated_at,updated_by,version\n"+
+		"foo-id,foo-name,\"foo \"\"desc\"\"\",false,1970-01-01T00:00:00.1Z,,1970-01-01T00:00:00.2Z,,1\n", w.Body.String())
+}
+
+func TestCTRLExport_NDJSON(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?format=ndjson")
+	assert.Nil(t, err)
+
+	orgs := []org.Org{
+		{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", CreatedAt: time.UnixMilli(100).UTC(), UpdatedAt: time.UnixMilli(200).UTC(), Version: 1},
+		{ID: "bar-id", Name: "bar-name", Desc: "bar-desc", CreatedAt: time.UnixMilli(100).UTC(), UpdatedAt: time.UnixMilli(200).UTC(), Version: 1},
+	}
+	ms.On("Export", mock.Anything).Return(seqOf(orgs, nil))
+
+	c.Export(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	var actual org.Org
+	err = json.Unmarshal([]byte(lines[0]), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, orgs[0], actual)
+}
+
+func TestCTRLExport_InvalidFormat(t *testing.T) {
+	c, _ := initCTRL()
+	gc, _, err := ginCtx("/?format=xml")
+	assert.Nil(t, err)
+
+	c.Export(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLExport_UnauthenticatedError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(nil, authz.ErrUnauthenticated{}))
+
+	c.Export(gc)
+	assert.Equal(t, 401, gc.Writer.Status())
+}
+
+func TestCTRLExport_ServiceError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(nil, errors.New("unit-test mock error")))
+
+	c.Export(gc)
+	assert.Equal(t, 500, gc.Writer.Status())
+}
+
+func TestCTRLExport_ServiceErrorAfterFirstRow(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	orgs := []org.Org{
+		{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", CreatedAt: time.UnixMilli(100).UTC(), UpdatedAt: time.UnixMilli(200).UTC(), Version: 1},
+	}
+	ms.On("Export", mock.Anything).Return(seqOf(orgs, errors.New("unit-test mock error")))
+
+	// the status was sent with the first row, so the connection is dropped
+	// to tell the client it's cut short
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { c.Export(gc) })
+	assert.Equal(t, 200, gc.Writer.Status())
+	// the header and the row that made it
+	assert.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 2)
 }
@@ -0,0 +1,24 @@
+package org
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/pkg/org"
+)
+
+var csvHeader = []string{"id", "name", "desc", "is_system", "created_at", "created_by", "updated_at", "updated_by", "version"}
+
+func toCSV(o org.Org) []string {
+	return []string{
+		o.ID,
+		o.Name,
+		o.Desc,
+		strconv.FormatBool(o.IsSystem),
+		o.CreatedAt.Format(time.RFC3339Nano),
+		o.CreatedBy,
+		o.UpdatedAt.Format(time.RFC3339Nano),
+		o.UpdatedBy,
+		strconv.FormatInt(o.Version, 10),
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"time"
 
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
@@ -28,7 +31,9 @@ func NewDAO(log *slog.Logger, timeout time.Duration, db *sqlx.DB) *dao {
 	}
 }
 
-func (d dao) GetByID(ctx context.Context, id string) (o org.Org, err error) {
+func (d dao) GetByID(ctx context.Context, id string, includeDeleted bool) (o org.Org, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.GetByID")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -36,9 +41,11 @@ func (d dao) GetByID(ctx context.Context, id string) (o org.Org, err error) {
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetByID"),
 		logAttrOrgID(id),
+		logAttrIncludeDeleted(includeDeleted),
 	)
 	log.Debug("called")
-	err = d.db.GetContext(ctx, &o, getByIDQuery, id)
+	span.SetAttributes(tracing.AttrStatement("getByIDQuery"))
+	err = d.db.GetContext(ctx, &o, getByIDQuery, id, includeDeleted)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return o, ErrNotFound{ID: id}
@@ -49,16 +56,52 @@ func (d dao) GetByID(ctx context.Context, id string) (o org.Org, err error) {
 	return o, err
 }
 
-func (d dao) GetAll(ctx context.Context) (orgs []org.Org, err error) {
+func (d dao) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (o org.Org, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.GetByIDForUpdate")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByIDForUpdate"),
+		logAttrOrgID(id),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getByIDForUpdateQuery"))
+	err = tx.GetContext(ctx, &o, getByIDForUpdateQuery, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return o, ErrNotFound{ID: id}
+		}
+		return o, err
+	}
+	log.Debug("success")
+	return o, err
+}
+
+func (d dao) GetAll(ctx context.Context, includeDeleted bool, after *page.Cursor, limit int) (orgs []org.Org, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.GetAll")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetAll"),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(after),
+		logAttrLimit(limit),
 	)
 	log.Debug("called")
-	err = d.db.SelectContext(ctx, &orgs, getAllQuery)
+	orgs = []org.Org{}
+	if after == nil {
+		span.SetAttributes(tracing.AttrStatement("getAllQuery"))
+		err = d.db.SelectContext(ctx, &orgs, getAllQuery, limit, includeDeleted)
+	} else {
+		span.SetAttributes(tracing.AttrStatement("getAllAfterQuery"))
+		err = d.db.SelectContext(ctx, &orgs, getAllAfterQuery, after.Key, after.CreatedAt, after.ID, limit, includeDeleted)
+	}
 	if err != nil {
 		return orgs, err
 	}
@@ -66,7 +109,9 @@ func (d dao) GetAll(ctx context.Context) (orgs []org.Org, err error) {
 	return orgs, err
 }
 
-func (d dao) SearchByName(ctx context.Context, name string) (orgs []org.Org, err error) {
+func (d dao) SearchByName(ctx context.Context, name string, includeDeleted bool, after *page.Cursor, limit int) (orgs []org.Org, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.SearchByName")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -74,10 +119,19 @@ func (d dao) SearchByName(ctx context.Context, name string) (orgs []org.Org, err
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("SearchByName"),
 		logAttrOrgName(name),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(after),
+		logAttrLimit(limit),
 	)
 	log.Debug("called")
 	orgs = []org.Org{}
-	err = d.db.SelectContext(ctx, &orgs, searchByNameQuery, "%"+name+"%")
+	if after == nil {
+		span.SetAttributes(tracing.AttrStatement("searchByNameQuery"))
+		err = d.db.SelectContext(ctx, &orgs, searchByNameQuery, "%"+name+"%", limit, includeDeleted)
+	} else {
+		span.SetAttributes(tracing.AttrStatement("searchByNameAfterQuery"))
+		err = d.db.SelectContext(ctx, &orgs, searchByNameAfterQuery, "%"+name+"%", after.Key, after.CreatedAt, after.ID, limit, includeDeleted)
+	}
 	if err != nil {
 		return orgs, err
 	}
@@ -86,6 +140,8 @@ func (d dao) SearchByName(ctx context.Context, name string) (orgs []org.Org, err
 }
 
 func (d dao) Create(ctx context.Context, tx *sqlx.Tx, o org.Org) (err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.Create")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -95,6 +151,7 @@ func (d dao) Create(ctx context.Context, tx *sqlx.Tx, o org.Org) (err error) {
 		logAttrOrg(o),
 	)
 	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("createQuery"))
 	r, err := tx.NamedExecContext(ctx, createQuery, &o)
 	if err != nil {
 		var pqErr *pq.Error
@@ -118,6 +175,8 @@ func (d dao) Create(ctx context.Context, tx *sqlx.Tx, o org.Org) (err error) {
 }
 
 func (d dao) Update(ctx context.Context, tx *sqlx.Tx, input org.Org) (o org.Org, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.Update")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -127,6 +186,7 @@ func (d dao) Update(ctx context.Context, tx *sqlx.Tx, input org.Org) (o org.Org,
 		logAttrOrg(input),
 	)
 	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("updateQuery"))
 	r, err := tx.NamedExecContext(ctx, updateQuery, &input)
 	if err != nil {
 		var pqErr *pq.Error
@@ -153,7 +213,10 @@ func (d dao) Update(ctx context.Context, tx *sqlx.Tx, input org.Org) (o org.Org,
 	return input, err
 }
 
-func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg) (err error) {
+// Delete is a soft delete, o needs DeletedAt and DeletedBy set.
+func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, o org.Org) (err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.Delete")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -163,6 +226,7 @@ func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg) (err erro
 		logAttrOrg(o),
 	)
 	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("deleteQuery"))
 	r, err := tx.NamedExecContext(ctx, deleteQuery, &o)
 	if err != nil {
 		return err
@@ -181,3 +245,140 @@ func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg) (err erro
 	log.Debug("success")
 	return err
 }
+
+// Restore undoes Delete, o needs UpdatedAt and UpdatedBy set.
+func (d dao) Restore(ctx context.Context, tx *sqlx.Tx, input org.Org) (o org.Org, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.Restore")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Restore"),
+		logAttrOrg(input),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("restoreQuery"))
+	r, err := tx.NamedExecContext(ctx, restoreQuery, &input)
+	if err != nil {
+		return o, err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return o, err
+	}
+	if numRows == 0 {
+		return o, ErrOptimisticLock{ID: input.ID, Version: input.Version}
+	}
+	if numRows != 1 {
+		return o, fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	input.Version = input.Version + 1
+	input.DeletedAt = nil
+	input.DeletedBy = ""
+	return input, err
+}
+
+// GetUsersForUpdate returns the org's users and locks them until tx ends.
+func (d dao) GetUsersForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string) (users []user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.GetUsersForUpdate")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetUsersForUpdate"),
+		logAttrOrgID(orgID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getUsersForUpdateQuery"))
+	users = []user.User{}
+	err = tx.SelectContext(ctx, &users, getUsersForUpdateQuery, orgID)
+	if err != nil {
+		return users, err
+	}
+	log.With(logAttrUsersLen(len(users))).Debug("success")
+	return users, err
+}
+
+// DeleteUsers soft deletes every user of the org.
+func (d dao) DeleteUsers(ctx context.Context, tx *sqlx.Tx, orgID string, deletedAt time.Time, deletedBy string) (numRows int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.DeleteUsers")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("DeleteUsers"),
+		logAttrOrgID(orgID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("deleteUsersQuery"))
+	r, err := tx.ExecContext(ctx, deleteUsersQuery, orgID, deletedAt, deletedBy)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err = r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	log.With(logAttrNumRows(numRows)).Debug("success")
+	return numRows, err
+}
+
+// ReassignUsers moves every user of the org to toOrgID.
+func (d dao) ReassignUsers(ctx context.Context, tx *sqlx.Tx, orgID string, toOrgID string, updatedAt time.Time, updatedBy string) (numRows int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.ReassignUsers")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ReassignUsers"),
+		logAttrOrgID(orgID),
+		logAttrReassignTo(toOrgID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("reassignUsersQuery"))
+	r, err := tx.ExecContext(ctx, reassignUsersQuery, orgID, toOrgID, updatedAt, updatedBy)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err = r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	log.With(logAttrNumRows(numRows)).Debug("success")
+	return numRows, err
+}
+
+// Purge hard deletes the orgs that were soft deleted before the cutoff.
+func (d dao) Purge(ctx context.Context, before time.Time) (numRows int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "OrgDAO.Purge")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Purge"),
+		logAttrBefore(before),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("purgeQuery"))
+	r, err := d.db.ExecContext(ctx, purgeQuery, before)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err = r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	log.With(logAttrNumRows(numRows)).Debug("success")
+	return numRows, err
+}
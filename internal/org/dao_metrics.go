@@ -0,0 +1,133 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+)
+
+const daoName = "OrgDAO"
+
+type DAOMetrics interface {
+	ObserveQuery(dao string, method string, d time.Duration, errClass string)
+}
+
+// instrumentedDAO records the duration and error class of every OrgDAO call.
+type instrumentedDAO struct {
+	dao     OrgDAO
+	metrics DAOMetrics
+}
+
+func NewInstrumentedDAO(dao OrgDAO, metrics DAOMetrics) *instrumentedDAO {
+	return &instrumentedDAO{
+		dao:     dao,
+		metrics: metrics,
+	}
+}
+
+func (d instrumentedDAO) observe(method string, start time.Time, err error) {
+	d.metrics.ObserveQuery(daoName, method, time.Since(start), errClass(err))
+}
+
+func (d instrumentedDAO) GetByID(ctx context.Context, id string, includeDeleted bool) (o org.Org, err error) {
+	start := time.Now()
+	o, err = d.dao.GetByID(ctx, id, includeDeleted)
+	d.observe("GetByID", start, err)
+	return o, err
+}
+
+func (d instrumentedDAO) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (o org.Org, err error) {
+	start := time.Now()
+	o, err = d.dao.GetByIDForUpdate(ctx, tx, id)
+	d.observe("GetByIDForUpdate", start, err)
+	return o, err
+}
+
+func (d instrumentedDAO) GetAll(ctx context.Context, includeDeleted bool, after *page.Cursor, limit int) (orgs []org.Org, err error) {
+	start := time.Now()
+	orgs, err = d.dao.GetAll(ctx, includeDeleted, after, limit)
+	d.observe("GetAll", start, err)
+	return orgs, err
+}
+
+func (d instrumentedDAO) SearchByName(ctx context.Context, name string, includeDeleted bool, after *page.Cursor, limit int) (orgs []org.Org, err error) {
+	start := time.Now()
+	orgs, err = d.dao.SearchByName(ctx, name, includeDeleted, after, limit)
+	d.observe("SearchByName", start, err)
+	return orgs, err
+}
+
+func (d instrumentedDAO) Create(ctx context.Context, tx *sqlx.Tx, o org.Org) (err error) {
+	start := time.Now()
+	err = d.dao.Create(ctx, tx, o)
+	d.observe("Create", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Update(ctx context.Context, tx *sqlx.Tx, input org.Org) (o org.Org, err error) {
+	start := time.Now()
+	o, err = d.dao.Update(ctx, tx, input)
+	d.observe("Update", start, err)
+	return o, err
+}
+
+func (d instrumentedDAO) Delete(ctx context.Context, tx *sqlx.Tx, o org.Org) (err error) {
+	start := time.Now()
+	err = d.dao.Delete(ctx, tx, o)
+	d.observe("Delete", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Restore(ctx context.Context, tx *sqlx.Tx, input org.Org) (o org.Org, err error) {
+	start := time.Now()
+	o, err = d.dao.Restore(ctx, tx, input)
+	d.observe("Restore", start, err)
+	return o, err
+}
+
+func (d instrumentedDAO) GetUsersForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string) (users []user.User, err error) {
+	start := time.Now()
+	users, err = d.dao.GetUsersForUpdate(ctx, tx, orgID)
+	d.observe("GetUsersForUpdate", start, err)
+	return users, err
+}
+
+func (d instrumentedDAO) DeleteUsers(ctx context.Context, tx *sqlx.Tx, orgID string, deletedAt time.Time, deletedBy string) (numRows int64, err error) {
+	start := time.Now()
+	numRows, err = d.dao.DeleteUsers(ctx, tx, orgID, deletedAt, deletedBy)
+	d.observe("DeleteUsers", start, err)
+	return numRows, err
+}
+
+func (d instrumentedDAO) ReassignUsers(ctx context.Context, tx *sqlx.Tx, orgID string, toOrgID string, updatedAt time.Time, updatedBy string) (numRows int64, err error) {
+	start := time.Now()
+	numRows, err = d.dao.ReassignUsers(ctx, tx, orgID, toOrgID, updatedAt, updatedBy)
+	d.observe("ReassignUsers", start, err)
+	return numRows, err
+}
+
+func (d instrumentedDAO) Purge(ctx context.Context, before time.Time) (numRows int64, err error) {
+	start := time.Now()
+	numRows, err = d.dao.Purge(ctx, before)
+	d.observe("Purge", start, err)
+	return numRows, err
+}
+
+func errClass(err error) string {
+	switch {
+	case errors.As(err, &ErrNotFound{}):
+		return "not_found"
+	case errors.As(err, &ErrOptimisticLock{}):
+		return "optimistic_lock"
+	case errors.As(err, &ErrNameAlreadyInUse{}):
+		return "conflict"
+	default:
+		return metrics.ErrClass(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
 	"github.com/RyanBard/go-service-ex/pkg/org"
 	"github.com/jmoiron/sqlx"
@@ -22,6 +23,12 @@ var (
 	ctx       = context.Background()
 	createdAt = time.UnixMilli(100)
 	updatedAt = time.UnixMilli(200)
+	deletedAt = time.UnixMilli(300)
+	after     = page.Cursor{
+		Key:       "after-name",
+		CreatedAt: time.UnixMilli(50),
+		ID:        "after-id",
+	}
 )
 
 const (
@@ -30,6 +37,8 @@ const (
 	name        = "foo-name"
 	desc        = "foo-desc"
 	version     = int64(3)
+	limit       = 11
+	deletedBy   = "deleter-id"
 )
 
 func getRows() *sqlmock.Rows {
@@ -67,10 +76,10 @@ func TestDAOGetByID(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
-		WithArgs(id).
+		WithArgs(id, false).
 		WillReturnRows(getRows())
 
-	actual, err := d.GetByID(ctx, id)
+	actual, err := d.GetByID(ctx, id, false)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Nil(t, err)
@@ -86,10 +95,10 @@ func TestDAOGetByID_NotFoundErr(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
-		WithArgs(id).
+		WithArgs(id, false).
 		WillReturnError(sql.ErrNoRows)
 
-	_, err := d.GetByID(ctx, id)
+	_, err := d.GetByID(ctx, id, false)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	var expected ErrNotFound
@@ -103,22 +112,69 @@ func TestDAOGetByID_OtherErr(t *testing.T) {
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
-		WithArgs(id).
+		WithArgs(id, false).
 		WillReturnError(&mockErr)
 
-	_, err := d.GetByID(ctx, id)
+	_, err := d.GetByID(ctx, id, false)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Equal(t, &mockErr, err)
 }
 
+func TestDAOGetByID_IncludeDeleted(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id, true).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id",
+			"version",
+			"deleted_at",
+			"deleted_by",
+		}).AddRow(
+			id,
+			version,
+			deletedAt,
+			deletedBy,
+		))
+
+	actual, err := d.GetByID(ctx, id, true)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, &deletedAt, actual.DeletedAt)
+	assert.Equal(t, deletedBy, actual.DeletedBy)
+}
+
 func TestDAOGetAll(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(getAllQuery)).
+		WithArgs(limit, false).
+		WillReturnRows(getRows())
+
+	actuals, err := d.GetAll(ctx, false, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(actuals))
+	actual := actuals[0]
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, name, actual.Name)
+	assert.Equal(t, desc, actual.Desc)
+	assert.Equal(t, createdAt, actual.CreatedAt)
+	assert.Equal(t, updatedAt, actual.UpdatedAt)
+	assert.Equal(t, version, actual.Version)
+}
+
+func TestDAOGetAll_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getAllAfterQuery)).
+		WithArgs(after.Key, after.CreatedAt, after.ID, limit, false).
 		WillReturnRows(getRows())
 
-	actuals, err := d.GetAll(ctx)
+	actuals, err := d.GetAll(ctx, false, &after, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Nil(t, err)
@@ -137,9 +193,10 @@ func TestDAOGetAll_Error(t *testing.T) {
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectQuery(regexp.QuoteMeta(getAllQuery)).
+		WithArgs(limit, false).
 		WillReturnError(&mockErr)
 
-	_, err := d.GetAll(ctx)
+	_, err := d.GetAll(ctx, false, nil, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Equal(t, &mockErr, err)
@@ -149,10 +206,31 @@ func TestDAOSearchByName(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(searchByNameQuery)).
-		WithArgs("%" + partialName + "%").
+		WithArgs("%"+partialName+"%", limit, false).
 		WillReturnRows(getRows())
 
-	actuals, err := d.SearchByName(ctx, partialName)
+	actuals, err := d.SearchByName(ctx, partialName, false, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(actuals))
+	actual := actuals[0]
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, name, actual.Name)
+	assert.Equal(t, desc, actual.Desc)
+	assert.Equal(t, createdAt, actual.CreatedAt)
+	assert.Equal(t, updatedAt, actual.UpdatedAt)
+	assert.Equal(t, version, actual.Version)
+}
+
+func TestDAOSearchByName_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(searchByNameAfterQuery)).
+		WithArgs("%"+partialName+"%", after.Key, after.CreatedAt, after.ID, limit, false).
+		WillReturnRows(getRows())
+
+	actuals, err := d.SearchByName(ctx, partialName, false, &after, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Nil(t, err)
@@ -171,10 +249,10 @@ func TestDAOSearchByName_Error(t *testing.T) {
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectQuery(regexp.QuoteMeta(searchByNameQuery)).
-		WithArgs("%" + partialName + "%").
+		WithArgs("%"+partialName+"%", limit, false).
 		WillReturnError(&mockErr)
 
-	_, err := d.SearchByName(ctx, partialName)
+	_, err := d.SearchByName(ctx, partialName, false, nil, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Equal(t, &mockErr, err)
@@ -484,13 +562,16 @@ func TestDAOUpdate_TooManyRowsAffected(t *testing.T) {
 func TestDAODelete(t *testing.T) {
 	d, db, md := initDAO()
 
-	o := org.DeleteOrg{
-		ID:      id,
-		Version: version,
+	o := org.Org{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM orgs").
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = \?`).
+		WithArgs(deletedAt, deletedBy, version, id, version).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	tx, err := db.Beginx()
@@ -505,13 +586,16 @@ func TestDAODelete(t *testing.T) {
 func TestDAODelete_OptimisticLockErr(t *testing.T) {
 	d, db, md := initDAO()
 
-	o := org.DeleteOrg{
-		ID:      id,
-		Version: version,
+	o := org.Org{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM orgs").
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = \?`).
+		WithArgs(deletedAt, deletedBy, version, id, version).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
 	tx, err := db.Beginx()
@@ -530,14 +614,17 @@ func TestDAODelete_OptimisticLockErr(t *testing.T) {
 func TestDAODelete_OtherErr(t *testing.T) {
 	d, db, md := initDAO()
 
-	o := org.DeleteOrg{
-		ID:      id,
-		Version: version,
+	o := org.Org{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM orgs").
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = \?`).
+		WithArgs(deletedAt, deletedBy, version, id, version).
 		WillReturnError(&mockErr)
 
 	tx, err := db.Beginx()
@@ -552,13 +639,16 @@ func TestDAODelete_OtherErr(t *testing.T) {
 func TestDAODelete_TooManyRowsAffected(t *testing.T) {
 	d, db, md := initDAO()
 
-	o := org.DeleteOrg{
-		ID:      id,
-		Version: version,
+	o := org.Org{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM orgs").
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = \?`).
+		WithArgs(deletedAt, deletedBy, version, id, version).
 		WillReturnResult(sqlmock.NewResult(1, 2))
 
 	tx, err := db.Beginx()
@@ -569,3 +659,261 @@ func TestDAODelete_TooManyRowsAffected(t *testing.T) {
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Contains(t, err.Error(), "unexpected number of rows affected")
 }
+
+func TestDAORestore(t *testing.T) {
+	d, db, md := initDAO()
+
+	o := org.Org{
+		ID:        id,
+		Name:      name,
+		UpdatedAt: updatedAt,
+		UpdatedBy: "updater-id",
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = NULL`).
+		WithArgs(updatedAt, "updater-id", version, id, version).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.Restore(ctx, tx, o)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, version+1, actual.Version)
+	assert.Nil(t, actual.DeletedAt)
+	assert.Equal(t, "", actual.DeletedBy)
+}
+
+func TestDAORestore_OptimisticLockErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	o := org.Org{
+		ID:      id,
+		Version: version,
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.Restore(ctx, tx, o)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrOptimisticLock
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestDAORestore_OtherErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	o := org.Org{
+		ID:      id,
+		Version: version,
+	}
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectBegin()
+	md.ExpectExec(`UPDATE orgs SET\s+deleted_at = NULL`).
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.Restore(ctx, tx, o)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOPurge(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(deletedAt).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	actual, err := d.Purge(ctx, deletedAt)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), actual)
+}
+
+func TestDAOPurge_Err(t *testing.T) {
+	d, _, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(deletedAt).
+		WillReturnError(&mockErr)
+
+	_, err := d.Purge(ctx, deletedAt)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOGetByIDForUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByIDForUpdateQuery)).
+		WithArgs(id).
+		WillReturnRows(getRows())
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetByIDForUpdate(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, name, actual.Name)
+	assert.Equal(t, version, actual.Version)
+}
+
+func TestDAOGetByIDForUpdate_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByIDForUpdateQuery)).
+		WithArgs(id).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetByIDForUpdate(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrNotFound{ID: id}, err)
+}
+
+func TestDAOGetUsersForUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getUsersForUpdateQuery)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id",
+			"org_id",
+			"version",
+		}).AddRow(
+			"user-id",
+			id,
+			version,
+		))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetUsersForUpdate(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(actual))
+	assert.Equal(t, "user-id", actual[0].ID)
+	assert.Equal(t, id, actual[0].OrgID)
+	assert.Equal(t, version, actual[0].Version)
+}
+
+func TestDAOGetUsersForUpdate_Err(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getUsersForUpdateQuery)).
+		WithArgs(id).
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetUsersForUpdate(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAODeleteUsers(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(deleteUsersQuery)).
+		WithArgs(id, deletedAt, deletedBy).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.DeleteUsers(ctx, tx, id, deletedAt, deletedBy)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), actual)
+}
+
+func TestDAODeleteUsers_Err(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(deleteUsersQuery)).
+		WithArgs(id, deletedAt, deletedBy).
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.DeleteUsers(ctx, tx, id, deletedAt, deletedBy)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOReassignUsers(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(reassignUsersQuery)).
+		WithArgs(id, "bar-id", updatedAt, "updater-id").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.ReassignUsers(ctx, tx, id, "bar-id", updatedAt, "updater-id")
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), actual)
+}
+
+func TestDAOReassignUsers_Err(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(reassignUsersQuery)).
+		WithArgs(id, "bar-id", updatedAt, "updater-id").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.ReassignUsers(ctx, tx, id, "bar-id", updatedAt, "updater-id")
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
@@ -2,6 +2,8 @@ package org
 
 import (
 	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 )
 
 type ErrNameAlreadyInUse struct {
@@ -12,6 +14,10 @@ func (err ErrNameAlreadyInUse) Error() string {
 	return fmt.Sprintf("Cannot save org, name '%s' is already in use by another org", err.Name)
 }
 
+func (err ErrNameAlreadyInUse) Code() string {
+	return problem.CodeOrgNameInUse
+}
+
 type ErrCannotModifySysOrg struct {
 	ID string
 }
@@ -20,6 +26,10 @@ func (err ErrCannotModifySysOrg) Error() string {
 	return fmt.Sprintf("Cannot modify system org: id=%s", err.ID)
 }
 
+func (err ErrCannotModifySysOrg) Code() string {
+	return problem.CodeSysOrgReadOnly
+}
+
 type ErrNotFound struct {
 	ID string
 }
@@ -28,6 +38,10 @@ func (err ErrNotFound) Error() string {
 	return fmt.Sprintf("Org not found: id=%s", err.ID)
 }
 
+func (err ErrNotFound) Code() string {
+	return problem.CodeOrgNotFound
+}
+
 type ErrOptimisticLock struct {
 	ID      string
 	Version int64
@@ -36,3 +50,61 @@ type ErrOptimisticLock struct {
 func (err ErrOptimisticLock) Error() string {
 	return fmt.Sprintf("Org was modified since last retrieved: id=%s version=%d", err.ID, err.Version)
 }
+
+func (err ErrOptimisticLock) Code() string {
+	return problem.CodeOrgVersionConflict
+}
+
+type ErrInvalidIncludeDeleted struct {
+	Value string
+}
+
+func (err ErrInvalidIncludeDeleted) Error() string {
+	return fmt.Sprintf("Invalid include_deleted, must be true or false: include_deleted=%s", err.Value)
+}
+
+func (err ErrInvalidIncludeDeleted) Code() string {
+	return problem.CodeInvalidIncludeDeleted
+}
+
+type ErrOrgHasUsers struct {
+	ID       string
+	NumUsers int
+}
+
+func (err ErrOrgHasUsers) Error() string {
+	return fmt.Sprintf("Org still has users, delete them first or use mode=cascade or reassign_to=<orgID>: id=%s numUsers=%d", err.ID, err.NumUsers)
+}
+
+func (err ErrOrgHasUsers) Code() string {
+	return problem.CodeOrgHasUsers
+}
+
+func (err ErrOrgHasUsers) Extensions() map[string]any {
+	return map[string]any{"num_users": err.NumUsers}
+}
+
+type ErrInvalidDeleteMode struct {
+	Mode       string
+	ReassignTo string
+}
+
+func (err ErrInvalidDeleteMode) Error() string {
+	return fmt.Sprintf("Invalid delete mode, must be empty or cascade and can't be combined with reassign_to: mode=%s reassignTo=%s", err.Mode, err.ReassignTo)
+}
+
+func (err ErrInvalidDeleteMode) Code() string {
+	return problem.CodeInvalidDeleteMode
+}
+
+type ErrInvalidReassignTarget struct {
+	ID string
+}
+
+func (err ErrInvalidReassignTarget) Error() string {
+	return fmt.Sprintf("Invalid reassign_to, must be another existing org that isn't the system org: reassignTo=%s", err.ID)
+}
+
+func (err ErrInvalidReassignTarget) Code() string {
+	return problem.CodeInvalidReassignTarget
+}
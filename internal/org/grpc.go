@@ -0,0 +1,271 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	orgv1 "github.com/RyanBard/go-service-ex/pkg/pb/org/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer is the controller for gRPC, it calls the same service.
+type grpcServer struct {
+	orgv1.UnimplementedOrgServiceServer
+	log     *slog.Logger
+	service OrgService
+}
+
+func NewGRPCServer(log *slog.Logger, service OrgService) *grpcServer {
+	return &grpcServer{
+		log:     log.With(logutil.LogAttrSVC("OrgGRPC")),
+		service: service,
+	}
+}
+
+func (srv grpcServer) GetOrg(ctx context.Context, req *orgv1.GetOrgRequest) (*orgv1.Org, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetOrg"),
+		logAttrOrgID(req.GetId()),
+	)
+	log.Debug("called")
+	o, err := srv.service.GetByID(ctx, req.GetId(), req.GetIncludeDeleted())
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log.With(logAttrOrg(o)).Debug("success")
+	return toProto(o), nil
+}
+
+func (srv grpcServer) ListOrgs(ctx context.Context, req *orgv1.ListOrgsRequest) (*orgv1.ListOrgsResponse, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ListOrgs"),
+		logAttrOrgName(req.GetName()),
+	)
+	log.Debug("called")
+	pr, err := page.NewRequest(int(req.GetLimit()), req.GetCursor())
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	op, err := srv.service.GetAll(ctx, req.GetName(), req.GetIncludeDeleted(), pr)
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	resp := &orgv1.ListOrgsResponse{NextCursor: op.NextCursor}
+	for _, o := range op.Orgs {
+		resp.Orgs = append(resp.Orgs, toProto(o))
+	}
+	log.With(logAttrOrgsLen(len(op.Orgs))).Debug("success")
+	return resp, nil
+}
+
+func (srv grpcServer) CreateOrg(ctx context.Context, req *orgv1.CreateOrgRequest) (*orgv1.Org, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("CreateOrg"),
+	)
+	log.Debug("called")
+	o := org.Org{
+		Name: req.GetName(),
+		Desc: req.GetDesc(),
+	}
+	return srv.save(ctx, log, o)
+}
+
+func (srv grpcServer) UpdateOrg(ctx context.Context, req *orgv1.UpdateOrgRequest) (*orgv1.Org, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("UpdateOrg"),
+		logAttrOrgID(req.GetId()),
+	)
+	log.Debug("called")
+	// without an id Save would create an org
+	if req.GetId() == "" {
+		return nil, grpcErr(log, validate.Violations{validate.Required("id")}.Err())
+	}
+	o := org.Org{
+		ID:      req.GetId(),
+		Name:    req.GetName(),
+		Desc:    req.GetDesc(),
+		Version: req.GetVersion(),
+	}
+	return srv.save(ctx, log, o)
+}
+
+// save checks a create's rules the same as the json body's are before calling
+// the service, which checks an update's.
+func (srv grpcServer) save(ctx context.Context, log *slog.Logger, o org.Org) (*orgv1.Org, error) {
+	if o.ID == "" {
+		if err := validate.Struct(o, ""); err != nil {
+			return nil, grpcErr(log, err)
+		}
+	}
+	log = log.With(logAttrOrg(o))
+	log.Debug("request processed, about to call service")
+	o, err := srv.service.Save(ctx, o)
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log.Debug("success")
+	return toProto(o), nil
+}
+
+func (srv grpcServer) DeleteOrg(ctx context.Context, req *orgv1.DeleteOrgRequest) (*emptypb.Empty, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("DeleteOrg"),
+		logAttrOrgID(req.GetId()),
+	)
+	log.Debug("called")
+	o := org.DeleteOrg{ID: req.GetId(), Version: req.GetVersion()}
+	if err := validate.Struct(o, ""); err != nil {
+		return nil, grpcErr(log, err)
+	}
+	opts := org.DeleteOrgOptions{
+		Mode:       req.GetMode(),
+		ReassignTo: req.GetReassignTo(),
+	}
+	log = log.With(logAttrOrg(o), logAttrOpts(opts))
+	log.Debug("request processed, about to call service")
+	if err := srv.service.Delete(ctx, o, opts); err != nil {
+		if errors.As(err, &ErrNotFound{}) {
+			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not deleting")
+			return &emptypb.Empty{}, nil
+		}
+		return nil, grpcErr(log, err)
+	}
+	log.Debug("success")
+	return &emptypb.Empty{}, nil
+}
+
+func (srv grpcServer) RestoreOrg(ctx context.Context, req *orgv1.RestoreOrgRequest) (*orgv1.Org, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("RestoreOrg"),
+		logAttrOrgID(req.GetId()),
+	)
+	log.Debug("called")
+	o := org.RestoreOrg{ID: req.GetId(), Version: req.GetVersion()}
+	if err := validate.Struct(o, ""); err != nil {
+		return nil, grpcErr(log, err)
+	}
+	restored, err := srv.service.Restore(ctx, o)
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log.Debug("success")
+	return toProto(restored), nil
+}
+
+// ExportOrgs sends an org at a time, an error part way through ends the
+// stream with that error.
+func (srv grpcServer) ExportOrgs(req *orgv1.ExportOrgsRequest, stream grpc.ServerStreamingServer[orgv1.Org]) error {
+	ctx := stream.Context()
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ExportOrgs"),
+	)
+	log.Debug("called")
+	numRows := 0
+	for o, err := range srv.service.Export(ctx) {
+		if err != nil {
+			return grpcErr(log.With(logAttrRows(numRows)), err)
+		}
+		if err := stream.Send(toProto(o)); err != nil {
+			// the client went away
+			log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Warn("failed to send row")
+			return err
+		}
+		numRows++
+	}
+	log.With(logAttrRows(numRows)).Debug("success")
+	return nil
+}
+
+// grpcErr is err with the gRPC version of the status the controller would
+// have responded with.
+func grpcErr(log *slog.Logger, err error) error {
+	var code codes.Code
+	var notFound ErrNotFound
+	var modSysOrg ErrCannotModifySysOrg
+	var optLock ErrOptimisticLock
+	var dupName ErrNameAlreadyInUse
+	var hasUsers ErrOrgHasUsers
+	var invalidMode ErrInvalidDeleteMode
+	var invalidTarget ErrInvalidReassignTarget
+	var invalid validate.ErrInvalid
+	var invalidLimit page.ErrInvalidLimit
+	var invalidCursor page.ErrInvalidCursor
+	var forbidden authz.ErrForbidden
+	var unauthenticated authz.ErrUnauthenticated
+	if errors.As(err, &notFound) {
+		log.With(logutil.LogAttrError(err)).Warn("resource not found")
+		code = codes.NotFound
+	} else if errors.As(err, &modSysOrg) {
+		log.With(logutil.LogAttrError(err)).Warn("cannot modify system org")
+		code = codes.PermissionDenied
+	} else if errors.As(err, &optLock) {
+		log.With(logutil.LogAttrError(err)).Warn("optimistic lock error")
+		code = codes.Aborted
+	} else if errors.As(err, &dupName) {
+		log.With(logutil.LogAttrError(err)).Warn("duplicate name error")
+		code = codes.AlreadyExists
+	} else if errors.As(err, &hasUsers) {
+		log.With(logutil.LogAttrError(err)).Warn("org still has users")
+		code = codes.FailedPrecondition
+	} else if errors.As(err, &invalidMode) ||
+		errors.As(err, &invalidTarget) ||
+		errors.As(err, &invalid) ||
+		errors.As(err, &invalidLimit) ||
+		errors.As(err, &invalidCursor) {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		code = codes.InvalidArgument
+	} else if errors.As(err, &forbidden) {
+		log.With(logutil.LogAttrError(err)).Warn("forbidden")
+		code = codes.PermissionDenied
+	} else if errors.As(err, &unauthenticated) {
+		log.With(logutil.LogAttrError(err)).Warn("unauthenticated")
+		code = codes.Unauthenticated
+	} else {
+		log.With(logutil.LogAttrError(err)).Error("service call failed")
+		code = codes.Internal
+	}
+	return rpcerr.New(code, err)
+}
+
+func toProto(o org.Org) *orgv1.Org {
+	pb := &orgv1.Org{
+		Id:        o.ID,
+		Name:      o.Name,
+		Desc:      o.Desc,
+		IsSystem:  o.IsSystem,
+		CreatedAt: timestamppb.New(o.CreatedAt),
+		CreatedBy: o.CreatedBy,
+		UpdatedAt: timestamppb.New(o.UpdatedAt),
+		UpdatedBy: o.UpdatedBy,
+		Version:   o.Version,
+		DeletedBy: o.DeletedBy,
+	}
+	if o.DeletedAt != nil {
+		pb.DeletedAt = timestamppb.New(*o.DeletedAt)
+	}
+	return pb
+}
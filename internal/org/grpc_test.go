@@ -0,0 +1,268 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	orgv1 "github.com/RyanBard/go-service-ex/pkg/pb/org/v1"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func initGRPC() (srv *grpcServer, ms *mockSVC) {
+	ms = new(mockSVC)
+	srv = NewGRPCServer(testutil.GetLogger(), ms)
+	return srv, ms
+}
+
+func TestGRPCGetOrg(t *testing.T) {
+	srv, ms := initGRPC()
+	deletedAt := time.UnixMilli(300).UTC()
+	o := org.Org{
+		ID:        "foo-id",
+		Name:      "foo-name",
+		Desc:      "foo-desc",
+		CreatedAt: time.UnixMilli(100).UTC(),
+		UpdatedAt: time.UnixMilli(200).UTC(),
+		Version:   2,
+		DeletedAt: &deletedAt,
+		DeletedBy: "bar-id",
+	}
+	ms.On("GetByID", mock.Anything, "foo-id", true).Return(o, nil)
+
+	actual, err := srv.GetOrg(context.Background(), &orgv1.GetOrgRequest{Id: "foo-id", IncludeDeleted: true})
+
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(&orgv1.Org{
+		Id:        "foo-id",
+		Name:      "foo-name",
+		Desc:      "foo-desc",
+		CreatedAt: timestamppb.New(time.UnixMilli(100)),
+		UpdatedAt: timestamppb.New(time.UnixMilli(200)),
+		Version:   2,
+		DeletedAt: timestamppb.New(deletedAt),
+		DeletedBy: "bar-id",
+	}, actual))
+}
+
+func TestGRPCGetOrg_NotFoundError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetByID", mock.Anything, "foo-id", false).Return(org.Org{}, ErrNotFound{ID: "foo-id"})
+
+	_, err := srv.GetOrg(context.Background(), &orgv1.GetOrgRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, problem.CodeOrgNotFound, rpcerr.Reason(err))
+}
+
+func TestGRPCGetOrg_ForbiddenError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetByID", mock.Anything, "foo-id", false).Return(org.Org{}, authz.ErrForbidden{UserID: "bar-id", Action: "org:read"})
+
+	_, err := srv.GetOrg(context.Background(), &orgv1.GetOrgRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGRPCGetOrg_ServiceError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetByID", mock.Anything, "foo-id", false).Return(org.Org{}, errors.New("unit-test mock service error"))
+
+	_, err := srv.GetOrg(context.Background(), &orgv1.GetOrgRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "unit-test mock service error", status.Convert(err).Message())
+}
+
+func TestGRPCListOrgs(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetAll", mock.Anything, "foo", false, page.Request{Limit: 10}).Return(org.OrgPage{
+		Orgs:       []org.Org{{ID: "foo-id"}, {ID: "bar-id"}},
+		NextCursor: "next",
+	}, nil)
+
+	actual, err := srv.ListOrgs(context.Background(), &orgv1.ListOrgsRequest{Name: "foo", Limit: 10})
+
+	assert.Nil(t, err)
+	assert.Len(t, actual.Orgs, 2)
+	assert.Equal(t, "bar-id", actual.Orgs[1].Id)
+	assert.Equal(t, "next", actual.NextCursor)
+}
+
+func TestGRPCListOrgs_InvalidLimit(t *testing.T) {
+	srv, _ := initGRPC()
+
+	_, err := srv.ListOrgs(context.Background(), &orgv1.ListOrgsRequest{Limit: -1})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, problem.CodeInvalidLimit, rpcerr.Reason(err))
+}
+
+func TestGRPCCreateOrg(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Save", mock.Anything, org.Org{Name: "foo-name", Desc: "foo-desc"}).Return(org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 1}, nil)
+
+	actual, err := srv.CreateOrg(context.Background(), &orgv1.CreateOrgRequest{Name: "foo-name", Desc: "foo-desc"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-id", actual.Id)
+	assert.Equal(t, int64(1), actual.Version)
+}
+
+func TestGRPCCreateOrg_ValidationError(t *testing.T) {
+	srv, ms := initGRPC()
+
+	_, err := srv.CreateOrg(context.Background(), &orgv1.CreateOrgRequest{Desc: "foo-desc"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, problem.CodeValidationFailed, rpcerr.Reason(err))
+	ms.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestGRPCCreateOrg_NameAlreadyInUseError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Save", mock.Anything, mock.Anything).Return(org.Org{}, ErrNameAlreadyInUse{Name: "foo-name"})
+
+	_, err := srv.CreateOrg(context.Background(), &orgv1.CreateOrgRequest{Name: "foo-name", Desc: "foo-desc"})
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestGRPCUpdateOrg(t *testing.T) {
+	srv, ms := initGRPC()
+	o := org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 1}
+	ms.On("Save", mock.Anything, o).Return(org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 2}, nil)
+
+	actual, err := srv.UpdateOrg(context.Background(), &orgv1.UpdateOrgRequest{Id: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), actual.Version)
+}
+
+func TestGRPCUpdateOrg_MissingID(t *testing.T) {
+	srv, ms := initGRPC()
+
+	_, err := srv.UpdateOrg(context.Background(), &orgv1.UpdateOrgRequest{Name: "foo-name", Desc: "foo-desc", Version: 1})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	ms.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestGRPCUpdateOrg_OptimisticLockError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Save", mock.Anything, mock.Anything).Return(org.Org{}, ErrOptimisticLock{ID: "foo-id", Version: 1})
+
+	_, err := srv.UpdateOrg(context.Background(), &orgv1.UpdateOrgRequest{Id: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 1})
+
+	assert.Equal(t, codes.Aborted, status.Code(err))
+}
+
+func TestGRPCDeleteOrg(t *testing.T) {
+	srv, ms := initGRPC()
+	opts := org.DeleteOrgOptions{Mode: org.DeleteModeCascade}
+	ms.On("Delete", mock.Anything, org.DeleteOrg{ID: "foo-id", Version: 1}, opts).Return(nil)
+
+	_, err := srv.DeleteOrg(context.Background(), &orgv1.DeleteOrgRequest{Id: "foo-id", Version: 1, Mode: org.DeleteModeCascade})
+
+	assert.Nil(t, err)
+}
+
+func TestGRPCDeleteOrg_NotFoundError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(ErrNotFound{ID: "foo-id"})
+
+	_, err := srv.DeleteOrg(context.Background(), &orgv1.DeleteOrgRequest{Id: "foo-id", Version: 1})
+
+	assert.Nil(t, err)
+}
+
+func TestGRPCDeleteOrg_HasUsersError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(ErrOrgHasUsers{ID: "foo-id", NumUsers: 2})
+
+	_, err := srv.DeleteOrg(context.Background(), &orgv1.DeleteOrgRequest{Id: "foo-id", Version: 1})
+
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, problem.CodeOrgHasUsers, rpcerr.Reason(err))
+}
+
+func TestGRPCDeleteOrg_ValidationError(t *testing.T) {
+	srv, ms := initGRPC()
+
+	_, err := srv.DeleteOrg(context.Background(), &orgv1.DeleteOrgRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	ms.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGRPCRestoreOrg(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Restore", mock.Anything, org.RestoreOrg{ID: "foo-id", Version: 2}).Return(org.Org{ID: "foo-id", Version: 3}, nil)
+
+	actual, err := srv.RestoreOrg(context.Background(), &orgv1.RestoreOrgRequest{Id: "foo-id", Version: 2})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), actual.Version)
+}
+
+func TestGRPCExportOrgs(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Export", mock.Anything).Return(seqOf([]org.Org{{ID: "foo-id"}, {ID: "bar-id"}}, nil))
+	stream := &mockOrgStream{ctx: context.Background()}
+
+	err := srv.ExportOrgs(&orgv1.ExportOrgsRequest{}, stream)
+
+	assert.Nil(t, err)
+	assert.Len(t, stream.sent, 2)
+	assert.Equal(t, "bar-id", stream.sent[1].Id)
+}
+
+func TestGRPCExportOrgs_ErrorPartWay(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Export", mock.Anything).Return(seqOf([]org.Org{{ID: "foo-id"}}, errors.New("unit-test mock service error")))
+	stream := &mockOrgStream{ctx: context.Background()}
+
+	err := srv.ExportOrgs(&orgv1.ExportOrgsRequest{}, stream)
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Len(t, stream.sent, 1)
+}
+
+func TestGRPCExportOrgs_UnauthenticatedError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Export", mock.Anything).Return(seqOf(nil, authz.ErrUnauthenticated{}))
+	stream := &mockOrgStream{ctx: context.Background()}
+
+	err := srv.ExportOrgs(&orgv1.ExportOrgsRequest{}, stream)
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Empty(t, stream.sent)
+}
+
+type mockOrgStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*orgv1.Org
+}
+
+func (m *mockOrgStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockOrgStream) Send(o *orgv1.Org) error {
+	m.sent = append(m.sent, o)
+	return nil
+}
@@ -1,6 +1,12 @@
 package org
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+)
 
 func logAttrOrgID(orgID string) slog.Attr {
 	return slog.String("orgID", orgID)
@@ -21,3 +27,47 @@ func logAttrPathID(pathID string) slog.Attr {
 func logAttrOrgsLen(len int) slog.Attr {
 	return slog.Int("orgsLen", len)
 }
+
+func logAttrAfter(after *page.Cursor) slog.Attr {
+	return slog.Any("after", after)
+}
+
+func logAttrLimit(limit int) slog.Attr {
+	return slog.Int("limit", limit)
+}
+
+func logAttrIncludeDeleted(includeDeleted bool) slog.Attr {
+	return slog.Bool("includeDeleted", includeDeleted)
+}
+
+func logAttrBefore(before time.Time) slog.Attr {
+	return slog.Time("before", before)
+}
+
+func logAttrNumRows(numRows int64) slog.Attr {
+	return slog.Int64("numRows", numRows)
+}
+
+func logAttrOpts(opts org.DeleteOrgOptions) slog.Attr {
+	return slog.Any("opts", opts)
+}
+
+func logAttrReassignTo(reassignTo string) slog.Attr {
+	return slog.String("reassignTo", reassignTo)
+}
+
+func logAttrUsersLen(len int) slog.Attr {
+	return slog.Int("usersLen", len)
+}
+
+func logAttrRows(rows int) slog.Attr {
+	return slog.Int("rows", rows)
+}
+
+func logAttrVersion(version int64) slog.Attr {
+	return slog.Int64("version", version)
+}
+
+func logAttrContentType(contentType string) slog.Attr {
+	return slog.String("contentType", contentType)
+}
@@ -3,23 +3,40 @@ package org
 import (
 	"context"
 	"errors"
+	"iter"
 	"log/slog"
 	"strings"
 	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/jmoiron/sqlx"
 )
 
 type OrgDAO interface {
-	GetByID(ctx context.Context, id string) (org.Org, error)
-	GetAll(ctx context.Context) ([]org.Org, error)
-	SearchByName(ctx context.Context, name string) ([]org.Org, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error)
+	GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (org.Org, error)
+	GetAll(ctx context.Context, includeDeleted bool, after *page.Cursor, limit int) ([]org.Org, error)
+	SearchByName(ctx context.Context, name string, includeDeleted bool, after *page.Cursor, limit int) ([]org.Org, error)
 	Create(ctx context.Context, tx *sqlx.Tx, o org.Org) error
 	Update(ctx context.Context, tx *sqlx.Tx, o org.Org) (org.Org, error)
-	Delete(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg) error
+	Delete(ctx context.Context, tx *sqlx.Tx, o org.Org) error
+	Restore(ctx context.Context, tx *sqlx.Tx, o org.Org) (org.Org, error)
+	GetUsersForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string) ([]user.User, error)
+	DeleteUsers(ctx context.Context, tx *sqlx.Tx, orgID string, deletedAt time.Time, deletedBy string) (int64, error)
+	ReassignUsers(ctx context.Context, tx *sqlx.Tx, orgID string, toOrgID string, updatedAt time.Time, updatedBy string) (int64, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error
 }
 
 type TXManager interface {
@@ -35,50 +52,138 @@ type IDGenerator interface {
 }
 
 type service struct {
-	log   *slog.Logger
-	dao   OrgDAO
-	txMGR TXManager
-	timer Timer
-	idGen IDGenerator
+	log     *slog.Logger
+	dao     OrgDAO
+	auditor Auditor
+	txMGR   TXManager
+	timer   Timer
+	idGen   IDGenerator
 }
 
-func NewService(log *slog.Logger, dao OrgDAO, txMGR TXManager, timer Timer, idGen IDGenerator) *service {
+func NewService(log *slog.Logger, dao OrgDAO, auditor Auditor, txMGR TXManager, timer Timer, idGen IDGenerator) *service {
 	return &service{
-		log:   log.With(logutil.LogAttrSVC("OrgSVC")),
-		dao:   dao,
-		txMGR: txMGR,
-		timer: timer,
-		idGen: idGen,
+		log:     log.With(logutil.LogAttrSVC("OrgSVC")),
+		dao:     dao,
+		auditor: auditor,
+		txMGR:   txMGR,
+		timer:   timer,
+		idGen:   idGen,
 	}
 }
 
-func (s service) GetByID(ctx context.Context, id string) (org.Org, error) {
+func (s service) GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error) {
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetByID"),
 		logAttrOrgID(id),
+		logAttrIncludeDeleted(includeDeleted),
 	)
 	log.Debug("called")
-	return s.dao.GetByID(ctx, id)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return org.Org{}, err
+	}
+	if !p.CanRead(id) {
+		log.Warn("forbidden")
+		return org.Org{}, authz.ErrForbidden{UserID: p.UserID, Action: "org:read"}
+	}
+	// only system admins can delete orgs, so they're the only ones who can
+	// see them afterwards
+	if includeDeleted && !p.IsSystemAdmin() {
+		log.Warn("forbidden")
+		return org.Org{}, authz.ErrForbidden{UserID: p.UserID, Action: "org:read_deleted"}
+	}
+	return s.dao.GetByID(ctx, id, includeDeleted)
 }
 
-func (s service) GetAll(ctx context.Context, name string) ([]org.Org, error) {
+func (s service) GetAll(ctx context.Context, name string, includeDeleted bool, pr page.Request) (op org.OrgPage, err error) {
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetAll"),
 		logAttrOrgName(name),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(pr.After),
+		logAttrLimit(pr.Limit),
 	)
 	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return op, err
+	}
+	if includeDeleted && !p.IsSystemAdmin() {
+		log.Warn("forbidden")
+		return op, authz.ErrForbidden{UserID: p.UserID, Action: "org:read_deleted"}
+	}
+	if !p.IsSystemAdmin() {
+		return s.getOwnOrg(ctx, p, name, pr)
+	}
+	var orgs []org.Org
 	if name == "" {
-		return s.dao.GetAll(ctx)
+		orgs, err = s.dao.GetAll(ctx, includeDeleted, pr.After, pr.Limit+1)
 	} else {
-		return s.dao.SearchByName(ctx, strings.ToLower(name))
+		orgs, err = s.dao.SearchByName(ctx, strings.ToLower(name), includeDeleted, pr.After, pr.Limit+1)
 	}
+	if err != nil {
+		return op, err
+	}
+	op.Orgs, op.NextCursor = page.Trim(orgs, pr.Limit, toCursor)
+	return op, nil
 }
 
+// getOwnOrg is the org listing for everyone but system admins, it's a single
+// page of at most their own org (the others they're a member of are listed by
+// GET /api/users/:id/memberships).
+func (s service) getOwnOrg(ctx context.Context, p authz.Principal, name string, pr page.Request) (op org.OrgPage, err error) {
+	op.Orgs = []org.Org{}
+	if p.OrgID == "" || pr.After != nil {
+		return op, nil
+	}
+	o, err := s.dao.GetByID(ctx, p.OrgID, false)
+	if err != nil {
+		var notFound ErrNotFound
+		if errors.As(err, &notFound) {
+			return op, nil
+		}
+		return op, err
+	}
+	if strings.Contains(strings.ToLower(o.Name), strings.ToLower(name)) {
+		op.Orgs = append(op.Orgs, o)
+	}
+	return op, nil
+}
+
+// Export pages through GetAll so the whole table is never in memory, a
+// failure part way through is yielded after the rows that made it.
+func (s service) Export(ctx context.Context) iter.Seq2[org.Org, error] {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Export"),
+	)
+	log.Debug("called")
+	return page.All(page.MaxLimit, func(pr page.Request) ([]org.Org, string, error) {
+		op, err := s.GetAll(ctx, "", false, pr)
+		return op.Orgs, op.NextCursor, err
+	})
+}
+
+func toCursor(o org.Org) page.Cursor {
+	return page.Cursor{
+		Key:       o.Name,
+		CreatedAt: o.CreatedAt,
+		ID:        o.ID,
+	}
+}
+
+// Save creates o when it doesn't have an id (its rules are checked by the
+// caller) and updates it otherwise, an update checks the rules of the fields
+// it changes.
 func (s service) Save(ctx context.Context, o org.Org) (out org.Org, err error) {
+	if o.ID == "" {
+		return s.Create(ctx, nil, o)
+	}
 	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
 	if loggedInUserID == "" {
 		return out, errors.New("user not logged in")
@@ -90,33 +195,139 @@ func (s service) Save(ctx context.Context, o org.Org) (out org.Org, err error) {
 		logAttrOrg(o),
 	)
 	log.Debug("called")
-	if o.ID != "" {
-		orgInDB, err := s.GetByID(ctx, o.ID)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	if !p.CanManage(o.ID) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "org:update"}
+	}
+	// Update's version check fails if this is stale by the time it's audited
+	orgInDB, err := s.GetByID(ctx, o.ID, false)
+	if err != nil {
+		return out, err
+	}
+	if orgInDB.IsSystem {
+		err = ErrCannotModifySysOrg{ID: o.ID}
+		return out, err
+	}
+	// only the changed fields, an org from before a rule was added can still
+	// be updated
+	if err := validate.Changed(o, orgInDB, ""); err != nil {
+		return out, err
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		o.UpdatedAt = s.timer.Now()
+		o.UpdatedBy = loggedInUserID
+		out, err = s.dao.Update(ctx, tx, o)
 		if err != nil {
-			return out, err
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityOrg, o.ID, orgInDB, out)
+	})
+	if err != nil {
+		return org.Org{}, err
+	}
+	return out, nil
+}
+
+// Patch applies changes to the org as it is in the db, it's read in the same
+// tx as the update (and locked) so nothing can land in between. Only the
+// name, desc and version can be changed, a changed version (or the If-Match
+// version, when it isn't 0) has to match the org's.
+func (s service) Patch(ctx context.Context, id string, version int64, changes patch.Patch) (out org.Org, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Patch"),
+		logAttrOrgID(id),
+		logAttrVersion(version),
+		logAttrContentType(changes.ContentType),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	if !p.CanManage(id) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "org:update"}
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		orgInDB, err := s.dao.GetByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
 		}
 		if orgInDB.IsSystem {
-			err = ErrCannotModifySysOrg{ID: o.ID}
-			return out, err
+			return ErrCannotModifySysOrg{ID: id}
 		}
+		if version != 0 && version != orgInDB.Version {
+			return ErrOptimisticLock{ID: id, Version: version}
+		}
+		o, err := patch.To(changes, orgInDB, "name", "desc", "version")
+		if err != nil {
+			return err
+		}
+		if o.Version != orgInDB.Version {
+			return ErrOptimisticLock{ID: id, Version: o.Version}
+		}
+		if err := validate.Changed(o, orgInDB, ""); err != nil {
+			return err
+		}
+		o.UpdatedAt = s.timer.Now()
+		o.UpdatedBy = loggedInUserID
+		out, err = s.dao.Update(ctx, tx, o)
+		if err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityOrg, id, orgInDB, out)
+	})
+	if err != nil {
+		return org.Org{}, err
 	}
-	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
-		if o.ID == "" {
-			o.ID = s.idGen.GenID()
-			o.Version = 1
-			o.CreatedAt = s.timer.Now()
-			o.CreatedBy = loggedInUserID
-			o.UpdatedAt = s.timer.Now()
-			o.UpdatedBy = loggedInUserID
-			o.IsSystem = false
-			out = o
-			return s.dao.Create(ctx, tx, o)
-		} else {
-			o.UpdatedAt = s.timer.Now()
-			o.UpdatedBy = loggedInUserID
-			out, err = s.dao.Update(ctx, tx, o)
+	return out, nil
+}
+
+// Create is the create half of Save for callers that need the org to be part
+// of their own tx, a nil joinTX creates one.
+func (s service) Create(ctx context.Context, joinTX *sqlx.Tx, o org.Org) (out org.Org, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrOrg(o),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	if !p.IsSystemAdmin() {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "org:create"}
+	}
+	err = s.txMGR.Do(ctx, joinTX, func(tx *sqlx.Tx) error {
+		o.ID = s.idGen.GenID()
+		o.Version = 1
+		o.CreatedAt = s.timer.Now()
+		o.CreatedBy = loggedInUserID
+		o.UpdatedAt = s.timer.Now()
+		o.UpdatedBy = loggedInUserID
+		o.IsSystem = false
+		out = o
+		if err := s.dao.Create(ctx, tx, o); err != nil {
 			return err
 		}
+		return s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityOrg, o.ID, nil, out)
 	})
 	if err != nil {
 		return org.Org{}, err
@@ -124,15 +335,33 @@ func (s service) Save(ctx context.Context, o org.Org) (out org.Org, err error) {
 	return out, nil
 }
 
-func (s service) Delete(ctx context.Context, o org.DeleteOrg) error {
+// Delete refuses to delete an org that still has users unless opts says to
+// cascade the delete to them or to move them to another org first.
+func (s service) Delete(ctx context.Context, o org.DeleteOrg, opts org.DeleteOrgOptions) error {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return errors.New("user not logged in")
+	}
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("Delete"),
 		logAttrOrg(o),
+		logAttrOpts(opts),
 	)
 	log.Debug("called")
-	orgInDB, err := s.GetByID(ctx, o.ID)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !p.IsSystemAdmin() {
+		log.Warn("forbidden")
+		return authz.ErrForbidden{UserID: p.UserID, Action: "org:delete"}
+	}
+	if (opts.Mode != "" && opts.Mode != org.DeleteModeCascade) || (opts.Mode != "" && opts.ReassignTo != "") {
+		return ErrInvalidDeleteMode{Mode: opts.Mode, ReassignTo: opts.ReassignTo}
+	}
+	orgInDB, err := s.GetByID(ctx, o.ID, false)
 	if err != nil {
 		return err
 	}
@@ -140,11 +369,142 @@ func (s service) Delete(ctx context.Context, o org.DeleteOrg) error {
 		err = ErrCannotModifySysOrg{ID: o.ID}
 		return err
 	}
+	if opts.ReassignTo != "" {
+		if err := s.checkReassignTarget(ctx, o.ID, opts.ReassignTo); err != nil {
+			return err
+		}
+	}
+	now := s.timer.Now()
+	deleted := orgInDB
+	deleted.Version = o.Version
+	deleted.DeletedAt = &now
+	deleted.DeletedBy = loggedInUserID
 	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
-		return s.dao.Delete(ctx, tx, o)
+		users, err := s.dao.GetUsersForUpdate(ctx, tx, o.ID)
+		if err != nil {
+			return err
+		}
+		if len(users) > 0 {
+			if opts.Mode == org.DeleteModeCascade {
+				err = s.deleteUsers(ctx, tx, o, users, now, loggedInUserID)
+			} else if opts.ReassignTo != "" {
+				err = s.reassignUsers(ctx, tx, o, users, opts.ReassignTo, now, loggedInUserID)
+			} else {
+				log.With(logAttrUsersLen(len(users))).Warn("org still has users")
+				err = ErrOrgHasUsers{ID: o.ID, NumUsers: len(users)}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := s.dao.Delete(ctx, tx, deleted); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionDelete, audit.EntityOrg, o.ID, orgInDB, nil)
 	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+func (s service) checkReassignTarget(ctx context.Context, id string, reassignTo string) error {
+	if reassignTo == id {
+		return ErrInvalidReassignTarget{ID: reassignTo}
+	}
+	target, err := s.GetByID(ctx, reassignTo, false)
+	if errors.As(err, &ErrNotFound{}) {
+		return ErrInvalidReassignTarget{ID: reassignTo}
+	}
+	if err != nil {
+		return err
+	}
+	if target.IsSystem {
+		return ErrInvalidReassignTarget{ID: reassignTo}
+	}
+	return nil
+}
+
+// deleteUsers soft deletes users, they were locked by GetUsersForUpdate so a
+// different row count means one was added in the meantime.
+func (s service) deleteUsers(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg, users []user.User, deletedAt time.Time, deletedBy string) error {
+	numRows, err := s.dao.DeleteUsers(ctx, tx, o.ID, deletedAt, deletedBy)
+	if err != nil {
+		return err
+	}
+	if numRows != int64(len(users)) {
+		return ErrOptimisticLock{ID: o.ID, Version: o.Version}
+	}
+	for _, u := range users {
+		if err := s.auditor.Record(ctx, tx, audit.ActionDelete, audit.EntityUser, u.ID, u, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s service) reassignUsers(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg, users []user.User, toOrgID string, updatedAt time.Time, updatedBy string) error {
+	numRows, err := s.dao.ReassignUsers(ctx, tx, o.ID, toOrgID, updatedAt, updatedBy)
+	if err != nil {
+		return err
+	}
+	if numRows != int64(len(users)) {
+		return ErrOptimisticLock{ID: o.ID, Version: o.Version}
+	}
+	for _, u := range users {
+		moved := u
+		moved.OrgID = toOrgID
+		moved.UpdatedAt = updatedAt
+		moved.UpdatedBy = updatedBy
+		moved.Version = u.Version + 1
+		if err := s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, u.ID, u, moved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s service) Restore(ctx context.Context, o org.RestoreOrg) (out org.Org, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Restore"),
+		logAttrOrg(o),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	if !p.IsSystemAdmin() {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "org:restore"}
+	}
+	orgInDB, err := s.GetByID(ctx, o.ID, true)
+	if err != nil {
+		return out, err
+	}
+	if orgInDB.DeletedAt == nil {
+		log.Info("not deleted, nothing to restore")
+		return orgInDB, nil
+	}
+	restored := orgInDB
+	restored.Version = o.Version
+	restored.UpdatedAt = s.timer.Now()
+	restored.UpdatedBy = loggedInUserID
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		out, err = s.dao.Restore(ctx, tx, restored)
+		if err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionRestore, audit.EntityOrg, o.ID, orgInDB, out)
+	})
+	if err != nil {
+		return org.Org{}, err
+	}
+	return out, nil
+}
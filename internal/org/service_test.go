@@ -8,8 +8,17 @@ import (
 	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	pkgpatch "github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,6 +28,10 @@ type mockDAO struct {
 	mock.Mock
 }
 
+type mockAuditor struct {
+	mock.Mock
+}
+
 type mockTXManager struct {
 	mock.Mock
 }
@@ -31,21 +44,30 @@ type mockIDGen struct {
 	mock.Mock
 }
 
-func initSVC() (s *service, md *mockDAO, mm *mockTXManager, mt *mockTimer, mi *mockIDGen) {
+func initSVC() (s *service, md *mockDAO, ma *mockAuditor, mm *mockTXManager, mt *mockTimer, mi *mockIDGen) {
 	log := testutil.GetLogger()
 	md = new(mockDAO)
+	ma = new(mockAuditor)
 	mm = new(mockTXManager)
 	mt = new(mockTimer)
 	mi = new(mockIDGen)
-	s = NewService(log, md, mm, mt, mi)
-	return s, md, mm, mt, mi
+	s = NewService(log, md, ma, mm, mt, mi)
+	return s, md, ma, mm, mt, mi
+}
+
+// principalCTX is what the Auth middleware would leave in the context for a
+// token with the given claims.
+func principalCTX(userID string, claims jwt.MapClaims) context.Context {
+	claims["sub"] = userID
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, userID)
+	return context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
 }
 
 func TestSVCGetByID(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	id := "foo-id"
 
 	mockRes := org.Org{
@@ -53,35 +75,35 @@ func TestSVCGetByID(t *testing.T) {
 		Name: "foo-name",
 		Desc: "foo-desc",
 	}
-	md.On("GetByID", ctx, id).Return(mockRes, nil)
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
 
-	actual, err := s.GetByID(ctx, id)
+	actual, err := s.GetByID(ctx, id, false)
 
 	assert.Nil(t, err)
 	assert.Equal(t, mockRes, actual)
 }
 
 func TestSVCGetByID_DAOErr(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	id := "foo-id"
 
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetByID", ctx, id).Return(org.Org{}, mockErr)
+	md.On("GetByID", ctx, id, false).Return(org.Org{}, mockErr)
 
-	actual, err := s.GetByID(ctx, id)
+	actual, err := s.GetByID(ctx, id, false)
 
 	assert.Equal(t, mockErr, err)
 	assert.Equal(t, org.Org{}, actual)
 }
 
 func TestSVCGetAll(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	name := ""
 
 	mockRes := []org.Org{
@@ -91,35 +113,69 @@ func TestSVCGetAll(t *testing.T) {
 			Desc: "foo-desc",
 		},
 	}
-	md.On("GetAll", ctx).Return(mockRes, nil)
+	var after *page.Cursor
+	md.On("GetAll", ctx, false, after, 2).Return(mockRes, nil)
 
-	actual, err := s.GetAll(ctx, name)
+	actual, err := s.GetAll(ctx, name, false, page.Request{Limit: 1})
 
 	assert.Nil(t, err)
-	assert.Equal(t, mockRes, actual)
+	assert.Equal(t, org.OrgPage{Orgs: mockRes}, actual)
+}
+
+func TestSVCGetAll_MorePages(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	name := ""
+
+	after := page.Cursor{ID: "after-id"}
+	mockRes := []org.Org{
+		{
+			ID:        "foo-id",
+			Name:      "foo-name",
+			Desc:      "foo-desc",
+			CreatedAt: time.UnixMilli(100).UTC(),
+		},
+		{
+			ID:   "bar-id",
+			Name: "bar-name",
+			Desc: "bar-desc",
+		},
+	}
+	md.On("GetAll", ctx, false, &after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, name, false, page.Request{Limit: 1, After: &after})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[:1], actual.Orgs)
+	next, err := page.DecodeCursor(actual.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, page.Cursor{Key: "foo-name", CreatedAt: time.UnixMilli(100).UTC(), ID: "foo-id"}, next)
 }
 
 func TestSVCGetAll_DAOErr(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	name := ""
 
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetAll", ctx).Return([]org.Org{}, mockErr)
+	var after *page.Cursor
+	md.On("GetAll", ctx, false, after, 2).Return([]org.Org{}, mockErr)
 
-	actual, err := s.GetAll(ctx, name)
+	actual, err := s.GetAll(ctx, name, false, page.Request{Limit: 1})
 
 	assert.Equal(t, mockErr, err)
-	assert.Equal(t, []org.Org{}, actual)
+	assert.Equal(t, org.OrgPage{}, actual)
 }
 
 func TestSVCGetAll_NameSpecified(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	name := "foo"
 
 	mockRes := []org.Org{
@@ -129,19 +185,20 @@ func TestSVCGetAll_NameSpecified(t *testing.T) {
 			Desc: "foo-desc",
 		},
 	}
-	md.On("SearchByName", ctx, name).Return(mockRes, nil)
+	var after *page.Cursor
+	md.On("SearchByName", ctx, name, false, after, 2).Return(mockRes, nil)
 
-	actual, err := s.GetAll(ctx, name)
+	actual, err := s.GetAll(ctx, name, false, page.Request{Limit: 1})
 
 	assert.Nil(t, err)
-	assert.Equal(t, mockRes, actual)
+	assert.Equal(t, org.OrgPage{Orgs: mockRes}, actual)
 }
 
 func TestSVCGetAll_UpperCaseNameSpecified(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	name := "FOO"
 
 	mockRes := []org.Org{
@@ -151,35 +208,121 @@ func TestSVCGetAll_UpperCaseNameSpecified(t *testing.T) {
 			Desc: "foo-desc",
 		},
 	}
-	md.On("SearchByName", ctx, strings.ToLower(name)).Return(mockRes, nil)
+	var after *page.Cursor
+	md.On("SearchByName", ctx, strings.ToLower(name), false, after, 2).Return(mockRes, nil)
 
-	actual, err := s.GetAll(ctx, name)
+	actual, err := s.GetAll(ctx, name, false, page.Request{Limit: 1})
 
 	assert.Nil(t, err)
-	assert.Equal(t, mockRes, actual)
+	assert.Equal(t, org.OrgPage{Orgs: mockRes}, actual)
 }
 
 func TestSVCGetAll_NameSpecified_DAOErr(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	name := "foo"
 
 	mockErr := errors.New("unit-test mock error")
-	md.On("SearchByName", ctx, name).Return([]org.Org{}, mockErr)
+	var after *page.Cursor
+	md.On("SearchByName", ctx, name, false, after, 2).Return([]org.Org{}, mockErr)
 
-	actual, err := s.GetAll(ctx, name)
+	actual, err := s.GetAll(ctx, name, false, page.Request{Limit: 1})
 
 	assert.Equal(t, mockErr, err)
-	assert.Equal(t, []org.Org{}, actual)
+	assert.Equal(t, org.OrgPage{}, actual)
+}
+
+func assertForbidden(t *testing.T, err error) {
+	var forbidden authz.ErrForbidden
+	assert.True(t, errors.As(err, &forbidden))
+}
+
+func TestSVCGetByID_OwnOrg(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": id, "role": authz.RoleMember})
+
+	mockRes := org.Org{ID: id, Name: "foo-name"}
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
+
+	actual, err := s.GetByID(ctx, id, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByID_OtherOrgForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "other-org-id", "role": authz.RoleOrgAdmin})
+
+	actual, err := s.GetByID(ctx, "foo-id", false)
+
+	assertForbidden(t, err)
+	assert.Equal(t, org.Org{}, actual)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetByID_NotLoggedIn(t *testing.T) {
+	s, _, _, _, _, _ := initSVC()
+
+	_, err := s.GetByID(context.Background(), "foo-id", false)
+
+	var expected authz.ErrUnauthenticated
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestSVCGetAll_NonSystemAdminOnlySeesOwnOrg(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": id, "role": authz.RoleMember})
+
+	mockRes := org.Org{ID: id, Name: "Foo Name"}
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, "foo", false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, org.OrgPage{Orgs: []org.Org{mockRes}}, actual)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "SearchByName", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetAll_NonSystemAdminNameMismatch(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": id, "role": authz.RoleMember})
+
+	md.On("GetByID", ctx, id, false).Return(org.Org{ID: id, Name: "Foo Name"}, nil)
+
+	actual, err := s.GetAll(ctx, "bar", false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Len(t, actual.Orgs, 0)
+}
+
+func TestSVCGetAll_NonSystemAdminNoOrg(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{})
+
+	actual, err := s.GetAll(ctx, "", false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Len(t, actual.Orgs, 0)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestSVCSave_NoID(t *testing.T) {
-	s, md, _, mt, mi := initSVC()
+	s, md, ma, _, mt, mi := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.Org{
 		Name:      "foo-name",
 		Desc:      "foo-desc",
@@ -208,18 +351,58 @@ func TestSVCSave_NoID(t *testing.T) {
 	}
 	var expectedTX *sqlx.Tx
 	md.On("Create", ctx, expectedTX, expectedOrg).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityOrg, id, nil, expectedOrg).Return(nil)
 
 	actual, err := s.Save(ctx, o)
 
 	assert.Nil(t, err)
 	assert.Equal(t, expectedOrg, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCCreate_JoinTX(t *testing.T) {
+	s, md, ma, _, mt, mi := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	o := org.Org{
+		Name: "foo-name",
+		Desc: "foo-desc",
+	}
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return("foo-id")
+
+	joinTX := &sqlx.Tx{}
+	md.On("Create", ctx, joinTX, mock.Anything).Return(nil)
+	ma.On("Record", ctx, joinTX, audit.ActionCreate, audit.EntityOrg, "foo-id", nil, mock.Anything).Return(nil)
+
+	actual, err := s.Create(ctx, joinTX, o)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-id", actual.ID)
+	assert.Equal(t, int64(1), actual.Version)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCCreate_OrgAdminForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-id", "role": authz.RoleOrgAdmin})
+
+	_, err := s.Create(ctx, &sqlx.Tx{}, org.Org{Name: "foo-name", Desc: "foo-desc"})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestSVCSave_NoID_DAOErr(t *testing.T) {
-	s, md, _, mt, mi := initSVC()
+	s, md, _, _, mt, mi := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.Org{
 		Name: "foo-name",
 		Desc: "foo-desc",
@@ -241,11 +424,35 @@ func TestSVCSave_NoID_DAOErr(t *testing.T) {
 	assert.Equal(t, org.Org{}, actual)
 }
 
+func TestSVCSave_NoID_AuditErr(t *testing.T) {
+	s, md, ma, _, mt, mi := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	o := org.Org{
+		Name: "foo-name",
+		Desc: "foo-desc",
+	}
+
+	mt.On("Now").Return(time.UnixMilli(100))
+	mi.On("GenID").Return("foo-id")
+
+	var expectedTX *sqlx.Tx
+	mockErr := errors.New("unit-test mock error")
+	md.On("Create", ctx, expectedTX, mock.Anything).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityOrg, "foo-id", nil, mock.Anything).Return(mockErr)
+
+	actual, err := s.Save(ctx, o)
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, org.Org{}, actual)
+}
+
 func TestSVCSave_ID(t *testing.T) {
-	s, md, _, mt, _ := initSVC()
+	s, md, ma, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.Org{
 		ID:        "foo-id",
 		Name:      "foo-name",
@@ -267,8 +474,42 @@ func TestSVCSave_ID(t *testing.T) {
 		Version:   o.Version,
 	}
 	var expectedTX *sqlx.Tx
-	md.On("GetByID", ctx, o.ID).Return(org.Org{}, nil)
+	orgInDB := org.Org{ID: o.ID, Name: "old-name", Desc: o.Desc, Version: o.Version}
+	md.On("GetByID", ctx, o.ID, false).Return(orgInDB, nil)
+	md.On("Update", ctx, expectedTX, expectedOrg).Return(expectedOrg, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityOrg, o.ID, orgInDB, expectedOrg).Return(nil)
+
+	actual, err := s.Save(ctx, o)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedOrg, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCSave_ID_UnchangedDescTooLong(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	// saved before desc had a max
+	o := org.Org{
+		ID:      "foo-id",
+		Name:    "foo-name",
+		Desc:    strings.Repeat("a", 1001),
+		Version: 1,
+	}
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+
+	expectedOrg := o
+	expectedOrg.UpdatedAt = now
+	expectedOrg.UpdatedBy = loggedInUserID
+	var expectedTX *sqlx.Tx
+	orgInDB := org.Org{ID: o.ID, Name: "old-name", Desc: o.Desc, Version: o.Version}
+	md.On("GetByID", ctx, o.ID, false).Return(orgInDB, nil)
 	md.On("Update", ctx, expectedTX, expectedOrg).Return(expectedOrg, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityOrg, o.ID, orgInDB, expectedOrg).Return(nil)
 
 	actual, err := s.Save(ctx, o)
 
@@ -276,11 +517,28 @@ func TestSVCSave_ID(t *testing.T) {
 	assert.Equal(t, expectedOrg, actual)
 }
 
+func TestSVCSave_ID_ValidationErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.Org{ID: "foo-id", Name: strings.Repeat("a", 101), Desc: "foo-desc", Version: 1}
+
+	orgInDB := org.Org{ID: o.ID, Name: "old-name", Desc: o.Desc, Version: o.Version}
+	md.On("GetByID", ctx, o.ID, false).Return(orgInDB, nil)
+
+	_, err := s.Save(ctx, o)
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "name", Rule: "max", Message: "name must be at most 100 characters"},
+	}}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestSVCSave_ID_DAOUpdateErr(t *testing.T) {
-	s, md, _, mt, _ := initSVC()
+	s, md, _, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.Org{
 		ID:        "foo-id",
 		Name:      "foo-name",
@@ -295,7 +553,7 @@ func TestSVCSave_ID_DAOUpdateErr(t *testing.T) {
 
 	var expectedTX *sqlx.Tx
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetByID", ctx, o.ID).Return(org.Org{}, nil)
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{}, nil)
 	md.On("Update", ctx, expectedTX, mock.Anything).Return(org.Org{}, mockErr)
 
 	actual, err := s.Save(ctx, o)
@@ -305,10 +563,10 @@ func TestSVCSave_ID_DAOUpdateErr(t *testing.T) {
 }
 
 func TestSVCSave_ID_OrgNotFound(t *testing.T) {
-	s, md, _, mt, _ := initSVC()
+	s, md, _, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.Org{
 		ID:        "foo-id",
 		Name:      "foo-name",
@@ -325,7 +583,7 @@ func TestSVCSave_ID_OrgNotFound(t *testing.T) {
 	mockErr := ErrNotFound{
 		ID: o.ID,
 	}
-	md.On("GetByID", ctx, o.ID).Return(org.Org{}, mockErr)
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{}, mockErr)
 
 	actual, err := s.Save(ctx, o)
 
@@ -334,10 +592,10 @@ func TestSVCSave_ID_OrgNotFound(t *testing.T) {
 }
 
 func TestSVCSave_ID_SystemOrgNotAllowed(t *testing.T) {
-	s, md, _, mt, _ := initSVC()
+	s, md, _, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.Org{
 		ID:        "foo-id",
 		Name:      "foo-name",
@@ -351,7 +609,7 @@ func TestSVCSave_ID_SystemOrgNotAllowed(t *testing.T) {
 	now := time.UnixMilli(200)
 	mt.On("Now").Return(now)
 
-	md.On("GetByID", ctx, o.ID).Return(org.Org{IsSystem: true}, nil)
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{IsSystem: true}, nil)
 
 	actual, err := s.Save(ctx, o)
 
@@ -364,8 +622,61 @@ func TestSVCSave_ID_SystemOrgNotAllowed(t *testing.T) {
 	assert.Equal(t, org.Org{}, actual)
 }
 
+func TestSVCSave_NoID_OrgAdminForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-id", "role": authz.RoleOrgAdmin})
+
+	actual, err := s.Save(ctx, org.Org{Name: "foo-name", Desc: "foo-desc"})
+
+	assertForbidden(t, err)
+	assert.Equal(t, org.Org{}, actual)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSave_ID_OrgAdminOwnOrg(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	o := org.Org{
+		ID:      "foo-id",
+		Name:    "foo-name",
+		Desc:    "foo-desc",
+		Version: 1,
+	}
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"org_id": o.ID, "role": authz.RoleOrgAdmin})
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+
+	expectedOrg := o
+	expectedOrg.UpdatedAt = now
+	expectedOrg.UpdatedBy = loggedInUserID
+	var expectedTX *sqlx.Tx
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{}, nil)
+	md.On("Update", ctx, expectedTX, expectedOrg).Return(expectedOrg, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityOrg, o.ID, org.Org{}, expectedOrg).Return(nil)
+
+	actual, err := s.Save(ctx, o)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedOrg, actual)
+}
+
+func TestSVCSave_ID_MemberForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	o := org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 1}
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": o.ID, "role": authz.RoleMember})
+
+	_, err := s.Save(ctx, o)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestSVCSave_ErrIfNoAuditInfo(t *testing.T) {
-	s, _, _, _, _ := initSVC()
+	s, _, _, _, _, _ := initSVC()
 
 	ctx := context.Background()
 	o := org.Org{
@@ -386,28 +697,37 @@ func TestSVCSave_ErrIfNoAuditInfo(t *testing.T) {
 }
 
 func TestSVCDelete(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, ma, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.DeleteOrg{
 		ID:      "foo-id",
 		Version: 2,
 	}
 
 	var expectedTX *sqlx.Tx
-	md.On("GetByID", ctx, o.ID).Return(org.Org{}, nil)
-	md.On("Delete", ctx, expectedTX, o).Return(nil)
-
-	err := s.Delete(ctx, o)
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	orgInDB := org.Org{ID: o.ID, Name: "foo-name", Version: o.Version}
+	deleted := orgInDB
+	deleted.DeletedAt = &now
+	deleted.DeletedBy = loggedInUserID
+	md.On("GetByID", ctx, o.ID, false).Return(orgInDB, nil)
+	md.On("GetUsersForUpdate", ctx, expectedTX, o.ID).Return([]user.User{}, nil)
+	md.On("Delete", ctx, expectedTX, deleted).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityOrg, o.ID, orgInDB, nil).Return(nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{})
 	assert.Nil(t, err)
+	ma.AssertExpectations(t)
 }
 
 func TestSVCDelete_DAOErr(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.DeleteOrg{
 		ID:      "foo-id",
 		Version: 2,
@@ -415,18 +735,20 @@ func TestSVCDelete_DAOErr(t *testing.T) {
 
 	var expectedTX *sqlx.Tx
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetByID", ctx, o.ID).Return(org.Org{}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{}, nil)
+	md.On("GetUsersForUpdate", ctx, expectedTX, o.ID).Return([]user.User{}, nil)
 	md.On("Delete", ctx, expectedTX, mock.Anything).Return(mockErr)
 
-	err := s.Delete(ctx, o)
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{})
 	assert.Equal(t, mockErr, err)
 }
 
 func TestSVCDelete_OrgNotFound(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.DeleteOrg{
 		ID:      "foo-id",
 		Version: 2,
@@ -435,25 +757,25 @@ func TestSVCDelete_OrgNotFound(t *testing.T) {
 	mockErr := ErrNotFound{
 		ID: o.ID,
 	}
-	md.On("GetByID", ctx, o.ID).Return(org.Org{}, mockErr)
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{}, mockErr)
 
-	err := s.Delete(ctx, o)
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{})
 	assert.Equal(t, mockErr, err)
 }
 
 func TestSVCDelete_SystemOrgNotAllowed(t *testing.T) {
-	s, md, _, _, _ := initSVC()
+	s, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	o := org.DeleteOrg{
 		ID:      "foo-id",
 		Version: 2,
 	}
 
-	md.On("GetByID", ctx, o.ID).Return(org.Org{IsSystem: true}, nil)
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{IsSystem: true}, nil)
 
-	err := s.Delete(ctx, o)
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{})
 	assert.NotNil(t, err)
 	var sysOrgErr ErrCannotModifySysOrg
 	assert.True(t, errors.As(err, &sysOrgErr))
@@ -462,18 +784,559 @@ func TestSVCDelete_SystemOrgNotAllowed(t *testing.T) {
 	assert.Contains(t, err.Error(), o.ID)
 }
 
-func (d *mockDAO) GetByID(ctx context.Context, id string) (org.Org, error) {
-	args := d.Called(ctx, id)
+func TestSVCDelete_OrgAdminForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": o.ID, "role": authz.RoleOrgAdmin})
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_ErrIfNoAuditInfo(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	err := s.Delete(context.Background(), org.DeleteOrg{ID: "foo-id", Version: 2}, org.DeleteOrgOptions{})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, err.Error(), "user not logged in")
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_HasUsers(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	var expectedTX *sqlx.Tx
+	mt.On("Now").Return(time.UnixMilli(200))
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{ID: o.ID, Version: o.Version}, nil)
+	md.On("GetUsersForUpdate", ctx, expectedTX, o.ID).Return([]user.User{{ID: "user-1"}, {ID: "user-2"}}, nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{})
+
+	var hasUsers ErrOrgHasUsers
+	assert.True(t, errors.As(err, &hasUsers))
+	assert.Equal(t, 2, hasUsers.NumUsers)
+	assert.Contains(t, err.Error(), o.ID)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_Cascade(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	orgInDB := org.Org{ID: o.ID, Name: "foo-name", Version: o.Version}
+	users := []user.User{{ID: "user-1", OrgID: o.ID}, {ID: "user-2", OrgID: o.ID}}
+	md.On("GetByID", ctx, o.ID, false).Return(orgInDB, nil)
+	md.On("GetUsersForUpdate", ctx, expectedTX, o.ID).Return(users, nil)
+	md.On("DeleteUsers", ctx, expectedTX, o.ID, now, loggedInUserID).Return(int64(2), nil)
+	md.On("Delete", ctx, expectedTX, mock.Anything).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityUser, "user-1", users[0], nil).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityUser, "user-2", users[1], nil).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityOrg, o.ID, orgInDB, nil).Return(nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{Mode: org.DeleteModeCascade})
+
+	assert.Nil(t, err)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCDelete_Cascade_UserAddedConcurrently(t *testing.T) {
+	s, md, _, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	var expectedTX *sqlx.Tx
+	mt.On("Now").Return(time.UnixMilli(200))
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{ID: o.ID, Version: o.Version}, nil)
+	md.On("GetUsersForUpdate", ctx, expectedTX, o.ID).Return([]user.User{{ID: "user-1"}}, nil)
+	md.On("DeleteUsers", ctx, expectedTX, o.ID, mock.Anything, mock.Anything).Return(int64(2), nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{Mode: org.DeleteModeCascade})
+
+	var optLock ErrOptimisticLock
+	assert.True(t, errors.As(err, &optLock))
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_Reassign(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+	toOrgID := "bar-id"
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	orgInDB := org.Org{ID: o.ID, Name: "foo-name", Version: o.Version}
+	u := user.User{ID: "user-1", OrgID: o.ID, Version: 4}
+	moved := u
+	moved.OrgID = toOrgID
+	moved.UpdatedAt = now
+	moved.UpdatedBy = loggedInUserID
+	moved.Version = 5
+	md.On("GetByID", ctx, o.ID, false).Return(orgInDB, nil)
+	md.On("GetByID", ctx, toOrgID, false).Return(org.Org{ID: toOrgID}, nil)
+	md.On("GetUsersForUpdate", ctx, expectedTX, o.ID).Return([]user.User{u}, nil)
+	md.On("ReassignUsers", ctx, expectedTX, o.ID, toOrgID, now, loggedInUserID).Return(int64(1), nil)
+	md.On("Delete", ctx, expectedTX, mock.Anything).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, u.ID, u, moved).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityOrg, o.ID, orgInDB, nil).Return(nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{ReassignTo: toOrgID})
+
+	assert.Nil(t, err)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCDelete_ReassignTargetNotFound(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{ID: o.ID}, nil)
+	md.On("GetByID", ctx, "bar-id", false).Return(org.Org{}, ErrNotFound{ID: "bar-id"})
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{ReassignTo: "bar-id"})
+
+	var invalidTarget ErrInvalidReassignTarget
+	assert.True(t, errors.As(err, &invalidTarget))
+	assert.Equal(t, "bar-id", invalidTarget.ID)
+	md.AssertNotCalled(t, "GetUsersForUpdate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_ReassignToSysOrg(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{ID: o.ID}, nil)
+	md.On("GetByID", ctx, "sys-id", false).Return(org.Org{ID: "sys-id", IsSystem: true}, nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{ReassignTo: "sys-id"})
+
+	var invalidTarget ErrInvalidReassignTarget
+	assert.True(t, errors.As(err, &invalidTarget))
+}
+
+func TestSVCDelete_ReassignToSelf(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	md.On("GetByID", ctx, o.ID, false).Return(org.Org{ID: o.ID}, nil)
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{ReassignTo: o.ID})
+
+	var invalidTarget ErrInvalidReassignTarget
+	assert.True(t, errors.As(err, &invalidTarget))
+}
+
+func TestSVCDelete_InvalidMode(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{Mode: "everything"})
+
+	var invalidMode ErrInvalidDeleteMode
+	assert.True(t, errors.As(err, &invalidMode))
+	assert.Contains(t, err.Error(), "everything")
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_CascadeAndReassign(t *testing.T) {
+	s, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.DeleteOrg{ID: "foo-id", Version: 2}
+
+	err := s.Delete(ctx, o, org.DeleteOrgOptions{Mode: org.DeleteModeCascade, ReassignTo: "bar-id"})
+
+	var invalidMode ErrInvalidDeleteMode
+	assert.True(t, errors.As(err, &invalidMode))
+}
+
+func TestSVCGetByID_IncludeDeleted(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	id := "foo-id"
+	deletedAt := time.UnixMilli(100).UTC()
+
+	mockRes := org.Org{ID: id, Name: "foo-name", DeletedAt: &deletedAt, DeletedBy: "deleter-id"}
+	md.On("GetByID", ctx, id, true).Return(mockRes, nil)
+
+	actual, err := s.GetByID(ctx, id, true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByID_IncludeDeleted_OrgAdminForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": id, "role": authz.RoleOrgAdmin})
+
+	_, err := s.GetByID(ctx, id, true)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetAll_IncludeDeleted(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockRes := []org.Org{{ID: "foo-id", Name: "foo-name"}}
+	var after *page.Cursor
+	md.On("GetAll", ctx, true, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, "", true, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, org.OrgPage{Orgs: mockRes}, actual)
+}
+
+func TestSVCGetAll_IncludeDeleted_NonSystemAdminForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-id", "role": authz.RoleOrgAdmin})
+
+	_, err := s.GetAll(ctx, "", true, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	o := org.RestoreOrg{ID: "foo-id", Version: 3}
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	deletedAt := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	orgInDB := org.Org{ID: o.ID, Name: "foo-name", Version: o.Version, DeletedAt: &deletedAt, DeletedBy: "deleter-id"}
+	restored := orgInDB
+	restored.UpdatedAt = now
+	restored.UpdatedBy = loggedInUserID
+	mockRes := restored
+	mockRes.Version = 4
+	mockRes.DeletedAt = nil
+	mockRes.DeletedBy = ""
+	md.On("GetByID", ctx, o.ID, true).Return(orgInDB, nil)
+	md.On("Restore", ctx, expectedTX, restored).Return(mockRes, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionRestore, audit.EntityOrg, o.ID, orgInDB, mockRes).Return(nil)
+
+	actual, err := s.Restore(ctx, o)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCRestore_NotDeleted(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.RestoreOrg{ID: "foo-id", Version: 3}
+
+	orgInDB := org.Org{ID: o.ID, Name: "foo-name", Version: o.Version}
+	md.On("GetByID", ctx, o.ID, true).Return(orgInDB, nil)
+
+	actual, err := s.Restore(ctx, o)
+
+	assert.Nil(t, err)
+	assert.Equal(t, orgInDB, actual)
+	md.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore_DAOErr(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.RestoreOrg{ID: "foo-id", Version: 3}
+
+	var expectedTX *sqlx.Tx
+	deletedAt := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(time.UnixMilli(300))
+	mockErr := ErrOptimisticLock{ID: o.ID, Version: o.Version}
+	md.On("GetByID", ctx, o.ID, true).Return(org.Org{ID: o.ID, DeletedAt: &deletedAt}, nil)
+	md.On("Restore", ctx, expectedTX, mock.Anything).Return(org.Org{}, mockErr)
+
+	actual, err := s.Restore(ctx, o)
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, org.Org{}, actual)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore_NotFound(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.RestoreOrg{ID: "foo-id", Version: 3}
+
+	mockErr := ErrNotFound{ID: o.ID}
+	md.On("GetByID", ctx, o.ID, true).Return(org.Org{}, mockErr)
+
+	_, err := s.Restore(ctx, o)
+
+	assert.Equal(t, mockErr, err)
+}
+
+func TestSVCRestore_OrgAdminForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	o := org.RestoreOrg{ID: "foo-id", Version: 3}
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": o.ID, "role": authz.RoleOrgAdmin})
+
+	_, err := s.Restore(ctx, o)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func mergePatch(t *testing.T, body string) patch.Patch {
+	p, err := patch.Parse(pkgpatch.ContentTypeMerge, []byte(body))
+	assert.Nil(t, err)
+	return p
+}
+
+func patchOrgInDB() org.Org {
+	return org.Org{
+		ID:        "foo-id",
+		Name:      "foo-name",
+		Desc:      "foo-desc",
+		CreatedAt: time.UnixMilli(100).UTC(),
+		CreatedBy: "creator-id",
+		UpdatedAt: time.UnixMilli(100).UTC(),
+		UpdatedBy: "updater-id",
+		Version:   3,
+	}
+}
+
+func TestSVCPatch(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+
+	orgInDB := patchOrgInDB()
+	expectedOrg := orgInDB
+	expectedOrg.Desc = "bar-desc"
+	expectedOrg.UpdatedAt = now
+	expectedOrg.UpdatedBy = loggedInUserID
+	mockRes := expectedOrg
+	mockRes.Version = 4
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+	md.On("Update", ctx, expectedTX, expectedOrg).Return(mockRes, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityOrg, orgInDB.ID, orgInDB, mockRes).Return(nil)
+
+	actual, err := s.Patch(ctx, orgInDB.ID, 0, mergePatch(t, `{"desc":"bar-desc"}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCPatch_IfMatch(t *testing.T) {
+	s, md, ma, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mt.On("Now").Return(time.UnixMilli(200).UTC())
+
+	orgInDB := patchOrgInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+	md.On("Update", ctx, expectedTX, mock.Anything).Return(orgInDB, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityOrg, orgInDB.ID, orgInDB, orgInDB).Return(nil)
+
+	_, err := s.Patch(ctx, orgInDB.ID, orgInDB.Version, mergePatch(t, `{"name":"bar-name"}`))
+
+	assert.Nil(t, err)
+}
+
+func TestSVCPatch_IfMatchOptimisticLockErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	orgInDB := patchOrgInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+
+	_, err := s.Patch(ctx, orgInDB.ID, 2, mergePatch(t, `{"desc":"bar-desc"}`))
+
+	assert.Equal(t, ErrOptimisticLock{ID: orgInDB.ID, Version: 2}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_VersionOptimisticLockErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	orgInDB := patchOrgInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+
+	_, err := s.Patch(ctx, orgInDB.ID, 0, mergePatch(t, `{"desc":"bar-desc","version":2}`))
+
+	assert.Equal(t, ErrOptimisticLock{ID: orgInDB.ID, Version: 2}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_ReadOnlyErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	orgInDB := patchOrgInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+
+	_, err := s.Patch(ctx, orgInDB.ID, 0, mergePatch(t, `{"is_system":true,"created_by":"bar"}`))
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		validate.ReadOnly("created_by"),
+		validate.ReadOnly("is_system"),
+	}}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_ValidationErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	orgInDB := patchOrgInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+
+	_, err := s.Patch(ctx, orgInDB.ID, 0, mergePatch(t, `{"name":null}`))
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "name", Rule: "required", Message: "name is required"},
+	}}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_TestFailedErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	orgInDB := patchOrgInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, orgInDB.ID).Return(orgInDB, nil)
+	changes, err := patch.Parse(pkgpatch.ContentTypeJSON, []byte(`[{"op":"test","path":"/desc","value":"bar-desc"},{"op":"replace","path":"/desc","value":"baz-desc"}]`))
+	assert.Nil(t, err)
+
+	_, err = s.Patch(ctx, orgInDB.ID, 0, changes)
+
+	assert.Equal(t, patch.ErrTestFailed{Path: "/desc"}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_NotFoundErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockErr := ErrNotFound{ID: "foo-id"}
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, "foo-id").Return(org.Org{}, mockErr)
+
+	actual, err := s.Patch(ctx, "foo-id", 0, mergePatch(t, `{"desc":"bar-desc"}`))
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, org.Org{}, actual)
+}
+
+func TestSVCPatch_SystemOrgNotAllowed(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, "foo-id").Return(org.Org{ID: "foo-id", IsSystem: true}, nil)
+
+	_, err := s.Patch(ctx, "foo-id", 0, mergePatch(t, `{"desc":"bar-desc"}`))
+
+	assert.Equal(t, ErrCannotModifySysOrg{ID: "foo-id"}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_MemberForbidden(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-id", "role": authz.RoleMember})
+
+	_, err := s.Patch(ctx, "foo-id", 0, mergePatch(t, `{"desc":"bar-desc"}`))
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_ErrIfNoAuditInfo(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	_, err := s.Patch(context.Background(), "foo-id", 0, mergePatch(t, `{"desc":"bar-desc"}`))
+
+	assert.NotNil(t, err)
+	md.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (d *mockDAO) GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error) {
+	args := d.Called(ctx, id, includeDeleted)
 	return args.Get(0).(org.Org), args.Error(1)
 }
 
-func (d *mockDAO) GetAll(ctx context.Context) ([]org.Org, error) {
-	args := d.Called(ctx)
+func (d *mockDAO) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (org.Org, error) {
+	args := d.Called(ctx, tx, id)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (d *mockDAO) GetAll(ctx context.Context, includeDeleted bool, after *page.Cursor, limit int) ([]org.Org, error) {
+	args := d.Called(ctx, includeDeleted, after, limit)
 	return args.Get(0).([]org.Org), args.Error(1)
 }
 
-func (d *mockDAO) SearchByName(ctx context.Context, name string) ([]org.Org, error) {
-	args := d.Called(ctx, name)
+func (d *mockDAO) SearchByName(ctx context.Context, name string, includeDeleted bool, after *page.Cursor, limit int) ([]org.Org, error) {
+	args := d.Called(ctx, name, includeDeleted, after, limit)
 	return args.Get(0).([]org.Org), args.Error(1)
 }
 
@@ -487,13 +1350,43 @@ func (d *mockDAO) Update(ctx context.Context, tx *sqlx.Tx, o org.Org) (org.Org,
 	return args.Get(0).(org.Org), args.Error(1)
 }
 
-func (d *mockDAO) Delete(ctx context.Context, tx *sqlx.Tx, o org.DeleteOrg) error {
+func (d *mockDAO) Delete(ctx context.Context, tx *sqlx.Tx, o org.Org) error {
 	args := d.Called(ctx, tx, o)
 	return args.Error(0)
 }
 
+func (d *mockDAO) Restore(ctx context.Context, tx *sqlx.Tx, o org.Org) (org.Org, error) {
+	args := d.Called(ctx, tx, o)
+	return args.Get(0).(org.Org), args.Error(1)
+}
+
+func (d *mockDAO) GetUsersForUpdate(ctx context.Context, tx *sqlx.Tx, orgID string) ([]user.User, error) {
+	args := d.Called(ctx, tx, orgID)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
+func (d *mockDAO) DeleteUsers(ctx context.Context, tx *sqlx.Tx, orgID string, deletedAt time.Time, deletedBy string) (int64, error) {
+	args := d.Called(ctx, tx, orgID, deletedAt, deletedBy)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *mockDAO) ReassignUsers(ctx context.Context, tx *sqlx.Tx, orgID string, toOrgID string, updatedAt time.Time, updatedBy string) (int64, error) {
+	args := d.Called(ctx, tx, orgID, toOrgID, updatedAt, updatedBy)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (d *mockDAO) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := d.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockAuditor) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error {
+	args := m.Called(ctx, tx, action, entityType, entityID, before, after)
+	return args.Error(0)
+}
+
 func (m *mockTXManager) Do(ctx context.Context, tx *sqlx.Tx, f func(tx *sqlx.Tx) error) error {
-	return f(nil)
+	return f(tx)
 }
 
 func (t *mockTimer) Now() time.Time {
@@ -505,3 +1398,60 @@ func (t *mockIDGen) GenID() string {
 	args := t.Called()
 	return args.String(0)
 }
+
+func TestSVCExport(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockRes := []org.Org{
+		{ID: "foo-id", Name: "foo-name"},
+		{ID: "bar-id", Name: "bar-name"},
+	}
+	var after *page.Cursor
+	md.On("GetAll", ctx, false, after, page.MaxLimit+1).Return(mockRes, nil)
+
+	var actual []org.Org
+	for o, err := range s.Export(ctx) {
+		assert.Nil(t, err)
+		actual = append(actual, o)
+	}
+
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCExport_NonSystemAdminOnlyGetsOwnOrg(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": id, "role": authz.RoleMember})
+
+	mockRes := org.Org{ID: id, Name: "foo-name"}
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
+
+	var actual []org.Org
+	for o, err := range s.Export(ctx) {
+		assert.Nil(t, err)
+		actual = append(actual, o)
+	}
+
+	assert.Equal(t, []org.Org{mockRes}, actual)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCExport_DAOErr(t *testing.T) {
+	s, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockErr := errors.New("unit-test mock error")
+	var after *page.Cursor
+	md.On("GetAll", ctx, false, after, page.MaxLimit+1).Return([]org.Org{}, mockErr)
+
+	var errs []error
+	for _, err := range s.Export(ctx) {
+		errs = append(errs, err)
+	}
+
+	assert.Equal(t, []error{mockErr}, errs)
+}
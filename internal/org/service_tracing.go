@@ -0,0 +1,81 @@
+package org
+
+import (
+	"context"
+	"iter"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/jmoiron/sqlx"
+)
+
+// tracedService wraps every OrgService call in a span.
+type tracedService struct {
+	svc OrgService
+}
+
+func NewTracedService(svc OrgService) *tracedService {
+	return &tracedService{
+		svc: svc,
+	}
+}
+
+func (s tracedService) GetByID(ctx context.Context, id string, includeDeleted bool) (o org.Org, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.GetByID")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetByID(ctx, id, includeDeleted)
+}
+
+func (s tracedService) GetAll(ctx context.Context, name string, includeDeleted bool, pr page.Request) (op org.OrgPage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.GetAll")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetAll(ctx, name, includeDeleted, pr)
+}
+
+func (s tracedService) Save(ctx context.Context, input org.Org) (o org.Org, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.Save")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Save(ctx, input)
+}
+
+func (s tracedService) Patch(ctx context.Context, id string, version int64, changes patch.Patch) (o org.Org, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.Patch")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Patch(ctx, id, version, changes)
+}
+
+func (s tracedService) Create(ctx context.Context, joinTX *sqlx.Tx, input org.Org) (o org.Org, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.Create")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Create(ctx, joinTX, input)
+}
+
+func (s tracedService) Delete(ctx context.Context, o org.DeleteOrg, opts org.DeleteOrgOptions) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.Delete")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Delete(ctx, o, opts)
+}
+
+func (s tracedService) Restore(ctx context.Context, input org.RestoreOrg) (o org.Org, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.Restore")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Restore(ctx, input)
+}
+
+// Export's span covers the whole iteration rather than the call that sets it
+// up.
+func (s tracedService) Export(ctx context.Context) iter.Seq2[org.Org, error] {
+	return func(yield func(org.Org, error) bool) {
+		ctx, span := tracing.Tracer().Start(ctx, "OrgSVC.Export")
+		var err error
+		defer func() { tracing.End(span, err) }()
+		for o, rowErr := range s.svc.Export(ctx) {
+			err = rowErr
+			if !yield(o, rowErr) {
+				return
+			}
+		}
+	}
+}
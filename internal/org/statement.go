@@ -1,5 +1,7 @@
 package org
 
+// Every read takes an include deleted flag, soft deleted orgs are filtered
+// out unless it's true.
 const getByIDQuery = `
 	SELECT
 		o.id,
@@ -10,11 +12,37 @@ const getByIDQuery = `
 		o.created_by,
 		o.updated_at,
 		o.updated_by,
-		o.version
+		o.version,
+		o.deleted_at,
+		COALESCE(o.deleted_by, '') AS deleted_by
 	FROM orgs o
 	WHERE o.id = $1
+	AND ($2::BOOLEAN OR o.deleted_at IS NULL)
 `
 
+// Locks the row so a PATCH can't lose an update that lands between reading
+// the org and writing the patched org.
+const getByIDForUpdateQuery = `
+	SELECT
+		o.id,
+		o.name,
+		o.description,
+		o.is_system,
+		o.created_at,
+		o.created_by,
+		o.updated_at,
+		o.updated_by,
+		o.version,
+		o.deleted_at,
+		COALESCE(o.deleted_by, '') AS deleted_by
+	FROM orgs o
+	WHERE o.id = $1
+	AND o.deleted_at IS NULL
+	FOR UPDATE
+`
+
+// The keyset queries follow the ORDER BY of the first page queries, the
+// id is only there as a tie breaker so the cursor is always unique.
 const getAllQuery = `
 	SELECT
 		o.id,
@@ -25,9 +53,37 @@ const getAllQuery = `
 		o.created_by,
 		o.updated_at,
 		o.updated_by,
-		o.version
+		o.version,
+		o.deleted_at,
+		COALESCE(o.deleted_by, '') AS deleted_by
+	FROM orgs o
+	WHERE ($2::BOOLEAN OR o.deleted_at IS NULL)
+	ORDER BY o.name ASC, o.created_at DESC, o.id ASC
+	LIMIT $1
+`
+
+const getAllAfterQuery = `
+	SELECT
+		o.id,
+		o.name,
+		o.description,
+		o.is_system,
+		o.created_at,
+		o.created_by,
+		o.updated_at,
+		o.updated_by,
+		o.version,
+		o.deleted_at,
+		COALESCE(o.deleted_by, '') AS deleted_by
 	FROM orgs o
-	ORDER BY o.name ASC, o.created_at DESC
+	WHERE (
+		o.name > $1
+		OR (o.name = $1 AND o.created_at < $2)
+		OR (o.name = $1 AND o.created_at = $2 AND o.id > $3)
+	)
+	AND ($5::BOOLEAN OR o.deleted_at IS NULL)
+	ORDER BY o.name ASC, o.created_at DESC, o.id ASC
+	LIMIT $4
 `
 
 const searchByNameQuery = `
@@ -40,10 +96,39 @@ const searchByNameQuery = `
 		o.created_by,
 		o.updated_at,
 		o.updated_by,
-		o.version
+		o.version,
+		o.deleted_at,
+		COALESCE(o.deleted_by, '') AS deleted_by
+	FROM orgs o
+	WHERE LOWER(o.name) LIKE $1
+	AND ($3::BOOLEAN OR o.deleted_at IS NULL)
+	ORDER BY o.name ASC, o.created_at DESC, o.id ASC
+	LIMIT $2
+`
+
+const searchByNameAfterQuery = `
+	SELECT
+		o.id,
+		o.name,
+		o.description,
+		o.is_system,
+		o.created_at,
+		o.created_by,
+		o.updated_at,
+		o.updated_by,
+		o.version,
+		o.deleted_at,
+		COALESCE(o.deleted_by, '') AS deleted_by
 	FROM orgs o
 	WHERE LOWER(o.name) LIKE $1
-	ORDER BY o.name ASC, o.created_at DESC
+	AND (
+		o.name > $2
+		OR (o.name = $2 AND o.created_at < $3)
+		OR (o.name = $2 AND o.created_at = $3 AND o.id > $4)
+	)
+	AND ($6::BOOLEAN OR o.deleted_at IS NULL)
+	ORDER BY o.name ASC, o.created_at DESC, o.id ASC
+	LIMIT $5
 `
 
 const createQuery = `
@@ -77,10 +162,83 @@ const updateQuery = `
 		version = 1 + :version
 	WHERE id = :id
 	AND version = :version
+	AND deleted_at IS NULL
 `
 
+// Deleting bumps the version so a stale client can't restore (or delete
+// again) without re-reading first.
 const deleteQuery = `
-	DELETE FROM orgs
+	UPDATE orgs SET
+		deleted_at = :deleted_at,
+		deleted_by = :deleted_by,
+		version = 1 + :version
+	WHERE id = :id
+	AND version = :version
+	AND deleted_at IS NULL
+`
+
+const restoreQuery = `
+	UPDATE orgs SET
+		deleted_at = NULL,
+		deleted_by = NULL,
+		updated_at = :updated_at,
+		updated_by = :updated_by,
+		version = 1 + :version
 	WHERE id = :id
 	AND version = :version
+	AND deleted_at IS NOT NULL
+`
+
+// The org's users are locked so none can be modified while the org is being
+// deleted.
+const getUsersForUpdateQuery = `
+	SELECT
+		u.id,
+		u.org_id,
+		u.name,
+		u.email,
+		u.is_system,
+		u.is_admin,
+		u.is_active,
+		u.created_at,
+		u.created_by,
+		u.updated_at,
+		u.updated_by,
+		u.version
+	FROM users u
+	WHERE u.org_id = $1
+	AND u.deleted_at IS NULL
+	ORDER BY u.id
+	FOR UPDATE
+`
+
+const deleteUsersQuery = `
+	UPDATE users SET
+		deleted_at = $2,
+		deleted_by = $3,
+		version = 1 + version
+	WHERE org_id = $1
+	AND deleted_at IS NULL
+`
+
+const reassignUsersQuery = `
+	UPDATE users SET
+		org_id = $2,
+		updated_at = $3,
+		updated_by = $4,
+		version = 1 + version
+	WHERE org_id = $1
+	AND deleted_at IS NULL
+`
+
+// Orgs that still have users (deleted or not) are left for a later run, the
+// users are purged first so they're normally gone by then.
+const purgeQuery = `
+	DELETE FROM orgs o
+	WHERE o.deleted_at < $1
+	AND NOT EXISTS (
+		SELECT 1
+		FROM users u
+		WHERE u.org_id = o.id
+	)
 `
@@ -0,0 +1,31 @@
+package page
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrInvalidLimit struct {
+	Limit string
+}
+
+func (err ErrInvalidLimit) Error() string {
+	return fmt.Sprintf("Invalid limit, must be a positive integer: limit=%s", err.Limit)
+}
+
+func (err ErrInvalidLimit) Code() string {
+	return problem.CodeInvalidLimit
+}
+
+type ErrInvalidCursor struct {
+	Cursor string
+}
+
+func (err ErrInvalidCursor) Error() string {
+	return fmt.Sprintf("Invalid cursor: cursor=%s", err.Cursor)
+}
+
+func (err ErrInvalidCursor) Code() string {
+	return problem.CodeInvalidCursor
+}
@@ -0,0 +1,114 @@
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"iter"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Cursor is the keyset position of the last row returned, it is handed to
+// callers as an opaque string and decoded on the next request.
+type Cursor struct {
+	Key       string    `json:"k"`
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+type Request struct {
+	Limit int
+	After *Cursor
+}
+
+func ParseRequest(limitStr string, cursorStr string) (pr Request, err error) {
+	pr.Limit = DefaultLimit
+	if limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return pr, ErrInvalidLimit{Limit: limitStr}
+		}
+		pr.Limit = min(limit, MaxLimit)
+	}
+	if cursorStr != "" {
+		c, err := DecodeCursor(cursorStr)
+		if err != nil {
+			return pr, err
+		}
+		pr.After = &c
+	}
+	return pr, nil
+}
+
+// NewRequest is ParseRequest for a limit that's already a number (ex. from a
+// gRPC request), 0 is the default limit.
+func NewRequest(limit int, cursorStr string) (Request, error) {
+	if limit == 0 {
+		return ParseRequest("", cursorStr)
+	}
+	return ParseRequest(strconv.Itoa(limit), cursorStr)
+}
+
+func EncodeCursor(c Cursor) string {
+	// marshalling a struct of strings and a time can't fail
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (c Cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor{Cursor: s}
+	}
+	if err = json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor{Cursor: s}
+	}
+	return c, nil
+}
+
+// Trim expects items to have been queried with limit+1 so it can tell whether
+// there is another page without a separate count query.
+func Trim[T any](items []T, limit int, toCursor func(T) Cursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, EncodeCursor(toCursor(items[limit-1]))
+}
+
+// All yields every item across pages of size limit, one page at a time so the
+// whole result set is never in memory. A failed page is yielded once as an
+// error and ends the iteration.
+func All[T any](limit int, getPage func(pr Request) ([]T, string, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		pr := Request{Limit: limit}
+		for {
+			items, nextCursor, err := getPage(pr)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if nextCursor == "" {
+				return
+			}
+			c, err := DecodeCursor(nextCursor)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			pr.After = &c
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package page
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequest_Defaults(t *testing.T) {
+	actual, err := ParseRequest("", "")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultLimit, actual.Limit)
+	assert.Nil(t, actual.After)
+}
+
+func TestParseRequest_Limit(t *testing.T) {
+	actual, err := ParseRequest("10", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, actual.Limit)
+}
+
+func TestParseRequest_LimitCappedAtMax(t *testing.T) {
+	actual, err := ParseRequest("100000", "")
+	assert.Nil(t, err)
+	assert.Equal(t, MaxLimit, actual.Limit)
+}
+
+func TestParseRequest_InvalidLimit(t *testing.T) {
+	for _, limit := range []string{"foo", "0", "-1"} {
+		_, err := ParseRequest(limit, "")
+		var expected ErrInvalidLimit
+		assert.True(t, errors.As(err, &expected))
+		assert.Contains(t, err.Error(), limit)
+	}
+}
+
+func TestNewRequest_Defaults(t *testing.T) {
+	actual, err := NewRequest(0, "")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultLimit, actual.Limit)
+}
+
+func TestNewRequest_Limit(t *testing.T) {
+	actual, err := NewRequest(10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, actual.Limit)
+}
+
+func TestNewRequest_InvalidLimit(t *testing.T) {
+	_, err := NewRequest(-1, "")
+	var expected ErrInvalidLimit
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestParseRequest_Cursor(t *testing.T) {
+	c := Cursor{
+		Key:       "foo@bar.com",
+		CreatedAt: time.UnixMilli(100).UTC(),
+		ID:        "foo-id",
+	}
+	actual, err := ParseRequest("", EncodeCursor(c))
+	assert.Nil(t, err)
+	assert.Equal(t, &c, actual.After)
+}
+
+func TestParseRequest_InvalidCursor(t *testing.T) {
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, err := ParseRequest("", cursor)
+		var expected ErrInvalidCursor
+		assert.True(t, errors.As(err, &expected))
+		assert.Contains(t, err.Error(), cursor)
+	}
+}
+
+func TestTrim_LastPage(t *testing.T) {
+	items, next := Trim([]string{"a", "b"}, 2, func(s string) Cursor {
+		return Cursor{ID: s}
+	})
+	assert.Equal(t, []string{"a", "b"}, items)
+	assert.Equal(t, "", next)
+}
+
+func TestTrim_MorePages(t *testing.T) {
+	items, next := Trim([]string{"a", "b", "c"}, 2, func(s string) Cursor {
+		return Cursor{ID: s}
+	})
+	assert.Equal(t, []string{"a", "b"}, items)
+	c, err := DecodeCursor(next)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", c.ID)
+}
+
+func TestAll(t *testing.T) {
+	c := Cursor{Key: "b", CreatedAt: time.UnixMilli(100).UTC(), ID: "b-id"}
+	var requests []Request
+	getPage := func(pr Request) ([]string, string, error) {
+		requests = append(requests, pr)
+		if pr.After == nil {
+			return []string{"a", "b"}, EncodeCursor(c), nil
+		}
+		return []string{"c"}, "", nil
+	}
+
+	var actual []string
+	for item, err := range All(2, getPage) {
+		assert.Nil(t, err)
+		actual = append(actual, item)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, actual)
+	assert.Equal(t, []Request{{Limit: 2}, {Limit: 2, After: &c}}, requests)
+}
+
+func TestAll_Err(t *testing.T) {
+	mockErr := errors.New("unit-test mock error")
+	getPage := func(pr Request) ([]string, string, error) {
+		return nil, "", mockErr
+	}
+
+	var errs []error
+	for _, err := range All(2, getPage) {
+		errs = append(errs, err)
+	}
+
+	assert.Equal(t, []error{mockErr}, errs)
+}
+
+func TestAll_StopEarly(t *testing.T) {
+	calls := 0
+	getPage := func(pr Request) ([]string, string, error) {
+		calls++
+		return []string{"a", "b"}, EncodeCursor(Cursor{ID: "b-id"}), nil
+	}
+
+	for item := range All(2, getPage) {
+		if item == "a" {
+			break
+		}
+	}
+
+	assert.Equal(t, 1, calls)
+}
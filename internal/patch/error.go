@@ -0,0 +1,44 @@
+package patch
+
+import (
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrUnsupported struct {
+	ContentType string
+}
+
+func (err ErrUnsupported) Error() string {
+	return fmt.Sprintf("Unsupported patch, content type must be %s or %s: contentType=%s", patch.ContentTypeMerge, patch.ContentTypeJSON, err.ContentType)
+}
+
+func (err ErrUnsupported) Code() string {
+	return problem.CodeUnsupportedPatch
+}
+
+type ErrMalformed struct {
+	Reason string
+}
+
+func (err ErrMalformed) Error() string {
+	return fmt.Sprintf("Malformed patch: %s", err.Reason)
+}
+
+func (err ErrMalformed) Code() string {
+	return problem.CodeMalformedPatch
+}
+
+type ErrTestFailed struct {
+	Path string
+}
+
+func (err ErrTestFailed) Error() string {
+	return fmt.Sprintf("Patch test failed, the value doesn't match: path=%s", err.Path)
+}
+
+func (err ErrTestFailed) Code() string {
+	return problem.CodePatchTestFailed
+}
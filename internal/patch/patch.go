@@ -0,0 +1,374 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+)
+
+// Patch is the body of a PATCH, it's only made by Parse so it's always one of
+// the supported content types and well formed.
+type Patch struct {
+	ContentType string
+	Body        []byte
+}
+
+// op is one operation of a json patch, Path and From are pointers so a
+// missing one can be told apart from the root ("") and a missing Value from
+// null.
+type op struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Parse checks the body is a merge patch object or a list of json patch ops,
+// it doesn't know what it'll be applied to yet so bad paths are only found by
+// Apply.
+func Parse(contentType string, body []byte) (Patch, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Patch{}, ErrUnsupported{ContentType: contentType}
+	}
+	p := Patch{ContentType: mediaType, Body: body}
+	switch mediaType {
+	case patch.ContentTypeMerge:
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return Patch{}, ErrMalformed{Reason: err.Error()}
+		}
+	case patch.ContentTypeJSON:
+		if _, err := p.ops(); err != nil {
+			return Patch{}, err
+		}
+	default:
+		return Patch{}, ErrUnsupported{ContentType: contentType}
+	}
+	return p, nil
+}
+
+func (p Patch) ops() ([]op, error) {
+	var ops []op
+	if err := json.Unmarshal(p.Body, &ops); err != nil {
+		return nil, ErrMalformed{Reason: err.Error()}
+	}
+	for i, o := range ops {
+		if err := o.check(); err != nil {
+			return nil, ErrMalformed{Reason: fmt.Sprintf("ops[%d] %v", i, err)}
+		}
+	}
+	return ops, nil
+}
+
+func (o op) check() error {
+	if o.Path == nil {
+		return errors.New("is missing its path")
+	}
+	switch o.Op {
+	case patch.OpAdd, patch.OpReplace, patch.OpTest:
+		if o.Value == nil {
+			return errors.New("is missing its value")
+		}
+	case patch.OpMove, patch.OpCopy:
+		if o.From == nil {
+			return errors.New("is missing its from")
+		}
+	case patch.OpRemove:
+	default:
+		return fmt.Errorf("has an unknown op %q", o.Op)
+	}
+	return nil
+}
+
+// Apply patches doc (a json document), doc isn't changed. A json patch is all
+// or nothing, an op that fails leaves nothing applied.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	if p.ContentType == patch.ContentTypeMerge {
+		var mp any
+		if err := json.Unmarshal(p.Body, &mp); err != nil {
+			return nil, ErrMalformed{Reason: err.Error()}
+		}
+		return json.Marshal(merge(v, mp))
+	}
+	ops, err := p.ops()
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range ops {
+		if v, err = o.apply(v); err != nil {
+			var testFailed ErrTestFailed
+			if errors.As(err, &testFailed) {
+				return nil, err
+			}
+			return nil, ErrMalformed{Reason: fmt.Sprintf("ops[%d] %s %s: %v", i, o.Op, *o.Path, err)}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// To applies p to v, only the members in writable (top level json names, ex.
+// name) can be changed. Changing any other one is a validate.ErrInvalid with
+// a violation per member.
+func To[T any](p Patch, v T, writable ...string) (out T, err error) {
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return out, err
+	}
+	patched, err := p.Apply(doc)
+	if err != nil {
+		return out, err
+	}
+	var before, after map[string]any
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return out, ErrMalformed{Reason: "the patched document isn't an object"}
+	}
+	if err := readOnly(before, after, writable); err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(patched, &out); err != nil {
+		return out, ErrMalformed{Reason: err.Error()}
+	}
+	return out, nil
+}
+
+func readOnly(before map[string]any, after map[string]any, writable []string) error {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, found := before[name]; !found {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	var vs validate.Violations
+	for _, name := range names {
+		if !slices.Contains(writable, name) && !reflect.DeepEqual(before[name], after[name]) {
+			vs = append(vs, validate.ReadOnly(name))
+		}
+	}
+	return vs.Err()
+}
+
+// merge is RFC 7396, target is changed in place.
+func merge(target any, mp any) any {
+	members, ok := mp.(map[string]any)
+	if !ok {
+		return mp
+	}
+	obj, ok := target.(map[string]any)
+	if !ok {
+		obj = map[string]any{}
+	}
+	for name, v := range members {
+		if v == nil {
+			delete(obj, name)
+		} else {
+			obj[name] = merge(obj[name], v)
+		}
+	}
+	return obj
+}
+
+// apply is one op of RFC 6902, doc is changed in place.
+func (o op) apply(doc any) (any, error) {
+	path, err := parsePointer(*o.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if o.Value != nil {
+		if err := json.Unmarshal(o.Value, &value); err != nil {
+			return nil, err
+		}
+	}
+	switch o.Op {
+	case patch.OpAdd:
+		return add(doc, path, value)
+	case patch.OpRemove:
+		return remove(doc, path)
+	case patch.OpReplace:
+		return modify(doc, path, func(any) (any, error) {
+			return value, nil
+		})
+	case patch.OpTest:
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(actual, value) {
+			return nil, ErrTestFailed{Path: *o.Path}
+		}
+		return doc, nil
+	}
+	from, err := parsePointer(*o.From)
+	if err != nil {
+		return nil, err
+	}
+	value, err = get(doc, from)
+	if err != nil {
+		return nil, err
+	}
+	if o.Op == patch.OpMove {
+		if len(from) < len(path) && slices.Equal(from, path[:len(from)]) {
+			return nil, errors.New("can't move a value into itself")
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return add(doc, path, deepCopy(value))
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens, the
+// root ("") has none.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc any, path []string) (v any, err error) {
+	_, err = modify(doc, path, func(found any) (any, error) {
+		v = found
+		return found, nil
+	})
+	return v, err
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	last := path[len(path)-1]
+	return modify(doc, path[:len(path)-1], func(parent any) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[last] = value
+			return p, nil
+		case []any:
+			if last == "-" {
+				return append(p, value), nil
+			}
+			i, err := index(last, len(p)+1)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(p, i, value), nil
+		}
+		return nil, errors.New("parent isn't an object or array")
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("can't remove the whole document")
+	}
+	last := path[len(path)-1]
+	return modify(doc, path[:len(path)-1], func(parent any) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, found := p[last]; !found {
+				return nil, errors.New("path not found")
+			}
+			delete(p, last)
+			return p, nil
+		case []any:
+			i, err := index(last, len(p))
+			if err != nil {
+				return nil, err
+			}
+			return slices.Delete(p, i, i+1), nil
+		}
+		return nil, errors.New("parent isn't an object or array")
+	})
+}
+
+// modify replaces the value at path with what f returns for it, the value
+// has to exist.
+func modify(doc any, path []string, f func(any) (any, error)) (any, error) {
+	if len(path) == 0 {
+		return f(doc)
+	}
+	switch d := doc.(type) {
+	case map[string]any:
+		child, found := d[path[0]]
+		if !found {
+			return nil, errors.New("path not found")
+		}
+		v, err := modify(child, path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		d[path[0]] = v
+		return d, nil
+	case []any:
+		i, err := index(path[0], len(d))
+		if err != nil {
+			return nil, err
+		}
+		v, err := modify(d[i], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = v
+		return d, nil
+	}
+	return nil, errors.New("path not found")
+}
+
+// index is an array index that's less than n, leading zeros aren't allowed.
+func index(token string, n int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i >= n {
+		return 0, fmt.Errorf("array index %d out of bounds", i)
+	}
+	return i, nil
+}
+
+func deepCopy(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(t))
+		for name, child := range t {
+			c[name] = deepCopy(child)
+		}
+		return c
+	case []any:
+		c := make([]any, len(t))
+		for i, child := range t {
+			c[i] = deepCopy(child)
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,228 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParse(t *testing.T, contentType string, body string) Patch {
+	p, err := Parse(contentType, []byte(body))
+	assert.Nil(t, err)
+	return p
+}
+
+func TestParse_Merge(t *testing.T) {
+	p, err := Parse("application/merge-patch+json; charset=utf-8", []byte(`{"name":"foo"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, Patch{ContentType: patch.ContentTypeMerge, Body: []byte(`{"name":"foo"}`)}, p)
+}
+
+func TestParse_JSON(t *testing.T) {
+	p, err := Parse(patch.ContentTypeJSON, []byte(`[{"op":"remove","path":"/name"}]`))
+	assert.Nil(t, err)
+	assert.Equal(t, patch.ContentTypeJSON, p.ContentType)
+}
+
+func TestParse_Unsupported(t *testing.T) {
+	_, err := Parse("application/json", []byte(`{}`))
+	var unsupported ErrUnsupported
+	assert.True(t, errors.As(err, &unsupported))
+	assert.Equal(t, problem.CodeUnsupportedPatch, unsupported.Code())
+}
+
+func TestParse_UnsupportedMissing(t *testing.T) {
+	_, err := Parse("", []byte(`{}`))
+	var unsupported ErrUnsupported
+	assert.True(t, errors.As(err, &unsupported))
+	assert.Equal(t, problem.CodeUnsupportedPatch, unsupported.Code())
+}
+
+func TestParse_UnsupportedInvalid(t *testing.T) {
+	_, err := Parse("foo;;", []byte(`{}`))
+	var unsupported ErrUnsupported
+	assert.True(t, errors.As(err, &unsupported))
+	assert.Equal(t, problem.CodeUnsupportedPatch, unsupported.Code())
+}
+
+func TestParse_MalformedMerge(t *testing.T) {
+	_, err := Parse(patch.ContentTypeMerge, []byte(`{"name":`))
+	var malformed ErrMalformed
+	assert.True(t, errors.As(err, &malformed))
+	assert.Equal(t, problem.CodeMalformedPatch, malformed.Code())
+}
+
+func TestParse_JSONNotList(t *testing.T) {
+	_, err := Parse(patch.ContentTypeJSON, []byte(`{"op":"remove","path":"/name"}`))
+	assert.Equal(t, ErrMalformed{Reason: "json: cannot unmarshal object into Go value of type []patch.op"}, err)
+}
+
+func TestParse_JSONMissingPath(t *testing.T) {
+	_, err := Parse(patch.ContentTypeJSON, []byte(`[{"op":"remove"}]`))
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] is missing its path"}, err)
+}
+
+func TestParse_JSONMissingValue(t *testing.T) {
+	_, err := Parse(patch.ContentTypeJSON, []byte(`[{"op":"add","path":"/name"}]`))
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] is missing its value"}, err)
+}
+
+func TestParse_JSONMissingFrom(t *testing.T) {
+	_, err := Parse(patch.ContentTypeJSON, []byte(`[{"op":"test","path":"","value":1},{"op":"copy","path":"/name"}]`))
+	assert.Equal(t, ErrMalformed{Reason: "ops[1] is missing its from"}, err)
+}
+
+func TestParse_JSONUnknownOp(t *testing.T) {
+	_, err := Parse(patch.ContentTypeJSON, []byte(`[{"op":"foo","path":"/name"}]`))
+	assert.Equal(t, ErrMalformed{Reason: `ops[0] has an unknown op "foo"`}, err)
+}
+
+func TestApply_Merge(t *testing.T) {
+	// the example from RFC 7396
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	p := mustParse(t, patch.ContentTypeMerge, `{"title":"Hello!","phoneNumber":"+01-555-555-5555","author":{"familyName":null},"tags":["example"]}`)
+
+	b, err := p.Apply([]byte(doc))
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-555-555-5555"}`, string(b))
+}
+
+func TestApply_MergeNotObject(t *testing.T) {
+	p := mustParse(t, patch.ContentTypeMerge, `["foo"]`)
+
+	b, err := p.Apply([]byte(`{"foo":"bar"}`))
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `["foo"]`, string(b))
+}
+
+func TestApply_JSON(t *testing.T) {
+	doc := `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"},"arr":[1,2,3]}`
+	p := mustParse(t, patch.ContentTypeJSON, `[
+		{"op":"test","path":"/foo/bar","value":"baz"},
+		{"op":"add","path":"/arr/1","value":"x"},
+		{"op":"add","path":"/arr/-","value":null},
+		{"op":"remove","path":"/arr/0"},
+		{"op":"replace","path":"/qux/corge","value":{"a":1}},
+		{"op":"move","from":"/foo/waldo","path":"/qux/thud"},
+		{"op":"copy","from":"/qux/corge","path":"/copied"},
+		{"op":"add","path":"/a~1b~0c","value":true}
+	]`)
+
+	b, err := p.Apply([]byte(doc))
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"foo":{"bar":"baz"},"qux":{"corge":{"a":1},"thud":"fred"},"arr":["x",2,3,null],"copied":{"a":1},"a/b~c":true}`, string(b))
+}
+
+func TestApply_JSONRoot(t *testing.T) {
+	p := mustParse(t, patch.ContentTypeJSON, `[{"op":"replace","path":"","value":{"foo":"bar"}}]`)
+
+	b, err := p.Apply([]byte(`{"baz":"qux"}`))
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"foo":"bar"}`, string(b))
+}
+
+func TestApply_JSONTestFailed(t *testing.T) {
+	p := mustParse(t, patch.ContentTypeJSON, `[{"op":"replace","path":"/name","value":"bar"},{"op":"test","path":"/version","value":2}]`)
+
+	_, err := p.Apply([]byte(`{"name":"foo","version":3}`))
+
+	var testFailed ErrTestFailed
+	assert.True(t, errors.As(err, &testFailed))
+	assert.Equal(t, "/version", testFailed.Path)
+	assert.Equal(t, problem.CodePatchTestFailed, testFailed.Code())
+}
+
+// applyMalformed applies ops to a doc with an object, an array and a string.
+func applyMalformed(t *testing.T, ops string) error {
+	p := mustParse(t, patch.ContentTypeJSON, ops)
+	_, err := p.Apply([]byte(`{"name":"foo","arr":[1,2,3],"obj":{}}`))
+	return err
+}
+
+func TestApply_JSONReplaceNotFound(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"replace","path":"/missing","value":1}]`)
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] replace /missing: path not found"}, err)
+}
+
+func TestApply_JSONRemoveNotFound(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"remove","path":"/missing"}]`)
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] remove /missing: path not found"}, err)
+}
+
+func TestApply_JSONRemoveRoot(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"remove","path":""}]`)
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] remove : can't remove the whole document"}, err)
+}
+
+func TestApply_JSONRelativePath(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"add","path":"name","value":1}]`)
+	assert.Equal(t, ErrMalformed{Reason: `ops[0] add name: path "name" must start with /`}, err)
+}
+
+func TestApply_JSONIndexOutOfBounds(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"add","path":"/arr/4","value":1}]`)
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] add /arr/4: array index 4 out of bounds"}, err)
+}
+
+func TestApply_JSONLeadingZeroIndex(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"add","path":"/arr/01","value":1}]`)
+	assert.Equal(t, ErrMalformed{Reason: `ops[0] add /arr/01: invalid array index "01"`}, err)
+}
+
+func TestApply_JSONNotContainer(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"add","path":"/name/foo","value":1}]`)
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] add /name/foo: parent isn't an object or array"}, err)
+}
+
+func TestApply_JSONMoveIntoItself(t *testing.T) {
+	err := applyMalformed(t, `[{"op":"move","from":"/obj","path":"/obj/a"}]`)
+	assert.Equal(t, ErrMalformed{Reason: "ops[0] move /obj/a: can't move a value into itself"}, err)
+}
+
+func TestTo(t *testing.T) {
+	o := org.Org{ID: "foo-id", Name: "foo-name", Desc: "foo-desc", Version: 3}
+	p := mustParse(t, patch.ContentTypeMerge, `{"desc":"bar-desc","version":2}`)
+
+	out, err := To(p, o, "name", "desc", "version")
+
+	assert.Nil(t, err)
+	assert.Equal(t, org.Org{ID: "foo-id", Name: "foo-name", Desc: "bar-desc", Version: 2}, out)
+}
+
+func TestTo_ReadOnly(t *testing.T) {
+	o := org.Org{ID: "foo-id", Name: "foo-name", Version: 3}
+	p := mustParse(t, patch.ContentTypeJSON, `[{"op":"replace","path":"/is_system","value":true},{"op":"remove","path":"/id"},{"op":"replace","path":"/name","value":"bar"}]`)
+
+	_, err := To(p, o, "name")
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		validate.ReadOnly("id"),
+		validate.ReadOnly("is_system"),
+	}}, err)
+}
+
+func TestTo_WrongType(t *testing.T) {
+	p := mustParse(t, patch.ContentTypeMerge, `{"name":5}`)
+
+	_, err := To(p, org.Org{Name: "foo-name"}, "name")
+
+	var malformed ErrMalformed
+	assert.True(t, errors.As(err, &malformed))
+}
+
+func TestTo_NotObject(t *testing.T) {
+	p := mustParse(t, patch.ContentTypeMerge, `"foo"`)
+
+	_, err := To(p, org.Org{Name: "foo-name"}, "name")
+
+	assert.Equal(t, ErrMalformed{Reason: "the patched document isn't an object"}, err)
+}
@@ -0,0 +1,22 @@
+package purge
+
+import (
+	"log/slog"
+	"time"
+)
+
+func logAttrBefore(before time.Time) slog.Attr {
+	return slog.Time("before", before)
+}
+
+func logAttrName(name string) slog.Attr {
+	return slog.String("name", name)
+}
+
+func logAttrNumRows(numRows int64) slog.Attr {
+	return slog.Int64("numRows", numRows)
+}
+
+func logAttrInterval(interval time.Duration) slog.Attr {
+	return slog.Duration("interval", interval)
+}
@@ -0,0 +1,89 @@
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+)
+
+// Purger hard deletes the rows that were soft deleted before the cutoff and
+// returns how many it removed.
+type Purger interface {
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Timer interface {
+	Now() time.Time
+}
+
+type namedPurger struct {
+	name   string
+	purger Purger
+}
+
+type job struct {
+	log       *slog.Logger
+	timer     Timer
+	retention time.Duration
+	purgers   []namedPurger
+}
+
+func NewJob(log *slog.Logger, timer Timer, retention time.Duration) *job {
+	return &job{
+		log:       log.With(logutil.LogAttrSVC("PurgeJob")),
+		timer:     timer,
+		retention: retention,
+	}
+}
+
+// Add registers a purger, they run in the order they were added so anything
+// with a foreign key to another table (ex. users -> orgs) has to come first.
+func (j *job) Add(name string, purger Purger) {
+	j.purgers = append(j.purgers, namedPurger{name: name, purger: purger})
+}
+
+// RunOnce purges everything that was soft deleted longer than the retention
+// window ago, it stops at the first failure and the rest are left for the
+// next run.
+func (j *job) RunOnce(ctx context.Context) error {
+	before := j.timer.Now().Add(-j.retention)
+	log := j.log.With(
+		logutil.LogAttrFN("RunOnce"),
+		logAttrBefore(before),
+	)
+	log.Debug("called")
+	for _, p := range j.purgers {
+		log := log.With(logAttrName(p.name))
+		numRows, err := p.purger.Purge(ctx, before)
+		if err != nil {
+			log.With(logutil.LogAttrError(err)).Error("purge failed")
+			return err
+		}
+		log.With(logAttrNumRows(numRows)).Info("purged")
+	}
+	return nil
+}
+
+// Run calls RunOnce every interval until ctx is done. Every instance runs it,
+// that's safe since purging the same rows twice just deletes nothing.
+func (j *job) Run(ctx context.Context, interval time.Duration) {
+	log := j.log.With(
+		logutil.LogAttrFN("Run"),
+		logAttrInterval(interval),
+	)
+	log.Info("started")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("stopped")
+			return
+		case <-ticker.C:
+			// failures are already logged, the next tick is the retry
+			_ = j.RunOnce(ctx)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPurger struct {
+	mock.Mock
+}
+
+type mockTimer struct {
+	mock.Mock
+}
+
+var (
+	ctx       = context.Background()
+	now       = time.UnixMilli(1_000_000_000).UTC()
+	retention = 24 * time.Hour
+	before    = now.Add(-retention)
+)
+
+func initJob() (j *job, mu *mockPurger, mo *mockPurger, mt *mockTimer) {
+	mu = new(mockPurger)
+	mo = new(mockPurger)
+	mt = new(mockTimer)
+	j = NewJob(testutil.GetLogger(), mt, retention)
+	j.Add("users", mu)
+	j.Add("orgs", mo)
+	return j, mu, mo, mt
+}
+
+func TestRunOnce(t *testing.T) {
+	j, mu, mo, mt := initJob()
+	mt.On("Now").Return(now)
+	var order []string
+	mu.On("Purge", ctx, before).Return(int64(2), nil).Run(func(mock.Arguments) { order = append(order, "users") })
+	mo.On("Purge", ctx, before).Return(int64(1), nil).Run(func(mock.Arguments) { order = append(order, "orgs") })
+
+	err := j.RunOnce(ctx)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"users", "orgs"}, order)
+	mu.AssertExpectations(t)
+	mo.AssertExpectations(t)
+}
+
+func TestRunOnce_PurgeErr(t *testing.T) {
+	j, mu, mo, mt := initJob()
+	mt.On("Now").Return(now)
+	mockErr := errors.New("unit-test mock error")
+	mu.On("Purge", ctx, before).Return(int64(0), mockErr)
+
+	err := j.RunOnce(ctx)
+
+	assert.Equal(t, mockErr, err)
+	mo.AssertNotCalled(t, "Purge", mock.Anything, mock.Anything)
+}
+
+func TestRun_StopsWhenCTXDone(t *testing.T) {
+	j, mu, mo, mt := initJob()
+	mt.On("Now").Return(now)
+	mu.On("Purge", mock.Anything, before).Return(int64(0), nil)
+	ran := make(chan struct{}, 1)
+	mo.On("Purge", mock.Anything, before).Return(int64(0), nil).Run(func(mock.Arguments) {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+	})
+	runCTX, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		j.Run(runCTX, time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Run never purged")
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after ctx was done")
+	}
+}
+
+func (m *mockPurger) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (t *mockTimer) Now() time.Time {
+	args := t.Called()
+	return args.Get(0).(time.Time)
+}
@@ -0,0 +1,79 @@
+package rpcerr
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Domain is the ErrorInfo's domain, the reasons are only unique within it.
+const Domain = "go-service-ex"
+
+// coder is implemented by the typed errors that have a stable code.
+type coder interface {
+	Code() string
+}
+
+// extender is implemented by the typed errors that add members to the
+// problem (ex. num_users).
+type extender interface {
+	Extensions() map[string]any
+}
+
+// New is err as a gRPC status error, the code is still picked by the caller
+// like apierr.Respond's status. The typed errors' stable code is the
+// ErrorInfo's reason (the same code the problem+json has) and the validator's
+// errors are a BadRequest with a field violation each.
+func New(c codes.Code, err error) error {
+	err = validate.FromErr(err, "")
+	st := status.New(c, err.Error())
+	var details []protoadapt.MessageV1
+	var cd coder
+	if errors.As(err, &cd) {
+		info := &errdetails.ErrorInfo{Reason: cd.Code(), Domain: Domain}
+		var ext extender
+		if errors.As(err, &ext) {
+			info.Metadata = map[string]string{}
+			for k, v := range ext.Extensions() {
+				info.Metadata[k] = fmt.Sprint(v)
+			}
+		}
+		details = append(details, info)
+	}
+	var invalid validate.ErrInvalid
+	if errors.As(err, &invalid) {
+		br := &errdetails.BadRequest{}
+		for _, v := range invalid.Violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+				Reason:      v.Rule,
+			})
+		}
+		details = append(details, br)
+	}
+	if len(details) == 0 {
+		return st.Err()
+	}
+	withDetails, dErr := st.WithDetails(details...)
+	if dErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// Reason is the stable code of a status error New returned, it's empty for
+// any other error.
+func Reason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			return info.Reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package rpcerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type errCoded struct{}
+
+func (err errCoded) Error() string {
+	return "unit-test coded error"
+}
+
+func (err errCoded) Code() string {
+	return problem.CodeOrgHasUsers
+}
+
+func (err errCoded) Extensions() map[string]any {
+	return map[string]any{"num_users": 3}
+}
+
+func TestNew_Coded(t *testing.T) {
+	err := New(codes.FailedPrecondition, fmt.Errorf("wrapped: %w", errCoded{}))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	assert.Equal(t, "wrapped: unit-test coded error", st.Message())
+	assert.Len(t, st.Details(), 1)
+	assert.True(t, proto.Equal(&errdetails.ErrorInfo{
+		Reason:   problem.CodeOrgHasUsers,
+		Domain:   Domain,
+		Metadata: map[string]string{"num_users": "3"},
+	}, st.Details()[0].(*errdetails.ErrorInfo)))
+	assert.Equal(t, problem.CodeOrgHasUsers, Reason(err))
+}
+
+func TestNew_ValidationErr(t *testing.T) {
+	err := New(codes.InvalidArgument, validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "name", Rule: "required", Message: "name is required"},
+	}})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Len(t, st.Details(), 2)
+	assert.True(t, proto.Equal(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "name", Description: "name is required", Reason: "required"},
+		},
+	}, st.Details()[1].(*errdetails.BadRequest)))
+	assert.Equal(t, problem.CodeValidationFailed, Reason(err))
+}
+
+func TestNew_Uncoded(t *testing.T) {
+	err := New(codes.Internal, errors.New("unit-test db error"))
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.Equal(t, "unit-test db error", st.Message())
+	assert.Empty(t, st.Details())
+	assert.Equal(t, "", Reason(err))
+}
@@ -0,0 +1,13 @@
+package tracing
+
+import (
+	"fmt"
+)
+
+type ErrUnknownExporter struct {
+	Exporter string
+}
+
+func (err ErrUnknownExporter) Error() string {
+	return fmt.Sprintf("Unknown tracing exporter, expected none, stdout or otlp: exporter=%s", err.Exporter)
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/RyanBard/go-service-ex"
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Tracer is safe to call before Setup, spans are no-ops until a provider is
+// installed.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The OTLP exporter is configured with the standard OTEL_EXPORTER_OTLP_*
+// env vars. The returned func flushes any buffered spans.
+func Setup(ctx context.Context, log *slog.Logger, cfg config.TracingConfig) (shutdown func(context.Context) error, err error) {
+	log = log.With(
+		logutil.LogAttrSVC("Tracing"),
+		logutil.LogAttrFN("Setup"),
+		slog.String("exporter", cfg.Exporter),
+	)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone:
+		log.Info("tracing disabled, trace context is still propagated")
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, ErrUnknownExporter{Exporter: cfg.Exporter}
+	}
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(
+		ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	log.Info("tracing enabled")
+	return tp.Shutdown, nil
+}
+
+// End records err (if any) on the span and ends it, call it from a defer with
+// the method's named err return.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartDB starts a span for a DAO method, set the statement that actually ran
+// with AttrStatement.
+func StartDB(ctx context.Context, name string) (context.Context, trace.Span) {
+	return Tracer().Start(
+		ctx,
+		name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL),
+	)
+}
+
+// AttrStatement is the name of the statement.go const, not the SQL, so it's
+// low cardinality and doesn't leak values.
+func AttrStatement(name string) attribute.KeyValue {
+	return semconv.DBQuerySummary(name)
+}
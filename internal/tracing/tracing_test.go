@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/internal/config"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ctx = context.Background()
+
+func initRecorder() *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	return sr
+}
+
+func TestSetup_None(t *testing.T) {
+	shutdown, err := Setup(ctx, testutil.GetLogger(), config.TracingConfig{Exporter: ExporterNone})
+
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(ctx))
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	shutdown, err := Setup(ctx, testutil.GetLogger(), config.TracingConfig{
+		Exporter:    ExporterStdout,
+		ServiceName: "unit-test",
+		SampleRatio: 1,
+	})
+
+	assert.Nil(t, err)
+	_, span := Tracer().Start(ctx, "unit-test")
+	assert.True(t, span.IsRecording())
+	span.End()
+	assert.Nil(t, shutdown(ctx))
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(ctx, testutil.GetLogger(), config.TracingConfig{Exporter: "foo"})
+
+	assert.Equal(t, ErrUnknownExporter{Exporter: "foo"}, err)
+}
+
+func TestEnd(t *testing.T) {
+	sr := initRecorder()
+	_, span := Tracer().Start(ctx, "unit-test")
+
+	End(span, nil)
+
+	assert.Equal(t, codes.Unset, sr.Ended()[0].Status().Code)
+	assert.Len(t, sr.Ended()[0].Events(), 0)
+}
+
+func TestEnd_Err(t *testing.T) {
+	sr := initRecorder()
+	_, span := Tracer().Start(ctx, "unit-test")
+
+	End(span, errors.New("unit-test mock error"))
+
+	assert.Equal(t, codes.Error, sr.Ended()[0].Status().Code)
+	assert.Equal(t, "unit-test mock error", sr.Ended()[0].Status().Description)
+	assert.Len(t, sr.Ended()[0].Events(), 1)
+}
+
+func TestStartDB(t *testing.T) {
+	sr := initRecorder()
+	_, span := StartDB(ctx, "UserDAO.GetByID")
+	span.SetAttributes(AttrStatement("getByIDQuery"))
+
+	End(span, nil)
+
+	actual := sr.Ended()[0]
+	assert.Equal(t, "UserDAO.GetByID", actual.Name())
+	assert.Equal(t, trace.SpanKindClient, actual.SpanKind())
+	assert.Contains(t, actual.Attributes(), AttrStatement("getByIDQuery"))
+}
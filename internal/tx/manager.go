@@ -5,22 +5,35 @@ import (
 	"log/slog"
 
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+type TXMetrics interface {
+	ObserveTX(outcome string)
+}
+
 type txmgr struct {
-	log *slog.Logger
-	db  *sqlx.DB
+	log     *slog.Logger
+	db      *sqlx.DB
+	metrics TXMetrics
 }
 
-func NewTXMGR(log *slog.Logger, db *sqlx.DB) *txmgr {
+func NewTXMGR(log *slog.Logger, db *sqlx.DB, metrics TXMetrics) *txmgr {
 	return &txmgr{
-		log: log.With(logutil.LogAttrSVC("TXManager")),
-		db:  db,
+		log:     log.With(logutil.LogAttrSVC("TXManager")),
+		db:      db,
+		metrics: metrics,
 	}
 }
 
 func (m txmgr) Do(ctx context.Context, joinTX *sqlx.Tx, f func(*sqlx.Tx) error) (err error) {
+	// registered first so it ends after the commit/rollback below
+	ctx, span := tracing.Tracer().Start(ctx, "TXManager.Do", trace.WithAttributes(attribute.Bool("tx.joined", joinTX != nil)))
+	defer func() { tracing.End(span, err) }()
 	log := m.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
@@ -32,6 +45,7 @@ func (m txmgr) Do(ctx context.Context, joinTX *sqlx.Tx, f func(*sqlx.Tx) error)
 		tx, err = m.db.Beginx()
 		if err != nil {
 			log.With(logutil.LogAttrError(err)).Error("failed to create tx")
+			m.metrics.ObserveTX(metrics.TXBeginError)
 			return err
 		}
 	} else {
@@ -49,13 +63,17 @@ func (m txmgr) Do(ctx context.Context, joinTX *sqlx.Tx, f func(*sqlx.Tx) error)
 			if rbErr != nil {
 				log.With(logutil.LogAttrError(rbErr)).Error("rollback failed")
 			}
+			m.metrics.ObserveTX(metrics.TXRollback)
 			return
 		}
 		log.Debug("f succeeded, committing tx")
 		err = tx.Commit()
 		if err != nil {
 			log.With(logutil.LogAttrError(err)).Error("failed to commit tx")
+			m.metrics.ObserveTX(metrics.TXCommitError)
+			return
 		}
+		m.metrics.ObserveTX(metrics.TXCommit)
 	}()
 	log.Debug("calling f")
 	err = f(tx)
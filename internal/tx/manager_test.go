@@ -7,6 +7,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/metrics"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -16,7 +17,15 @@ var (
 	ctx = context.Background()
 )
 
-func initMGR() (m *txmgr, dbx *sqlx.DB, md sqlmock.Sqlmock) {
+type mockTXMetrics struct {
+	outcomes []string
+}
+
+func (m *mockTXMetrics) ObserveTX(outcome string) {
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+func initMGR() (m *txmgr, dbx *sqlx.DB, md sqlmock.Sqlmock, mm *mockTXMetrics) {
 	log := testutil.GetLogger()
 	db, md, err := sqlmock.New()
 	if err != nil {
@@ -24,12 +33,13 @@ func initMGR() (m *txmgr, dbx *sqlx.DB, md sqlmock.Sqlmock) {
 		panic(err)
 	}
 	dbx = sqlx.NewDb(db, "sqlmock")
-	m = NewTXMGR(log, dbx)
-	return m, dbx, md
+	mm = &mockTXMetrics{}
+	m = NewTXMGR(log, dbx, mm)
+	return m, dbx, md, mm
 }
 
 func TestDo_CommitOnSuccess(t *testing.T) {
-	m, _, md := initMGR()
+	m, _, md, mm := initMGR()
 	md.ExpectBegin()
 	md.ExpectCommit()
 	actual := m.Do(ctx, nil, func(tx *sqlx.Tx) error {
@@ -37,10 +47,11 @@ func TestDo_CommitOnSuccess(t *testing.T) {
 	})
 	assert.Nil(t, actual)
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, []string{metrics.TXCommit}, mm.outcomes)
 }
 
 func TestDo_RollbackOnError(t *testing.T) {
-	m, _, md := initMGR()
+	m, _, md, mm := initMGR()
 	md.ExpectBegin()
 	md.ExpectRollback()
 	mockErr := errors.New("unit-test error")
@@ -49,20 +60,22 @@ func TestDo_RollbackOnError(t *testing.T) {
 	})
 	assert.Equal(t, mockErr, actual)
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, []string{metrics.TXRollback}, mm.outcomes)
 }
 
 func TestDo_ErrorOnFailedToBegin(t *testing.T) {
-	m, _, md := initMGR()
+	m, _, md, mm := initMGR()
 	actual := m.Do(ctx, nil, func(tx *sqlx.Tx) error {
 		return nil
 	})
 	assert.NotNil(t, actual)
 	assert.Contains(t, actual.Error(), "call to database transaction Begin was not expected")
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, []string{metrics.TXBeginError}, mm.outcomes)
 }
 
 func TestDo_ErrorOnFailedToCommit(t *testing.T) {
-	m, _, md := initMGR()
+	m, _, md, mm := initMGR()
 	md.ExpectBegin()
 	actual := m.Do(ctx, nil, func(tx *sqlx.Tx) error {
 		return nil
@@ -70,10 +83,11 @@ func TestDo_ErrorOnFailedToCommit(t *testing.T) {
 	assert.NotNil(t, actual)
 	assert.Contains(t, actual.Error(), "call to Commit transaction was not expected")
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, []string{metrics.TXCommitError}, mm.outcomes)
 }
 
 func TestDo_OriginalErrOnFailedToRollback(t *testing.T) {
-	m, _, md := initMGR()
+	m, _, md, mm := initMGR()
 	md.ExpectBegin()
 	mockErr := errors.New("unit-test error")
 	actual := m.Do(ctx, nil, func(tx *sqlx.Tx) error {
@@ -81,10 +95,11 @@ func TestDo_OriginalErrOnFailedToRollback(t *testing.T) {
 	})
 	assert.Equal(t, mockErr, actual)
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, []string{metrics.TXRollback}, mm.outcomes)
 }
 
 func TestDo_JoinTX_NoCommitOnSuccess(t *testing.T) {
-	m, dbx, md := initMGR()
+	m, dbx, md, mm := initMGR()
 	md.ExpectBegin()
 	tx, err := dbx.Beginx()
 	assert.Nil(t, err)
@@ -93,10 +108,11 @@ func TestDo_JoinTX_NoCommitOnSuccess(t *testing.T) {
 	})
 	assert.Nil(t, actual)
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Empty(t, mm.outcomes)
 }
 
 func TestDo_JoinTX_NoRollbackOnError(t *testing.T) {
-	m, dbx, md := initMGR()
+	m, dbx, md, mm := initMGR()
 	md.ExpectBegin()
 	tx, err := dbx.Beginx()
 	assert.Nil(t, err)
@@ -106,4 +122,5 @@ func TestDo_JoinTX_NoRollbackOnError(t *testing.T) {
 	})
 	assert.Equal(t, mockErr, actual)
 	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Empty(t, mm.outcomes)
 }
@@ -3,23 +3,49 @@ package user
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apierr"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/internal/etag"
 	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	pkgorg "github.com/RyanBard/go-service-ex/pkg/org"
 	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 )
 
 type UserService interface {
-	GetByID(ctx context.Context, id string) (user.User, error)
-	GetAll(ctx context.Context) ([]user.User, error)
-	GetAllByOrgID(ctx context.Context, orgID string) ([]user.User, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error)
+	GetAll(ctx context.Context, q user.Query, includeDeleted bool, pr page.Request) (user.UserPage, error)
+	GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, pr page.Request) (user.UserPage, error)
 	Save(ctx context.Context, u user.User) (user.User, error)
+	Patch(ctx context.Context, id string, version int64, changes patch.Patch) (user.User, error)
+	Create(ctx context.Context, joinTX *sqlx.Tx, u user.User) (user.User, error)
+	CreateInOrg(ctx context.Context, joinTX *sqlx.Tx, o pkgorg.Org, u user.User) (user.User, error)
+	SaveInvited(ctx context.Context, joinTX *sqlx.Tx, u user.User) (user.User, error)
 	Delete(ctx context.Context, u user.DeleteUser) error
+	Restore(ctx context.Context, u user.RestoreUser) (user.User, error)
+	Batch(ctx context.Context, b user.Batch) ([]BatchResult, error)
+	Export(ctx context.Context) iter.Seq2[user.User, error]
+	Import(ctx context.Context, rows iter.Seq2[user.User, error], dryRun bool) (ImportResult, error)
+	RequestEmailChange(ctx context.Context, id string, ec user.EmailChange) (user.PendingEmailChange, error)
+	ConfirmEmailChange(ctx context.Context, id string, cec user.ConfirmEmailChange) (user.User, error)
 }
 
+// exportFlushEvery is how many rows are written before they're flushed to the
+// client.
+const exportFlushEvery = 100
+
 type ctrl struct {
 	log     *slog.Logger
 	service UserService
@@ -42,18 +68,22 @@ func (ctr ctrl) GetByID(c *gin.Context) {
 		logAttrUserID(id),
 	)
 	log.Debug("called")
-	u, err := ctr.service.GetByID(ctx, id)
+	includeDeleted, err := parseIncludeDeleted(c)
 	if err != nil {
-		var statusCode int
-		var notFound ErrNotFound
-		if errors.As(err, &notFound) {
-			log.With(logutil.LogAttrError(err)).Warn("resource not found")
-			statusCode = http.StatusNotFound
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
-		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	u, err := ctr.service.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	tag := etag.New(u.ID, u.Version)
+	c.Header(etag.HeaderETag, tag)
+	if etag.NoneMatch(c.GetHeader(etag.HeaderIfNoneMatch), tag) {
+		log.Debug("not modified")
+		c.Status(http.StatusNotModified)
 		return
 	}
 	log.With(logAttrUser(u)).Debug("success")
@@ -68,14 +98,82 @@ func (ctr ctrl) GetAll(c *gin.Context) {
 		logutil.LogAttrFN("GetAll"),
 	)
 	log.Debug("called")
-	u, err := ctr.service.GetAll(ctx)
+	pr, err := page.ParseRequest(c.Query("limit"), c.Query("cursor"))
 	if err != nil {
-		log.With(logutil.LogAttrError(err)).Error("service call failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
-	log.With(logAttrUsersLen(len(u))).Debug("success")
-	c.JSON(http.StatusOK, u)
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	q, err := parseQuery(c)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrQuery(q))
+	up, err := ctr.service.GetAll(ctx, q, includeDeleted, pr)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrUsersLen(len(up.Users))).Debug("success")
+	c.JSON(http.StatusOK, up)
+}
+
+// parseQuery leaves the sort to the service, it's the one that knows which
+// columns can be sorted by.
+func parseQuery(c *gin.Context) (q user.Query, err error) {
+	q.OrgID = c.Query("org_id")
+	q.Email = c.Query("email")
+	q.Name = c.Query("name")
+	q.Sort = c.Query("sort")
+	if q.IsActive, err = parseBoolFilter("is_active", c.Query("is_active")); err != nil {
+		return q, err
+	}
+	if q.IsAdmin, err = parseBoolFilter("is_admin", c.Query("is_admin")); err != nil {
+		return q, err
+	}
+	if q.CreatedFrom, err = parseTimeFilter("created_from", c.Query("created_from")); err != nil {
+		return q, err
+	}
+	if q.CreatedTo, err = parseTimeFilter("created_to", c.Query("created_to")); err != nil {
+		return q, err
+	}
+	if q.UpdatedFrom, err = parseTimeFilter("updated_from", c.Query("updated_from")); err != nil {
+		return q, err
+	}
+	if q.UpdatedTo, err = parseTimeFilter("updated_to", c.Query("updated_to")); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+func parseBoolFilter(param string, value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, ErrInvalidFilter{Param: param, Value: value}
+	}
+	return &b, nil
+}
+
+func parseTimeFilter(param string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, ErrInvalidFilter{Param: param, Value: value}
+	}
+	return &t, nil
 }
 
 func (ctr ctrl) GetAllByOrgID(c *gin.Context) {
@@ -88,22 +186,25 @@ func (ctr ctrl) GetAllByOrgID(c *gin.Context) {
 		logAttrOrgID(orgID),
 	)
 	log.Debug("called")
-	u, err := ctr.service.GetAllByOrgID(ctx, orgID)
+	pr, err := page.ParseRequest(c.Query("limit"), c.Query("cursor"))
 	if err != nil {
-		var statusCode int
-		var orgNotFound org.ErrNotFound
-		if errors.As(err, &orgNotFound) {
-			log.With(logutil.LogAttrError(err)).Warn("org resource not found")
-			statusCode = http.StatusNotFound
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
-		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
-	log.With(logAttrUsersLen(len(u))).Debug("success")
-	c.JSON(http.StatusOK, u)
+	includeDeleted, err := parseIncludeDeleted(c)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	up, err := ctr.service.GetAllByOrgID(ctx, orgID, includeDeleted, pr)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.With(logAttrUsersLen(len(up.Users))).Debug("success")
+	c.JSON(http.StatusOK, up)
 }
 
 func (ctr ctrl) Save(c *gin.Context) {
@@ -116,53 +217,87 @@ func (ctr ctrl) Save(c *gin.Context) {
 		logAttrPathID(pathID),
 	)
 	log.Debug("called")
-	var u user.User
-	if err := c.ShouldBindJSON(&u); err != nil {
+	// an update's rules are checked by the service, only against the fields
+	// it changes
+	var body struct {
+		user.User `binding:"-"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
 		log.With(logutil.LogAttrError(err)).Warn("invalid request")
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
+	u := body.User
 	if pathID != "" {
 		u.ID = pathID
 	}
+	if u.ID == "" {
+		if err := validate.Struct(u, ""); err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("invalid request")
+			apierr.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	// If-Match takes the place of the version in the body
+	version, ifMatch, err := etag.ParseIfMatch(c.GetHeader(etag.HeaderIfMatch), u.ID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("precondition failed")
+		apierr.Respond(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	if ifMatch {
+		u.Version = version
+	}
 	log = log.With(logAttrUser(u))
 	log.Debug("body processed, about to call service")
-	u, err := ctr.service.Save(ctx, u)
-	if err != nil {
-		var statusCode int
-		var notFound ErrNotFound
-		var modSysUser ErrCannotModifySysUser
-		var assocSysOrg ErrCannotAssociateSysOrg
-		var orgNotFound org.ErrNotFound
-		var optLock ErrOptimisticLock
-		var dupEmail ErrEmailAlreadyInUse
-		if errors.As(err, &notFound) {
-			log.With(logutil.LogAttrError(err)).Warn("resource not found")
-			statusCode = http.StatusNotFound
-		} else if errors.As(err, &modSysUser) {
-			log.With(logutil.LogAttrError(err)).Warn("cannot modify system user")
-			statusCode = http.StatusForbidden
-		} else if errors.As(err, &optLock) {
-			log.With(logutil.LogAttrError(err)).Warn("optimistic lock error")
-			statusCode = http.StatusConflict
-		} else if errors.As(err, &dupEmail) {
-			log.With(logutil.LogAttrError(err)).Warn("duplicate email error")
-			statusCode = http.StatusConflict
-		} else if errors.As(err, &orgNotFound) {
-			log.With(logutil.LogAttrError(err)).Warn("org resource not found")
-			statusCode = http.StatusBadRequest
-		} else if errors.As(err, &assocSysOrg) {
-			// TODO - you could choose to 404 this to obfuscate for security reasons, but I'm letting error details go through in the response atm, so probably not worth it right now
-			log.With(logutil.LogAttrError(err)).Warn("cannot associate system org")
-			statusCode = http.StatusForbidden
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
-		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
+	u, err = ctr.service.Save(ctx, u)
+	if err != nil {
+		apierr.FromErrorWithStatus(c, log, ifMatchStatus(ifMatch, err, saveErrStatus(err)), err)
+		return
+	}
+	log.Debug("success")
+	c.Header(etag.HeaderETag, etag.New(u.ID, u.Version))
+	c.JSON(http.StatusOK, u)
+}
+
+// Patch takes a merge patch or a json patch (picked by the Content-Type),
+// If-Match is the only way to check the version besides the patch itself.
+func (ctr ctrl) Patch(c *gin.Context) {
+	ctx := c.Request.Context()
+	pathID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Patch"),
+		logAttrPathID(pathID),
+	)
+	log.Debug("called")
+	body, err := c.GetRawData()
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	changes, err := patch.Parse(c.ContentType(), body)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	version, ifMatch, err := etag.ParseIfMatch(c.GetHeader(etag.HeaderIfMatch), pathID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("precondition failed")
+		apierr.Respond(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	log = log.With(logAttrContentType(changes.ContentType), logAttrVersion(version))
+	log.Debug("body processed, about to call service")
+	u, err := ctr.service.Patch(ctx, pathID, version, changes)
+	if err != nil {
+		apierr.FromErrorWithStatus(c, log, ifMatchStatus(ifMatch, err, saveErrStatus(err)), err)
 		return
 	}
 	log.Debug("success")
+	c.Header(etag.HeaderETag, etag.New(u.ID, u.Version))
 	c.JSON(http.StatusOK, u)
 }
 
@@ -176,10 +311,54 @@ func (ctr ctrl) Delete(c *gin.Context) {
 		logAttrPathID(pathID),
 	)
 	log.Debug("called")
-	var u user.DeleteUser
+	version, ifMatch, err := etag.ParseIfMatch(c.GetHeader(etag.HeaderIfMatch), pathID)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("precondition failed")
+		apierr.Respond(c, http.StatusPreconditionFailed, err)
+		return
+	}
+	// with If-Match there's nothing left for the body to say
+	u := user.DeleteUser{ID: pathID, Version: version}
+	if !ifMatch {
+		if err := c.ShouldBindJSON(&u); err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("invalid request")
+			apierr.Respond(c, http.StatusBadRequest, err)
+			return
+		}
+		if pathID != "" {
+			u.ID = pathID
+		}
+	}
+	log = log.With(logAttrUser(u))
+	log.Debug("body processed, about to call service")
+	if err := ctr.service.Delete(ctx, u); err != nil {
+		statusCode := ifMatchStatus(ifMatch, err, deleteErrStatus(err))
+		if statusCode == http.StatusNoContent {
+			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not deleting")
+			c.Status(statusCode)
+			return
+		}
+		apierr.FromErrorWithStatus(c, log, statusCode, err)
+		return
+	}
+	log.Debug("success")
+	c.Status(http.StatusNoContent)
+}
+
+func (ctr ctrl) Restore(c *gin.Context) {
+	ctx := c.Request.Context()
+	pathID := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Restore"),
+		logAttrPathID(pathID),
+	)
+	log.Debug("called")
+	var u user.RestoreUser
 	if err := c.ShouldBindJSON(&u); err != nil {
 		log.With(logutil.LogAttrError(err)).Warn("invalid request")
-		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		apierr.Respond(c, http.StatusBadRequest, err)
 		return
 	}
 	if pathID != "" {
@@ -187,28 +366,370 @@ func (ctr ctrl) Delete(c *gin.Context) {
 	}
 	log = log.With(logAttrUser(u))
 	log.Debug("body processed, about to call service")
-	if err := ctr.service.Delete(ctx, u); err != nil {
-		var statusCode int
-		var notFound ErrNotFound
-		var modSysUser ErrCannotModifySysUser
-		var optLock ErrOptimisticLock
-		if errors.As(err, &notFound) {
-			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not deleting")
-			c.Status(http.StatusNoContent)
+	restored, err := ctr.service.Restore(ctx, u)
+	if err != nil {
+		// the user's org is deleted, it has to be restored first
+		if errors.As(err, &org.ErrNotFound{}) {
+			apierr.FromErrorWithStatus(c, log, http.StatusConflict, err)
 			return
-		} else if errors.As(err, &modSysUser) {
-			log.With(logutil.LogAttrError(err)).Warn("cannot modify system user")
-			statusCode = http.StatusForbidden
-		} else if errors.As(err, &optLock) {
-			log.With(logutil.LogAttrError(err)).Warn("optimistic lock error")
-			statusCode = http.StatusConflict
-		} else {
-			log.With(logutil.LogAttrError(err)).Error("service call failed")
-			statusCode = http.StatusInternalServerError
 		}
-		c.JSON(statusCode, gin.H{"message": err.Error()})
+		apierr.FromError(c, log, err)
 		return
 	}
 	log.Debug("success")
-	c.Status(http.StatusNoContent)
+	c.Header(etag.HeaderETag, etag.New(restored.ID, restored.Version))
+	c.JSON(http.StatusOK, restored)
+}
+
+// RequestEmailChange responds with a 202, the email only changes once the
+// token sent to it is confirmed.
+func (ctr ctrl) RequestEmailChange(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("RequestEmailChange"),
+		logAttrUserID(id),
+	)
+	log.Debug("called")
+	var ec user.EmailChange
+	if err := c.ShouldBindJSON(&ec); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	pec, err := ctr.service.RequestEmailChange(ctx, id, ec)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.JSON(http.StatusAccepted, pec)
+}
+
+func (ctr ctrl) ConfirmEmailChange(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ConfirmEmailChange"),
+		logAttrUserID(id),
+	)
+	log.Debug("called")
+	var cec user.ConfirmEmailChange
+	if err := c.ShouldBindJSON(&cec); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	u, err := ctr.service.ConfirmEmailChange(ctx, id, cec)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	log.Debug("success")
+	c.JSON(http.StatusOK, u)
+}
+
+// ifMatchStatus is 412 for an optimistic lock error when the version came
+// from If-Match, a stale version in the body is still a 409.
+func ifMatchStatus(ifMatch bool, err error, statusCode int) int {
+	if ifMatch && errors.As(err, &ErrOptimisticLock{}) {
+		return http.StatusPreconditionFailed
+	}
+	return statusCode
+}
+
+// parseIncludeDeleted treats a missing include_deleted as false.
+func parseIncludeDeleted(c *gin.Context) (bool, error) {
+	v := c.Query("include_deleted")
+	if v == "" {
+		return false, nil
+	}
+	includeDeleted, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, ErrInvalidIncludeDeleted{Value: v}
+	}
+	return includeDeleted, nil
+}
+
+// Batch is routed as /users:method since gin can't route a literal colon
+// unless it's started with Run, so anything but :batch is a 404.
+func (ctr ctrl) Batch(c *gin.Context) {
+	ctx := c.Request.Context()
+	method := c.Param("method")
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Batch"),
+		logAttrMethod(method),
+	)
+	log.Debug("called")
+	if method != ":batch" {
+		log.Warn("unknown method")
+		apierr.Respond(c, http.StatusNotFound, ErrUnknownMethod{Method: method})
+		return
+	}
+	var b user.Batch
+	if err := c.ShouldBindJSON(&b); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := validateBatchOps(b.Ops); err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrBatchMode(b.Mode), logAttrOpsLen(len(b.Ops)))
+	log.Debug("body processed, about to call service")
+	results, err := ctr.service.Batch(ctx, b)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	// all-or-nothing responds with the status of the op that failed,
+	// best-effort with a 207 if any op failed
+	statusCode := http.StatusOK
+	res := user.BatchResponse{Results: make([]user.BatchResult, len(results))}
+	for i, r := range results {
+		op := b.Ops[i].Op
+		res.Results[i] = batchResult(log.With(logAttrOpIndex(i)), op, r)
+		status := res.Results[i].Status
+		var aborted ErrBatchAborted
+		if status < http.StatusBadRequest || errors.As(r.Err, &aborted) {
+			continue
+		}
+		if b.Mode == user.BatchModeBestEffort {
+			statusCode = http.StatusMultiStatus
+		} else {
+			statusCode = status
+		}
+	}
+	log.Debug("success")
+	c.JSON(statusCode, res)
+}
+
+func batchResult(log *slog.Logger, op string, r BatchResult) user.BatchResult {
+	res := user.BatchResult{Op: op}
+	if r.Err == nil {
+		res.Status = http.StatusOK
+		if op == user.BatchOpDelete {
+			res.Status = http.StatusNoContent
+		} else {
+			res.User = &r.User
+		}
+		return res
+	}
+	if op == user.BatchOpDelete {
+		res.Status = deleteErrStatus(r.Err)
+		if res.Status == http.StatusNoContent {
+			return res
+		}
+	} else {
+		res.Status = saveErrStatus(r.Err)
+	}
+	apierr.Log(log, res.Status, r.Err)
+	res.Code = apierr.Code(res.Status, r.Err)
+	res.Message = r.Err.Error()
+	return res
+}
+
+// validateBatchOps does the validation binding skipped, each op's user is
+// checked against what the single user endpoint would bind it to.
+func validateBatchOps(ops []user.BatchOp) error {
+	var violations validate.Violations
+	for i, op := range ops {
+		prefix := fmt.Sprintf("ops[%d].user.", i)
+		var err error
+		switch op.Op {
+		case user.BatchOpCreate:
+			err = validate.Struct(op.User, prefix)
+		case user.BatchOpUpdate:
+			// the rest is checked by the service, only against the fields
+			// the update changes
+			if op.User.ID == "" {
+				violations = append(violations, validate.Required(prefix+"id"))
+			}
+		case user.BatchOpDelete:
+			err = validate.Struct(user.DeleteUser{ID: op.User.ID, Version: op.User.Version}, prefix)
+		}
+		if err := violations.Add(err); err != nil {
+			return err
+		}
+	}
+	return violations.Err()
+}
+
+// saveErrStatus is the status a failed create or update responds with, it's
+// shared with Batch and Import so an op's result matches the single user
+// endpoint. The org is in the body, so it not being found is a 400.
+func saveErrStatus(err error) int {
+	if errors.As(err, &org.ErrNotFound{}) {
+		return http.StatusBadRequest
+	}
+	return apierr.Status(err)
+}
+
+// deleteErrStatus is the status a failed delete responds with, deleting a user
+// that's already gone is a 204.
+func deleteErrStatus(err error) int {
+	if errors.As(err, &ErrNotFound{}) {
+		return http.StatusNoContent
+	}
+	return apierr.Status(err)
+}
+
+// Export streams every user the logged in user can see. Errors before the
+// first row are responded with as usual, after that the status is already
+// sent so the connection is dropped (see dataio.Abort), a cut short export
+// can't be mistaken for a whole one.
+func (ctr ctrl) Export(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Export"),
+	)
+	log.Debug("called")
+	format, err := dataio.ParseFormat(c.Query("format"))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	enc := dataio.NewEncoder(c.Writer, format, csvHeader, toCSV)
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", dataio.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, format))
+		c.Status(http.StatusOK)
+		if err := enc.WriteHeader(); err != nil {
+			log.With(logutil.LogAttrError(err)).Warn("failed to write header")
+		}
+	}
+	numRows := 0
+	for u, err := range ctr.service.Export(ctx) {
+		if err != nil && !started {
+			apierr.FromError(c, log, err)
+			return
+		}
+		if err != nil {
+			log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Error("export failed part way through")
+			enc.Flush()
+			dataio.Abort(c.Writer)
+		}
+		if !started {
+			start()
+		}
+		if err := enc.Encode(u); err != nil {
+			// the client went away
+			log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Warn("failed to write row")
+			return
+		}
+		numRows++
+		if numRows%exportFlushEvery == 0 {
+			enc.Flush()
+			if err := dataio.FlushResponse(c.Writer); err != nil {
+				log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Warn("failed to flush")
+				return
+			}
+		}
+	}
+	if !started {
+		start()
+	}
+	enc.Flush()
+	log.With(logAttrRows(numRows)).Debug("success")
+}
+
+// Import creates a user for every row of the body, see ImportReport for what
+// it responds with. Rows are validated the same as Save's body.
+func (ctr ctrl) Import(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := ctr.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Import"),
+	)
+	log.Debug("called")
+	format, err := dataio.ParseFormat(c.Query("format"))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	dryRun, err := parseDryRun(c)
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		apierr.Respond(c, http.StatusBadRequest, err)
+		return
+	}
+	log = log.With(logAttrDryRun(dryRun))
+	rows := validateImportRows(dataio.Decode(c.Request.Body, format, csvRequired, fromCSV))
+	res, err := ctr.service.Import(ctx, rows, dryRun)
+	if err != nil {
+		apierr.FromError(c, log, err)
+		return
+	}
+	report := user.ImportReport{
+		DryRun:   dryRun,
+		Rows:     res.Rows,
+		Imported: res.Imported,
+		Errors:   make([]user.ImportError, len(res.Errors)),
+	}
+	for i, rowErr := range res.Errors {
+		report.Errors[i] = importError(log, rowErr)
+	}
+	if res.Stopped != nil {
+		stopped := importError(log, *res.Stopped)
+		report.Stopped = &stopped
+	}
+	log.With(logAttrRows(res.Rows), logAttrImported(res.Imported)).Debug("success")
+	c.JSON(http.StatusOK, report)
+}
+
+// parseDryRun treats a missing dry_run as false.
+func parseDryRun(c *gin.Context) (bool, error) {
+	v := c.Query("dry_run")
+	if v == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, ErrInvalidDryRun{Value: v}
+	}
+	return dryRun, nil
+}
+
+// validateImportRows checks each row against what Save would bind its body
+// to, a row that fails is an invalid row rather than the end of the import.
+func validateImportRows(rows iter.Seq2[user.User, error]) iter.Seq2[user.User, error] {
+	return func(yield func(user.User, error) bool) {
+		for u, err := range rows {
+			if err == nil {
+				if validationErr := validate.Struct(u, ""); validationErr != nil {
+					err = dataio.ErrInvalidRow{Reason: validationErr.Error()}
+				}
+			}
+			if !yield(u, err) {
+				return
+			}
+		}
+	}
+}
+
+func importError(log *slog.Logger, rowErr ImportRowError) user.ImportError {
+	// the status the single user endpoint would have responded to the row with
+	status := saveErrStatus(rowErr.Err)
+	apierr.Log(log.With(logAttrRow(rowErr.Row)), status, rowErr.Err)
+	return user.ImportError{
+		Row:     rowErr.Row,
+		Status:  status,
+		Code:    apierr.Code(status, rowErr.Err),
+		Message: rowErr.Err.Error(),
+	}
 }
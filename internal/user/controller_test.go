@@ -5,16 +5,26 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
 	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	pkgorg "github.com/RyanBard/go-service-ex/pkg/org"
+	pkgpatch "github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -113,7 +123,7 @@ func TestCTRLGetByID(t *testing.T) {
 		CreatedAt: time.UnixMilli(100),
 		UpdatedAt: time.UnixMilli(200),
 	}
-	ms.On("GetByID", mock.Anything, id).Return(mockRes, nil)
+	ms.On("GetByID", mock.Anything, id, false).Return(mockRes, nil)
 
 	c.GetByID(gc)
 	res := w.Result()
@@ -142,18 +152,18 @@ func TestCTRLGetByID_NotFoundError(t *testing.T) {
 	}
 
 	mockErr := ErrNotFound{ID: id}
-	ms.On("GetByID", mock.Anything, id).Return(user.User{}, mockErr)
+	ms.On("GetByID", mock.Anything, id, false).Return(user.User{}, mockErr)
 
 	c.GetByID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 404, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLGetByID_ServiceError(t *testing.T) {
@@ -171,18 +181,18 @@ func TestCTRLGetByID_ServiceError(t *testing.T) {
 	}
 
 	mockErr := errors.New("unit-test mock service error")
-	ms.On("GetByID", mock.Anything, id).Return(user.User{}, mockErr)
+	ms.On("GetByID", mock.Anything, id, false).Return(user.User{}, mockErr)
 
 	c.GetByID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLGetAll(t *testing.T) {
@@ -190,22 +200,52 @@ func TestCTRLGetAll(t *testing.T) {
 	gc, w, err := ginCtx("/")
 	assert.Nil(t, err)
 
-	mockRes := []user.User{
-		{
-			ID:        "foo-id",
-			Name:      "foo-name",
-			Email:     "foo@bar.com",
-			CreatedAt: time.UnixMilli(100),
-			UpdatedAt: time.UnixMilli(200),
+	mockRes := user.UserPage{
+		Users: []user.User{
+			{
+				ID:        "foo-id",
+				Name:      "foo-name",
+				Email:     "foo@bar.com",
+				CreatedAt: time.UnixMilli(100),
+				UpdatedAt: time.UnixMilli(200),
+			},
 		},
+		NextCursor: "foo-cursor",
+	}
+	ms.On("GetAll", mock.Anything, user.Query{}, false, page.Request{Limit: page.DefaultLimit}).Return(mockRes, nil)
+
+	c.GetAll(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual user.UserPage
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLGetAll_PageParams(t *testing.T) {
+	after := page.Cursor{
+		Key:       "foo@bar.com",
+		CreatedAt: time.UnixMilli(100).UTC(),
+		ID:        "foo-id",
+	}
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?limit=10&cursor=" + page.EncodeCursor(after))
+	assert.Nil(t, err)
+
+	mockRes := user.UserPage{
+		Users: []user.User{},
 	}
-	ms.On("GetAll", mock.Anything).Return(mockRes, nil)
+	ms.On("GetAll", mock.Anything, user.Query{}, false, page.Request{Limit: 10, After: &after}).Return(mockRes, nil)
 
 	c.GetAll(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual []user.User
+	var actual user.UserPage
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
@@ -213,24 +253,118 @@ func TestCTRLGetAll(t *testing.T) {
 	assert.Equal(t, mockRes, actual)
 }
 
+func TestCTRLGetAll_InvalidLimit(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtx("/?limit=foo")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, actual.Detail, "limit")
+}
+
+func TestCTRLGetAll_InvalidCursor(t *testing.T) {
+	c, _ := initCTRL()
+	gc, w, err := ginCtx("/?cursor=foo")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, actual.Detail, "cursor")
+}
+
+func TestCTRLGetAll_Query(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?org_id=foo-org-id&is_active=true&is_admin=false&email=foo&name=bar&created_from=2024-01-02T03:04:05Z&updated_to=2024-02-03T04:05:06Z&sort=-created_at")
+	assert.Nil(t, err)
+
+	isActive := true
+	isAdmin := false
+	createdFrom := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	updatedTo := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	q := user.Query{
+		OrgID:       "foo-org-id",
+		IsActive:    &isActive,
+		IsAdmin:     &isAdmin,
+		Email:       "foo",
+		Name:        "bar",
+		CreatedFrom: &createdFrom,
+		UpdatedTo:   &updatedTo,
+		Sort:        "-created_at",
+	}
+	ms.On("GetAll", mock.Anything, q, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, nil)
+
+	c.GetAll(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLGetAll_InvalidBoolFilter(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?is_active=maybe")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, w.Body.String(), "is_active=maybe")
+	ms.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLGetAll_InvalidTimeFilter(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?created_to=yesterday")
+	assert.Nil(t, err)
+
+	c.GetAll(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, w.Body.String(), "created_to=yesterday")
+	ms.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLGetAll_InvalidSort(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?sort=password")
+	assert.Nil(t, err)
+
+	mockErr := ErrInvalidSort{Sort: "password"}
+	ms.On("GetAll", mock.Anything, user.Query{Sort: "password"}, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, mockErr)
+
+	c.GetAll(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
 func TestCTRLGetAll_ServiceError(t *testing.T) {
 	c, ms := initCTRL()
 	gc, w, err := ginCtx("/")
 	assert.Nil(t, err)
 
 	mockErr := errors.New("unit-test mock service error")
-	ms.On("GetAll", mock.Anything).Return([]user.User{}, mockErr)
+	ms.On("GetAll", mock.Anything, user.Query{}, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, mockErr)
 
 	c.GetAll(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLGetAllByOrgID(t *testing.T) {
@@ -246,23 +380,26 @@ func TestCTRLGetAllByOrgID(t *testing.T) {
 		},
 	}
 
-	mockRes := []user.User{
-		{
-			ID:        "foo-id",
-			OrgID:     orgID,
-			Name:      "foo-name",
-			Email:     "foo@bar.com",
-			CreatedAt: time.UnixMilli(100),
-			UpdatedAt: time.UnixMilli(200),
+	mockRes := user.UserPage{
+		Users: []user.User{
+			{
+				ID:        "foo-id",
+				OrgID:     orgID,
+				Name:      "foo-name",
+				Email:     "foo@bar.com",
+				CreatedAt: time.UnixMilli(100),
+				UpdatedAt: time.UnixMilli(200),
+			},
 		},
+		NextCursor: "foo-cursor",
 	}
-	ms.On("GetAllByOrgID", mock.Anything, orgID).Return(mockRes, nil)
+	ms.On("GetAllByOrgID", mock.Anything, orgID, false, page.Request{Limit: page.DefaultLimit}).Return(mockRes, nil)
 
 	c.GetAllByOrgID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual []user.User
+	var actual user.UserPage
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
@@ -270,6 +407,31 @@ func TestCTRLGetAllByOrgID(t *testing.T) {
 	assert.Equal(t, mockRes, actual)
 }
 
+func TestCTRLGetAllByOrgID_InvalidLimit(t *testing.T) {
+	orgID := "foo-id"
+	c, _ := initCTRL()
+	gc, w, err := ginCtx("/?limit=0")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: orgID,
+		},
+	}
+
+	c.GetAllByOrgID(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, actual.Detail, "limit")
+}
+
 func TestCTRLGetAllByOrgID_OrgNotFound(t *testing.T) {
 	orgID := "foo-id"
 	c, ms := initCTRL()
@@ -284,18 +446,18 @@ func TestCTRLGetAllByOrgID_OrgNotFound(t *testing.T) {
 	}
 
 	mockErr := org.ErrNotFound{ID: orgID}
-	ms.On("GetAllByOrgID", mock.Anything, orgID).Return([]user.User{}, mockErr)
+	ms.On("GetAllByOrgID", mock.Anything, orgID, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, mockErr)
 
 	c.GetAllByOrgID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 404, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLGetAllByOrgID_ServiceError(t *testing.T) {
@@ -312,18 +474,18 @@ func TestCTRLGetAllByOrgID_ServiceError(t *testing.T) {
 	}
 
 	mockErr := errors.New("unit-test mock service error")
-	ms.On("GetAllByOrgID", mock.Anything, orgID).Return([]user.User{}, mockErr)
+	ms.On("GetAllByOrgID", mock.Anything, orgID, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, mockErr)
 
 	c.GetAllByOrgID(gc)
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave(t *testing.T) {
@@ -415,12 +577,12 @@ func TestCTRLSave_ReadError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, mockIOErrMsg, actual["message"])
+	assert.Equal(t, mockIOErrMsg, actual.Detail)
 }
 
 func TestCTRLSave_UnmarshalError(t *testing.T) {
@@ -434,12 +596,12 @@ func TestCTRLSave_UnmarshalError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, "unexpected EOF", actual["message"])
+	assert.Equal(t, "unexpected EOF", actual.Detail)
 }
 
 func TestCTRLSave_ValidationError_MissingName(t *testing.T) {
@@ -457,14 +619,15 @@ func TestCTRLSave_ValidationError_MissingName(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Name")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "name", Rule: "required", Message: "name is required"},
+	}, actual.Violations)
 }
 
 func TestCTRLSave_ValidationError_MissingDesc(t *testing.T) {
@@ -482,14 +645,15 @@ func TestCTRLSave_ValidationError_MissingDesc(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Email")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "email", Rule: "required", Message: "email is required"},
+	}, actual.Violations)
 }
 
 func TestCTRLSave_ValidationError_MissingNameAndDesc(t *testing.T) {
@@ -507,15 +671,75 @@ func TestCTRLSave_ValidationError_MissingNameAndDesc(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "name", Rule: "required", Message: "name is required"},
+		{Field: "email", Rule: "required", Message: "email is required"},
+	}, actual.Violations)
+}
+
+func TestCTRLSave_ValidationError_InvalidFields(t *testing.T) {
+	u := user.User{
+		Name:  strings.Repeat("a", 101),
+		Email: "not-an-email",
+	}
+
+	c, _ := initCTRL()
+	gc, w, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	c.Save(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "org_id", Rule: "required", Message: "org_id is required"},
+		{Field: "name", Rule: "max", Message: "name must be at most 100 characters"},
+		{Field: "email", Rule: "email", Message: "email must be a valid email"},
+	}, actual.Violations)
+}
+
+func TestCTRLSave_ID_ValidatedByService(t *testing.T) {
+	// an update's unchanged fields don't have to follow the rules, so only the
+	// service can tell
+	u := user.User{
+		ID:    "body-foo-id",
+		OrgID: "foo-org-id",
+		Name:  "foo-name",
+		Email: "not-an-email",
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	mockErr := validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "email", Rule: "email", Message: "email must be a valid email"},
+	}}
+	ms.On("Save", mock.Anything, u).Return(user.User{}, mockErr)
+
+	c.Save(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Name")
-	assert.Contains(t, errMsg, "Email")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, mockErr.Violations, actual.Violations)
 }
 
 func TestCTRLSave_NotFoundError(t *testing.T) {
@@ -537,12 +761,12 @@ func TestCTRLSave_NotFoundError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 404, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_CannotModifySysUserError(t *testing.T) {
@@ -564,12 +788,12 @@ func TestCTRLSave_CannotModifySysUserError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 403, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_CannotAssociateSysOrgError(t *testing.T) {
@@ -591,12 +815,12 @@ func TestCTRLSave_CannotAssociateSysOrgError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 403, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_OptimisticLockError(t *testing.T) {
@@ -618,12 +842,12 @@ func TestCTRLSave_OptimisticLockError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 409, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_EmailAlreadyInUseError(t *testing.T) {
@@ -645,12 +869,12 @@ func TestCTRLSave_EmailAlreadyInUseError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 409, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_OrgNotFoundError(t *testing.T) {
@@ -672,12 +896,12 @@ func TestCTRLSave_OrgNotFoundError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLSave_ServiceError(t *testing.T) {
@@ -699,12 +923,12 @@ func TestCTRLSave_ServiceError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 500, gc.Writer.Status())
-	assert.Equal(t, mockErr.Error(), actual["message"])
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
 func TestCTRLDelete(t *testing.T) {
@@ -746,12 +970,12 @@ func TestCTRLDelete_ReadError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, mockIOErrMsg, actual["message"])
+	assert.Equal(t, mockIOErrMsg, actual.Detail)
 }
 
 func TestCTRLDelete_UnmarshalError(t *testing.T) {
@@ -772,12 +996,12 @@ func TestCTRLDelete_UnmarshalError(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	assert.Equal(t, "unexpected EOF", actual["message"])
+	assert.Equal(t, "unexpected EOF", actual.Detail)
 }
 
 func TestCTRLDelete_ValidationError_MissingVersion(t *testing.T) {
@@ -800,14 +1024,15 @@ func TestCTRLDelete_ValidationError_MissingVersion(t *testing.T) {
 	res := w.Result()
 	bytes, err := io.ReadAll(res.Body)
 	assert.Nil(t, err)
-	var actual map[string]string
+	var actual problem.Problem
 	err = json.Unmarshal(bytes, &actual)
 	assert.Nil(t, err)
 	defer res.Body.Close()
 	assert.Equal(t, 400, gc.Writer.Status())
-	errMsg := actual["message"]
-	assert.Contains(t, errMsg, "required")
-	assert.Contains(t, errMsg, "Version")
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "version", Rule: "required", Message: "version is required"},
+	}, actual.Violations)
 }
 
 func TestCTRLDelete_NotFoundError(t *testing.T) {
@@ -906,27 +1131,1287 @@ func TestCTRLDelete_ServiceError(t *testing.T) {
 	assert.Equal(t, 500, gc.Writer.Status())
 }
 
-func (m *mockSVC) GetByID(ctx context.Context, id string) (user.User, error) {
-	args := m.Called(ctx, id)
-	return args.Get(0).(user.User), args.Error(1)
+func TestCTRLGetByID_ForbiddenError(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:read"}
+	ms.On("GetByID", mock.Anything, id, false).Return(user.User{}, mockErr)
+
+	c.GetByID(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, mockErr.Error(), actual.Detail)
 }
 
-func (m *mockSVC) GetAll(ctx context.Context) ([]user.User, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]user.User), args.Error(1)
+func TestCTRLGetAll_ForbiddenError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:list"}
+	ms.On("GetAll", mock.Anything, user.Query{}, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, mockErr)
+
+	c.GetAll(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
 }
 
-func (m *mockSVC) GetAllByOrgID(ctx context.Context, orgID string) ([]user.User, error) {
-	args := m.Called(ctx, orgID)
-	return args.Get(0).([]user.User), args.Error(1)
+func TestCTRLGetAllByOrgID_ForbiddenError(t *testing.T) {
+	orgID := "foo-org-id"
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: orgID,
+		},
+	}
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:list"}
+	ms.On("GetAllByOrgID", mock.Anything, orgID, false, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, mockErr)
+
+	c.GetAllByOrgID(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
 }
 
-func (m *mockSVC) Save(ctx context.Context, u user.User) (user.User, error) {
-	args := m.Called(ctx, u)
-	return args.Get(0).(user.User), args.Error(1)
+func TestCTRLSave_ForbiddenError(t *testing.T) {
+	u := user.User{
+		ID:    "body-foo-id",
+		OrgID: "foo-org-id",
+		Name:  "foo-name",
+		Email: "foo@bar.com",
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:update"}
+	ms.On("Save", mock.Anything, u).Return(user.User{}, mockErr)
+
+	c.Save(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
 }
 
-func (m *mockSVC) Delete(ctx context.Context, u user.DeleteUser) error {
-	args := m.Called(ctx, u)
-	return args.Error(0)
+func TestCTRLDelete_ForbiddenError(t *testing.T) {
+	u := user.DeleteUser{
+		ID:      "body-foo-id",
+		Version: 1,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: u.ID,
+		},
+	}
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:delete"}
+	ms.On("Delete", mock.Anything, u).Return(mockErr)
+
+	c.Delete(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLGetByID_IncludeDeleted(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?include_deleted=true")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, true).Return(user.User{ID: id}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLGetByID_InvalidIncludeDeleted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?include_deleted=maybe")
+	assert.Nil(t, err)
+
+	c.GetByID(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Contains(t, w.Body.String(), "include_deleted")
+	ms.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLGetAll_IncludeDeleted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?include_deleted=true")
+	assert.Nil(t, err)
+
+	ms.On("GetAll", mock.Anything, user.Query{}, true, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{}, nil)
+
+	c.GetAll(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	ms.AssertExpectations(t)
+}
+
+func TestCTRLGetAllByOrgID_InvalidIncludeDeleted(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/?include_deleted=maybe")
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-org-id",
+		},
+	}
+
+	c.GetAllByOrgID(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "GetAllByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLRestore(t *testing.T) {
+	u := user.RestoreUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", user.RestoreUser{Version: u.Version})
+	assert.Nil(t, err)
+
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: u.ID,
+		},
+	}
+
+	mockRes := user.User{ID: u.ID, Name: "foo-name", Version: 3}
+	ms.On("Restore", mock.Anything, u).Return(mockRes, nil)
+
+	c.Restore(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	var actual user.User
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLRestore_ValidationError_MissingVersion(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", user.RestoreUser{ID: "foo-id"})
+	assert.Nil(t, err)
+
+	c.Restore(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
+
+func TestCTRLRestore_NotFoundErr(t *testing.T) {
+	u := user.RestoreUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, u).Return(user.User{}, ErrNotFound{ID: u.ID})
+
+	c.Restore(gc)
+	assert.Equal(t, 404, gc.Writer.Status())
+}
+
+func TestCTRLRestore_OrgDeletedErr(t *testing.T) {
+	u := user.RestoreUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, u).Return(user.User{}, org.ErrNotFound{ID: "foo-org-id"})
+
+	c.Restore(gc)
+	assert.Equal(t, 409, gc.Writer.Status())
+}
+
+func TestCTRLRestore_OptimisticLockError(t *testing.T) {
+	u := user.RestoreUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, u).Return(user.User{}, ErrOptimisticLock{ID: u.ID, Version: u.Version})
+
+	c.Restore(gc)
+	assert.Equal(t, 409, gc.Writer.Status())
+}
+
+func TestCTRLRestore_ForbiddenError(t *testing.T) {
+	u := user.RestoreUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	mockErr := authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:restore"}
+	ms.On("Restore", mock.Anything, u).Return(user.User{}, mockErr)
+
+	c.Restore(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLRestore_ServiceError(t *testing.T) {
+	u := user.RestoreUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	ms.On("Restore", mock.Anything, u).Return(user.User{}, errors.New("unit-test mock error"))
+
+	c.Restore(gc)
+	assert.Equal(t, 500, gc.Writer.Status())
+}
+
+func TestCTRLRequestEmailChange(t *testing.T) {
+	id := "foo-id"
+	ec := user.EmailChange{Email: "new@bar.com"}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", ec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	mockRes := user.PendingEmailChange{UserID: id, Email: ec.Email, ExpiresAt: time.UnixMilli(100).UTC()}
+	ms.On("RequestEmailChange", mock.Anything, id, ec).Return(mockRes, nil)
+
+	c.RequestEmailChange(gc)
+	var actual user.PendingEmailChange
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, 202, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLRequestEmailChange_InvalidEmail(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", user.EmailChange{Email: "not-an-email"})
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: "foo-id"}}
+
+	c.RequestEmailChange(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "RequestEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLRequestEmailChange_EmailTooLong(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", user.EmailChange{Email: strings.Repeat("a", 247) + "@bar.com"})
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: "foo-id"}}
+
+	c.RequestEmailChange(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "RequestEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLRequestEmailChange_UnchangedErr(t *testing.T) {
+	id := "foo-id"
+	ec := user.EmailChange{Email: "foo@bar.com"}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", ec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	ms.On("RequestEmailChange", mock.Anything, id, ec).Return(user.PendingEmailChange{}, ErrEmailUnchanged{Email: ec.Email})
+
+	c.RequestEmailChange(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLRequestEmailChange_ForbiddenErr(t *testing.T) {
+	id := "foo-id"
+	ec := user.EmailChange{Email: "new@bar.com"}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", ec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	ms.On("RequestEmailChange", mock.Anything, id, ec).Return(user.PendingEmailChange{}, authz.ErrForbidden{UserID: "logged-in-user-id", Action: "user:change_email"})
+
+	c.RequestEmailChange(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+}
+
+func TestCTRLConfirmEmailChange(t *testing.T) {
+	id := "foo-id"
+	cec := user.ConfirmEmailChange{Token: "foo-token"}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", cec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	mockRes := user.User{ID: id, Email: "new@bar.com", Version: 4}
+	ms.On("ConfirmEmailChange", mock.Anything, id, cec).Return(mockRes, nil)
+
+	c.ConfirmEmailChange(gc)
+	var actual user.User
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLConfirmEmailChange_MissingToken(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", user.ConfirmEmailChange{})
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: "foo-id"}}
+
+	c.ConfirmEmailChange(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "ConfirmEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLConfirmEmailChange_InvalidTokenErr(t *testing.T) {
+	id := "foo-id"
+	cec := user.ConfirmEmailChange{Token: "foo-token"}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", cec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	ms.On("ConfirmEmailChange", mock.Anything, id, cec).Return(user.User{}, ErrInvalidEmailChangeToken{UserID: id})
+
+	c.ConfirmEmailChange(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLConfirmEmailChange_NoPendingErr(t *testing.T) {
+	id := "foo-id"
+	cec := user.ConfirmEmailChange{Token: "foo-token"}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", cec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	ms.On("ConfirmEmailChange", mock.Anything, id, cec).Return(user.User{}, ErrNoPendingEmailChange{UserID: id})
+
+	c.ConfirmEmailChange(gc)
+	assert.Equal(t, 404, gc.Writer.Status())
+}
+
+func TestCTRLConfirmEmailChange_ExpiredErr(t *testing.T) {
+	id := "foo-id"
+	cec := user.ConfirmEmailChange{Token: "foo-token"}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", cec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	ms.On("ConfirmEmailChange", mock.Anything, id, cec).Return(user.User{}, ErrEmailChangeExpired{UserID: id})
+
+	c.ConfirmEmailChange(gc)
+	assert.Equal(t, 410, gc.Writer.Status())
+}
+
+func TestCTRLConfirmEmailChange_EmailInUseErr(t *testing.T) {
+	id := "foo-id"
+	cec := user.ConfirmEmailChange{Token: "foo-token"}
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", cec)
+	assert.Nil(t, err)
+	gc.Params = []gin.Param{{Key: "id", Value: id}}
+
+	ms.On("ConfirmEmailChange", mock.Anything, id, cec).Return(user.User{}, ErrEmailAlreadyInUse{Email: "new@bar.com"})
+
+	c.ConfirmEmailChange(gc)
+	assert.Equal(t, 409, gc.Writer.Status())
+}
+
+func TestCTRLGetByID_NotModified(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-None-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, false).Return(user.User{ID: id, Version: 3}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 304, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:3"`, w.Header().Get("ETag"))
+	assert.Equal(t, 0, w.Body.Len())
+}
+
+func TestCTRLGetByID_ETag(t *testing.T) {
+	id := "foo-id"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-None-Match", `"foo-id:2"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: id,
+		},
+	}
+
+	ms.On("GetByID", mock.Anything, id, false).Return(user.User{ID: id, Version: 3}, nil)
+
+	c.GetByID(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:3"`, w.Header().Get("ETag"))
+}
+
+func TestCTRLSave_IfMatch(t *testing.T) {
+	u := user.User{
+		OrgID:   "foo-org-id",
+		Name:    "foo-name",
+		Email:   "foo@bar.com",
+		Version: 1,
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithBody("/", u)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	expected := u
+	expected.ID = "foo-id"
+	expected.Version = 3
+	mockRes := expected
+	mockRes.Version = 4
+	ms.On("Save", mock.Anything, expected).Return(mockRes, nil)
+
+	c.Save(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:4"`, w.Header().Get("ETag"))
+}
+
+func TestCTRLSave_IfMatchOtherID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"})
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"bar-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	c.Save(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestCTRLSave_IfMatchOptimisticLockError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithBody("/", user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"})
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	mockErr := ErrOptimisticLock{ID: "foo-id", Version: 3}
+	ms.On("Save", mock.Anything, mock.Anything).Return(user.User{}, mockErr)
+
+	c.Save(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+}
+
+func patchCtx(contentType string, body string) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gc, w, err := ginCtxWithStrBody("/", &body)
+	if err != nil {
+		return gc, w, err
+	}
+	gc.Request.Method = "PATCH"
+	gc.Request.Header.Set("Content-Type", contentType)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+	return gc, w, nil
+}
+
+// patchWithErr is a merge patch the service fails with mockErr.
+func patchWithErr(t *testing.T, mockErr error) (*gin.Context, problem.Problem) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx(pkgpatch.ContentTypeMerge, `{"is_active":false}`)
+	assert.Nil(t, err)
+
+	ms.On("Patch", mock.Anything, "foo-id", int64(0), mock.Anything).Return(user.User{}, mockErr)
+
+	c.Patch(gc)
+	var actual problem.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	return gc, actual
+}
+
+func TestCTRLPatch(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx(pkgpatch.ContentTypeMerge, `{"is_active":false}`)
+	assert.Nil(t, err)
+
+	expectedPatch := patch.Patch{ContentType: pkgpatch.ContentTypeMerge, Body: []byte(`{"is_active":false}`)}
+	mockRes := user.User{ID: "foo-id", OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", Version: 4}
+	ms.On("Patch", mock.Anything, "foo-id", int64(0), expectedPatch).Return(mockRes, nil)
+
+	c.Patch(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, `"foo-id:4"`, w.Header().Get("ETag"))
+	var actual user.User
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestCTRLPatch_IfMatch(t *testing.T) {
+	c, ms := initCTRL()
+	body := `[{"op":"replace","path":"/is_admin","value":true}]`
+	gc, _, err := patchCtx(pkgpatch.ContentTypeJSON, body)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	expectedPatch := patch.Patch{ContentType: pkgpatch.ContentTypeJSON, Body: []byte(body)}
+	ms.On("Patch", mock.Anything, "foo-id", int64(3), expectedPatch).Return(user.User{ID: "foo-id", Version: 4}, nil)
+
+	c.Patch(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+}
+
+func TestCTRLPatch_ReadError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithIOErr("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("Content-Type", pkgpatch.ContentTypeMerge)
+
+	c.Patch(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_UnsupportedError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := patchCtx("application/json", `{"is_active":false}`)
+	assert.Nil(t, err)
+
+	c.Patch(gc)
+	assert.Equal(t, 415, gc.Writer.Status())
+	var actual problem.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, problem.CodeUnsupportedPatch, actual.Code)
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_MalformedError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := patchCtx(pkgpatch.ContentTypeMerge, `{"is_active":`)
+	assert.Nil(t, err)
+
+	c.Patch(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_IfMatchOtherID(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := patchCtx(pkgpatch.ContentTypeMerge, `{"is_active":false}`)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"bar-id:3"`)
+
+	c.Patch(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+	ms.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCTRLPatch_IfMatchOptimisticLockError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := patchCtx(pkgpatch.ContentTypeMerge, `{"is_active":false}`)
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	mockErr := ErrOptimisticLock{ID: "foo-id", Version: 3}
+	ms.On("Patch", mock.Anything, "foo-id", int64(3), mock.Anything).Return(user.User{}, mockErr)
+
+	c.Patch(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+}
+
+func TestCTRLPatch_NotFoundError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrNotFound{ID: "foo-id"})
+	assert.Equal(t, 404, gc.Writer.Status())
+	assert.Equal(t, problem.CodeUserNotFound, actual.Code)
+}
+
+func TestCTRLPatch_CannotModifySysUserError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrCannotModifySysUser{ID: "foo-id"})
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, problem.CodeSysUserReadOnly, actual.Code)
+}
+
+func TestCTRLPatch_OptimisticLockError(t *testing.T) {
+	gc, actual := patchWithErr(t, ErrOptimisticLock{ID: "foo-id", Version: 2})
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, problem.CodeUserVersionConflict, actual.Code)
+}
+
+func TestCTRLPatch_ValidationError(t *testing.T) {
+	gc, actual := patchWithErr(t, validate.ErrInvalid{Violations: []problem.Violation{validate.ReadOnly("email")}})
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "email", Rule: "readonly", Message: "email is read only"},
+	}, actual.Violations)
+}
+
+func TestCTRLPatch_MalformedPatchError(t *testing.T) {
+	gc, actual := patchWithErr(t, patch.ErrMalformed{Reason: "unit-test reason"})
+	assert.Equal(t, 400, gc.Writer.Status())
+	assert.Equal(t, problem.CodeMalformedPatch, actual.Code)
+}
+
+func TestCTRLPatch_TestFailedError(t *testing.T) {
+	gc, actual := patchWithErr(t, patch.ErrTestFailed{Path: "/version"})
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, problem.CodePatchTestFailed, actual.Code)
+}
+
+func TestCTRLPatch_ForbiddenError(t *testing.T) {
+	gc, actual := patchWithErr(t, authz.ErrForbidden{UserID: "foo-user-id", Action: "user:update"})
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, problem.CodeForbidden, actual.Code)
+}
+
+func TestCTRLPatch_ServiceError(t *testing.T) {
+	gc, actual := patchWithErr(t, errors.New("unit-test mock error"))
+	assert.Equal(t, 500, gc.Writer.Status())
+	assert.Equal(t, problem.CodeInternal, actual.Code)
+}
+
+func TestCTRLDelete_IfMatch(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	ms.On("Delete", mock.Anything, user.DeleteUser{ID: "foo-id", Version: 3}).Return(nil)
+
+	c.Delete(gc)
+	assert.Equal(t, 204, gc.Writer.Status())
+}
+
+func TestCTRLDelete_IfMatchOptimisticLockError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, _, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	gc.Request.Header.Set("If-Match", `"foo-id:3"`)
+	gc.Params = []gin.Param{
+		{
+			Key:   "id",
+			Value: "foo-id",
+		},
+	}
+
+	mockErr := ErrOptimisticLock{ID: "foo-id", Version: 3}
+	ms.On("Delete", mock.Anything, user.DeleteUser{ID: "foo-id", Version: 3}).Return(mockErr)
+
+	c.Delete(gc)
+	assert.Equal(t, 412, gc.Writer.Status())
+}
+
+func (m *mockSVC) GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error) {
+	args := m.Called(ctx, id, includeDeleted)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) GetAll(ctx context.Context, q user.Query, includeDeleted bool, pr page.Request) (user.UserPage, error) {
+	args := m.Called(ctx, q, includeDeleted, pr)
+	return args.Get(0).(user.UserPage), args.Error(1)
+}
+
+func (m *mockSVC) GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, pr page.Request) (user.UserPage, error) {
+	args := m.Called(ctx, orgID, includeDeleted, pr)
+	return args.Get(0).(user.UserPage), args.Error(1)
+}
+
+func (m *mockSVC) Save(ctx context.Context, u user.User) (user.User, error) {
+	args := m.Called(ctx, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) Patch(ctx context.Context, id string, version int64, changes patch.Patch) (user.User, error) {
+	args := m.Called(ctx, id, version, changes)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) Create(ctx context.Context, joinTX *sqlx.Tx, u user.User) (user.User, error) {
+	args := m.Called(ctx, joinTX, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) CreateInOrg(ctx context.Context, joinTX *sqlx.Tx, o pkgorg.Org, u user.User) (user.User, error) {
+	args := m.Called(ctx, joinTX, o, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) SaveInvited(ctx context.Context, joinTX *sqlx.Tx, u user.User) (user.User, error) {
+	args := m.Called(ctx, joinTX, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) Batch(ctx context.Context, b user.Batch) ([]BatchResult, error) {
+	args := m.Called(ctx, b)
+	results, _ := args.Get(0).([]BatchResult)
+	return results, args.Error(1)
+}
+
+func (m *mockSVC) Export(ctx context.Context) iter.Seq2[user.User, error] {
+	args := m.Called(ctx)
+	return args.Get(0).(iter.Seq2[user.User, error])
+}
+
+// mockRow is a row Import was handed, the rows are read before the call is
+// matched so the tests can check what the controller decoded.
+type mockRow struct {
+	User user.User
+	Err  error
+}
+
+func (m *mockSVC) Import(ctx context.Context, rows iter.Seq2[user.User, error], dryRun bool) (ImportResult, error) {
+	var read []mockRow
+	for u, err := range rows {
+		read = append(read, mockRow{User: u, Err: err})
+	}
+	args := m.Called(ctx, read, dryRun)
+	return args.Get(0).(ImportResult), args.Error(1)
+}
+
+func (m *mockSVC) Delete(ctx context.Context, u user.DeleteUser) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *mockSVC) Restore(ctx context.Context, u user.RestoreUser) (user.User, error) {
+	args := m.Called(ctx, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (m *mockSVC) RequestEmailChange(ctx context.Context, id string, ec user.EmailChange) (user.PendingEmailChange, error) {
+	args := m.Called(ctx, id, ec)
+	return args.Get(0).(user.PendingEmailChange), args.Error(1)
+}
+
+func (m *mockSVC) ConfirmEmailChange(ctx context.Context, id string, cec user.ConfirmEmailChange) (user.User, error) {
+	args := m.Called(ctx, id, cec)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func batchCtx(method string, b user.Batch) (*gin.Context, *httptest.ResponseRecorder, error) {
+	gc, w, err := ginCtxWithBody("/", b)
+	if err != nil {
+		return gc, w, err
+	}
+	gc.Params = []gin.Param{
+		{
+			Key:   "method",
+			Value: method,
+		},
+	}
+	return gc, w, nil
+}
+
+func batchRes(t *testing.T, w *httptest.ResponseRecorder) user.BatchResponse {
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	var actual user.BatchResponse
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	return actual
+}
+
+func TestCTRLBatch(t *testing.T) {
+	b := user.Batch{
+		Mode: user.BatchModeAllOrNothing,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpCreate, User: user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpDelete, User: user.User{ID: "bar-id", Version: 2}},
+		},
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	created := user.User{ID: "foo-id", OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", Version: 1}
+	ms.On("Batch", mock.Anything, b).Return([]BatchResult{{User: created}, {}}, nil)
+
+	c.Batch(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, user.BatchResponse{Results: []user.BatchResult{
+		{Op: user.BatchOpCreate, Status: 200, User: &created},
+		{Op: user.BatchOpDelete, Status: 204},
+	}}, batchRes(t, w))
+}
+
+func TestCTRLBatch_AllOrNothingFailed(t *testing.T) {
+	b := user.Batch{
+		Mode: user.BatchModeAllOrNothing,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpCreate, User: user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpUpdate, User: user.User{ID: "bar-id", OrgID: "foo-org-id", Name: "bar-name", Email: "bar@baz.com", Version: 2}},
+		},
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	aborted := ErrBatchAborted{FailedIndex: 1}
+	optLock := ErrOptimisticLock{ID: "bar-id", Version: 2}
+	ms.On("Batch", mock.Anything, b).Return([]BatchResult{{Err: aborted}, {Err: optLock}}, nil)
+
+	c.Batch(gc)
+	assert.Equal(t, 409, gc.Writer.Status())
+	assert.Equal(t, user.BatchResponse{Results: []user.BatchResult{
+		{Op: user.BatchOpCreate, Status: 424, Code: problem.CodeBatchAborted, Message: aborted.Error()},
+		{Op: user.BatchOpUpdate, Status: 409, Code: problem.CodeUserVersionConflict, Message: optLock.Error()},
+	}}, batchRes(t, w))
+}
+
+func TestCTRLBatch_BestEffortPartial(t *testing.T) {
+	b := user.Batch{
+		Mode: user.BatchModeBestEffort,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpCreate, User: user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpDelete, User: user.User{ID: "sys-id", Version: 1}},
+		},
+	}
+
+	c, ms := initCTRL()
+	gc, w, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	dupEmail := ErrEmailAlreadyInUse{Email: "foo@bar.com"}
+	sysUser := ErrCannotModifySysUser{ID: "sys-id"}
+	ms.On("Batch", mock.Anything, b).Return([]BatchResult{{Err: dupEmail}, {Err: sysUser}}, nil)
+
+	c.Batch(gc)
+	assert.Equal(t, 207, gc.Writer.Status())
+	assert.Equal(t, user.BatchResponse{Results: []user.BatchResult{
+		{Op: user.BatchOpCreate, Status: 409, Code: problem.CodeEmailInUse, Message: dupEmail.Error()},
+		{Op: user.BatchOpDelete, Status: 403, Code: problem.CodeSysUserReadOnly, Message: sysUser.Error()},
+	}}, batchRes(t, w))
+}
+
+func TestCTRLBatch_UnknownMethod(t *testing.T) {
+	c, _ := initCTRL()
+	gc, _, err := batchCtx(":import", user.Batch{})
+	assert.Nil(t, err)
+
+	c.Batch(gc)
+	assert.Equal(t, 404, gc.Writer.Status())
+}
+
+func TestCTRLBatch_ValidationError_InvalidMode(t *testing.T) {
+	b := user.Batch{
+		Mode: "foo",
+		Ops:  []user.BatchOp{{Op: user.BatchOpDelete, User: user.User{ID: "bar-id", Version: 1}}},
+	}
+
+	c, _ := initCTRL()
+	gc, _, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	c.Batch(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLBatch_ValidationError_CreateMissingEmail(t *testing.T) {
+	b := user.Batch{
+		Mode: user.BatchModeBestEffort,
+		Ops:  []user.BatchOp{{Op: user.BatchOpCreate, User: user.User{OrgID: "foo-org-id", Name: "foo-name"}}},
+	}
+
+	c, _ := initCTRL()
+	gc, w, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	c.Batch(gc)
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, 400, gc.Writer.Status())
+	var actual problem.Problem
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, problem.CodeValidationFailed, actual.Code)
+	assert.Equal(t, []problem.Violation{
+		{Field: "ops[0].user.email", Rule: "required", Message: "ops[0].user.email is required"},
+	}, actual.Violations)
+}
+
+func TestCTRLBatch_ValidationError_UpdateMissingID(t *testing.T) {
+	b := user.Batch{
+		Mode: user.BatchModeBestEffort,
+		Ops:  []user.BatchOp{{Op: user.BatchOpUpdate, User: user.User{Name: "foo-name", Email: "foo@bar.com"}}},
+	}
+
+	c, _ := initCTRL()
+	gc, _, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	c.Batch(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLBatch_ServiceError(t *testing.T) {
+	b := user.Batch{
+		Mode: user.BatchModeBestEffort,
+		Ops:  []user.BatchOp{{Op: user.BatchOpDelete, User: user.User{ID: "bar-id", Version: 1}}},
+	}
+
+	c, ms := initCTRL()
+	gc, _, err := batchCtx(":batch", b)
+	assert.Nil(t, err)
+
+	ms.On("Batch", mock.Anything, b).Return(nil, authz.ErrUnauthenticated{})
+
+	c.Batch(gc)
+	assert.Equal(t, 401, gc.Writer.Status())
+}
+
+// seqOf yields the users, then err if there is one.
+func seqOf(users []user.User, err error) iter.Seq2[user.User, error] {
+	return func(yield func(user.User, error) bool) {
+		for _, u := range users {
+			if !yield(u, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(user.User{}, err)
+		}
+	}
+}
+
+func exportUsers() []user.User {
+	return []user.User{
+		{ID: "foo-id", OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsActive: true, CreatedAt: time.UnixMilli(100).UTC(), UpdatedAt: time.UnixMilli(200).UTC(), Version: 1},
+		{ID: "bar-id", OrgID: "foo-org-id", Name: "bar, name", Email: "bar@baz.com", IsAdmin: true, CreatedAt: time.UnixMilli(100).UTC(), UpdatedAt: time.UnixMilli(200).UTC(), Version: 2},
+	}
+}
+
+func TestCTRLExport_CSV(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(exportUsers(), nil))
+
+	c.Export(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="users.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,org_id,name,email,is_system,is_admin,is_active,created_at,created_by,updated_at,updated_by,version\n"+
+		"foo-id,foo-org-id,foo-name,foo@bar.com,false,false,true,1970-01-01T00:00:00.1Z,,1970-01-01T00:00:00.2Z,,1\n"+
+		"bar-id,foo-org-id,\"bar, name\",bar@baz.com,false,true,false,1970-01-01T00:00:00.1Z,,1970-01-01T00:00:00.2Z,,2\n", w.Body.String())
+}
+
+func TestCTRLExport_NDJSON(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?format=ndjson")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(exportUsers(), nil))
+
+	c.Export(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	var actual user.User
+	err = json.Unmarshal([]byte(lines[1]), &actual)
+	assert.Nil(t, err)
+	assert.Equal(t, exportUsers()[1], actual)
+}
+
+func TestCTRLExport_Empty(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(nil, nil))
+
+	c.Export(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, "id,org_id,name,email,is_system,is_admin,is_active,created_at,created_by,updated_at,updated_by,version\n", w.Body.String())
+}
+
+func TestCTRLExport_InvalidFormat(t *testing.T) {
+	c, _ := initCTRL()
+	gc, _, err := ginCtx("/?format=xml")
+	assert.Nil(t, err)
+
+	c.Export(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLExport_ForbiddenError(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(nil, authz.ErrForbidden{UserID: "foo-id", Action: "user:list"}))
+
+	c.Export(gc)
+	assert.Equal(t, 403, gc.Writer.Status())
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+}
+
+func TestCTRLExport_ServiceErrorAfterFirstRow(t *testing.T) {
+	c, ms := initCTRL()
+	gc, w, err := ginCtx("/?format=ndjson")
+	assert.Nil(t, err)
+
+	ms.On("Export", mock.Anything).Return(seqOf(exportUsers()[:1], errors.New("unit-test mock error")))
+
+	// the status was sent with the first row, so the connection is dropped
+	// to tell the client it's cut short
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { c.Export(gc) })
+	assert.Len(t, strings.Split(strings.TrimSpace(w.Body.String()), "\n"), 1)
+}
+
+func importRes(t *testing.T, w *httptest.ResponseRecorder) user.ImportReport {
+	res := w.Result()
+	bytes, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	var actual user.ImportReport
+	err = json.Unmarshal(bytes, &actual)
+	assert.Nil(t, err)
+	return actual
+}
+
+func TestCTRLImport_CSV(t *testing.T) {
+	body := "org_id,name,email,is_admin\nfoo-org-id,foo-name,foo@bar.com,true\nfoo-org-id,bar-name,,false\nfoo-org-id,baz-name,baz@bar.com,false\n"
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithStrBody("/", &body)
+	assert.Nil(t, err)
+
+	dupEmail := ErrEmailAlreadyInUse{Email: "baz@bar.com"}
+	ms.On("Import", mock.Anything, mock.MatchedBy(func(rows []mockRow) bool {
+		var invalidRow dataio.ErrInvalidRow
+		return len(rows) == 3 &&
+			rows[0] == mockRow{User: user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsAdmin: true}} &&
+			errors.As(rows[1].Err, &invalidRow) &&
+			rows[2] == mockRow{User: user.User{OrgID: "foo-org-id", Name: "baz-name", Email: "baz@bar.com"}}
+	}), false).Return(ImportResult{
+		Rows:     3,
+		Imported: 1,
+		Errors: []ImportRowError{
+			{Row: 2, Err: dataio.ErrInvalidRow{Reason: "email is required"}},
+			{Row: 3, Err: dupEmail},
+		},
+	}, nil)
+
+	c.Import(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, user.ImportReport{
+		Rows:     3,
+		Imported: 1,
+		Errors: []user.ImportError{
+			{Row: 2, Status: 400, Code: problem.CodeInvalidRow, Message: dataio.ErrInvalidRow{Reason: "email is required"}.Error()},
+			{Row: 3, Status: 409, Code: problem.CodeEmailInUse, Message: dupEmail.Error()},
+		},
+	}, importRes(t, w))
+}
+
+func TestCTRLImport_NDJSONDryRun(t *testing.T) {
+	body := `{"org_id":"foo-org-id","name":"foo-name","email":"foo@bar.com"}`
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithStrBody("/?format=ndjson&dry_run=true", &body)
+	assert.Nil(t, err)
+
+	rows := []mockRow{{User: user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"}}}
+	ms.On("Import", mock.Anything, rows, true).Return(ImportResult{Rows: 1, Imported: 1}, nil)
+
+	c.Import(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, user.ImportReport{DryRun: true, Rows: 1, Imported: 1, Errors: []user.ImportError{}}, importRes(t, w))
+}
+
+func TestCTRLImport_Stopped(t *testing.T) {
+	body := ""
+
+	c, ms := initCTRL()
+	gc, w, err := ginCtxWithStrBody("/?format=ndjson", &body)
+	assert.Nil(t, err)
+
+	malformed := dataio.ErrMalformed{Reason: "unexpected EOF"}
+	ms.On("Import", mock.Anything, mock.Anything, false).Return(ImportResult{
+		Rows:     2,
+		Imported: 2,
+		Stopped:  &ImportRowError{Row: 3, Err: malformed},
+	}, nil)
+
+	c.Import(gc)
+	assert.Equal(t, 200, gc.Writer.Status())
+	assert.Equal(t, user.ImportReport{
+		Rows:     2,
+		Imported: 2,
+		Errors:   []user.ImportError{},
+		Stopped: &user.ImportError{
+			Row:     3,
+			Status:  400,
+			Code:    problem.CodeMalformedBody,
+			Message: malformed.Error(),
+		},
+	}, importRes(t, w))
+}
+
+func TestCTRLImport_InvalidDryRun(t *testing.T) {
+	body := ""
+
+	c, _ := initCTRL()
+	gc, _, err := ginCtxWithStrBody("/?dry_run=foo", &body)
+	assert.Nil(t, err)
+
+	c.Import(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLImport_InvalidFormat(t *testing.T) {
+	body := ""
+
+	c, _ := initCTRL()
+	gc, _, err := ginCtxWithStrBody("/?format=xml", &body)
+	assert.Nil(t, err)
+
+	c.Import(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLImport_MissingColumn(t *testing.T) {
+	body := "name,email\nfoo-name,foo@bar.com\n"
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithStrBody("/", &body)
+	assert.Nil(t, err)
+
+	missingColumn := dataio.ErrMissingColumn{Column: "org_id"}
+	ms.On("Import", mock.Anything, []mockRow{{Err: missingColumn}}, false).Return(ImportResult{}, missingColumn)
+
+	c.Import(gc)
+	assert.Equal(t, 400, gc.Writer.Status())
+}
+
+func TestCTRLImport_ServiceError(t *testing.T) {
+	body := ""
+
+	c, ms := initCTRL()
+	gc, _, err := ginCtxWithStrBody("/", &body)
+	assert.Nil(t, err)
+
+	ms.On("Import", mock.Anything, mock.Anything, false).Return(ImportResult{}, errors.New("unit-test mock error"))
+
+	c.Import(gc)
+	assert.Equal(t, 500, gc.Writer.Status())
 }
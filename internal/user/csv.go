@@ -0,0 +1,45 @@
+package user
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
+
+var csvHeader = []string{"id", "org_id", "name", "email", "is_system", "is_admin", "is_active", "created_at", "created_by", "updated_at", "updated_by", "version"}
+
+// csvRequired are the columns an import has to have, the rest of an export's
+// columns are owned by the service and ignored.
+var csvRequired = []string{"org_id", "name", "email"}
+
+func toCSV(u user.User) []string {
+	return []string{
+		u.ID,
+		u.OrgID,
+		u.Name,
+		u.Email,
+		strconv.FormatBool(u.IsSystem),
+		strconv.FormatBool(u.IsAdmin),
+		strconv.FormatBool(u.IsActive),
+		u.CreatedAt.Format(time.RFC3339Nano),
+		u.CreatedBy,
+		u.UpdatedAt.Format(time.RFC3339Nano),
+		u.UpdatedBy,
+		strconv.FormatInt(u.Version, 10),
+	}
+}
+
+func fromCSV(row map[string]string) (u user.User, err error) {
+	u.OrgID = row["org_id"]
+	u.Name = row["name"]
+	u.Email = row["email"]
+	if u.IsAdmin, err = dataio.ParseBool(row, "is_admin"); err != nil {
+		return u, err
+	}
+	if u.IsActive, err = dataio.ParseBool(row, "is_active"); err != nil {
+		return u, err
+	}
+	return u, nil
+}
@@ -6,14 +6,19 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"regexp"
 	"time"
 
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
 	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 )
 
+var dupEmailRegex = regexp.MustCompile(`^Key \(email\)=\((.*)\) already exists`)
+
 type dao struct {
 	log     *slog.Logger
 	timeout time.Duration
@@ -28,7 +33,9 @@ func NewDAO(log *slog.Logger, timeout time.Duration, db *sqlx.DB) *dao {
 	}
 }
 
-func (d dao) GetByID(ctx context.Context, id string) (u user.User, err error) {
+func (d dao) GetByID(ctx context.Context, id string, includeDeleted bool) (u user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.GetByID")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -36,9 +43,11 @@ func (d dao) GetByID(ctx context.Context, id string) (u user.User, err error) {
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetByID"),
 		logAttrUserID(id),
+		logAttrIncludeDeleted(includeDeleted),
 	)
 	log.Debug("called")
-	err = d.db.GetContext(ctx, &u, getByIDQuery, id)
+	span.SetAttributes(tracing.AttrStatement("getByIDQuery"))
+	err = d.db.GetContext(ctx, &u, getByIDQuery, id, includeDeleted)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return u, ErrNotFound{ID: id}
@@ -49,16 +58,78 @@ func (d dao) GetByID(ctx context.Context, id string) (u user.User, err error) {
 	return u, err
 }
 
-func (d dao) GetAll(ctx context.Context) (users []user.User, err error) {
+func (d dao) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (u user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.GetByIDForUpdate")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByIDForUpdate"),
+		logAttrUserID(id),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getByIDForUpdateQuery"))
+	err = tx.GetContext(ctx, &u, getByIDForUpdateQuery, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u, ErrNotFound{ID: id}
+		}
+		return u, err
+	}
+	log.Debug("success")
+	return u, err
+}
+
+// GetByEmail reads in tx so the caller sees the users it has written, soft
+// deleted users are returned too.
+func (d dao) GetByEmail(ctx context.Context, tx *sqlx.Tx, email string) (u user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.GetByEmail")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetByEmail"),
+		logAttrEmail(email),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getByEmailQuery"))
+	err = tx.GetContext(ctx, &u, getByEmailQuery, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return u, ErrEmailNotFound{Email: email}
+		}
+		return u, err
+	}
+	log.Debug("success")
+	return u, err
+}
+
+func (d dao) GetAll(ctx context.Context, q user.Query, includeDeleted bool, after *page.Cursor, limit int) (users []user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.GetAll")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetAll"),
+		logAttrQuery(q),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(after),
+		logAttrLimit(limit),
 	)
 	log.Debug("called")
-	err = d.db.SelectContext(ctx, &users, getAllQuery)
+	users = []user.User{}
+	query, args, err := buildGetAllQuery(q, includeDeleted, after, limit)
+	if err != nil {
+		return users, err
+	}
+	span.SetAttributes(tracing.AttrStatement("getAllSelect"))
+	err = d.db.SelectContext(ctx, &users, query, args...)
 	if err != nil {
 		return users, err
 	}
@@ -66,7 +137,9 @@ func (d dao) GetAll(ctx context.Context) (users []user.User, err error) {
 	return users, err
 }
 
-func (d dao) GetAllByOrgID(ctx context.Context, orgID string) (users []user.User, err error) {
+func (d dao) GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, after *page.Cursor, limit int) (users []user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.GetAllByOrgID")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -74,10 +147,19 @@ func (d dao) GetAllByOrgID(ctx context.Context, orgID string) (users []user.User
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetAllByOrgID"),
 		logAttrOrgID(orgID),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(after),
+		logAttrLimit(limit),
 	)
 	log.Debug("called")
 	users = []user.User{}
-	err = d.db.SelectContext(ctx, &users, getAllByOrgIDQuery, orgID)
+	if after == nil {
+		span.SetAttributes(tracing.AttrStatement("getAllByOrgIDQuery"))
+		err = d.db.SelectContext(ctx, &users, getAllByOrgIDQuery, orgID, limit, includeDeleted)
+	} else {
+		span.SetAttributes(tracing.AttrStatement("getAllByOrgIDAfterQuery"))
+		err = d.db.SelectContext(ctx, &users, getAllByOrgIDAfterQuery, orgID, after.Key, after.CreatedAt, after.ID, limit, includeDeleted)
+	}
 	if err != nil {
 		return users, err
 	}
@@ -86,6 +168,8 @@ func (d dao) GetAllByOrgID(ctx context.Context, orgID string) (users []user.User
 }
 
 func (d dao) Create(ctx context.Context, tx *sqlx.Tx, u user.User) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.Create")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -95,6 +179,7 @@ func (d dao) Create(ctx context.Context, tx *sqlx.Tx, u user.User) (err error) {
 		logAttrUser(u),
 	)
 	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("createQuery"))
 	r, err := tx.NamedExecContext(ctx, createQuery, &u)
 	if err != nil {
 		var pqErr *pq.Error
@@ -117,7 +202,56 @@ func (d dao) Create(ctx context.Context, tx *sqlx.Tx, u user.User) (err error) {
 	return err
 }
 
+// CreateBatch inserts every user with a single multi row insert. A duplicate
+// email is reported for the first row postgres finds it on.
+func (d dao) CreateBatch(ctx context.Context, tx *sqlx.Tx, users []user.User) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.CreateBatch")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("CreateBatch"),
+		logAttrUsersLen(len(users)),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("createQuery"))
+	r, err := tx.NamedExecContext(ctx, createQuery, users)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			if pqErr.Constraint == "users_email_uk" {
+				return ErrEmailAlreadyInUse{Email: dupEmail(pqErr.Detail)}
+			}
+		}
+		return err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows != int64(len(users)) {
+		return fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	return err
+}
+
+// dupEmail pulls the email out of a unique violation's detail, ex.
+// Key (email)=(foo@bar.com) already exists.
+func dupEmail(detail string) string {
+	matches := dupEmailRegex.FindStringSubmatch(detail)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
 func (d dao) Update(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.Update")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -127,6 +261,7 @@ func (d dao) Update(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.U
 		logAttrUser(input),
 	)
 	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("updateQuery"))
 	r, err := tx.NamedExecContext(ctx, updateQuery, &input)
 	if err != nil {
 		var pqErr *pq.Error
@@ -153,7 +288,10 @@ func (d dao) Update(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.U
 	return input, err
 }
 
-func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, u user.DeleteUser) (err error) {
+// Delete is a soft delete, u needs DeletedAt and DeletedBy set.
+func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, u user.User) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.Delete")
+	defer func() { tracing.End(span, err) }()
 	ctx, cancel := context.WithTimeout(ctx, d.timeout)
 	defer cancel()
 	log := d.log.With(
@@ -163,6 +301,7 @@ func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, u user.DeleteUser) (err er
 		logAttrUser(u),
 	)
 	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("deleteQuery"))
 	r, err := tx.NamedExecContext(ctx, deleteQuery, &u)
 	if err != nil {
 		return err
@@ -181,3 +320,170 @@ func (d dao) Delete(ctx context.Context, tx *sqlx.Tx, u user.DeleteUser) (err er
 	log.Debug("success")
 	return err
 }
+
+// Restore undoes Delete, u needs UpdatedAt and UpdatedBy set.
+func (d dao) Restore(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.Restore")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Restore"),
+		logAttrUser(input),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("restoreQuery"))
+	r, err := tx.NamedExecContext(ctx, restoreQuery, &input)
+	if err != nil {
+		return u, err
+	}
+	log.Debug("query ran")
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return u, err
+	}
+	if numRows == 0 {
+		return u, ErrOptimisticLock{ID: input.ID, Version: input.Version}
+	}
+	if numRows != 1 {
+		return u, fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	input.Version = input.Version + 1
+	input.DeletedAt = nil
+	input.DeletedBy = ""
+	return input, err
+}
+
+// Purge hard deletes the users that were soft deleted before the cutoff.
+func (d dao) Purge(ctx context.Context, before time.Time) (numRows int64, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.Purge")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrFN("Purge"),
+		logAttrBefore(before),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("purgeQuery"))
+	r, err := d.db.ExecContext(ctx, purgeQuery, before)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err = r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	log.With(logAttrNumRows(numRows)).Debug("success")
+	return numRows, err
+}
+
+// SaveEmailChange replaces the user's pending email change, if there is one.
+func (d dao) SaveEmailChange(ctx context.Context, tx *sqlx.Tx, ec StoredEmailChange) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.SaveEmailChange")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("SaveEmailChange"),
+		logAttrUserID(ec.UserID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("saveEmailChangeQuery"))
+	if _, err = tx.NamedExecContext(ctx, saveEmailChangeQuery, &ec); err != nil {
+		return err
+	}
+	log.Debug("success")
+	return nil
+}
+
+// GetEmailChange locks the user's pending email change until tx ends.
+func (d dao) GetEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) (ec StoredEmailChange, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.GetEmailChange")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetEmailChange"),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("getEmailChangeQuery"))
+	err = tx.GetContext(ctx, &ec, getEmailChangeQuery, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ec, ErrNoPendingEmailChange{UserID: userID}
+		}
+		return ec, err
+	}
+	log.Debug("success")
+	return ec, nil
+}
+
+func (d dao) DeleteEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) (err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.DeleteEmailChange")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("DeleteEmailChange"),
+		logAttrUserID(userID),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("deleteEmailChangeQuery"))
+	if _, err = tx.ExecContext(ctx, deleteEmailChangeQuery, userID); err != nil {
+		return err
+	}
+	log.Debug("success")
+	return nil
+}
+
+// UpdateEmail is the only way a user's email changes, u needs UpdatedAt and
+// UpdatedBy set.
+func (d dao) UpdateEmail(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.User, err error) {
+	ctx, span := tracing.StartDB(ctx, "UserDAO.UpdateEmail")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	log := d.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("UpdateEmail"),
+		logAttrUser(input),
+	)
+	log.Debug("called")
+	span.SetAttributes(tracing.AttrStatement("updateEmailQuery"))
+	r, err := tx.NamedExecContext(ctx, updateEmailQuery, &input)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			if pqErr.Constraint == "users_email_uk" {
+				return u, ErrEmailAlreadyInUse{Email: input.Email}
+			}
+		}
+		return u, err
+	}
+	numRows, err := r.RowsAffected()
+	if err != nil {
+		return u, err
+	}
+	if numRows == 0 {
+		return u, ErrOptimisticLock{ID: input.ID, Version: input.Version}
+	}
+	if numRows != 1 {
+		return u, fmt.Errorf("unexpected number of rows affected: %d", numRows)
+	}
+	log.Debug("success")
+	input.Version = input.Version + 1
+	return input, err
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/metrics"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+)
+
+const daoName = "UserDAO"
+
+type DAOMetrics interface {
+	ObserveQuery(dao string, method string, d time.Duration, errClass string)
+}
+
+// instrumentedDAO records the duration and error class of every UserDAO call.
+type instrumentedDAO struct {
+	dao     UserDAO
+	metrics DAOMetrics
+}
+
+func NewInstrumentedDAO(dao UserDAO, metrics DAOMetrics) *instrumentedDAO {
+	return &instrumentedDAO{
+		dao:     dao,
+		metrics: metrics,
+	}
+}
+
+func (d instrumentedDAO) observe(method string, start time.Time, err error) {
+	d.metrics.ObserveQuery(daoName, method, time.Since(start), errClass(err))
+}
+
+func (d instrumentedDAO) GetByID(ctx context.Context, id string, includeDeleted bool) (u user.User, err error) {
+	start := time.Now()
+	u, err = d.dao.GetByID(ctx, id, includeDeleted)
+	d.observe("GetByID", start, err)
+	return u, err
+}
+
+func (d instrumentedDAO) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (u user.User, err error) {
+	start := time.Now()
+	u, err = d.dao.GetByIDForUpdate(ctx, tx, id)
+	d.observe("GetByIDForUpdate", start, err)
+	return u, err
+}
+
+func (d instrumentedDAO) GetByEmail(ctx context.Context, tx *sqlx.Tx, email string) (u user.User, err error) {
+	start := time.Now()
+	u, err = d.dao.GetByEmail(ctx, tx, email)
+	d.observe("GetByEmail", start, err)
+	return u, err
+}
+
+func (d instrumentedDAO) GetAll(ctx context.Context, q user.Query, includeDeleted bool, after *page.Cursor, limit int) (users []user.User, err error) {
+	start := time.Now()
+	users, err = d.dao.GetAll(ctx, q, includeDeleted, after, limit)
+	d.observe("GetAll", start, err)
+	return users, err
+}
+
+func (d instrumentedDAO) GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, after *page.Cursor, limit int) (users []user.User, err error) {
+	start := time.Now()
+	users, err = d.dao.GetAllByOrgID(ctx, orgID, includeDeleted, after, limit)
+	d.observe("GetAllByOrgID", start, err)
+	return users, err
+}
+
+func (d instrumentedDAO) Create(ctx context.Context, tx *sqlx.Tx, u user.User) (err error) {
+	start := time.Now()
+	err = d.dao.Create(ctx, tx, u)
+	d.observe("Create", start, err)
+	return err
+}
+
+func (d instrumentedDAO) CreateBatch(ctx context.Context, tx *sqlx.Tx, users []user.User) (err error) {
+	start := time.Now()
+	err = d.dao.CreateBatch(ctx, tx, users)
+	d.observe("CreateBatch", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Update(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.User, err error) {
+	start := time.Now()
+	u, err = d.dao.Update(ctx, tx, input)
+	d.observe("Update", start, err)
+	return u, err
+}
+
+func (d instrumentedDAO) Delete(ctx context.Context, tx *sqlx.Tx, u user.User) (err error) {
+	start := time.Now()
+	err = d.dao.Delete(ctx, tx, u)
+	d.observe("Delete", start, err)
+	return err
+}
+
+func (d instrumentedDAO) Restore(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.User, err error) {
+	start := time.Now()
+	u, err = d.dao.Restore(ctx, tx, input)
+	d.observe("Restore", start, err)
+	return u, err
+}
+
+func (d instrumentedDAO) Purge(ctx context.Context, before time.Time) (numRows int64, err error) {
+	start := time.Now()
+	numRows, err = d.dao.Purge(ctx, before)
+	d.observe("Purge", start, err)
+	return numRows, err
+}
+
+func (d instrumentedDAO) SaveEmailChange(ctx context.Context, tx *sqlx.Tx, ec StoredEmailChange) (err error) {
+	start := time.Now()
+	err = d.dao.SaveEmailChange(ctx, tx, ec)
+	d.observe("SaveEmailChange", start, err)
+	return err
+}
+
+func (d instrumentedDAO) GetEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) (ec StoredEmailChange, err error) {
+	start := time.Now()
+	ec, err = d.dao.GetEmailChange(ctx, tx, userID)
+	d.observe("GetEmailChange", start, err)
+	return ec, err
+}
+
+func (d instrumentedDAO) DeleteEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) (err error) {
+	start := time.Now()
+	err = d.dao.DeleteEmailChange(ctx, tx, userID)
+	d.observe("DeleteEmailChange", start, err)
+	return err
+}
+
+func (d instrumentedDAO) UpdateEmail(ctx context.Context, tx *sqlx.Tx, input user.User) (u user.User, err error) {
+	start := time.Now()
+	u, err = d.dao.UpdateEmail(ctx, tx, input)
+	d.observe("UpdateEmail", start, err)
+	return u, err
+}
+
+func errClass(err error) string {
+	switch {
+	case errors.As(err, &ErrNotFound{}), errors.As(err, &ErrEmailNotFound{}), errors.As(err, &ErrNoPendingEmailChange{}):
+		return "not_found"
+	case errors.As(err, &ErrOptimisticLock{}):
+		return "optimistic_lock"
+	case errors.As(err, &ErrEmailAlreadyInUse{}):
+		return "conflict"
+	default:
+		return metrics.ErrClass(err)
+	}
+}
@@ -0,0 +1,110 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockDAOMetrics struct {
+	mock.Mock
+}
+
+func (m *mockDAOMetrics) ObserveQuery(dao string, method string, d time.Duration, errClass string) {
+	m.Called(dao, method, d, errClass)
+}
+
+func initInstrumentedDAO() (d *instrumentedDAO, md *mockDAO, mm *mockDAOMetrics) {
+	md = new(mockDAO)
+	mm = new(mockDAOMetrics)
+	d = NewInstrumentedDAO(md, mm)
+	return d, md, mm
+}
+
+func TestInstrumentedDAO_GetByID(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	mockRes := user.User{ID: "foo-id"}
+	md.On("GetByID", ctx, "foo-id", false).Return(mockRes, nil)
+	mm.On("ObserveQuery", daoName, "GetByID", mock.AnythingOfType("time.Duration"), "none")
+
+	actual, err := d.GetByID(ctx, "foo-id", false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_Purge(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	before := time.UnixMilli(100)
+	md.On("Purge", ctx, before).Return(int64(2), nil)
+	mm.On("ObserveQuery", daoName, "Purge", mock.AnythingOfType("time.Duration"), "none")
+
+	actual, err := d.Purge(ctx, before)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), actual)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_CreateBatch(t *testing.T) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	users := []user.User{{ID: "foo-id"}}
+	mockErr := ErrEmailAlreadyInUse{Email: "foo@bar.com"}
+	md.On("CreateBatch", ctx, (*sqlx.Tx)(nil), users).Return(mockErr)
+	mm.On("ObserveQuery", daoName, "CreateBatch", mock.AnythingOfType("time.Duration"), "conflict")
+
+	err := d.CreateBatch(ctx, nil, users)
+
+	assert.Equal(t, mockErr, err)
+	mm.AssertExpectations(t)
+}
+
+func assertErrClass(t *testing.T, mockErr error, expected string) {
+	d, md, mm := initInstrumentedDAO()
+	ctx := context.Background()
+	md.On("GetByID", ctx, "foo-id", false).Return(user.User{}, mockErr)
+	mm.On("ObserveQuery", daoName, "GetByID", mock.AnythingOfType("time.Duration"), expected)
+
+	_, err := d.GetByID(ctx, "foo-id", false)
+
+	assert.Equal(t, mockErr, err)
+	mm.AssertExpectations(t)
+}
+
+func TestInstrumentedDAO_NotFound(t *testing.T) {
+	assertErrClass(t, ErrNotFound{ID: "foo-id"}, "not_found")
+}
+
+func TestInstrumentedDAO_EmailNotFound(t *testing.T) {
+	assertErrClass(t, ErrEmailNotFound{Email: "foo@bar.com"}, "not_found")
+}
+
+func TestInstrumentedDAO_NoPendingEmailChange(t *testing.T) {
+	assertErrClass(t, ErrNoPendingEmailChange{UserID: "foo-id"}, "not_found")
+}
+
+func TestInstrumentedDAO_OptimisticLock(t *testing.T) {
+	assertErrClass(t, ErrOptimisticLock{ID: "foo-id"}, "optimistic_lock")
+}
+
+func TestInstrumentedDAO_EmailAlreadyInUse(t *testing.T) {
+	assertErrClass(t, ErrEmailAlreadyInUse{Email: "foo@bar.com"}, "conflict")
+}
+
+func TestInstrumentedDAO_Timeout(t *testing.T) {
+	assertErrClass(t, context.DeadlineExceeded, "timeout")
+}
+
+func TestInstrumentedDAO_OtherErr(t *testing.T) {
+	assertErrClass(t, errors.New("unit-test mock error"), "other")
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/page"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
 	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/jmoiron/sqlx"
@@ -22,6 +23,12 @@ var (
 	ctx       = context.Background()
 	createdAt = time.UnixMilli(100)
 	updatedAt = time.UnixMilli(200)
+	deletedAt = time.UnixMilli(300)
+	after     = page.Cursor{
+		Key:       "after@bar.com",
+		CreatedAt: time.UnixMilli(50),
+		ID:        "after-id",
+	}
 )
 
 const (
@@ -34,6 +41,8 @@ const (
 	createdBy   = "logged-in-user-id"
 	updatedBy   = "logged-in-user-id"
 	version     = int64(3)
+	deletedBy   = "deleter-id"
+	limit       = 11
 )
 
 func getRows() *sqlmock.Rows {
@@ -79,10 +88,10 @@ func TestDAOGetByID(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
-		WithArgs(id).
+		WithArgs(id, false).
 		WillReturnRows(getRows())
 
-	actual, err := d.GetByID(ctx, id)
+	actual, err := d.GetByID(ctx, id, false)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Nil(t, err)
@@ -102,10 +111,10 @@ func TestDAOGetByID_NotFoundErr(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
-		WithArgs(id).
+		WithArgs(id, false).
 		WillReturnError(sql.ErrNoRows)
 
-	_, err := d.GetByID(ctx, id)
+	_, err := d.GetByID(ctx, id, false)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	var expected ErrNotFound
@@ -114,27 +123,157 @@ func TestDAOGetByID_NotFoundErr(t *testing.T) {
 	assert.Contains(t, err.Error(), id)
 }
 
+func TestDAOGetByIDForUpdate(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByIDForUpdateQuery)).
+		WithArgs(id).
+		WillReturnRows(getRows())
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetByIDForUpdate(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, orgID, actual.OrgID)
+	assert.Equal(t, version, actual.Version)
+}
+
+func TestDAOGetByIDForUpdate_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByIDForUpdateQuery)).
+		WithArgs(id).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetByIDForUpdate(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrNotFound{ID: id}, err)
+}
+
+func TestDAOGetByEmail(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByEmailQuery)).
+		WithArgs(email).
+		WillReturnRows(getRows())
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetByEmail(ctx, tx, email)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, email, actual.Email)
+}
+
+func TestDAOGetByEmail_NotFoundErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getByEmailQuery)).
+		WithArgs(email).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetByEmail(ctx, tx, email)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrEmailNotFound
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, email, expected.Email)
+}
+
 func TestDAOGetByID_OtherErr(t *testing.T) {
 	d, _, md := initDAO()
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
-		WithArgs(id).
+		WithArgs(id, false).
 		WillReturnError(&mockErr)
 
-	_, err := d.GetByID(ctx, id)
+	_, err := d.GetByID(ctx, id, false)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Equal(t, &mockErr, err)
 }
 
+func TestDAOGetByID_IncludeDeleted(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getByIDQuery)).
+		WithArgs(id, true).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id",
+			"version",
+			"deleted_at",
+			"deleted_by",
+		}).AddRow(
+			id,
+			version,
+			deletedAt,
+			deletedBy,
+		))
+
+	actual, err := d.GetByID(ctx, id, true)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, &deletedAt, actual.DeletedAt)
+	assert.Equal(t, deletedBy, actual.DeletedBy)
+}
+
 func TestDAOGetAll(t *testing.T) {
 	d, _, md := initDAO()
 
-	md.ExpectQuery(regexp.QuoteMeta(getAllQuery)).
+	query, _, err := buildGetAllQuery(user.Query{}, false, nil, limit)
+	assert.Nil(t, err)
+	md.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(false, limit).
+		WillReturnRows(getRows())
+
+	actuals, err := d.GetAll(ctx, user.Query{}, false, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(actuals))
+	actual := actuals[0]
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, orgID, actual.OrgID)
+	assert.Equal(t, name, actual.Name)
+	assert.Equal(t, email, actual.Email)
+	assert.Equal(t, isAdmin, actual.IsAdmin)
+	assert.Equal(t, createdAt, actual.CreatedAt)
+	assert.Equal(t, createdBy, actual.CreatedBy)
+	assert.Equal(t, updatedAt, actual.UpdatedAt)
+	assert.Equal(t, updatedBy, actual.UpdatedBy)
+	assert.Equal(t, version, actual.Version)
+}
+
+func TestDAOGetAll_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	query, _, err := buildGetAllQuery(user.Query{}, false, &after, limit)
+	assert.Nil(t, err)
+	md.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(false, after.Key, after.Key, after.ID, limit).
 		WillReturnRows(getRows())
 
-	actuals, err := d.GetAll(ctx)
+	actuals, err := d.GetAll(ctx, user.Query{}, false, &after, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Nil(t, err)
@@ -156,23 +295,79 @@ func TestDAOGetAll_Error(t *testing.T) {
 	d, _, md := initDAO()
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
-	md.ExpectQuery(regexp.QuoteMeta(getAllQuery)).
+	query, _, err := buildGetAllQuery(user.Query{}, false, nil, limit)
+	assert.Nil(t, err)
+	md.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(false, limit).
 		WillReturnError(&mockErr)
 
-	_, err := d.GetAll(ctx)
+	_, err = d.GetAll(ctx, user.Query{}, false, nil, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Equal(t, &mockErr, err)
 }
 
+func TestDAOGetAll_Query(t *testing.T) {
+	d, _, md := initDAO()
+
+	isAdmin := true
+	q := user.Query{OrgID: orgID, IsAdmin: &isAdmin, Email: partialName, Sort: "-name"}
+	query, _, err := buildGetAllQuery(q, true, nil, limit)
+	assert.Nil(t, err)
+	md.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(true, orgID, isAdmin, partialName, limit).
+		WillReturnRows(getRows())
+
+	actuals, err := d.GetAll(ctx, q, true, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(actuals))
+}
+
+func TestDAOGetAll_InvalidSort(t *testing.T) {
+	d, _, md := initDAO()
+
+	_, err := d.GetAll(ctx, user.Query{Sort: "password"}, false, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrInvalidSort
+	assert.True(t, errors.As(err, &expected))
+}
+
 func TestDAOGetAllByOrgID(t *testing.T) {
 	d, _, md := initDAO()
 
 	md.ExpectQuery(regexp.QuoteMeta(getAllByOrgIDQuery)).
-		WithArgs(orgID).
+		WithArgs(orgID, limit, false).
+		WillReturnRows(getRows())
+
+	actuals, err := d.GetAllByOrgID(ctx, orgID, false, nil, limit)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(actuals))
+	actual := actuals[0]
+	assert.Equal(t, id, actual.ID)
+	assert.Equal(t, orgID, actual.OrgID)
+	assert.Equal(t, name, actual.Name)
+	assert.Equal(t, email, actual.Email)
+	assert.Equal(t, isAdmin, actual.IsAdmin)
+	assert.Equal(t, createdAt, actual.CreatedAt)
+	assert.Equal(t, createdBy, actual.CreatedBy)
+	assert.Equal(t, updatedAt, actual.UpdatedAt)
+	assert.Equal(t, updatedBy, actual.UpdatedBy)
+	assert.Equal(t, version, actual.Version)
+}
+
+func TestDAOGetAllByOrgID_After(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectQuery(regexp.QuoteMeta(getAllByOrgIDAfterQuery)).
+		WithArgs(orgID, after.Key, after.CreatedAt, after.ID, limit, false).
 		WillReturnRows(getRows())
 
-	actuals, err := d.GetAllByOrgID(ctx, orgID)
+	actuals, err := d.GetAllByOrgID(ctx, orgID, false, &after, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Nil(t, err)
@@ -195,10 +390,10 @@ func TestDAOGetAllByOrgID_Error(t *testing.T) {
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectQuery(regexp.QuoteMeta(getAllByOrgIDQuery)).
-		WithArgs(orgID).
+		WithArgs(orgID, limit, false).
 		WillReturnError(&mockErr)
 
-	_, err := d.GetAllByOrgID(ctx, orgID)
+	_, err := d.GetAllByOrgID(ctx, orgID, false, nil, limit)
 
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Equal(t, &mockErr, err)
@@ -233,6 +428,75 @@ func TestDAOCreate(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestDAOCreateBatch(t *testing.T) {
+	d, db, md := initDAO()
+
+	users := []user.User{
+		{ID: id, OrgID: orgID, Name: name, Email: email, Version: version},
+		{ID: "bar-id", OrgID: orgID, Name: name, Email: "bar@baz.com", Version: version},
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec(`INSERT INTO users .* VALUES \(.*\),\(.*\)`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.CreateBatch(ctx, tx, users)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOCreateBatch_EmailAlreadyInUseErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	users := []user.User{
+		{ID: id, OrgID: orgID, Name: name, Email: email, Version: version},
+		{ID: "bar-id", OrgID: orgID, Name: name, Email: "bar@baz.com", Version: version},
+	}
+
+	mockErr := pq.Error{
+		Message:    "unit-test mock error",
+		Constraint: "users_email_uk",
+		Detail:     "Key (email)=(bar@baz.com) already exists.",
+	}
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO users").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.CreateBatch(ctx, tx, users)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, ErrEmailAlreadyInUse{Email: "bar@baz.com"}, err)
+}
+
+func TestDAOCreateBatch_UnexpectedNumRows(t *testing.T) {
+	d, db, md := initDAO()
+
+	users := []user.User{
+		{ID: id, OrgID: orgID, Name: name, Email: email, Version: version},
+		{ID: "bar-id", OrgID: orgID, Name: name, Email: "bar@baz.com", Version: version},
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO users").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.CreateBatch(ctx, tx, users)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unexpected number of rows")
+}
+
 func TestDAOCreate_EmailALreadyInUseErr(t *testing.T) {
 	d, db, md := initDAO()
 
@@ -556,13 +820,16 @@ func TestDAOUpdate_TooManyRowsAffected(t *testing.T) {
 func TestDAODelete(t *testing.T) {
 	d, db, md := initDAO()
 
-	u := user.DeleteUser{
-		ID:      id,
-		Version: version,
+	u := user.User{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM users").
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = \?`).
+		WithArgs(deletedAt, deletedBy, version, id, version).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	tx, err := db.Beginx()
@@ -577,13 +844,15 @@ func TestDAODelete(t *testing.T) {
 func TestDAODelete_OptimisticLockErr(t *testing.T) {
 	d, db, md := initDAO()
 
-	u := user.DeleteUser{
-		ID:      id,
-		Version: version,
+	u := user.User{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM users").
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = \?`).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
 	tx, err := db.Beginx()
@@ -602,14 +871,16 @@ func TestDAODelete_OptimisticLockErr(t *testing.T) {
 func TestDAODelete_OtherErr(t *testing.T) {
 	d, db, md := initDAO()
 
-	u := user.DeleteUser{
-		ID:      id,
-		Version: version,
+	u := user.User{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	mockErr := pq.Error{Message: "unit-test mock error"}
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM users").
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = \?`).
 		WillReturnError(&mockErr)
 
 	tx, err := db.Beginx()
@@ -624,13 +895,15 @@ func TestDAODelete_OtherErr(t *testing.T) {
 func TestDAODelete_TooManyRowsAffected(t *testing.T) {
 	d, db, md := initDAO()
 
-	u := user.DeleteUser{
-		ID:      id,
-		Version: version,
+	u := user.User{
+		ID:        id,
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
 	}
 
 	md.ExpectBegin()
-	md.ExpectExec("DELETE FROM users").
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = \?`).
 		WillReturnResult(sqlmock.NewResult(1, 2))
 
 	tx, err := db.Beginx()
@@ -641,3 +914,264 @@ func TestDAODelete_TooManyRowsAffected(t *testing.T) {
 	assert.Nil(t, md.ExpectationsWereMet())
 	assert.Contains(t, err.Error(), "unexpected number of rows affected")
 }
+
+func TestDAORestore(t *testing.T) {
+	d, db, md := initDAO()
+
+	u := user.User{
+		ID:        id,
+		OrgID:     orgID,
+		Name:      name,
+		Email:     email,
+		UpdatedAt: updatedAt,
+		UpdatedBy: "updater-id",
+		Version:   version,
+		DeletedAt: &deletedAt,
+		DeletedBy: deletedBy,
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = NULL`).
+		WithArgs(updatedAt, "updater-id", version, id, version).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.Restore(ctx, tx, u)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, version+1, actual.Version)
+	assert.Nil(t, actual.DeletedAt)
+	assert.Equal(t, "", actual.DeletedBy)
+}
+
+func TestDAORestore_OptimisticLockErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	u := user.User{
+		ID:      id,
+		Version: version,
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.Restore(ctx, tx, u)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrOptimisticLock
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestDAORestore_OtherErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	u := user.User{
+		ID:      id,
+		Version: version,
+	}
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectBegin()
+	md.ExpectExec(`UPDATE users SET\s+deleted_at = NULL`).
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.Restore(ctx, tx, u)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOPurge(t *testing.T) {
+	d, _, md := initDAO()
+
+	md.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(deletedAt).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	actual, err := d.Purge(ctx, deletedAt)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), actual)
+}
+
+func TestDAOPurge_Err(t *testing.T) {
+	d, _, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error"}
+	md.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(deletedAt).
+		WillReturnError(&mockErr)
+
+	_, err := d.Purge(ctx, deletedAt)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Equal(t, &mockErr, err)
+}
+
+func TestDAOSaveEmailChange(t *testing.T) {
+	d, db, md := initDAO()
+
+	ec := StoredEmailChange{
+		UserID:    id,
+		NewEmail:  email,
+		TokenHash: "foo-hash",
+		ExpiresAt: updatedAt,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy,
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec("INSERT INTO email_changes").
+		WithArgs(id, email, "foo-hash", updatedAt, createdAt, createdBy).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.SaveEmailChange(ctx, tx, ec)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOGetEmailChange(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getEmailChangeQuery)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"user_id",
+			"new_email",
+			"token_hash",
+			"expires_at",
+			"created_at",
+			"created_by",
+		}).AddRow(id, email, "foo-hash", updatedAt, createdAt, createdBy))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.GetEmailChange(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, StoredEmailChange{
+		UserID:    id,
+		NewEmail:  email,
+		TokenHash: "foo-hash",
+		ExpiresAt: updatedAt,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy,
+	}, actual)
+}
+
+func TestDAOGetEmailChange_NotFound(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectQuery(regexp.QuoteMeta(getEmailChangeQuery)).
+		WithArgs(id).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.GetEmailChange(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrNoPendingEmailChange
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, id, expected.UserID)
+}
+
+func TestDAODeleteEmailChange(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec(regexp.QuoteMeta(deleteEmailChangeQuery)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	err = d.DeleteEmailChange(ctx, tx, id)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+}
+
+func TestDAOUpdateEmail(t *testing.T) {
+	d, db, md := initDAO()
+
+	u := user.User{
+		ID:        id,
+		Email:     email,
+		UpdatedAt: updatedAt,
+		UpdatedBy: updatedBy,
+		Version:   version,
+	}
+
+	md.ExpectBegin()
+	md.ExpectExec("UPDATE users SET\\s+email").
+		WithArgs(email, updatedAt, updatedBy, version, id, version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	actual, err := d.UpdateEmail(ctx, tx, u)
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	assert.Nil(t, err)
+	assert.Equal(t, email, actual.Email)
+	assert.Equal(t, version+1, actual.Version)
+}
+
+func TestDAOUpdateEmail_OptimisticLockErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	md.ExpectBegin()
+	md.ExpectExec("UPDATE users").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.UpdateEmail(ctx, tx, user.User{ID: id, Email: email, Version: version})
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrOptimisticLock
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestDAOUpdateEmail_EmailAlreadyInUseErr(t *testing.T) {
+	d, db, md := initDAO()
+
+	mockErr := pq.Error{Message: "unit-test mock error", Constraint: "users_email_uk"}
+	md.ExpectBegin()
+	md.ExpectExec("UPDATE users").
+		WillReturnError(&mockErr)
+
+	tx, err := db.Beginx()
+	assert.Nil(t, err)
+
+	_, err = d.UpdateEmail(ctx, tx, user.User{ID: id, Email: email, Version: version})
+
+	assert.Nil(t, md.ExpectationsWereMet())
+	var expected ErrEmailAlreadyInUse
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, email, expected.Email)
+}
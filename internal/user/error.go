@@ -2,6 +2,8 @@ package user
 
 import (
 	"fmt"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 )
 
 type ErrEmailAlreadyInUse struct {
@@ -12,6 +14,10 @@ func (err ErrEmailAlreadyInUse) Error() string {
 	return fmt.Sprintf("Cannot save user, email '%s' is already in use by another user", err.Email)
 }
 
+func (err ErrEmailAlreadyInUse) Code() string {
+	return problem.CodeEmailInUse
+}
+
 type ErrCannotAssociateSysOrg struct {
 	UserID string
 	OrgID  string
@@ -21,6 +27,10 @@ func (err ErrCannotAssociateSysOrg) Error() string {
 	return fmt.Sprintf("Cannot associate user with system org: userID=%s orgID=%s", err.UserID, err.OrgID)
 }
 
+func (err ErrCannotAssociateSysOrg) Code() string {
+	return problem.CodeCannotAssociateSysOrg
+}
+
 type ErrCannotModifySysUser struct {
 	ID string
 }
@@ -29,6 +39,10 @@ func (err ErrCannotModifySysUser) Error() string {
 	return fmt.Sprintf("Cannot modify system user: id=%s", err.ID)
 }
 
+func (err ErrCannotModifySysUser) Code() string {
+	return problem.CodeSysUserReadOnly
+}
+
 type ErrNotFound struct {
 	ID string
 }
@@ -37,6 +51,10 @@ func (err ErrNotFound) Error() string {
 	return fmt.Sprintf("User not found: id=%s ", err.ID)
 }
 
+func (err ErrNotFound) Code() string {
+	return problem.CodeUserNotFound
+}
+
 type ErrOptimisticLock struct {
 	ID      string
 	Version int64
@@ -45,3 +63,168 @@ type ErrOptimisticLock struct {
 func (err ErrOptimisticLock) Error() string {
 	return fmt.Sprintf("User was modified since last retrieved: id=%s version=%d", err.ID, err.Version)
 }
+
+func (err ErrOptimisticLock) Code() string {
+	return problem.CodeUserVersionConflict
+}
+
+type ErrInvalidIncludeDeleted struct {
+	Value string
+}
+
+func (err ErrInvalidIncludeDeleted) Error() string {
+	return fmt.Sprintf("Invalid include_deleted, must be true or false: include_deleted=%s", err.Value)
+}
+
+func (err ErrInvalidIncludeDeleted) Code() string {
+	return problem.CodeInvalidIncludeDeleted
+}
+
+type ErrInvalidDryRun struct {
+	Value string
+}
+
+func (err ErrInvalidDryRun) Error() string {
+	return fmt.Sprintf("Invalid dry_run, must be true or false: dry_run=%s", err.Value)
+}
+
+func (err ErrInvalidDryRun) Code() string {
+	return problem.CodeInvalidDryRun
+}
+
+type ErrInvalidBatchOp struct {
+	Index  int
+	Op     string
+	Reason string
+}
+
+func (err ErrInvalidBatchOp) Error() string {
+	return fmt.Sprintf("Invalid batch op: index=%d op=%s %s", err.Index, err.Op, err.Reason)
+}
+
+func (err ErrInvalidBatchOp) Code() string {
+	return problem.CodeInvalidBatchOp
+}
+
+// ErrBatchAborted is the result of every other op when an all-or-nothing
+// batch is rolled back.
+type ErrBatchAborted struct {
+	FailedIndex int
+}
+
+func (err ErrBatchAborted) Error() string {
+	return fmt.Sprintf("Batch was rolled back, the op at index %d failed", err.FailedIndex)
+}
+
+func (err ErrBatchAborted) Code() string {
+	return problem.CodeBatchAborted
+}
+
+type ErrUnknownMethod struct {
+	Method string
+}
+
+func (err ErrUnknownMethod) Error() string {
+	return fmt.Sprintf("Unknown method: %s", err.Method)
+}
+
+func (err ErrUnknownMethod) Code() string {
+	return problem.CodeUnknownMethod
+}
+
+type ErrInvalidFilter struct {
+	Param string
+	Value string
+}
+
+func (err ErrInvalidFilter) Error() string {
+	return fmt.Sprintf("Invalid filter, expected true/false or an RFC 3339 time: %s=%s", err.Param, err.Value)
+}
+
+func (err ErrInvalidFilter) Code() string {
+	return problem.CodeInvalidFilter
+}
+
+type ErrInvalidSort struct {
+	Sort string
+}
+
+func (err ErrInvalidSort) Error() string {
+	return fmt.Sprintf("Invalid sort, must be one of email, name, created_at or updated_at (prefix with - for descending): sort=%s", err.Sort)
+}
+
+func (err ErrInvalidSort) Code() string {
+	return problem.CodeInvalidSort
+}
+
+type ErrNoPendingEmailChange struct {
+	UserID string
+}
+
+func (err ErrNoPendingEmailChange) Error() string {
+	return fmt.Sprintf("No pending email change: userID=%s", err.UserID)
+}
+
+func (err ErrNoPendingEmailChange) Code() string {
+	return problem.CodeNoPendingEmailChange
+}
+
+type ErrInvalidEmailChangeToken struct {
+	UserID string
+}
+
+func (err ErrInvalidEmailChangeToken) Error() string {
+	return fmt.Sprintf("Invalid email change token: userID=%s", err.UserID)
+}
+
+func (err ErrInvalidEmailChangeToken) Code() string {
+	return problem.CodeInvalidEmailChangeToken
+}
+
+type ErrEmailChangeExpired struct {
+	UserID string
+}
+
+func (err ErrEmailChangeExpired) Error() string {
+	return fmt.Sprintf("Email change expired, request a new one: userID=%s", err.UserID)
+}
+
+func (err ErrEmailChangeExpired) Code() string {
+	return problem.CodeEmailChangeExpired
+}
+
+type ErrEmailUnchanged struct {
+	Email string
+}
+
+func (err ErrEmailUnchanged) Error() string {
+	return fmt.Sprintf("Cannot change email, it's already '%s'", err.Email)
+}
+
+func (err ErrEmailUnchanged) Code() string {
+	return problem.CodeEmailUnchanged
+}
+
+type ErrEmailNotFound struct {
+	Email string
+}
+
+func (err ErrEmailNotFound) Error() string {
+	return fmt.Sprintf("User not found: email=%s", err.Email)
+}
+
+func (err ErrEmailNotFound) Code() string {
+	return problem.CodeUserNotFound
+}
+
+type ErrNameRequired struct {
+	Email string
+}
+
+func (err ErrNameRequired) Error() string {
+	return fmt.Sprintf("Cannot create user without a name: email=%s", err.Email)
+}
+
+func (err ErrNameRequired) Code() string {
+	return problem.CodeNameRequired
+}
@@ -0,0 +1,302 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	userv1 "github.com/RyanBard/go-service-ex/pkg/pb/user/v1"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer is the controller for gRPC, it calls the same service.
+type grpcServer struct {
+	userv1.UnimplementedUserServiceServer
+	log     *slog.Logger
+	service UserService
+}
+
+func NewGRPCServer(log *slog.Logger, service UserService) *grpcServer {
+	return &grpcServer{
+		log:     log.With(logutil.LogAttrSVC("UserGRPC")),
+		service: service,
+	}
+}
+
+func (srv grpcServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("GetUser"),
+		logAttrUserID(req.GetId()),
+	)
+	log.Debug("called")
+	u, err := srv.service.GetByID(ctx, req.GetId(), req.GetIncludeDeleted())
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log.With(logAttrUser(u)).Debug("success")
+	return toProto(u), nil
+}
+
+func (srv grpcServer) ListUsers(ctx context.Context, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ListUsers"),
+	)
+	log.Debug("called")
+	pr, err := page.NewRequest(int(req.GetLimit()), req.GetCursor())
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	q := user.Query{
+		OrgID:       req.GetOrgId(),
+		IsActive:    req.IsActive,
+		IsAdmin:     req.IsAdmin,
+		Email:       req.GetEmail(),
+		Name:        req.GetName(),
+		CreatedFrom: timeOf(req.GetCreatedFrom()),
+		CreatedTo:   timeOf(req.GetCreatedTo()),
+		UpdatedFrom: timeOf(req.GetUpdatedFrom()),
+		UpdatedTo:   timeOf(req.GetUpdatedTo()),
+		Sort:        req.GetSort(),
+	}
+	log = log.With(logAttrQuery(q))
+	up, err := srv.service.GetAll(ctx, q, req.GetIncludeDeleted(), pr)
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	resp := &userv1.ListUsersResponse{NextCursor: up.NextCursor}
+	for _, u := range up.Users {
+		resp.Users = append(resp.Users, toProto(u))
+	}
+	log.With(logAttrUsersLen(len(up.Users))).Debug("success")
+	return resp, nil
+}
+
+func (srv grpcServer) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.User, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("CreateUser"),
+		logAttrOrgID(req.GetOrgId()),
+	)
+	log.Debug("called")
+	u := user.User{
+		OrgID:    req.GetOrgId(),
+		Name:     req.GetName(),
+		Email:    req.GetEmail(),
+		IsAdmin:  req.GetIsAdmin(),
+		IsActive: req.GetIsActive(),
+	}
+	return srv.save(ctx, log, u)
+}
+
+func (srv grpcServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("UpdateUser"),
+		logAttrUserID(req.GetId()),
+	)
+	log.Debug("called")
+	// without an id Save would create a user
+	if req.GetId() == "" {
+		return nil, grpcErr(log, validate.Violations{validate.Required("id")}.Err())
+	}
+	u := user.User{
+		ID:       req.GetId(),
+		OrgID:    req.GetOrgId(),
+		Name:     req.GetName(),
+		Email:    req.GetEmail(),
+		IsAdmin:  req.GetIsAdmin(),
+		IsActive: req.GetIsActive(),
+		Version:  req.GetVersion(),
+	}
+	return srv.save(ctx, log, u)
+}
+
+// save checks a create's rules the same as the json body's are before calling
+// the service, which checks an update's.
+func (srv grpcServer) save(ctx context.Context, log *slog.Logger, u user.User) (*userv1.User, error) {
+	if u.ID == "" {
+		if err := validate.Struct(u, ""); err != nil {
+			return nil, grpcErr(log, err)
+		}
+	}
+	log = log.With(logAttrUser(u))
+	log.Debug("request processed, about to call service")
+	u, err := srv.service.Save(ctx, u)
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log.Debug("success")
+	return toProto(u), nil
+}
+
+func (srv grpcServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("DeleteUser"),
+		logAttrUserID(req.GetId()),
+	)
+	log.Debug("called")
+	u := user.DeleteUser{ID: req.GetId(), Version: req.GetVersion()}
+	if err := validate.Struct(u, ""); err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log = log.With(logAttrUser(u))
+	log.Debug("request processed, about to call service")
+	if err := srv.service.Delete(ctx, u); err != nil {
+		if errors.As(err, &ErrNotFound{}) {
+			log.With(logutil.LogAttrError(err)).Warn("resource already gone, not deleting")
+			return &emptypb.Empty{}, nil
+		}
+		return nil, grpcErr(log, err)
+	}
+	log.Debug("success")
+	return &emptypb.Empty{}, nil
+}
+
+func (srv grpcServer) RestoreUser(ctx context.Context, req *userv1.RestoreUserRequest) (*userv1.User, error) {
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("RestoreUser"),
+		logAttrUserID(req.GetId()),
+	)
+	log.Debug("called")
+	u := user.RestoreUser{ID: req.GetId(), Version: req.GetVersion()}
+	if err := validate.Struct(u, ""); err != nil {
+		return nil, grpcErr(log, err)
+	}
+	restored, err := srv.service.Restore(ctx, u)
+	if err != nil {
+		return nil, grpcErr(log, err)
+	}
+	log.Debug("success")
+	return toProto(restored), nil
+}
+
+// ExportUsers sends a user at a time, an error part way through ends the
+// stream with that error.
+func (srv grpcServer) ExportUsers(req *userv1.ExportUsersRequest, stream grpc.ServerStreamingServer[userv1.User]) error {
+	ctx := stream.Context()
+	log := srv.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ExportUsers"),
+	)
+	log.Debug("called")
+	numRows := 0
+	for u, err := range srv.service.Export(ctx) {
+		if err != nil {
+			return grpcErr(log.With(logAttrRows(numRows)), err)
+		}
+		if err := stream.Send(toProto(u)); err != nil {
+			// the client went away
+			log.With(logutil.LogAttrError(err), logAttrRows(numRows)).Warn("failed to send row")
+			return err
+		}
+		numRows++
+	}
+	log.With(logAttrRows(numRows)).Debug("success")
+	return nil
+}
+
+// grpcErr is err with the gRPC version of the status the controller would
+// have responded with.
+func grpcErr(log *slog.Logger, err error) error {
+	var code codes.Code
+	var notFound ErrNotFound
+	var modSysUser ErrCannotModifySysUser
+	var assocSysOrg ErrCannotAssociateSysOrg
+	var orgNotFound org.ErrNotFound
+	var optLock ErrOptimisticLock
+	var dupEmail ErrEmailAlreadyInUse
+	var invalidSort ErrInvalidSort
+	var invalid validate.ErrInvalid
+	var invalidLimit page.ErrInvalidLimit
+	var invalidCursor page.ErrInvalidCursor
+	var forbidden authz.ErrForbidden
+	var unauthenticated authz.ErrUnauthenticated
+	if errors.As(err, &notFound) {
+		log.With(logutil.LogAttrError(err)).Warn("resource not found")
+		code = codes.NotFound
+	} else if errors.As(err, &modSysUser) {
+		log.With(logutil.LogAttrError(err)).Warn("cannot modify system user")
+		code = codes.PermissionDenied
+	} else if errors.As(err, &optLock) {
+		log.With(logutil.LogAttrError(err)).Warn("optimistic lock error")
+		code = codes.Aborted
+	} else if errors.As(err, &dupEmail) {
+		log.With(logutil.LogAttrError(err)).Warn("duplicate email error")
+		code = codes.AlreadyExists
+	} else if errors.As(err, &orgNotFound) {
+		log.With(logutil.LogAttrError(err)).Warn("org resource not found")
+		code = codes.InvalidArgument
+	} else if errors.As(err, &assocSysOrg) {
+		log.With(logutil.LogAttrError(err)).Warn("cannot associate system org")
+		code = codes.PermissionDenied
+	} else if errors.As(err, &invalidSort) ||
+		errors.As(err, &invalid) ||
+		errors.As(err, &invalidLimit) ||
+		errors.As(err, &invalidCursor) {
+		log.With(logutil.LogAttrError(err)).Warn("invalid request")
+		code = codes.InvalidArgument
+	} else if errors.As(err, &forbidden) {
+		log.With(logutil.LogAttrError(err)).Warn("forbidden")
+		code = codes.PermissionDenied
+	} else if errors.As(err, &unauthenticated) {
+		log.With(logutil.LogAttrError(err)).Warn("unauthenticated")
+		code = codes.Unauthenticated
+	} else {
+		log.With(logutil.LogAttrError(err)).Error("service call failed")
+		code = codes.Internal
+	}
+	return rpcerr.New(code, err)
+}
+
+// timeOf is nil for an unset timestamp, so the filter isn't applied.
+func timeOf(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toProto(u user.User) *userv1.User {
+	pb := &userv1.User{
+		Id:        u.ID,
+		OrgId:     u.OrgID,
+		Name:      u.Name,
+		Email:     u.Email,
+		IsSystem:  u.IsSystem,
+		IsAdmin:   u.IsAdmin,
+		IsActive:  u.IsActive,
+		CreatedAt: timestamppb.New(u.CreatedAt),
+		CreatedBy: u.CreatedBy,
+		UpdatedAt: timestamppb.New(u.UpdatedAt),
+		UpdatedBy: u.UpdatedBy,
+		Version:   u.Version,
+		DeletedBy: u.DeletedBy,
+	}
+	if u.DeletedAt != nil {
+		pb.DeletedAt = timestamppb.New(*u.DeletedAt)
+	}
+	return pb
+}
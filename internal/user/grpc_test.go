@@ -0,0 +1,297 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/org"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	userv1 "github.com/RyanBard/go-service-ex/pkg/pb/user/v1"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func initGRPC() (srv *grpcServer, ms *mockSVC) {
+	ms = new(mockSVC)
+	srv = NewGRPCServer(testutil.GetLogger(), ms)
+	return srv, ms
+}
+
+func TestGRPCGetUser(t *testing.T) {
+	srv, ms := initGRPC()
+	u := user.User{
+		ID:        "foo-id",
+		OrgID:     "foo-org-id",
+		Name:      "foo-name",
+		Email:     "foo@bar.com",
+		IsActive:  true,
+		CreatedAt: time.UnixMilli(100).UTC(),
+		UpdatedAt: time.UnixMilli(200).UTC(),
+		Version:   2,
+	}
+	ms.On("GetByID", mock.Anything, "foo-id", false).Return(u, nil)
+
+	actual, err := srv.GetUser(context.Background(), &userv1.GetUserRequest{Id: "foo-id"})
+
+	assert.Nil(t, err)
+	assert.True(t, proto.Equal(&userv1.User{
+		Id:        "foo-id",
+		OrgId:     "foo-org-id",
+		Name:      "foo-name",
+		Email:     "foo@bar.com",
+		IsActive:  true,
+		CreatedAt: timestamppb.New(time.UnixMilli(100)),
+		UpdatedAt: timestamppb.New(time.UnixMilli(200)),
+		Version:   2,
+	}, actual))
+}
+
+func TestGRPCGetUser_NotFoundError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetByID", mock.Anything, "foo-id", false).Return(user.User{}, ErrNotFound{ID: "foo-id"})
+
+	_, err := srv.GetUser(context.Background(), &userv1.GetUserRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, problem.CodeUserNotFound, rpcerr.Reason(err))
+}
+
+func TestGRPCGetUser_UnauthenticatedError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetByID", mock.Anything, "foo-id", false).Return(user.User{}, authz.ErrUnauthenticated{})
+
+	_, err := srv.GetUser(context.Background(), &userv1.GetUserRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCListUsers(t *testing.T) {
+	srv, ms := initGRPC()
+	isActive := true
+	createdFrom := time.UnixMilli(100).UTC()
+	q := user.Query{
+		OrgID:       "foo-org-id",
+		IsActive:    &isActive,
+		Email:       "foo",
+		CreatedFrom: &createdFrom,
+		Sort:        "-name",
+	}
+	ms.On("GetAll", mock.Anything, q, true, page.Request{Limit: page.DefaultLimit}).Return(user.UserPage{
+		Users:      []user.User{{ID: "foo-id"}, {ID: "bar-id"}},
+		NextCursor: "next",
+	}, nil)
+
+	actual, err := srv.ListUsers(context.Background(), &userv1.ListUsersRequest{
+		OrgId:          "foo-org-id",
+		IsActive:       &isActive,
+		Email:          "foo",
+		CreatedFrom:    timestamppb.New(createdFrom),
+		Sort:           "-name",
+		IncludeDeleted: true,
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, actual.Users, 2)
+	assert.Equal(t, "bar-id", actual.Users[1].Id)
+	assert.Equal(t, "next", actual.NextCursor)
+}
+
+func TestGRPCListUsers_InvalidSortError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("GetAll", mock.Anything, mock.Anything, false, mock.Anything).Return(user.UserPage{}, ErrInvalidSort{Sort: "foo"})
+
+	_, err := srv.ListUsers(context.Background(), &userv1.ListUsersRequest{Sort: "foo"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, problem.CodeInvalidSort, rpcerr.Reason(err))
+}
+
+func TestGRPCListUsers_InvalidCursor(t *testing.T) {
+	srv, _ := initGRPC()
+
+	_, err := srv.ListUsers(context.Background(), &userv1.ListUsersRequest{Cursor: "not-a-cursor"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, problem.CodeInvalidCursor, rpcerr.Reason(err))
+}
+
+func TestGRPCCreateUser(t *testing.T) {
+	srv, ms := initGRPC()
+	u := user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsActive: true}
+	ms.On("Save", mock.Anything, u).Return(user.User{ID: "foo-id", OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsActive: true, Version: 1}, nil)
+
+	actual, err := srv.CreateUser(context.Background(), &userv1.CreateUserRequest{OrgId: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsActive: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "foo-id", actual.Id)
+	assert.Equal(t, int64(1), actual.Version)
+}
+
+func TestGRPCCreateUser_ValidationError(t *testing.T) {
+	srv, ms := initGRPC()
+
+	_, err := srv.CreateUser(context.Background(), &userv1.CreateUserRequest{OrgId: "foo-org-id", Name: "foo-name", Email: "not-an-email"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, problem.CodeValidationFailed, rpcerr.Reason(err))
+	ms.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestGRPCCreateUser_EmailAlreadyInUseError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Save", mock.Anything, mock.Anything).Return(user.User{}, ErrEmailAlreadyInUse{Email: "foo@bar.com"})
+
+	_, err := srv.CreateUser(context.Background(), &userv1.CreateUserRequest{OrgId: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"})
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Equal(t, problem.CodeEmailInUse, rpcerr.Reason(err))
+}
+
+func TestGRPCCreateUser_OrgNotFoundError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Save", mock.Anything, mock.Anything).Return(user.User{}, org.ErrNotFound{ID: "foo-org-id"})
+
+	_, err := srv.CreateUser(context.Background(), &userv1.CreateUserRequest{OrgId: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGRPCUpdateUser(t *testing.T) {
+	srv, ms := initGRPC()
+	u := user.User{ID: "foo-id", OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsAdmin: true, Version: 1}
+	ms.On("Save", mock.Anything, u).Return(user.User{ID: "foo-id", Version: 2}, nil)
+
+	actual, err := srv.UpdateUser(context.Background(), &userv1.UpdateUserRequest{Id: "foo-id", OrgId: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", IsAdmin: true, Version: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), actual.Version)
+}
+
+func TestGRPCUpdateUser_ValidatedByService(t *testing.T) {
+	srv, ms := initGRPC()
+	u := user.User{ID: "foo-id", OrgID: "foo-org-id", Name: "foo-name", Email: "not-an-email", Version: 1}
+	ms.On("Save", mock.Anything, u).Return(user.User{}, validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "email", Rule: "email", Message: "email must be a valid email"},
+	}})
+
+	_, err := srv.UpdateUser(context.Background(), &userv1.UpdateUserRequest{Id: "foo-id", OrgId: "foo-org-id", Name: "foo-name", Email: "not-an-email", Version: 1})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, problem.CodeValidationFailed, rpcerr.Reason(err))
+}
+
+func TestGRPCUpdateUser_MissingID(t *testing.T) {
+	srv, ms := initGRPC()
+
+	_, err := srv.UpdateUser(context.Background(), &userv1.UpdateUserRequest{OrgId: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", Version: 1})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	ms.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestGRPCUpdateUser_CannotModifySysUserError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Save", mock.Anything, mock.Anything).Return(user.User{}, ErrCannotModifySysUser{ID: "foo-id"})
+
+	_, err := srv.UpdateUser(context.Background(), &userv1.UpdateUserRequest{Id: "foo-id", OrgId: "foo-org-id", Name: "foo-name", Email: "foo@bar.com", Version: 1})
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGRPCDeleteUser(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Delete", mock.Anything, user.DeleteUser{ID: "foo-id", Version: 1}).Return(nil)
+
+	_, err := srv.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: "foo-id", Version: 1})
+
+	assert.Nil(t, err)
+}
+
+func TestGRPCDeleteUser_NotFoundError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Delete", mock.Anything, mock.Anything).Return(ErrNotFound{ID: "foo-id"})
+
+	_, err := srv.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: "foo-id", Version: 1})
+
+	assert.Nil(t, err)
+}
+
+func TestGRPCDeleteUser_OptimisticLockError(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Delete", mock.Anything, mock.Anything).Return(ErrOptimisticLock{ID: "foo-id", Version: 1})
+
+	_, err := srv.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: "foo-id", Version: 1})
+
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Equal(t, problem.CodeUserVersionConflict, rpcerr.Reason(err))
+}
+
+func TestGRPCRestoreUser(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Restore", mock.Anything, user.RestoreUser{ID: "foo-id", Version: 2}).Return(user.User{ID: "foo-id", Version: 3}, nil)
+
+	actual, err := srv.RestoreUser(context.Background(), &userv1.RestoreUserRequest{Id: "foo-id", Version: 2})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), actual.Version)
+}
+
+func TestGRPCRestoreUser_ValidationError(t *testing.T) {
+	srv, ms := initGRPC()
+
+	_, err := srv.RestoreUser(context.Background(), &userv1.RestoreUserRequest{Id: "foo-id"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	ms.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+}
+
+func TestGRPCExportUsers(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Export", mock.Anything).Return(seqOf([]user.User{{ID: "foo-id"}, {ID: "bar-id"}}, nil))
+	stream := &mockUserStream{ctx: context.Background()}
+
+	err := srv.ExportUsers(&userv1.ExportUsersRequest{}, stream)
+
+	assert.Nil(t, err)
+	assert.Len(t, stream.sent, 2)
+	assert.Equal(t, "bar-id", stream.sent[1].Id)
+}
+
+func TestGRPCExportUsers_ErrorPartWay(t *testing.T) {
+	srv, ms := initGRPC()
+	ms.On("Export", mock.Anything).Return(seqOf([]user.User{{ID: "foo-id"}}, errors.New("unit-test mock service error")))
+	stream := &mockUserStream{ctx: context.Background()}
+
+	err := srv.ExportUsers(&userv1.ExportUsersRequest{}, stream)
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Len(t, stream.sent, 1)
+}
+
+type mockUserStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*userv1.User
+}
+
+func (m *mockUserStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockUserStream) Send(u *userv1.User) error {
+	m.sent = append(m.sent, u)
+	return nil
+}
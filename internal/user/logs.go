@@ -1,6 +1,12 @@
 package user
 
-import "log/slog"
+import (
+	"log/slog"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
 
 func logAttrOrgID(orgID string) slog.Attr {
 	return slog.String("orgID", orgID)
@@ -21,3 +27,75 @@ func logAttrPathID(pathID string) slog.Attr {
 func logAttrUsersLen(len int) slog.Attr {
 	return slog.Int("usersLen", len)
 }
+
+func logAttrAfter(after *page.Cursor) slog.Attr {
+	return slog.Any("after", after)
+}
+
+func logAttrLimit(limit int) slog.Attr {
+	return slog.Int("limit", limit)
+}
+
+func logAttrIncludeDeleted(includeDeleted bool) slog.Attr {
+	return slog.Bool("includeDeleted", includeDeleted)
+}
+
+func logAttrBefore(before time.Time) slog.Attr {
+	return slog.Time("before", before)
+}
+
+func logAttrNumRows(numRows int64) slog.Attr {
+	return slog.Int64("numRows", numRows)
+}
+
+func logAttrBatchMode(mode string) slog.Attr {
+	return slog.String("batchMode", mode)
+}
+
+func logAttrOpsLen(len int) slog.Attr {
+	return slog.Int("opsLen", len)
+}
+
+func logAttrFailedIndex(i int) slog.Attr {
+	return slog.Int("failedIndex", i)
+}
+
+func logAttrMethod(method string) slog.Attr {
+	return slog.String("method", method)
+}
+
+func logAttrOpIndex(i int) slog.Attr {
+	return slog.Int("opIndex", i)
+}
+
+func logAttrDryRun(dryRun bool) slog.Attr {
+	return slog.Bool("dryRun", dryRun)
+}
+
+func logAttrRow(row int) slog.Attr {
+	return slog.Int("row", row)
+}
+
+func logAttrRows(rows int) slog.Attr {
+	return slog.Int("rows", rows)
+}
+
+func logAttrImported(imported int) slog.Attr {
+	return slog.Int("imported", imported)
+}
+
+func logAttrQuery(q user.Query) slog.Attr {
+	return slog.Any("query", q)
+}
+
+func logAttrEmail(email string) slog.Attr {
+	return slog.String("email", email)
+}
+
+func logAttrVersion(version int64) slog.Attr {
+	return slog.Int64("version", version)
+}
+
+func logAttrContentType(contentType string) slog.Attr {
+	return slog.String("contentType", contentType)
+}
@@ -0,0 +1,137 @@
+package user
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
+
+// sortColumn is a column GET /api/users can be sorted by, only the columns in
+// sortColumns can end up in the ORDER BY so the sort param is never written
+// into the sql.
+type sortColumn struct {
+	column string
+	// toCursor and cursorKey save and read back the column's value
+	toCursor  func(u user.User) page.Cursor
+	cursorKey func(c page.Cursor) any
+}
+
+var sortColumns = map[string]sortColumn{
+	user.SortEmail: {
+		column:    "u.email",
+		toCursor:  func(u user.User) page.Cursor { return page.Cursor{Key: u.Email, CreatedAt: u.CreatedAt, ID: u.ID} },
+		cursorKey: func(c page.Cursor) any { return c.Key },
+	},
+	user.SortName: {
+		column:    "u.name",
+		toCursor:  func(u user.User) page.Cursor { return page.Cursor{Key: u.Name, CreatedAt: u.CreatedAt, ID: u.ID} },
+		cursorKey: func(c page.Cursor) any { return c.Key },
+	},
+	user.SortCreatedAt: {
+		column:    "u.created_at",
+		toCursor:  func(u user.User) page.Cursor { return page.Cursor{CreatedAt: u.CreatedAt, ID: u.ID} },
+		cursorKey: func(c page.Cursor) any { return c.CreatedAt },
+	},
+	// the cursor only has the one time, it holds updated_at here
+	user.SortUpdatedAt: {
+		column:    "u.updated_at",
+		toCursor:  func(u user.User) page.Cursor { return page.Cursor{CreatedAt: u.UpdatedAt, ID: u.ID} },
+		cursorKey: func(c page.Cursor) any { return c.CreatedAt },
+	},
+}
+
+type sortBy struct {
+	sortColumn
+	desc bool
+}
+
+// parseSort defaults to email, a "-" prefix sorts descending.
+func parseSort(sort string) (sortBy, error) {
+	if sort == "" {
+		sort = user.SortEmail
+	}
+	name, desc := strings.CutPrefix(sort, "-")
+	col, ok := sortColumns[name]
+	if !ok {
+		return sortBy{}, ErrInvalidSort{Sort: sort}
+	}
+	return sortBy{sortColumn: col, desc: desc}, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// queryBuilder collects the WHERE conditions and their args, every value is
+// bound as a $n param.
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+// where adds a condition, each ? in it is bound to the next of args.
+func (b *queryBuilder) where(cond string, args ...any) {
+	for _, arg := range args {
+		b.args = append(b.args, arg)
+		cond = strings.Replace(cond, "?", "$"+strconv.Itoa(len(b.args)), 1)
+	}
+	b.conds = append(b.conds, cond)
+}
+
+// buildGetAllQuery orders by the sort column then the id (as a tie breaker so
+// the cursor is always unique), the keyset predicate follows that order.
+func buildGetAllQuery(q user.Query, includeDeleted bool, after *page.Cursor, limit int) (string, []any, error) {
+	sb, err := parseSort(q.Sort)
+	if err != nil {
+		return "", nil, err
+	}
+	var b queryBuilder
+	b.where("(?::BOOLEAN OR u.deleted_at IS NULL)", includeDeleted)
+	// any member of the org, not only the users whose home org it is
+	if q.OrgID != "" {
+		b.where("EXISTS (SELECT 1 FROM memberships m WHERE m.user_id = u.id AND m.org_id = ?)", q.OrgID)
+	}
+	if q.IsActive != nil {
+		b.where("u.is_active = ?", *q.IsActive)
+	}
+	if q.IsAdmin != nil {
+		b.where("u.is_admin = ?", *q.IsAdmin)
+	}
+	if q.Email != "" {
+		b.where(`u.email ILIKE '%' || ? || '%'`, likeEscaper.Replace(q.Email))
+	}
+	if q.Name != "" {
+		b.where(`u.name ILIKE '%' || ? || '%'`, likeEscaper.Replace(q.Name))
+	}
+	if q.CreatedFrom != nil {
+		b.where("u.created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		b.where("u.created_at < ?", *q.CreatedTo)
+	}
+	if q.UpdatedFrom != nil {
+		b.where("u.updated_at >= ?", *q.UpdatedFrom)
+	}
+	if q.UpdatedTo != nil {
+		b.where("u.updated_at < ?", *q.UpdatedTo)
+	}
+	dir, op := "ASC", ">"
+	if sb.desc {
+		dir, op = "DESC", "<"
+	}
+	if after != nil {
+		key := sb.cursorKey(*after)
+		b.where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND u.id > ?))", sb.column, op), key, key, after.ID)
+	}
+	b.args = append(b.args, limit)
+	query := fmt.Sprintf(
+		"%s\tWHERE %s\n\tORDER BY %s %s, u.id ASC\n\tLIMIT $%d\n",
+		getAllSelect,
+		strings.Join(b.conds, "\n\tAND "),
+		sb.column,
+		dir,
+		len(b.args),
+	)
+	return query, b.args, nil
+}
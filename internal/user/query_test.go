@@ -0,0 +1,121 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildGetAllQuery(t *testing.T) {
+	query, args, err := buildGetAllQuery(user.Query{}, false, nil, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, getAllSelect+`	WHERE ($1::BOOLEAN OR u.deleted_at IS NULL)
+	ORDER BY u.email ASC, u.id ASC
+	LIMIT $2
+`, query)
+	assert.Equal(t, []any{false, 10}, args)
+}
+
+func TestBuildGetAllQuery_Filters(t *testing.T) {
+	isActive := true
+	isAdmin := false
+	createdFrom := time.UnixMilli(100)
+	createdTo := time.UnixMilli(200)
+	updatedFrom := time.UnixMilli(300)
+	updatedTo := time.UnixMilli(400)
+	q := user.Query{
+		OrgID:       "foo-org-id",
+		IsActive:    &isActive,
+		IsAdmin:     &isAdmin,
+		Email:       "foo",
+		Name:        "bar",
+		CreatedFrom: &createdFrom,
+		CreatedTo:   &createdTo,
+		UpdatedFrom: &updatedFrom,
+		UpdatedTo:   &updatedTo,
+	}
+
+	query, args, err := buildGetAllQuery(q, true, nil, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, getAllSelect+`	WHERE ($1::BOOLEAN OR u.deleted_at IS NULL)
+	AND EXISTS (SELECT 1 FROM memberships m WHERE m.user_id = u.id AND m.org_id = $2)
+	AND u.is_active = $3
+	AND u.is_admin = $4
+	AND u.email ILIKE '%' || $5 || '%'
+	AND u.name ILIKE '%' || $6 || '%'
+	AND u.created_at >= $7
+	AND u.created_at < $8
+	AND u.updated_at >= $9
+	AND u.updated_at < $10
+	ORDER BY u.email ASC, u.id ASC
+	LIMIT $11
+`, query)
+	assert.Equal(t, []any{true, "foo-org-id", true, false, "foo", "bar", createdFrom, createdTo, updatedFrom, updatedTo, 10}, args)
+}
+
+func TestBuildGetAllQuery_EscapesLike(t *testing.T) {
+	_, args, err := buildGetAllQuery(user.Query{Email: `50%_off\`}, false, nil, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []any{false, `50\%\_off\\`, 10}, args)
+}
+
+func TestBuildGetAllQuery_After(t *testing.T) {
+	after := page.Cursor{Key: "foo-name", ID: "foo-id"}
+
+	query, args, err := buildGetAllQuery(user.Query{Sort: user.SortName}, false, &after, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, getAllSelect+`	WHERE ($1::BOOLEAN OR u.deleted_at IS NULL)
+	AND (u.name > $2 OR (u.name = $3 AND u.id > $4))
+	ORDER BY u.name ASC, u.id ASC
+	LIMIT $5
+`, query)
+	assert.Equal(t, []any{false, "foo-name", "foo-name", "foo-id", 10}, args)
+}
+
+func TestBuildGetAllQuery_AfterDesc(t *testing.T) {
+	after := page.Cursor{CreatedAt: time.UnixMilli(100), ID: "foo-id"}
+
+	query, args, err := buildGetAllQuery(user.Query{Sort: "-updated_at"}, false, &after, 10)
+
+	assert.Nil(t, err)
+	assert.Equal(t, getAllSelect+`	WHERE ($1::BOOLEAN OR u.deleted_at IS NULL)
+	AND (u.updated_at < $2 OR (u.updated_at = $3 AND u.id > $4))
+	ORDER BY u.updated_at DESC, u.id ASC
+	LIMIT $5
+`, query)
+	assert.Equal(t, []any{false, time.UnixMilli(100), time.UnixMilli(100), "foo-id", 10}, args)
+}
+
+func TestBuildGetAllQuery_InvalidSort(t *testing.T) {
+	_, _, err := buildGetAllQuery(user.Query{Sort: "email; DROP TABLE users"}, false, nil, 10)
+
+	var expected ErrInvalidSort
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "email; DROP TABLE users", expected.Sort)
+}
+
+func TestParseSort(t *testing.T) {
+	sb, err := parseSort("-created_at")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "u.created_at", sb.column)
+	assert.True(t, sb.desc)
+	u := user.User{ID: "foo-id", CreatedAt: time.UnixMilli(100)}
+	assert.Equal(t, page.Cursor{CreatedAt: time.UnixMilli(100), ID: "foo-id"}, sb.toCursor(u))
+}
+
+func TestParseSort_DefaultsToEmail(t *testing.T) {
+	sb, err := parseSort("")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "u.email", sb.column)
+	assert.False(t, sb.desc)
+}
@@ -2,28 +2,55 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"iter"
 	"log/slog"
 	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/internal/notify"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
 	"github.com/RyanBard/go-service-ex/pkg/org"
 	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/jmoiron/sqlx"
 )
 
 type OrgSVC interface {
-	GetByID(ctx context.Context, id string) (org.Org, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error)
 }
 
 type UserDAO interface {
-	GetByID(ctx context.Context, id string) (user.User, error)
-	GetAll(ctx context.Context) ([]user.User, error)
-	GetAllByOrgID(ctx context.Context, orgID string) ([]user.User, error)
+	GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error)
+	GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (user.User, error)
+	GetByEmail(ctx context.Context, tx *sqlx.Tx, email string) (user.User, error)
+	GetAll(ctx context.Context, q user.Query, includeDeleted bool, after *page.Cursor, limit int) ([]user.User, error)
+	GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, after *page.Cursor, limit int) ([]user.User, error)
 	Create(ctx context.Context, tx *sqlx.Tx, u user.User) error
+	CreateBatch(ctx context.Context, tx *sqlx.Tx, users []user.User) error
 	Update(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error)
-	Delete(ctx context.Context, tx *sqlx.Tx, u user.DeleteUser) error
+	Delete(ctx context.Context, tx *sqlx.Tx, u user.User) error
+	Restore(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	SaveEmailChange(ctx context.Context, tx *sqlx.Tx, ec StoredEmailChange) error
+	GetEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) (StoredEmailChange, error)
+	DeleteEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) error
+	UpdateEmail(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error)
+}
+
+type Auditor interface {
+	Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error
 }
 
 type TXManager interface {
@@ -38,59 +65,172 @@ type IDGenerator interface {
 	GenID() string
 }
 
+type Notifier interface {
+	Send(ctx context.Context, m notify.Message) error
+}
+
 type service struct {
-	log    *slog.Logger
-	orgSVC OrgSVC
-	dao    UserDAO
-	txMGR  TXManager
-	timer  Timer
-	idGen  IDGenerator
+	log            *slog.Logger
+	orgSVC         OrgSVC
+	dao            UserDAO
+	auditor        Auditor
+	txMGR          TXManager
+	timer          Timer
+	idGen          IDGenerator
+	notifier       Notifier
+	emailChangeTTL time.Duration
 }
 
-func NewService(log *slog.Logger, orgSVC OrgSVC, dao UserDAO, txMGR TXManager, timer Timer, idGen IDGenerator) *service {
+func NewService(log *slog.Logger, orgSVC OrgSVC, dao UserDAO, auditor Auditor, txMGR TXManager, timer Timer, idGen IDGenerator, notifier Notifier, emailChangeTTL time.Duration) *service {
 	return &service{
-		log:    log.With(logutil.LogAttrSVC("UserSVC")),
-		orgSVC: orgSVC,
-		dao:    dao,
-		txMGR:  txMGR,
-		timer:  timer,
-		idGen:  idGen,
+		log:            log.With(logutil.LogAttrSVC("UserSVC")),
+		orgSVC:         orgSVC,
+		dao:            dao,
+		auditor:        auditor,
+		txMGR:          txMGR,
+		timer:          timer,
+		idGen:          idGen,
+		notifier:       notifier,
+		emailChangeTTL: emailChangeTTL,
 	}
 }
 
-func (s service) GetByID(ctx context.Context, id string) (user.User, error) {
+func (s service) GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error) {
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetByID"),
 		logAttrUserID(id),
+		logAttrIncludeDeleted(includeDeleted),
 	)
 	log.Debug("called")
-	return s.dao.GetByID(ctx, id)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+	u, err := s.dao.GetByID(ctx, id, includeDeleted)
+	if err != nil {
+		return user.User{}, err
+	}
+	if !p.CanRead(u.OrgID) {
+		log.Warn("forbidden")
+		return user.User{}, authz.ErrForbidden{UserID: p.UserID, Action: "user:read"}
+	}
+	// deleted users are only visible to the ones who could have deleted them
+	if includeDeleted && !p.CanManage(u.OrgID) {
+		log.Warn("forbidden")
+		return user.User{}, authz.ErrForbidden{UserID: p.UserID, Action: "user:read_deleted"}
+	}
+	return u, nil
 }
 
-func (s service) GetAll(ctx context.Context) ([]user.User, error) {
+func (s service) GetAll(ctx context.Context, q user.Query, includeDeleted bool, pr page.Request) (up user.UserPage, err error) {
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetAll"),
+		logAttrQuery(q),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(pr.After),
+		logAttrLimit(pr.Limit),
 	)
 	log.Debug("called")
-	return s.dao.GetAll(ctx)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return up, err
+	}
+	sb, err := parseSort(q.Sort)
+	if err != nil {
+		return up, err
+	}
+	if !p.IsSystemAdmin() {
+		// everyone else sees one org at a time, their own unless they ask
+		// for another they're a member of
+		if q.OrgID == "" {
+			q.OrgID = p.OrgID
+		}
+		if !p.CanRead(q.OrgID) {
+			log.Warn("forbidden")
+			return up, authz.ErrForbidden{UserID: p.UserID, Action: "user:list"}
+		}
+		if includeDeleted && !p.CanManage(q.OrgID) {
+			log.Warn("forbidden")
+			return up, authz.ErrForbidden{UserID: p.UserID, Action: "user:read_deleted"}
+		}
+	}
+	users, err := s.dao.GetAll(ctx, q, includeDeleted, pr.After, pr.Limit+1)
+	if err != nil {
+		return up, err
+	}
+	up.Users, up.NextCursor = page.Trim(users, pr.Limit, sb.toCursor)
+	return up, nil
 }
 
-func (s service) GetAllByOrgID(ctx context.Context, orgID string) ([]user.User, error) {
+func (s service) GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, pr page.Request) (up user.UserPage, err error) {
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
 		logutil.LogAttrFN("GetAllByOrgID"),
 		logAttrOrgID(orgID),
+		logAttrIncludeDeleted(includeDeleted),
+		logAttrAfter(pr.After),
+		logAttrLimit(pr.Limit),
 	)
 	log.Debug("called")
-	return s.dao.GetAllByOrgID(ctx, orgID)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return up, err
+	}
+	if !p.CanRead(orgID) {
+		log.Warn("forbidden")
+		return up, authz.ErrForbidden{UserID: p.UserID, Action: "user:list"}
+	}
+	if includeDeleted && !p.CanManage(orgID) {
+		log.Warn("forbidden")
+		return up, authz.ErrForbidden{UserID: p.UserID, Action: "user:read_deleted"}
+	}
+	users, err := s.dao.GetAllByOrgID(ctx, orgID, includeDeleted, pr.After, pr.Limit+1)
+	if err != nil {
+		return up, err
+	}
+	up.Users, up.NextCursor = page.Trim(users, pr.Limit, toCursor)
+	return up, nil
+}
+
+// Export pages through GetAll so the whole table is never in memory, a
+// failure part way through is yielded after the rows that made it.
+func (s service) Export(ctx context.Context) iter.Seq2[user.User, error] {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Export"),
+	)
+	log.Debug("called")
+	return page.All(page.MaxLimit, func(pr page.Request) ([]user.User, string, error) {
+		up, err := s.GetAll(ctx, user.Query{}, false, pr)
+		return up.Users, up.NextCursor, err
+	})
+}
+
+func toCursor(u user.User) page.Cursor {
+	return page.Cursor{
+		Key:       u.Email,
+		CreatedAt: u.CreatedAt,
+		ID:        u.ID,
+	}
 }
 
+// Save creates u when it doesn't have an id (its rules are checked by the
+// caller) and updates it otherwise, an update checks the rules of the fields
+// it changes.
 func (s service) Save(ctx context.Context, u user.User) (out user.User, err error) {
+	if u.ID == "" {
+		return s.Create(ctx, nil, u)
+	}
+	return s.update(ctx, nil, u)
+}
+
+func (s service) update(ctx context.Context, joinTX *sqlx.Tx, u user.User) (out user.User, err error) {
 	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
 	if loggedInUserID == "" {
 		return out, errors.New("user not logged in")
@@ -98,45 +238,275 @@ func (s service) Save(ctx context.Context, u user.User) (out user.User, err erro
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
-		logutil.LogAttrFN("Save"),
+		logutil.LogAttrFN("update"),
 		logAttrUser(u),
 	)
 	log.Debug("called")
-	if u.ID != "" {
-		userInDB, err := s.GetByID(ctx, u.ID)
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	userInDB, err := s.getForWrite(ctx, joinTX, u.ID)
+	if err != nil {
+		return out, err
+	}
+	if !p.CanManage(userInDB.OrgID) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "user:update"}
+	}
+	if userInDB.IsSystem {
+		err = ErrCannotModifySysUser{ID: u.ID}
+		return out, err
+	}
+	// only the changed fields, a user from before a rule was added can still
+	// be updated
+	if err := validate.Changed(u, userInDB, ""); err != nil {
+		return out, err
+	}
+	// checked for updates too so an org admin can't move a user out of their
+	// org
+	if !p.CanManage(u.OrgID) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "user:save"}
+	}
+	orgInDB, err := s.orgSVC.GetByID(ctx, u.OrgID, false)
+	if err != nil {
+		return out, err
+	}
+	if orgInDB.IsSystem {
+		err = ErrCannotAssociateSysOrg{UserID: u.ID, OrgID: u.OrgID}
+		return out, err
+	}
+	err = s.txMGR.Do(ctx, joinTX, func(tx *sqlx.Tx) error {
+		u.UpdatedAt = s.timer.Now()
+		u.UpdatedBy = loggedInUserID
+		out, err = s.dao.Update(ctx, tx, u)
+		if err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, u.ID, userInDB, out)
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+	return out, nil
+}
+
+// getForWrite reads the user an update or delete is about to change. Without
+// joinTX it's read outside the tx, the version check in the write means it's
+// still what's being overwritten when the audit event is recorded. In joinTX
+// it's read (and locked) in the tx so it sees what the caller has already
+// written there, ex. an earlier op of the same batch.
+func (s service) getForWrite(ctx context.Context, joinTX *sqlx.Tx, id string) (user.User, error) {
+	if joinTX == nil {
+		return s.GetByID(ctx, id, false)
+	}
+	return s.dao.GetByIDForUpdate(ctx, joinTX, id)
+}
+
+// Patch applies changes to the user as it is in the db, it's read in the same
+// tx as the update (and locked) so nothing can land in between. Only the
+// name, is_admin, is_active and version can be changed (the email has its own
+// flow), a changed version (or the If-Match version, when it isn't 0) has to
+// match the user's.
+func (s service) Patch(ctx context.Context, id string, version int64, changes patch.Patch) (out user.User, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Patch"),
+		logAttrUserID(id),
+		logAttrVersion(version),
+		logAttrContentType(changes.ContentType),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		userInDB, err := s.dao.GetByIDForUpdate(ctx, tx, id)
 		if err != nil {
-			return out, err
+			return err
+		}
+		if !p.CanManage(userInDB.OrgID) {
+			log.Warn("forbidden")
+			return authz.ErrForbidden{UserID: p.UserID, Action: "user:update"}
 		}
 		if userInDB.IsSystem {
-			err = ErrCannotModifySysUser{ID: u.ID}
-			return out, err
+			return ErrCannotModifySysUser{ID: id}
+		}
+		if version != 0 && version != userInDB.Version {
+			return ErrOptimisticLock{ID: id, Version: version}
+		}
+		u, err := patch.To(changes, userInDB, "name", "is_admin", "is_active", "version")
+		if err != nil {
+			return err
+		}
+		if u.Version != userInDB.Version {
+			return ErrOptimisticLock{ID: id, Version: u.Version}
+		}
+		if err := validate.Changed(u, userInDB, ""); err != nil {
+			return err
+		}
+		u.UpdatedAt = s.timer.Now()
+		u.UpdatedBy = loggedInUserID
+		out, err = s.dao.Update(ctx, tx, u)
+		if err != nil {
+			return err
 		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, id, userInDB, out)
+	})
+	if err != nil {
+		return user.User{}, err
 	}
-	orgInDB, err := s.orgSVC.GetByID(ctx, u.OrgID)
+	return out, nil
+}
+
+// Create is the create half of Save for callers that need the user to be part
+// of their own tx, a nil joinTX creates one.
+func (s service) Create(ctx context.Context, joinTX *sqlx.Tx, u user.User) (out user.User, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Create"),
+		logAttrUser(u),
+	)
+	log.Debug("called")
+	orgInDB, err := s.getOrgForCreate(ctx, u.OrgID)
 	if err != nil {
 		return out, err
 	}
-	if orgInDB.IsSystem {
-		err = ErrCannotAssociateSysOrg{UserID: u.ID, OrgID: u.OrgID}
+	return s.CreateInOrg(ctx, joinTX, orgInDB, u)
+}
+
+// CreateInOrg is Create for callers that already have the org, ex. one that
+// was just created in joinTX and can't be read outside of it yet.
+func (s service) CreateInOrg(ctx context.Context, joinTX *sqlx.Tx, o org.Org, u user.User) (out user.User, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("CreateInOrg"),
+		logAttrOrgID(o.ID),
+		logAttrUser(u),
+	)
+	log.Debug("called")
+	u, err = s.newUser(ctx, o, u)
+	if err != nil {
 		return out, err
 	}
-	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
-		if u.ID == "" {
-			u.ID = s.idGen.GenID()
-			u.Version = 1
-			u.CreatedAt = s.timer.Now()
-			u.CreatedBy = loggedInUserID
-			u.UpdatedAt = s.timer.Now()
-			u.UpdatedBy = loggedInUserID
-			u.IsSystem = false
-			out = u
-			return s.dao.Create(ctx, tx, u)
-		} else {
-			u.UpdatedAt = s.timer.Now()
-			u.UpdatedBy = loggedInUserID
-			out, err = s.dao.Update(ctx, tx, u)
+	err = s.txMGR.Do(ctx, joinTX, func(tx *sqlx.Tx) error {
+		if err := s.dao.Create(ctx, tx, u); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityUser, u.ID, nil, u)
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+	return u, nil
+}
+
+// getOrgForCreate checks the logged in user can add users to the org before
+// reading it, so it can't be used to probe for orgs.
+func (s service) getOrgForCreate(ctx context.Context, orgID string) (org.Org, error) {
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return org.Org{}, err
+	}
+	if !p.CanManage(orgID) {
+		s.log.With(
+			logutil.LogAttrReqID(ctx),
+			logutil.LogAttrLoggedInUserID(ctx),
+			logutil.LogAttrFN("getOrgForCreate"),
+			logAttrOrgID(orgID),
+		).Warn("forbidden")
+		return org.Org{}, authz.ErrForbidden{UserID: p.UserID, Action: "user:save"}
+	}
+	return s.orgSVC.GetByID(ctx, orgID, false)
+}
+
+// newUser checks u can be created in o and fills in the fields the service
+// owns, nothing is written.
+func (s service) newUser(ctx context.Context, o org.Org, u user.User) (user.User, error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return user.User{}, errors.New("user not logged in")
+	}
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+	if !p.CanManage(o.ID) {
+		s.log.With(
+			logutil.LogAttrReqID(ctx),
+			logutil.LogAttrLoggedInUserID(ctx),
+			logutil.LogAttrFN("newUser"),
+			logAttrOrgID(o.ID),
+		).Warn("forbidden")
+		return user.User{}, authz.ErrForbidden{UserID: p.UserID, Action: "user:save"}
+	}
+	if o.IsSystem {
+		return user.User{}, ErrCannotAssociateSysOrg{UserID: u.ID, OrgID: o.ID}
+	}
+	u.ID = s.idGen.GenID()
+	u.OrgID = o.ID
+	u.Version = 1
+	u.CreatedAt = s.timer.Now()
+	u.CreatedBy = loggedInUserID
+	u.UpdatedAt = s.timer.Now()
+	u.UpdatedBy = loggedInUserID
+	u.IsSystem = false
+	return u, nil
+}
+
+// SaveInvited creates the user an accepted invitation is for in its org, or
+// activates them if the email already belongs to an inactive user of that org.
+// A user of another org is left as is (the caller gives them a membership), an
+// invite can't undo another org's admin deactivating them. There's no
+// logged in user to check, the caller has already checked the invitation's
+// token, so the user is recorded as making the change themselves.
+func (s service) SaveInvited(ctx context.Context, joinTX *sqlx.Tx, u user.User) (out user.User, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("SaveInvited"),
+		logAttrUser(u),
+	)
+	log.Debug("called")
+	err = s.txMGR.Do(ctx, joinTX, func(tx *sqlx.Tx) error {
+		userInDB, err := s.dao.GetByEmail(ctx, tx, u.Email)
+		if errors.As(err, &ErrEmailNotFound{}) {
+			out, err = s.createInvited(ctx, tx, u)
+			return err
+		}
+		if err != nil {
 			return err
 		}
+		if userInDB.DeletedAt != nil || userInDB.IsSystem {
+			return ErrEmailAlreadyInUse{Email: u.Email}
+		}
+		if userInDB.IsActive || userInDB.OrgID != u.OrgID {
+			out = userInDB
+			return nil
+		}
+		ctx := context.WithValue(ctx, ctxutil.ContextKeyUserID{}, userInDB.ID)
+		activated := userInDB
+		activated.IsActive = true
+		activated.UpdatedAt = s.timer.Now()
+		activated.UpdatedBy = userInDB.ID
+		out, err = s.dao.Update(ctx, tx, activated)
+		if err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, out.ID, userInDB, out)
 	})
 	if err != nil {
 		return user.User{}, err
@@ -144,27 +514,529 @@ func (s service) Save(ctx context.Context, u user.User) (out user.User, err erro
 	return out, nil
 }
 
+func (s service) createInvited(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error) {
+	if u.Name == "" {
+		return user.User{}, ErrNameRequired{Email: u.Email}
+	}
+	u.ID = s.idGen.GenID()
+	ctx = context.WithValue(ctx, ctxutil.ContextKeyUserID{}, u.ID)
+	u.Version = 1
+	u.CreatedAt = s.timer.Now()
+	u.CreatedBy = u.ID
+	u.UpdatedAt = s.timer.Now()
+	u.UpdatedBy = u.ID
+	u.IsSystem = false
+	u.IsActive = true
+	if err := s.dao.Create(ctx, tx, u); err != nil {
+		return user.User{}, err
+	}
+	if err := s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityUser, u.ID, nil, u); err != nil {
+		return user.User{}, err
+	}
+	return u, nil
+}
+
 func (s service) Delete(ctx context.Context, u user.DeleteUser) error {
+	return s.delete(ctx, nil, u)
+}
+
+func (s service) delete(ctx context.Context, joinTX *sqlx.Tx, u user.DeleteUser) error {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return errors.New("user not logged in")
+	}
 	log := s.log.With(
 		logutil.LogAttrReqID(ctx),
 		logutil.LogAttrLoggedInUserID(ctx),
-		logutil.LogAttrFN("Delete"),
+		logutil.LogAttrFN("delete"),
 		logAttrUser(u),
 	)
 	log.Debug("called")
-	userInDB, err := s.GetByID(ctx, u.ID)
+	p, err := authz.FromContext(ctx)
 	if err != nil {
 		return err
 	}
+	userInDB, err := s.getForWrite(ctx, joinTX, u.ID)
+	if err != nil {
+		return err
+	}
+	if !p.CanManage(userInDB.OrgID) {
+		log.Warn("forbidden")
+		return authz.ErrForbidden{UserID: p.UserID, Action: "user:delete"}
+	}
 	if userInDB.IsSystem {
 		err = ErrCannotModifySysUser{ID: u.ID}
 		return err
 	}
+	deletedAt := s.timer.Now()
+	deleted := userInDB
+	deleted.Version = u.Version
+	deleted.DeletedAt = &deletedAt
+	deleted.DeletedBy = loggedInUserID
+	err = s.txMGR.Do(ctx, joinTX, func(tx *sqlx.Tx) error {
+		if err := s.dao.Delete(ctx, tx, deleted); err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionDelete, audit.EntityUser, u.ID, userInDB, nil)
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s service) Restore(ctx context.Context, u user.RestoreUser) (out user.User, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Restore"),
+		logAttrUser(u),
+	)
+	log.Debug("called")
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return out, err
+	}
+	userInDB, err := s.GetByID(ctx, u.ID, true)
+	if err != nil {
+		return out, err
+	}
+	if !p.CanManage(userInDB.OrgID) {
+		log.Warn("forbidden")
+		return out, authz.ErrForbidden{UserID: p.UserID, Action: "user:restore"}
+	}
+	if userInDB.DeletedAt == nil {
+		log.Info("not deleted, nothing to restore")
+		return userInDB, nil
+	}
+	// a user can't come back into an org that's been deleted, the org has to
+	// be restored first
+	if _, err := s.orgSVC.GetByID(ctx, userInDB.OrgID, false); err != nil {
+		return out, err
+	}
+	restored := userInDB
+	restored.Version = u.Version
+	restored.UpdatedAt = s.timer.Now()
+	restored.UpdatedBy = loggedInUserID
 	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
-		return s.dao.Delete(ctx, tx, u)
+		out, err = s.dao.Restore(ctx, tx, restored)
+		if err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionRestore, audit.EntityUser, u.ID, userInDB, out)
 	})
 	if err != nil {
+		return user.User{}, err
+	}
+	return out, nil
+}
+
+// BatchResult is the outcome of the op at the same index, User is only set
+// for creates and updates that succeeded.
+type BatchResult struct {
+	User user.User
+	Err  error
+}
+
+// Batch runs the ops in order, the errors of individual ops are in the
+// results so only ones that stop the whole batch are returned.
+func (s service) Batch(ctx context.Context, b user.Batch) (results []BatchResult, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return nil, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Batch"),
+		logAttrBatchMode(b.Mode),
+		logAttrOpsLen(len(b.Ops)),
+	)
+	log.Debug("called")
+	if _, err := authz.FromContext(ctx); err != nil {
+		return nil, err
+	}
+	if b.Mode == user.BatchModeBestEffort {
+		results = make([]BatchResult, len(b.Ops))
+		for i, op := range b.Ops {
+			results[i].User, results[i].Err = s.runBatchOp(ctx, nil, i, op)
+		}
+		return results, nil
+	}
+	return s.batchAllOrNothing(ctx, b.Ops)
+}
+
+// batchAllOrNothing runs every op in one tx, in order. Creates are held back
+// and saved with a single insert, but the ones held are inserted before the
+// next update or delete runs, so every op sees the ones before it.
+func (s service) batchAllOrNothing(ctx context.Context, ops []user.BatchOp) (results []BatchResult, err error) {
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("batchAllOrNothing"),
+	)
+	results = make([]BatchResult, len(ops))
+	failed := -1
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		var creates []user.User
+		var createIndexes []int
+		orgs := map[string]org.Org{}
+		flush := func() error {
+			if len(creates) == 0 {
+				return nil
+			}
+			if err := s.dao.CreateBatch(ctx, tx, creates); err != nil {
+				failed = createIndexes[0]
+				// postgres reports a duplicate on the later of the 2 rows
+				var dupEmail ErrEmailAlreadyInUse
+				if errors.As(err, &dupEmail) {
+					for j, u := range creates {
+						if u.Email == dupEmail.Email {
+							failed = createIndexes[j]
+						}
+					}
+				}
+				results[failed].Err = err
+				return err
+			}
+			for _, u := range creates {
+				if err := s.auditor.Record(ctx, tx, audit.ActionCreate, audit.EntityUser, u.ID, nil, u); err != nil {
+					return err
+				}
+			}
+			creates, createIndexes = nil, nil
+			return nil
+		}
+		for i, op := range ops {
+			var err error
+			if op.Op == user.BatchOpCreate {
+				results[i].User, err = s.newBatchUser(ctx, orgs, op.User)
+				if err == nil {
+					creates = append(creates, results[i].User)
+					createIndexes = append(createIndexes, i)
+				}
+			} else {
+				if err := flush(); err != nil {
+					return err
+				}
+				results[i].User, err = s.runBatchOp(ctx, tx, i, op)
+			}
+			if err != nil {
+				failed = i
+				results[i].Err = err
+				return err
+			}
+		}
+		return flush()
+	})
+	if err == nil {
+		return results, nil
+	}
+	if failed == -1 {
+		return nil, err
+	}
+	log.With(logutil.LogAttrError(err), logAttrFailedIndex(failed)).Warn("batch rolled back")
+	for i := range results {
+		if i != failed {
+			results[i] = BatchResult{Err: ErrBatchAborted{FailedIndex: failed}}
+		}
+	}
+	results[failed].User = user.User{}
+	return results, nil
+}
+
+// newBatchUser is Create without the write, orgs caches the orgs already read
+// for the batch.
+func (s service) newBatchUser(ctx context.Context, orgs map[string]org.Org, u user.User) (user.User, error) {
+	o, ok := orgs[u.OrgID]
+	if !ok {
+		var err error
+		o, err = s.getOrgForCreate(ctx, u.OrgID)
+		if err != nil {
+			return user.User{}, err
+		}
+		orgs[u.OrgID] = o
+	}
+	return s.newUser(ctx, o, u)
+}
+
+func (s service) runBatchOp(ctx context.Context, joinTX *sqlx.Tx, i int, op user.BatchOp) (user.User, error) {
+	switch op.Op {
+	case user.BatchOpCreate:
+		return s.Create(ctx, joinTX, op.User)
+	case user.BatchOpUpdate:
+		return s.update(ctx, joinTX, op.User)
+	case user.BatchOpDelete:
+		err := s.delete(ctx, joinTX, user.DeleteUser{ID: op.User.ID, Version: op.User.Version})
+		// already gone is a success, same as Delete's 204, and it's found out
+		// before anything is written so the tx can carry on
+		if errors.As(err, &ErrNotFound{}) {
+			return user.User{}, nil
+		}
+		return user.User{}, err
+	default:
+		return user.User{}, ErrInvalidBatchOp{Index: i, Op: op.Op, Reason: "unknown op"}
+	}
+}
+
+// ImportResult only keeps the rows that failed so a large import isn't held in
+// memory.
+type ImportResult struct {
+	Rows     int
+	Imported int
+	Errors   []ImportRowError
+	// Stopped is set when the rest of the input couldn't be read, the rows
+	// before it were still imported
+	Stopped *ImportRowError
+}
+
+// ImportRowError is a row that wasn't imported, Row is 1 based.
+type ImportRowError struct {
+	Row int
+	Err error
+}
+
+// errDryRun rolls back a dry run row once it's been through everything a real
+// one would.
+var errDryRun = errors.New("dry run")
+
+// Import creates every row the same way Create does, each in its own tx, so a
+// bad row only fails itself. A dry run rolls each row back instead, the
+// report is the same as a real import's. Rows that couldn't be read are
+// reported along with the rest, anything else stops the import. Stopping
+// before the first row is an error, after that the rows before it are already
+// committed so it's reported as Stopped.
+func (s service) Import(ctx context.Context, rows iter.Seq2[user.User, error], dryRun bool) (res ImportResult, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return res, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("Import"),
+		logAttrDryRun(dryRun),
+	)
+	log.Debug("called")
+	if _, err := authz.FromContext(ctx); err != nil {
+		return res, err
+	}
+	// the emails in the file, a dry run never writes them so the db can't
+	// catch a duplicate within the file
+	seen := map[string]bool{}
+	for u, err := range rows {
+		if err != nil && !errors.As(err, &dataio.ErrInvalidRow{}) {
+			log.With(logutil.LogAttrError(err), logAttrRow(res.Rows+1)).Warn("import stopped")
+			if res.Rows == 0 {
+				return res, err
+			}
+			res.Stopped = &ImportRowError{Row: res.Rows + 1, Err: err}
+			break
+		}
+		res.Rows++
+		if err == nil {
+			err = s.importRow(ctx, seen, u, dryRun)
+		}
+		if err != nil {
+			res.Errors = append(res.Errors, ImportRowError{Row: res.Rows, Err: err})
+			continue
+		}
+		res.Imported++
+	}
+	log.With(logAttrRows(res.Rows), logAttrImported(res.Imported)).Info("imported")
+	return res, nil
+}
+
+func (s service) importRow(ctx context.Context, seen map[string]bool, u user.User, dryRun bool) error {
+	if seen[u.Email] {
+		return ErrEmailAlreadyInUse{Email: u.Email}
+	}
+	err := s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		if _, err := s.Create(ctx, tx, u); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
 		return err
 	}
+	seen[u.Email] = true
 	return nil
 }
+
+// StoredEmailChange is a pending email change as it's stored, only a hash of
+// the token is kept.
+type StoredEmailChange struct {
+	UserID    string    `db:"user_id"`
+	NewEmail  string    `db:"new_email"`
+	TokenHash string    `db:"token_hash"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+	CreatedBy string    `db:"created_by"`
+}
+
+// RequestEmailChange sends a single use token to the new email, the email
+// only changes once it's confirmed. A new request replaces the pending one.
+func (s service) RequestEmailChange(ctx context.Context, id string, ec user.EmailChange) (pec user.PendingEmailChange, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return pec, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("RequestEmailChange"),
+		logAttrUserID(id),
+	)
+	log.Debug("called")
+	userInDB, err := s.getForEmailChange(ctx, id)
+	if err != nil {
+		return pec, err
+	}
+	if ec.Email == userInDB.Email {
+		return pec, ErrEmailUnchanged{Email: ec.Email}
+	}
+	token, tokenHash, err := newEmailChangeToken()
+	if err != nil {
+		return pec, err
+	}
+	now := s.timer.Now()
+	stored := StoredEmailChange{
+		UserID:    id,
+		NewEmail:  ec.Email,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(s.emailChangeTTL),
+		CreatedAt: now,
+		CreatedBy: loggedInUserID,
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		if err := s.dao.SaveEmailChange(ctx, tx, stored); err != nil {
+			return err
+		}
+		return s.notifier.Send(ctx, emailChangeMessage(stored, token))
+	})
+	if err != nil {
+		return pec, err
+	}
+	log.Info("email change requested")
+	return user.PendingEmailChange{
+		UserID:    id,
+		Email:     stored.NewEmail,
+		ExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// ConfirmEmailChange swaps in the pending email if the token matches, the
+// pending change is gone afterwards so the token can't be used again.
+func (s service) ConfirmEmailChange(ctx context.Context, id string, cec user.ConfirmEmailChange) (out user.User, err error) {
+	loggedInUserID, _ := ctx.Value(ctxutil.ContextKeyUserID{}).(string)
+	if loggedInUserID == "" {
+		return out, errors.New("user not logged in")
+	}
+	log := s.log.With(
+		logutil.LogAttrReqID(ctx),
+		logutil.LogAttrLoggedInUserID(ctx),
+		logutil.LogAttrFN("ConfirmEmailChange"),
+		logAttrUserID(id),
+	)
+	log.Debug("called")
+	// UpdateEmail checks the version, like the writes after getForWrite
+	userInDB, err := s.getForEmailChange(ctx, id)
+	if err != nil {
+		return out, err
+	}
+	err = s.txMGR.Do(ctx, nil, func(tx *sqlx.Tx) error {
+		stored, err := s.dao.GetEmailChange(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(hashEmailChangeToken(cec.Token)), []byte(stored.TokenHash)) != 1 {
+			log.Warn("invalid token")
+			return ErrInvalidEmailChangeToken{UserID: id}
+		}
+		now := s.timer.Now()
+		if !now.Before(stored.ExpiresAt) {
+			return ErrEmailChangeExpired{UserID: id}
+		}
+		if err := s.dao.DeleteEmailChange(ctx, tx, id); err != nil {
+			return err
+		}
+		u := userInDB
+		u.Email = stored.NewEmail
+		u.UpdatedAt = now
+		u.UpdatedBy = loggedInUserID
+		out, err = s.dao.UpdateEmail(ctx, tx, u)
+		if err != nil {
+			return err
+		}
+		return s.auditor.Record(ctx, tx, audit.ActionUpdate, audit.EntityUser, id, userInDB, out)
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+	log.Info("email changed")
+	return out, nil
+}
+
+// getForEmailChange only lets users change their own email, or the admins
+// that manage them.
+func (s service) getForEmailChange(ctx context.Context, id string) (user.User, error) {
+	p, err := authz.FromContext(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+	u, err := s.GetByID(ctx, id, false)
+	if err != nil {
+		return user.User{}, err
+	}
+	if p.UserID != u.ID && !p.CanManage(u.OrgID) {
+		s.log.With(
+			logutil.LogAttrReqID(ctx),
+			logutil.LogAttrLoggedInUserID(ctx),
+			logutil.LogAttrFN("getForEmailChange"),
+			logAttrUserID(id),
+		).Warn("forbidden")
+		return user.User{}, authz.ErrForbidden{UserID: p.UserID, Action: "user:change_email"}
+	}
+	if u.IsSystem {
+		return user.User{}, ErrCannotModifySysUser{ID: u.ID}
+	}
+	return u, nil
+}
+
+// newEmailChangeToken is 32 random bytes, only its hash is stored so the
+// table alone isn't enough to confirm a change.
+func newEmailChangeToken() (token string, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashEmailChangeToken(token), nil
+}
+
+func hashEmailChangeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func emailChangeMessage(ec StoredEmailChange, token string) notify.Message {
+	return notify.Message{
+		To:      ec.NewEmail,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf(
+			"To use %s as your email, confirm it before %s with:\n\nPOST /api/users/%s/email-change/confirm {\"token\": \"%s\"}",
+			ec.NewEmail,
+			ec.ExpiresAt.Format(time.RFC3339),
+			ec.UserID,
+			token,
+		),
+	}
+}
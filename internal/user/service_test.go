@@ -3,13 +3,25 @@ package user
 import (
 	"context"
 	"errors"
+	"iter"
+	"strings"
 	"testing"
 	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/authz"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/internal/notify"
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/internal/validate"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	pkgpatch "github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -23,8 +35,15 @@ type mockDAO struct {
 	mock.Mock
 }
 
+type mockAuditor struct {
+	mock.Mock
+}
+
 type mockTXManager struct {
 	mock.Mock
+	// tx is passed on when the caller doesn't join one, so a test can tell
+	// what was done in the tx from what wasn't
+	tx *sqlx.Tx
 }
 
 type mockTimer struct {
@@ -35,22 +54,42 @@ type mockIDGen struct {
 	mock.Mock
 }
 
-func initSVC() (s *service, ms *mockOrgSVC, md *mockDAO, mm *mockTXManager, mt *mockTimer, mi *mockIDGen) {
+type mockNotifier struct {
+	mock.Mock
+}
+
+const emailChangeTTL = time.Hour
+
+func initSVC() (s *service, ms *mockOrgSVC, md *mockDAO, ma *mockAuditor, mm *mockTXManager, mt *mockTimer, mi *mockIDGen) {
 	log := testutil.GetLogger()
 	ms = new(mockOrgSVC)
 	md = new(mockDAO)
+	ma = new(mockAuditor)
 	mm = new(mockTXManager)
 	mt = new(mockTimer)
 	mi = new(mockIDGen)
-	s = NewService(log, ms, md, mm, mt, mi)
-	return s, ms, md, mm, mt, mi
+	s = NewService(log, ms, md, ma, mm, mt, mi, new(mockNotifier), emailChangeTTL)
+	return s, ms, md, ma, mm, mt, mi
+}
+
+// notifierOf is the mock notifier initSVC gave s.
+func notifierOf(s *service) *mockNotifier {
+	return s.notifier.(*mockNotifier)
+}
+
+// principalCTX is what the Auth middleware would leave in the context for a
+// token with the given claims.
+func principalCTX(userID string, claims jwt.MapClaims) context.Context {
+	claims["sub"] = userID
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, userID)
+	return context.WithValue(ctx, ctxutil.ContextKeyJWTClaims{}, claims)
 }
 
 func TestSVCGetByID(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	id := "foo-id"
 
 	mockRes := user.User{
@@ -58,35 +97,97 @@ func TestSVCGetByID(t *testing.T) {
 		Name:  "foo-name",
 		Email: "foo@bar.com",
 	}
-	md.On("GetByID", ctx, id).Return(mockRes, nil)
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
 
-	actual, err := s.GetByID(ctx, id)
+	actual, err := s.GetByID(ctx, id, false)
 
 	assert.Nil(t, err)
 	assert.Equal(t, mockRes, actual)
 }
 
 func TestSVCGetByID_DAOErr(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	id := "foo-id"
 
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetByID", ctx, id).Return(user.User{}, mockErr)
+	md.On("GetByID", ctx, id, false).Return(user.User{}, mockErr)
 
-	actual, err := s.GetByID(ctx, id)
+	actual, err := s.GetByID(ctx, id, false)
 
 	assert.Equal(t, mockErr, err)
 	assert.Equal(t, user.User{}, actual)
 }
 
+func assertForbidden(t *testing.T, err error) {
+	var forbidden authz.ErrForbidden
+	assert.True(t, errors.As(err, &forbidden))
+}
+
+func TestSVCGetByID_OwnOrg(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+	id := "foo-id"
+
+	mockRes := user.User{ID: id, OrgID: "foo-org-id"}
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
+
+	actual, err := s.GetByID(ctx, id, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByID_OtherOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	id := "foo-id"
+
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "other-org-id"}, nil)
+
+	actual, err := s.GetByID(ctx, id, false)
+
+	assertForbidden(t, err)
+	assert.Equal(t, user.User{}, actual)
+}
+
+func TestSVCGetByID_MemberOfOtherOrg(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := authz.WithMemberships(
+		principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember}),
+		map[string]string{"other-org-id": authz.RoleMember},
+	)
+	id := "foo-id"
+
+	mockRes := user.User{ID: id, OrgID: "other-org-id"}
+	md.On("GetByID", ctx, id, false).Return(mockRes, nil)
+
+	actual, err := s.GetByID(ctx, id, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByID_NotLoggedIn(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	_, err := s.GetByID(context.Background(), "foo-id", false)
+
+	var expected authz.ErrUnauthenticated
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestSVCGetAll(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 
 	mockRes := []user.User{
 		{
@@ -95,34 +196,204 @@ func TestSVCGetAll(t *testing.T) {
 			Email: "foo@bar.com",
 		},
 	}
-	md.On("GetAll", ctx).Return(mockRes, nil)
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{}, false, after, 2).Return(mockRes, nil)
 
-	actual, err := s.GetAll(ctx)
+	actual, err := s.GetAll(ctx, user.Query{}, false, page.Request{Limit: 1})
 
 	assert.Nil(t, err)
-	assert.Equal(t, mockRes, actual)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAll_MorePages(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+
+	after := page.Cursor{ID: "after-id"}
+	mockRes := []user.User{
+		{
+			ID:        "foo-id",
+			Name:      "foo-name",
+			Email:     "foo@bar.com",
+			CreatedAt: time.UnixMilli(100).UTC(),
+		},
+		{
+			ID:    "bar-id",
+			Name:  "bar-name",
+			Email: "bar@bar.com",
+		},
+	}
+	md.On("GetAll", ctx, user.Query{}, false, &after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, user.Query{}, false, page.Request{Limit: 1, After: &after})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[:1], actual.Users)
+	next, err := page.DecodeCursor(actual.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, page.Cursor{Key: "foo@bar.com", CreatedAt: time.UnixMilli(100).UTC(), ID: "foo-id"}, next)
 }
 
 func TestSVCGetAll_DAOErr(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetAll", ctx).Return([]user.User{}, mockErr)
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{}, false, after, 2).Return([]user.User{}, mockErr)
 
-	actual, err := s.GetAll(ctx)
+	actual, err := s.GetAll(ctx, user.Query{}, false, page.Request{Limit: 1})
 
 	assert.Equal(t, mockErr, err)
-	assert.Equal(t, []user.User{}, actual)
+	assert.Equal(t, user.UserPage{}, actual)
+}
+
+func TestSVCGetAll_ScopedToOwnOrg(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	orgID := "foo-org-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": orgID, "role": authz.RoleOrgAdmin})
+
+	mockRes := []user.User{{ID: "foo-id", OrgID: orgID}}
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{OrgID: orgID}, false, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, user.Query{}, false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAll_NoOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{})
+
+	_, err := s.GetAll(ctx, user.Query{}, false, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetAll_Query(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	isActive := true
+	q := user.Query{OrgID: "foo-org-id", IsActive: &isActive, Name: "foo", Sort: "-name"}
+	mockRes := []user.User{{ID: "foo-id", Name: "foo-name"}}
+	var after *page.Cursor
+	md.On("GetAll", ctx, q, false, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, q, false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAll_SortCursor(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	q := user.Query{Sort: "-updated_at"}
+	mockRes := []user.User{
+		{ID: "foo-id", UpdatedAt: time.UnixMilli(200).UTC()},
+		{ID: "bar-id", UpdatedAt: time.UnixMilli(100).UTC()},
+	}
+	var after *page.Cursor
+	md.On("GetAll", ctx, q, false, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, q, false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	next, err := page.DecodeCursor(actual.NextCursor)
+	assert.Nil(t, err)
+	assert.Equal(t, page.Cursor{CreatedAt: time.UnixMilli(200).UTC(), ID: "foo-id"}, next)
+}
+
+func TestSVCGetAll_InvalidSort(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	_, err := s.GetAll(ctx, user.Query{Sort: "password"}, false, page.Request{Limit: 1})
+
+	var expected ErrInvalidSort
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, "password", expected.Sort)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetAll_OwnOrgFilter(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	orgID := "foo-org-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": orgID, "role": authz.RoleMember})
+
+	mockRes := []user.User{{ID: "foo-id", OrgID: orgID}}
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{OrgID: orgID}, false, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, user.Query{OrgID: orgID}, false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAll_OtherOrgFilterForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	_, err := s.GetAll(ctx, user.Query{OrgID: "other-org-id"}, false, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetAll_MemberOfOtherOrgFilter(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := authz.WithMemberships(
+		principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember}),
+		map[string]string{"other-org-id": authz.RoleMember},
+	)
+
+	mockRes := []user.User{{ID: "foo-id", OrgID: "other-org-id"}}
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{OrgID: "other-org-id"}, false, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, user.Query{OrgID: "other-org-id"}, false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAll_MemberOfOtherOrgIncludeDeletedForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := authz.WithMemberships(
+		principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin}),
+		map[string]string{"other-org-id": authz.RoleMember},
+	)
+
+	_, err := s.GetAll(ctx, user.Query{OrgID: "other-org-id"}, true, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestSVCGetAllByOrgID(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	orgID := "foo-org-id"
 
 	mockRes := []user.User{
@@ -133,35 +404,79 @@ func TestSVCGetAllByOrgID(t *testing.T) {
 			Email: "foo@bar.com",
 		},
 	}
-	md.On("GetAllByOrgID", ctx, orgID).Return(mockRes, nil)
+	var after *page.Cursor
+	md.On("GetAllByOrgID", ctx, orgID, false, after, 2).Return(mockRes, nil)
 
-	actual, err := s.GetAllByOrgID(ctx, orgID)
+	actual, err := s.GetAllByOrgID(ctx, orgID, false, page.Request{Limit: 1})
 
 	assert.Nil(t, err)
-	assert.Equal(t, mockRes, actual)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAllByOrgID_MorePages(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+
+	mockRes := []user.User{
+		{
+			ID:    "foo-id",
+			OrgID: orgID,
+			Name:  "foo-name",
+			Email: "foo@bar.com",
+		},
+		{
+			ID:    "bar-id",
+			OrgID: orgID,
+			Name:  "bar-name",
+			Email: "bar@bar.com",
+		},
+	}
+	var after *page.Cursor
+	md.On("GetAllByOrgID", ctx, orgID, false, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAllByOrgID(ctx, orgID, false, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes[:1], actual.Users)
+	assert.NotEqual(t, "", actual.NextCursor)
 }
 
 func TestSVCGetAllByOrgID_DAOErr(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	orgID := "foo-org-id"
 
 	mockErr := errors.New("unit-test mock error")
-	md.On("GetAllByOrgID", ctx, orgID).Return([]user.User{}, mockErr)
+	var after *page.Cursor
+	md.On("GetAllByOrgID", ctx, orgID, false, after, 2).Return([]user.User{}, mockErr)
 
-	actual, err := s.GetAllByOrgID(ctx, orgID)
+	actual, err := s.GetAllByOrgID(ctx, orgID, false, page.Request{Limit: 1})
 
 	assert.Equal(t, mockErr, err)
-	assert.Equal(t, []user.User{}, actual)
+	assert.Equal(t, user.UserPage{}, actual)
+}
+
+func TestSVCGetAllByOrgID_OtherOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	_, err := s.GetAllByOrgID(ctx, "other-org-id", false, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetAllByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestSVCSave_NoID(t *testing.T) {
-	s, ms, md, _, mt, mi := initSVC()
+	s, ms, md, ma, _, mt, mi := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		OrgID:     "foo-org-id",
 		Name:      "foo-name",
@@ -173,7 +488,7 @@ func TestSVCSave_NoID(t *testing.T) {
 		Version:   99,
 	}
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
 
 	now := time.UnixMilli(200)
 	mt.On("Now").Return(now)
@@ -194,18 +509,20 @@ func TestSVCSave_NoID(t *testing.T) {
 	}
 	var expectedTX *sqlx.Tx
 	md.On("Create", ctx, expectedTX, expectedUser).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityUser, id, nil, expectedUser).Return(nil)
 
 	actual, err := s.Save(ctx, u)
 
 	assert.Nil(t, err)
 	assert.Equal(t, expectedUser, actual)
+	ma.AssertExpectations(t)
 }
 
 func TestSVCSave_NoID_OrgNotFound(t *testing.T) {
-	s, ms, _, _, _, _ := initSVC()
+	s, ms, _, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		OrgID: "foo-org-id",
 		Name:  "foo-name",
@@ -213,7 +530,7 @@ func TestSVCSave_NoID_OrgNotFound(t *testing.T) {
 	}
 
 	mockErr := errors.New("unit-test org not found")
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{}, mockErr)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{}, mockErr)
 
 	actual, err := s.Save(ctx, u)
 
@@ -222,17 +539,17 @@ func TestSVCSave_NoID_OrgNotFound(t *testing.T) {
 }
 
 func TestSVCSave_NoID_CannotAssociateSysOrg(t *testing.T) {
-	s, ms, _, _, _, _ := initSVC()
+	s, ms, _, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		OrgID: "foo-org-id",
 		Name:  "foo-name",
 		Email: "foo@bar.com",
 	}
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID, IsSystem: true}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID, IsSystem: true}, nil)
 
 	actual, err := s.Save(ctx, u)
 
@@ -247,17 +564,17 @@ func TestSVCSave_NoID_CannotAssociateSysOrg(t *testing.T) {
 }
 
 func TestSVCSave_NoID_DAOErr(t *testing.T) {
-	s, ms, md, _, mt, mi := initSVC()
+	s, ms, md, _, _, mt, mi := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		OrgID: "foo-org-id",
 		Name:  "foo-name",
 		Email: "foo@bar.com",
 	}
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
 
 	now := time.UnixMilli(100)
 	mt.On("Now").Return(now)
@@ -276,10 +593,10 @@ func TestSVCSave_NoID_DAOErr(t *testing.T) {
 }
 
 func TestSVCSave_ID(t *testing.T) {
-	s, ms, md, _, mt, _ := initSVC()
+	s, ms, md, ma, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		ID:        "foo-id",
 		OrgID:     "foo-org-id",
@@ -290,9 +607,10 @@ func TestSVCSave_ID(t *testing.T) {
 		Version:   1,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID}, nil)
+	userInDB := user.User{ID: u.ID, OrgID: u.OrgID, Name: "old-name", Email: u.Email, Version: u.Version}
+	md.On("GetByID", ctx, u.ID, false).Return(userInDB, nil)
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
 
 	now := time.UnixMilli(200)
 	mt.On("Now").Return(now)
@@ -308,6 +626,43 @@ func TestSVCSave_ID(t *testing.T) {
 	}
 	var expectedTX *sqlx.Tx
 	md.On("Update", ctx, expectedTX, expectedUser).Return(expectedUser, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, u.ID, userInDB, expectedUser).Return(nil)
+
+	actual, err := s.Save(ctx, u)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedUser, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCSave_ID_UnchangedInvalidEmail(t *testing.T) {
+	s, ms, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	// saved before emails were validated
+	u := user.User{
+		ID:      "foo-id",
+		OrgID:   "foo-org-id",
+		Name:    "foo-name",
+		Email:   "not-an-email",
+		Version: 1,
+	}
+
+	userInDB := user.User{ID: u.ID, OrgID: u.OrgID, Name: "old-name", Email: u.Email, Version: u.Version}
+	md.On("GetByID", ctx, u.ID, false).Return(userInDB, nil)
+
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+
+	expectedUser := u
+	expectedUser.UpdatedAt = now
+	expectedUser.UpdatedBy = loggedInUserID
+	var expectedTX *sqlx.Tx
+	md.On("Update", ctx, expectedTX, expectedUser).Return(expectedUser, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, u.ID, userInDB, expectedUser).Return(nil)
 
 	actual, err := s.Save(ctx, u)
 
@@ -315,11 +670,34 @@ func TestSVCSave_ID(t *testing.T) {
 	assert.Equal(t, expectedUser, actual)
 }
 
+func TestSVCSave_ID_ValidationErr(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	u := user.User{
+		ID:      "foo-id",
+		OrgID:   "foo-org-id",
+		Name:    strings.Repeat("a", 101),
+		Email:   "not-an-email",
+		Version: 1,
+	}
+
+	userInDB := user.User{ID: u.ID, OrgID: u.OrgID, Name: "old-name", Email: u.Email, Version: u.Version}
+	md.On("GetByID", ctx, u.ID, false).Return(userInDB, nil)
+
+	_, err := s.Save(ctx, u)
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "name", Rule: "max", Message: "name must be at most 100 characters"},
+	}}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestSVCSave_ID_OrgNotFound(t *testing.T) {
-	s, ms, md, _, _, _ := initSVC()
+	s, ms, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		ID:        "foo-id",
 		OrgID:     "foo-org-id",
@@ -330,10 +708,10 @@ func TestSVCSave_ID_OrgNotFound(t *testing.T) {
 		Version:   1,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID}, nil)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID}, nil)
 
 	mockErr := errors.New("unit-test org not found")
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{}, mockErr)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{}, mockErr)
 
 	actual, err := s.Save(ctx, u)
 
@@ -342,10 +720,10 @@ func TestSVCSave_ID_OrgNotFound(t *testing.T) {
 }
 
 func TestSVCSave_ID_CannotAssociateSysOrg(t *testing.T) {
-	s, ms, md, _, _, _ := initSVC()
+	s, ms, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		ID:        "foo-id",
 		OrgID:     "foo-org-id",
@@ -356,9 +734,9 @@ func TestSVCSave_ID_CannotAssociateSysOrg(t *testing.T) {
 		Version:   1,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID}, nil)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID}, nil)
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID, IsSystem: true}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID, IsSystem: true}, nil)
 
 	actual, err := s.Save(ctx, u)
 
@@ -373,10 +751,10 @@ func TestSVCSave_ID_CannotAssociateSysOrg(t *testing.T) {
 }
 
 func TestSVCSave_ID_CannotModifySysUser(t *testing.T) {
-	s, ms, md, _, _, _ := initSVC()
+	s, ms, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		ID:        "foo-id",
 		OrgID:     "foo-org-id",
@@ -387,9 +765,9 @@ func TestSVCSave_ID_CannotModifySysUser(t *testing.T) {
 		Version:   1,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID, IsSystem: true}, nil)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, IsSystem: true}, nil)
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
 
 	actual, err := s.Save(ctx, u)
 
@@ -403,10 +781,10 @@ func TestSVCSave_ID_CannotModifySysUser(t *testing.T) {
 }
 
 func TestSVCSave_ID_UserNotFound(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		ID:        "foo-id",
 		OrgID:     "foo-org-id",
@@ -418,7 +796,7 @@ func TestSVCSave_ID_UserNotFound(t *testing.T) {
 	}
 
 	mockErr := ErrNotFound{ID: u.ID}
-	md.On("GetByID", ctx, u.ID).Return(user.User{}, mockErr)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{}, mockErr)
 
 	actual, err := s.Save(ctx, u)
 
@@ -427,10 +805,10 @@ func TestSVCSave_ID_UserNotFound(t *testing.T) {
 }
 
 func TestSVCSave_ID_DAOUpdateErr(t *testing.T) {
-	s, ms, md, _, mt, _ := initSVC()
+	s, ms, md, _, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.User{
 		ID:        "foo-id",
 		OrgID:     "foo-org-id",
@@ -441,9 +819,9 @@ func TestSVCSave_ID_DAOUpdateErr(t *testing.T) {
 		Version:   1,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID}, nil)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID}, nil)
 
-	ms.On("GetByID", ctx, u.OrgID).Return(org.Org{ID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
 
 	now := time.UnixMilli(200)
 	mt.On("Now").Return(now)
@@ -458,8 +836,143 @@ func TestSVCSave_ID_DAOUpdateErr(t *testing.T) {
 	assert.Equal(t, user.User{}, actual)
 }
 
+func TestSVCSave_NoID_OrgAdminOtherOrgForbidden(t *testing.T) {
+	s, ms, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	u := user.User{OrgID: "other-org-id", Name: "foo-name", Email: "foo@bar.com"}
+
+	_, err := s.Save(ctx, u)
+
+	assertForbidden(t, err)
+	ms.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSave_NoID_MemberForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+	u := user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"}
+
+	_, err := s.Save(ctx, u)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSave_ID_OrgAdminOwnOrg(t *testing.T) {
+	s, ms, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	u := user.User{
+		ID:      "foo-id",
+		OrgID:   "foo-org-id",
+		Name:    "foo-name",
+		Email:   "foo@bar.com",
+		Version: 1,
+	}
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"org_id": u.OrgID, "role": authz.RoleOrgAdmin})
+
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, OrgID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+
+	expectedUser := u
+	expectedUser.UpdatedAt = now
+	expectedUser.UpdatedBy = loggedInUserID
+	var expectedTX *sqlx.Tx
+	md.On("Update", ctx, expectedTX, expectedUser).Return(expectedUser, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, u.ID, user.User{ID: u.ID, OrgID: u.OrgID}, expectedUser).Return(nil)
+
+	actual, err := s.Save(ctx, u)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedUser, actual)
+}
+
+func TestSVCSave_ID_OrgAdminOfOtherOrg(t *testing.T) {
+	s, ms, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	u := user.User{
+		ID:      "foo-id",
+		OrgID:   "other-org-id",
+		Name:    "foo-name",
+		Email:   "foo@bar.com",
+		Version: 1,
+	}
+	ctx := authz.WithMemberships(
+		principalCTX(loggedInUserID, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember}),
+		map[string]string{u.OrgID: authz.RoleOrgAdmin},
+	)
+
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, OrgID: u.OrgID}, nil)
+	ms.On("GetByID", ctx, u.OrgID, false).Return(org.Org{ID: u.OrgID}, nil)
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+
+	expectedUser := u
+	expectedUser.UpdatedAt = now
+	expectedUser.UpdatedBy = loggedInUserID
+	var expectedTX *sqlx.Tx
+	md.On("Update", ctx, expectedTX, expectedUser).Return(expectedUser, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, u.ID, user.User{ID: u.ID, OrgID: u.OrgID}, expectedUser).Return(nil)
+
+	actual, err := s.Save(ctx, u)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedUser, actual)
+}
+
+func TestSVCSave_ID_MemberOfOtherOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	u := user.User{
+		ID:      "foo-id",
+		OrgID:   "other-org-id",
+		Name:    "foo-name",
+		Email:   "foo@bar.com",
+		Version: 1,
+	}
+	ctx := authz.WithMemberships(
+		principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin}),
+		map[string]string{u.OrgID: authz.RoleMember},
+	)
+
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, OrgID: u.OrgID}, nil)
+
+	_, err := s.Save(ctx, u)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSave_ID_OrgAdminMoveToOtherOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	u := user.User{
+		ID:      "foo-id",
+		OrgID:   "other-org-id",
+		Name:    "foo-name",
+		Email:   "foo@bar.com",
+		Version: 1,
+	}
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, OrgID: "foo-org-id"}, nil)
+
+	_, err := s.Save(ctx, u)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestSVCSave_ErrIfNoAuditInfo(t *testing.T) {
-	s, _, _, _, _, _ := initSVC()
+	s, _, _, _, _, _, _ := initSVC()
 
 	ctx := context.Background()
 	u := user.User{
@@ -480,73 +993,101 @@ func TestSVCSave_ErrIfNoAuditInfo(t *testing.T) {
 }
 
 func TestSVCDelete(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, ma, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.DeleteUser{
 		ID:      "foo-id",
 		Version: 2,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID}, nil)
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID}, nil)
 
 	var expectedTX *sqlx.Tx
-	md.On("Delete", ctx, expectedTX, u).Return(nil)
+	md.On("Delete", ctx, expectedTX, user.User{ID: u.ID, Version: u.Version, DeletedAt: &now, DeletedBy: loggedInUserID}).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityUser, u.ID, user.User{ID: u.ID}, nil).Return(nil)
 
 	err := s.Delete(ctx, u)
 	assert.Nil(t, err)
+	ma.AssertExpectations(t)
 }
 
-func TestSVCDelete_UserNotFound(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+func TestSVCDelete_AuditErr(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.DeleteUser{
 		ID:      "foo-id",
 		Version: 2,
 	}
 
-	mockErr := ErrNotFound{ID: u.ID}
-	md.On("GetByID", ctx, u.ID).Return(user.User{}, mockErr)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID}, nil)
+
+	mt.On("Now").Return(time.UnixMilli(200))
+
+	var expectedTX *sqlx.Tx
+	mockErr := errors.New("unit-test mock error")
+	md.On("Delete", ctx, expectedTX, mock.Anything).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityUser, u.ID, mock.Anything, nil).Return(mockErr)
 
 	err := s.Delete(ctx, u)
 	assert.Equal(t, mockErr, err)
 }
 
-func TestSVCDelete_CannotModifySysUser(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+func TestSVCDelete_UserNotFound(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.DeleteUser{
 		ID:      "foo-id",
 		Version: 2,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID, IsSystem: true}, nil)
+	mockErr := ErrNotFound{ID: u.ID}
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{}, mockErr)
 
 	err := s.Delete(ctx, u)
-	assert.NotNil(t, err)
-	var sysUserErr ErrCannotModifySysUser
-	assert.True(t, errors.As(err, &sysUserErr))
-	assert.Contains(t, err.Error(), "Cannot modify")
-	assert.Contains(t, err.Error(), "system user")
-	assert.Contains(t, err.Error(), u.ID)
+	assert.Equal(t, mockErr, err)
+}
+
+func TestSVCDelete_CannotModifySysUser(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	u := user.DeleteUser{
+		ID:      "foo-id",
+		Version: 2,
+	}
+
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, IsSystem: true}, nil)
+
+	err := s.Delete(ctx, u)
+	assert.NotNil(t, err)
+	var sysUserErr ErrCannotModifySysUser
+	assert.True(t, errors.As(err, &sysUserErr))
+	assert.Contains(t, err.Error(), "Cannot modify")
+	assert.Contains(t, err.Error(), "system user")
+	assert.Contains(t, err.Error(), u.ID)
 }
 
 func TestSVCDelete_DAOErr(t *testing.T) {
-	s, _, md, _, _, _ := initSVC()
+	s, _, md, _, _, mt, _ := initSVC()
 
 	loggedInUserID := "logged-in-user-id"
-	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyUserID{}, loggedInUserID)
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
 	u := user.DeleteUser{
 		ID:      "foo-id",
 		Version: 2,
 	}
 
-	md.On("GetByID", ctx, u.ID).Return(user.User{ID: u.ID}, nil)
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
 
 	var expectedTX *sqlx.Tx
 	mockErr := errors.New("unit-test mock error")
@@ -556,23 +1097,437 @@ func TestSVCDelete_DAOErr(t *testing.T) {
 	assert.Equal(t, mockErr, err)
 }
 
-func (d *mockOrgSVC) GetByID(ctx context.Context, id string) (org.Org, error) {
-	args := d.Called(ctx, id)
+func TestSVCDelete_MemberForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	u := user.DeleteUser{ID: "foo-id", Version: 2}
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	md.On("GetByID", ctx, u.ID, false).Return(user.User{ID: u.ID, OrgID: "foo-org-id"}, nil)
+
+	err := s.Delete(ctx, u)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetByID_IncludeDeleted_OrgAdmin(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	deletedAt := time.UnixMilli(100).UTC()
+
+	mockRes := user.User{ID: id, OrgID: "foo-org-id", DeletedAt: &deletedAt, DeletedBy: "deleter-id"}
+	md.On("GetByID", ctx, id, true).Return(mockRes, nil)
+
+	actual, err := s.GetByID(ctx, id, true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCGetByID_IncludeDeleted_MemberForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	md.On("GetByID", ctx, id, true).Return(user.User{ID: id, OrgID: "foo-org-id"}, nil)
+
+	actual, err := s.GetByID(ctx, id, true)
+
+	assertForbidden(t, err)
+	assert.Equal(t, user.User{}, actual)
+}
+
+func TestSVCGetAll_IncludeDeleted(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockRes := []user.User{{ID: "foo-id"}}
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{}, true, after, 2).Return(mockRes, nil)
+
+	actual, err := s.GetAll(ctx, user.Query{}, true, page.Request{Limit: 1})
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.UserPage{Users: mockRes}, actual)
+}
+
+func TestSVCGetAll_IncludeDeleted_MemberForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	_, err := s.GetAll(ctx, user.Query{}, true, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCGetAllByOrgID_IncludeDeleted_MemberForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	orgID := "foo-org-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": orgID, "role": authz.RoleMember})
+
+	_, err := s.GetAllByOrgID(ctx, orgID, true, page.Request{Limit: 1})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetAllByOrgID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCDelete_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	err := s.Delete(context.Background(), user.DeleteUser{ID: "foo-id", Version: 2})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "user not logged in", err.Error())
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore(t *testing.T) {
+	s, ms, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	u := user.RestoreUser{ID: "foo-id", Version: 3}
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	deletedAt := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	userInDB := user.User{ID: u.ID, OrgID: "foo-org-id", Version: u.Version, DeletedAt: &deletedAt, DeletedBy: "deleter-id"}
+	restored := userInDB
+	restored.UpdatedAt = now
+	restored.UpdatedBy = loggedInUserID
+	mockRes := restored
+	mockRes.Version = 4
+	mockRes.DeletedAt = nil
+	mockRes.DeletedBy = ""
+	md.On("GetByID", ctx, u.ID, true).Return(userInDB, nil)
+	ms.On("GetByID", ctx, userInDB.OrgID, false).Return(org.Org{ID: userInDB.OrgID}, nil)
+	md.On("Restore", ctx, expectedTX, restored).Return(mockRes, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionRestore, audit.EntityUser, u.ID, userInDB, mockRes).Return(nil)
+
+	actual, err := s.Restore(ctx, u)
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCRestore_NotDeleted(t *testing.T) {
+	s, ms, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	u := user.RestoreUser{ID: "foo-id", Version: 3}
+
+	userInDB := user.User{ID: u.ID, OrgID: "foo-org-id", Version: u.Version}
+	md.On("GetByID", ctx, u.ID, true).Return(userInDB, nil)
+
+	actual, err := s.Restore(ctx, u)
+
+	assert.Nil(t, err)
+	assert.Equal(t, userInDB, actual)
+	ms.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore_OrgDeleted(t *testing.T) {
+	s, ms, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	u := user.RestoreUser{ID: "foo-id", Version: 3}
+
+	deletedAt := time.UnixMilli(200).UTC()
+	userInDB := user.User{ID: u.ID, OrgID: "foo-org-id", Version: u.Version, DeletedAt: &deletedAt}
+	mockErr := errors.New("unit-test mock error")
+	md.On("GetByID", ctx, u.ID, true).Return(userInDB, nil)
+	ms.On("GetByID", ctx, userInDB.OrgID, false).Return(org.Org{}, mockErr)
+
+	_, err := s.Restore(ctx, u)
+
+	assert.Equal(t, mockErr, err)
+	md.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore_DAOErr(t *testing.T) {
+	s, ms, md, ma, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	u := user.RestoreUser{ID: "foo-id", Version: 3}
+
+	var expectedTX *sqlx.Tx
+	deletedAt := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(time.UnixMilli(300))
+	mockErr := ErrOptimisticLock{ID: u.ID, Version: u.Version}
+	md.On("GetByID", ctx, u.ID, true).Return(user.User{ID: u.ID, OrgID: "foo-org-id", DeletedAt: &deletedAt}, nil)
+	ms.On("GetByID", ctx, "foo-org-id", false).Return(org.Org{ID: "foo-org-id"}, nil)
+	md.On("Restore", ctx, expectedTX, mock.Anything).Return(user.User{}, mockErr)
+
+	actual, err := s.Restore(ctx, u)
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, user.User{}, actual)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRestore_MemberForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	u := user.RestoreUser{ID: "foo-id", Version: 3}
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	deletedAt := time.UnixMilli(200).UTC()
+	md.On("GetByID", ctx, u.ID, true).Return(user.User{ID: u.ID, OrgID: "foo-org-id", DeletedAt: &deletedAt}, nil)
+
+	_, err := s.Restore(ctx, u)
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func mergePatch(t *testing.T, body string) patch.Patch {
+	p, err := patch.Parse(pkgpatch.ContentTypeMerge, []byte(body))
+	assert.Nil(t, err)
+	return p
+}
+
+func patchUserInDB() user.User {
+	return user.User{
+		ID:        "foo-id",
+		OrgID:     "foo-org-id",
+		Name:      "foo-name",
+		Email:     "foo@bar.com",
+		IsActive:  true,
+		CreatedAt: time.UnixMilli(100).UTC(),
+		CreatedBy: "creator-id",
+		UpdatedAt: time.UnixMilli(100).UTC(),
+		UpdatedBy: "updater-id",
+		Version:   3,
+	}
+}
+
+func TestSVCPatch(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+
+	userInDB := patchUserInDB()
+	expectedUser := userInDB
+	expectedUser.IsActive = false
+	expectedUser.UpdatedAt = now
+	expectedUser.UpdatedBy = loggedInUserID
+	mockRes := expectedUser
+	mockRes.Version = 4
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+	md.On("Update", ctx, expectedTX, expectedUser).Return(mockRes, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, userInDB.ID, userInDB, mockRes).Return(nil)
+
+	actual, err := s.Patch(ctx, userInDB.ID, 0, mergePatch(t, `{"is_active":false}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCPatch_OrgAdminOwnOrg(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	mt.On("Now").Return(time.UnixMilli(200).UTC())
+
+	userInDB := patchUserInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+	md.On("Update", ctx, expectedTX, mock.Anything).Return(userInDB, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, userInDB.ID, userInDB, userInDB).Return(nil)
+
+	_, err := s.Patch(ctx, userInDB.ID, userInDB.Version, mergePatch(t, `{"is_admin":true}`))
+
+	assert.Nil(t, err)
+}
+
+func TestSVCPatch_OtherOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "bar-org-id", "role": authz.RoleOrgAdmin})
+
+	userInDB := patchUserInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+
+	_, err := s.Patch(ctx, userInDB.ID, 0, mergePatch(t, `{"is_active":false}`))
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_IfMatchOptimisticLockErr(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	userInDB := patchUserInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+
+	_, err := s.Patch(ctx, userInDB.ID, 2, mergePatch(t, `{"is_active":false}`))
+
+	assert.Equal(t, ErrOptimisticLock{ID: userInDB.ID, Version: 2}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_VersionOptimisticLockErr(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	userInDB := patchUserInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+	changes, err := patch.Parse(pkgpatch.ContentTypeJSON, []byte(`[{"op":"replace","path":"/version","value":2},{"op":"replace","path":"/name","value":"bar-name"}]`))
+	assert.Nil(t, err)
+
+	_, err = s.Patch(ctx, userInDB.ID, 0, changes)
+
+	assert.Equal(t, ErrOptimisticLock{ID: userInDB.ID, Version: 2}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_ReadOnlyErr(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	userInDB := patchUserInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+
+	_, err := s.Patch(ctx, userInDB.ID, 0, mergePatch(t, `{"email":"bar@bar.com","org_id":"bar-org-id"}`))
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		validate.ReadOnly("email"),
+		validate.ReadOnly("org_id"),
+	}}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_ValidationErr(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	userInDB := patchUserInDB()
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+
+	_, err := s.Patch(ctx, userInDB.ID, 0, mergePatch(t, `{"name":"`+strings.Repeat("a", 101)+`"}`))
+
+	assert.Equal(t, validate.ErrInvalid{Violations: []problem.Violation{
+		{Field: "name", Rule: "max", Message: "name must be at most 100 characters"},
+	}}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_UnchangedInvalidEmail(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mt.On("Now").Return(time.UnixMilli(200).UTC())
+
+	// saved before emails were validated
+	userInDB := patchUserInDB()
+	userInDB.Email = "not-an-email"
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, userInDB.ID).Return(userInDB, nil)
+	md.On("Update", ctx, expectedTX, mock.MatchedBy(func(u user.User) bool {
+		return u.Name == "bar-name" && u.Email == userInDB.Email
+	})).Return(userInDB, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, userInDB.ID, userInDB, userInDB).Return(nil)
+
+	_, err := s.Patch(ctx, userInDB.ID, 0, mergePatch(t, `{"name":"bar-name"}`))
+
+	assert.Nil(t, err)
+	md.AssertExpectations(t)
+}
+
+func TestSVCPatch_NotFoundErr(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockErr := ErrNotFound{ID: "foo-id"}
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, "foo-id").Return(user.User{}, mockErr)
+
+	actual, err := s.Patch(ctx, "foo-id", 0, mergePatch(t, `{"is_active":false}`))
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, user.User{}, actual)
+}
+
+func TestSVCPatch_SystemUserNotAllowed(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	var expectedTX *sqlx.Tx
+	md.On("GetByIDForUpdate", ctx, expectedTX, "foo-id").Return(user.User{ID: "foo-id", IsSystem: true}, nil)
+
+	_, err := s.Patch(ctx, "foo-id", 0, mergePatch(t, `{"is_active":false}`))
+
+	assert.Equal(t, ErrCannotModifySysUser{ID: "foo-id"}, err)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCPatch_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	_, err := s.Patch(context.Background(), "foo-id", 0, mergePatch(t, `{"is_active":false}`))
+
+	assert.NotNil(t, err)
+	md.AssertNotCalled(t, "GetByIDForUpdate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (d *mockOrgSVC) GetByID(ctx context.Context, id string, includeDeleted bool) (org.Org, error) {
+	args := d.Called(ctx, id, includeDeleted)
 	return args.Get(0).(org.Org), args.Error(1)
 }
 
-func (d *mockDAO) GetByID(ctx context.Context, id string) (user.User, error) {
-	args := d.Called(ctx, id)
+func (d *mockDAO) GetByID(ctx context.Context, id string, includeDeleted bool) (user.User, error) {
+	args := d.Called(ctx, id, includeDeleted)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (d *mockDAO) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, id string) (user.User, error) {
+	args := d.Called(ctx, tx, id)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (d *mockDAO) GetByEmail(ctx context.Context, tx *sqlx.Tx, email string) (user.User, error) {
+	args := d.Called(ctx, tx, email)
 	return args.Get(0).(user.User), args.Error(1)
 }
 
-func (d *mockDAO) GetAll(ctx context.Context) ([]user.User, error) {
-	args := d.Called(ctx)
+func (d *mockDAO) GetAll(ctx context.Context, q user.Query, includeDeleted bool, after *page.Cursor, limit int) ([]user.User, error) {
+	args := d.Called(ctx, q, includeDeleted, after, limit)
 	return args.Get(0).([]user.User), args.Error(1)
 }
 
-func (d *mockDAO) GetAllByOrgID(ctx context.Context, orgID string) ([]user.User, error) {
-	args := d.Called(ctx, orgID)
+func (d *mockDAO) GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, after *page.Cursor, limit int) ([]user.User, error) {
+	args := d.Called(ctx, orgID, includeDeleted, after, limit)
 	return args.Get(0).([]user.User), args.Error(1)
 }
 
@@ -581,18 +1536,66 @@ func (d *mockDAO) Create(ctx context.Context, tx *sqlx.Tx, u user.User) error {
 	return args.Error(0)
 }
 
+func (d *mockDAO) CreateBatch(ctx context.Context, tx *sqlx.Tx, users []user.User) error {
+	args := d.Called(ctx, tx, users)
+	return args.Error(0)
+}
+
 func (d *mockDAO) Update(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error) {
 	args := d.Called(ctx, tx, u)
 	return args.Get(0).(user.User), args.Error(1)
 }
 
-func (d *mockDAO) Delete(ctx context.Context, tx *sqlx.Tx, u user.DeleteUser) error {
+func (d *mockDAO) Delete(ctx context.Context, tx *sqlx.Tx, u user.User) error {
+	args := d.Called(ctx, tx, u)
+	return args.Error(0)
+}
+
+func (d *mockDAO) Restore(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error) {
+	args := d.Called(ctx, tx, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (d *mockDAO) Purge(ctx context.Context, before time.Time) (int64, error) {
+	args := d.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockAuditor) Record(ctx context.Context, tx *sqlx.Tx, action string, entityType string, entityID string, before any, after any) error {
+	args := m.Called(ctx, tx, action, entityType, entityID, before, after)
+	return args.Error(0)
+}
+
+func (d *mockDAO) SaveEmailChange(ctx context.Context, tx *sqlx.Tx, ec StoredEmailChange) error {
+	args := d.Called(ctx, tx, ec)
+	return args.Error(0)
+}
+
+func (d *mockDAO) GetEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) (StoredEmailChange, error) {
+	args := d.Called(ctx, tx, userID)
+	return args.Get(0).(StoredEmailChange), args.Error(1)
+}
+
+func (d *mockDAO) DeleteEmailChange(ctx context.Context, tx *sqlx.Tx, userID string) error {
+	args := d.Called(ctx, tx, userID)
+	return args.Error(0)
+}
+
+func (d *mockDAO) UpdateEmail(ctx context.Context, tx *sqlx.Tx, u user.User) (user.User, error) {
 	args := d.Called(ctx, tx, u)
+	return args.Get(0).(user.User), args.Error(1)
+}
+
+func (n *mockNotifier) Send(ctx context.Context, m notify.Message) error {
+	args := n.Called(ctx, m)
 	return args.Error(0)
 }
 
 func (m *mockTXManager) Do(ctx context.Context, tx *sqlx.Tx, f func(tx *sqlx.Tx) error) error {
-	return f(nil)
+	if tx == nil {
+		tx = m.tx
+	}
+	return f(tx)
 }
 
 func (t *mockTimer) Now() time.Time {
@@ -604,3 +1607,907 @@ func (t *mockIDGen) GenID() string {
 	args := t.Called()
 	return args.String(0)
 }
+
+func TestSVCCreateInOrg_JoinTX(t *testing.T) {
+	s, _, md, ma, _, mt, mi := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	o := org.Org{ID: "foo-org-id"}
+	u := user.User{
+		OrgID: "ignored",
+		Name:  "foo-name",
+		Email: "foo@bar.com",
+	}
+
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+
+	id := "foo-id"
+	mi.On("GenID").Return(id)
+
+	expectedUser := user.User{
+		ID:        id,
+		OrgID:     o.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		CreatedAt: now,
+		CreatedBy: loggedInUserID,
+		UpdatedAt: now,
+		UpdatedBy: loggedInUserID,
+		Version:   1,
+	}
+	tx := &sqlx.Tx{}
+	md.On("Create", ctx, tx, expectedUser).Return(nil)
+	ma.On("Record", ctx, tx, audit.ActionCreate, audit.EntityUser, id, nil, expectedUser).Return(nil)
+
+	actual, err := s.CreateInOrg(ctx, tx, o, u)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expectedUser, actual)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCCreateInOrg_CannotAssociateSysOrg(t *testing.T) {
+	s, _, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	o := org.Org{ID: "foo-org-id", IsSystem: true}
+
+	actual, err := s.CreateInOrg(ctx, nil, o, user.User{Name: "foo-name"})
+
+	var orgErr ErrCannotAssociateSysOrg
+	assert.True(t, errors.As(err, &orgErr))
+	assert.Equal(t, user.User{}, actual)
+}
+
+func TestSVCCreateInOrg_OrgAdminOtherOrgForbidden(t *testing.T) {
+	s, _, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+	o := org.Org{ID: "bar-org-id"}
+
+	_, err := s.CreateInOrg(ctx, nil, o, user.User{Name: "foo-name"})
+
+	assertForbidden(t, err)
+}
+
+func TestSVCCreateInOrg_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, _, _, _, _, _ := initSVC()
+
+	_, err := s.CreateInOrg(context.Background(), nil, org.Org{ID: "foo-org-id"}, user.User{})
+
+	assert.NotNil(t, err)
+}
+
+func TestSVCBatch_AllOrNothing(t *testing.T) {
+	s, ms, md, ma, mm, mt, mi := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	b := user.Batch{
+		Mode: user.BatchModeAllOrNothing,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpCreate, User: user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpDelete, User: user.User{ID: "bar-id", Version: 2}},
+			{Op: user.BatchOpCreate, User: user.User{OrgID: orgID, Name: "baz-name", Email: "baz@bar.com"}},
+		},
+	}
+
+	// the org is only read once for both creates
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil).Once()
+	now := time.UnixMilli(200).UTC()
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return("new-id")
+
+	mm.tx = new(sqlx.Tx)
+	expectedTX := mm.tx
+	userInDB := user.User{ID: "bar-id", OrgID: orgID}
+	md.On("GetByIDForUpdate", ctx, expectedTX, "bar-id").Return(userInDB, nil)
+	md.On("Delete", ctx, expectedTX, user.User{ID: "bar-id", OrgID: orgID, Version: 2, DeletedAt: &now, DeletedBy: loggedInUserID}).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionDelete, audit.EntityUser, "bar-id", userInDB, nil).Return(nil)
+
+	newUser := func(name string, email string) user.User {
+		return user.User{
+			ID:        "new-id",
+			OrgID:     orgID,
+			Name:      name,
+			Email:     email,
+			CreatedAt: now,
+			CreatedBy: loggedInUserID,
+			UpdatedAt: now,
+			UpdatedBy: loggedInUserID,
+			Version:   1,
+		}
+	}
+	created := []user.User{newUser("foo-name", "foo@bar.com"), newUser("baz-name", "baz@bar.com")}
+	md.On("CreateBatch", ctx, expectedTX, created[:1]).Return(nil)
+	md.On("CreateBatch", ctx, expectedTX, created[1:]).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityUser, "new-id", nil, created[0]).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityUser, "new-id", nil, created[1]).Return(nil)
+
+	actual, err := s.Batch(ctx, b)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{{User: created[0]}, {}, {User: created[1]}}, actual)
+	// the ops ran in the order they were sent, the delete read in the tx
+	var calls []string
+	for _, c := range md.Calls {
+		calls = append(calls, c.Method)
+	}
+	assert.Equal(t, []string{"CreateBatch", "GetByIDForUpdate", "Delete", "CreateBatch"}, calls)
+	ms.AssertExpectations(t)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCBatch_AllOrNothing_UpdateSeesEarlierUpdate(t *testing.T) {
+	s, ms, md, ma, mm, mt, _ := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	b := user.Batch{
+		Mode: user.BatchModeAllOrNothing,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpUpdate, User: user.User{ID: "bar-id", OrgID: orgID, Name: "bar-name", Email: "bar@baz.com", Version: 1}},
+			{Op: user.BatchOpUpdate, User: user.User{ID: "bar-id", OrgID: orgID, Name: "baz-name", Email: "bar@baz.com", Version: 2}},
+		},
+	}
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+	mm.tx = new(sqlx.Tx)
+	expectedTX := mm.tx
+	v1 := user.User{ID: "bar-id", OrgID: orgID, Name: "foo-name", Email: "bar@baz.com", Version: 1}
+	v2 := user.User{ID: "bar-id", OrgID: orgID, Name: "bar-name", Email: "bar@baz.com", Version: 2}
+	v3 := user.User{ID: "bar-id", OrgID: orgID, Name: "baz-name", Email: "bar@baz.com", Version: 3}
+	// the second read is in the tx so it gets what the first update wrote
+	md.On("GetByIDForUpdate", ctx, expectedTX, "bar-id").Return(v1, nil).Once()
+	md.On("GetByIDForUpdate", ctx, expectedTX, "bar-id").Return(v2, nil).Once()
+	md.On("Update", ctx, expectedTX, mock.MatchedBy(func(u user.User) bool { return u.Version == 1 })).Return(v2, nil)
+	md.On("Update", ctx, expectedTX, mock.MatchedBy(func(u user.User) bool { return u.Version == 2 })).Return(v3, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, "bar-id", v1, v2).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, "bar-id", v2, v3).Return(nil)
+
+	actual, err := s.Batch(ctx, b)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{{User: v2}, {User: v3}}, actual)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCBatch_AllOrNothing_DuplicateEmail(t *testing.T) {
+	s, ms, md, _, _, mt, mi := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	b := user.Batch{
+		Mode: user.BatchModeAllOrNothing,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpCreate, User: user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpCreate, User: user.User{OrgID: orgID, Name: "baz-name", Email: "baz@bar.com"}},
+		},
+	}
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
+	mi.On("GenID").Return("new-id")
+
+	mockErr := ErrEmailAlreadyInUse{Email: "baz@bar.com"}
+	md.On("CreateBatch", ctx, (*sqlx.Tx)(nil), mock.Anything).Return(mockErr)
+
+	actual, err := s.Batch(ctx, b)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{{Err: ErrBatchAborted{FailedIndex: 1}}, {Err: mockErr}}, actual)
+}
+
+func TestSVCBatch_AllOrNothing_UpdateErr(t *testing.T) {
+	s, ms, md, ma, _, mt, mi := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	b := user.Batch{
+		Mode: user.BatchModeAllOrNothing,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpCreate, User: user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpUpdate, User: user.User{ID: "bar-id", OrgID: orgID, Name: "bar-name", Email: "bar@baz.com", Version: 1}},
+			{Op: user.BatchOpDelete, User: user.User{ID: "baz-id", Version: 1}},
+		},
+	}
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
+	mi.On("GenID").Return("new-id")
+	// the create is inserted before the update runs, the rollback undoes it
+	md.On("CreateBatch", ctx, (*sqlx.Tx)(nil), mock.Anything).Return(nil)
+	ma.On("Record", ctx, (*sqlx.Tx)(nil), audit.ActionCreate, audit.EntityUser, "new-id", nil, mock.Anything).Return(nil)
+	mockErr := ErrNotFound{ID: "bar-id"}
+	md.On("GetByID", ctx, "bar-id", false).Return(user.User{}, mockErr)
+
+	actual, err := s.Batch(ctx, b)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []BatchResult{
+		{Err: ErrBatchAborted{FailedIndex: 1}},
+		{Err: mockErr},
+		{Err: ErrBatchAborted{FailedIndex: 1}},
+	}, actual)
+	md.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCBatch_BestEffort(t *testing.T) {
+	s, ms, md, ma, _, mt, mi := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	b := user.Batch{
+		Mode: user.BatchModeBestEffort,
+		Ops: []user.BatchOp{
+			{Op: user.BatchOpDelete, User: user.User{ID: "sys-id", Version: 1}},
+			{Op: user.BatchOpCreate, User: user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}},
+			{Op: user.BatchOpDelete, User: user.User{ID: "gone-id", Version: 1}},
+		},
+	}
+
+	md.On("GetByID", ctx, "sys-id", false).Return(user.User{ID: "sys-id", OrgID: orgID, IsSystem: true}, nil)
+	md.On("GetByID", ctx, "gone-id", false).Return(user.User{}, ErrNotFound{ID: "gone-id"})
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return("new-id")
+	created := user.User{
+		ID:        "new-id",
+		OrgID:     orgID,
+		Name:      "foo-name",
+		Email:     "foo@bar.com",
+		CreatedAt: now,
+		CreatedBy: loggedInUserID,
+		UpdatedAt: now,
+		UpdatedBy: loggedInUserID,
+		Version:   1,
+	}
+	var expectedTX *sqlx.Tx
+	md.On("Create", ctx, expectedTX, created).Return(nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityUser, "new-id", nil, created).Return(nil)
+
+	actual, err := s.Batch(ctx, b)
+
+	assert.Nil(t, err)
+	// deleting a user that's already gone isn't a failure
+	assert.Equal(t, []BatchResult{
+		{Err: ErrCannotModifySysUser{ID: "sys-id"}},
+		{User: created},
+		{},
+	}, actual)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCBatch_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, _, _, _, _, _ := initSVC()
+
+	actual, err := s.Batch(context.Background(), user.Batch{Mode: user.BatchModeBestEffort})
+
+	assert.NotNil(t, err)
+	assert.Nil(t, actual)
+}
+
+func TestSVCExport(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	mockRes := []user.User{
+		{ID: "foo-id", Email: "foo@bar.com"},
+		{ID: "bar-id", Email: "bar@baz.com"},
+	}
+	var after *page.Cursor
+	md.On("GetAll", ctx, user.Query{}, false, after, page.MaxLimit+1).Return(mockRes, nil)
+
+	var actual []user.User
+	for u, err := range s.Export(ctx) {
+		assert.Nil(t, err)
+		actual = append(actual, u)
+	}
+
+	assert.Equal(t, mockRes, actual)
+}
+
+func TestSVCExport_NoOrgForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"role": authz.RoleMember})
+
+	var errs []error
+	for _, err := range s.Export(ctx) {
+		errs = append(errs, err)
+	}
+
+	assert.Len(t, errs, 1)
+	assertForbidden(t, errs[0])
+	md.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// rowsOf yields each row with its error, the way the controller hands them to
+// Import.
+func rowsOf(rows ...mockRow) iter.Seq2[user.User, error] {
+	return func(yield func(user.User, error) bool) {
+		for _, r := range rows {
+			if !yield(r.User, r.Err) {
+				return
+			}
+		}
+	}
+}
+
+func TestSVCImport(t *testing.T) {
+	s, ms, md, ma, _, mt, mi := initSVC()
+
+	loggedInUserID := "logged-in-user-id"
+	ctx := principalCTX(loggedInUserID, jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	u := user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}
+	invalidRow := dataio.ErrInvalidRow{Reason: "email is required"}
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	ms.On("GetByID", ctx, "sys-org-id", false).Return(org.Org{ID: "sys-org-id", IsSystem: true}, nil)
+	now := time.UnixMilli(200)
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return("new-id")
+	created := user.User{
+		ID:        "new-id",
+		OrgID:     orgID,
+		Name:      "foo-name",
+		Email:     "foo@bar.com",
+		CreatedAt: now,
+		CreatedBy: loggedInUserID,
+		UpdatedAt: now,
+		UpdatedBy: loggedInUserID,
+		Version:   1,
+	}
+	var expectedTX *sqlx.Tx
+	md.On("Create", ctx, expectedTX, created).Return(nil).Once()
+	ma.On("Record", ctx, expectedTX, audit.ActionCreate, audit.EntityUser, "new-id", nil, created).Return(nil)
+
+	actual, err := s.Import(ctx, rowsOf(
+		mockRow{User: u},
+		mockRow{Err: invalidRow},
+		mockRow{User: u},
+		mockRow{User: user.User{OrgID: "sys-org-id", Name: "bar-name", Email: "bar@baz.com"}},
+	), false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResult{
+		Rows:     4,
+		Imported: 1,
+		Errors: []ImportRowError{
+			{Row: 2, Err: invalidRow},
+			{Row: 3, Err: ErrEmailAlreadyInUse{Email: "foo@bar.com"}},
+			{Row: 4, Err: ErrCannotAssociateSysOrg{OrgID: "sys-org-id"}},
+		},
+	}, actual)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCImport_DryRun(t *testing.T) {
+	s, ms, md, ma, _, mt, mi := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	u := user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
+	mi.On("GenID").Return("new-id")
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(nil)
+	ma.On("Record", ctx, mock.Anything, audit.ActionCreate, audit.EntityUser, "new-id", nil, mock.Anything).Return(nil)
+
+	actual, err := s.Import(ctx, rowsOf(mockRow{User: u}, mockRow{User: u}), true)
+
+	// the create is rolled back, but the 2nd row is still a duplicate
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResult{
+		Rows:     2,
+		Imported: 1,
+		Errors:   []ImportRowError{{Row: 2, Err: ErrEmailAlreadyInUse{Email: "foo@bar.com"}}},
+	}, actual)
+	md.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestSVCImport_DAOErr(t *testing.T) {
+	s, ms, md, _, _, mt, mi := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
+	mi.On("GenID").Return("new-id")
+	dupEmail := ErrEmailAlreadyInUse{Email: "foo@bar.com"}
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(dupEmail)
+
+	actual, err := s.Import(ctx, rowsOf(mockRow{User: user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}}), true)
+
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResult{Rows: 1, Errors: []ImportRowError{{Row: 1, Err: dupEmail}}}, actual)
+}
+
+func TestSVCImport_Malformed(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	malformed := dataio.ErrMalformed{Reason: "bare quote"}
+
+	actual, err := s.Import(ctx, rowsOf(mockRow{Err: malformed}), false)
+
+	assert.Equal(t, malformed, err)
+	assert.Equal(t, ImportResult{}, actual)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCImport_MalformedAfterGoodRows(t *testing.T) {
+	s, ms, md, ma, _, mt, mi := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+	orgID := "foo-org-id"
+	malformed := dataio.ErrMalformed{Reason: "bare quote"}
+
+	ms.On("GetByID", ctx, orgID, false).Return(org.Org{ID: orgID}, nil)
+	mt.On("Now").Return(time.UnixMilli(200))
+	mi.On("GenID").Return("new-id")
+	md.On("Create", ctx, mock.Anything, mock.Anything).Return(nil)
+	ma.On("Record", ctx, mock.Anything, audit.ActionCreate, audit.EntityUser, "new-id", nil, mock.Anything).Return(nil)
+
+	actual, err := s.Import(ctx, rowsOf(
+		mockRow{User: user.User{OrgID: orgID, Name: "foo-name", Email: "foo@bar.com"}},
+		mockRow{User: user.User{OrgID: orgID, Name: "bar-name", Email: "bar@bar.com"}},
+		mockRow{Err: malformed},
+		mockRow{User: user.User{OrgID: orgID, Name: "baz-name", Email: "baz@bar.com"}},
+	), false)
+
+	// the first 2 are already committed, so they're reported rather than lost
+	// in an error
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResult{
+		Rows:     2,
+		Imported: 2,
+		Stopped:  &ImportRowError{Row: 3, Err: malformed},
+	}, actual)
+	md.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestSVCImport_OrgAdminOtherOrgForbidden(t *testing.T) {
+	s, ms, _, _, _, _, _ := initSVC()
+
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	actual, err := s.Import(ctx, rowsOf(mockRow{User: user.User{OrgID: "other-org-id", Name: "foo-name", Email: "foo@bar.com"}}), false)
+
+	assert.Nil(t, err)
+	assert.Len(t, actual.Errors, 1)
+	assertForbidden(t, actual.Errors[0].Err)
+	ms.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCImport_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, _, _, _, _, _ := initSVC()
+
+	_, err := s.Import(context.Background(), rowsOf(), false)
+
+	assert.NotNil(t, err)
+}
+
+func TestSVCRequestEmailChange(t *testing.T) {
+	s, _, md, _, _, mt, _ := initSVC()
+	mn := notifierOf(s)
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id", Email: "foo@bar.com"}, nil)
+	var saved StoredEmailChange
+	md.On("SaveEmailChange", ctx, expectedTX, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(2).(StoredEmailChange)
+	}).Return(nil)
+	var sent notify.Message
+	mn.On("Send", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		sent = args.Get(1).(notify.Message)
+	}).Return(nil)
+
+	actual, err := s.RequestEmailChange(ctx, id, user.EmailChange{Email: "new@bar.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, user.PendingEmailChange{UserID: id, Email: "new@bar.com", ExpiresAt: now.Add(emailChangeTTL)}, actual)
+	assert.Equal(t, id, saved.UserID)
+	assert.Equal(t, "new@bar.com", saved.NewEmail)
+	assert.Equal(t, now.Add(emailChangeTTL), saved.ExpiresAt)
+	assert.Equal(t, now, saved.CreatedAt)
+	assert.Equal(t, id, saved.CreatedBy)
+	assert.Equal(t, "new@bar.com", sent.To)
+	// only the hash of the token that was sent is stored
+	token := sent.Body[strings.LastIndex(sent.Body, `"token": "`)+len(`"token": "`) : len(sent.Body)-len(`"}`)]
+	assert.Equal(t, hashEmailChangeToken(token), saved.TokenHash)
+	assert.NotContains(t, sent.Body, saved.TokenHash)
+}
+
+func TestSVCRequestEmailChange_OrgAdmin(t *testing.T) {
+	s, _, md, _, _, mt, _ := initSVC()
+	mn := notifierOf(s)
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleOrgAdmin})
+
+	var expectedTX *sqlx.Tx
+	mt.On("Now").Return(time.UnixMilli(300).UTC())
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id", Email: "foo@bar.com"}, nil)
+	md.On("SaveEmailChange", ctx, expectedTX, mock.Anything).Return(nil)
+	mn.On("Send", ctx, mock.Anything).Return(nil)
+
+	_, err := s.RequestEmailChange(ctx, id, user.EmailChange{Email: "new@bar.com"})
+
+	assert.Nil(t, err)
+	mn.AssertExpectations(t)
+}
+
+func TestSVCRequestEmailChange_OtherUserForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id", Email: "foo@bar.com"}, nil)
+
+	_, err := s.RequestEmailChange(ctx, id, user.EmailChange{Email: "new@bar.com"})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "SaveEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRequestEmailChange_SysUser(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"admin": true})
+
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, IsSystem: true}, nil)
+
+	_, err := s.RequestEmailChange(ctx, id, user.EmailChange{Email: "new@bar.com"})
+
+	var expected ErrCannotModifySysUser
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "SaveEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRequestEmailChange_Unchanged(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id", Email: "foo@bar.com"}, nil)
+
+	_, err := s.RequestEmailChange(ctx, id, user.EmailChange{Email: "foo@bar.com"})
+
+	var expected ErrEmailUnchanged
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "SaveEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCRequestEmailChange_NotifierErr(t *testing.T) {
+	s, _, md, _, _, mt, _ := initSVC()
+	mn := notifierOf(s)
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	mt.On("Now").Return(time.UnixMilli(300).UTC())
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id", Email: "foo@bar.com"}, nil)
+	md.On("SaveEmailChange", ctx, expectedTX, mock.Anything).Return(nil)
+	mockErr := errors.New("unit-test mock error")
+	mn.On("Send", ctx, mock.Anything).Return(mockErr)
+
+	actual, err := s.RequestEmailChange(ctx, id, user.EmailChange{Email: "new@bar.com"})
+
+	assert.Equal(t, mockErr, err)
+	assert.Equal(t, user.PendingEmailChange{}, actual)
+}
+
+func TestSVCRequestEmailChange_ErrIfNoAuditInfo(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	_, err := s.RequestEmailChange(context.Background(), "foo-id", user.EmailChange{Email: "new@bar.com"})
+
+	assert.NotNil(t, err)
+	md.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCConfirmEmailChange(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	userInDB := user.User{ID: id, OrgID: "foo-org-id", Email: "foo@bar.com", Version: 3}
+	md.On("GetByID", ctx, id, false).Return(userInDB, nil)
+	md.On("GetEmailChange", ctx, expectedTX, id).Return(StoredEmailChange{
+		UserID:    id,
+		NewEmail:  "new@bar.com",
+		TokenHash: hashEmailChangeToken("foo-token"),
+		ExpiresAt: now.Add(time.Minute),
+	}, nil)
+	md.On("DeleteEmailChange", ctx, expectedTX, id).Return(nil)
+	changed := userInDB
+	changed.Email = "new@bar.com"
+	changed.UpdatedAt = now
+	changed.UpdatedBy = id
+	mockRes := changed
+	mockRes.Version = 4
+	md.On("UpdateEmail", ctx, expectedTX, changed).Return(mockRes, nil)
+	ma.On("Record", ctx, expectedTX, audit.ActionUpdate, audit.EntityUser, id, userInDB, mockRes).Return(nil)
+
+	actual, err := s.ConfirmEmailChange(ctx, id, user.ConfirmEmailChange{Token: "foo-token"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCConfirmEmailChange_InvalidToken(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id"}, nil)
+	md.On("GetEmailChange", ctx, expectedTX, id).Return(StoredEmailChange{
+		UserID:    id,
+		NewEmail:  "new@bar.com",
+		TokenHash: hashEmailChangeToken("foo-token"),
+		ExpiresAt: time.UnixMilli(400).UTC(),
+	}, nil)
+
+	_, err := s.ConfirmEmailChange(ctx, id, user.ConfirmEmailChange{Token: "bar-token"})
+
+	var expected ErrInvalidEmailChangeToken
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "DeleteEmailChange", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "UpdateEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCConfirmEmailChange_Expired(t *testing.T) {
+	s, _, md, _, _, mt, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id"}, nil)
+	md.On("GetEmailChange", ctx, expectedTX, id).Return(StoredEmailChange{
+		UserID:    id,
+		NewEmail:  "new@bar.com",
+		TokenHash: hashEmailChangeToken("foo-token"),
+		ExpiresAt: now,
+	}, nil)
+
+	_, err := s.ConfirmEmailChange(ctx, id, user.ConfirmEmailChange{Token: "foo-token"})
+
+	var expected ErrEmailChangeExpired
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "UpdateEmail", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCConfirmEmailChange_NoPending(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id"}, nil)
+	md.On("GetEmailChange", ctx, expectedTX, id).Return(StoredEmailChange{}, ErrNoPendingEmailChange{UserID: id})
+
+	_, err := s.ConfirmEmailChange(ctx, id, user.ConfirmEmailChange{Token: "foo-token"})
+
+	var expected ErrNoPendingEmailChange
+	assert.True(t, errors.As(err, &expected))
+}
+
+func TestSVCConfirmEmailChange_EmailInUse(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX(id, jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id"}, nil)
+	md.On("GetEmailChange", ctx, expectedTX, id).Return(StoredEmailChange{
+		UserID:    id,
+		NewEmail:  "new@bar.com",
+		TokenHash: hashEmailChangeToken("foo-token"),
+		ExpiresAt: now.Add(time.Minute),
+	}, nil)
+	md.On("DeleteEmailChange", ctx, expectedTX, id).Return(nil)
+	md.On("UpdateEmail", ctx, expectedTX, mock.Anything).Return(user.User{}, ErrEmailAlreadyInUse{Email: "new@bar.com"})
+
+	actual, err := s.ConfirmEmailChange(ctx, id, user.ConfirmEmailChange{Token: "foo-token"})
+
+	var expected ErrEmailAlreadyInUse
+	assert.True(t, errors.As(err, &expected))
+	assert.Equal(t, user.User{}, actual)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCConfirmEmailChange_OtherUserForbidden(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	id := "foo-id"
+	ctx := principalCTX("logged-in-user-id", jwt.MapClaims{"org_id": "foo-org-id", "role": authz.RoleMember})
+
+	md.On("GetByID", ctx, id, false).Return(user.User{ID: id, OrgID: "foo-org-id"}, nil)
+
+	_, err := s.ConfirmEmailChange(ctx, id, user.ConfirmEmailChange{Token: "foo-token"})
+
+	assertForbidden(t, err)
+	md.AssertNotCalled(t, "GetEmailChange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNewEmailChangeToken(t *testing.T) {
+	token, tokenHash, err := newEmailChangeToken()
+	assert.Nil(t, err)
+	assert.Len(t, token, 43)
+	assert.Equal(t, hashEmailChangeToken(token), tokenHash)
+
+	other, _, err := newEmailChangeToken()
+	assert.Nil(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+// actorCTX matches a ctx with userID as the logged in user.
+func actorCTX(userID string) any {
+	return mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Value(ctxutil.ContextKeyUserID{}) == userID
+	})
+}
+
+func TestSVCSaveInvited_NewUser(t *testing.T) {
+	s, _, md, ma, _, mt, mi := initSVC()
+
+	ctx := context.Background()
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	mi.On("GenID").Return("new-id")
+	md.On("GetByEmail", ctx, expectedTX, "foo@bar.com").Return(user.User{}, ErrEmailNotFound{Email: "foo@bar.com"})
+	expected := user.User{
+		ID:        "new-id",
+		OrgID:     "foo-org-id",
+		Name:      "Foo",
+		Email:     "foo@bar.com",
+		IsAdmin:   true,
+		IsActive:  true,
+		CreatedAt: now,
+		CreatedBy: "new-id",
+		UpdatedAt: now,
+		UpdatedBy: "new-id",
+		Version:   1,
+	}
+	md.On("Create", actorCTX("new-id"), expectedTX, expected).Return(nil)
+	ma.On("Record", actorCTX("new-id"), expectedTX, audit.ActionCreate, audit.EntityUser, "new-id", nil, expected).Return(nil)
+
+	actual, err := s.SaveInvited(ctx, nil, user.User{OrgID: "foo-org-id", Name: "Foo", Email: "foo@bar.com", IsAdmin: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCSaveInvited_NewUserNameRequired(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := context.Background()
+	var expectedTX *sqlx.Tx
+	md.On("GetByEmail", ctx, expectedTX, "foo@bar.com").Return(user.User{}, ErrEmailNotFound{Email: "foo@bar.com"})
+
+	_, err := s.SaveInvited(ctx, nil, user.User{OrgID: "foo-org-id", Email: "foo@bar.com"})
+
+	var expected ErrNameRequired
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSaveInvited_ActivatesInactiveUser(t *testing.T) {
+	s, _, md, ma, _, mt, _ := initSVC()
+
+	ctx := context.Background()
+	var expectedTX *sqlx.Tx
+	now := time.UnixMilli(300).UTC()
+	mt.On("Now").Return(now)
+	userInDB := user.User{ID: "foo-id", OrgID: "foo-org-id", Name: "Foo", Email: "foo@bar.com", Version: 2}
+	md.On("GetByEmail", ctx, expectedTX, "foo@bar.com").Return(userInDB, nil)
+	activated := userInDB
+	activated.IsActive = true
+	activated.UpdatedAt = now
+	activated.UpdatedBy = "foo-id"
+	mockRes := activated
+	mockRes.Version = 3
+	md.On("Update", actorCTX("foo-id"), expectedTX, activated).Return(mockRes, nil)
+	ma.On("Record", actorCTX("foo-id"), expectedTX, audit.ActionUpdate, audit.EntityUser, "foo-id", userInDB, mockRes).Return(nil)
+
+	actual, err := s.SaveInvited(ctx, nil, user.User{OrgID: "foo-org-id", Email: "foo@bar.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, mockRes, actual)
+	md.AssertExpectations(t)
+	ma.AssertExpectations(t)
+}
+
+func TestSVCSaveInvited_ActiveUserUnchanged(t *testing.T) {
+	s, _, md, ma, _, _, _ := initSVC()
+
+	ctx := context.Background()
+	var expectedTX *sqlx.Tx
+	userInDB := user.User{ID: "foo-id", OrgID: "bar-org-id", Email: "foo@bar.com", IsActive: true}
+	md.On("GetByEmail", ctx, expectedTX, "foo@bar.com").Return(userInDB, nil)
+
+	actual, err := s.SaveInvited(ctx, nil, user.User{OrgID: "foo-org-id", Email: "foo@bar.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, userInDB, actual)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSaveInvited_InactiveUserOfOtherOrgUnchanged(t *testing.T) {
+	s, _, md, ma, _, _, _ := initSVC()
+
+	ctx := context.Background()
+	var expectedTX *sqlx.Tx
+	userInDB := user.User{ID: "foo-id", OrgID: "bar-org-id", Email: "foo@bar.com", IsActive: false}
+	md.On("GetByEmail", ctx, expectedTX, "foo@bar.com").Return(userInDB, nil)
+
+	actual, err := s.SaveInvited(ctx, nil, user.User{OrgID: "foo-org-id", Email: "foo@bar.com"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, userInDB, actual)
+	assert.False(t, actual.IsActive)
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	ma.AssertNotCalled(t, "Record", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSVCSaveInvited_DeletedUserEmailInUse(t *testing.T) {
+	s, _, md, _, _, _, _ := initSVC()
+
+	ctx := context.Background()
+	var expectedTX *sqlx.Tx
+	deletedAt := time.UnixMilli(200).UTC()
+	md.On("GetByEmail", ctx, expectedTX, "foo@bar.com").Return(user.User{ID: "foo-id", Email: "foo@bar.com", DeletedAt: &deletedAt}, nil)
+
+	_, err := s.SaveInvited(ctx, nil, user.User{OrgID: "foo-org-id", Name: "Foo", Email: "foo@bar.com"})
+
+	var expected ErrEmailAlreadyInUse
+	assert.True(t, errors.As(err, &expected))
+	md.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	md.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
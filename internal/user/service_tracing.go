@@ -0,0 +1,124 @@
+package user
+
+import (
+	"context"
+	"iter"
+
+	"github.com/RyanBard/go-service-ex/internal/page"
+	"github.com/RyanBard/go-service-ex/internal/patch"
+	"github.com/RyanBard/go-service-ex/internal/tracing"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/jmoiron/sqlx"
+)
+
+// tracedService wraps every UserService call in a span.
+type tracedService struct {
+	svc UserService
+}
+
+func NewTracedService(svc UserService) *tracedService {
+	return &tracedService{
+		svc: svc,
+	}
+}
+
+func (s tracedService) GetByID(ctx context.Context, id string, includeDeleted bool) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.GetByID")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetByID(ctx, id, includeDeleted)
+}
+
+func (s tracedService) GetAll(ctx context.Context, q user.Query, includeDeleted bool, pr page.Request) (up user.UserPage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.GetAll")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetAll(ctx, q, includeDeleted, pr)
+}
+
+func (s tracedService) GetAllByOrgID(ctx context.Context, orgID string, includeDeleted bool, pr page.Request) (up user.UserPage, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.GetAllByOrgID")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.GetAllByOrgID(ctx, orgID, includeDeleted, pr)
+}
+
+func (s tracedService) Save(ctx context.Context, input user.User) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Save")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Save(ctx, input)
+}
+
+func (s tracedService) Patch(ctx context.Context, id string, version int64, changes patch.Patch) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Patch")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Patch(ctx, id, version, changes)
+}
+
+func (s tracedService) Create(ctx context.Context, joinTX *sqlx.Tx, input user.User) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Create")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Create(ctx, joinTX, input)
+}
+
+func (s tracedService) CreateInOrg(ctx context.Context, joinTX *sqlx.Tx, o org.Org, input user.User) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.CreateInOrg")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.CreateInOrg(ctx, joinTX, o, input)
+}
+
+func (s tracedService) SaveInvited(ctx context.Context, joinTX *sqlx.Tx, input user.User) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.SaveInvited")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.SaveInvited(ctx, joinTX, input)
+}
+
+func (s tracedService) Delete(ctx context.Context, u user.DeleteUser) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Delete")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Delete(ctx, u)
+}
+
+func (s tracedService) Restore(ctx context.Context, input user.RestoreUser) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Restore")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Restore(ctx, input)
+}
+
+func (s tracedService) Batch(ctx context.Context, b user.Batch) (results []BatchResult, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Batch")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Batch(ctx, b)
+}
+
+// Export's span covers the whole iteration rather than the call that sets it
+// up.
+func (s tracedService) Export(ctx context.Context) iter.Seq2[user.User, error] {
+	return func(yield func(user.User, error) bool) {
+		ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Export")
+		var err error
+		defer func() { tracing.End(span, err) }()
+		for u, rowErr := range s.svc.Export(ctx) {
+			err = rowErr
+			if !yield(u, rowErr) {
+				return
+			}
+		}
+	}
+}
+
+func (s tracedService) Import(ctx context.Context, rows iter.Seq2[user.User, error], dryRun bool) (res ImportResult, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.Import")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.Import(ctx, rows, dryRun)
+}
+
+func (s tracedService) RequestEmailChange(ctx context.Context, id string, ec user.EmailChange) (pec user.PendingEmailChange, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.RequestEmailChange")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.RequestEmailChange(ctx, id, ec)
+}
+
+func (s tracedService) ConfirmEmailChange(ctx context.Context, id string, cec user.ConfirmEmailChange) (u user.User, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "UserSVC.ConfirmEmailChange")
+	defer func() { tracing.End(span, err) }()
+	return s.svc.ConfirmEmailChange(ctx, id, cec)
+}
@@ -1,5 +1,7 @@
 package user
 
+// Every read takes an include deleted flag, soft deleted users are filtered
+// out unless it's true.
 const getByIDQuery = `
 	SELECT
 		u.id,
@@ -13,12 +15,17 @@ const getByIDQuery = `
 		u.created_by,
 		u.updated_at,
 		u.updated_by,
-		u.version
+		u.version,
+		u.deleted_at,
+		COALESCE(u.deleted_by, '') AS deleted_by
 	FROM users u
 	WHERE u.id = $1
+	AND ($2::BOOLEAN OR u.deleted_at IS NULL)
 `
 
-const getAllQuery = `
+// Locks the row so a PATCH can't lose an update that lands between reading
+// the user and writing the patched user.
+const getByIDForUpdateQuery = `
 	SELECT
 		u.id,
 		u.org_id,
@@ -31,11 +38,40 @@ const getAllQuery = `
 		u.created_by,
 		u.updated_at,
 		u.updated_by,
-		u.version
+		u.version,
+		u.deleted_at,
+		COALESCE(u.deleted_by, '') AS deleted_by
+	FROM users u
+	WHERE u.id = $1
+	AND u.deleted_at IS NULL
+	FOR UPDATE
+`
+
+// getAllSelect is completed by buildGetAllQuery, which adds the filters, the
+// keyset predicate, the ORDER BY and the LIMIT.
+const getAllSelect = `
+	SELECT
+		u.id,
+		u.org_id,
+		u.name,
+		u.email,
+		u.is_system,
+		u.is_admin,
+		u.is_active,
+		u.created_at,
+		u.created_by,
+		u.updated_at,
+		u.updated_by,
+		u.version,
+		u.deleted_at,
+		COALESCE(u.deleted_by, '') AS deleted_by
 	FROM users u
-	ORDER BY u.email ASC, u.created_at DESC
 `
 
+// The keyset queries follow the ORDER BY of the first page queries, the
+// id is only there as a tie breaker so the cursor is always unique. An org's
+// users are everyone with a membership in it, not just the ones whose org_id
+// it is.
 const getAllByOrgIDQuery = `
 	SELECT
 		u.id,
@@ -49,10 +85,44 @@ const getAllByOrgIDQuery = `
 		u.created_by,
 		u.updated_at,
 		u.updated_by,
-		u.version
+		u.version,
+		u.deleted_at,
+		COALESCE(u.deleted_by, '') AS deleted_by
+	FROM users u
+	JOIN memberships m ON m.user_id = u.id
+	WHERE m.org_id = $1
+	AND ($3::BOOLEAN OR u.deleted_at IS NULL)
+	ORDER BY u.email ASC, u.created_at DESC, u.id ASC
+	LIMIT $2
+`
+
+const getAllByOrgIDAfterQuery = `
+	SELECT
+		u.id,
+		u.org_id,
+		u.name,
+		u.email,
+		u.is_system,
+		u.is_admin,
+		u.is_active,
+		u.created_at,
+		u.created_by,
+		u.updated_at,
+		u.updated_by,
+		u.version,
+		u.deleted_at,
+		COALESCE(u.deleted_by, '') AS deleted_by
 	FROM users u
-	WHERE u.org_id = $1
-	ORDER BY u.email ASC, u.created_at DESC
+	JOIN memberships m ON m.user_id = u.id
+	WHERE m.org_id = $1
+	AND (
+		u.email > $2
+		OR (u.email = $2 AND u.created_at < $3)
+		OR (u.email = $2 AND u.created_at = $3 AND u.id > $4)
+	)
+	AND ($6::BOOLEAN OR u.deleted_at IS NULL)
+	ORDER BY u.email ASC, u.created_at DESC, u.id ASC
+	LIMIT $5
 `
 
 const createQuery = `
@@ -83,7 +153,8 @@ const createQuery = `
 	)
 `
 
-// Note: I'm not allowing the user to change their email
+// Note: the email is only changed through updateEmailQuery, once the new one
+// has been verified
 const updateQuery = `
 	UPDATE users SET
 		name = :name,
@@ -94,10 +165,110 @@ const updateQuery = `
 		version = 1 + :version
 	WHERE id = :id
 	AND version = :version
+	AND deleted_at IS NULL
 `
 
+// Deleting bumps the version so a stale client can't restore (or delete
+// again) without re-reading first.
 const deleteQuery = `
+	UPDATE users SET
+		deleted_at = :deleted_at,
+		deleted_by = :deleted_by,
+		version = 1 + :version
+	WHERE id = :id
+	AND version = :version
+	AND deleted_at IS NULL
+`
+
+const restoreQuery = `
+	UPDATE users SET
+		deleted_at = NULL,
+		deleted_by = NULL,
+		updated_at = :updated_at,
+		updated_by = :updated_by,
+		version = 1 + :version
+	WHERE id = :id
+	AND version = :version
+	AND deleted_at IS NOT NULL
+`
+
+const purgeQuery = `
 	DELETE FROM users
+	WHERE deleted_at < $1
+`
+
+// A new request replaces the pending one, along with its token.
+const saveEmailChangeQuery = `
+	INSERT INTO email_changes(
+		user_id,
+		new_email,
+		token_hash,
+		expires_at,
+		created_at,
+		created_by
+	) VALUES (
+		:user_id,
+		:new_email,
+		:token_hash,
+		:expires_at,
+		:created_at,
+		:created_by
+	)
+	ON CONFLICT (user_id) DO UPDATE SET
+		new_email = EXCLUDED.new_email,
+		token_hash = EXCLUDED.token_hash,
+		expires_at = EXCLUDED.expires_at,
+		created_at = EXCLUDED.created_at,
+		created_by = EXCLUDED.created_by
+`
+
+// Locked so two confirms of the same token can't both go through.
+const getEmailChangeQuery = `
+	SELECT
+		user_id,
+		new_email,
+		token_hash,
+		expires_at,
+		created_at,
+		created_by
+	FROM email_changes
+	WHERE user_id = $1
+	FOR UPDATE
+`
+
+const deleteEmailChangeQuery = `
+	DELETE FROM email_changes
+	WHERE user_id = $1
+`
+
+const updateEmailQuery = `
+	UPDATE users SET
+		email = :email,
+		updated_at = :updated_at,
+		updated_by = :updated_by,
+		version = 1 + :version
 	WHERE id = :id
 	AND version = :version
+	AND deleted_at IS NULL
+`
+
+// Soft deleted users are returned too, their email is still reserved.
+const getByEmailQuery = `
+	SELECT
+		u.id,
+		u.org_id,
+		u.name,
+		u.email,
+		u.is_system,
+		u.is_admin,
+		u.is_active,
+		u.created_at,
+		u.created_by,
+		u.updated_at,
+		u.updated_by,
+		u.version,
+		u.deleted_at,
+		COALESCE(u.deleted_by, '') AS deleted_by
+	FROM users u
+	WHERE u.email = $1
 `
@@ -0,0 +1,17 @@
+package validate
+
+import (
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+)
+
+type ErrInvalid struct {
+	Violations []problem.Violation
+}
+
+func (err ErrInvalid) Error() string {
+	return problem.ValidationError{Violations: err.Violations}.Error()
+}
+
+func (err ErrInvalid) Code() string {
+	return problem.CodeValidationFailed
+}
@@ -0,0 +1,186 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// name the fields after their json so violations match the body
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonName)
+	}
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// Struct checks v against the binding rules on its fields, the same ones
+// ShouldBindJSON checks. Every violation's field is prefixed with prefix (ex.
+// users[1]. when v is one of a list).
+func Struct(v any, prefix string) error {
+	return FromErr(binding.Validator.ValidateStruct(v), prefix)
+}
+
+// StructExcept is Struct without the rules of the fields (named after the go
+// field, ex. OrgID), every violation's field is prefixed with prefix.
+func StructExcept(v any, prefix string, fields ...string) error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return Struct(v, prefix)
+	}
+	return FromErr(engine.StructExcept(v, fields...), prefix)
+}
+
+// Changed is Struct for an update of old, only the fields v changes are
+// checked. A row saved before a rule was added (ex. an email that isn't valid
+// anymore) can still have its other fields updated, changing the field itself
+// has to follow the rule.
+func Changed(v any, old any, prefix string) error {
+	var validationErrs validator.ValidationErrors
+	err := binding.Validator.ValidateStruct(v)
+	if !errors.As(err, &validationErrs) {
+		return FromErr(err, prefix)
+	}
+	var changed validator.ValidationErrors
+	for _, fe := range validationErrs {
+		if !unchanged(v, old, fe) {
+			changed = append(changed, fe)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return FromErr(changed, prefix)
+}
+
+// unchanged is true when fe is on a top level field that's the same in v and
+// old, a nested field is always treated as changed.
+func unchanged(v any, old any, fe validator.FieldError) bool {
+	if strings.Count(fe.StructNamespace(), ".") != 1 {
+		return false
+	}
+	newField := reflect.Indirect(reflect.ValueOf(v)).FieldByName(fe.StructField())
+	oldField := reflect.Indirect(reflect.ValueOf(old)).FieldByName(fe.StructField())
+	if !newField.IsValid() || !oldField.IsValid() {
+		return false
+	}
+	return reflect.DeepEqual(newField.Interface(), oldField.Interface())
+}
+
+// Required is the violation for a field that's only required in some cases,
+// so it can't be a binding rule.
+func Required(field string) problem.Violation {
+	return problem.Violation{
+		Field:   field,
+		Rule:    "required",
+		Message: fmt.Sprintf("%s is required", field),
+	}
+}
+
+// ReadOnly is the violation for a field a PATCH tried to change but can't.
+func ReadOnly(field string) problem.Violation {
+	return problem.Violation{
+		Field:   field,
+		Rule:    "readonly",
+		Message: fmt.Sprintf("%s is read only", field),
+	}
+}
+
+// Violations collects the violations of more than one struct (ex. every op of
+// a batch) so they're all responded with at once.
+type Violations []problem.Violation
+
+// Add keeps err's violations, any other error is returned.
+func (vs *Violations) Add(err error) error {
+	var invalid ErrInvalid
+	if errors.As(err, &invalid) {
+		*vs = append(*vs, invalid.Violations...)
+		return nil
+	}
+	return err
+}
+
+// Err is nil when nothing was violated.
+func (vs Violations) Err() error {
+	if len(vs) == 0 {
+		return nil
+	}
+	return ErrInvalid{Violations: vs}
+}
+
+// FromErr turns the validator's errors into an ErrInvalid, anything else
+// (including nil) is returned as is.
+func FromErr(err error, prefix string) error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+	invalid := ErrInvalid{Violations: make([]problem.Violation, len(validationErrs))}
+	for i, fe := range validationErrs {
+		invalid.Violations[i] = toViolation(fe, prefix)
+	}
+	return invalid
+}
+
+func toViolation(fe validator.FieldError, prefix string) problem.Violation {
+	// the namespace starts with the top level struct's name
+	_, field, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		field = fe.Field()
+	}
+	field = prefix + field
+	return problem.Violation{
+		Field:   field,
+		Rule:    fe.Tag(),
+		Message: fmt.Sprintf("%s %s", field, describe(fe)),
+	}
+}
+
+// describe is what breaking the rule means, for the rules the models use.
+func describe(fe validator.FieldError) string {
+	isStr := fe.Kind() == reflect.String
+	isList := fe.Kind() == reflect.Slice || fe.Kind() == reflect.Array || fe.Kind() == reflect.Map
+	switch {
+	case fe.Tag() == "required":
+		return "is required"
+	case fe.Tag() == "email":
+		return "must be a valid email"
+	case fe.Tag() == "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fe.Param(), " ", ", "))
+	case fe.Tag() == "max" && isStr:
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case fe.Tag() == "max" && isList:
+		return fmt.Sprintf("must have at most %s", items(fe.Param()))
+	case fe.Tag() == "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case fe.Tag() == "min" && isStr:
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case fe.Tag() == "min" && isList:
+		return fmt.Sprintf("must have at least %s", items(fe.Param()))
+	case fe.Tag() == "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+func items(n string) string {
+	if n == "1" {
+		return "1 item"
+	}
+	return n + " items"
+}
@@ -0,0 +1,138 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStruct_Valid(t *testing.T) {
+	err := Struct(user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "foo@bar.com"}, "")
+	assert.Nil(t, err)
+}
+
+func TestStruct_User(t *testing.T) {
+	err := Struct(user.User{Name: strings.Repeat("a", 101), Email: "not-an-email"}, "")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "org_id", Rule: "required", Message: "org_id is required"},
+		{Field: "name", Rule: "max", Message: "name must be at most 100 characters"},
+		{Field: "email", Rule: "email", Message: "email must be a valid email"},
+	}, invalid.Violations)
+	assert.Equal(t, "org_id is required, name must be at most 100 characters, email must be a valid email", err.Error())
+	assert.Equal(t, problem.CodeValidationFailed, invalid.Code())
+}
+
+func TestStruct_Org(t *testing.T) {
+	err := Struct(org.Org{Name: "foo-name", Desc: strings.Repeat("a", 1001)}, "")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "desc", Rule: "max", Message: "desc must be at most 1000 characters"},
+	}, invalid.Violations)
+}
+
+func TestStruct_Nested(t *testing.T) {
+	b := user.Batch{Mode: "foo", Ops: []user.BatchOp{{Op: "bar"}}}
+
+	err := Struct(b, "")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "mode", Rule: "oneof", Message: "mode must be one of all-or-nothing, best-effort"},
+		{Field: "ops[0].op", Rule: "oneof", Message: "ops[0].op must be one of create, update, delete"},
+	}, invalid.Violations)
+}
+
+func TestStruct_Prefix(t *testing.T) {
+	err := Struct(user.DeleteUser{ID: "foo-id"}, "ops[1].user.")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "ops[1].user.version", Rule: "required", Message: "ops[1].user.version is required"},
+	}, invalid.Violations)
+}
+
+func TestStructExcept(t *testing.T) {
+	err := StructExcept(user.User{Name: "foo-name"}, "users[0].", "OrgID")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "users[0].email", Rule: "required", Message: "users[0].email is required"},
+	}, invalid.Violations)
+}
+
+func TestChanged_UnchangedViolationIgnored(t *testing.T) {
+	old := user.User{OrgID: "foo-org-id", Name: "foo-name", Email: "not-an-email"}
+	u := old
+	u.Name = "bar-name"
+
+	err := Changed(u, old, "")
+
+	assert.Nil(t, err)
+}
+
+func TestChanged_ChangedViolation(t *testing.T) {
+	old := user.User{OrgID: "foo-org-id", Name: strings.Repeat("a", 101), Email: "not-an-email"}
+	u := old
+	u.Email = "still-not-an-email"
+
+	err := Changed(u, old, "")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "email", Rule: "email", Message: "email must be a valid email"},
+	}, invalid.Violations)
+}
+
+func TestChanged_ClearedRequired(t *testing.T) {
+	old := org.Org{Name: "foo-name", Desc: "foo-desc"}
+	o := old
+	o.Desc = ""
+
+	err := Changed(o, old, "ops[0].")
+
+	var invalid ErrInvalid
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, []problem.Violation{
+		{Field: "ops[0].desc", Rule: "required", Message: "ops[0].desc is required"},
+	}, invalid.Violations)
+}
+
+func TestFromErr_NotValidation(t *testing.T) {
+	mockErr := errors.New("unit-test error")
+	assert.Equal(t, mockErr, FromErr(mockErr, ""))
+	assert.Nil(t, FromErr(nil, ""))
+}
+
+func TestReadOnly(t *testing.T) {
+	assert.Equal(t, problem.Violation{Field: "email", Rule: "readonly", Message: "email is read only"}, ReadOnly("email"))
+}
+
+func TestViolations(t *testing.T) {
+	var vs Violations
+	assert.Nil(t, vs.Err())
+
+	assert.Nil(t, vs.Add(nil))
+	assert.Nil(t, vs.Add(ErrInvalid{Violations: []problem.Violation{Required("foo")}}))
+	assert.Nil(t, vs.Add(ErrInvalid{Violations: []problem.Violation{Required("bar")}}))
+	mockErr := errors.New("unit-test error")
+	assert.Equal(t, mockErr, vs.Add(mockErr))
+
+	assert.Equal(t, ErrInvalid{Violations: []problem.Violation{
+		{Field: "foo", Rule: "required", Message: "foo is required"},
+		{Field: "bar", Rule: "required", Message: "bar is required"},
+	}}, vs.Err())
+}
@@ -6,10 +6,15 @@ import (
 
 type Config struct {
 	BaseURL     string `envconfig:"BASE_URL" default:"http://localhost:4000"`
+	GRPCAddr    string `envconfig:"GRPC_ADDR" default:"localhost:4001"`
 	LogLevel    string `envconfig:"LOG_LEVEL" default:"debug"`
 	JWTSecret   string `envconfig:"JWT_SECRET"`
 	JWTAudience string `envconfig:"JWT_AUDIENCE" default:"gin-ex"`
 	JWTIssuer   string `envconfig:"JWT_ISSUER" default:"something"`
+	// NotifierDir is the server's NOTIFIER_DIR (as an absolute path) when it
+	// runs with NOTIFIER=file, the tests that need a token from a
+	// notification are skipped without it
+	NotifierDir string `envconfig:"IT_NOTIFIER_DIR"`
 }
 
 func LoadConfig() (c Config, err error) {
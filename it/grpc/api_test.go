@@ -0,0 +1,229 @@
+//go:build integration
+// +build integration
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	logutil "github.com/RyanBard/go-log-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/rpcerr"
+	"github.com/RyanBard/go-service-ex/internal/testutil"
+	"github.com/RyanBard/go-service-ex/it/config"
+	orgv1 "github.com/RyanBard/go-service-ex/pkg/pb/org/v1"
+	userv1 "github.com/RyanBard/go-service-ex/pkg/pb/user/v1"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	sysOrgID       = "a517c24e-9b5f-4e5a-b840-e4f70a74725f"
+	adminUserID    = "fc83cf36-bba0-41f0-8125-2ebc03087140"
+	nonAdminUserID = "ffff0000-0000-0000-0000-000000000000"
+)
+
+type info struct {
+	config       config.Config
+	conn         *grpc.ClientConn
+	orgClient    orgv1.OrgServiceClient
+	userClient   userv1.UserServiceClient
+	healthClient healthpb.HealthClient
+	log          *slog.Logger
+	reqID        string
+}
+
+func setupSuite(tb testing.TB) (*info, func(tb testing.TB)) {
+	reqID := uuid.NewString()
+	logger := testutil.GetLogger()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.With(logutil.LogAttrError(err)).Error("invalid config")
+		panic(err)
+	}
+
+	lvl, err := logutil.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logger.With(
+			logutil.LogAttrError(err),
+			slog.String("logLevel", cfg.LogLevel),
+		).Error("invalid log level")
+		panic(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: lvl})).
+		With(slog.String("reqID", reqID))
+
+	conn, err := grpc.NewClient(cfg.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.With(logutil.LogAttrError(err)).Error("failed to create grpc client")
+		panic(err)
+	}
+	gi := info{
+		config:       cfg,
+		conn:         conn,
+		orgClient:    orgv1.NewOrgServiceClient(conn),
+		userClient:   userv1.NewUserServiceClient(conn),
+		healthClient: healthpb.NewHealthClient(conn),
+		log:          log,
+		reqID:        reqID,
+	}
+	return &gi, func(tb testing.TB) {
+		conn.Close()
+	}
+}
+
+// ctx sends the request id and token (when there is one) as metadata.
+func (gi *info) ctx(name string, token string) context.Context {
+	md := []string{"x-request-id", fmt.Sprintf("%s-%s", name, gi.reqID)}
+	if token != "" {
+		md = append(md, "authorization", "Bearer "+token)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), md...)
+}
+
+func (gi *info) getAdminJWT() string {
+	claims := gi.getClaims(adminUserID)
+	claims["admin"] = true
+	return gi.hmacJWT(claims)
+}
+
+func (gi *info) getNonAdminJWT() string {
+	claims := gi.getClaims(nonAdminUserID)
+	claims["org_id"] = sysOrgID
+	return gi.hmacJWT(claims)
+}
+
+func (gi *info) getClaims(userID string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": userID,
+		"aud": gi.config.JWTAudience,
+		"iss": gi.config.JWTIssuer,
+		"exp": time.Now().AddDate(0, 0, 1).Unix(),
+	}
+}
+
+func (gi *info) hmacJWT(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenStr, err := token.SignedString([]byte(gi.config.JWTSecret))
+	if err != nil {
+		gi.log.With(logutil.LogAttrError(err)).Error("failed to sign jwt")
+		panic(err)
+	}
+	return tokenStr
+}
+
+func TestGRPCAPI(t *testing.T) {
+	s, teardown := setupSuite(t)
+	defer teardown(t)
+
+	s.log.Info("gRPC Integration Test run")
+
+	t.Run("Health", func(t *testing.T) {
+		resp, err := s.healthClient.Check(s.ctx("health", ""), &healthpb.HealthCheckRequest{})
+		assert.Nil(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	})
+
+	t.Run("GetOrg", func(t *testing.T) {
+		t.Run("Found", func(t *testing.T) {
+			o, err := s.orgClient.GetOrg(s.ctx("getOrg-valid", s.getAdminJWT()), &orgv1.GetOrgRequest{Id: sysOrgID})
+			assert.Nil(t, err)
+			assert.Equal(t, sysOrgID, o.Id)
+			assert.Equal(t, "System Org", o.Name)
+			assert.True(t, o.IsSystem)
+			assert.Equal(t, int64(1), o.Version)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			_, err := s.orgClient.GetOrg(s.ctx("getOrg-not-found", s.getAdminJWT()), &orgv1.GetOrgRequest{Id: "will-not-find"})
+			assert.Equal(t, codes.NotFound, status.Code(err))
+			assert.Equal(t, problem.CodeOrgNotFound, rpcerr.Reason(err))
+		})
+
+		t.Run("NoToken", func(t *testing.T) {
+			_, err := s.orgClient.GetOrg(s.ctx("getOrg-no-jwt", ""), &orgv1.GetOrgRequest{Id: sysOrgID})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+
+		t.Run("InvalidToken", func(t *testing.T) {
+			_, err := s.orgClient.GetOrg(s.ctx("getOrg-invalid-jwt", "x.y.z"), &orgv1.GetOrgRequest{Id: sysOrgID})
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	})
+
+	t.Run("OrgLifecycle", func(t *testing.T) {
+		name := fmt.Sprintf("grpc-it-%s", s.reqID)
+		created, err := s.orgClient.CreateOrg(s.ctx("createOrg", s.getAdminJWT()), &orgv1.CreateOrgRequest{Name: name, Desc: "created over grpc"})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, created.Id)
+		assert.Equal(t, int64(1), created.Version)
+
+		_, err = s.orgClient.CreateOrg(s.ctx("createOrg-dup", s.getAdminJWT()), &orgv1.CreateOrgRequest{Name: name, Desc: "created over grpc"})
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+		updated, err := s.orgClient.UpdateOrg(s.ctx("updateOrg", s.getAdminJWT()), &orgv1.UpdateOrgRequest{Id: created.Id, Name: name, Desc: "updated over grpc", Version: created.Version})
+		assert.Nil(t, err)
+		assert.Equal(t, "updated over grpc", updated.Desc)
+		assert.Equal(t, int64(2), updated.Version)
+
+		_, err = s.orgClient.UpdateOrg(s.ctx("updateOrg-stale", s.getAdminJWT()), &orgv1.UpdateOrgRequest{Id: created.Id, Name: name, Desc: "stale", Version: created.Version})
+		assert.Equal(t, codes.Aborted, status.Code(err))
+
+		_, err = s.orgClient.DeleteOrg(s.ctx("deleteOrg-non-admin", s.getNonAdminJWT()), &orgv1.DeleteOrgRequest{Id: created.Id, Version: updated.Version})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, problem.CodeForbidden, rpcerr.Reason(err))
+
+		_, err = s.orgClient.DeleteOrg(s.ctx("deleteOrg", s.getAdminJWT()), &orgv1.DeleteOrgRequest{Id: created.Id, Version: updated.Version})
+		assert.Nil(t, err)
+
+		_, err = s.orgClient.GetOrg(s.ctx("getOrg-deleted", s.getAdminJWT()), &orgv1.GetOrgRequest{Id: created.Id})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("CreateUser", func(t *testing.T) {
+		t.Run("ValidationError", func(t *testing.T) {
+			_, err := s.userClient.CreateUser(s.ctx("createUser-invalid", s.getAdminJWT()), &userv1.CreateUserRequest{OrgId: sysOrgID, Name: "foo"})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Equal(t, problem.CodeValidationFailed, rpcerr.Reason(err))
+		})
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		resp, err := s.userClient.ListUsers(s.ctx("listUsers", s.getAdminJWT()), &userv1.ListUsersRequest{OrgId: sysOrgID, Limit: 1})
+		assert.Nil(t, err)
+		assert.Len(t, resp.Users, 1)
+		assert.Equal(t, sysOrgID, resp.Users[0].OrgId)
+	})
+
+	t.Run("ExportUsers", func(t *testing.T) {
+		stream, err := s.userClient.ExportUsers(s.ctx("exportUsers", s.getAdminJWT()), &userv1.ExportUsersRequest{})
+		assert.Nil(t, err)
+		numUsers := 0
+		for {
+			_, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err)
+			if err != nil {
+				break
+			}
+			numUsers++
+		}
+		assert.GreaterOrEqual(t, numUsers, 1)
+	})
+}
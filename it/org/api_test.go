@@ -10,6 +10,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,7 +20,10 @@ import (
 	"github.com/RyanBard/go-service-ex/internal/httpx"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
 	"github.com/RyanBard/go-service-ex/it/config"
+	"github.com/RyanBard/go-service-ex/pkg/audit"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -34,20 +38,31 @@ const (
 
 type orgClient interface {
 	GetByID(ctx context.Context, id string) (org.Org, error)
+	GetByIDIncludingDeleted(ctx context.Context, id string) (org.Org, error)
 	GetAll(ctx context.Context) ([]org.Org, error)
+	GetPage(ctx context.Context, limit int, cursor string) (org.OrgPage, error)
 	SearchByName(ctx context.Context, name string) ([]org.Org, error)
 	Save(ctx context.Context, input org.Org) (org.Org, error)
+	Patch(ctx context.Context, id string, p patch.Patch) (org.Org, error)
 	Delete(ctx context.Context, input org.DeleteOrg) error
+	Restore(ctx context.Context, input org.RestoreOrg) (org.Org, error)
+	Export(ctx context.Context, format string) (string, error)
+}
+
+type auditClient interface {
+	Search(ctx context.Context, q audit.Query) ([]audit.Event, error)
 }
 
 type info struct {
-	config            config.Config
-	orgsToCleanup     map[string]org.DeleteOrg
-	orgClient         orgClient
-	invJWTOrgClient   orgClient
-	nonAdminOrgClient orgClient
-	log               *slog.Logger
-	reqID             string
+	config              config.Config
+	orgsToCleanup       map[string]org.DeleteOrg
+	orgClient           orgClient
+	invJWTOrgClient     orgClient
+	nonAdminOrgClient   orgClient
+	auditClient         auditClient
+	nonAdminAuditClient auditClient
+	log                 *slog.Logger
+	reqID               string
 }
 
 func setupSuite(tb testing.TB) (*info, func(tb testing.TB)) {
@@ -114,6 +129,30 @@ func setupSuite(tb testing.TB) (*info, func(tb testing.TB)) {
 			},
 		),
 	)
+	oi.auditClient = audit.NewClient(
+		audit.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				token := oi.getAdminJWT()
+				return token, nil
+			},
+		),
+	)
+	oi.nonAdminAuditClient = audit.NewClient(
+		audit.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				token := oi.getNonAdminJWT()
+				return token, nil
+			},
+		),
+	)
 	return &oi, func(tb testing.TB) {
 		for i, o := range oi.orgsToCleanup {
 			if o.ID != "" {
@@ -134,6 +173,26 @@ func (oi *info) addOrgToCleanup(o org.Org) {
 	oi.orgsToCleanup[o.ID] = org.DeleteOrg{ID: o.ID, Version: o.Version}
 }
 
+// doRaw sends a request as an admin straight through net/http, for the
+// headers the clients don't expose.
+func (oi *info) doRaw(t *testing.T, method string, id string, headers map[string]string, body string) *http.Response {
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/api/orgs/%s", oi.config.BaseURL, id), strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+oi.getAdminJWT())
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	res.Body.Close()
+	return res
+}
+
 func (oi *info) getAdminJWT() string {
 	claims := oi.getClaims(adminUserID)
 	claims["admin"] = true
@@ -142,6 +201,7 @@ func (oi *info) getAdminJWT() string {
 
 func (oi *info) getNonAdminJWT() string {
 	claims := oi.getClaims(nonAdminUserID)
+	claims["org_id"] = sysOrgID
 	return oi.hmacJWT(claims)
 }
 
@@ -235,6 +295,25 @@ func TestOrgAPI(t *testing.T) {
 			assert.True(t, found)
 		})
 
+		t.Run("Paged", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("getAll-paged-%s", s.reqID))
+			all, err := s.orgClient.GetAll(ctx)
+			assert.Nil(t, err)
+			var paged []org.Org
+			cursor := ""
+			for {
+				op, err := s.orgClient.GetPage(ctx, 1, cursor)
+				assert.Nil(t, err)
+				assert.LessOrEqual(t, len(op.Orgs), 1)
+				paged = append(paged, op.Orgs...)
+				if op.NextCursor == "" {
+					break
+				}
+				cursor = op.NextCursor
+			}
+			assert.Equal(t, len(all), len(paged))
+		})
+
 		t.Run("NonAdminToken", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("getAll-non-admin-jwt-%s", s.reqID))
 			orgs, err := s.nonAdminOrgClient.GetAll(ctx)
@@ -265,6 +344,39 @@ func TestOrgAPI(t *testing.T) {
 		})
 	})
 
+	t.Run("Export", func(t *testing.T) {
+		t.Run("CSV", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-csv-%s", s.reqID))
+			out, err := s.orgClient.Export(ctx, "csv")
+			assert.Nil(t, err)
+			assert.True(t, strings.HasPrefix(out, "id,name,desc,is_system,"))
+			assert.Contains(t, out, sysOrgID+",System Org,")
+		})
+
+		t.Run("NDJSON", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-ndjson-%s", s.reqID))
+			out, err := s.orgClient.Export(ctx, "ndjson")
+			assert.Nil(t, err)
+			assert.Contains(t, out, `"id":"`+sysOrgID+`"`)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-non-admin-jwt-%s", s.reqID))
+			out, err := s.nonAdminOrgClient.Export(ctx, "ndjson")
+			assert.Nil(t, err)
+			// only their own org
+			assert.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 1)
+		})
+
+		t.Run("InvalidToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-invalid-jwt-%s", s.reqID))
+			_, err := s.invJWTOrgClient.Export(ctx, "csv")
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 401, httpErr.StatusCode)
+		})
+	})
+
 	t.Run("SearchByName", func(t *testing.T) {
 		t.Run("Found", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("searchByName-valid-%s", s.reqID))
@@ -337,6 +449,30 @@ func TestOrgAPI(t *testing.T) {
 			assert.LessOrEqual(t, o.CreatedAt, o.UpdatedAt)
 		})
 
+		t.Run("IdempotencyKeyReplayed", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("create-idempotency-key-%s", s.reqID))
+			ctx = apiclient.WithIdempotencyKey(ctx, uuid.NewString())
+			input := org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			}
+			o, err := s.orgClient.Save(ctx, input)
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			// the retry gets the first response instead of a 409 for the name
+			retried, err := s.orgClient.Save(ctx, input)
+			assert.Nil(t, err)
+			assert.Equal(t, o, retried)
+			// the same key for something else
+			_, err = s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 422, httpErr.StatusCode)
+		})
+
 		t.Run("MissingName", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("create-missing-name-%s", s.reqID))
 			o, err := s.orgClient.Save(ctx, org.Org{
@@ -579,6 +715,150 @@ func TestOrgAPI(t *testing.T) {
 		})
 	})
 
+	t.Run("Patch", func(t *testing.T) {
+		newOrg := func(t *testing.T, name string) org.Org {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-%s-setup-%s", name, s.reqID))
+			o, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			return o
+		}
+
+		t.Run("Merge", func(t *testing.T) {
+			o := newOrg(t, "merge")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-merge-%s", s.reqID))
+			o2, err := s.orgClient.Patch(ctx, o.ID, patch.Merge(map[string]any{"desc": "Patched", "version": o.Version}))
+			s.addOrgToCleanup(o2)
+			assert.Nil(t, err)
+			assert.Equal(t, o.Name, o2.Name)
+			assert.Equal(t, "Patched", o2.Desc)
+			assert.Equal(t, o.Version+1, o2.Version)
+			assert.Equal(t, o.CreatedAt, o2.CreatedAt)
+			assert.Less(t, o.UpdatedAt, o2.UpdatedAt)
+		})
+
+		t.Run("JSON", func(t *testing.T) {
+			o := newOrg(t, "json")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-json-%s", s.reqID))
+			o2, err := s.orgClient.Patch(ctx, o.ID, patch.JSON(
+				patch.Test("/version", o.Version),
+				patch.Replace("/name", o.Name+"-patched"),
+			))
+			s.addOrgToCleanup(o2)
+			assert.Nil(t, err)
+			assert.Equal(t, o.Name+"-patched", o2.Name)
+			assert.Equal(t, o.Desc, o2.Desc)
+			assert.Equal(t, o.Version+1, o2.Version)
+		})
+
+		t.Run("TestFailed", func(t *testing.T) {
+			o := newOrg(t, "test-failed")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-test-failed-%s", s.reqID))
+			_, err := s.orgClient.Patch(ctx, o.ID, patch.JSON(
+				patch.Test("/version", o.Version+1),
+				patch.Replace("/name", o.Name+"-patched"),
+			))
+			assert.True(t, problem.HasCode(err, problem.CodePatchTestFailed))
+		})
+
+		t.Run("ReadOnly", func(t *testing.T) {
+			o := newOrg(t, "read-only")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-read-only-%s", s.reqID))
+			_, err := s.orgClient.Patch(ctx, o.ID, patch.Merge(map[string]any{"is_system": true}))
+			var validationErr problem.ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, []problem.Violation{
+				{Field: "is_system", Rule: "readonly", Message: "is_system is read only"},
+			}, validationErr.Violations)
+		})
+
+		t.Run("Malformed", func(t *testing.T) {
+			o := newOrg(t, "malformed")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-malformed-%s", s.reqID))
+			_, err := s.orgClient.Patch(ctx, o.ID, patch.JSON(patch.Remove("/missing")))
+			assert.True(t, problem.HasCode(err, problem.CodeMalformedPatch))
+		})
+
+		t.Run("UnsupportedContentType", func(t *testing.T) {
+			o := newOrg(t, "unsupported")
+			res := s.doRaw(t, "PATCH", o.ID, nil, `{"desc": "Patched"}`)
+			assert.Equal(t, 415, res.StatusCode)
+		})
+
+		t.Run("IfMatch", func(t *testing.T) {
+			o := newOrg(t, "if-match")
+			tag := fmt.Sprintf(`"%s:%d"`, o.ID, o.Version)
+			headers := map[string]string{"If-Match": tag, "Content-Type": patch.ContentTypeMerge}
+			res := s.doRaw(t, "PATCH", o.ID, headers, `{"desc": "Patched"}`)
+			assert.Equal(t, 200, res.StatusCode)
+			assert.Equal(t, fmt.Sprintf(`"%s:%d"`, o.ID, o.Version+1), res.Header.Get("ETag"))
+
+			// the old tag is stale now
+			res = s.doRaw(t, "PATCH", o.ID, headers, `{"desc": "Patched again"}`)
+			assert.Equal(t, 412, res.StatusCode)
+		})
+
+		t.Run("SysOrg", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-sys-org-%s", s.reqID))
+			_, err := s.orgClient.Patch(ctx, sysOrgID, patch.Merge(map[string]any{"desc": "Patched"}))
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-not-found-%s", s.reqID))
+			_, err := s.orgClient.Patch(ctx, uuid.NewString(), patch.Merge(map[string]any{"desc": "Patched"}))
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			o := newOrg(t, "non-admin")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-non-admin-%s", s.reqID))
+			_, err := s.nonAdminOrgClient.Patch(ctx, o.ID, patch.Merge(map[string]any{"desc": "Patched"}))
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("Conditional", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("conditional-setup-%s", s.reqID))
+		o, err := s.orgClient.Save(ctx, org.Org{
+			Name: "Test-" + uuid.NewString(),
+			Desc: "Integration Test",
+		})
+		assert.Nil(t, err)
+		tag := fmt.Sprintf(`"%s:%d"`, o.ID, o.Version)
+
+		res := s.doRaw(t, "GET", o.ID, nil, "")
+		assert.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, tag, res.Header.Get("ETag"))
+
+		res = s.doRaw(t, "GET", o.ID, map[string]string{"If-None-Match": tag}, "")
+		assert.Equal(t, 304, res.StatusCode)
+
+		body := fmt.Sprintf(`{"name": "%s", "desc": "Updated"}`, o.Name)
+		res = s.doRaw(t, "PUT", o.ID, map[string]string{"If-Match": tag}, body)
+		assert.Equal(t, 200, res.StatusCode)
+		newTag := res.Header.Get("ETag")
+		assert.Equal(t, fmt.Sprintf(`"%s:%d"`, o.ID, o.Version+1), newTag)
+
+		// the old tag is stale now
+		res = s.doRaw(t, "PUT", o.ID, map[string]string{"If-Match": tag}, body)
+		assert.Equal(t, 412, res.StatusCode)
+		res = s.doRaw(t, "DELETE", o.ID, map[string]string{"If-Match": tag}, "")
+		assert.Equal(t, 412, res.StatusCode)
+
+		res = s.doRaw(t, "DELETE", o.ID, map[string]string{"If-Match": newTag}, "")
+		assert.Equal(t, 204, res.StatusCode)
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		t.Run("Valid", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("delete-valid-setup-%s", s.reqID))
@@ -631,6 +911,9 @@ func TestOrgAPI(t *testing.T) {
 			var httpErr httpx.HTTPError
 			assert.True(t, errors.As(err, &httpErr))
 			assert.Equal(t, 409, httpErr.StatusCode)
+			var p problem.Problem
+			assert.True(t, errors.As(err, &p))
+			assert.Equal(t, problem.CodeOrgVersionConflict, p.Code)
 		})
 
 		t.Run("MissingVersion", func(t *testing.T) {
@@ -696,4 +979,110 @@ func TestOrgAPI(t *testing.T) {
 			assert.Equal(t, 401, httpErr.StatusCode)
 		})
 	})
+
+	t.Run("Restore", func(t *testing.T) {
+		t.Run("Valid", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-valid-setup-%s", s.reqID))
+			o, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			err = s.orgClient.Delete(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version})
+			assert.Nil(t, err)
+			_, err = s.orgClient.GetByID(ctx, o.ID)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+			deleted, err := s.orgClient.GetByIDIncludingDeleted(ctx, o.ID)
+			assert.Nil(t, err)
+			assert.NotNil(t, deleted.DeletedAt)
+			assert.Equal(t, adminUserID, deleted.DeletedBy)
+			ctx = context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-valid-%s", s.reqID))
+			restored, err := s.orgClient.Restore(ctx, org.RestoreOrg{ID: o.ID, Version: deleted.Version})
+			assert.Nil(t, err)
+			s.addOrgToCleanup(restored)
+			assert.Nil(t, restored.DeletedAt)
+			assert.Equal(t, "", restored.DeletedBy)
+			assert.Equal(t, deleted.Version+1, restored.Version)
+			actual, err := s.orgClient.GetByID(ctx, o.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, restored.Name, actual.Name)
+		})
+
+		t.Run("NotDeleted", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-not-deleted-setup-%s", s.reqID))
+			o, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			ctx = context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-not-deleted-%s", s.reqID))
+			actual, err := s.orgClient.Restore(ctx, org.RestoreOrg{ID: o.ID, Version: o.Version})
+			assert.Nil(t, err)
+			assert.Equal(t, o.Version, actual.Version)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-not-found-%s", s.reqID))
+			_, err := s.orgClient.Restore(ctx, org.RestoreOrg{ID: "will-not-find", Version: 1})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-non-admin-jwt-setup-%s", s.reqID))
+			o, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			err = s.orgClient.Delete(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version})
+			assert.Nil(t, err)
+			ctx = context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-non-admin-jwt-%s", s.reqID))
+			_, err = s.nonAdminOrgClient.Restore(ctx, org.RestoreOrg{ID: o.ID, Version: o.Version + 1})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		t.Run("CreateAndUpdate", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("audit-setup-%s", s.reqID))
+			o, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			o.Desc = o.Desc + "-updated"
+			o, err = s.orgClient.Save(ctx, o)
+			s.addOrgToCleanup(o)
+			assert.Nil(t, err)
+			ctx = context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("audit-%s", s.reqID))
+			events, err := s.auditClient.Search(ctx, audit.Query{EntityType: audit.EntityOrg, EntityID: o.ID})
+			assert.Nil(t, err)
+			assert.Len(t, events, 2)
+			if len(events) == 2 {
+				assert.Equal(t, audit.ActionUpdate, events[0].Action)
+				assert.Equal(t, adminUserID, events[0].ActorID)
+				assert.Contains(t, events[0].Diff, "desc")
+				assert.Equal(t, audit.ActionCreate, events[1].Action)
+			}
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("audit-non-admin-jwt-%s", s.reqID))
+			_, err := s.nonAdminAuditClient.Search(ctx, audit.Query{EntityType: audit.EntityOrg})
+			assert.NotNil(t, err)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
 }
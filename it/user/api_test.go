@@ -5,11 +5,14 @@ package user
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -19,7 +22,12 @@ import (
 	"github.com/RyanBard/go-service-ex/internal/httpx"
 	"github.com/RyanBard/go-service-ex/internal/testutil"
 	"github.com/RyanBard/go-service-ex/it/config"
+	"github.com/RyanBard/go-service-ex/pkg/invitation"
+	"github.com/RyanBard/go-service-ex/pkg/membership"
+	"github.com/RyanBard/go-service-ex/pkg/onboarding"
 	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/RyanBard/go-service-ex/pkg/user"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
@@ -36,14 +44,43 @@ const (
 type orgClient interface {
 	Save(ctx context.Context, input org.Org) (org.Org, error)
 	Delete(ctx context.Context, input org.DeleteOrg) error
+	DeleteWithOptions(ctx context.Context, input org.DeleteOrg, opts org.DeleteOrgOptions) error
+	SearchByName(ctx context.Context, name string) ([]org.Org, error)
+}
+
+type onboardingClient interface {
+	Onboard(ctx context.Context, input onboarding.Onboarding) (onboarding.Onboarding, error)
+}
+
+type membershipClient interface {
+	GetByUserID(ctx context.Context, userID string) ([]membership.Membership, error)
+	Add(ctx context.Context, orgID string, input membership.AddMembership) (membership.Membership, error)
+	Remove(ctx context.Context, orgID string, userID string) error
+}
+
+type invitationClient interface {
+	GetByOrgID(ctx context.Context, orgID string, status string) ([]invitation.Invitation, error)
+	Create(ctx context.Context, orgID string, input invitation.CreateInvitation) (invitation.Invitation, error)
+	Resend(ctx context.Context, id string) (invitation.Invitation, error)
+	Revoke(ctx context.Context, id string) error
+	Accept(ctx context.Context, input invitation.AcceptInvitation) (user.User, error)
 }
 
 type userClient interface {
 	GetByID(ctx context.Context, id string) (user.User, error)
+	GetByIDIncludingDeleted(ctx context.Context, id string) (user.User, error)
 	GetAll(ctx context.Context) ([]user.User, error)
+	Search(ctx context.Context, q user.Query) ([]user.User, error)
 	GetAllByOrgID(ctx context.Context, orgID string) ([]user.User, error)
 	Save(ctx context.Context, input user.User) (user.User, error)
+	Patch(ctx context.Context, id string, p patch.Patch) (user.User, error)
 	Delete(ctx context.Context, input user.DeleteUser) error
+	Restore(ctx context.Context, input user.RestoreUser) (user.User, error)
+	RequestEmailChange(ctx context.Context, id string, input user.EmailChange) (user.PendingEmailChange, error)
+	ConfirmEmailChange(ctx context.Context, id string, input user.ConfirmEmailChange) (user.User, error)
+	Batch(ctx context.Context, input user.Batch) (user.BatchResponse, error)
+	Export(ctx context.Context, format string) (string, error)
+	Import(ctx context.Context, format string, body string, dryRun bool) (user.ImportReport, error)
 }
 
 type info struct {
@@ -51,9 +88,15 @@ type info struct {
 	usersToCleanup     map[string]user.DeleteUser
 	testOrg            org.Org
 	orgClient          orgClient
+	onboardingClient   onboardingClient
+	membershipClient   membershipClient
+	orgAdminMSClient   membershipClient
+	invitationClient   invitationClient
+	nonAdminInvClient  invitationClient
 	userClient         userClient
 	invJWTUserClient   userClient
 	nonAdminUserClient userClient
+	orgAdminUserClient userClient
 	log                *slog.Logger
 	reqID              string
 }
@@ -98,6 +141,61 @@ func setupSuite(tb testing.TB) (*info, func(tb testing.TB)) {
 			},
 		),
 	)
+	ui.onboardingClient = onboarding.NewClient(
+		onboarding.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				return ui.getAdminJWT(), nil
+			},
+		),
+	)
+	ui.membershipClient = membership.NewClient(
+		membership.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				return ui.getAdminJWT(), nil
+			},
+		),
+	)
+	ui.orgAdminMSClient = membership.NewClient(
+		membership.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				return ui.getOrgAdminJWT(), nil
+			},
+		),
+	)
+	ui.invitationClient = invitation.NewClient(
+		invitation.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				return ui.getAdminJWT(), nil
+			},
+		),
+	)
+	ui.nonAdminInvClient = invitation.NewClient(
+		invitation.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				return ui.getNonAdminJWT(), nil
+			},
+		),
+	)
 	ui.userClient = user.NewClient(
 		user.Config{
 			BaseURL: cfg.BaseURL,
@@ -131,6 +229,17 @@ func setupSuite(tb testing.TB) (*info, func(tb testing.TB)) {
 			},
 		),
 	)
+	ui.orgAdminUserClient = user.NewClient(
+		user.Config{
+			BaseURL: cfg.BaseURL,
+		},
+		apiclient.NewClient(
+			httpx.NewClient(http.Client{}),
+			func(isRetry bool) (string, error) {
+				return ui.getOrgAdminJWT(), nil
+			},
+		),
+	)
 
 	ctx = context.WithValue(ctx, ctxutil.ContextKeyReqID{}, fmt.Sprintf("user-suite-setup-%s", reqID))
 	testOrg, err := ui.orgClient.Save(ctx, org.Org{
@@ -158,7 +267,8 @@ func setupSuite(tb testing.TB) (*info, func(tb testing.TB)) {
 		}
 		ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("user-teardown-%s", reqID))
 		o := org.DeleteOrg{ID: ui.testOrg.ID, Version: ui.testOrg.Version}
-		err := ui.orgClient.Delete(ctx, o)
+		// cascade so a user that failed to cleanup doesn't leave the org behind
+		err := ui.orgClient.DeleteWithOptions(ctx, o, org.DeleteOrgOptions{Mode: org.DeleteModeCascade})
 		if err != nil {
 			log.With(
 				logutil.LogAttrError(err),
@@ -172,6 +282,74 @@ func (ui *info) addUserToCleanup(u user.User) {
 	ui.usersToCleanup[u.ID] = user.DeleteUser{ID: u.ID, Version: u.Version}
 }
 
+// readEmailChangeToken finds the notification the server's file notifier wrote
+// for the user's email change and pulls the token out of it.
+func (ui *info) readEmailChangeToken(t *testing.T, userID string) string {
+	entries, err := os.ReadDir(ui.config.NotifierDir)
+	if err != nil {
+		t.Fatalf("failed to read the notifier dir: %v", err)
+	}
+	confirmPath := fmt.Sprintf("POST /api/users/%s/email-change/confirm ", userID)
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(ui.config.NotifierDir, e.Name()))
+		if err != nil {
+			t.Fatalf("failed to read the notification: %v", err)
+		}
+		_, rest, found := strings.Cut(string(b), confirmPath)
+		if !found {
+			continue
+		}
+		var body user.ConfirmEmailChange
+		if err := json.NewDecoder(strings.NewReader(rest)).Decode(&body); err != nil {
+			t.Fatalf("failed to parse the notification: %v", err)
+		}
+		return body.Token
+	}
+	t.Fatalf("no notification for user: %s", userID)
+	return ""
+}
+
+// readInvitationToken finds the newest notification the server's file notifier
+// wrote for an invite to the email and pulls the token out of it, a resend
+// writes another one with a new token.
+func (ui *info) readInvitationToken(t *testing.T, email string) string {
+	entries, err := os.ReadDir(ui.config.NotifierDir)
+	if err != nil {
+		t.Fatalf("failed to read the notifier dir: %v", err)
+	}
+	var token string
+	var newest time.Time
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(ui.config.NotifierDir, e.Name()))
+		if err != nil {
+			t.Fatalf("failed to read the notification: %v", err)
+		}
+		if !strings.HasPrefix(string(b), "To: "+email+"\n") {
+			continue
+		}
+		_, rest, found := strings.Cut(string(b), "POST /api/invitations/accept ")
+		if !found {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			t.Fatalf("failed to stat the notification: %v", err)
+		}
+		if token != "" && fi.ModTime().Before(newest) {
+			continue
+		}
+		var body invitation.AcceptInvitation
+		if err := json.NewDecoder(strings.NewReader(rest)).Decode(&body); err != nil {
+			t.Fatalf("failed to parse the notification: %v", err)
+		}
+		token, newest = body.Token, fi.ModTime()
+	}
+	if token == "" {
+		t.Fatalf("no invitation for email: %s", email)
+	}
+	return token
+}
+
 func (ui *info) getAdminJWT() string {
 	claims := ui.getClaims(adminUserID)
 	claims["admin"] = true
@@ -180,6 +358,16 @@ func (ui *info) getAdminJWT() string {
 
 func (ui *info) getNonAdminJWT() string {
 	claims := ui.getClaims(nonAdminUserID)
+	claims["org_id"] = sysOrgID
+	return ui.hmacJWT(claims)
+}
+
+// getOrgAdminJWT is for an admin of the test org, it can't see anything in the
+// system org
+func (ui *info) getOrgAdminJWT() string {
+	claims := ui.getClaims(nonAdminUserID)
+	claims["org_id"] = ui.testOrg.ID
+	claims["role"] = "org_admin"
 	return ui.hmacJWT(claims)
 }
 
@@ -246,6 +434,15 @@ func TestUserAPI(t *testing.T) {
 			assert.LessOrEqual(t, u.CreatedAt, u.UpdatedAt)
 		})
 
+		t.Run("OtherOrgToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("getByID-other-org-jwt-%s", s.reqID))
+			_, err := s.orgAdminUserClient.GetByID(ctx, sysUserID)
+			assert.NotNil(t, err)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+
 		t.Run("InvalidToken", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("getByID-invalid-jwt-%s", s.reqID))
 			_, err := s.invJWTUserClient.GetByID(ctx, sysUserID)
@@ -313,6 +510,60 @@ func TestUserAPI(t *testing.T) {
 		})
 	})
 
+	t.Run("Search", func(t *testing.T) {
+		t.Run("Filters", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("search-filters-%s", s.reqID))
+			isAdmin := true
+			users, err := s.userClient.Search(ctx, user.Query{OrgID: sysOrgID, IsAdmin: &isAdmin, Name: "system ADMIN"})
+			assert.Nil(t, err)
+			found := false
+			for _, u := range users {
+				assert.Equal(t, sysOrgID, u.OrgID)
+				assert.True(t, u.IsAdmin)
+				assert.Contains(t, strings.ToLower(u.Name), "system admin")
+				if u.ID == sysUserID {
+					found = true
+				}
+			}
+			assert.True(t, found)
+		})
+
+		t.Run("NoMatch", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("search-no-match-%s", s.reqID))
+			createdTo := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+			users, err := s.userClient.Search(ctx, user.Query{CreatedTo: &createdTo})
+			assert.Nil(t, err)
+			assert.Len(t, users, 0)
+		})
+
+		t.Run("SortDesc", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("search-sort-desc-%s", s.reqID))
+			users, err := s.userClient.Search(ctx, user.Query{Sort: "-" + user.SortCreatedAt})
+			assert.Nil(t, err)
+			for i := 1; i < len(users); i++ {
+				assert.False(t, users[i].CreatedAt.After(users[i-1].CreatedAt))
+			}
+		})
+
+		t.Run("InvalidSort", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("search-invalid-sort-%s", s.reqID))
+			_, err := s.userClient.Search(ctx, user.Query{Sort: "password"})
+			assert.NotNil(t, err)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+		})
+
+		t.Run("OrgAdminOtherOrg", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("search-org-admin-other-org-%s", s.reqID))
+			_, err := s.orgAdminUserClient.Search(ctx, user.Query{OrgID: sysOrgID})
+			assert.NotNil(t, err)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
 	t.Run("GetAllByOrgID", func(t *testing.T) {
 		t.Run("Found", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("getAllByOrgID-valid-%s", s.reqID))
@@ -425,6 +676,25 @@ func TestUserAPI(t *testing.T) {
 			assert.Equal(t, 400, httpErr.StatusCode)
 		})
 
+		t.Run("InvalidEmail", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("create-invalid-email-%s", s.reqID))
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "not-an-email",
+				OrgID: s.testOrg.ID,
+			})
+			s.addUserToCleanup(u)
+			assert.NotNil(t, err)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+			var validationErr problem.ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, []problem.Violation{
+				{Field: "email", Rule: "email", Message: "email must be a valid email"},
+			}, validationErr.Violations)
+		})
+
 		t.Run("DuplicateEmail", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("create-dup-email-%s", s.reqID))
 			u, err := s.userClient.Save(ctx, user.User{
@@ -700,6 +970,94 @@ func TestUserAPI(t *testing.T) {
 		})
 	})
 
+	t.Run("Patch", func(t *testing.T) {
+		newUser := func(t *testing.T, name string) user.User {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-%s-setup-%s", name, s.reqID))
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:     "Test-" + uuid.NewString(),
+				Email:    "foo+" + uuid.NewString() + "@bar.com",
+				OrgID:    s.testOrg.ID,
+				IsActive: true,
+			})
+			s.addUserToCleanup(u)
+			assert.Nil(t, err)
+			return u
+		}
+
+		t.Run("Merge", func(t *testing.T) {
+			u := newUser(t, "merge")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-merge-%s", s.reqID))
+			u2, err := s.userClient.Patch(ctx, u.ID, patch.Merge(map[string]any{"is_active": false, "version": u.Version}))
+			s.addUserToCleanup(u2)
+			assert.Nil(t, err)
+			assert.False(t, u2.IsActive)
+			assert.Equal(t, u.Name, u2.Name)
+			assert.Equal(t, u.Email, u2.Email)
+			assert.Equal(t, u.Version+1, u2.Version)
+		})
+
+		t.Run("JSON", func(t *testing.T) {
+			u := newUser(t, "json")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-json-%s", s.reqID))
+			u2, err := s.userClient.Patch(ctx, u.ID, patch.JSON(
+				patch.Test("/version", u.Version),
+				patch.Replace("/name", u.Name+"-patched"),
+			))
+			s.addUserToCleanup(u2)
+			assert.Nil(t, err)
+			assert.Equal(t, u.Name+"-patched", u2.Name)
+			assert.True(t, u2.IsActive)
+			assert.Equal(t, u.Version+1, u2.Version)
+		})
+
+		t.Run("TestFailed", func(t *testing.T) {
+			u := newUser(t, "test-failed")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-test-failed-%s", s.reqID))
+			_, err := s.userClient.Patch(ctx, u.ID, patch.JSON(
+				patch.Test("/version", u.Version+1),
+				patch.Replace("/name", u.Name+"-patched"),
+			))
+			assert.True(t, problem.HasCode(err, problem.CodePatchTestFailed))
+		})
+
+		t.Run("OptimisticLock", func(t *testing.T) {
+			u := newUser(t, "opt-lock")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-opt-lock-%s", s.reqID))
+			_, err := s.userClient.Patch(ctx, u.ID, patch.Merge(map[string]any{"name": u.Name + "-patched", "version": u.Version + 1}))
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+		})
+
+		t.Run("ReadOnly", func(t *testing.T) {
+			u := newUser(t, "read-only")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-read-only-%s", s.reqID))
+			_, err := s.userClient.Patch(ctx, u.ID, patch.JSON(patch.Replace("/email", "baz+"+uuid.NewString()+"@bar.com")))
+			var validationErr problem.ValidationError
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, []problem.Violation{
+				{Field: "email", Rule: "readonly", Message: "email is read only"},
+			}, validationErr.Violations)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-not-found-%s", s.reqID))
+			_, err := s.userClient.Patch(ctx, uuid.NewString(), patch.Merge(map[string]any{"name": "foo"}))
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			u := newUser(t, "non-admin")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("patch-non-admin-%s", s.reqID))
+			_, err := s.nonAdminUserClient.Patch(ctx, u.ID, patch.Merge(map[string]any{"is_admin": true}))
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		t.Run("Valid", func(t *testing.T) {
 			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("delete-valid-setup-%s", s.reqID))
@@ -823,4 +1181,741 @@ func TestUserAPI(t *testing.T) {
 			assert.Equal(t, 401, httpErr.StatusCode)
 		})
 	})
+
+	t.Run("Restore", func(t *testing.T) {
+		t.Run("Valid", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-valid-setup-%s", s.reqID))
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "foo+" + uuid.NewString() + "@bar.com",
+				OrgID: s.testOrg.ID,
+			})
+			s.addUserToCleanup(u)
+			assert.Nil(t, err)
+			err = s.userClient.Delete(ctx, user.DeleteUser{ID: u.ID, Version: u.Version})
+			assert.Nil(t, err)
+			_, err = s.userClient.GetByID(ctx, u.ID)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+			deleted, err := s.userClient.GetByIDIncludingDeleted(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.NotNil(t, deleted.DeletedAt)
+			assert.Equal(t, adminUserID, deleted.DeletedBy)
+			ctx = context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-valid-%s", s.reqID))
+			restored, err := s.userClient.Restore(ctx, user.RestoreUser{ID: u.ID, Version: deleted.Version})
+			assert.Nil(t, err)
+			s.addUserToCleanup(restored)
+			assert.Nil(t, restored.DeletedAt)
+			assert.Equal(t, deleted.Version+1, restored.Version)
+			actual, err := s.userClient.GetByID(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, restored.Email, actual.Email)
+		})
+
+		t.Run("NotFound", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-not-found-%s", s.reqID))
+			_, err := s.userClient.Restore(ctx, user.RestoreUser{ID: "will-not-find", Version: 1})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-non-admin-jwt-setup-%s", s.reqID))
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "foo+" + uuid.NewString() + "@bar.com",
+				OrgID: s.testOrg.ID,
+			})
+			s.addUserToCleanup(u)
+			assert.Nil(t, err)
+			err = s.userClient.Delete(ctx, user.DeleteUser{ID: u.ID, Version: u.Version})
+			assert.Nil(t, err)
+			ctx = context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("restore-non-admin-jwt-%s", s.reqID))
+			_, err = s.nonAdminUserClient.Restore(ctx, user.RestoreUser{ID: u.ID, Version: u.Version + 1})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("EmailChange", func(t *testing.T) {
+		setupUser := func(t *testing.T, reqIDPrefix string) user.User {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("%s-setup-%s", reqIDPrefix, s.reqID))
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "foo+" + uuid.NewString() + "@bar.com",
+				OrgID: s.testOrg.ID,
+			})
+			s.addUserToCleanup(u)
+			assert.Nil(t, err)
+			return u
+		}
+
+		t.Run("Valid", func(t *testing.T) {
+			if s.config.NotifierDir == "" {
+				t.Skip("IT_NOTIFIER_DIR isn't set, the token can't be read back")
+			}
+			u := setupUser(t, "email-change-valid")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("email-change-valid-%s", s.reqID))
+			newEmail := "new+" + uuid.NewString() + "@bar.com"
+			pec, err := s.userClient.RequestEmailChange(ctx, u.ID, user.EmailChange{Email: newEmail})
+			assert.Nil(t, err)
+			assert.Equal(t, newEmail, pec.Email)
+			// not changed until it's confirmed
+			actual, err := s.userClient.GetByID(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, u.Email, actual.Email)
+			token := s.readEmailChangeToken(t, u.ID)
+			confirmed, err := s.userClient.ConfirmEmailChange(ctx, u.ID, user.ConfirmEmailChange{Token: token})
+			assert.Nil(t, err)
+			s.addUserToCleanup(confirmed)
+			assert.Equal(t, newEmail, confirmed.Email)
+			assert.Equal(t, u.Version+1, confirmed.Version)
+			// single use
+			_, err = s.userClient.ConfirmEmailChange(ctx, u.ID, user.ConfirmEmailChange{Token: token})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("InvalidToken", func(t *testing.T) {
+			u := setupUser(t, "email-change-invalid-token")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("email-change-invalid-token-%s", s.reqID))
+			_, err := s.userClient.RequestEmailChange(ctx, u.ID, user.EmailChange{Email: "new+" + uuid.NewString() + "@bar.com"})
+			assert.Nil(t, err)
+			_, err = s.userClient.ConfirmEmailChange(ctx, u.ID, user.ConfirmEmailChange{Token: "will-not-match"})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+		})
+
+		t.Run("NoPending", func(t *testing.T) {
+			u := setupUser(t, "email-change-no-pending")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("email-change-no-pending-%s", s.reqID))
+			_, err := s.userClient.ConfirmEmailChange(ctx, u.ID, user.ConfirmEmailChange{Token: "will-not-match"})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("InvalidEmail", func(t *testing.T) {
+			u := setupUser(t, "email-change-invalid-email")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("email-change-invalid-email-%s", s.reqID))
+			_, err := s.userClient.RequestEmailChange(ctx, u.ID, user.EmailChange{Email: "not-an-email"})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			u := setupUser(t, "email-change-non-admin-jwt")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("email-change-non-admin-jwt-%s", s.reqID))
+			_, err := s.nonAdminUserClient.RequestEmailChange(ctx, u.ID, user.EmailChange{Email: "new+" + uuid.NewString() + "@bar.com"})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("Memberships", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-setup-%s", s.reqID))
+		u, err := s.userClient.Save(ctx, user.User{
+			Name:  "Test-" + uuid.NewString(),
+			Email: "foo+" + uuid.NewString() + "@bar.com",
+			OrgID: s.testOrg.ID,
+		})
+		assert.Nil(t, err)
+		s.addUserToCleanup(u)
+		otherOrg, err := s.orgClient.Save(ctx, org.Org{
+			Name: "Test-" + uuid.NewString(),
+			Desc: "Integration Test",
+		})
+		assert.Nil(t, err)
+		defer func() {
+			err := s.orgClient.Delete(ctx, org.DeleteOrg{ID: otherOrg.ID, Version: otherOrg.Version})
+			assert.Nil(t, err)
+		}()
+
+		t.Run("Add", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-add-%s", s.reqID))
+			m, err := s.membershipClient.Add(ctx, otherOrg.ID, membership.AddMembership{UserID: u.ID})
+			assert.Nil(t, err)
+			assert.Equal(t, membership.RoleMember, m.Role)
+			assert.False(t, m.IsHome)
+			users, err := s.userClient.GetAllByOrgID(ctx, otherOrg.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(users))
+			assert.Equal(t, u.ID, users[0].ID)
+			// the user's own org is unchanged
+			assert.Equal(t, s.testOrg.ID, users[0].OrgID)
+			memberships, err := s.membershipClient.GetByUserID(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(memberships))
+		})
+
+		t.Run("MemberReadsOtherOrg", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-member-reads-other-org-%s", s.reqID))
+			// a plain member whose token only has their own org
+			claims := s.getClaims(u.ID)
+			claims["org_id"] = s.testOrg.ID
+			memberJWT := s.hmacJWT(claims)
+			memberClient := user.NewClient(
+				user.Config{
+					BaseURL: s.config.BaseURL,
+				},
+				apiclient.NewClient(
+					httpx.NewClient(http.Client{}),
+					func(isRetry bool) (string, error) {
+						return memberJWT, nil
+					},
+				),
+			)
+			users, err := memberClient.Search(ctx, user.Query{OrgID: otherOrg.ID})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(users))
+			users, err = memberClient.GetAllByOrgID(ctx, otherOrg.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(users))
+			_, err = memberClient.Search(ctx, user.Query{OrgID: sysOrgID})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+
+		t.Run("AlreadyMember", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-already-member-%s", s.reqID))
+			_, err := s.membershipClient.Add(ctx, otherOrg.ID, membership.AddMembership{UserID: u.ID})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+		})
+
+		t.Run("OrgAdminOtherOrg", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-org-admin-other-org-%s", s.reqID))
+			err := s.orgAdminMSClient.Remove(ctx, otherOrg.ID, u.ID)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+
+		t.Run("RemoveHome", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-remove-home-%s", s.reqID))
+			err := s.membershipClient.Remove(ctx, s.testOrg.ID, u.ID)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+		})
+
+		t.Run("Remove", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-remove-%s", s.reqID))
+			err := s.membershipClient.Remove(ctx, otherOrg.ID, u.ID)
+			assert.Nil(t, err)
+			users, err := s.userClient.GetAllByOrgID(ctx, otherOrg.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(users))
+			// already gone
+			err = s.membershipClient.Remove(ctx, otherOrg.ID, u.ID)
+			assert.Nil(t, err)
+		})
+
+		t.Run("UserNotFound", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("memberships-user-not-found-%s", s.reqID))
+			_, err := s.membershipClient.Add(ctx, otherOrg.ID, membership.AddMembership{UserID: "will-not-find"})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("Invitations", func(t *testing.T) {
+		newEmail := func() string {
+			return "invited+" + uuid.NewString() + "@bar.com"
+		}
+
+		t.Run("AcceptNewUser", func(t *testing.T) {
+			if s.config.NotifierDir == "" {
+				t.Skip("IT_NOTIFIER_DIR isn't set, the token can't be read back")
+			}
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-accept-new-%s", s.reqID))
+			email := newEmail()
+			i, err := s.invitationClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: email})
+			assert.Nil(t, err)
+			assert.Equal(t, invitation.StatusPending, i.Status)
+			assert.Equal(t, invitation.RoleMember, i.Role)
+			token := s.readInvitationToken(t, email)
+			// a new user needs a name
+			_, err = s.invitationClient.Accept(ctx, invitation.AcceptInvitation{Token: token})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+			u, err := s.invitationClient.Accept(ctx, invitation.AcceptInvitation{Token: token, Name: "Test-" + uuid.NewString()})
+			assert.Nil(t, err)
+			s.addUserToCleanup(u)
+			assert.Equal(t, email, u.Email)
+			assert.Equal(t, s.testOrg.ID, u.OrgID)
+			assert.True(t, u.IsActive)
+			assert.False(t, u.IsAdmin)
+			invitations, err := s.invitationClient.GetByOrgID(ctx, s.testOrg.ID, invitation.StatusAccepted)
+			assert.Nil(t, err)
+			found := false
+			for _, actual := range invitations {
+				if actual.ID == i.ID {
+					found = true
+					assert.Equal(t, u.ID, actual.AcceptedBy)
+				}
+			}
+			assert.True(t, found)
+			// single use
+			_, err = s.invitationClient.Accept(ctx, invitation.AcceptInvitation{Token: token})
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 410, httpErr.StatusCode)
+			// accepted invites can't be revoked
+			err = s.invitationClient.Revoke(ctx, i.ID)
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+		})
+
+		t.Run("AcceptExistingUserOtherOrg", func(t *testing.T) {
+			if s.config.NotifierDir == "" {
+				t.Skip("IT_NOTIFIER_DIR isn't set, the token can't be read back")
+			}
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-accept-existing-%s", s.reqID))
+			otherOrg, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			assert.Nil(t, err)
+			defer func() {
+				err := s.orgClient.Delete(ctx, org.DeleteOrg{ID: otherOrg.ID, Version: otherOrg.Version})
+				assert.Nil(t, err)
+			}()
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: newEmail(),
+				OrgID: s.testOrg.ID,
+			})
+			assert.Nil(t, err)
+			s.addUserToCleanup(u)
+			_, err = s.invitationClient.Create(ctx, otherOrg.ID, invitation.CreateInvitation{Email: u.Email, Role: invitation.RoleOrgAdmin})
+			assert.Nil(t, err)
+			token := s.readInvitationToken(t, u.Email)
+			actual, err := s.invitationClient.Accept(ctx, invitation.AcceptInvitation{Token: token})
+			assert.Nil(t, err)
+			// the user keeps their own org, and is_active is up to its admins
+			assert.Equal(t, u.ID, actual.ID)
+			assert.Equal(t, s.testOrg.ID, actual.OrgID)
+			assert.Equal(t, u.IsActive, actual.IsActive)
+			memberships, err := s.membershipClient.GetByUserID(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(memberships))
+			for _, m := range memberships {
+				if m.OrgID == otherOrg.ID {
+					assert.Equal(t, membership.RoleOrgAdmin, m.Role)
+				}
+			}
+			err = s.membershipClient.Remove(ctx, otherOrg.ID, u.ID)
+			assert.Nil(t, err)
+		})
+
+		t.Run("Resend", func(t *testing.T) {
+			if s.config.NotifierDir == "" {
+				t.Skip("IT_NOTIFIER_DIR isn't set, the token can't be read back")
+			}
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-resend-%s", s.reqID))
+			email := newEmail()
+			i, err := s.invitationClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: email})
+			assert.Nil(t, err)
+			oldToken := s.readInvitationToken(t, email)
+			// so the new notification is always the newest one
+			time.Sleep(10 * time.Millisecond)
+			resent, err := s.invitationClient.Resend(ctx, i.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, invitation.StatusPending, resent.Status)
+			assert.False(t, resent.ExpiresAt.Before(i.ExpiresAt))
+			token := s.readInvitationToken(t, email)
+			assert.NotEqual(t, oldToken, token)
+			_, err = s.invitationClient.Accept(ctx, invitation.AcceptInvitation{Token: oldToken, Name: "Test-" + uuid.NewString()})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+			err = s.invitationClient.Revoke(ctx, i.ID)
+			assert.Nil(t, err)
+		})
+
+		t.Run("Revoke", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-revoke-%s", s.reqID))
+			email := newEmail()
+			i, err := s.invitationClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: email})
+			assert.Nil(t, err)
+			err = s.invitationClient.Revoke(ctx, i.ID)
+			assert.Nil(t, err)
+			// already revoked
+			err = s.invitationClient.Revoke(ctx, i.ID)
+			assert.Nil(t, err)
+			invitations, err := s.invitationClient.GetByOrgID(ctx, s.testOrg.ID, invitation.StatusRevoked)
+			assert.Nil(t, err)
+			found := false
+			for _, actual := range invitations {
+				found = found || actual.ID == i.ID
+			}
+			assert.True(t, found)
+			// a revoked invite doesn't block a new one
+			again, err := s.invitationClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: email})
+			assert.Nil(t, err)
+			err = s.invitationClient.Revoke(ctx, again.ID)
+			assert.Nil(t, err)
+		})
+
+		t.Run("AlreadyInvited", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-already-invited-%s", s.reqID))
+			email := newEmail()
+			i, err := s.invitationClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: email})
+			assert.Nil(t, err)
+			_, err = s.invitationClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: email})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+			err = s.invitationClient.Revoke(ctx, i.ID)
+			assert.Nil(t, err)
+		})
+
+		t.Run("InvalidToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-invalid-token-%s", s.reqID))
+			_, err := s.invitationClient.Accept(ctx, invitation.AcceptInvitation{Token: "will-not-match", Name: "Test-" + uuid.NewString()})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("SysOrg", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-sys-org-%s", s.reqID))
+			_, err := s.invitationClient.Create(ctx, sysOrgID, invitation.CreateInvitation{Email: newEmail()})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("invitations-non-admin-jwt-%s", s.reqID))
+			_, err := s.nonAdminInvClient.Create(ctx, s.testOrg.ID, invitation.CreateInvitation{Email: newEmail()})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 403, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("DeleteOrg", func(t *testing.T) {
+		setupOrgWithUser := func(t *testing.T, reqIDPrefix string) (org.Org, user.User) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("%s-setup-%s", reqIDPrefix, s.reqID))
+			o, err := s.orgClient.Save(ctx, org.Org{
+				Name: "Test-" + uuid.NewString(),
+				Desc: "Integration Test",
+			})
+			assert.Nil(t, err)
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "foo+" + uuid.NewString() + "@bar.com",
+				OrgID: o.ID,
+			})
+			assert.Nil(t, err)
+			return o, u
+		}
+
+		t.Run("HasUsers", func(t *testing.T) {
+			o, u := setupOrgWithUser(t, "delete-org-has-users")
+			s.addUserToCleanup(u)
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("delete-org-has-users-%s", s.reqID))
+			err := s.orgClient.Delete(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+			err = s.userClient.Delete(ctx, user.DeleteUser{ID: u.ID, Version: u.Version})
+			assert.Nil(t, err)
+			err = s.orgClient.Delete(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version})
+			assert.Nil(t, err)
+		})
+
+		t.Run("Cascade", func(t *testing.T) {
+			o, u := setupOrgWithUser(t, "delete-org-cascade")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("delete-org-cascade-%s", s.reqID))
+			err := s.orgClient.DeleteWithOptions(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version}, org.DeleteOrgOptions{Mode: org.DeleteModeCascade})
+			assert.Nil(t, err)
+			deleted, err := s.userClient.GetByIDIncludingDeleted(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.NotNil(t, deleted.DeletedAt)
+		})
+
+		t.Run("ReassignTo", func(t *testing.T) {
+			o, u := setupOrgWithUser(t, "delete-org-reassign")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("delete-org-reassign-%s", s.reqID))
+			err := s.orgClient.DeleteWithOptions(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version}, org.DeleteOrgOptions{ReassignTo: s.testOrg.ID})
+			assert.Nil(t, err)
+			moved, err := s.userClient.GetByID(ctx, u.ID)
+			assert.Nil(t, err)
+			s.addUserToCleanup(moved)
+			assert.Equal(t, s.testOrg.ID, moved.OrgID)
+			assert.Equal(t, u.Version+1, moved.Version)
+			memberships, err := s.membershipClient.GetByUserID(ctx, u.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(memberships))
+			assert.Equal(t, s.testOrg.ID, memberships[0].OrgID)
+			assert.True(t, memberships[0].IsHome)
+		})
+
+		t.Run("ReassignToNotFound", func(t *testing.T) {
+			o, u := setupOrgWithUser(t, "delete-org-reassign-not-found")
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("delete-org-reassign-not-found-%s", s.reqID))
+			err := s.orgClient.DeleteWithOptions(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version}, org.DeleteOrgOptions{ReassignTo: "will-not-find"})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+			err = s.orgClient.DeleteWithOptions(ctx, org.DeleteOrg{ID: o.ID, Version: o.Version}, org.DeleteOrgOptions{Mode: org.DeleteModeCascade})
+			assert.Nil(t, err)
+			s.addUserToCleanup(u)
+		})
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		t.Run("AllOrNothing", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("batch-all-or-nothing-%s", s.reqID))
+			existing, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "foo+" + uuid.NewString() + "@bar.com",
+				OrgID: s.testOrg.ID,
+			})
+			assert.Nil(t, err)
+			br, err := s.userClient.Batch(ctx, user.Batch{
+				Mode: user.BatchModeAllOrNothing,
+				Ops: []user.BatchOp{
+					{Op: user.BatchOpCreate, User: user.User{Name: "Test-" + uuid.NewString(), Email: "foo+" + uuid.NewString() + "@bar.com", OrgID: s.testOrg.ID}},
+					{Op: user.BatchOpCreate, User: user.User{Name: "Test-" + uuid.NewString(), Email: "foo+" + uuid.NewString() + "@bar.com", OrgID: s.testOrg.ID}},
+					{Op: user.BatchOpDelete, User: user.User{ID: existing.ID, Version: existing.Version}},
+				},
+			})
+			assert.Nil(t, err)
+			assert.Len(t, br.Results, 3)
+			for _, r := range br.Results[:2] {
+				assert.Equal(t, 200, r.Status)
+				s.addUserToCleanup(*r.User)
+			}
+			assert.Equal(t, 204, br.Results[2].Status)
+			_, err = s.userClient.GetByID(ctx, existing.ID)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 404, httpErr.StatusCode)
+		})
+
+		t.Run("AllOrNothingRollsBack", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("batch-all-or-nothing-rollback-%s", s.reqID))
+			email := "foo+" + uuid.NewString() + "@bar.com"
+			_, err := s.userClient.Batch(ctx, user.Batch{
+				Mode: user.BatchModeAllOrNothing,
+				Ops: []user.BatchOp{
+					{Op: user.BatchOpCreate, User: user.User{Name: "Test-" + uuid.NewString(), Email: email, OrgID: s.testOrg.ID}},
+					{Op: user.BatchOpCreate, User: user.User{Name: "Test-" + uuid.NewString(), Email: email, OrgID: s.testOrg.ID}},
+				},
+			})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+			users, err := s.userClient.GetAllByOrgID(ctx, s.testOrg.ID)
+			assert.Nil(t, err)
+			for _, u := range users {
+				assert.NotEqual(t, email, u.Email)
+			}
+		})
+
+		t.Run("BestEffort", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("batch-best-effort-%s", s.reqID))
+			br, err := s.userClient.Batch(ctx, user.Batch{
+				Mode: user.BatchModeBestEffort,
+				Ops: []user.BatchOp{
+					{Op: user.BatchOpCreate, User: user.User{Name: "Test-" + uuid.NewString(), Email: "foo+" + uuid.NewString() + "@bar.com", OrgID: s.testOrg.ID}},
+					{Op: user.BatchOpDelete, User: user.User{ID: sysUserID, Version: 1}},
+				},
+			})
+			assert.Nil(t, err)
+			assert.Len(t, br.Results, 2)
+			assert.Equal(t, 200, br.Results[0].Status)
+			s.addUserToCleanup(*br.Results[0].User)
+			assert.Equal(t, 403, br.Results[1].Status)
+		})
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		t.Run("CSV", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-csv-%s", s.reqID))
+			u, err := s.userClient.Save(ctx, user.User{
+				Name:  "Test-" + uuid.NewString(),
+				Email: "foo+" + uuid.NewString() + "@bar.com",
+				OrgID: s.testOrg.ID,
+			})
+			assert.Nil(t, err)
+			s.addUserToCleanup(u)
+			out, err := s.userClient.Export(ctx, "csv")
+			assert.Nil(t, err)
+			assert.True(t, strings.HasPrefix(out, "id,org_id,name,email,"))
+			assert.Contains(t, out, u.Email)
+		})
+
+		t.Run("NDJSON", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-ndjson-%s", s.reqID))
+			out, err := s.userClient.Export(ctx, "ndjson")
+			assert.Nil(t, err)
+			assert.Contains(t, out, sysUserID)
+		})
+
+		t.Run("OrgAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-org-admin-%s", s.reqID))
+			out, err := s.orgAdminUserClient.Export(ctx, "ndjson")
+			assert.Nil(t, err)
+			// only their own org
+			assert.NotContains(t, out, sysUserID)
+		})
+
+		t.Run("InvalidFormat", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("export-invalid-format-%s", s.reqID))
+			_, err := s.userClient.Export(ctx, "xml")
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+		})
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		t.Run("DryRun", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("import-dry-run-%s", s.reqID))
+			email := "foo+" + uuid.NewString() + "@bar.com"
+			body := "org_id,name,email\n" +
+				s.testOrg.ID + ",Test-" + uuid.NewString() + "," + email + "\n" +
+				s.testOrg.ID + ",Test-" + uuid.NewString() + "," + email + "\n" +
+				sysOrgID + ",Test-" + uuid.NewString() + ",foo+" + uuid.NewString() + "@bar.com\n"
+			ir, err := s.userClient.Import(ctx, "csv", body, true)
+			assert.Nil(t, err)
+			assert.True(t, ir.DryRun)
+			assert.Equal(t, 3, ir.Rows)
+			assert.Equal(t, 1, ir.Imported)
+			assert.Len(t, ir.Errors, 2)
+			for i, r := range ir.Errors {
+				// a duplicate within the file, then the system org
+				assert.Equal(t, []int{2, 3}[i], r.Row)
+				assert.Equal(t, []int{409, 403}[i], r.Status)
+			}
+			users, err := s.userClient.GetAllByOrgID(ctx, s.testOrg.ID)
+			assert.Nil(t, err)
+			for _, u := range users {
+				assert.NotEqual(t, email, u.Email)
+			}
+		})
+
+		t.Run("Valid", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("import-valid-%s", s.reqID))
+			email := "foo+" + uuid.NewString() + "@bar.com"
+			body := fmt.Sprintf(`{"org_id":"%s","name":"Test-%s","email":"%s","is_active":true}`+"\n"+`{"org_id":"%s","name":"Test-%s"}`+"\n",
+				s.testOrg.ID, uuid.NewString(), email, s.testOrg.ID, uuid.NewString())
+			ir, err := s.userClient.Import(ctx, "ndjson", body, false)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, ir.Rows)
+			assert.Equal(t, 1, ir.Imported)
+			assert.Len(t, ir.Errors, 1)
+			assert.Equal(t, 2, ir.Errors[0].Row)
+			assert.Equal(t, 400, ir.Errors[0].Status)
+			users, err := s.userClient.GetAllByOrgID(ctx, s.testOrg.ID)
+			assert.Nil(t, err)
+			found := false
+			for _, u := range users {
+				if u.Email == email {
+					found = true
+					assert.True(t, u.IsActive)
+					s.addUserToCleanup(u)
+				}
+			}
+			assert.True(t, found)
+		})
+
+		t.Run("MissingColumn", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("import-missing-column-%s", s.reqID))
+			_, err := s.userClient.Import(ctx, "csv", "name,email\nfoo,foo@bar.com\n", true)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+		})
+
+		t.Run("NonAdminToken", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("import-non-admin-%s", s.reqID))
+			body := "org_id,name,email\n" + s.testOrg.ID + ",Test-" + uuid.NewString() + ",foo+" + uuid.NewString() + "@bar.com\n"
+			ir, err := s.nonAdminUserClient.Import(ctx, "csv", body, true)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, ir.Imported)
+			assert.Len(t, ir.Errors, 1)
+			assert.Equal(t, 403, ir.Errors[0].Status)
+		})
+	})
+
+	t.Run("Onboarding", func(t *testing.T) {
+		t.Run("Valid", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("onboarding-valid-%s", s.reqID))
+			input := onboarding.Onboarding{
+				Org: org.Org{
+					Name: "Test-" + uuid.NewString(),
+					Desc: "Integration Test",
+				},
+				Users: []user.User{
+					{Name: "Test-" + uuid.NewString(), Email: "foo+" + uuid.NewString() + "@bar.com", IsAdmin: true},
+					{Name: "Test-" + uuid.NewString(), Email: "foo+" + uuid.NewString() + "@bar.com"},
+				},
+			}
+			actual, err := s.onboardingClient.Onboard(ctx, input)
+			assert.Nil(t, err)
+			assert.NotEqual(t, "", actual.Org.ID)
+			assert.Equal(t, input.Org.Name, actual.Org.Name)
+			assert.Len(t, actual.Users, 2)
+			for _, u := range actual.Users {
+				assert.Equal(t, actual.Org.ID, u.OrgID)
+			}
+			users, err := s.userClient.GetAllByOrgID(ctx, actual.Org.ID)
+			assert.Nil(t, err)
+			assert.Len(t, users, 2)
+			o := org.DeleteOrg{ID: actual.Org.ID, Version: actual.Org.Version}
+			err = s.orgClient.DeleteWithOptions(ctx, o, org.DeleteOrgOptions{Mode: org.DeleteModeCascade})
+			assert.Nil(t, err)
+		})
+
+		t.Run("DuplicateEmailRollsBack", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("onboarding-dup-email-%s", s.reqID))
+			email := "foo+" + uuid.NewString() + "@bar.com"
+			input := onboarding.Onboarding{
+				Org: org.Org{
+					Name: "Test-" + uuid.NewString(),
+					Desc: "Integration Test",
+				},
+				Users: []user.User{
+					{Name: "Test-" + uuid.NewString(), Email: email},
+					{Name: "Test-" + uuid.NewString(), Email: email},
+				},
+			}
+			_, err := s.onboardingClient.Onboard(ctx, input)
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 409, httpErr.StatusCode)
+			orgs, err := s.orgClient.SearchByName(ctx, input.Org.Name)
+			assert.Nil(t, err)
+			assert.Len(t, orgs, 0)
+		})
+
+		t.Run("NoUsers", func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, fmt.Sprintf("onboarding-no-users-%s", s.reqID))
+			_, err := s.onboardingClient.Onboard(ctx, onboarding.Onboarding{
+				Org: org.Org{
+					Name: "Test-" + uuid.NewString(),
+					Desc: "Integration Test",
+				},
+			})
+			var httpErr httpx.HTTPError
+			assert.True(t, errors.As(err, &httpErr))
+			assert.Equal(t, 400, httpErr.StatusCode)
+		})
+	})
 }
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+	"time"
+
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+)
+
+type Config struct {
+	BaseURL string
+}
+
+type auditClient struct {
+	cfg Config
+	ac  *apiclient.Client
+}
+
+func NewClient(cfg Config, ac *apiclient.Client) *auditClient {
+	return &auditClient{
+		cfg: cfg,
+		ac:  ac,
+	}
+}
+
+// Search follows next_cursor until every page has been retrieved, use Iter
+// when the result set is too large to hold in memory.
+func (ac *auditClient) Search(ctx context.Context, q Query) (e []Event, err error) {
+	e = []Event{}
+	for event, err := range ac.Iter(ctx, q, 0) {
+		if err != nil {
+			return e, err
+		}
+		e = append(e, event)
+	}
+	return e, nil
+}
+
+func (ac *auditClient) SearchPage(ctx context.Context, q Query, limit int, cursor string) (ep EventPage, err error) {
+	path := fmt.Sprintf("%s/api/audit", ac.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := pageQueryParams(limit, cursor)
+	addQueryParam(queryParams, "entity_type", q.EntityType)
+	addQueryParam(queryParams, "entity_id", q.EntityID)
+	addQueryParam(queryParams, "actor_id", q.ActorID)
+	if q.From != nil {
+		addQueryParam(queryParams, "from", q.From.Format(time.RFC3339Nano))
+	}
+	if q.To != nil {
+		addQueryParam(queryParams, "to", q.To.Format(time.RFC3339Nano))
+	}
+	err = ac.ac.Get(ctx, path, pathParams, queryParams, &ep)
+	return ep, err
+}
+
+func (ac *auditClient) Iter(ctx context.Context, q Query, limit int) iter.Seq2[Event, error] {
+	return iterPages(func(cursor string) (EventPage, error) {
+		return ac.SearchPage(ctx, q, limit, cursor)
+	})
+}
+
+func addQueryParam(queryParams map[string][]string, name string, value string) {
+	if value != "" {
+		queryParams[name] = []string{value}
+	}
+}
+
+func pageQueryParams(limit int, cursor string) map[string][]string {
+	queryParams := map[string][]string{}
+	if limit > 0 {
+		queryParams["limit"] = []string{strconv.Itoa(limit)}
+	}
+	if cursor != "" {
+		queryParams["cursor"] = []string{cursor}
+	}
+	return queryParams
+}
+
+// iterPages yields every event across pages, a failed page fetch is yielded
+// once as an error and ends the iteration.
+func iterPages(getPage func(cursor string) (EventPage, error)) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		cursor := ""
+		for {
+			ep, err := getPage(cursor)
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+			for _, e := range ep.Events {
+				if !yield(e, nil) {
+					return
+				}
+			}
+			if ep.NextCursor == "" {
+				return
+			}
+			cursor = ep.NextCursor
+		}
+	}
+}
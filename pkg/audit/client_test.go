@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+func initClient(getToken func(isRetry bool) (string, error), f func(w http.ResponseWriter, r *http.Request)) (*auditClient, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(f))
+	cfg := Config{
+		BaseURL: server.URL,
+	}
+	client := NewClient(cfg, apiclient.NewClient(httpx.NewClient(http.Client{}), getToken))
+	return client, server
+}
+
+func bearer(s string) string {
+	return fmt.Sprintf("Bearer %s", s)
+}
+
+func TestSearch(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte{}, b)
+		assert.Equal(t, "/api/audit", r.URL.Path)
+		assert.Equal(t, "user", r.URL.Query().Get("entity_type"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"events":[{"id":"test-event-id","diff":{"name":{"before":"foo","after":"bar"}}}],"next_cursor":"test-cursor"}`))
+		} else {
+			w.Write([]byte(`{"events":[{"id":"test-event-id-2"}]}`))
+		}
+	})
+	e, err := client.Search(ctx, Query{EntityType: EntityUser})
+	assert.Nil(t, err)
+	assert.Equal(t, []Event{
+		{ID: "test-event-id", Diff: Diff{"name": {Before: "foo", After: "bar"}}},
+		{ID: "test-event-id-2"},
+	}, e)
+}
+
+func TestSearchPage(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	from := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	to := from.Add(time.Hour)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/audit", r.URL.Path)
+		assert.Equal(t, "actor_id=actor-id&cursor=test-cursor&entity_id=entity-id&entity_type=org&from=2024-01-02T03%3A04%3A05Z&limit=10&to=2024-01-02T04%3A04%3A05Z", r.URL.RawQuery)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"events":[{"id":"test-event-id"}],"next_cursor":"next-cursor"}`))
+	})
+	ep, err := client.SearchPage(ctx, Query{
+		EntityType: EntityOrg,
+		EntityID:   "entity-id",
+		ActorID:    "actor-id",
+		From:       &from,
+		To:         &to,
+	}, 10, "test-cursor")
+	assert.Nil(t, err)
+	assert.Equal(t, EventPage{Events: []Event{{ID: "test-event-id"}}, NextCursor: "next-cursor"}, ep)
+}
+
+func TestSearch_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+	})
+	e, err := client.Search(ctx, Query{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Len(t, e, 0)
+}
+
+func TestDiffValue(t *testing.T) {
+	v, err := Diff{"name": {Before: nil, After: "foo"}}.Value()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":{"before":null,"after":"foo"}}`, v)
+}
+
+func TestDiffScan(t *testing.T) {
+	var d Diff
+	err := d.Scan([]byte(`{"name":{"before":"foo","after":null}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, Diff{"name": {Before: "foo", After: nil}}, d)
+}
+
+func TestDiffScan_UnsupportedType(t *testing.T) {
+	var d Diff
+	err := d.Scan(1)
+	assert.NotNil(t, err)
+}
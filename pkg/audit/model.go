@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+const (
+	EntityOrg  = "org"
+	EntityUser = "user"
+	// EntityMembership events have "<orgID>/<userID>" as their entity id
+	EntityMembership = "membership"
+	EntityInvitation = "invitation"
+)
+
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Diff is keyed by the json name of each field that changed.
+type Diff map[string]Change
+
+func (d Diff) Value() (driver.Value, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	// lib/pq sends []byte as bytea, jsonb needs it as text
+	return string(b), nil
+}
+
+func (d *Diff) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, d)
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	case nil:
+		*d = nil
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into audit.Diff", src)
+	}
+}
+
+type Event struct {
+	ID         string    `json:"id" db:"id"`
+	OccurredAt time.Time `json:"occurred_at" db:"occurred_at"`
+	ActorID    string    `json:"actor_id" db:"actor_id"`
+	ReqID      string    `json:"req_id" db:"req_id"`
+	Action     string    `json:"action" db:"action"`
+	EntityType string    `json:"entity_type" db:"entity_type"`
+	EntityID   string    `json:"entity_id" db:"entity_id"`
+	Diff       Diff      `json:"diff" db:"diff"`
+}
+
+// Query narrows GET /api/audit, the zero value matches everything.
+type Query struct {
+	EntityType string
+	EntityID   string
+	ActorID    string
+	From       *time.Time
+	To         *time.Time
+}
+
+type EventPage struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,133 @@
+package invitation
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
+
+type Config struct {
+	BaseURL string
+}
+
+type invitationClient struct {
+	cfg Config
+	ac  *apiclient.Client
+}
+
+func NewClient(cfg Config, ac *apiclient.Client) *invitationClient {
+	return &invitationClient{
+		cfg: cfg,
+		ac:  ac,
+	}
+}
+
+// GetByOrgID follows next_cursor until every page has been retrieved, an
+// empty status returns the invitations in every status.
+func (ic *invitationClient) GetByOrgID(ctx context.Context, orgID string, status string) (i []Invitation, err error) {
+	i = []Invitation{}
+	for invitation, err := range ic.IterByOrgID(ctx, orgID, status, 0) {
+		if err != nil {
+			return i, err
+		}
+		i = append(i, invitation)
+	}
+	return i, nil
+}
+
+func (ic *invitationClient) GetPageByOrgID(ctx context.Context, orgID string, status string, limit int, cursor string) (ip InvitationPage, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id/invitations", ic.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": orgID,
+	}
+	queryParams := pageQueryParams(limit, cursor)
+	if status != "" {
+		queryParams["status"] = []string{status}
+	}
+	err = ic.ac.Get(ctx, path, pathParams, queryParams, &ip)
+	return ip, err
+}
+
+func (ic *invitationClient) IterByOrgID(ctx context.Context, orgID string, status string, limit int) iter.Seq2[Invitation, error] {
+	return iterPages(func(cursor string) (InvitationPage, error) {
+		return ic.GetPageByOrgID(ctx, orgID, status, limit, cursor)
+	})
+}
+
+func (ic *invitationClient) Create(ctx context.Context, orgID string, input CreateInvitation) (i Invitation, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id/invitations", ic.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": orgID,
+	}
+	queryParams := map[string][]string{}
+	err = ic.ac.Post(ctx, path, pathParams, queryParams, input, &i)
+	return i, err
+}
+
+func (ic *invitationClient) Resend(ctx context.Context, id string) (i Invitation, err error) {
+	path := fmt.Sprintf("%s/api/invitations/:id/resend", ic.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{}
+	err = ic.ac.Post(ctx, path, pathParams, queryParams, nil, &i)
+	return i, err
+}
+
+func (ic *invitationClient) Revoke(ctx context.Context, id string) (err error) {
+	path := fmt.Sprintf("%s/api/invitations/:id", ic.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{}
+	return ic.ac.Delete(ctx, path, pathParams, queryParams, nil, nil)
+}
+
+// Accept doesn't need a token with access to the org, the invitation's token
+// is what's checked.
+func (ic *invitationClient) Accept(ctx context.Context, input AcceptInvitation) (u user.User, err error) {
+	path := fmt.Sprintf("%s/api/invitations/accept", ic.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	err = ic.ac.Post(ctx, path, pathParams, queryParams, input, &u)
+	return u, err
+}
+
+func pageQueryParams(limit int, cursor string) map[string][]string {
+	queryParams := map[string][]string{}
+	if limit > 0 {
+		queryParams["limit"] = []string{strconv.Itoa(limit)}
+	}
+	if cursor != "" {
+		queryParams["cursor"] = []string{cursor}
+	}
+	return queryParams
+}
+
+// iterPages yields every invitation across pages, a failed page fetch is
+// yielded once as an error and ends the iteration.
+func iterPages(getPage func(cursor string) (InvitationPage, error)) iter.Seq2[Invitation, error] {
+	return func(yield func(Invitation, error) bool) {
+		cursor := ""
+		for {
+			ip, err := getPage(cursor)
+			if err != nil {
+				yield(Invitation{}, err)
+				return
+			}
+			for _, i := range ip.Invitations {
+				if !yield(i, nil) {
+					return
+				}
+			}
+			if ip.NextCursor == "" {
+				return
+			}
+			cursor = ip.NextCursor
+		}
+	}
+}
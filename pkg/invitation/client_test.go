@@ -0,0 +1,186 @@
+package invitation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func initClient(getToken func(isRetry bool) (string, error), f func(w http.ResponseWriter, r *http.Request)) (*invitationClient, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(f))
+	cfg := Config{
+		BaseURL: server.URL,
+	}
+	client := NewClient(cfg, apiclient.NewClient(httpx.NewClient(http.Client{}), getToken))
+	return client, server
+}
+
+func bearer(s string) string {
+	return fmt.Sprintf("Bearer %s", s)
+}
+
+func TestGetByOrgID(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs/test-org-id/invitations", r.URL.Path)
+		assert.Equal(t, "pending", r.URL.Query().Get("status"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"invitations":[{"id":"foo-id","org_id":"test-org-id","email":"foo@bar.com","role":"member","status":"pending"}],"next_cursor":"next"}`))
+		} else {
+			assert.Equal(t, "next", r.URL.Query().Get("cursor"))
+			w.Write([]byte(`{"invitations":[{"id":"bar-id","org_id":"test-org-id","email":"bar@bar.com","role":"org_admin","status":"pending"}]}`))
+		}
+	})
+	i, err := client.GetByOrgID(ctx, "test-org-id", StatusPending)
+	assert.Nil(t, err)
+	assert.Equal(t, []Invitation{
+		{ID: "foo-id", OrgID: "test-org-id", Email: "foo@bar.com", Role: RoleMember, Status: StatusPending},
+		{ID: "bar-id", OrgID: "test-org-id", Email: "bar@bar.com", Role: RoleOrgAdmin, Status: StatusPending},
+	}, i)
+}
+
+func TestGetPageByOrgID_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "3", r.URL.Query().Get("limit"))
+		assert.False(t, r.URL.Query().Has("status"))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(403)
+		w.Write([]byte(`{"message":"forbidden"}`))
+	})
+	_, err := client.GetPageByOrgID(ctx, "test-org-id", "", 3, "")
+	var httpErr httpx.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 403, httpErr.StatusCode)
+}
+
+func TestCreate(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"email":"foo@bar.com","role":"org_admin"}`), b)
+		assert.Equal(t, "/api/orgs/test-org-id/invitations", r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"foo-id","org_id":"test-org-id","email":"foo@bar.com","role":"org_admin","status":"pending"}`))
+	})
+	i, err := client.Create(ctx, "test-org-id", CreateInvitation{Email: "foo@bar.com", Role: RoleOrgAdmin})
+	assert.Nil(t, err)
+	assert.Equal(t, Invitation{ID: "foo-id", OrgID: "test-org-id", Email: "foo@bar.com", Role: RoleOrgAdmin, Status: StatusPending}, i)
+}
+
+func TestCreate_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(409)
+		w.Write([]byte(`{"message":"already invited"}`))
+	})
+	_, err := client.Create(ctx, "test-org-id", CreateInvitation{Email: "foo@bar.com"})
+	var httpErr httpx.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 409, httpErr.StatusCode)
+}
+
+func TestResend(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/invitations/foo-id/resend", r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"foo-id","org_id":"test-org-id","email":"foo@bar.com","role":"member","status":"pending"}`))
+	})
+	i, err := client.Resend(ctx, "foo-id")
+	assert.Nil(t, err)
+	assert.Equal(t, Invitation{ID: "foo-id", OrgID: "test-org-id", Email: "foo@bar.com", Role: RoleMember, Status: StatusPending}, i)
+}
+
+func TestRevoke(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/api/invitations/foo-id", r.URL.Path)
+		w.WriteHeader(204)
+	})
+	err := client.Revoke(ctx, "foo-id")
+	assert.Nil(t, err)
+}
+
+func TestAccept(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"token":"foo-token","name":"Foo"}`), b)
+		assert.Equal(t, "/api/invitations/accept", r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"foo-user-id","org_id":"test-org-id","name":"Foo","email":"foo@bar.com","is_active":true}`))
+	})
+	u, err := client.Accept(ctx, AcceptInvitation{Token: "foo-token", Name: "Foo"})
+	assert.Nil(t, err)
+	assert.Equal(t, user.User{ID: "foo-user-id", OrgID: "test-org-id", Name: "Foo", Email: "foo@bar.com", IsActive: true}, u)
+}
+
+func TestAccept_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(410)
+		w.Write([]byte(`{"message":"expired"}`))
+	})
+	_, err := client.Accept(ctx, AcceptInvitation{Token: "foo-token"})
+	var httpErr httpx.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 410, httpErr.StatusCode)
+}
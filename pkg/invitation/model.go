@@ -0,0 +1,54 @@
+package invitation
+
+import "time"
+
+// The status of an invitation, it's worked out when it's read so an
+// invitation goes from pending to expired without anything being written.
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRevoked  = "revoked"
+	StatusExpired  = "expired"
+)
+
+// The roles an invitation can give, they match the membership roles.
+const (
+	RoleMember   = "member"
+	RoleOrgAdmin = "org_admin"
+)
+
+// Invitation asks someone to join an org, the token that accepts it is only
+// ever in the notice sent to the email.
+type Invitation struct {
+	ID         string     `json:"id" db:"id"`
+	OrgID      string     `json:"org_id" db:"org_id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	Status     string     `json:"status" db:"status"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	CreatedBy  string     `json:"created_by,omitempty" db:"created_by"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	AcceptedBy string     `json:"accepted_by,omitempty" db:"accepted_by"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy  string     `json:"revoked_by,omitempty" db:"revoked_by"`
+}
+
+// CreateInvitation invites the email to the org in the path, the role
+// defaults to member.
+type CreateInvitation struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"omitempty,oneof=member org_admin"`
+}
+
+// AcceptInvitation only needs a name when the email doesn't belong to a user
+// yet, an existing user keeps theirs.
+type AcceptInvitation struct {
+	Token string `json:"token" binding:"required"`
+	Name  string `json:"name"`
+}
+
+type InvitationPage struct {
+	Invitations []Invitation `json:"invitations"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,121 @@
+package membership
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strconv"
+
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+)
+
+type Config struct {
+	BaseURL string
+}
+
+type membershipClient struct {
+	cfg Config
+	ac  *apiclient.Client
+}
+
+func NewClient(cfg Config, ac *apiclient.Client) *membershipClient {
+	return &membershipClient{
+		cfg: cfg,
+		ac:  ac,
+	}
+}
+
+// GetByOrgID follows next_cursor until every page has been retrieved, use
+// IterByOrgID when the result set is too large to hold in memory.
+func (mc *membershipClient) GetByOrgID(ctx context.Context, orgID string) (m []Membership, err error) {
+	m = []Membership{}
+	for membership, err := range mc.IterByOrgID(ctx, orgID, 0) {
+		if err != nil {
+			return m, err
+		}
+		m = append(m, membership)
+	}
+	return m, nil
+}
+
+func (mc *membershipClient) GetPageByOrgID(ctx context.Context, orgID string, limit int, cursor string) (mp MembershipPage, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id/memberships", mc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": orgID,
+	}
+	queryParams := pageQueryParams(limit, cursor)
+	err = mc.ac.Get(ctx, path, pathParams, queryParams, &mp)
+	return mp, err
+}
+
+func (mc *membershipClient) IterByOrgID(ctx context.Context, orgID string, limit int) iter.Seq2[Membership, error] {
+	return iterPages(func(cursor string) (MembershipPage, error) {
+		return mc.GetPageByOrgID(ctx, orgID, limit, cursor)
+	})
+}
+
+// GetByUserID isn't paged, a user is only ever in a handful of orgs.
+func (mc *membershipClient) GetByUserID(ctx context.Context, userID string) (m []Membership, err error) {
+	path := fmt.Sprintf("%s/api/users/:id/memberships", mc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": userID,
+	}
+	queryParams := map[string][]string{}
+	err = mc.ac.Get(ctx, path, pathParams, queryParams, &m)
+	return m, err
+}
+
+func (mc *membershipClient) Add(ctx context.Context, orgID string, input AddMembership) (m Membership, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id/memberships", mc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": orgID,
+	}
+	queryParams := map[string][]string{}
+	err = mc.ac.Post(ctx, path, pathParams, queryParams, input, &m)
+	return m, err
+}
+
+func (mc *membershipClient) Remove(ctx context.Context, orgID string, userID string) (err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id/memberships/:userID", mc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id":     orgID,
+		"userID": userID,
+	}
+	queryParams := map[string][]string{}
+	return mc.ac.Delete(ctx, path, pathParams, queryParams, nil, nil)
+}
+
+func pageQueryParams(limit int, cursor string) map[string][]string {
+	queryParams := map[string][]string{}
+	if limit > 0 {
+		queryParams["limit"] = []string{strconv.Itoa(limit)}
+	}
+	if cursor != "" {
+		queryParams["cursor"] = []string{cursor}
+	}
+	return queryParams
+}
+
+// iterPages yields every membership across pages, a failed page fetch is
+// yielded once as an error and ends the iteration.
+func iterPages(getPage func(cursor string) (MembershipPage, error)) iter.Seq2[Membership, error] {
+	return func(yield func(Membership, error) bool) {
+		cursor := ""
+		for {
+			mp, err := getPage(cursor)
+			if err != nil {
+				yield(Membership{}, err)
+				return
+			}
+			for _, m := range mp.Memberships {
+				if !yield(m, nil) {
+					return
+				}
+			}
+			if mp.NextCursor == "" {
+				return
+			}
+			cursor = mp.NextCursor
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/stretchr/testify/assert"
+)
+
+func initClient(getToken func(isRetry bool) (string, error), f func(w http.ResponseWriter, r *http.Request)) (*membershipClient, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(f))
+	cfg := Config{
+		BaseURL: server.URL,
+	}
+	client := NewClient(cfg, apiclient.NewClient(httpx.NewClient(http.Client{}), getToken))
+	return client, server
+}
+
+func bearer(s string) string {
+	return fmt.Sprintf("Bearer %s", s)
+}
+
+func TestGetByOrgID(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs/test-org-id/memberships", r.URL.Path)
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"memberships":[{"user_id":"foo-user-id","org_id":"test-org-id","role":"member","is_home":true}],"next_cursor":"next"}`))
+		} else {
+			assert.Equal(t, "next", r.URL.Query().Get("cursor"))
+			w.Write([]byte(`{"memberships":[{"user_id":"bar-user-id","org_id":"test-org-id","role":"org_admin"}]}`))
+		}
+	})
+	m, err := client.GetByOrgID(ctx, "test-org-id")
+	assert.Nil(t, err)
+	assert.Equal(t, []Membership{
+		{UserID: "foo-user-id", OrgID: "test-org-id", Role: RoleMember, IsHome: true},
+		{UserID: "bar-user-id", OrgID: "test-org-id", Role: RoleOrgAdmin},
+	}, m)
+}
+
+func TestGetPageByOrgID_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "3", r.URL.Query().Get("limit"))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(403)
+		w.Write([]byte(`{"message":"forbidden"}`))
+	})
+	_, err := client.GetPageByOrgID(ctx, "test-org-id", 3, "")
+	var httpErr httpx.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 403, httpErr.StatusCode)
+}
+
+func TestGetByUserID(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/users/test-user-id/memberships", r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`[{"user_id":"test-user-id","org_id":"foo-org-id","role":"member","is_home":true,"joined_at":"2024-01-02T03:04:05Z"}]`))
+	})
+	m, err := client.GetByUserID(ctx, "test-user-id")
+	assert.Nil(t, err)
+	assert.Equal(t, []Membership{
+		{UserID: "test-user-id", OrgID: "foo-org-id", Role: RoleMember, IsHome: true, JoinedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}, m)
+}
+
+func TestAdd(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"user_id":"test-user-id","role":"org_admin"}`), b)
+		assert.Equal(t, "/api/orgs/test-org-id/memberships", r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"user_id":"test-user-id","org_id":"test-org-id","role":"org_admin"}`))
+	})
+	m, err := client.Add(ctx, "test-org-id", AddMembership{UserID: "test-user-id", Role: RoleOrgAdmin})
+	assert.Nil(t, err)
+	assert.Equal(t, Membership{UserID: "test-user-id", OrgID: "test-org-id", Role: RoleOrgAdmin}, m)
+}
+
+func TestAdd_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(409)
+		w.Write([]byte(`{"message":"already a member"}`))
+	})
+	_, err := client.Add(ctx, "test-org-id", AddMembership{UserID: "test-user-id"})
+	var httpErr httpx.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 409, httpErr.StatusCode)
+}
+
+func TestRemove(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/api/orgs/test-org-id/memberships/test-user-id", r.URL.Path)
+		w.WriteHeader(204)
+	})
+	err := client.Remove(ctx, "test-org-id", "test-user-id")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,32 @@
+package membership
+
+import "time"
+
+// The roles a user can have in an org, they match the jwt role claim.
+const (
+	RoleMember   = "member"
+	RoleOrgAdmin = "org_admin"
+)
+
+// Membership puts a user in an org, every user has one for their own org_id
+// (IsHome) and can have more for the other orgs they belong to.
+type Membership struct {
+	UserID    string    `json:"user_id" db:"user_id"`
+	OrgID     string    `json:"org_id" db:"org_id"`
+	Role      string    `json:"role" db:"role"`
+	IsHome    bool      `json:"is_home" db:"is_home"`
+	JoinedAt  time.Time `json:"joined_at" db:"joined_at"`
+	CreatedBy string    `json:"created_by,omitempty" db:"created_by"`
+}
+
+// AddMembership adds the user to the org in the path, the role defaults to
+// member.
+type AddMembership struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"omitempty,oneof=member org_admin"`
+}
+
+type MembershipPage struct {
+	Memberships []Membership `json:"memberships"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,32 @@
+package onboarding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+)
+
+type Config struct {
+	BaseURL string
+}
+
+type onboardingClient struct {
+	cfg Config
+	ac  *apiclient.Client
+}
+
+func NewClient(cfg Config, ac *apiclient.Client) *onboardingClient {
+	return &onboardingClient{
+		cfg: cfg,
+		ac:  ac,
+	}
+}
+
+func (oc *onboardingClient) Onboard(ctx context.Context, input Onboarding) (o Onboarding, err error) {
+	path := fmt.Sprintf("%s/api/onboarding", oc.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	err = oc.ac.Post(ctx, path, pathParams, queryParams, input, &o)
+	return o, err
+}
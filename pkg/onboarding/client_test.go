@@ -0,0 +1,78 @@
+package onboarding
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
+	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func initClient(getToken func(isRetry bool) (string, error), f func(w http.ResponseWriter, r *http.Request)) (*onboardingClient, *httptest.Server) {
+	server := httptest.NewServer(http.HandlerFunc(f))
+	cfg := Config{
+		BaseURL: server.URL,
+	}
+	client := NewClient(cfg, apiclient.NewClient(httpx.NewClient(http.Client{}), getToken))
+	return client, server
+}
+
+func bearer(s string) string {
+	return fmt.Sprintf("Bearer %s", s)
+}
+
+func TestOnboard(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	input := Onboarding{
+		Org:   org.Org{Name: "foo"},
+		Users: []user.User{{Name: "bar"}},
+	}
+	expected := Onboarding{
+		Org:   org.Org{ID: "test-org-id", Name: "foo"},
+		Users: []user.User{{ID: "test-user-id", OrgID: "test-org-id", Name: "bar"}},
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Contains(t, string(b), `"org":{"name":"foo"`)
+		assert.Contains(t, string(b), `"users":[{"name":"bar"`)
+		assert.Equal(t, "/api/onboarding", r.URL.Path)
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Write([]byte(`{"org":{"id":"test-org-id","name":"foo"},"users":[{"id":"test-user-id","org_id":"test-org-id","name":"bar"}]}`))
+	})
+	o, err := client.Onboard(ctx, input)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, o)
+}
+
+func TestOnboard_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+	})
+	o, err := client.Onboard(ctx, Onboarding{Org: org.Org{Name: "foo"}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "409")
+	assert.Equal(t, Onboarding{}, o)
+}
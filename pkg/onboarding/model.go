@@ -0,0 +1,14 @@
+package onboarding
+
+import (
+	"github.com/RyanBard/go-service-ex/pkg/org"
+	"github.com/RyanBard/go-service-ex/pkg/user"
+)
+
+// Onboarding is a new org and its initial users, they're created together or
+// not at all. The users' org_id is ignored, they all join the new org, so
+// they're validated without it instead of when binding.
+type Onboarding struct {
+	Org   org.Org     `json:"org" binding:"required"`
+	Users []user.User `json:"users" binding:"required,min=1,max=100"`
+}
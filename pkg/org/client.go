@@ -3,8 +3,12 @@ package org
 import (
 	"context"
 	"fmt"
+	"iter"
+	"strconv"
 
 	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
 )
 
 type Config struct {
@@ -33,22 +37,60 @@ func (oc *orgClient) GetByID(ctx context.Context, id string) (o Org, err error)
 	return o, err
 }
 
-func (oc *orgClient) GetAll(ctx context.Context) (o []Org, err error) {
-	path := fmt.Sprintf("%s/api/orgs", oc.cfg.BaseURL)
-	pathParams := map[string]string{}
-	queryParams := map[string][]string{}
+// GetByIDIncludingDeleted also returns soft deleted orgs, it's restricted
+// to the admins that are allowed to restore them.
+func (oc *orgClient) GetByIDIncludingDeleted(ctx context.Context, id string) (o Org, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id", oc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{
+		"include_deleted": {"true"},
+	}
 	err = oc.ac.Get(ctx, path, pathParams, queryParams, &o)
 	return o, err
 }
 
+// GetAll follows next_cursor until every page has been retrieved, use Iter
+// when the result set is too large to hold in memory.
+func (oc *orgClient) GetAll(ctx context.Context) (o []Org, err error) {
+	return oc.SearchByName(ctx, "")
+}
+
+func (oc *orgClient) GetPage(ctx context.Context, limit int, cursor string) (op OrgPage, err error) {
+	return oc.SearchPageByName(ctx, "", limit, cursor)
+}
+
+func (oc *orgClient) Iter(ctx context.Context, limit int) iter.Seq2[Org, error] {
+	return oc.IterByName(ctx, "", limit)
+}
+
 func (oc *orgClient) SearchByName(ctx context.Context, name string) (o []Org, err error) {
+	o = []Org{}
+	for org, err := range oc.IterByName(ctx, name, 0) {
+		if err != nil {
+			return o, err
+		}
+		o = append(o, org)
+	}
+	return o, nil
+}
+
+func (oc *orgClient) SearchPageByName(ctx context.Context, name string, limit int, cursor string) (op OrgPage, err error) {
 	path := fmt.Sprintf("%s/api/orgs", oc.cfg.BaseURL)
 	pathParams := map[string]string{}
-	queryParams := map[string][]string{
-		"name": {name},
+	queryParams := pageQueryParams(limit, cursor)
+	if name != "" {
+		queryParams["name"] = []string{name}
 	}
-	err = oc.ac.Get(ctx, path, pathParams, queryParams, &o)
-	return o, err
+	err = oc.ac.Get(ctx, path, pathParams, queryParams, &op)
+	return op, err
+}
+
+func (oc *orgClient) IterByName(ctx context.Context, name string, limit int) iter.Seq2[Org, error] {
+	return iterPages(func(cursor string) (OrgPage, error) {
+		return oc.SearchPageByName(ctx, name, limit, cursor)
+	})
 }
 
 func (oc *orgClient) Save(ctx context.Context, input Org) (o Org, err error) {
@@ -67,11 +109,93 @@ func (oc *orgClient) Save(ctx context.Context, input Org) (o Org, err error) {
 	return o, err
 }
 
+// Patch changes only what p says to, ex. patch.Merge(map[string]any{"name":
+// "foo"}) or patch.JSON(patch.Test("/version", 3), patch.Replace("/name", "foo")).
+func (oc *orgClient) Patch(ctx context.Context, id string, p patch.Patch) (o Org, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id", oc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{}
+	err = oc.ac.Patch(ctx, path, pathParams, queryParams, p.ContentType, p.Body, &o)
+	return o, err
+}
+
+// Export reads the whole export into memory, it's meant for small exports
+// (ex. tests), a large one should be streamed straight from the endpoint.
+// format is csv or ndjson.
+func (oc *orgClient) Export(ctx context.Context, format string) (out string, err error) {
+	path := fmt.Sprintf("%s/api/orgs/export", oc.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{
+		"format": {format},
+	}
+	err = oc.ac.GetStr(ctx, path, pathParams, queryParams, dataio.ContentType(format), &out)
+	return out, err
+}
+
 func (oc *orgClient) Delete(ctx context.Context, input DeleteOrg) (err error) {
+	return oc.DeleteWithOptions(ctx, input, DeleteOrgOptions{})
+}
+
+// DeleteWithOptions is Delete for orgs that still have users, opts decides
+// whether they're deleted too or moved to another org.
+func (oc *orgClient) DeleteWithOptions(ctx context.Context, input DeleteOrg, opts DeleteOrgOptions) (err error) {
 	path := fmt.Sprintf("%s/api/orgs/:id", oc.cfg.BaseURL)
 	pathParams := map[string]string{
 		"id": input.ID,
 	}
 	queryParams := map[string][]string{}
+	if opts.Mode != "" {
+		queryParams["mode"] = []string{opts.Mode}
+	}
+	if opts.ReassignTo != "" {
+		queryParams["reassign_to"] = []string{opts.ReassignTo}
+	}
 	return oc.ac.Delete(ctx, path, pathParams, queryParams, input, nil)
 }
+
+func (oc *orgClient) Restore(ctx context.Context, input RestoreOrg) (o Org, err error) {
+	path := fmt.Sprintf("%s/api/orgs/:id/restore", oc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": input.ID,
+	}
+	queryParams := map[string][]string{}
+	err = oc.ac.Post(ctx, path, pathParams, queryParams, input, &o)
+	return o, err
+}
+
+func pageQueryParams(limit int, cursor string) map[string][]string {
+	queryParams := map[string][]string{}
+	if limit > 0 {
+		queryParams["limit"] = []string{strconv.Itoa(limit)}
+	}
+	if cursor != "" {
+		queryParams["cursor"] = []string{cursor}
+	}
+	return queryParams
+}
+
+// iterPages yields every org across pages, a failed page fetch is yielded
+// once as an error and ends the iteration.
+func iterPages(getPage func(cursor string) (OrgPage, error)) iter.Seq2[Org, error] {
+	return func(yield func(Org, error) bool) {
+		cursor := ""
+		for {
+			op, err := getPage(cursor)
+			if err != nil {
+				yield(Org{}, err)
+				return
+			}
+			for _, o := range op.Orgs {
+				if !yield(o, nil) {
+					return
+				}
+			}
+			if op.NextCursor == "" {
+				return
+			}
+			cursor = op.NextCursor
+		}
+	}
+}
@@ -12,6 +12,8 @@ import (
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	"github.com/RyanBard/go-service-ex/internal/apiclient"
 	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -111,11 +113,67 @@ func TestGetAll(t *testing.T) {
 		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
 		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
 		w.Header().Add("Content-Type", "application/json")
-		w.Write([]byte(`[{"id":"test-org-id","name":"foo"}]`))
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"orgs":[{"id":"test-org-id","name":"foo"}],"next_cursor":"test-cursor"}`))
+		} else {
+			assert.Equal(t, "cursor=test-cursor", r.URL.RawQuery)
+			w.Write([]byte(`{"orgs":[{"id":"test-org-id-2","name":"bar"}]}`))
+		}
 	})
 	o, err := client.GetAll(ctx)
 	assert.Nil(t, err)
-	assert.Equal(t, []Org{expectedOrg}, o)
+	assert.Equal(t, []Org{expectedOrg, {ID: "test-org-id-2", Name: "bar"}}, o)
+}
+
+func TestGetPage(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	expectedPage := OrgPage{
+		Orgs: []Org{
+			{
+				ID:   "test-org-id",
+				Name: "foo",
+			},
+		},
+		NextCursor: "next-cursor",
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs", r.URL.Path)
+		assert.Equal(t, "cursor=test-cursor&limit=10", r.URL.RawQuery)
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"orgs":[{"id":"test-org-id","name":"foo"}],"next_cursor":"next-cursor"}`))
+	})
+	op, err := client.GetPage(ctx, 10, "test-cursor")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedPage, op)
+}
+
+func TestIter_StopEarly(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	calls := 0
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"orgs":[{"id":"test-org-id","name":"foo"}],"next_cursor":"test-cursor"}`))
+	})
+	for o, err := range client.Iter(ctx, 1) {
+		assert.Nil(t, err)
+		assert.Equal(t, "test-org-id", o.ID)
+		break
+	}
+	assert.Equal(t, 1, calls)
 }
 
 func TestGetAll_TokenErr(t *testing.T) {
@@ -171,13 +229,40 @@ func TestSearchByName(t *testing.T) {
 		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
 		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
 		w.Header().Add("Content-Type", "application/json")
-		w.Write([]byte(`[{"id":"test-org-id","name":"foo"}]`))
+		w.Write([]byte(`{"orgs":[{"id":"test-org-id","name":"foo"}]}`))
 	})
 	o, err := client.SearchByName(ctx, name)
 	assert.Nil(t, err)
 	assert.Equal(t, []Org{expectedOrg}, o)
 }
 
+func TestSearchPageByName(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	expectedPage := OrgPage{
+		Orgs: []Org{
+			{
+				ID:   "test-org-id",
+				Name: "foo",
+			},
+		},
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs", r.URL.Path)
+		assert.Equal(t, "limit=5&name=foo", r.URL.RawQuery)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"orgs":[{"id":"test-org-id","name":"foo"}]}`))
+	})
+	op, err := client.SearchPageByName(ctx, "foo", 5, "")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedPage, op)
+}
+
 func TestSearchByName_TokenErr(t *testing.T) {
 	reqID := "test-req-id"
 	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
@@ -345,6 +430,68 @@ func TestUpdate_HTTPErr(t *testing.T) {
 	assert.Equal(t, "", o.Name)
 }
 
+func TestPatch_Merge(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-org-id"
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"desc":null,"version":3}`), b)
+		assert.Equal(t, "/api/orgs/"+id, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, patch.ContentTypeMerge, r.Header.Get("content-type"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Write([]byte(`{"id":"test-org-id","name":"foo","version":4}`))
+	})
+	o, err := client.Patch(ctx, id, patch.Merge(map[string]any{"desc": nil, "version": 3}))
+	assert.Nil(t, err)
+	assert.Equal(t, Org{ID: id, Name: "foo", Version: 4}, o)
+}
+
+func TestPatch_JSON(t *testing.T) {
+	ctx := context.Background()
+	id := "test-org-id"
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`[{"op":"test","path":"/version","value":3},{"op":"replace","path":"/name","value":"foo"}]`), b)
+		assert.Equal(t, patch.ContentTypeJSON, r.Header.Get("content-type"))
+		w.Write([]byte(`{"id":"test-org-id","name":"foo","version":4}`))
+	})
+	o, err := client.Patch(ctx, id, patch.JSON(patch.Test("/version", 3), patch.Replace("/name", "foo")))
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", o.Name)
+}
+
+func TestPatch_ProblemErr(t *testing.T) {
+	ctx := context.Background()
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", problem.ContentType)
+		w.WriteHeader(409)
+		w.Write([]byte(`{"title":"Conflict","status":409,"detail":"Patch test failed","code":"patch_test_failed"}`))
+	})
+	o, err := client.Patch(ctx, "test-org-id", patch.JSON(patch.Test("/version", 3)))
+	var p problem.Problem
+	assert.True(t, errors.As(err, &p))
+	assert.Equal(t, problem.CodePatchTestFailed, p.Code)
+	assert.Equal(t, "", o.Name)
+}
+
 func TestDelete(t *testing.T) {
 	reqID := "test-req-id"
 	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
@@ -409,3 +556,178 @@ func TestDelete_HTTPErr(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "500")
 }
+
+func TestDelete_ProblemErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	input := DeleteOrg{
+		ID:      "test-org-id",
+		Version: 1,
+	}
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", problem.ContentType)
+		w.WriteHeader(409)
+		w.Write([]byte(`{"title":"Conflict","status":409,"detail":"Org still has users","code":"org_has_users","num_users":2}`))
+	})
+	err := client.Delete(ctx, input)
+	var p problem.Problem
+	assert.True(t, errors.As(err, &p))
+	assert.Equal(t, problem.CodeOrgHasUsers, p.Code)
+	assert.Equal(t, 409, p.Status)
+	assert.Equal(t, float64(2), p.Extensions["num_users"])
+}
+
+func TestGetByIDIncludingDeleted(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-org-id"
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs/"+id, r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("include_deleted"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"test-org-id","deleted_by":"deleter-id"}`))
+	})
+	o, err := client.GetByIDIncludingDeleted(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, id, o.ID)
+	assert.Equal(t, "deleter-id", o.DeletedBy)
+}
+
+func TestRestore(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-org-id"
+	input := RestoreOrg{
+		ID:      id,
+		Version: 1,
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"id":"test-org-id","version":1}`), b)
+		assert.Equal(t, "/api/orgs/"+id+"/restore", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"test-org-id","version":2}`))
+	})
+	o, err := client.Restore(ctx, input)
+	assert.Nil(t, err)
+	assert.Equal(t, Org{ID: id, Version: 2}, o)
+}
+
+func TestRestore_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	input := RestoreOrg{
+		ID:      "test-org-id",
+		Version: 1,
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+	})
+	o, err := client.Restore(ctx, input)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "409")
+	assert.Equal(t, "", o.ID)
+}
+
+func TestDeleteWithOptions(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-org-id"
+	input := DeleteOrg{
+		ID:      id,
+		Version: 1,
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		assert.Equal(t, "/api/orgs/"+id, r.URL.Path)
+		assert.Equal(t, "cascade", r.URL.Query().Get("mode"))
+		assert.False(t, r.URL.Query().Has("reassign_to"))
+		w.WriteHeader(204)
+	})
+	err := client.DeleteWithOptions(ctx, input, DeleteOrgOptions{Mode: DeleteModeCascade})
+	assert.Nil(t, err)
+}
+
+func TestDeleteWithOptions_ReassignTo(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-org-id"
+	input := DeleteOrg{
+		ID:      id,
+		Version: 1,
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "other-org-id", r.URL.Query().Get("reassign_to"))
+		assert.False(t, r.URL.Query().Has("mode"))
+		w.WriteHeader(204)
+	})
+	err := client.DeleteWithOptions(ctx, input, DeleteOrgOptions{ReassignTo: "other-org-id"})
+	assert.Nil(t, err)
+}
+
+func TestExport(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	mockResp := `{"id":"test-org-id","name":"foo"}` + "\n"
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs/export", r.URL.Path)
+		assert.Equal(t, "ndjson", r.URL.Query().Get("format"))
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("accept"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/x-ndjson")
+		w.Write([]byte(mockResp))
+	})
+	out, err := client.Export(ctx, "ndjson")
+	assert.Nil(t, err)
+	assert.Equal(t, mockResp, out)
+}
+
+func TestExport_HTTPErr(t *testing.T) {
+	ctx := context.Background()
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	})
+	out, err := client.Export(ctx, "csv")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.Equal(t, "", out)
+}
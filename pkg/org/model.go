@@ -4,17 +4,42 @@ import "time"
 
 type Org struct {
 	ID        string    `json:"id,omitempty" db:"id"`
-	Name      string    `json:"name,omitempty" binding:"required" db:"name"`
-	Desc      string    `json:"desc,omitempty" binding:"required" db:"description"`
+	Name      string    `json:"name,omitempty" binding:"required,max=100" db:"name"`
+	Desc      string    `json:"desc,omitempty" binding:"required,max=1000" db:"description"`
 	IsSystem  bool      `json:"is_system" db:"is_system"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	CreatedBy string    `json:"created_by,omitempty" db:"created_by"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	UpdatedBy string    `json:"updated_by,omitempty" db:"updated_by"`
 	Version   int64     `json:"version" db:"version"`
+	// DeletedAt is only set on soft deleted orgs, which are only returned
+	// when include_deleted is asked for
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy string     `json:"deleted_by,omitempty" db:"deleted_by"`
 }
 
 type DeleteOrg struct {
 	ID      string `json:"id,omitempty" binding:"required" db:"id"`
 	Version int64  `json:"version" binding:"required" db:"version"`
 }
+
+// DeleteModeCascade soft deletes the org's users along with it.
+const DeleteModeCascade = "cascade"
+
+// DeleteOrgOptions decides what happens to the org's users, without a mode or
+// a reassign target an org that still has users can't be deleted.
+type DeleteOrgOptions struct {
+	Mode       string
+	ReassignTo string
+}
+
+// RestoreOrg only needs the version in the body, the id comes from the path.
+type RestoreOrg struct {
+	ID      string `json:"id,omitempty" db:"id"`
+	Version int64  `json:"version" binding:"required" db:"version"`
+}
+
+type OrgPage struct {
+	Orgs       []Org  `json:"orgs"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
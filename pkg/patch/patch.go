@@ -0,0 +1,69 @@
+package patch
+
+// The content types PATCH accepts, Merge is RFC 7396 and JSON is RFC 6902.
+const (
+	ContentTypeMerge = "application/merge-patch+json"
+	ContentTypeJSON  = "application/json-patch+json"
+)
+
+// The ops of a json patch.
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Patch is the body of a PATCH along with its content type.
+type Patch struct {
+	ContentType string
+	Body        any
+}
+
+// Merge sets every member of body on the resource, a null removes it. Add the
+// version (ex. {"is_active":false,"version":3}) to have it rejected if the
+// resource was modified since.
+func Merge(body any) Patch {
+	return Patch{ContentType: ContentTypeMerge, Body: body}
+}
+
+// JSON applies ops in order, all or nothing. Start with a test of /version to
+// have it rejected if the resource was modified since.
+func JSON(ops ...Op) Patch {
+	return Patch{ContentType: ContentTypeJSON, Body: ops}
+}
+
+// Op is one operation of a json patch, Path and From are JSON Pointers (ex.
+// /name). Value is always sent since null is a valid value.
+type Op struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value"`
+}
+
+func Add(path string, value any) Op {
+	return Op{Op: OpAdd, Path: path, Value: value}
+}
+
+func Remove(path string) Op {
+	return Op{Op: OpRemove, Path: path}
+}
+
+func Replace(path string, value any) Op {
+	return Op{Op: OpReplace, Path: path, Value: value}
+}
+
+func Move(from string, path string) Op {
+	return Op{Op: OpMove, Path: path, From: from}
+}
+
+func Copy(from string, path string) Op {
+	return Op{Op: OpCopy, Path: path, From: from}
+}
+
+func Test(path string, value any) Op {
+	return Op{Op: OpTest, Path: path, Value: value}
+}
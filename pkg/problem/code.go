@@ -0,0 +1,107 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// The codes an error response can have, they won't change once released.
+// A code that isn't specific to one resource is used for every resource.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeForbidden          = "forbidden"
+	CodePreconditionFailed = "precondition_failed"
+	CodeInternal           = "internal"
+	CodeUnavailable        = "unavailable"
+
+	CodeInvalidLimit          = "invalid_limit"
+	CodeInvalidCursor         = "invalid_cursor"
+	CodeInvalidIncludeDeleted = "invalid_include_deleted"
+	CodeInvalidDryRun         = "invalid_dry_run"
+	CodeInvalidFormat         = "invalid_format"
+	CodeMissingColumn         = "missing_column"
+	CodeInvalidRow            = "invalid_row"
+	CodeMalformedBody         = "malformed_body"
+	CodeInvalidTime           = "invalid_time"
+
+	CodeUnsupportedPatch = "unsupported_patch"
+	CodeMalformedPatch   = "malformed_patch"
+	CodePatchTestFailed  = "patch_test_failed"
+
+	CodeOrgNotFound           = "org_not_found"
+	CodeOrgNameInUse          = "org_name_in_use"
+	CodeOrgVersionConflict    = "org_version_conflict"
+	CodeSysOrgReadOnly        = "sys_org_read_only"
+	CodeOrgHasUsers           = "org_has_users"
+	CodeInvalidDeleteMode     = "invalid_delete_mode"
+	CodeInvalidReassignTarget = "invalid_reassign_target"
+
+	CodeUserNotFound            = "user_not_found"
+	CodeEmailInUse              = "email_in_use"
+	CodeUserVersionConflict     = "user_version_conflict"
+	CodeSysUserReadOnly         = "sys_user_read_only"
+	CodeCannotAssociateSysOrg   = "cannot_associate_sys_org"
+	CodeInvalidFilter           = "invalid_filter"
+	CodeInvalidSort             = "invalid_sort"
+	CodeUnknownMethod           = "unknown_method"
+	CodeInvalidBatchOp          = "invalid_batch_op"
+	CodeBatchAborted            = "batch_aborted"
+	CodeNoPendingEmailChange    = "no_pending_email_change"
+	CodeInvalidEmailChangeToken = "invalid_email_change_token"
+	CodeEmailChangeExpired      = "email_change_expired"
+	CodeEmailUnchanged          = "email_unchanged"
+	CodeNameRequired            = "name_required"
+
+	CodeMembershipNotFound         = "membership_not_found"
+	CodeAlreadyMember              = "already_member"
+	CodeCannotRemoveHomeMembership = "cannot_remove_home_membership"
+	CodeNoMemberships              = "no_memberships"
+	CodeCannotAddSysUser           = "cannot_add_sys_user"
+
+	CodeInvitationNotFound     = "invitation_not_found"
+	CodeInvalidInvitationToken = "invalid_invitation_token"
+	CodeAlreadyInvited         = "already_invited"
+	CodeInvitationNotPending   = "invitation_not_pending"
+	CodeCannotAcceptInvitation = "cannot_accept_invitation"
+	CodeCannotInviteToSysOrg   = "cannot_invite_to_sys_org"
+	CodeInvalidInvitationState = "invalid_invitation_status"
+
+	CodeIdempotencyKeyTooLong    = "idempotency_key_too_long"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	CodeIdempotencyBodyTooLarge  = "idempotency_body_too_large"
+)
+
+// CodeForStatus is the code of an error that doesn't have a more specific
+// one, ex. not_found for a 404.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusPreconditionFailed:
+		return CodePreconditionFailed
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInvalidRequest
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// HasCode is true when err is (or wraps) a Problem with the code.
+func HasCode(err error, code string) bool {
+	var p Problem
+	return errors.As(err, &p) && p.Code == code
+}
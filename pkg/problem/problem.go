@@ -0,0 +1,131 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ContentType is what every error response is sent as.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error response. Code is a stable, machine readable
+// reason (one of the Code constants) while Detail is meant for people and can
+// change at any time. Violations are only set when the body broke its model's
+// rules. Extensions holds any other members (ex. num_users when deleting an
+// org that still has users).
+type Problem struct {
+	Type       string         `json:"type,omitempty"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Code       string         `json:"code"`
+	Violations []Violation    `json:"violations,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+// Violation is one broken rule, Field is the json path to it (ex. name or
+// users[1].email) and Rule is the name of the rule (ex. required, email or
+// max).
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is what a Problem with violations unwraps to.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (err ValidationError) Error() string {
+	msgs := make([]string, len(err.Violations))
+	for i, v := range err.Violations {
+		msgs[i] = v.Message
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// New leaves Type empty, which means about:blank, so the title is the status
+// text.
+func New(status int, code string, detail string) Problem {
+	return Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Detail
+}
+
+// Unwrap lets callers errors.As a ValidationError out of the problem.
+func (p Problem) Unwrap() error {
+	if len(p.Violations) == 0 {
+		return nil
+	}
+	return ValidationError{Violations: p.Violations}
+}
+
+// problemJSON keeps MarshalJSON/UnmarshalJSON from calling themselves.
+type problemJSON Problem
+
+// members are the names that aren't extensions.
+var members = map[string]bool{
+	"type":       true,
+	"title":      true,
+	"status":     true,
+	"detail":     true,
+	"instance":   true,
+	"code":       true,
+	"violations": true,
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(problemJSON(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return b, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range p.Extensions {
+		if !members[k] {
+			m[k] = v
+		}
+	}
+	return json.Marshal(m)
+}
+
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	var pj problemJSON
+	if err := json.Unmarshal(b, &pj); err != nil {
+		return err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		if members[k] {
+			continue
+		}
+		var ext any
+		if err := json.Unmarshal(v, &ext); err != nil {
+			return err
+		}
+		if pj.Extensions == nil {
+			pj.Extensions = map[string]any{}
+		}
+		pj.Extensions[k] = ext
+	}
+	*p = Problem(pj)
+	return nil
+}
@@ -0,0 +1,98 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	p := New(404, CodeOrgNotFound, "Org not found: id=foo-id")
+	assert.Equal(t, Problem{Title: "Not Found", Status: 404, Detail: "Org not found: id=foo-id", Code: CodeOrgNotFound}, p)
+	assert.Equal(t, "Org not found: id=foo-id", p.Error())
+}
+
+func TestError_NoDetail(t *testing.T) {
+	p := New(500, CodeInternal, "")
+	assert.Equal(t, "Internal Server Error", p.Error())
+}
+
+func TestMarshalJSON(t *testing.T) {
+	p := New(409, CodeOrgHasUsers, "foo")
+	p.Instance = "/api/orgs/foo-id"
+	b, err := json.Marshal(p)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"title":"Conflict","status":409,"detail":"foo","instance":"/api/orgs/foo-id","code":"org_has_users"}`, string(b))
+}
+
+func TestMarshalJSON_Extensions(t *testing.T) {
+	p := New(409, CodeOrgHasUsers, "foo")
+	p.Extensions = map[string]any{"num_users": 3, "code": "not-overridden"}
+	b, err := json.Marshal(p)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"code":"org_has_users","detail":"foo","num_users":3,"status":409,"title":"Conflict"}`, string(b))
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	var p Problem
+	err := json.Unmarshal([]byte(`{"type":"about:blank","title":"Conflict","status":409,"detail":"foo","code":"org_has_users","num_users":3}`), &p)
+	assert.Nil(t, err)
+	assert.Equal(t, Problem{
+		Type:       "about:blank",
+		Title:      "Conflict",
+		Status:     409,
+		Detail:     "foo",
+		Code:       CodeOrgHasUsers,
+		Extensions: map[string]any{"num_users": float64(3)},
+	}, p)
+}
+
+func TestUnmarshalJSON_NotAnObject(t *testing.T) {
+	var p Problem
+	err := json.Unmarshal([]byte(`"foo"`), &p)
+	assert.NotNil(t, err)
+}
+
+func TestCodeForStatus(t *testing.T) {
+	assert.Equal(t, CodeInvalidRequest, CodeForStatus(400))
+	assert.Equal(t, CodeUnauthenticated, CodeForStatus(401))
+	assert.Equal(t, CodeForbidden, CodeForStatus(403))
+	assert.Equal(t, "not_found", CodeForStatus(404))
+	assert.Equal(t, "conflict", CodeForStatus(409))
+	assert.Equal(t, CodePreconditionFailed, CodeForStatus(412))
+	assert.Equal(t, "unprocessable_entity", CodeForStatus(422))
+	assert.Equal(t, CodeInternal, CodeForStatus(500))
+	assert.Equal(t, CodeInternal, CodeForStatus(502))
+	assert.Equal(t, CodeUnavailable, CodeForStatus(503))
+	assert.Equal(t, CodeInvalidRequest, CodeForStatus(499))
+}
+
+func TestHasCode(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", New(404, CodeUserNotFound, "foo"))
+	assert.True(t, HasCode(err, CodeUserNotFound))
+	assert.False(t, HasCode(err, CodeOrgNotFound))
+	assert.False(t, HasCode(errors.New("foo"), CodeUserNotFound))
+}
+
+func TestUnmarshalJSON_Violations(t *testing.T) {
+	var p Problem
+	err := json.Unmarshal([]byte(`{"title":"Bad Request","status":400,"detail":"email must be a valid email","code":"validation_failed","violations":[{"field":"email","rule":"email","message":"email must be a valid email"}]}`), &p)
+	assert.Nil(t, err)
+	assert.Equal(t, []Violation{{Field: "email", Rule: "email", Message: "email must be a valid email"}}, p.Violations)
+	assert.Nil(t, p.Extensions)
+
+	wrapped := fmt.Errorf("wrapped: %w", p)
+	var ve ValidationError
+	assert.True(t, errors.As(wrapped, &ve))
+	assert.Equal(t, p.Violations, ve.Violations)
+	assert.Equal(t, "email must be a valid email", ve.Error())
+}
+
+func TestUnwrap_NoViolations(t *testing.T) {
+	p := New(404, CodeUserNotFound, "foo")
+	var ve ValidationError
+	assert.False(t, errors.As(p, &ve))
+}
@@ -3,8 +3,13 @@ package user
 import (
 	"context"
 	"fmt"
+	"iter"
+	"strconv"
+	"time"
 
 	"github.com/RyanBard/go-service-ex/internal/apiclient"
+	"github.com/RyanBard/go-service-ex/internal/dataio"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
 )
 
 type Config struct {
@@ -33,22 +38,122 @@ func (uc *userClient) GetByID(ctx context.Context, id string) (u User, err error
 	return u, err
 }
 
+// GetByIDIncludingDeleted also returns soft deleted users, it's restricted
+// to the admins that are allowed to restore them.
+func (uc *userClient) GetByIDIncludingDeleted(ctx context.Context, id string) (u User, err error) {
+	path := fmt.Sprintf("%s/api/users/:id", uc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{
+		"include_deleted": {"true"},
+	}
+	err = uc.ac.Get(ctx, path, pathParams, queryParams, &u)
+	return u, err
+}
+
+// GetAll follows next_cursor until every page has been retrieved, use Iter
+// when the result set is too large to hold in memory.
 func (uc *userClient) GetAll(ctx context.Context) (u []User, err error) {
+	u = []User{}
+	for user, err := range uc.Iter(ctx, 0) {
+		if err != nil {
+			return u, err
+		}
+		u = append(u, user)
+	}
+	return u, nil
+}
+
+func (uc *userClient) GetPage(ctx context.Context, limit int, cursor string) (up UserPage, err error) {
 	path := fmt.Sprintf("%s/api/users", uc.cfg.BaseURL)
 	pathParams := map[string]string{}
-	queryParams := map[string][]string{}
-	err = uc.ac.Get(ctx, path, pathParams, queryParams, &u)
-	return u, err
+	queryParams := pageQueryParams(limit, cursor)
+	err = uc.ac.Get(ctx, path, pathParams, queryParams, &up)
+	return up, err
+}
+
+func (uc *userClient) Iter(ctx context.Context, limit int) iter.Seq2[User, error] {
+	return iterPages(func(cursor string) (UserPage, error) {
+		return uc.GetPage(ctx, limit, cursor)
+	})
+}
+
+// Search is GetAll narrowed by q, it follows next_cursor until every page has
+// been retrieved, use IterSearch when the result set is too large to hold in
+// memory.
+func (uc *userClient) Search(ctx context.Context, q Query) (u []User, err error) {
+	u = []User{}
+	for user, err := range uc.IterSearch(ctx, q, 0) {
+		if err != nil {
+			return u, err
+		}
+		u = append(u, user)
+	}
+	return u, nil
+}
+
+func (uc *userClient) SearchPage(ctx context.Context, q Query, limit int, cursor string) (up UserPage, err error) {
+	path := fmt.Sprintf("%s/api/users", uc.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := pageQueryParams(limit, cursor)
+	addQueryParam(queryParams, "org_id", q.OrgID)
+	addQueryParam(queryParams, "email", q.Email)
+	addQueryParam(queryParams, "name", q.Name)
+	addQueryParam(queryParams, "sort", q.Sort)
+	if q.IsActive != nil {
+		addQueryParam(queryParams, "is_active", strconv.FormatBool(*q.IsActive))
+	}
+	if q.IsAdmin != nil {
+		addQueryParam(queryParams, "is_admin", strconv.FormatBool(*q.IsAdmin))
+	}
+	if q.CreatedFrom != nil {
+		addQueryParam(queryParams, "created_from", q.CreatedFrom.Format(time.RFC3339Nano))
+	}
+	if q.CreatedTo != nil {
+		addQueryParam(queryParams, "created_to", q.CreatedTo.Format(time.RFC3339Nano))
+	}
+	if q.UpdatedFrom != nil {
+		addQueryParam(queryParams, "updated_from", q.UpdatedFrom.Format(time.RFC3339Nano))
+	}
+	if q.UpdatedTo != nil {
+		addQueryParam(queryParams, "updated_to", q.UpdatedTo.Format(time.RFC3339Nano))
+	}
+	err = uc.ac.Get(ctx, path, pathParams, queryParams, &up)
+	return up, err
+}
+
+func (uc *userClient) IterSearch(ctx context.Context, q Query, limit int) iter.Seq2[User, error] {
+	return iterPages(func(cursor string) (UserPage, error) {
+		return uc.SearchPage(ctx, q, limit, cursor)
+	})
 }
 
 func (uc *userClient) GetAllByOrgID(ctx context.Context, orgID string) (u []User, err error) {
+	u = []User{}
+	for user, err := range uc.IterByOrgID(ctx, orgID, 0) {
+		if err != nil {
+			return u, err
+		}
+		u = append(u, user)
+	}
+	return u, nil
+}
+
+func (uc *userClient) GetPageByOrgID(ctx context.Context, orgID string, limit int, cursor string) (up UserPage, err error) {
 	path := fmt.Sprintf("%s/api/orgs/:orgID/users", uc.cfg.BaseURL)
 	pathParams := map[string]string{
 		"orgID": orgID,
 	}
-	queryParams := map[string][]string{}
-	err = uc.ac.Get(ctx, path, pathParams, queryParams, &u)
-	return u, err
+	queryParams := pageQueryParams(limit, cursor)
+	err = uc.ac.Get(ctx, path, pathParams, queryParams, &up)
+	return up, err
+}
+
+func (uc *userClient) IterByOrgID(ctx context.Context, orgID string, limit int) iter.Seq2[User, error] {
+	return iterPages(func(cursor string) (UserPage, error) {
+		return uc.GetPageByOrgID(ctx, orgID, limit, cursor)
+	})
 }
 
 func (uc *userClient) Save(ctx context.Context, input User) (u User, err error) {
@@ -67,6 +172,56 @@ func (uc *userClient) Save(ctx context.Context, input User) (u User, err error)
 	return u, err
 }
 
+// Patch changes only what p says to, ex. patch.Merge(map[string]any{"name":
+// "foo"}) or patch.JSON(patch.Test("/version", 3), patch.Replace("/name", "foo")).
+func (uc *userClient) Patch(ctx context.Context, id string, p patch.Patch) (u User, err error) {
+	path := fmt.Sprintf("%s/api/users/:id", uc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{}
+	err = uc.ac.Patch(ctx, path, pathParams, queryParams, p.ContentType, p.Body, &u)
+	return u, err
+}
+
+// Batch sends up to 500 creates/updates/deletes at once. A failed
+// all-or-nothing batch is an httpx.HTTPError, best-effort ones respond with a
+// 207 and the per op results say which ones failed.
+func (uc *userClient) Batch(ctx context.Context, input Batch) (br BatchResponse, err error) {
+	path := fmt.Sprintf("%s/api/users:batch", uc.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{}
+	err = uc.ac.Post(ctx, path, pathParams, queryParams, input, &br)
+	return br, err
+}
+
+// Export reads the whole export into memory, it's meant for small exports
+// (ex. tests), a large one should be streamed straight from the endpoint.
+// format is csv or ndjson.
+func (uc *userClient) Export(ctx context.Context, format string) (out string, err error) {
+	path := fmt.Sprintf("%s/api/users/export", uc.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{
+		"format": {format},
+	}
+	err = uc.ac.GetStr(ctx, path, pathParams, queryParams, dataio.ContentType(format), &out)
+	return out, err
+}
+
+// Import creates a user for every row of body, which is in the same format as
+// Export. The rows that failed are in the report rather than an error, a dry
+// run reports what a real import would have without creating anything.
+func (uc *userClient) Import(ctx context.Context, format string, body string, dryRun bool) (ir ImportReport, err error) {
+	path := fmt.Sprintf("%s/api/users/import", uc.cfg.BaseURL)
+	pathParams := map[string]string{}
+	queryParams := map[string][]string{
+		"format":  {format},
+		"dry_run": {strconv.FormatBool(dryRun)},
+	}
+	err = uc.ac.PostStr(ctx, path, pathParams, queryParams, dataio.ContentType(format), body, &ir)
+	return ir, err
+}
+
 func (uc *userClient) Delete(ctx context.Context, input DeleteUser) (err error) {
 	path := fmt.Sprintf("%s/api/users/:id", uc.cfg.BaseURL)
 	pathParams := map[string]string{
@@ -75,3 +230,76 @@ func (uc *userClient) Delete(ctx context.Context, input DeleteUser) (err error)
 	queryParams := map[string][]string{}
 	return uc.ac.Delete(ctx, path, pathParams, queryParams, input, nil)
 }
+
+func (uc *userClient) Restore(ctx context.Context, input RestoreUser) (u User, err error) {
+	path := fmt.Sprintf("%s/api/users/:id/restore", uc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": input.ID,
+	}
+	queryParams := map[string][]string{}
+	err = uc.ac.Post(ctx, path, pathParams, queryParams, input, &u)
+	return u, err
+}
+
+// RequestEmailChange sends a token to the new email, the email only changes
+// once it's passed to ConfirmEmailChange.
+func (uc *userClient) RequestEmailChange(ctx context.Context, id string, input EmailChange) (pec PendingEmailChange, err error) {
+	path := fmt.Sprintf("%s/api/users/:id/email-change", uc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{}
+	err = uc.ac.Post(ctx, path, pathParams, queryParams, input, &pec)
+	return pec, err
+}
+
+func (uc *userClient) ConfirmEmailChange(ctx context.Context, id string, input ConfirmEmailChange) (u User, err error) {
+	path := fmt.Sprintf("%s/api/users/:id/email-change/confirm", uc.cfg.BaseURL)
+	pathParams := map[string]string{
+		"id": id,
+	}
+	queryParams := map[string][]string{}
+	err = uc.ac.Post(ctx, path, pathParams, queryParams, input, &u)
+	return u, err
+}
+
+func addQueryParam(queryParams map[string][]string, name string, value string) {
+	if value != "" {
+		queryParams[name] = []string{value}
+	}
+}
+
+func pageQueryParams(limit int, cursor string) map[string][]string {
+	queryParams := map[string][]string{}
+	if limit > 0 {
+		queryParams["limit"] = []string{strconv.Itoa(limit)}
+	}
+	if cursor != "" {
+		queryParams["cursor"] = []string{cursor}
+	}
+	return queryParams
+}
+
+// iterPages yields every user across pages, a failed page fetch is yielded
+// once as an error and ends the iteration.
+func iterPages(getPage func(cursor string) (UserPage, error)) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		cursor := ""
+		for {
+			up, err := getPage(cursor)
+			if err != nil {
+				yield(User{}, err)
+				return
+			}
+			for _, u := range up.Users {
+				if !yield(u, nil) {
+					return
+				}
+			}
+			if up.NextCursor == "" {
+				return
+			}
+			cursor = up.NextCursor
+		}
+	}
+}
@@ -8,10 +8,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	ctxutil "github.com/RyanBard/go-ctx-util/pkg"
 	"github.com/RyanBard/go-service-ex/internal/apiclient"
 	"github.com/RyanBard/go-service-ex/internal/httpx"
+	"github.com/RyanBard/go-service-ex/pkg/patch"
+	"github.com/RyanBard/go-service-ex/pkg/problem"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -111,11 +114,112 @@ func TestGetAll(t *testing.T) {
 		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
 		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
 		w.Header().Add("Content-Type", "application/json")
-		w.Write([]byte(`[{"id":"test-user-id","name":"foo"}]`))
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"users":[{"id":"test-user-id","name":"foo"}],"next_cursor":"test-cursor"}`))
+		} else {
+			assert.Equal(t, "cursor=test-cursor", r.URL.RawQuery)
+			w.Write([]byte(`{"users":[{"id":"test-user-id-2","name":"bar"}]}`))
+		}
 	})
 	u, err := client.GetAll(ctx)
 	assert.Nil(t, err)
-	assert.Equal(t, []User{expectedUser}, u)
+	assert.Equal(t, []User{expectedUser, {ID: "test-user-id-2", Name: "bar"}}, u)
+}
+
+func TestGetPage(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	expectedPage := UserPage{
+		Users: []User{
+			{
+				ID:   "test-user-id",
+				Name: "foo",
+			},
+		},
+		NextCursor: "next-cursor",
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/users", r.URL.Path)
+		assert.Equal(t, "cursor=test-cursor&limit=10", r.URL.RawQuery)
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"users":[{"id":"test-user-id","name":"foo"}],"next_cursor":"next-cursor"}`))
+	})
+	up, err := client.GetPage(ctx, 10, "test-cursor")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedPage, up)
+}
+
+func TestSearchPage(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	isActive := true
+	createdFrom := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/users", r.URL.Path)
+		assert.Equal(t, "created_from=2024-01-02T03%3A04%3A05Z&cursor=test-cursor&email=foo&is_active=true&limit=10&org_id=test-org-id&sort=-created_at", r.URL.RawQuery)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"users":[{"id":"test-user-id","name":"foo"}],"next_cursor":"next-cursor"}`))
+	})
+	up, err := client.SearchPage(ctx, Query{
+		OrgID:       "test-org-id",
+		IsActive:    &isActive,
+		Email:       "foo",
+		CreatedFrom: &createdFrom,
+		Sort:        "-" + SortCreatedAt,
+	}, 10, "test-cursor")
+	assert.Nil(t, err)
+	assert.Equal(t, UserPage{Users: []User{{ID: "test-user-id", Name: "foo"}}, NextCursor: "next-cursor"}, up)
+}
+
+func TestSearch(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "is_admin=false", r.URL.RawQuery)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"users":[{"id":"test-user-id"}]}`))
+	})
+	isAdmin := false
+	u, err := client.Search(ctx, Query{IsAdmin: &isAdmin})
+	assert.Nil(t, err)
+	assert.Equal(t, []User{{ID: "test-user-id"}}, u)
+}
+
+func TestIter_StopEarly(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	calls := 0
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"users":[{"id":"test-user-id","name":"foo"}],"next_cursor":"test-cursor"}`))
+	})
+	for u, err := range client.Iter(ctx, 1) {
+		assert.Nil(t, err)
+		assert.Equal(t, "test-user-id", u.ID)
+		break
+	}
+	assert.Equal(t, 1, calls)
 }
 
 func TestGetAll_TokenErr(t *testing.T) {
@@ -171,13 +275,42 @@ func TestGetAllByOrgID(t *testing.T) {
 		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
 		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
 		w.Header().Add("Content-Type", "application/json")
-		w.Write([]byte(`[{"id":"test-user-id","org_id":"test-org-id","name":"foo"}]`))
+		w.Write([]byte(`{"users":[{"id":"test-user-id","org_id":"test-org-id","name":"foo"}]}`))
 	})
 	u, err := client.GetAllByOrgID(ctx, orgID)
 	assert.Nil(t, err)
 	assert.Equal(t, []User{expectedUser}, u)
 }
 
+func TestGetPageByOrgID(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	orgID := "test-org-id"
+	expectedPage := UserPage{
+		Users: []User{
+			{
+				ID:    "test-user-id",
+				OrgID: orgID,
+				Name:  "foo",
+			},
+		},
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/orgs/test-org-id/users", r.URL.Path)
+		assert.Equal(t, "limit=5", r.URL.RawQuery)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"users":[{"id":"test-user-id","org_id":"test-org-id","name":"foo"}]}`))
+	})
+	up, err := client.GetPageByOrgID(ctx, orgID, 5, "")
+	assert.Nil(t, err)
+	assert.Equal(t, expectedPage, up)
+}
+
 func TestGetAllByOrgID_TokenErr(t *testing.T) {
 	reqID := "test-req-id"
 	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
@@ -279,6 +412,31 @@ func TestCreate_HTTPErr(t *testing.T) {
 	assert.Equal(t, "", u.Name)
 }
 
+func TestCreate_ValidationErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	input := User{
+		OrgID: "test-org-id",
+		Name:  "foo",
+		Email: "not-an-email",
+	}
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", problem.ContentType)
+		w.WriteHeader(400)
+		w.Write([]byte(`{"title":"Bad Request","status":400,"detail":"email must be a valid email","code":"validation_failed","violations":[{"field":"email","rule":"email","message":"email must be a valid email"}]}`))
+	})
+	_, err := client.Save(ctx, input)
+	var validationErr problem.ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []problem.Violation{
+		{Field: "email", Rule: "email", Message: "email must be a valid email"},
+	}, validationErr.Violations)
+	assert.True(t, problem.HasCode(err, problem.CodeValidationFailed))
+}
+
 func TestUpdate(t *testing.T) {
 	reqID := "test-req-id"
 	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
@@ -352,6 +510,47 @@ func TestUpdate_HTTPErr(t *testing.T) {
 	assert.Equal(t, "", u.Name)
 }
 
+func TestPatch(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-user-id"
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "PATCH", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"is_active":false,"version":3}`), b)
+		assert.Equal(t, "/api/users/"+id, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, patch.ContentTypeMerge, r.Header.Get("content-type"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Write([]byte(`{"id":"test-user-id","org_id":"test-org-id","name":"foo","version":4}`))
+	})
+	u, err := client.Patch(ctx, id, patch.Merge(map[string]any{"is_active": false, "version": 3}))
+	assert.Nil(t, err)
+	assert.Equal(t, User{ID: id, OrgID: "test-org-id", Name: "foo", Version: 4}, u)
+}
+
+func TestPatch_HTTPErr(t *testing.T) {
+	ctx := context.Background()
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, patch.ContentTypeJSON, r.Header.Get("content-type"))
+		w.WriteHeader(500)
+	})
+	u, err := client.Patch(ctx, "test-user-id", patch.JSON(patch.Replace("/name", "foo")))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "500")
+	assert.Equal(t, "", u.Name)
+}
+
 func TestDelete(t *testing.T) {
 	reqID := "test-req-id"
 	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
@@ -416,3 +615,268 @@ func TestDelete_HTTPErr(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "500")
 }
+
+func TestGetByIDIncludingDeleted(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-user-id"
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/users/"+id, r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("include_deleted"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"test-user-id","deleted_by":"deleter-id"}`))
+	})
+	u, err := client.GetByIDIncludingDeleted(ctx, id)
+	assert.Nil(t, err)
+	assert.Equal(t, id, u.ID)
+	assert.Equal(t, "deleter-id", u.DeletedBy)
+}
+
+func TestRestore(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-user-id"
+	input := RestoreUser{
+		ID:      id,
+		Version: 1,
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"id":"test-user-id","version":1}`), b)
+		assert.Equal(t, "/api/users/"+id+"/restore", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("accept"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"test-user-id","version":2}`))
+	})
+	u, err := client.Restore(ctx, input)
+	assert.Nil(t, err)
+	assert.Equal(t, User{ID: id, Version: 2}, u)
+}
+
+func TestRestore_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	input := RestoreUser{
+		ID:      "test-user-id",
+		Version: 1,
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+	})
+	u, err := client.Restore(ctx, input)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "409")
+	assert.Equal(t, "", u.ID)
+}
+
+func TestBatch(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	input := Batch{
+		Mode: BatchModeBestEffort,
+		Ops: []BatchOp{
+			{Op: BatchOpDelete, User: User{ID: "test-user-id", Version: 1}},
+		},
+	}
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Contains(t, string(b), `{"mode":"best-effort","ops":[{"op":"delete","user":{"id":"test-user-id"`)
+		assert.Equal(t, "/api/users:batch", r.URL.Path)
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(207)
+		w.Write([]byte(`{"results":[{"op":"delete","status":409,"message":"test-message"}]}`))
+	})
+	br, err := client.Batch(ctx, input)
+	assert.Nil(t, err)
+	assert.Equal(t, BatchResponse{Results: []BatchResult{{Op: BatchOpDelete, Status: 409, Message: "test-message"}}}, br)
+}
+
+func TestBatch_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(409)
+	})
+	br, err := client.Batch(ctx, Batch{Mode: BatchModeAllOrNothing})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "409")
+	assert.Equal(t, BatchResponse{}, br)
+}
+
+func TestExport(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	mockResp := "id,org_id,name\ntest-user-id,test-org-id,foo\n"
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/api/users/export", r.URL.Path)
+		assert.Equal(t, "csv", r.URL.Query().Get("format"))
+		assert.Equal(t, "text/csv", r.Header.Get("accept"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "text/csv")
+		w.Write([]byte(mockResp))
+	})
+	out, err := client.Export(ctx, "csv")
+	assert.Nil(t, err)
+	assert.Equal(t, mockResp, out)
+}
+
+func TestExport_HTTPErr(t *testing.T) {
+	ctx := context.Background()
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+	})
+	out, err := client.Export(ctx, "ndjson")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "403")
+	assert.Equal(t, "", out)
+}
+
+func TestImport(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	body := `{"org_id":"test-org-id","name":"foo","email":"foo@bar.com"}`
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, body, string(b))
+		assert.Equal(t, "/api/users/import", r.URL.Path)
+		assert.Equal(t, "ndjson", r.URL.Query().Get("format"))
+		assert.Equal(t, "true", r.URL.Query().Get("dry_run"))
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("content-type"))
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		assert.Equal(t, reqID, r.Header.Get("x-request-id"))
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"dry_run":true,"rows":1,"imported":0,"errors":[{"row":1,"status":409,"message":"test-message"}]}`))
+	})
+	ir, err := client.Import(ctx, "ndjson", body, true)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportReport{DryRun: true, Rows: 1, Errors: []ImportError{{Row: 1, Status: 409, Message: "test-message"}}}, ir)
+}
+
+func TestImport_HTTPErr(t *testing.T) {
+	ctx := context.Background()
+	getToken := func(isRetry bool) (string, error) {
+		return "test-token", nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+	})
+	ir, err := client.Import(ctx, "csv", "name\n", false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Equal(t, ImportReport{}, ir)
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-user-id"
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"email":"new@bar.com"}`), b)
+		assert.Equal(t, "/api/users/"+id+"/email-change", r.URL.Path)
+		assert.Equal(t, bearer(token), r.Header.Get("authorization"))
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(202)
+		w.Write([]byte(`{"user_id":"test-user-id","email":"new@bar.com","expires_at":"2024-01-02T03:04:05Z"}`))
+	})
+	pec, err := client.RequestEmailChange(ctx, id, EmailChange{Email: "new@bar.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, PendingEmailChange{
+		UserID:    id,
+		Email:     "new@bar.com",
+		ExpiresAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, pec)
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	id := "test-user-id"
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		b, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, []byte(`{"token":"email-token"}`), b)
+		assert.Equal(t, "/api/users/"+id+"/email-change/confirm", r.URL.Path)
+		w.Header().Add("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"test-user-id","email":"new@bar.com","version":2}`))
+	})
+	u, err := client.ConfirmEmailChange(ctx, id, ConfirmEmailChange{Token: "email-token"})
+	assert.Nil(t, err)
+	assert.Equal(t, User{ID: id, Email: "new@bar.com", Version: 2}, u)
+}
+
+func TestConfirmEmailChange_HTTPErr(t *testing.T) {
+	reqID := "test-req-id"
+	ctx := context.WithValue(context.Background(), ctxutil.ContextKeyReqID{}, reqID)
+	token := "test-token"
+	getToken := func(isRetry bool) (string, error) {
+		return token, nil
+	}
+	client, _ := initClient(getToken, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(410)
+	})
+	_, err := client.ConfirmEmailChange(ctx, "test-user-id", ConfirmEmailChange{Token: "email-token"})
+	var httpErr httpx.HTTPError
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, 410, httpErr.StatusCode)
+}
@@ -4,9 +4,9 @@ import "time"
 
 type User struct {
 	ID        string    `json:"id,omitempty" db:"id"`
-	OrgID     string    `json:"org_id,omitempty" db:"org_id"`
-	Name      string    `json:"name,omitempty" binding:"required" db:"name"`
-	Email     string    `json:"email,omitempty" binding:"required" db:"email"`
+	OrgID     string    `json:"org_id,omitempty" binding:"required" db:"org_id"`
+	Name      string    `json:"name,omitempty" binding:"required,max=100" db:"name"`
+	Email     string    `json:"email,omitempty" binding:"required,email,max=254" db:"email"`
 	IsSystem  bool      `json:"is_system" db:"is_system"`
 	IsAdmin   bool      `json:"is_admin" db:"is_admin"`
 	IsActive  bool      `json:"is_active" db:"is_active"`
@@ -15,9 +15,130 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 	UpdatedBy string    `json:"updated_by,omitempty" db:"updated_by"`
 	Version   int64     `json:"version" db:"version"`
+	// DeletedAt is only set on soft deleted users, which are only returned
+	// when include_deleted is asked for
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	DeletedBy string     `json:"deleted_by,omitempty" db:"deleted_by"`
 }
 
 type DeleteUser struct {
 	ID      string `json:"id,omitempty" binding:"required" db:"id"`
 	Version int64  `json:"version" binding:"required" db:"version"`
 }
+
+// RestoreUser only needs the version in the body, the id comes from the path.
+type RestoreUser struct {
+	ID      string `json:"id,omitempty" db:"id"`
+	Version int64  `json:"version" binding:"required" db:"version"`
+}
+
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// The columns GET /api/users can be sorted by, prefix one with "-" to sort
+// descending (ex. "-created_at").
+const (
+	SortEmail     = "email"
+	SortName      = "name"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+)
+
+// Query narrows GET /api/users, the zero value matches every user (sorted by
+// email). Email and Name match case insensitive substrings, the From times
+// are inclusive and the To times are exclusive.
+type Query struct {
+	OrgID       string
+	IsActive    *bool
+	IsAdmin     *bool
+	Email       string
+	Name        string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Sort        string
+}
+
+const (
+	// BatchModeAllOrNothing runs the whole batch in one tx, the first failed
+	// op rolls back every other op.
+	BatchModeAllOrNothing = "all-or-nothing"
+	// BatchModeBestEffort runs every op in its own tx, a failed op doesn't
+	// stop the ones after it.
+	BatchModeBestEffort = "best-effort"
+)
+
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// BatchOp is one item of a batch, deletes only need the user's id and
+// version. The user is validated per op, so it's skipped when binding.
+type BatchOp struct {
+	Op   string `json:"op" binding:"required,oneof=create update delete"`
+	User User   `json:"user" binding:"-"`
+}
+
+type Batch struct {
+	Mode string    `json:"mode" binding:"required,oneof=all-or-nothing best-effort"`
+	Ops  []BatchOp `json:"ops" binding:"required,min=1,max=500,dive"`
+}
+
+// BatchResult is the outcome of the op at the same index, status and code are
+// what the single user endpoint would have responded with.
+type BatchResult struct {
+	Op      string `json:"op"`
+	Status  int    `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	User    *User  `json:"user,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// ImportReport only lists the rows that failed, the status of each is what
+// the single user endpoint would have responded with. A dry run's report is
+// what a real import would have reported.
+type ImportReport struct {
+	DryRun   bool          `json:"dry_run"`
+	Rows     int           `json:"rows"`
+	Imported int           `json:"imported"`
+	Errors   []ImportError `json:"errors"`
+	// Stopped is the row the input couldn't be read past, nothing after it
+	// was imported (the rows before it were)
+	Stopped *ImportError `json:"stopped,omitempty"`
+}
+
+// ImportError is a row that wasn't imported, Row is 1 based and doesn't count
+// the csv header.
+type ImportError struct {
+	Row     int    `json:"row"`
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// EmailChange asks for a user's email to be changed, it only takes effect
+// once the token sent to the new email is confirmed.
+type EmailChange struct {
+	Email string `json:"email" binding:"required,email,max=254"`
+}
+
+// PendingEmailChange is what's waiting to be confirmed, the token itself is
+// only ever sent to the new email.
+type PendingEmailChange struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ConfirmEmailChange struct {
+	Token string `json:"token" binding:"required"`
+}